@@ -0,0 +1,184 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/pkg/benchmarks"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// runBench generates a synthetic graph and runs a workload against it, or
+// runs a read-only workload against a remote server's existing graph
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	nodes := fs.Int("nodes", 10000, "nodes to generate (or already loaded, with --remote)")
+	edges := fs.Int("edges", 50000, "edges to generate")
+	distribution := fs.String("distribution", string(benchmarks.Uniform), "edge target distribution: uniform or powerlaw")
+	skew := fs.Float64("skew", benchmarks.DefaultSkew, "Zipf exponent of the powerlaw distribution")
+	queriesPath := fs.String("queries", "", "workload file of RQL queries (default: built-in lookups)")
+	concurrency := fs.Int("concurrency", 4, "parallel workers")
+	ops := fs.Int("ops", 10000, "total operations")
+	writeRatio := fs.Float64("write-ratio", 0.1, "fraction of operations that are writes")
+	seed := fs.Int64("seed", benchmarks.DefaultSeed, "random seed for the graph and workload")
+	dataDir := fs.String("data-dir", "", "empty directory for the embedded graph (default: a temporary directory)")
+	remote := fs.String("remote", "", "server URL to benchmark instead of an embedded graph")
+	fs.Parse(args)
+
+	cfg := benchmarks.WorkloadConfig{
+		Operations:  *ops,
+		Concurrency: *concurrency,
+		WriteRatio:  *writeRatio,
+		Nodes:       *nodes,
+		Seed:        *seed,
+	}
+	if *queriesPath != "" {
+		f, err := os.Open(*queriesPath)
+		if err != nil {
+			return fmt.Errorf("failed to open workload: %w", err)
+		}
+		cfg.Queries, err = benchmarks.ParseWorkload(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if *remote != "" {
+		if cfg.WriteRatio > 0 {
+			return fmt.Errorf("remote servers only run read-only workloads; pass --write-ratio 0")
+		}
+		fmt.Printf("Running %d operations against %s with %d workers\n", cfg.Operations, *remote, cfg.Concurrency)
+		report, err := benchmarks.RunWorkload(&benchmarks.RemoteTarget{URL: *remote}, cfg)
+		if err != nil {
+			return err
+		}
+		printWorkloadReport(report)
+		return nil
+	}
+
+	dir := *dataDir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "rdgdb-bench-")
+		if err != nil {
+			return fmt.Errorf("failed to create data directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+	walDir := filepath.Join(dir, storage.WALSubdir)
+	snapshotDir := filepath.Join(dir, storage.SnapshotSubdir)
+	g, err := storage.NewPersistentGraph(walDir, snapshotDir)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+	if g.NodeCount() > 0 {
+		return fmt.Errorf("data directory %s is not empty", dir)
+	}
+
+	// The graph is generated in memory and persisted by one snapshot, as
+	// logging millions of entities one by one would dominate the run
+	start := time.Now()
+	err = benchmarks.GenerateSynthetic(g.Graph, benchmarks.SyntheticConfig{
+		Nodes:        *nodes,
+		Edges:        *edges,
+		Distribution: benchmarks.DegreeDistribution(*distribution),
+		Skew:         *skew,
+		Seed:         *seed,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Generated %d nodes, %d edges in %s\n", g.NodeCount(), g.EdgeCount(), time.Since(start).Round(time.Millisecond))
+
+	start = time.Now()
+	if err := g.Snapshot(); err != nil {
+		return err
+	}
+	snapshotTime := time.Since(start)
+	snapshotSize, err := dirSize(snapshotDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Snapshot: %s, %s\n", snapshotTime.Round(time.Millisecond), formatBytes(snapshotSize))
+
+	walBefore, err := dirSize(walDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Running %d operations with %d workers (%.0f%% writes)\n", cfg.Operations, cfg.Concurrency, cfg.WriteRatio*100)
+	report, err := benchmarks.RunWorkload(&benchmarks.EmbeddedTarget{Graph: g}, cfg)
+	if err != nil {
+		return err
+	}
+	printWorkloadReport(report)
+
+	walAfter, err := dirSize(walDir)
+	if err != nil {
+		return err
+	}
+	walBytes := walAfter - walBefore
+	fmt.Printf("WAL: %s written", formatBytes(walBytes))
+	if report.Writes.Count > 0 {
+		fmt.Printf(", %s per write", formatBytes(walBytes/int64(report.Writes.Count)))
+	}
+	fmt.Println()
+	return nil
+}
+
+func printWorkloadReport(report *benchmarks.WorkloadReport) {
+	fmt.Printf("Completed in %s: %.1f ops/s\n", report.Duration.Round(time.Millisecond), report.Throughput())
+	for _, kind := range []struct {
+		name  string
+		stats benchmarks.LatencyStats
+	}{{"reads", report.Reads}, {"writes", report.Writes}} {
+		s := kind.stats
+		if s.Count == 0 {
+			continue
+		}
+		us := func(d time.Duration) time.Duration { return d.Round(time.Microsecond) }
+		fmt.Printf("  %-6s %8d  mean %-9s p50 %-9s p90 %-9s p99 %-9s p99.9 %-9s max %s\n",
+			kind.name, s.Count, us(s.Mean), us(s.P50), us(s.P90), us(s.P99), us(s.P999), us(s.Max))
+	}
+}
+
+// dirSize returns the total size of the files under dir
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure %s: %w", dir, err)
+	}
+	return size, nil
+}
+
+// formatBytes renders n with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// runFsck recovers a data directory and checks the graph's structure,
+// optionally repairing what it finds. The repairs are written to the WAL.
+func runFsck(args []string) error {
+	fs := flag.NewFlagSet("fsck", flag.ExitOnError)
+	dataDir := fs.String("data-dir", dataDirFromEnv(), "data directory")
+	repair := fs.Bool("repair", false, "drop dangling references and fix what else is found, logging the repairs")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: rdgdb fsck [--data-dir dir] [--repair]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	// The directory may also be given as the only argument
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		*dataDir = fs.Arg(0)
+	default:
+		fs.Usage()
+		return fmt.Errorf("expected one data directory, got %d", fs.NArg())
+	}
+
+	// Without --repair nothing is written, so a running server may hold
+	// the directory
+	var g *storage.PersistentGraph
+	var err error
+	if *repair {
+		g, err = openGraph(*dataDir)
+	} else {
+		g, err = openGraphReadOnly(*dataDir)
+	}
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	found := g.Validate()
+	fmt.Printf("Checked %d nodes, %d edges in %s\n", g.NodeCount(), g.EdgeCount(), *dataDir)
+	for _, inc := range found {
+		fmt.Printf("  %s\n", inc)
+	}
+	if len(found) == 0 {
+		fmt.Println("No inconsistencies found")
+		return nil
+	}
+	if !*repair {
+		return fmt.Errorf("found %d inconsistencies; run with --repair to fix them", len(found))
+	}
+
+	if err := g.Repair(found); err != nil {
+		return fmt.Errorf("failed to repair graph: %w", err)
+	}
+	if remaining := g.Validate(); len(remaining) > 0 {
+		for _, inc := range remaining {
+			fmt.Printf("  still %s\n", inc)
+		}
+		return fmt.Errorf("%d inconsistencies remain after repair", len(remaining))
+	}
+	fmt.Printf("Repaired %d inconsistencies\n", len(found))
+	return nil
+}
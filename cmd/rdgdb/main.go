@@ -0,0 +1,359 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fnuworsu/rdgDB/pkg/graphio"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+const (
+	defaultDataDir = "./data"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: rdgdb <command> [options]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  import          Bulk import nodes and edges from CSV files or Cypher CREATE statements")
+	fmt.Fprintln(os.Stderr, "  dump            Write the graph as JSON Lines (gzip if the file ends in .gz)")
+	fmt.Fprintln(os.Stderr, "  restore         Load a JSON Lines dump")
+	fmt.Fprintln(os.Stderr, "  export          Export the graph as a Cypher script or GraphML")
+	fmt.Fprintln(os.Stderr, "  backup          Write a backup archive (snapshot + WAL)")
+	fmt.Fprintln(os.Stderr, "  restore-backup  Unpack a backup archive into an empty data directory")
+	fmt.Fprintln(os.Stderr, "  bench           Load-test a generated graph or a remote server")
+	fmt.Fprintln(os.Stderr, "  fsck            Check a data directory's graph for inconsistencies (--repair fixes them)")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "dump":
+		err = runDump(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	case "backup":
+		err = runBackup(os.Args[2:])
+	case "restore-backup":
+		err = runRestoreBackup(os.Args[2:])
+	case "bench":
+		err = runBench(os.Args[2:])
+	case "fsck":
+		err = runFsck(os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", os.Args[1])
+		usage()
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// dataDirFromEnv returns RDGDB_DATA_DIR or the default data directory
+func dataDirFromEnv() string {
+	if dir := os.Getenv("RDGDB_DATA_DIR"); dir != "" {
+		return dir
+	}
+	return defaultDataDir
+}
+
+// openGraph opens the persistent graph stored under dataDir
+func openGraph(dataDir string) (*storage.PersistentGraph, error) {
+	return storage.NewPersistentGraph(
+		filepath.Join(dataDir, storage.WALSubdir),
+		filepath.Join(dataDir, storage.SnapshotSubdir),
+	)
+}
+
+// openGraphReadOnly opens dataDir without write access, so it is safe to
+// use while a server holds the same directory
+func openGraphReadOnly(dataDir string) (*storage.PersistentGraph, error) {
+	opts := storage.DefaultOptions()
+	opts.ReadOnly = true
+	return storage.NewPersistentGraphWithOptions(
+		filepath.Join(dataDir, storage.WALSubdir),
+		filepath.Join(dataDir, storage.SnapshotSubdir),
+		opts,
+	)
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dataDir := fs.String("data-dir", dataDirFromEnv(), "data directory")
+	nodesPath := fs.String("nodes", "", "node CSV file")
+	edgesPath := fs.String("edges", "", "edge CSV file")
+	batchSize := fs.Int("batch-size", 1000, "rows applied per batch")
+	strict := fs.Bool("strict", false, "abort on edges referencing unknown node IDs")
+	cypherPath := fs.String("cypher", "", "file of Cypher CREATE statements to import instead of CSV")
+	fs.Parse(args)
+
+	if *nodesPath == "" && *cypherPath == "" {
+		return fmt.Errorf("--nodes or --cypher is required")
+	}
+
+	g, err := openGraph(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	if *cypherPath != "" {
+		f, err := os.Open(*cypherPath)
+		if err != nil {
+			return fmt.Errorf("failed to open Cypher file: %w", err)
+		}
+		defer f.Close()
+
+		var report *graphio.ImportReport
+		err = g.BulkLoad(func(loader *storage.BulkLoader) error {
+			var err error
+			report, err = graphio.ImportCypher(loader, f)
+			return err
+		})
+		if report != nil {
+			printImportReport(report)
+		}
+		if err != nil {
+			return err
+		}
+		return g.Snapshot()
+	}
+
+	opts := graphio.DefaultImportOptions()
+	opts.BatchSize = *batchSize
+	if *strict {
+		opts.OnDangling = graphio.DanglingError
+	}
+	opts.Progress = func(p graphio.ImportProgress) {
+		fmt.Printf("\r%s: %d lines (%d nodes, %d edges)", filepath.Base(p.File), p.Lines, p.Nodes, p.Edges)
+	}
+
+	// The whole import is logged with one fsync, and discarded if it fails
+	var report *graphio.ImportReport
+	err = g.BulkLoad(func(loader *storage.BulkLoader) error {
+		var err error
+		report, err = graphio.ImportCSV(loader, *nodesPath, *edgesPath, opts)
+		return err
+	})
+	fmt.Println()
+	if report != nil {
+		printImportReport(report)
+	}
+	if err != nil {
+		return err
+	}
+
+	return g.Snapshot()
+}
+
+func printImportReport(report *graphio.ImportReport) {
+	fmt.Printf("Imported %d nodes (%d skipped), %d edges (%d skipped)\n",
+		report.NodesImported, report.NodesSkipped, report.EdgesImported, report.EdgesSkipped)
+	for _, e := range report.Errors {
+		fmt.Printf("  %v\n", e)
+	}
+}
+
+func runDump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	dataDir := fs.String("data-dir", dataDirFromEnv(), "data directory")
+	outPath := fs.String("out", "", "output file (.gz to compress)")
+	compress := fs.Bool("gzip", false, "gzip the output")
+	fs.Parse(args)
+
+	if *outPath == "" {
+		return fmt.Errorf("--out is required")
+	}
+
+	g, err := openGraphReadOnly(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	f, err := os.Create(*outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create dump file: %w", err)
+	}
+
+	// Closing flushes the gzip stream and the file, so their errors
+	// mean the dump is incomplete
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if *compress || strings.HasSuffix(*outPath, ".gz") {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	if err := graphio.Dump(g.Graph, w); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to dump graph: %w", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to compress dump: %w", err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close dump file: %w", err)
+	}
+	fmt.Printf("Dumped %d nodes, %d edges to %s\n", g.NodeCount(), g.EdgeCount(), *outPath)
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dataDir := fs.String("data-dir", dataDirFromEnv(), "data directory")
+	inPath := fs.String("in", "", "dump file (plain or gzip)")
+	remap := fs.Bool("remap-ids", false, "assign fresh IDs instead of keeping dumped ones")
+	batchSize := fs.Int("batch-size", 1000, "records applied per batch")
+	fs.Parse(args)
+
+	if *inPath == "" {
+		return fmt.Errorf("--in is required")
+	}
+
+	f, err := os.Open(*inPath)
+	if err != nil {
+		return fmt.Errorf("failed to open dump file: %w", err)
+	}
+	defer f.Close()
+
+	g, err := openGraph(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	opts := graphio.DefaultRestoreOptions()
+	opts.BatchSize = *batchSize
+	opts.RemapIDs = *remap
+	opts.Progress = func(p graphio.ImportProgress) {
+		fmt.Printf("\r%d records (%d nodes, %d edges)", p.Lines, p.Nodes, p.Edges)
+	}
+
+	report, err := graphio.Restore(g, f, opts)
+	fmt.Println()
+	if report != nil {
+		printImportReport(report)
+	}
+	if err != nil {
+		return err
+	}
+
+	return g.Snapshot()
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dataDir := fs.String("data-dir", dataDirFromEnv(), "data directory")
+	outPath := fs.String("out", "", "output file")
+	format := fs.String("format", "cypher", "output format: cypher or graphml")
+	batchSize := fs.Int("batch-size", 1000, "Cypher statements per transaction (0 for none)")
+	includeIDs := fs.Bool("include-ids", false, "keep rdgDB node IDs as a property in Cypher output")
+	createOnly := fs.Bool("create-only", false, "write plain CREATE statements that 'rdgdb import --cypher' can read")
+	fs.Parse(args)
+
+	if *outPath == "" {
+		return fmt.Errorf("--out is required")
+	}
+	if *format != "cypher" && *format != "graphml" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	g, err := openGraphReadOnly(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	f, err := os.Create(*outPath)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	if *format == "graphml" {
+		err = graphio.ExportGraphML(g.Graph, f)
+	} else {
+		opts := graphio.DefaultCypherOptions()
+		opts.BatchSize = *batchSize
+		opts.IncludeIDs = *includeIDs
+		opts.CreateOnly = *createOnly
+		opts.Warn = func(msg string) {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+		}
+		err = graphio.ExportCypher(g.Graph, f, opts)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to export graph: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close export file: %w", err)
+	}
+	fmt.Printf("Exported %d nodes, %d edges to %s\n", g.NodeCount(), g.EdgeCount(), *outPath)
+	return nil
+}
+
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	dataDir := fs.String("data-dir", dataDirFromEnv(), "data directory")
+	outPath := fs.String("out", "", "backup archive (.tar.gz)")
+	fs.Parse(args)
+
+	if *outPath == "" {
+		return fmt.Errorf("--out is required")
+	}
+
+	g, err := openGraph(*dataDir)
+	if err != nil {
+		return err
+	}
+	defer g.Close()
+
+	manifest, err := g.Backup(*outPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Backed up %d nodes, %d edges (WAL index %d) to %s\n",
+		manifest.NodeCount, manifest.EdgeCount, manifest.WALIndex, *outPath)
+	return nil
+}
+
+func runRestoreBackup(args []string) error {
+	fs := flag.NewFlagSet("restore-backup", flag.ExitOnError)
+	dataDir := fs.String("data-dir", dataDirFromEnv(), "empty data directory to restore into")
+	inPath := fs.String("in", "", "backup archive")
+	fs.Parse(args)
+
+	if *inPath == "" {
+		return fmt.Errorf("--in is required")
+	}
+
+	manifest, err := storage.RestoreBackup(*inPath, *dataDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored backup from %s (%d nodes, %d edges) into %s\n",
+		manifest.CreatedAt.Format("2006-01-02 15:04:05"), manifest.NodeCount, manifest.EdgeCount, *dataDir)
+	return nil
+}
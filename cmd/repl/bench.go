@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// benchStats summarizes the durations of a benchmark run
+type benchStats struct {
+	Min, Max, Mean, P99 time.Duration
+	Total               time.Duration
+	RowsPerSec          float64
+}
+
+// percentile returns the p-th percentile (0-100) of sorted durations
+// using the nearest-rank method
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// summarize computes benchmark statistics from per-iteration durations
+func summarize(durations []time.Duration, rows int) benchStats {
+	var stats benchStats
+	if len(durations) == 0 {
+		return stats
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for _, d := range sorted {
+		stats.Total += d
+	}
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	stats.Mean = stats.Total / time.Duration(len(sorted))
+	stats.P99 = percentile(sorted, 99)
+	if stats.Total > 0 {
+		stats.RowsPerSec = float64(rows) / stats.Total.Seconds()
+	}
+	return stats
+}
+
+// parseBenchArgs splits "\bench <N> <query>" into its iteration count and query
+func parseBenchArgs(cmd string) (int, string, error) {
+	fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(cmd, `\bench`)), " ", 2)
+	if len(fields) < 2 || strings.TrimSpace(fields[1]) == "" {
+		return 0, "", fmt.Errorf(`usage: \bench <N> <query>`)
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil || n <= 0 {
+		return 0, "", fmt.Errorf("invalid iteration count %q", fields[0])
+	}
+	return n, strings.TrimSpace(fields[1]), nil
+}
+
+// benchmarkQuery parses and executes q n times against the live graph and
+// prints timing statistics
+func benchmarkQuery(n int, q string, g *storage.PersistentGraph) {
+	durations := make([]time.Duration, 0, n)
+	rows := 0
+
+	for i := 0; i < n; i++ {
+		start := time.Now()
+		parsed, err := query.NewParser(q).Parse()
+		if err != nil {
+			fmt.Printf("Parse Error: %v\n", err)
+			return
+		}
+		result, err := parsed.Execute(g)
+		if err != nil {
+			fmt.Printf("Execution Error: %v\n", err)
+			return
+		}
+		durations = append(durations, time.Since(start))
+		rows += len(result.Rows)
+	}
+
+	stats := summarize(durations, rows)
+	fmt.Printf("Ran %d iterations in %s\n", n, stats.Total)
+	fmt.Printf("  min:  %s\n", stats.Min)
+	fmt.Printf("  max:  %s\n", stats.Max)
+	fmt.Printf("  mean: %s\n", stats.Mean)
+	fmt.Printf("  p99:  %s\n", stats.P99)
+	fmt.Printf("  rows/sec: %.1f\n", stats.RowsPerSec)
+}
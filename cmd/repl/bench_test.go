@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 99))
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 50))
+	assert.Equal(t, 100*time.Millisecond, percentile(sorted, 100))
+	assert.Equal(t, 1*time.Millisecond, percentile(sorted, 0))
+	assert.Equal(t, time.Duration(0), percentile(nil, 99))
+
+	// Small samples round up to the next rank
+	assert.Equal(t, 3*time.Millisecond, percentile(sorted[:3], 99))
+}
+
+func TestSummarize(t *testing.T) {
+	durations := []time.Duration{30 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond}
+
+	stats := summarize(durations, 6)
+	assert.Equal(t, 10*time.Millisecond, stats.Min)
+	assert.Equal(t, 30*time.Millisecond, stats.Max)
+	assert.Equal(t, 20*time.Millisecond, stats.Mean)
+	assert.Equal(t, 30*time.Millisecond, stats.P99)
+	assert.Equal(t, 60*time.Millisecond, stats.Total)
+	assert.InDelta(t, 100.0, stats.RowsPerSec, 0.001)
+}
+
+func TestParseBenchArgs(t *testing.T) {
+	n, q, err := parseBenchArgs(`\bench 1000 MATCH (n:Person) RETURN n`)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, n)
+	assert.Equal(t, "MATCH (n:Person) RETURN n", q)
+
+	_, _, err = parseBenchArgs(`\bench abc MATCH (n) RETURN n`)
+	assert.Error(t, err)
+	_, _, err = parseBenchArgs(`\bench 10`)
+	assert.Error(t, err)
+}
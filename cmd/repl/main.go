@@ -3,13 +3,18 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
+	_ "github.com/fnuworsu/rdgDB/pkg/algorithms" // registers stats algorithms
+	"github.com/fnuworsu/rdgDB/pkg/graphio"
 	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/fnuworsu/rdgDB/pkg/report"
 	"github.com/fnuworsu/rdgDB/pkg/storage"
 )
 
@@ -36,6 +41,7 @@ func main() {
 	snapshotDir := filepath.Join(dataDir, "snapshots")
 
 	fmt.Printf("Initializing storage at %s...\n", dataDir)
+	start := time.Now()
 	g, err := storage.NewPersistentGraph(walDir, snapshotDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize graph: %v\n", err)
@@ -43,23 +49,20 @@ func main() {
 	}
 	defer g.Close()
 
-	fmt.Printf("✓ Connected to graph: %d nodes, %d edges\n", g.NodeCount(), g.EdgeCount())
+	fmt.Printf("✓ Connected to graph: %d nodes, %d edges (recovered in %s)\n",
+		g.NodeCount(), g.EdgeCount(), time.Since(start).Round(time.Millisecond))
 	fmt.Println("Type 'help' for available commands, 'exit' to quit")
 	fmt.Println()
 
-	reader := bufio.NewReader(os.Stdin)
-
+	stdinLines = readLines(os.Stdin)
 	for {
 		fmt.Print("rdgDB> ")
 
-		input, err := reader.ReadString('\n')
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
-			continue
+		input, ok := <-stdinLines
+		if !ok {
+			break
 		}
 
-		input = strings.TrimSpace(input)
-
 		if input == "" {
 			continue
 		}
@@ -72,6 +75,32 @@ func main() {
 	fmt.Println("Goodbye!")
 }
 
+// stdinLines carries the trimmed lines read from standard input, so that a
+// WATCH can listen for "exit" while waiting for changes. It is closed when
+// the input ends.
+var stdinLines <-chan string
+
+func readLines(r io.Reader) <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(r)
+		for {
+			input, err := reader.ReadString('\n')
+			if input != "" || err == nil {
+				lines <- strings.TrimSpace(input)
+			}
+			if err != nil {
+				if err != io.EOF {
+					fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+				}
+				return
+			}
+		}
+	}()
+	return lines
+}
+
 func processCommand(cmd string, g *storage.PersistentGraph) bool {
 	// Handle meta-commands
 	if strings.HasPrefix(strings.ToLower(cmd), "exit") ||
@@ -86,7 +115,7 @@ func processCommand(cmd string, g *storage.PersistentGraph) bool {
 	}
 
 	if cmd == "status" {
-		printStatus(g)
+		printStatus(os.Stdout, g)
 		return false
 	}
 
@@ -95,6 +124,43 @@ func processCommand(cmd string, g *storage.PersistentGraph) bool {
 		return false
 	}
 
+	if strings.HasPrefix(cmd, `\load`) {
+		loadCSV(strings.Fields(cmd)[1:], g)
+		return false
+	}
+
+	if strings.HasPrefix(cmd, `\bench`) {
+		n, q, err := parseBenchArgs(cmd)
+		if err != nil {
+			fmt.Println(err)
+			return false
+		}
+		benchmarkQuery(n, q, g)
+		return false
+	}
+
+	if cmd == `\report` {
+		if err := report.Generate(g.Graph).WriteText(os.Stdout); err != nil {
+			fmt.Printf("Report failed: %v\n", err)
+		}
+		return false
+	}
+
+	if cmd == `\reindex` {
+		reindex(g)
+		return false
+	}
+
+	if strings.HasPrefix(cmd, `\backup`) {
+		backup(strings.Fields(cmd)[1:], g)
+		return false
+	}
+
+	if strings.EqualFold(strings.Fields(cmd)[0], "WATCH") {
+		watch(cmd, g)
+		return false
+	}
+
 	// Treat as query
 	executeQuery(cmd, g)
 	return false
@@ -118,6 +184,74 @@ func seedData(g *storage.PersistentGraph) {
 	fmt.Println("✓ Created 4 nodes and 4 edges")
 }
 
+func loadCSV(args []string, g *storage.PersistentGraph) {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println(`Usage: \load <nodes.csv> [edges.csv]`)
+		return
+	}
+
+	edgesPath := ""
+	if len(args) == 2 {
+		edgesPath = args[1]
+	}
+
+	start := time.Now()
+	report, err := graphio.ImportCSV(g, args[0], edgesPath, graphio.DefaultImportOptions())
+	if report != nil {
+		fmt.Printf("✓ Imported %d nodes, %d edges (%d skipped) in %s\n",
+			report.NodesImported, report.EdgesImported,
+			report.NodesSkipped+report.EdgesSkipped, time.Since(start))
+		for _, e := range report.Errors {
+			fmt.Printf("  %v\n", e)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Import Error: %v\n", err)
+	}
+}
+
+func backup(args []string, g *storage.PersistentGraph) {
+	if len(args) != 1 {
+		fmt.Println(`Usage: \backup <file.tar.gz>`)
+		return
+	}
+
+	start := time.Now()
+	manifest, err := g.Backup(args[0])
+	if err != nil {
+		fmt.Printf("Backup Error: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Backed up %d nodes, %d edges (WAL index %d) to %s in %s\n",
+		manifest.NodeCount, manifest.EdgeCount, manifest.WALIndex, args[0], time.Since(start))
+}
+
+func reindex(g *storage.PersistentGraph) {
+	start := time.Now()
+	if err := g.Reindex(); err != nil {
+		fmt.Printf("Reindex Error: %v\n", err)
+		return
+	}
+	fmt.Printf("✓ Rebuilt all indexes in %s\n", time.Since(start))
+}
+
+func watch(cmd string, g *storage.PersistentGraph) {
+	spec, err := parseWatch(cmd)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Ctrl+C ends the watch rather than the REPL
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	if err := runWatch(spec, g, stdinLines, interrupt, os.Stdout); err != nil {
+		fmt.Printf("Watch Error: %v\n", err)
+	}
+}
+
 func executeQuery(input string, g *storage.PersistentGraph) {
 	start := time.Now()
 
@@ -125,13 +259,15 @@ func executeQuery(input string, g *storage.PersistentGraph) {
 	parser := query.NewParser(input)
 	q, err := parser.Parse()
 	if err != nil {
-		fmt.Printf("Parse Error: %v\n", err)
+		fmt.Printf("Parse Error: %s\n", parser.ErrorWithContext())
 		return
 	}
 
 	// 2. Execute
-	// Pass the underlying in-memory graph to the executor
-	result, err := q.Execute(g.Graph)
+	// Execute against the persistent graph so procedures can reach it.
+	// Stable ordering keeps demos reproducible from run to run.
+	q.StableOrder = true
+	result, err := q.Execute(g)
 	if err != nil {
 		fmt.Printf("Execution Error: %v\n", err)
 		return
@@ -158,7 +294,7 @@ func printResult(res *query.Result) {
 
 	for _, row := range res.Rows {
 		for i, col := range res.Columns {
-			val := fmt.Sprintf("%v", row[col])
+			val := formatValue(row[col])
 			if len(val) > widths[i] {
 				widths[i] = len(val)
 			}
@@ -180,26 +316,83 @@ func printResult(res *query.Result) {
 	// Print Rows
 	for _, row := range res.Rows {
 		for i, col := range res.Columns {
-			val := fmt.Sprintf("%v", row[col])
+			val := formatValue(row[col])
 			fmt.Printf("%-*s  ", widths[i], val)
 		}
 		fmt.Println()
 	}
 }
 
+// formatValue renders a result value, writing relationships in pattern
+// form such as [:KNOWS {since: 2020}] and paths as
+// (1:Person)-[:KNOWS]->(2:Person)
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case *graph.Edge:
+		return v.RelationshipString()
+	case []*graph.Edge:
+		parts := make([]string, len(v))
+		for i, edge := range v {
+			parts[i] = edge.RelationshipString()
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case []graph.PropertyValue:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatValue(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case *query.Path:
+		var b strings.Builder
+		b.WriteString(v.Nodes[0].String())
+		for i, edge := range v.Edges {
+			if edge.Source == v.Nodes[i].ID {
+				b.WriteString("-" + edge.RelationshipString() + "->")
+			} else {
+				b.WriteString("<-" + edge.RelationshipString() + "-")
+			}
+			b.WriteString(v.Nodes[i+1].String())
+		}
+		return b.String()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
 func printHelp() {
 	fmt.Println("Available commands:")
 	fmt.Println("  help, ?       - Show this help message")
 	fmt.Println("  status        - Show database status")
+	fmt.Println(`  \load <nodes.csv> [edges.csv] - Import nodes/edges from CSV`)
+	fmt.Println(`  \bench <N> <query>  - Run a query N times and print timings`)
+	fmt.Println(`  \backup <file.tar.gz> - Write a backup archive`)
+	fmt.Println(`  \report       - Print a graph summary (also CALL db.report())`)
+	fmt.Println(`  \reindex      - Rebuild all indexes from the graph`)
+	fmt.Println(`  WATCH (n:Label) [WHERE ...] - Print matching node changes until 'exit' or Ctrl+C`)
 	fmt.Println("  exit, quit, q - Exit the REPL")
 	fmt.Println()
 	fmt.Println("Query Examples:")
 	fmt.Println("  MATCH (n:Person) RETURN n.name")
 	fmt.Println("  MATCH (a)-[:KNOWS]->(b) RETURN a.name, b.name")
+	fmt.Println("  SHOW LABELS | SHOW RELATIONSHIP TYPES | SHOW INDEXES | SHOW CONSTRAINTS")
+	fmt.Println("  CALL db.labels() | CALL db.relationshipTypes() | CALL db.schema()")
 }
 
-func printStatus(g *storage.PersistentGraph) {
-	fmt.Printf("Nodes: %d\n", g.NodeCount())
-	fmt.Printf("Edges: %d\n", g.EdgeCount())
-	fmt.Println("Storage: Persistent (WAL + Snapshots)")
+// printStatus prints the node and edge counts, with a line per label
+// giving its property keys and a line per relationship type
+func printStatus(out io.Writer, g *storage.PersistentGraph) {
+	fmt.Fprintf(out, "Nodes: %d\n", g.NodeCount())
+	labelCounts := g.LabelCounts()
+	for _, label := range g.Labels() {
+		fmt.Fprintf(out, "  :%s (%d)", label, labelCounts[label])
+		if keys := g.PropertyKeys(label); len(keys) > 0 {
+			fmt.Fprintf(out, " {%s}", strings.Join(keys, ", "))
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintf(out, "Edges: %d\n", g.EdgeCount())
+	typeCounts := g.EdgeTypeCounts()
+	for _, typ := range g.RelationshipTypes() {
+		fmt.Fprintf(out, "  :%s (%d)\n", typ, typeCounts[typ])
+	}
+	fmt.Fprintln(out, "Storage: Persistent (WAL + Snapshots)")
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintStatus(t *testing.T) {
+	dir := t.TempDir()
+	g, err := storage.NewPersistentGraph(filepath.Join(dir, "wal"), filepath.Join(dir, "snapshots"))
+	require.NoError(t, err)
+	defer g.Close()
+
+	alice, err := g.AddNode("Person", graph.Properties{"name": "Alice", "age": 30})
+	require.NoError(t, err)
+	acme, err := g.AddNode("Company", nil)
+	require.NoError(t, err)
+	_, err = g.AddEdge(alice.ID, acme.ID, "WORKS_AT", nil)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	printStatus(&out, g)
+	assert.Equal(t, "Nodes: 2\n"+
+		"  :Company (1)\n"+
+		"  :Person (1) {age, name}\n"+
+		"Edges: 1\n"+
+		"  :WORKS_AT (1)\n"+
+		"Storage: Persistent (WAL + Snapshots)\n", out.String())
+}
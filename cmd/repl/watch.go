@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+)
+
+// watchSpec is a parsed WATCH command: a single node pattern and an
+// optional WHERE condition on it
+type watchSpec struct {
+	variable string
+	source   string // The pattern as a MATCH ... RETURN * query
+}
+
+// parseWatch parses WATCH <node pattern> [WHERE <condition>], such as
+// WATCH (n:Person) WHERE n.city = "SF"
+func parseWatch(cmd string) (*watchSpec, error) {
+	rest := strings.TrimSpace(cmd[len("WATCH"):])
+	if rest == "" {
+		return nil, fmt.Errorf(`Usage: WATCH (n:Label {key: value}) [WHERE condition]`)
+	}
+
+	source := "MATCH " + rest + " RETURN *"
+	parser := query.NewParser(source)
+	q, err := parser.Parse()
+	if err != nil {
+		return nil, fmt.Errorf("Parse Error: %s", parser.ErrorWithContext())
+	}
+	if q.Match == nil || q.Input != nil || len(q.Match.Patterns) != 1 ||
+		len(q.Match.Patterns[0].Nodes) != 1 {
+		return nil, fmt.Errorf("WATCH takes a single node pattern, as in (n:Person)")
+	}
+	variable := q.Match.Patterns[0].Nodes[0].Variable
+	if variable == "" {
+		return nil, fmt.Errorf("WATCH pattern needs a variable, as in (n:Person)")
+	}
+	return &watchSpec{variable: variable, source: source}, nil
+}
+
+// matches reports whether a node with the given label and properties
+// matches the pattern, by running it against a graph holding only that
+// node
+func (w *watchSpec) matches(label string, props graph.Properties) (bool, error) {
+	scratch := storage.NewGraph()
+	if _, err := scratch.AddNode(label, props); err != nil {
+		return false, err
+	}
+	result, err := w.run(scratch)
+	if err != nil {
+		return false, err
+	}
+	return len(result.Rows) > 0, nil
+}
+
+func (w *watchSpec) run(g query.GraphStorage) (*query.Result, error) {
+	q, err := query.NewParser(w.source).Parse()
+	if err != nil {
+		return nil, err
+	}
+	return q.Execute(g)
+}
+
+// runWatch prints the node changes that match spec as they are committed:
+// [+NODE] when a node starts matching, by being added or updated, and
+// [-NODE] when a matching node is deleted or stops matching. It returns
+// when a line reading "exit" arrives on lines, lines is closed, interrupt
+// fires or the change stream ends.
+func runWatch(spec *watchSpec, g *storage.PersistentGraph, lines <-chan string, interrupt <-chan os.Signal, out io.Writer) error {
+	// Subscribe before listing the current matches, so that no change
+	// falls in between
+	changes := g.Subscribe()
+	defer g.Unsubscribe(changes)
+
+	current, err := spec.run(g)
+	if err != nil {
+		return err
+	}
+	matching := make(map[graph.NodeID]bool, len(current.Rows))
+	for _, row := range current.Rows {
+		if node, ok := row[spec.variable].(*graph.Node); ok {
+			matching[node.ID] = true
+		}
+	}
+
+	fmt.Fprintf(out, "Watching %d matching nodes; type 'exit' or press Ctrl+C to stop\n", len(matching))
+	for {
+		select {
+		case entry, ok := <-changes:
+			if !ok {
+				return nil
+			}
+			if err := watchEntry(spec, g, entry, matching, out); err != nil {
+				return err
+			}
+		case line, ok := <-lines:
+			if !ok || strings.EqualFold(line, "exit") {
+				return nil
+			}
+		case <-interrupt:
+			return nil
+		}
+	}
+}
+
+// watchEntry prints the change entry makes to the set of matching nodes
+func watchEntry(spec *watchSpec, g *storage.PersistentGraph, entry wal.LogEntry, matching map[graph.NodeID]bool, out io.Writer) error {
+	if entry.OpType == wal.OpGap {
+		fmt.Fprintln(out, "[!] missed changes; the watch may be incomplete")
+		return nil
+	}
+	nodeID, ok := entry.Data["node_id"].(float64)
+	if !ok {
+		return nil
+	}
+	id := graph.NodeID(uint64(nodeID))
+
+	var label string
+	var props graph.Properties
+	switch entry.OpType {
+	case wal.OpAddNode:
+		label, _ = entry.Data["label"].(string)
+		props = graph.Properties{}
+		if m, ok := entry.Data["properties"].(map[string]interface{}); ok {
+			for k, v := range m {
+				props[k] = graph.DecodeJSONValue(v)
+			}
+		}
+	case wal.OpSetNodeProp, wal.OpRestoreNode:
+		// Match against the node as it is now, with every property
+		node, err := g.GetNode(id)
+		if err != nil {
+			return nil
+		}
+		node.Mu.RLock()
+		label = node.Label
+		props = node.Properties.Map()
+		node.Mu.RUnlock()
+	case wal.OpDeleteNode, wal.OpTombstoneNode:
+		if matching[id] {
+			delete(matching, id)
+			fmt.Fprintf(out, "[-NODE] id=%d\n", id)
+		}
+		return nil
+	default:
+		return nil
+	}
+
+	match, err := spec.matches(label, props)
+	if err != nil {
+		return err
+	}
+	switch {
+	case match && !matching[id]:
+		matching[id] = true
+		fmt.Fprintln(out, formatWatchedNode(id, label, props))
+	case !match && matching[id]:
+		delete(matching, id)
+		fmt.Fprintf(out, "[-NODE] id=%d\n", id)
+	}
+	return nil
+}
+
+// formatWatchedNode renders an added node as
+// [+NODE] id=3 label=Person city="SF" name="David", with its properties
+// in key order
+func formatWatchedNode(id graph.NodeID, label string, props graph.Properties) string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[+NODE] id=%d label=%s", id, label)
+	for _, k := range keys {
+		if s, ok := props[k].(string); ok {
+			fmt.Fprintf(&b, " %s=%q", k, s)
+		} else {
+			fmt.Fprintf(&b, " %s=%s", k, formatValue(props[k]))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// syncBuffer is a bytes.Buffer safe to read while a watch writes to it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestParseWatch(t *testing.T) {
+	spec, err := parseWatch(`WATCH (n:Person) WHERE n.city = "SF"`)
+	require.NoError(t, err)
+	assert.Equal(t, "n", spec.variable)
+
+	match, err := spec.matches("Person", graph.Properties{"city": "SF"})
+	require.NoError(t, err)
+	assert.True(t, match)
+	match, err = spec.matches("Person", graph.Properties{"city": "NY"})
+	require.NoError(t, err)
+	assert.False(t, match)
+	match, err = spec.matches("City", graph.Properties{"city": "SF"})
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	for _, cmd := range []string{
+		"WATCH",
+		"WATCH (a)-[:KNOWS]->(b)",
+		"WATCH (:Person)",
+		"WATCH (n:Person",
+	} {
+		_, err := parseWatch(cmd)
+		assert.Error(t, err, cmd)
+	}
+}
+
+func TestRunWatch(t *testing.T) {
+	dir := t.TempDir()
+	g, err := storage.NewPersistentGraph(filepath.Join(dir, "wal"), filepath.Join(dir, "snapshots"))
+	require.NoError(t, err)
+	defer g.Close()
+
+	existing, err := g.AddNode("Person", graph.Properties{"name": "Alice", "city": "SF"})
+	require.NoError(t, err)
+
+	spec, err := parseWatch(`WATCH (n:Person) WHERE n.city = "SF"`)
+	require.NoError(t, err)
+	lines := make(chan string)
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() {
+		done <- runWatch(spec, g, lines, nil, out)
+	}()
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "Watching 1 matching nodes")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Writers add nodes concurrently; only those in SF are reported
+	var wg sync.WaitGroup
+	for _, city := range []string{"SF", "NY", "SF"} {
+		wg.Add(1)
+		go func(city string) {
+			defer wg.Done()
+			_, err := g.AddNode("Person", graph.Properties{"name": "David", "city": city})
+			assert.NoError(t, err)
+		}(city)
+	}
+	_, err = g.AddNode("City", graph.Properties{"city": "SF"})
+	require.NoError(t, err)
+	wg.Wait()
+	require.Eventually(t, func() bool {
+		return strings.Count(out.String(), "[+NODE]") == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Regexp(t, `\[\+NODE\] id=\d+ label=Person city="SF" name="David"`, out.String())
+
+	// Deleting a matching node or moving it out of SF reports it gone
+	require.NoError(t, g.DeleteNode(existing.ID))
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "[-NODE] id=1\n")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	lines <- "exit"
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop on exit")
+	}
+
+	// Changes after the watch ended are not printed
+	before := out.String()
+	_, err = g.AddNode("Person", graph.Properties{"name": "Eve", "city": "SF"})
+	require.NoError(t, err)
+	assert.Equal(t, before, out.String())
+}
+
+func TestRunWatch_Updates(t *testing.T) {
+	dir := t.TempDir()
+	g, err := storage.NewPersistentGraph(filepath.Join(dir, "wal"), filepath.Join(dir, "snapshots"))
+	require.NoError(t, err)
+	defer g.Close()
+
+	node, err := g.AddNode("Person", graph.Properties{"name": "Bob", "city": "NY"})
+	require.NoError(t, err)
+
+	spec, err := parseWatch(`WATCH (n:Person {name: "Bob"}) WHERE n.city = "SF"`)
+	require.NoError(t, err)
+	lines := make(chan string)
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() {
+		done <- runWatch(spec, g, lines, nil, out)
+	}()
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "Watching 0 matching nodes")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A node that comes to match is reported with all its properties, and
+	// one that stops matching as gone
+	require.NoError(t, g.UpdateNode(node.ID, graph.Properties{"city": "SF"}))
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), `[+NODE] id=1 label=Person city="SF" name="Bob"`)
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, g.UpdateNode(node.ID, graph.Properties{"city": "LA"}))
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "[-NODE] id=1\n")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Closing the input ends the watch
+	close(lines)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop when the input closed")
+	}
+}
@@ -1,20 +1,27 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/fnuworsu/rdgDB/pkg/server"
 	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
 )
 
 const (
 	defaultDataDir     = "./data"
 	defaultWALDir      = "./data/wal"
 	defaultSnapshotDir = "./data/snapshots"
+	defaultHTTPAddr    = "127.0.0.1:7474"
 )
 
 func main() {
@@ -34,9 +41,66 @@ func main() {
 	fmt.Printf("WAL directory: %s\n", walDir)
 	fmt.Printf("Snapshot directory: %s\n\n", snapshotDir)
 
+	// Start the HTTP API before recovering, so readiness probes can follow
+	// a long recovery on GET /ready. Requests are answered 503 Service
+	// Unavailable until the graph is ready.
+	httpAddr := os.Getenv("RDGDB_HTTP_ADDR")
+	if httpAddr == "" {
+		httpAddr = defaultHTTPAddr
+	}
+	startup := server.NewStartup()
+	httpServer := &http.Server{Addr: httpAddr, Handler: startup}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "HTTP server failed: %v\n", err)
+		}
+	}()
+	fmt.Printf("HTTP API listening on %s (GET /ready, POST /query, POST /admin/backup)\n", httpAddr)
+
 	// Initialize the persistent graph storage (recovers from disk if exists)
 	fmt.Println("Initializing graph storage...")
-	graph, err := storage.NewPersistentGraph(walDir, snapshotDir)
+	storageOpts := storage.DefaultOptions()
+	storageOpts.RecoveryProgress = startup.Progress
+	if v := os.Getenv("RDGDB_WAL_FORMAT"); v != "" {
+		format, err := wal.ParseFormat(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid RDGDB_WAL_FORMAT: %v\n", err)
+			os.Exit(1)
+		}
+		storageOpts.WALFormat = format
+	}
+	if v := os.Getenv("RDGDB_RECOVERY_PARALLELISM"); v != "" {
+		parallelism, err := strconv.Atoi(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid RDGDB_RECOVERY_PARALLELISM %q: %v\n", v, err)
+			os.Exit(1)
+		}
+		storageOpts.RecoveryParallelism = parallelism
+	}
+	// Clients keep the IDs of the nodes they create, so IDs are reserved
+	// in the WAL before they are handed out and never reissued after a
+	// crash. RDGDB_ID_BLOCK_SIZE=0 turns this off.
+	storageOpts.IDBlockSize = storage.DefaultIDBlockSize
+	if v := os.Getenv("RDGDB_ID_BLOCK_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 0 {
+			fmt.Fprintf(os.Stderr, "Invalid RDGDB_ID_BLOCK_SIZE %q\n", v)
+			os.Exit(1)
+		}
+		storageOpts.IDBlockSize = size
+	}
+	// Expired nodes and edges are deleted once a minute by default.
+	// RDGDB_SWEEP_INTERVAL=0 turns the sweeper off.
+	storageOpts.SweepInterval = storage.DefaultSweepInterval
+	if v := os.Getenv("RDGDB_SWEEP_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval < 0 {
+			fmt.Fprintf(os.Stderr, "Invalid RDGDB_SWEEP_INTERVAL %q\n", v)
+			os.Exit(1)
+		}
+		storageOpts.SweepInterval = interval
+	}
+	graph, err := storage.NewPersistentGraphWithOptions(walDir, snapshotDir, storageOpts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize graph: %v\n", err)
 		os.Exit(1)
@@ -51,25 +115,62 @@ func main() {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
 
-	// Start periodic snapshotting in background
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute) // Snapshot every 5 minutes
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				fmt.Println("Creating periodic snapshot...")
-				if err := graph.Snapshot(); err != nil {
-					fmt.Fprintf(os.Stderr, "Snapshot failed: %v\n", err)
-				} else {
-					fmt.Println("✓ Snapshot complete")
-				}
-			case <-sigCh:
-				return
-			}
+	// Snapshot in the background on a timer, and sooner once the WAL
+	// grows past its limits
+	policy := storage.DefaultSnapshotPolicy()
+	if v := os.Getenv("RDGDB_SNAPSHOT_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid RDGDB_SNAPSHOT_INTERVAL %q: %v\n", v, err)
+			os.Exit(1)
 		}
-	}()
+		policy.Interval = interval
+	}
+	if v := os.Getenv("RDGDB_SNAPSHOT_MAX_WAL_ENTRIES"); v != "" {
+		entries, err := strconv.Atoi(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid RDGDB_SNAPSHOT_MAX_WAL_ENTRIES %q: %v\n", v, err)
+			os.Exit(1)
+		}
+		policy.MaxWALEntries = entries
+	}
+	if v := os.Getenv("RDGDB_SNAPSHOT_MAX_WAL_BYTES"); v != "" {
+		bytes, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid RDGDB_SNAPSHOT_MAX_WAL_BYTES %q: %v\n", v, err)
+			os.Exit(1)
+		}
+		policy.MaxWALBytes = bytes
+	}
+	stopSnapshots := graph.StartSnapshotter(policy, func(trigger storage.SnapshotTrigger, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Snapshot (%s) failed: %v\n", trigger, err)
+		} else {
+			fmt.Printf("✓ Snapshot complete (%s)\n", trigger)
+		}
+	})
+
+	// Hand the HTTP API over to the recovered graph
+	opts := server.DefaultOptions()
+	if v := os.Getenv("RDGDB_STABLE_ORDER"); v != "" {
+		stable, err := strconv.ParseBool(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid RDGDB_STABLE_ORDER %q: %v\n", v, err)
+			os.Exit(1)
+		}
+		opts.StableOrder = stable
+	}
+	if v := os.Getenv("RDGDB_WAL_SCAN_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid RDGDB_WAL_SCAN_INTERVAL %q: %v\n", v, err)
+			os.Exit(1)
+		}
+		opts.WALScanInterval = interval
+	}
+	srv := server.NewWithOptions(graph, opts)
+	defer srv.Close()
+	startup.Ready(srv)
 
 	// TODO: Add server initialization
 	// - gRPC server setup
@@ -85,7 +186,15 @@ func main() {
 	// Wait for shutdown signal
 	<-sigCh
 
-	fmt.Println("\nShutdown signal received, creating final snapshot...")
+	fmt.Println("\nShutdown signal received, stopping HTTP API...")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "HTTP shutdown failed: %v\n", err)
+	}
+
+	stopSnapshots()
+	fmt.Println("Creating final snapshot...")
 	if err := graph.Snapshot(); err != nil {
 		fmt.Fprintf(os.Stderr, "Final snapshot failed: %v\n", err)
 	} else {
@@ -0,0 +1,136 @@
+// Command snapshotdiff compares two snapshot files and prints the nodes and
+// edges that were added, deleted or modified between them
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("snapshotdiff", flag.ContinueOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: snapshotdiff [--format text|json] <old-snapshot> <new-snapshot>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected two snapshot files, got %d", fs.NArg())
+	}
+
+	before, err := wal.LoadSnapshot(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := wal.LoadSnapshot(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	diff, err := wal.SnapshotDiff(before, after)
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diff)
+	}
+	printText(diff, out)
+	return nil
+}
+
+// printText writes a summary line for nodes and edges followed by one line
+// per changed ID, and one indented line per changed property
+func printText(diff wal.SnapshotDiffResult, out io.Writer) {
+	fmt.Fprintf(out, "Nodes: +%d -%d ~%d\n", len(diff.NodesAdded), len(diff.NodesDeleted), len(diff.NodesModified))
+	fmt.Fprintf(out, "Edges: +%d -%d ~%d\n", len(diff.EdgesAdded), len(diff.EdgesDeleted), len(diff.EdgesModified))
+	if diff.Empty() {
+		fmt.Fprintln(out, "Snapshots are identical")
+		return
+	}
+
+	for _, id := range diff.NodesAdded {
+		fmt.Fprintf(out, "+ node %d\n", id)
+	}
+	for _, id := range diff.NodesDeleted {
+		fmt.Fprintf(out, "- node %d\n", id)
+	}
+	for _, d := range diff.NodesModified {
+		fmt.Fprintf(out, "~ node %d\n", d.ID)
+		printProperties(d.OldProps, d.NewProps, out)
+	}
+	for _, id := range diff.EdgesAdded {
+		fmt.Fprintf(out, "+ edge %d\n", id)
+	}
+	for _, id := range diff.EdgesDeleted {
+		fmt.Fprintf(out, "- edge %d\n", id)
+	}
+	for _, d := range diff.EdgesModified {
+		fmt.Fprintf(out, "~ edge %d\n", d.ID)
+		printProperties(d.OldProps, d.NewProps, out)
+	}
+}
+
+// printProperties writes "key: old -> new" for every property whose value
+// differs, ordered by key
+func printProperties(oldProps, newProps graph.Properties, out io.Writer) {
+	keys := make(map[string]struct{}, len(oldProps)+len(newProps))
+	for k := range oldProps {
+		keys[k] = struct{}{}
+	}
+	for k := range newProps {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		oldVal, oldOK := oldProps[k]
+		newVal, newOK := newProps[k]
+		oldStr, newStr := formatValue(oldVal, oldOK), formatValue(newVal, newOK)
+		if oldOK == newOK && oldStr == newStr && graph.TypeOf(oldVal) == graph.TypeOf(newVal) {
+			continue
+		}
+		if graph.TypeOf(oldVal) != graph.TypeOf(newVal) && oldOK && newOK {
+			oldStr += " (" + graph.TypeOf(oldVal) + ")"
+			newStr += " (" + graph.TypeOf(newVal) + ")"
+		}
+		fmt.Fprintf(out, "    %s: %s -> %s\n", k, oldStr, newStr)
+	}
+}
+
+func formatValue(v graph.PropertyValue, ok bool) string {
+	if !ok {
+		return "(none)"
+	}
+	if s, isString := v.(string); isString {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", v)
+}
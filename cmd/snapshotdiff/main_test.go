@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSnapshot saves nodes and edges as a snapshot and returns its path
+func writeSnapshot(t *testing.T, nodes []*graph.Node, edges []*graph.Edge) string {
+	sm, err := wal.NewSnapshotManager(filepath.Join(t.TempDir(), "snapshots"))
+	require.NoError(t, err)
+
+	nodeMap := make(map[graph.NodeID]*graph.Node)
+	for _, n := range nodes {
+		nodeMap[n.ID] = n
+	}
+	edgeMap := make(map[graph.EdgeID]*graph.Edge)
+	for _, e := range edges {
+		edgeMap[e.ID] = e
+	}
+	require.NoError(t, sm.CreateSnapshot(1, nodeMap, edgeMap))
+	return sm.LatestPath()
+}
+
+func writeSnapshots(t *testing.T) (string, string) {
+	before := writeSnapshot(t,
+		[]*graph.Node{
+			{ID: 1, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Alice", "age": 30})},
+			{ID: 2, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Bob"})},
+		},
+		[]*graph.Edge{{ID: 1, Source: 1, Target: 2, Label: "KNOWS", Properties: graph.NewSmallProperties(graph.Properties{"since": 2020})}},
+	)
+	after := writeSnapshot(t,
+		[]*graph.Node{
+			{ID: 1, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Alicia", "age": 30, "city": "Paris"})},
+			{ID: 3, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Carol"})},
+		},
+		[]*graph.Edge{{ID: 1, Source: 1, Target: 2, Label: "KNOWS", Properties: graph.NewSmallProperties(graph.Properties{"since": 2021})}},
+	)
+	return before, after
+}
+
+func TestDiffText(t *testing.T) {
+	before, after := writeSnapshots(t)
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{before, after}, &out))
+	assert.Equal(t, `Nodes: +1 -1 ~1
+Edges: +0 -0 ~1
++ node 3
+- node 2
+~ node 1
+    city: (none) -> "Paris"
+    name: "Alice" -> "Alicia"
+~ edge 1
+    since: 2020 -> 2021
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, run([]string{before, before}, &out))
+	assert.Contains(t, out.String(), "Snapshots are identical")
+}
+
+func TestDiffJSON(t *testing.T) {
+	before, after := writeSnapshots(t)
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"--format", "json", before, after}, &out))
+
+	var diff wal.SnapshotDiffResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &diff))
+	assert.Equal(t, []graph.NodeID{3}, diff.NodesAdded)
+	assert.Equal(t, []graph.NodeID{2}, diff.NodesDeleted)
+	require.Len(t, diff.NodesModified, 1)
+	assert.Equal(t, "Alicia", diff.NodesModified[0].NewProps["name"])
+	require.Len(t, diff.EdgesModified, 1)
+	assert.Equal(t, graph.EdgeID(1), diff.EdgesModified[0].ID)
+}
+
+func TestDiffErrors(t *testing.T) {
+	before, _ := writeSnapshots(t)
+
+	var out bytes.Buffer
+	assert.Error(t, run([]string{before}, &out))
+	assert.Error(t, run([]string{"--format", "yaml", before, before}, &out))
+	assert.Error(t, run([]string{before, filepath.Join(t.TempDir(), "missing.json")}, &out))
+}
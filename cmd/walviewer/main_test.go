@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeWAL creates a WAL with 7 node additions and 3 edge additions
+func writeWAL(t *testing.T) string {
+	dir := t.TempDir()
+	w, err := wal.NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+
+	for i := 1; i <= 7; i++ {
+		require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", graph.Properties{"n": i}, time.Time{}))
+	}
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, w.LogAddEdge(graph.EdgeID(i), graph.NodeID(i), graph.NodeID(i+1), "KNOWS", nil, time.Time{}))
+	}
+	return dir
+}
+
+func lines(s string) []string {
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func TestViewerJSON(t *testing.T) {
+	dir := writeWAL(t)
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"--wal-dir", dir, "--format", "json"}, &out))
+	printed := lines(out.String())
+	require.Len(t, printed, 10)
+
+	var entry wal.LogEntry
+	require.NoError(t, json.Unmarshal([]byte(printed[9]), &entry))
+	assert.Equal(t, uint64(10), entry.Index)
+	assert.Equal(t, wal.OpAddEdge, entry.OpType)
+}
+
+func TestViewerTable(t *testing.T) {
+	dir := writeWAL(t)
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"--wal-dir", dir}, &out))
+	printed := lines(out.String())
+	require.Len(t, printed, 11)
+	assert.True(t, strings.HasPrefix(printed[0], "INDEX  TIMESTAMP"))
+	assert.Contains(t, printed[1], "ADD_NODE")
+
+	out.Reset()
+	require.NoError(t, run([]string{"--wal-dir", dir, "--from", "3", "--to", "5"}, &out))
+	printed = lines(out.String())
+	require.Len(t, printed, 4)
+	assert.True(t, strings.HasPrefix(printed[1], "3 "))
+	assert.True(t, strings.HasPrefix(printed[3], "5 "))
+
+	assert.Error(t, run([]string{"--wal-dir", dir, "--format", "xml"}, &out))
+}
+
+func TestViewerStats(t *testing.T) {
+	dir := writeWAL(t)
+
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"--wal-dir", dir, "--stats"}, &out))
+	assert.Equal(t, []string{
+		"ADD_NODE  7  " + strings.Repeat("#", 40),
+		"ADD_EDGE  3  " + strings.Repeat("#", 17),
+		"TOTAL     10",
+	}, lines(out.String()))
+}
@@ -0,0 +1,412 @@
+package graph
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Nodes and edges implement gob.GobEncoder so that their mutex is skipped.
+// A gob encoder per entity would resend type information every time, so
+// the fields are instead packed into a compact byte layout: varints for
+// IDs and counts, length-prefixed strings and times, and properties as
+// tagged values. Values decode as TypedValue.Decode does, with integers
+// as int and floats as float64.
+
+// Value tags of the binary property encoding
+const (
+	tagNull byte = iota
+	tagString
+	tagInt
+	tagFloat
+	tagBool
+	tagPoint
+	tagDatetime
+	tagList
+	tagMap
+	tagHistogram
+)
+
+var errShortBuffer = errors.New("truncated binary data")
+
+// GobEncode implements gob.GobEncoder. The mutex is skipped.
+func (n *Node) GobEncode() ([]byte, error) {
+	n.Mu.RLock()
+	defer n.Mu.RUnlock()
+
+	buf := binary.AppendUvarint(nil, uint64(n.ID))
+	buf = appendString(buf, n.Label)
+	buf, err := appendSmallProperties(buf, &n.Properties)
+	if err != nil {
+		return nil, err
+	}
+	buf = appendEdgeIDs(buf, n.OutEdges)
+	buf = appendEdgeIDs(buf, n.InEdges)
+	return appendTimes(buf, n.CreatedAt, n.UpdatedAt, n.ExpiresAt, n.DeletedAt)
+}
+
+// GobDecode implements gob.GobDecoder. Empty properties and adjacency
+// lists decode as empty rather than nil, as NewNode creates them.
+func (n *Node) GobDecode(data []byte) error {
+	r := &byteReader{buf: data}
+	id := NodeID(r.uvarint())
+	label := r.string()
+	props := r.properties(0)
+	out := r.edgeIDs()
+	in := r.edgeIDs()
+	times := r.times(4)
+	if r.err != nil {
+		return fmt.Errorf("failed to decode node: %w", r.err)
+	}
+
+	n.Mu.Lock()
+	defer n.Mu.Unlock()
+	n.ID = id
+	n.Label = label
+	n.Properties = NewSmallProperties(props)
+	n.OutEdges = out
+	n.InEdges = in
+	n.CreatedAt, n.UpdatedAt, n.ExpiresAt, n.DeletedAt = times[0], times[1], times[2], times[3]
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder. The mutex is skipped.
+func (e *Edge) GobEncode() ([]byte, error) {
+	e.Mu.RLock()
+	defer e.Mu.RUnlock()
+
+	buf := binary.AppendUvarint(nil, uint64(e.ID))
+	buf = binary.AppendUvarint(buf, uint64(e.Source))
+	buf = binary.AppendUvarint(buf, uint64(e.Target))
+	buf = appendString(buf, e.Label)
+	buf, err := appendSmallProperties(buf, &e.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return appendTimes(buf, e.CreatedAt, e.UpdatedAt, e.ExpiresAt, e.DeletedAt)
+}
+
+// GobDecode implements gob.GobDecoder. Empty properties decode as an
+// empty map rather than nil.
+func (e *Edge) GobDecode(data []byte) error {
+	r := &byteReader{buf: data}
+	id := EdgeID(r.uvarint())
+	source := NodeID(r.uvarint())
+	target := NodeID(r.uvarint())
+	label := r.string()
+	props := r.properties(0)
+	times := r.times(4)
+	if r.err != nil {
+		return fmt.Errorf("failed to decode edge: %w", r.err)
+	}
+
+	e.Mu.Lock()
+	defer e.Mu.Unlock()
+	e.ID = id
+	e.Source = source
+	e.Target = target
+	e.Label = label
+	e.Properties = NewSmallProperties(props)
+	e.CreatedAt, e.UpdatedAt, e.ExpiresAt, e.DeletedAt = times[0], times[1], times[2], times[3]
+	return nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+func appendEdgeIDs(buf []byte, ids []EdgeID) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(ids)))
+	for _, id := range ids {
+		buf = binary.AppendUvarint(buf, uint64(id))
+	}
+	return buf
+}
+
+func appendTimes(buf []byte, times ...time.Time) ([]byte, error) {
+	for _, t := range times {
+		b, err := t.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(b)))
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+func appendProperties(buf []byte, props Properties) ([]byte, error) {
+	buf = binary.AppendUvarint(buf, uint64(len(props)))
+	for k, v := range props {
+		buf = appendString(buf, k)
+		var err error
+		if buf, err = appendValue(buf, v); err != nil {
+			return nil, fmt.Errorf("property %s: %w", k, err)
+		}
+	}
+	return buf, nil
+}
+
+func appendSmallProperties(buf []byte, props *SmallProperties) ([]byte, error) {
+	buf = binary.AppendUvarint(buf, uint64(props.Len()))
+	var err error
+	props.Range(func(k string, v PropertyValue) bool {
+		buf = appendString(buf, k)
+		if buf, err = appendValue(buf, v); err != nil {
+			err = fmt.Errorf("property %s: %w", k, err)
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// AppendBinaryValue appends v to buf in the tagged layout node and edge
+// properties are encoded with, for other binary formats to embed
+func AppendBinaryValue(buf []byte, v PropertyValue) ([]byte, error) {
+	return appendValue(buf, v)
+}
+
+// DecodeBinaryValue decodes a value written by AppendBinaryValue from the
+// start of buf and returns it with the rest of buf
+func DecodeBinaryValue(buf []byte) (PropertyValue, []byte, error) {
+	r := &byteReader{buf: buf}
+	v := r.value(0)
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	return v, r.buf, nil
+}
+
+func appendValue(buf []byte, v PropertyValue) ([]byte, error) {
+	switch val := v.(type) {
+	case nil:
+		return append(buf, tagNull), nil
+	case string:
+		return appendString(append(buf, tagString), val), nil
+	case int:
+		return binary.AppendVarint(append(buf, tagInt), int64(val)), nil
+	case int8:
+		return binary.AppendVarint(append(buf, tagInt), int64(val)), nil
+	case int16:
+		return binary.AppendVarint(append(buf, tagInt), int64(val)), nil
+	case int32:
+		return binary.AppendVarint(append(buf, tagInt), int64(val)), nil
+	case int64:
+		return binary.AppendVarint(append(buf, tagInt), val), nil
+	case uint:
+		return binary.AppendVarint(append(buf, tagInt), int64(val)), nil
+	case uint8:
+		return binary.AppendVarint(append(buf, tagInt), int64(val)), nil
+	case uint16:
+		return binary.AppendVarint(append(buf, tagInt), int64(val)), nil
+	case uint32:
+		return binary.AppendVarint(append(buf, tagInt), int64(val)), nil
+	case uint64:
+		return binary.AppendVarint(append(buf, tagInt), int64(val)), nil
+	case float32:
+		return binary.LittleEndian.AppendUint64(append(buf, tagFloat), math.Float64bits(float64(val))), nil
+	case float64:
+		return binary.LittleEndian.AppendUint64(append(buf, tagFloat), math.Float64bits(val)), nil
+	case bool:
+		if val {
+			return append(buf, tagBool, 1), nil
+		}
+		return append(buf, tagBool, 0), nil
+	case Point:
+		buf = binary.LittleEndian.AppendUint64(append(buf, tagPoint), math.Float64bits(val.Lat))
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(val.Lon)), nil
+	case time.Time:
+		return appendTimes(append(buf, tagDatetime), val)
+	case []PropertyValue:
+		buf = binary.AppendUvarint(append(buf, tagList), uint64(len(val)))
+		for i, elem := range val {
+			var err error
+			if buf, err = appendValue(buf, elem); err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		return buf, nil
+	case Properties:
+		return appendProperties(append(buf, tagMap), val)
+	case Histogram:
+		buf = binary.AppendUvarint(append(buf, tagHistogram), uint64(len(val.Counts)))
+		for _, boundary := range val.Buckets {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(boundary))
+		}
+		for _, c := range val.Counts {
+			buf = binary.AppendVarint(buf, c)
+		}
+		return buf, nil
+	}
+	return nil, fmt.Errorf("unsupported property type %T", v)
+}
+
+// byteReader decodes the layout written by the append functions. The
+// first error sticks; later reads return zero values.
+type byteReader struct {
+	buf []byte
+	err error
+}
+
+func (r *byteReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *byteReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail(errShortBuffer)
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *byteReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail(errShortBuffer)
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *byteReader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.buf)) < n {
+		r.fail(errShortBuffer)
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *byteReader) byte() byte {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *byteReader) float() float64 {
+	b := r.bytes(8)
+	if b == nil {
+		return 0
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func (r *byteReader) string() string {
+	return string(r.bytes(r.uvarint()))
+}
+
+// count reads a length and rejects one larger than the remaining input,
+// so corrupt data cannot trigger a huge allocation
+func (r *byteReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		r.fail(errShortBuffer)
+		return 0
+	}
+	return int(n)
+}
+
+func (r *byteReader) edgeIDs() []EdgeID {
+	ids := make([]EdgeID, r.count())
+	for i := range ids {
+		ids[i] = EdgeID(r.uvarint())
+	}
+	return ids
+}
+
+func (r *byteReader) times(n int) []time.Time {
+	times := make([]time.Time, n)
+	for i := range times {
+		b := r.bytes(r.uvarint())
+		if r.err != nil {
+			break
+		}
+		if err := times[i].UnmarshalBinary(b); err != nil {
+			r.fail(err)
+		}
+	}
+	return times
+}
+
+func (r *byteReader) properties(depth int) Properties {
+	n := r.count()
+	props := make(Properties, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		k := r.string()
+		props[k] = r.value(depth)
+	}
+	return props
+}
+
+func (r *byteReader) value(depth int) PropertyValue {
+	tag := r.byte()
+	if r.err != nil {
+		return nil
+	}
+	switch tag {
+	case tagNull:
+		return nil
+	case tagString:
+		return r.string()
+	case tagInt:
+		return int(r.varint())
+	case tagFloat:
+		return r.float()
+	case tagBool:
+		return r.byte() != 0
+	case tagPoint:
+		return Point{Lat: r.float(), Lon: r.float()}
+	case tagDatetime:
+		return r.times(1)[0]
+	case tagHistogram:
+		n := r.count()
+		h := Histogram{Buckets: make([]float64, n+1), Counts: make([]int64, n)}
+		for i := range h.Buckets {
+			h.Buckets[i] = r.float()
+		}
+		for i := range h.Counts {
+			h.Counts[i] = r.varint()
+		}
+		return h
+	case tagList, tagMap:
+		if depth >= MaxValueDepth {
+			r.fail(fmt.Errorf("value nested deeper than %d levels", MaxValueDepth))
+			return nil
+		}
+		if tag == tagMap {
+			return r.properties(depth + 1)
+		}
+		list := make([]PropertyValue, r.count())
+		for i := range list {
+			list[i] = r.value(depth + 1)
+		}
+		return list
+	}
+	r.fail(fmt.Errorf("unknown value tag %d", tag))
+	return nil
+}
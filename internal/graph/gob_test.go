@@ -0,0 +1,81 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeEdgeGobRoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	node := NewNode(7, "City")
+	node.Properties = NewSmallProperties(Properties{
+		"name":     "Oslo",
+		"pop":      709037,
+		"area":     454.0,
+		"capital":  true,
+		"location": Point{Lat: 59.91, Lon: 10.75},
+		"founded":  created,
+		"tags":     []PropertyValue{"fjord", 1},
+		"mayor":    Properties{"name": "Anne", "since": 2023},
+		"motto":    nil,
+		"visitors": Histogram{Buckets: []float64{0, 10, 20}, Counts: []int64{3, 1}},
+	})
+	node.OutEdges = []EdgeID{1, 2}
+	node.CreatedAt = created
+	node.ExpiresAt = created.Add(time.Hour)
+
+	edge := NewEdge(1, 7, 8, "ROAD")
+	edge.Properties.SetProperty("km", 42)
+	edge.DeletedAt = created
+
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode([]*Node{node, NewNode(8, "City")}))
+	require.NoError(t, gob.NewEncoder(&buf).Encode(edge))
+
+	var nodes []*Node
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&nodes))
+	require.Len(t, nodes, 2)
+	assert.Equal(t, node.ID, nodes[0].ID)
+	assert.Equal(t, node.Label, nodes[0].Label)
+	assert.Equal(t, node.Properties, nodes[0].Properties)
+	assert.Equal(t, node.OutEdges, nodes[0].OutEdges)
+	assert.Empty(t, nodes[0].InEdges)
+	assert.NotNil(t, nodes[0].InEdges)
+	assert.True(t, node.CreatedAt.Equal(nodes[0].CreatedAt))
+	assert.True(t, node.ExpiresAt.Equal(nodes[0].ExpiresAt))
+	assert.True(t, nodes[0].DeletedAt.IsZero())
+
+	// Empty properties decode as empty
+	assert.Equal(t, 0, nodes[1].Properties.Len())
+
+	var decoded Edge
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	assert.Equal(t, EdgeID(1), decoded.ID)
+	assert.Equal(t, NodeID(7), decoded.Source)
+	assert.Equal(t, NodeID(8), decoded.Target)
+	assert.Equal(t, "ROAD", decoded.Label)
+	assert.Equal(t, Properties{"km": 42}, decoded.Properties.Map())
+	assert.True(t, edge.DeletedAt.Equal(decoded.DeletedAt))
+}
+
+func TestNodeGobDecode_Truncated(t *testing.T) {
+	node := NewNode(1, "Person")
+	node.Properties.SetProperty("name", "Alice")
+	data, err := node.GobEncode()
+	require.NoError(t, err)
+
+	var decoded Node
+	require.NoError(t, decoded.GobDecode(data))
+	for i := 0; i < len(data); i++ {
+		assert.Error(t, decoded.GobDecode(data[:i]), "prefix of %d bytes", i)
+	}
+
+	_, err = (&Node{Properties: NewSmallProperties(Properties{"bad": struct{}{}})}).GobEncode()
+	assert.Error(t, err)
+}
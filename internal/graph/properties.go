@@ -0,0 +1,206 @@
+package graph
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// smallCapacity is the number of properties SmallProperties stores inline
+const smallCapacity = 8
+
+// SmallProperties holds the properties of a node or edge. Most entities
+// have only a few properties, so up to eight are kept inline in parallel
+// key and value arrays, sorted by key; a Go map costs a separate
+// allocation even for one entry. Beyond eight the properties move to a
+// map, and move back inline once deletes bring them down to eight again,
+// so equal property sets always have the same representation.
+//
+// The zero value is empty and ready to use. SmallProperties is not safe
+// for concurrent use; nodes and edges guard theirs with their mutex.
+type SmallProperties struct {
+	keys   [smallCapacity]string
+	values [smallCapacity]PropertyValue
+	n      int        // Number of inline properties
+	large  Properties // All properties once there are more than eight
+}
+
+// NewSmallProperties returns the properties of props
+func NewSmallProperties(props Properties) SmallProperties {
+	var p SmallProperties
+	p.SetAll(props)
+	return p
+}
+
+// Len returns the number of properties
+func (p *SmallProperties) Len() int {
+	if p.large != nil {
+		return len(p.large)
+	}
+	return p.n
+}
+
+// GetProperty returns the value of key and whether it is set
+func (p *SmallProperties) GetProperty(key string) (PropertyValue, bool) {
+	if p.large != nil {
+		v, ok := p.large[key]
+		return v, ok
+	}
+	if i, ok := p.find(key); ok {
+		return p.values[i], true
+	}
+	return nil, false
+}
+
+// SetProperty sets key to value, replacing any previous value
+func (p *SmallProperties) SetProperty(key string, value PropertyValue) {
+	if p.large != nil {
+		p.large[key] = value
+		return
+	}
+	i, ok := p.find(key)
+	if ok {
+		p.values[i] = value
+		return
+	}
+	if p.n == smallCapacity {
+		p.large = make(Properties, smallCapacity+1)
+		for j := 0; j < p.n; j++ {
+			p.large[p.keys[j]] = p.values[j]
+		}
+		p.large[key] = value
+		p.clearInline()
+		return
+	}
+	copy(p.keys[i+1:p.n+1], p.keys[i:p.n])
+	copy(p.values[i+1:p.n+1], p.values[i:p.n])
+	p.keys[i], p.values[i] = key, value
+	p.n++
+}
+
+// DeleteProperty removes key and reports whether it was set
+func (p *SmallProperties) DeleteProperty(key string) bool {
+	if p.large != nil {
+		if _, ok := p.large[key]; !ok {
+			return false
+		}
+		delete(p.large, key)
+		if len(p.large) <= smallCapacity {
+			large := p.large
+			p.large = nil
+			for k, v := range large {
+				p.SetProperty(k, v)
+			}
+		}
+		return true
+	}
+	i, ok := p.find(key)
+	if !ok {
+		return false
+	}
+	copy(p.keys[i:p.n-1], p.keys[i+1:p.n])
+	copy(p.values[i:p.n-1], p.values[i+1:p.n])
+	p.n--
+	p.keys[p.n], p.values[p.n] = "", nil
+	return true
+}
+
+// SetAll sets every property of props
+func (p *SmallProperties) SetAll(props Properties) {
+	for k, v := range props {
+		p.SetProperty(k, v)
+	}
+}
+
+// Clear removes all properties
+func (p *SmallProperties) Clear() {
+	p.clearInline()
+	p.large = nil
+}
+
+// Keys returns the property names in sorted order
+func (p *SmallProperties) Keys() []string {
+	if p.large != nil {
+		keys := make([]string, 0, len(p.large))
+		for k := range p.large {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	keys := make([]string, p.n)
+	copy(keys, p.keys[:p.n])
+	return keys
+}
+
+// Range calls fn for each property until fn returns false. Like ranging
+// over a map, the order is unspecified, and fn must not modify p.
+func (p *SmallProperties) Range(fn func(key string, value PropertyValue) bool) {
+	if p.large != nil {
+		for k, v := range p.large {
+			if !fn(k, v) {
+				return
+			}
+		}
+		return
+	}
+	for i := 0; i < p.n; i++ {
+		if !fn(p.keys[i], p.values[i]) {
+			return
+		}
+	}
+}
+
+// Map returns the properties as a new map, which is never nil
+func (p *SmallProperties) Map() Properties {
+	props := make(Properties, p.Len())
+	p.Range(func(k string, v PropertyValue) bool {
+		props[k] = v
+		return true
+	})
+	return props
+}
+
+// Clone returns a copy of p that shares no map with it. Values themselves
+// are not copied.
+func (p *SmallProperties) Clone() SmallProperties {
+	c := *p
+	if p.large != nil {
+		c.large = p.Map()
+	}
+	return c
+}
+
+// MarshalJSON writes the properties as a JSON object in the typed form of
+// Properties.MarshalJSON
+func (p SmallProperties) MarshalJSON() ([]byte, error) {
+	return p.Map().MarshalJSON()
+}
+
+// UnmarshalJSON reads properties written by MarshalJSON, replacing any
+// already set
+func (p *SmallProperties) UnmarshalJSON(data []byte) error {
+	var props Properties
+	if err := json.Unmarshal(data, &props); err != nil {
+		return err
+	}
+	p.Clear()
+	p.SetAll(props)
+	return nil
+}
+
+// find returns the index of key among the inline keys, or the index it
+// would be inserted at
+func (p *SmallProperties) find(key string) (int, bool) {
+	i := 0
+	for i < p.n && p.keys[i] < key {
+		i++
+	}
+	return i, i < p.n && p.keys[i] == key
+}
+
+func (p *SmallProperties) clearInline() {
+	for i := 0; i < p.n; i++ {
+		p.keys[i], p.values[i] = "", nil
+	}
+	p.n = 0
+}
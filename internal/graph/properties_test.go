@@ -0,0 +1,166 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmallProperties(t *testing.T) {
+	var p SmallProperties
+	assert.Equal(t, 0, p.Len())
+	_, ok := p.GetProperty("name")
+	assert.False(t, ok)
+
+	p.SetProperty("name", "Alice")
+	p.SetProperty("age", 30)
+	p.SetProperty("name", "Alicia")
+	assert.Equal(t, 2, p.Len())
+	v, ok := p.GetProperty("name")
+	assert.True(t, ok)
+	assert.Equal(t, "Alicia", v)
+	assert.Equal(t, []string{"age", "name"}, p.Keys())
+
+	// A nil value is still a set property
+	p.SetProperty("nick", nil)
+	_, ok = p.GetProperty("nick")
+	assert.True(t, ok)
+
+	assert.True(t, p.DeleteProperty("age"))
+	assert.False(t, p.DeleteProperty("age"))
+	assert.Equal(t, Properties{"name": "Alicia", "nick": nil}, p.Map())
+
+	p.Clear()
+	assert.Equal(t, SmallProperties{}, p)
+}
+
+func TestSmallProperties_Overflow(t *testing.T) {
+	var p SmallProperties
+	want := Properties{}
+	for i := 0; i < 12; i++ {
+		key := fmt.Sprintf("k%02d", i)
+		p.SetProperty(key, i)
+		want[key] = i
+		assert.Equal(t, len(want), p.Len())
+	}
+	assert.Equal(t, want, p.Map())
+	assert.Len(t, p.Keys(), 12)
+	assert.Equal(t, "k00", p.Keys()[0])
+	v, _ := p.GetProperty("k10")
+	assert.Equal(t, 10, v)
+
+	// Deleting back down to eight returns to the inline representation,
+	// so it equals the same properties set directly
+	for i := 8; i < 12; i++ {
+		require.True(t, p.DeleteProperty(fmt.Sprintf("k%02d", i)))
+		delete(want, fmt.Sprintf("k%02d", i))
+	}
+	assert.Equal(t, NewSmallProperties(want), p)
+
+	// Clones do not share the overflow map
+	p.SetProperty("k08", 8)
+	c := p.Clone()
+	c.SetProperty("extra", true)
+	assert.Equal(t, 9, p.Len())
+	assert.Equal(t, 10, c.Len())
+}
+
+func TestSmallProperties_Range(t *testing.T) {
+	p := NewSmallProperties(Properties{"a": 1, "b": 2, "c": 3})
+	seen := Properties{}
+	p.Range(func(k string, v PropertyValue) bool {
+		seen[k] = v
+		return true
+	})
+	assert.Equal(t, p.Map(), seen)
+
+	count := 0
+	p.Range(func(string, PropertyValue) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+}
+
+func TestSmallProperties_JSON(t *testing.T) {
+	props := Properties{
+		"name":     "Oslo",
+		"pop":      709037,
+		"location": Point{Lat: 59.91, Lon: 10.75},
+		"motto":    nil,
+	}
+	node := NewNode(1, "City")
+	node.Properties = NewSmallProperties(props)
+
+	data, err := json.Marshal(node)
+	require.NoError(t, err)
+	var decoded Node
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, props, decoded.Properties.Map())
+
+	// Empty properties are written as an empty object
+	data, err = json.Marshal(NewEdge(1, 1, 2, "ROAD"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"properties":{}`)
+}
+
+// The benchmarks build and read the properties of one entity, the
+// per-node cost of loading a graph. The sinks keep the properties on the
+// heap as a node would.
+var (
+	smallSink *SmallProperties
+	mapSink   Properties
+)
+
+func propertyKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("property%d", i)
+	}
+	return keys
+}
+
+func BenchmarkSmallProperties(b *testing.B) {
+	for _, n := range []int{1, 4, 8} {
+		keys := propertyKeys(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p := new(SmallProperties)
+				for j, k := range keys {
+					p.SetProperty(k, j)
+				}
+				for _, k := range keys {
+					if _, ok := p.GetProperty(k); !ok {
+						b.Fatal("missing property", k)
+					}
+				}
+				smallSink = p
+			}
+		})
+	}
+}
+
+func BenchmarkMapProperties(b *testing.B) {
+	for _, n := range []int{1, 4, 8} {
+		keys := propertyKeys(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p := make(Properties)
+				for j, k := range keys {
+					p[k] = j
+				}
+				for _, k := range keys {
+					if _, ok := p[k]; !ok {
+						b.Fatal("missing property", k)
+					}
+				}
+				mapSink = p
+			}
+		})
+	}
+}
@@ -2,6 +2,10 @@
 package graph
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,11 +22,208 @@ type PropertyValue interface{}
 // Properties is a map of property names to values
 type Properties map[string]PropertyValue
 
+// EarthRadiusKm is the mean Earth radius used for distance calculations
+const EarthRadiusKm = 6371.0
+
+// Point is a geographic coordinate stored as a property value
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// DistanceKm returns the great-circle distance to q in kilometers
+// using the Haversine formula
+func (p Point) DistanceKm(q Point) float64 {
+	lat1 := p.Lat * math.Pi / 180
+	lat2 := q.Lat * math.Pi / 180
+	dLat := (q.Lat - p.Lat) * math.Pi / 180
+	dLon := (q.Lon - p.Lon) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// DistanceMeters returns the great-circle distance to q in meters
+func (p Point) DistanceMeters(q Point) float64 {
+	return p.DistanceKm(q) * 1000
+}
+
+// geohashAlphabet is the base32 alphabet used by geohashes
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes p as a geohash of precision characters. Each character
+// adds five bits, alternating longitude and latitude halvings starting
+// with longitude, so points sharing a prefix lie in the same cell.
+func Geohash(p Point, precision int) string {
+	latLo, latHi := -90.0, 90.0
+	lonLo, lonHi := -180.0, 180.0
+
+	hash := make([]byte, 0, precision)
+	bits, ch := 0, 0
+	even := true
+	for len(hash) < precision {
+		if even {
+			mid := (lonLo + lonHi) / 2
+			if p.Lon >= mid {
+				ch = ch<<1 | 1
+				lonLo = mid
+			} else {
+				ch <<= 1
+				lonHi = mid
+			}
+		} else {
+			mid := (latLo + latHi) / 2
+			if p.Lat >= mid {
+				ch = ch<<1 | 1
+				latLo = mid
+			} else {
+				ch <<= 1
+				latHi = mid
+			}
+		}
+		even = !even
+
+		if bits++; bits == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// String renders the point in query syntax
+func (p Point) String() string {
+	return fmt.Sprintf("point({lat: %v, lon: %v})", p.Lat, p.Lon)
+}
+
+// PointFromMap converts a decoded JSON object {"lat": .., "lon": ..} back
+// into a Point
+func PointFromMap(m map[string]interface{}) (Point, bool) {
+	if len(m) != 2 {
+		return Point{}, false
+	}
+	lat, ok1 := m["lat"].(float64)
+	lon, ok2 := m["lon"].(float64)
+	return Point{Lat: lat, Lon: lon}, ok1 && ok2
+}
+
+// DefaultHistogramBuckets is the number of buckets AddToHistogram creates
+const DefaultHistogramBuckets = 10
+
+// Histogram counts values in linear buckets, so a node can summarize a
+// stream of measurements without storing each one. Buckets holds the
+// len(Counts)+1 bucket boundaries in ascending order: bucket i counts the
+// values v with Buckets[i] <= v < Buckets[i+1].
+type Histogram struct {
+	Buckets []float64 `json:"buckets"`
+	Counts  []int64   `json:"counts"`
+}
+
+// NewLinearHistogram returns an empty histogram of n buckets of the given
+// width, the first starting at start
+func NewLinearHistogram(start, width float64, n int) Histogram {
+	h := Histogram{Buckets: make([]float64, n+1), Counts: make([]int64, n)}
+	for i := range h.Buckets {
+		h.Buckets[i] = start + float64(i)*width
+	}
+	return h
+}
+
+// Add returns a copy of h that also counts value. A value at or above the
+// last boundary doubles the bucket width, merging the counts of adjacent
+// buckets, until the histogram covers it. Values below the first boundary
+// are rejected.
+func (h Histogram) Add(value float64) (Histogram, error) {
+	if err := h.validate(); err != nil {
+		return Histogram{}, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Histogram{}, fmt.Errorf("cannot add %v to a histogram", value)
+	}
+	n := len(h.Counts)
+	start := h.Buckets[0]
+	if value < start {
+		return Histogram{}, fmt.Errorf("value %v is below the histogram's first bucket at %v", value, start)
+	}
+
+	counts := append([]int64(nil), h.Counts...)
+	width := (h.Buckets[n] - start) / float64(n)
+	for value >= start+float64(n)*width {
+		for i := 0; i < n; i++ {
+			merged := int64(0)
+			for _, j := range []int{2 * i, 2*i + 1} {
+				if j < n {
+					merged += counts[j]
+				}
+			}
+			counts[i] = merged
+		}
+		width *= 2
+	}
+
+	added := NewLinearHistogram(start, width, n)
+	copy(added.Counts, counts)
+	i := int((value - start) / width)
+	if i >= n {
+		i = n - 1
+	}
+	added.Counts[i]++
+	return added, nil
+}
+
+// Total returns the number of values counted
+func (h Histogram) Total() int64 {
+	var total int64
+	for _, c := range h.Counts {
+		total += c
+	}
+	return total
+}
+
+// validate checks that h has at least one bucket, ascending boundaries and
+// a count per bucket
+func (h Histogram) validate() error {
+	if len(h.Counts) == 0 || len(h.Buckets) != len(h.Counts)+1 {
+		return fmt.Errorf("histogram needs one more boundary than counts, got %d boundaries and %d counts", len(h.Buckets), len(h.Counts))
+	}
+	for i := 1; i < len(h.Buckets); i++ {
+		if !(h.Buckets[i] > h.Buckets[i-1]) {
+			return fmt.Errorf("histogram boundaries must ascend, got %v after %v", h.Buckets[i], h.Buckets[i-1])
+		}
+	}
+	return nil
+}
+
+// MarshalJSON writes the histogram as {"type":"histogram","buckets":[..],
+// "counts":[..]}, the form it takes in the WAL and snapshots
+func (h Histogram) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string    `json:"type"`
+		Buckets []float64 `json:"buckets"`
+		Counts  []int64   `json:"counts"`
+	}{TypeHistogram, h.Buckets, h.Counts})
+}
+
+// String renders the histogram for display
+func (h Histogram) String() string {
+	return fmt.Sprintf("histogram({buckets: %v, counts: %v})", h.Buckets, h.Counts)
+}
+
+// histogramWidth returns the bucket width of a new histogram whose first
+// value is value: a tenth of the smallest power of ten above it
+func histogramWidth(value float64) float64 {
+	if value <= 0 {
+		return 0.1
+	}
+	return math.Pow(10, math.Floor(math.Log10(value)))
+}
+
 // Node represents a vertex in the graph
 type Node struct {
-	ID         NodeID     `json:"id"`
-	Label      string     `json:"label"`      // Node type/label
-	Properties Properties `json:"properties"` // Arbitrary properties
+	ID         NodeID          `json:"id"`
+	Label      string          `json:"label"`      // Node type/label
+	Properties SmallProperties `json:"properties"` // Arbitrary properties
 
 	// Adjacency lists for fast traversal
 	OutEdges []EdgeID `json:"out_edges"` // Outgoing edges
@@ -30,41 +231,96 @@ type Node struct {
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"` // Zero when the entity never expires
+	DeletedAt time.Time `json:"deleted_at"` // Set while the entity is tombstoned
 
 	Mu sync.RWMutex `json:"-"` // Protects concurrent access to this node (exported for cross-package use)
 }
 
 // Edge represents a relationship between two nodes
 type Edge struct {
-	ID         EdgeID     `json:"id"`
-	Source     NodeID     `json:"source"`     // Source node ID
-	Target     NodeID     `json:"target"`     // Target node ID
-	Label      string     `json:"label"`      // Edge type/label
-	Properties Properties `json:"properties"` // Edge properties
+	ID         EdgeID          `json:"id"`
+	Source     NodeID          `json:"source"`     // Source node ID
+	Target     NodeID          `json:"target"`     // Target node ID
+	Label      string          `json:"label"`      // Edge type/label
+	Properties SmallProperties `json:"properties"` // Edge properties
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	ExpiresAt time.Time `json:"expires_at"` // Zero when the entity never expires
+	DeletedAt time.Time `json:"deleted_at"` // Set while the entity is tombstoned
 
 	Mu sync.RWMutex `json:"-"` // Protects concurrent access to this edge (exported for cross-package use)
 }
 
+// Direction selects which edges of a node a traversal follows
+type Direction int
+
+const (
+	Outgoing Direction = iota // Edges whose source is the node
+	Incoming                  // Edges whose target is the node
+	Both                      // Outgoing edges, then incoming edges
+)
+
+// Adjacency is an edge of a node together with the node on its other end
+type Adjacency struct {
+	Edge *Edge
+	Node *Node
+}
+
 // GetProperty safely retrieves a property from a node
 func (n *Node) GetProperty(key string) (PropertyValue, bool) {
 	n.Mu.RLock()
 	defer n.Mu.RUnlock()
-	val, ok := n.Properties[key]
-	return val, ok
+	return n.Properties.GetProperty(key)
 }
 
 // SetProperty safely sets a property on a node
 func (n *Node) SetProperty(key string, value PropertyValue) {
 	n.Mu.Lock()
 	defer n.Mu.Unlock()
-	if n.Properties == nil {
-		n.Properties = make(Properties)
+	n.Properties.SetProperty(key, value)
+	n.UpdatedAt = time.Now()
+}
+
+// AddToHistogram counts value in the histogram held by the property key.
+// If the node has no such property, a histogram of
+// DefaultHistogramBuckets linear buckets starting at 0 is created, with
+// the width chosen so the first value falls in the first buckets. Only
+// the node in memory changes; the storage's AddToHistogram also logs it.
+func (n *Node) AddToHistogram(key string, value float64) error {
+	n.Mu.Lock()
+	defer n.Mu.Unlock()
+
+	current, _ := n.Properties.GetProperty(key)
+	h, err := CountInHistogram(key, current, value)
+	if err != nil {
+		return err
 	}
-	n.Properties[key] = value
+	n.Properties.SetProperty(key, h)
 	n.UpdatedAt = time.Now()
+	return nil
+}
+
+// CountInHistogram returns the histogram current, the value of the
+// property key, with value counted, creating it as AddToHistogram does
+// when current is nil
+func CountInHistogram(key string, current PropertyValue, value float64) (Histogram, error) {
+	var h Histogram
+	switch v := current.(type) {
+	case nil:
+		h = NewLinearHistogram(0, histogramWidth(value), DefaultHistogramBuckets)
+	case Histogram:
+		h = v
+	default:
+		return Histogram{}, fmt.Errorf("property %s is a %s, not a histogram", key, TypeOf(v))
+	}
+
+	h, err := h.Add(value)
+	if err != nil {
+		return Histogram{}, fmt.Errorf("property %s: %w", key, err)
+	}
+	return h, nil
 }
 
 // AddOutEdge adds an outgoing edge
@@ -87,18 +343,22 @@ func (n *Node) AddInEdge(edgeID EdgeID) {
 func (e *Edge) GetProperty(key string) (PropertyValue, bool) {
 	e.Mu.RLock()
 	defer e.Mu.RUnlock()
-	val, ok := e.Properties[key]
-	return val, ok
+	return e.Properties.GetProperty(key)
+}
+
+// Endpoints safely retrieves the source and target of an edge, which
+// change when the edge is reconnected
+func (e *Edge) Endpoints() (source, target NodeID) {
+	e.Mu.RLock()
+	defer e.Mu.RUnlock()
+	return e.Source, e.Target
 }
 
 // SetProperty safely sets a property on an edge
 func (e *Edge) SetProperty(key string, value PropertyValue) {
 	e.Mu.Lock()
 	defer e.Mu.Unlock()
-	if e.Properties == nil {
-		e.Properties = make(Properties)
-	}
-	e.Properties[key] = value
+	e.Properties.SetProperty(key, value)
 	e.UpdatedAt = time.Now()
 }
 
@@ -106,13 +366,12 @@ func (e *Edge) SetProperty(key string, value PropertyValue) {
 func NewNode(id NodeID, label string) *Node {
 	now := time.Now()
 	return &Node{
-		ID:         id,
-		Label:      label,
-		Properties: make(Properties),
-		OutEdges:   make([]EdgeID, 0),
-		InEdges:    make([]EdgeID, 0),
-		CreatedAt:  now,
-		UpdatedAt:  now,
+		ID:        id,
+		Label:     label,
+		OutEdges:  make([]EdgeID, 0),
+		InEdges:   make([]EdgeID, 0),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 }
 
@@ -120,12 +379,67 @@ func NewNode(id NodeID, label string) *Node {
 func NewEdge(id EdgeID, source, target NodeID, label string) *Edge {
 	now := time.Now()
 	return &Edge{
-		ID:         id,
-		Source:     source,
-		Target:     target,
-		Label:      label,
-		Properties: make(Properties),
-		CreatedAt:  now,
-		UpdatedAt:  now,
+		ID:        id,
+		Source:    source,
+		Target:    target,
+		Label:     label,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// String renders the node in pattern form, e.g. (1:Person {name: "Alice"})
+func (n *Node) String() string {
+	n.Mu.RLock()
+	defer n.Mu.RUnlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "(%d", n.ID)
+	if n.Label != "" {
+		b.WriteString(":" + n.Label)
+	}
+	b.WriteString(formatProperties(&n.Properties))
+	b.WriteString(")")
+	return b.String()
+}
+
+// String renders the edge in pattern form, e.g. (1)-[:KNOWS {since: 2020}]->(2)
+func (e *Edge) String() string {
+	return fmt.Sprintf("(%d)-%s->(%d)", e.Source, e.RelationshipString(), e.Target)
+}
+
+// RelationshipString renders the edge without its endpoints, e.g.
+// [:KNOWS {since: 2020}]
+func (e *Edge) RelationshipString() string {
+	e.Mu.RLock()
+	defer e.Mu.RUnlock()
+
+	var b strings.Builder
+	b.WriteString("[")
+	if e.Label != "" {
+		b.WriteString(":" + e.Label)
+	}
+	b.WriteString(formatProperties(&e.Properties))
+	b.WriteString("]")
+	return b.String()
+}
+
+// formatProperties renders properties as " {k: v, ...}" with sorted keys,
+// or an empty string when there are none
+func formatProperties(props *SmallProperties) string {
+	if props.Len() == 0 {
+		return ""
+	}
+
+	keys := props.Keys()
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		v, _ := props.GetProperty(k)
+		if s, ok := v.(string); ok {
+			parts[i] = fmt.Sprintf("%s: %q", k, s)
+		} else {
+			parts[i] = fmt.Sprintf("%s: %v", k, v)
+		}
 	}
+	return " {" + strings.Join(parts, ", ") + "}"
 }
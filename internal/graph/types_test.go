@@ -1,6 +1,8 @@
 package graph
 
 import (
+	"fmt"
+	"math"
 	"testing"
 	"time"
 
@@ -16,7 +18,7 @@ func TestNewNode(t *testing.T) {
 
 	assert.Equal(t, nodeID, node.ID)
 	assert.Equal(t, label, node.Label)
-	assert.NotNil(t, node.Properties)
+	assert.Equal(t, 0, node.Properties.Len())
 	assert.NotNil(t, node.OutEdges)
 	assert.NotNil(t, node.InEdges)
 	assert.False(t, node.CreatedAt.IsZero())
@@ -74,7 +76,7 @@ func TestNewEdge(t *testing.T) {
 	assert.Equal(t, source, edge.Source)
 	assert.Equal(t, target, edge.Target)
 	assert.Equal(t, label, edge.Label)
-	assert.NotNil(t, edge.Properties)
+	assert.Equal(t, 0, edge.Properties.Len())
 	assert.False(t, edge.CreatedAt.IsZero())
 }
 
@@ -130,3 +132,86 @@ func TestUpdateTimestamp(t *testing.T) {
 
 	assert.True(t, node.UpdatedAt.After(originalTime))
 }
+
+func TestNodeEdgeString(t *testing.T) {
+	node := NewNode(1, "Person")
+	node.SetProperty("name", "Alice")
+	node.SetProperty("age", 30)
+	assert.Equal(t, `(1:Person {age: 30, name: "Alice"})`, node.String())
+
+	edge := NewEdge(7, 1, 2, "KNOWS")
+	assert.Equal(t, "(1)-[:KNOWS]->(2)", edge.String())
+
+	edge.SetProperty("since", 2020)
+	assert.Equal(t, "(1)-[:KNOWS {since: 2020}]->(2)", fmt.Sprint(edge))
+	assert.Equal(t, "[:KNOWS {since: 2020}]", edge.RelationshipString())
+}
+
+func TestPointDistance(t *testing.T) {
+	nyc := Point{Lat: 40.7128, Lon: -74.0060}
+	london := Point{Lat: 51.5074, Lon: -0.1278}
+
+	assert.InDelta(t, 5570, nyc.DistanceKm(london), 10)
+	assert.InDelta(t, 0, nyc.DistanceKm(nyc), 1e-9)
+	assert.Equal(t, nyc.DistanceKm(london), london.DistanceKm(nyc))
+	assert.InDelta(t, nyc.DistanceKm(london)*1000, nyc.DistanceMeters(london), 1e-6)
+	assert.Equal(t, "point({lat: 40.7128, lon: -74.006})", nyc.String())
+}
+
+func TestGeohash(t *testing.T) {
+	assert.Equal(t, "dr5regw3p", Geohash(Point{Lat: 40.7128, Lon: -74.0060}, 9))
+	assert.Equal(t, "gcpvj", Geohash(Point{Lat: 51.5074, Lon: -0.1278}, 5))
+	assert.Equal(t, "s0000", Geohash(Point{}, 5))
+
+	// Nearby points share a prefix
+	newark := Geohash(Point{Lat: 40.7357, Lon: -74.1724}, 4)
+	assert.Equal(t, Geohash(Point{Lat: 40.7128, Lon: -74.0060}, 4), newark)
+}
+
+func TestHistogramAdd(t *testing.T) {
+	h := NewLinearHistogram(0, 10, 4)
+	assert.Equal(t, []float64{0, 10, 20, 30, 40}, h.Buckets)
+
+	for _, v := range []float64{0, 5, 10, 39.9} {
+		var err error
+		h, err = h.Add(v)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int64{2, 1, 0, 1}, h.Counts)
+
+	// A value past the last bucket doubles the width until it fits,
+	// merging adjacent buckets
+	grown, err := h.Add(150)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0, 40, 80, 120, 160}, grown.Buckets)
+	assert.Equal(t, []int64{4, 0, 0, 1}, grown.Counts)
+	assert.Equal(t, int64(5), grown.Total())
+	assert.Equal(t, []int64{2, 1, 0, 1}, h.Counts, "Add leaves the original unchanged")
+
+	_, err = h.Add(-1)
+	assert.ErrorContains(t, err, "below the histogram's first bucket")
+	_, err = h.Add(math.NaN())
+	assert.Error(t, err)
+	_, err = Histogram{Buckets: []float64{0, 1}, Counts: []int64{1, 2}}.Add(1)
+	assert.Error(t, err)
+}
+
+func TestNodeAddToHistogram(t *testing.T) {
+	node := NewNode(1, "Stats")
+	for _, latency := range []float64{42, 17, 99.5, 3, 250} {
+		require.NoError(t, node.AddToHistogram("latency_ms", latency))
+	}
+
+	v, ok := node.GetProperty("latency_ms")
+	require.True(t, ok)
+	h := v.(Histogram)
+	// The first value, 42, gives ten buckets of 10 up to 100; 250 widens
+	// them to 40
+	assert.Equal(t, []float64{0, 40, 80, 120, 160, 200, 240, 280, 320, 360, 400}, h.Buckets)
+	assert.Equal(t, []int64{2, 1, 1, 0, 0, 0, 1, 0, 0, 0}, h.Counts)
+	assert.Equal(t, "histogram({buckets: [0 40 80 120 160 200 240 280 320 360 400], counts: [2 1 1 0 0 0 1 0 0 0]})", h.String())
+
+	node.SetProperty("name", "api")
+	assert.ErrorContains(t, node.AddToHistogram("name", 1), "property name is a string, not a histogram")
+	assert.Error(t, node.AddToHistogram("latency_ms", -5))
+}
@@ -0,0 +1,516 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// MaxValueDepth is the deepest nesting of lists and maps a property value
+// may have. A flat list has depth 1.
+const MaxValueDepth = 8
+
+// Property value type tags used by TypedValue
+const (
+	TypeNull      = "null"
+	TypeString    = "string"
+	TypeInt       = "int"
+	TypeFloat     = "float"
+	TypeBool      = "bool"
+	TypePoint     = "point"
+	TypeDatetime  = "datetime"
+	TypeList      = "list"
+	TypeMap       = "map"
+	TypeHistogram = "histogram"
+)
+
+// TypedValue is the self-describing JSON form of a property value,
+// e.g. {"type":"int","value":30}. Plain JSON loses the distinction between
+// ints and floats; the type tag preserves it. Histograms are written as
+// {"type":"histogram","buckets":[..],"counts":[..]}, with Value holding
+// the whole object.
+type TypedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// typedValue has TypedValue's default JSON encoding
+type typedValue TypedValue
+
+// MarshalJSON implements json.Marshaler
+func (tv TypedValue) MarshalJSON() ([]byte, error) {
+	if tv.Type == TypeHistogram {
+		return tv.Value, nil
+	}
+	return json.Marshal(typedValue(tv))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (tv *TypedValue) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*typedValue)(tv)); err != nil {
+		return err
+	}
+	if tv.Type == TypeHistogram {
+		tv.Value = append(json.RawMessage(nil), data...)
+	}
+	return nil
+}
+
+// NormalizeValue checks that v is a supported property value and returns
+// it in canonical form: lists as []PropertyValue and maps as Properties,
+// with every element normalized in turn. Slices of any supported type and
+// maps with string keys are accepted.
+func NormalizeValue(v PropertyValue) (PropertyValue, error) {
+	return normalizeValue(v, 0)
+}
+
+func normalizeValue(v PropertyValue, depth int) (PropertyValue, error) {
+	switch v.(type) {
+	case nil, string, bool, Point, time.Time,
+		int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v, nil
+	case Histogram:
+		if err := v.(Histogram).validate(); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if depth >= MaxValueDepth {
+			return nil, fmt.Errorf("value nested deeper than %d levels", MaxValueDepth)
+		}
+		list := make([]PropertyValue, rv.Len())
+		for i := range list {
+			elem, err := normalizeValue(rv.Index(i).Interface(), depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			list[i] = elem
+		}
+		return list, nil
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if depth >= MaxValueDepth {
+			return nil, fmt.Errorf("value nested deeper than %d levels", MaxValueDepth)
+		}
+		props := make(Properties, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			elem, err := normalizeValue(iter.Value().Interface(), depth+1)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			props[key] = elem
+		}
+		return props, nil
+	}
+	return nil, fmt.Errorf("unsupported property type %T", v)
+}
+
+// NormalizeProperties returns a copy of props with every value normalized,
+// or an error naming the first unsupported property. The elements of each
+// stored list must share a type, as checked by CheckListTypes.
+func NormalizeProperties(props Properties) (Properties, error) {
+	return normalizeProperties(props, true)
+}
+
+// NormalizeStoredProperties is NormalizeProperties without the list type
+// check. It is meant for values that were already stored, such as those
+// in a dump or a log, so that older data with mixed lists still loads.
+func NormalizeStoredProperties(props Properties) (Properties, error) {
+	return normalizeProperties(props, false)
+}
+
+func normalizeProperties(props Properties, checkLists bool) (Properties, error) {
+	if props == nil {
+		return nil, nil
+	}
+	normalized := make(Properties, len(props))
+	for k, v := range props {
+		nv, err := NormalizeValue(v)
+		if err == nil && checkLists {
+			err = CheckListTypes(nv)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", k, err)
+		}
+		normalized[k] = nv
+	}
+	return normalized, nil
+}
+
+// CheckListTypes checks that the elements of every list in a normalized
+// value, including lists nested in lists and maps, share a type. Ints and
+// floats may be mixed, and nulls go with anything. Values already stored
+// are not checked; see NormalizeStoredProperties.
+func CheckListTypes(v PropertyValue) error {
+	switch val := v.(type) {
+	case []PropertyValue:
+		var first PropertyValue
+		for i, elem := range val {
+			if err := CheckListTypes(elem); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+			if elem == nil {
+				continue
+			}
+			if first == nil {
+				first = elem
+			} else if listElementType(elem) != listElementType(first) {
+				return fmt.Errorf("[%d]: list mixes %s and %s elements", i, TypeOf(first), TypeOf(elem))
+			}
+		}
+	case Properties:
+		for k, elem := range val {
+			if err := CheckListTypes(elem); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// listElementType is the type a list element must share with the others
+// in its list; ints and floats count as one
+func listElementType(v PropertyValue) string {
+	if typ := TypeOf(v); typ != TypeFloat {
+		return typ
+	}
+	return TypeInt
+}
+
+// TypeOf returns the type tag of a property value, such as TypeInt, or ""
+// if v is not a normalized property value
+func TypeOf(v PropertyValue) string {
+	switch v.(type) {
+	case nil:
+		return TypeNull
+	case string:
+		return TypeString
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return TypeInt
+	case float32, float64:
+		return TypeFloat
+	case bool:
+		return TypeBool
+	case Point:
+		return TypePoint
+	case time.Time:
+		return TypeDatetime
+	case []PropertyValue:
+		return TypeList
+	case Properties:
+		return TypeMap
+	case Histogram:
+		return TypeHistogram
+	}
+	return ""
+}
+
+// EncodeValue converts a property value to its typed form. Lists and maps
+// are encoded element by element.
+func EncodeValue(v PropertyValue) (TypedValue, error) {
+	nv, err := NormalizeValue(v)
+	if err != nil {
+		return TypedValue{}, err
+	}
+	return encodeNormalized(nv)
+}
+
+func encodeNormalized(v PropertyValue) (TypedValue, error) {
+	var typ string
+	switch val := v.(type) {
+	case nil:
+		return TypedValue{Type: TypeNull, Value: json.RawMessage("null")}, nil
+	case string:
+		typ = TypeString
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		typ = TypeInt
+	case float32, float64:
+		typ = TypeFloat
+	case bool:
+		typ = TypeBool
+	case Point:
+		typ = TypePoint
+	case time.Time:
+		typ = TypeDatetime
+	case []PropertyValue:
+		elems := make([]TypedValue, len(val))
+		for i, elem := range val {
+			tv, err := encodeNormalized(elem)
+			if err != nil {
+				return TypedValue{}, err
+			}
+			elems[i] = tv
+		}
+		return marshalTyped(TypeList, elems)
+	case Properties:
+		fields := make(map[string]TypedValue, len(val))
+		for k, elem := range val {
+			tv, err := encodeNormalized(elem)
+			if err != nil {
+				return TypedValue{}, err
+			}
+			fields[k] = tv
+		}
+		return marshalTyped(TypeMap, fields)
+	case Histogram:
+		return marshalTyped(TypeHistogram, val)
+	}
+	return marshalTyped(typ, v)
+}
+
+func marshalTyped(typ string, v interface{}) (TypedValue, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return TypedValue{}, err
+	}
+	return TypedValue{Type: typ, Value: raw}, nil
+}
+
+// Decode converts a typed value back to a property value.
+// Integers decode as int and floats as float64.
+func (tv TypedValue) Decode() (PropertyValue, error) {
+	switch tv.Type {
+	case TypeNull:
+		return nil, nil
+	case TypeString:
+		var s string
+		err := json.Unmarshal(tv.Value, &s)
+		return s, err
+	case TypeInt:
+		var n int64
+		if err := json.Unmarshal(tv.Value, &n); err != nil {
+			return nil, fmt.Errorf("invalid int %s: %w", tv.Value, err)
+		}
+		return int(n), nil
+	case TypeFloat:
+		var f float64
+		err := json.Unmarshal(tv.Value, &f)
+		return f, err
+	case TypeBool:
+		var b bool
+		err := json.Unmarshal(tv.Value, &b)
+		return b, err
+	case TypePoint:
+		var pt Point
+		err := json.Unmarshal(tv.Value, &pt)
+		return pt, err
+	case TypeDatetime:
+		var t time.Time
+		err := json.Unmarshal(tv.Value, &t)
+		return t, err
+	case TypeList:
+		var elems []TypedValue
+		if err := json.Unmarshal(tv.Value, &elems); err != nil {
+			return nil, fmt.Errorf("invalid list: %w", err)
+		}
+		list := make([]PropertyValue, len(elems))
+		for i, elem := range elems {
+			v, err := elem.Decode()
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case TypeMap:
+		var fields map[string]TypedValue
+		if err := json.Unmarshal(tv.Value, &fields); err != nil {
+			return nil, fmt.Errorf("invalid map: %w", err)
+		}
+		return DecodeProperties(fields)
+	case TypeHistogram:
+		var h Histogram
+		if err := json.Unmarshal(tv.Value, &h); err != nil {
+			return nil, fmt.Errorf("invalid histogram: %w", err)
+		}
+		if err := h.validate(); err != nil {
+			return nil, err
+		}
+		return h, nil
+	}
+	return nil, fmt.Errorf("unknown property type %q", tv.Type)
+}
+
+// EncodeProperties converts all property values to their typed form
+func EncodeProperties(props Properties) (map[string]TypedValue, error) {
+	typed := make(map[string]TypedValue, len(props))
+	for k, v := range props {
+		tv, err := EncodeValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", k, err)
+		}
+		typed[k] = tv
+	}
+	return typed, nil
+}
+
+// DecodeProperties converts typed property values back to Properties
+func DecodeProperties(typed map[string]TypedValue) (Properties, error) {
+	props := make(Properties, len(typed))
+	for k, tv := range typed {
+		v, err := tv.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", k, err)
+		}
+		props[k] = v
+	}
+	return props, nil
+}
+
+// MarshalJSON writes each value in typed form so that ints, times and
+// points survive the WAL and snapshots. Values of other types are written
+// as plain JSON.
+func (p Properties) MarshalJSON() ([]byte, error) {
+	if p == nil {
+		return []byte("null"), nil
+	}
+	out := make(map[string]interface{}, len(p))
+	for k, v := range p {
+		if tv, err := EncodeValue(v); err == nil {
+			out[k] = tv
+		} else {
+			out[k] = v
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads properties written by MarshalJSON. Plain values from
+// older data are accepted too; their numbers decode as float64.
+func (p *Properties) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*p = nil
+		return nil
+	}
+
+	props := make(Properties, len(raw))
+	for k, msg := range raw {
+		if tv, ok := asTypedValue(msg); ok {
+			v, err := tv.Decode()
+			if err != nil {
+				return fmt.Errorf("property %s: %w", k, err)
+			}
+			props[k] = v
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal(msg, &v); err != nil {
+			return fmt.Errorf("property %s: %w", k, err)
+		}
+		props[k] = legacyValue(v)
+	}
+	*p = props
+	return nil
+}
+
+// DecodeJSONValue converts a property value that went through a generic
+// JSON decode, such as WAL entry data, back to its Go type
+func DecodeJSONValue(v interface{}) PropertyValue {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return legacyValue(v)
+	}
+	if h, ok := histogramFromMap(obj); ok {
+		return h
+	}
+	if typ, ok := obj["type"].(string); ok && len(obj) == 2 && isKnownType(typ) {
+		if value, present := obj["value"]; present {
+			raw, err := json.Marshal(value)
+			if err == nil {
+				if decoded, err := (TypedValue{Type: typ, Value: raw}).Decode(); err == nil {
+					return decoded
+				}
+			}
+		}
+	}
+	return legacyValue(v)
+}
+
+// histogramFromMap converts a decoded JSON object {"type": "histogram",
+// "buckets": [..], "counts": [..]} back into a Histogram
+func histogramFromMap(m map[string]interface{}) (Histogram, bool) {
+	if m["type"] != TypeHistogram || len(m) != 3 {
+		return Histogram{}, false
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return Histogram{}, false
+	}
+	v, err := TypedValue{Type: TypeHistogram, Value: raw}.Decode()
+	if err != nil {
+		return Histogram{}, false
+	}
+	return v.(Histogram), true
+}
+
+// asTypedValue reports whether msg is a {"type": .., "value": ..} object
+// with a known type tag, or a histogram
+func asTypedValue(msg json.RawMessage) (TypedValue, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return TypedValue{}, false
+	}
+	var tv TypedValue
+	if len(fields) == 3 && json.Unmarshal(fields["type"], &tv.Type) == nil && tv.Type == TypeHistogram {
+		tv.Value = msg
+		if _, err := tv.Decode(); err != nil {
+			return TypedValue{}, false
+		}
+		return tv, true
+	}
+	if len(fields) != 2 {
+		return TypedValue{}, false
+	}
+	if _, ok := fields["value"]; !ok {
+		return TypedValue{}, false
+	}
+	if err := json.Unmarshal(fields["type"], &tv.Type); err != nil || !isKnownType(tv.Type) {
+		return TypedValue{}, false
+	}
+	tv.Value = fields["value"]
+	return tv, true
+}
+
+func isKnownType(typ string) bool {
+	switch typ {
+	case TypeNull, TypeString, TypeInt, TypeFloat, TypeBool, TypePoint, TypeDatetime, TypeList, TypeMap:
+		return true
+	}
+	return false
+}
+
+// legacyValue restores values written as plain JSON before typed encoding
+func legacyValue(v interface{}) PropertyValue {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if pt, ok := PointFromMap(val); ok {
+			return pt
+		}
+		props := make(Properties, len(val))
+		for k, elem := range val {
+			props[k] = legacyValue(elem)
+		}
+		return props
+	case []interface{}:
+		list := make([]PropertyValue, len(val))
+		for i, elem := range val {
+			list[i] = legacyValue(elem)
+		}
+		return list
+	}
+	return v
+}
@@ -0,0 +1,210 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypedValueRoundTrip(t *testing.T) {
+	props := Properties{
+		"name":   "Alice",
+		"age":    30,
+		"big":    int64(1) << 60,
+		"score":  0.5,
+		"whole":  2.0,
+		"active": true,
+		"none":   nil,
+	}
+
+	typed, err := EncodeProperties(props)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(typed)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"age":{"type":"int","value":30}`)
+	assert.Contains(t, string(data), `"whole":{"type":"float","value":2}`)
+
+	var decodedTyped map[string]TypedValue
+	require.NoError(t, json.Unmarshal(data, &decodedTyped))
+	decoded, err := DecodeProperties(decodedTyped)
+	require.NoError(t, err)
+
+	assert.Equal(t, 30, decoded["age"])
+	assert.Equal(t, 1<<60, decoded["big"])
+	assert.Equal(t, 2.0, decoded["whole"])
+	assert.Equal(t, "Alice", decoded["name"])
+	assert.Equal(t, true, decoded["active"])
+	assert.Nil(t, decoded["none"])
+	assert.Contains(t, decoded, "none")
+}
+
+func TestTypeOf(t *testing.T) {
+	assert.Equal(t, TypeNull, TypeOf(nil))
+	assert.Equal(t, TypeString, TypeOf("30"))
+	assert.Equal(t, TypeInt, TypeOf(int64(30)))
+	assert.Equal(t, TypeFloat, TypeOf(30.0))
+	assert.Equal(t, TypeBool, TypeOf(true))
+	assert.Equal(t, TypePoint, TypeOf(Point{}))
+	assert.Equal(t, TypeDatetime, TypeOf(time.Now()))
+	assert.Equal(t, TypeList, TypeOf([]PropertyValue{1}))
+	assert.Equal(t, TypeMap, TypeOf(Properties{}))
+	assert.Equal(t, "", TypeOf([]int{1}), "unnormalized values have no type")
+}
+
+func TestEncodeValue_Unsupported(t *testing.T) {
+	_, err := EncodeValue(struct{}{})
+	assert.Error(t, err)
+
+	_, err = TypedValue{Type: "complex", Value: json.RawMessage("1")}.Decode()
+	assert.Error(t, err)
+}
+
+func TestPropertiesJSON(t *testing.T) {
+	when := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	props := Properties{
+		"age":   30,
+		"score": 0.5,
+		"when":  when,
+		"home":  Point{Lat: 51.5, Lon: -0.12},
+	}
+
+	data, err := json.Marshal(props)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"when":{"type":"datetime","value":"2024-03-01T12:30:00Z"}`)
+
+	var decoded Properties
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, props, decoded)
+
+	// Plain values written before typed encoding still load
+	var legacy Properties
+	require.NoError(t, json.Unmarshal([]byte(`{"age":30,"home":{"lat":1,"lon":2},"when":"2024-03-01"}`), &legacy))
+	assert.Equal(t, 30.0, legacy["age"])
+	assert.Equal(t, Point{Lat: 1, Lon: 2}, legacy["home"])
+	assert.Equal(t, "2024-03-01", legacy["when"])
+}
+
+func TestHistogramJSON(t *testing.T) {
+	h := Histogram{Buckets: []float64{0, 10, 20}, Counts: []int64{3, 1}}
+	props := Properties{
+		"latency_ms": h,
+		"history":    []PropertyValue{h},
+	}
+
+	data, err := json.Marshal(props)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"latency_ms":{"type":"histogram","buckets":[0,10,20],"counts":[3,1]}`)
+
+	var decoded Properties
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, props, decoded)
+	assert.Equal(t, TypeHistogram, TypeOf(decoded["latency_ms"]))
+
+	// As read back from WAL entry data
+	var generic map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &generic))
+	assert.Equal(t, h, DecodeJSONValue(generic["latency_ms"]))
+
+	// Malformed histograms are rejected
+	_, err = NormalizeValue(Histogram{Buckets: []float64{0, 1}, Counts: []int64{1, 2}})
+	assert.Error(t, err)
+	_, err = TypedValue{Type: TypeHistogram, Value: json.RawMessage(`{"type":"histogram","buckets":[1,0],"counts":[1]}`)}.Decode()
+	assert.ErrorContains(t, err, "must ascend")
+}
+
+func TestDecodeJSONValue(t *testing.T) {
+	var generic map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"age": {"type": "int", "value": 30},
+		"when": {"type": "datetime", "value": "2024-03-01T12:30:00Z"},
+		"other": {"type": "unknown", "value": 1},
+		"plain": 1.5
+	}`), &generic))
+
+	assert.Equal(t, 30, DecodeJSONValue(generic["age"]))
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), DecodeJSONValue(generic["when"]))
+	assert.Equal(t, Properties{"type": "unknown", "value": 1.0}, DecodeJSONValue(generic["other"]))
+	assert.Equal(t, 1.5, DecodeJSONValue(generic["plain"]))
+}
+
+func TestNormalizeValue(t *testing.T) {
+	v, err := NormalizeValue([]string{"go", "rust"})
+	require.NoError(t, err)
+	assert.Equal(t, []PropertyValue{"go", "rust"}, v)
+
+	v, err = NormalizeValue(map[string]interface{}{"city": "SF", "zips": []int{94103}})
+	require.NoError(t, err)
+	assert.Equal(t, Properties{"city": "SF", "zips": []PropertyValue{94103}}, v)
+
+	_, err = NormalizeValue([]interface{}{struct{}{}})
+	assert.ErrorContains(t, err, "unsupported property type")
+
+	_, err = NormalizeValue(map[int]string{1: "x"})
+	assert.Error(t, err)
+
+	// A flat list has depth 1, so MaxValueDepth levels are allowed
+	var deep PropertyValue = "leaf"
+	for i := 0; i < MaxValueDepth; i++ {
+		deep = []PropertyValue{deep}
+	}
+	_, err = NormalizeValue(deep)
+	require.NoError(t, err)
+	_, err = NormalizeValue([]PropertyValue{deep})
+	assert.ErrorContains(t, err, "nested deeper")
+}
+
+func TestNormalizeProperties_ListTypes(t *testing.T) {
+	props, err := NormalizeProperties(Properties{
+		"hobbies": []string{"chess", "running"},
+		"scores":  []interface{}{nil, 1, 2.5},
+		"matrix":  [][]int64{{1, 2}, {3}},
+		"address": map[string]interface{}{"city": "SF", "zip": 94103},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []PropertyValue{"chess", "running"}, props["hobbies"])
+	assert.Equal(t, []PropertyValue{nil, 1, 2.5}, props["scores"])
+	assert.Equal(t, []PropertyValue{[]PropertyValue{int64(1), int64(2)}, []PropertyValue{int64(3)}}, props["matrix"])
+
+	_, err = NormalizeProperties(Properties{"tags": []interface{}{"chess", nil, 42}})
+	assert.EqualError(t, err, "property tags: [2]: list mixes string and int elements")
+	_, err = NormalizeProperties(Properties{"nested": []interface{}{[]string{"a"}, []interface{}{"b", true}}})
+	assert.EqualError(t, err, "property nested: [1]: [1]: list mixes string and bool elements")
+	_, err = NormalizeProperties(Properties{"address": map[string]interface{}{"zips": []interface{}{1, "x"}}})
+	assert.EqualError(t, err, "property address: zips: [1]: list mixes int and string elements")
+
+	// Stored values, as in a dump, may mix types
+	props, err = NormalizeStoredProperties(Properties{"tags": []interface{}{"chess", 42}})
+	require.NoError(t, err)
+	assert.Equal(t, []PropertyValue{"chess", 42}, props["tags"])
+
+	// Encoding, as when logging, accepts mixed lists
+	_, err = EncodeValue([]interface{}{"a", 1})
+	assert.NoError(t, err)
+}
+
+func TestCompositeValueRoundTrip(t *testing.T) {
+	props := Properties{
+		"skills":  []string{"go", "sql"},
+		"address": map[string]interface{}{"city": "SF", "geo": Point{Lat: 37.7, Lon: -122.4}},
+		"scores":  []interface{}{1, 2.5, nil},
+	}
+
+	data, err := json.Marshal(props)
+	require.NoError(t, err)
+	var decoded Properties
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	assert.Equal(t, []PropertyValue{"go", "sql"}, decoded["skills"])
+	assert.Equal(t, Properties{"city": "SF", "geo": Point{Lat: 37.7, Lon: -122.4}}, decoded["address"])
+	assert.Equal(t, []PropertyValue{1, 2.5, nil}, decoded["scores"])
+
+	// Generic decoding, as used for WAL entries, gives the same values
+	var generic map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &generic))
+	assert.Equal(t, decoded["address"], DecodeJSONValue(generic["address"]))
+	assert.Equal(t, []PropertyValue{"a", 1.0}, DecodeJSONValue([]interface{}{"a", 1.0}))
+}
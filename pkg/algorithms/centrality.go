@@ -1,7 +1,11 @@
 package algorithms
 
 import (
+	"fmt"
 	"math"
+	"runtime"
+	"sort"
+	"sync"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
 	"github.com/fnuworsu/rdgDB/pkg/storage"
@@ -44,11 +48,12 @@ func PageRank(g *storage.Graph, config PageRankConfig) (map[graph.NodeID]float64
 	// Get all nodes first to avoid locking repeatedly during iteration
 	// For very large graphs, this would need optimization (streaming/chunking)
 	var nodes []*graph.Node
-	g.IterateNodes(func(n *graph.Node) bool {
+	iter := g.Nodes()
+	for n, ok := iter.Next(); ok; n, ok = iter.Next() {
 		nodes = append(nodes, n)
 		scores[n.ID] = initialScore
-		return true
-	})
+	}
+	iter.Close()
 
 	// Pre-calculate outgoing degree for all nodes
 	outDegree := make(map[graph.NodeID]int)
@@ -68,19 +73,17 @@ func PageRank(g *storage.Graph, config PageRankConfig) (map[graph.NodeID]float64
 			// Score from incoming edges
 			incomingScore := 0.0
 
-			// We need incoming neighbors to calculate score
-			// This is expensive if not indexed.
-			// Graph storage has GetIncomingNeighbors which uses InEdges list.
-			incoming, err := g.GetIncomingNeighbors(node.ID)
-			if err != nil {
-				continue
-			}
-
-			for _, neighbor := range incoming {
+			// Incoming edges are streamed from the InEdges list, so a
+			// node with millions of them needs no slice of that size
+			err := g.IterateNeighbors(node.ID, graph.Incoming, func(_ *graph.Edge, neighbor *graph.Node) bool {
 				degree := outDegree[neighbor.ID]
 				if degree > 0 {
 					incomingScore += scores[neighbor.ID] / float64(degree)
 				}
+				return true
+			})
+			if err != nil {
+				continue
 			}
 
 			// Apply damping factor
@@ -100,3 +103,241 @@ func PageRank(g *storage.Graph, config PageRankConfig) (map[graph.NodeID]float64
 
 	return scores, nil
 }
+
+// BetweennessCentrality computes the betweenness centrality of every node
+// using Brandes' algorithm on the directed, unweighted graph.
+// Scores are not normalized.
+func BetweennessCentrality(g *storage.Graph) (map[graph.NodeID]float64, error) {
+	scores := make(map[graph.NodeID]float64)
+
+	var nodes []graph.NodeID
+	iter := g.Nodes()
+	for n, ok := iter.Next(); ok; n, ok = iter.Next() {
+		nodes = append(nodes, n.ID)
+		scores[n.ID] = 0
+	}
+	iter.Close()
+
+	for _, s := range nodes {
+		// Single-source shortest paths (BFS)
+		stack := make([]graph.NodeID, 0, len(nodes))
+		preds := make(map[graph.NodeID][]graph.NodeID)
+		sigma := map[graph.NodeID]float64{s: 1}
+		dist := map[graph.NodeID]int{s: 0}
+
+		queue := []graph.NodeID{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+
+			adjacent, err := g.GetAdjacent(v, graph.Outgoing)
+			if err != nil {
+				continue
+			}
+			for _, adj := range adjacent {
+				w := adj.Node
+				if _, seen := dist[w.ID]; !seen {
+					dist[w.ID] = dist[v] + 1
+					queue = append(queue, w.ID)
+				}
+				if dist[w.ID] == dist[v]+1 {
+					sigma[w.ID] += sigma[v]
+					preds[w.ID] = append(preds[w.ID], v)
+				}
+			}
+		}
+
+		// Accumulate dependencies in reverse BFS order
+		delta := make(map[graph.NodeID]float64)
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range preds[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				scores[w] += delta[w]
+			}
+		}
+	}
+
+	return scores, nil
+}
+
+// ClosenessConfig holds configuration for closeness centrality
+type ClosenessConfig struct {
+	// Normalized applies the Wasserman-Faust correction, scaling each score
+	// by the fraction of the graph the node can reach. Without it a node
+	// scores only against its reachable set.
+	Normalized bool
+}
+
+// DefaultClosenessConfig returns default configuration
+func DefaultClosenessConfig() ClosenessConfig {
+	return ClosenessConfig{Normalized: true}
+}
+
+// ClosenessCentrality computes the closeness centrality of every node
+// following outgoing edges. For a node reaching r nodes (itself included)
+// at total distance d in a graph of N nodes the score is (r-1)/d, or
+// (r-1)^2 / ((N-1)*d) when normalized. Nodes that reach no other node
+// score 0. One BFS runs per node, spread over runtime.NumCPU() workers.
+func ClosenessCentrality(g *storage.Graph, config ClosenessConfig) (map[graph.NodeID]float64, error) {
+	var nodes []graph.NodeID
+	iter := g.Nodes()
+	for n, ok := iter.Next(); ok; n, ok = iter.Next() {
+		nodes = append(nodes, n.ID)
+	}
+	iter.Close()
+
+	scores := make(map[graph.NodeID]float64, len(nodes))
+	n := len(nodes)
+	if n == 0 {
+		return scores, nil
+	}
+
+	sources := make(chan graph.NodeID)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range sources {
+				reached, total := distanceSum(g, s)
+
+				score := 0.0
+				if total > 0 {
+					others := float64(reached - 1)
+					score = others / float64(total)
+					if config.Normalized {
+						score *= others / float64(n-1)
+					}
+				}
+
+				mu.Lock()
+				scores[s] = score
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, s := range nodes {
+		sources <- s
+	}
+	close(sources)
+	wg.Wait()
+
+	return scores, nil
+}
+
+// distanceSum runs a BFS from s and returns the number of nodes reached,
+// s included, and the sum of their distances from s
+func distanceSum(g *storage.Graph, s graph.NodeID) (int, int) {
+	dist := map[graph.NodeID]int{s: 0}
+	queue := []graph.NodeID{s}
+	total := 0
+
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		adjacent, err := g.GetAdjacent(v, graph.Outgoing)
+		if err != nil {
+			continue
+		}
+		for _, adj := range adjacent {
+			w := adj.Node
+			if _, seen := dist[w.ID]; !seen {
+				dist[w.ID] = dist[v] + 1
+				total += dist[w.ID]
+				queue = append(queue, w.ID)
+			}
+		}
+	}
+	return len(dist), total
+}
+
+// DegreeMode selects which edges DegreeCentrality counts
+type DegreeMode int
+
+const (
+	DegreeIn  DegreeMode = iota // Incoming edges
+	DegreeOut                   // Outgoing edges
+	DegreeAll                   // Both directions
+)
+
+// RankedNode is one entry of a DegreeRank result
+type RankedNode struct {
+	ID     graph.NodeID
+	Degree float64 // Degree centrality
+	Rank   int     // 1-based position
+}
+
+// DegreeCentrality returns each node's degree in the given mode divided by
+// N-1. In DegreeAll mode a node linked both ways to every other node
+// scores 2. A single-node graph scores 0.
+func DegreeCentrality(g *storage.Graph, mode DegreeMode) (map[graph.NodeID]float64, error) {
+	if mode < DegreeIn || mode > DegreeAll {
+		return nil, fmt.Errorf("unknown degree mode %d", mode)
+	}
+
+	degrees := make(map[graph.NodeID]float64)
+	iter := g.Nodes()
+	for n, ok := iter.Next(); ok; n, ok = iter.Next() {
+		n.Mu.RLock()
+		switch mode {
+		case DegreeIn:
+			degrees[n.ID] = float64(len(n.InEdges))
+		case DegreeOut:
+			degrees[n.ID] = float64(len(n.OutEdges))
+		case DegreeAll:
+			degrees[n.ID] = float64(len(n.InEdges) + len(n.OutEdges))
+		}
+		n.Mu.RUnlock()
+	}
+	iter.Close()
+
+	others := float64(len(degrees) - 1)
+	for id, d := range degrees {
+		if others > 0 {
+			degrees[id] = d / others
+		} else {
+			degrees[id] = 0
+		}
+	}
+	return degrees, nil
+}
+
+// DegreeRank returns the k nodes with the highest degree centrality,
+// highest first, ties broken by ID. k <= 0 ranks every node.
+func DegreeRank(g *storage.Graph, mode DegreeMode, k int) ([]RankedNode, error) {
+	degrees, err := DegreeCentrality(g, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]RankedNode, 0, len(degrees))
+	for id, d := range degrees {
+		ranked = append(ranked, RankedNode{ID: id, Degree: d})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Degree != ranked[j].Degree {
+			return ranked[i].Degree > ranked[j].Degree
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+
+	if k > 0 && k < len(ranked) {
+		ranked = ranked[:k]
+	}
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+	return ranked, nil
+}
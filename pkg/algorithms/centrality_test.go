@@ -3,6 +3,7 @@ package algorithms
 import (
 	"testing"
 
+	"github.com/fnuworsu/rdgDB/internal/graph"
 	"github.com/fnuworsu/rdgDB/pkg/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -74,3 +75,179 @@ func TestPageRank_Empty(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, scores)
 }
+
+func TestBetweennessCentrality_Path(t *testing.T) {
+	g := storage.NewGraph()
+
+	// Path A -> B -> C: B lies on the only A..C shortest path
+	a, _ := g.AddNode("Node", nil)
+	b, _ := g.AddNode("Node", nil)
+	c, _ := g.AddNode("Node", nil)
+	g.AddEdge(a.ID, b.ID, "LINK", nil)
+	g.AddEdge(b.ID, c.ID, "LINK", nil)
+
+	scores, err := BetweennessCentrality(g)
+	require.NoError(t, err)
+
+	assert.Equal(t, 0.0, scores[a.ID])
+	assert.Equal(t, 1.0, scores[b.ID])
+	assert.Equal(t, 0.0, scores[c.ID])
+}
+
+// addUndirected links a and b in both directions
+func addUndirected(g *storage.Graph, a, b graph.NodeID) {
+	g.AddEdge(a, b, "LINK", nil)
+	g.AddEdge(b, a, "LINK", nil)
+}
+
+func TestClosenessCentrality_Path(t *testing.T) {
+	g := storage.NewGraph()
+
+	// Undirected path A - B - C - D - E
+	var ids []graph.NodeID
+	for i := 0; i < 5; i++ {
+		n, _ := g.AddNode("Node", nil)
+		ids = append(ids, n.ID)
+	}
+	for i := 0; i < len(ids)-1; i++ {
+		addUndirected(g, ids[i], ids[i+1])
+	}
+
+	scores, err := ClosenessCentrality(g, DefaultClosenessConfig())
+	require.NoError(t, err)
+
+	// Distances from C sum to 1+1+2+2 = 6, from A to 1+2+3+4 = 10
+	assert.InDelta(t, 4.0/6.0, scores[ids[2]], 1e-9)
+	assert.InDelta(t, 4.0/10.0, scores[ids[0]], 1e-9)
+	assert.InDelta(t, scores[ids[0]], scores[ids[4]], 1e-9)
+	for _, id := range ids {
+		if id != ids[2] {
+			assert.Greater(t, scores[ids[2]], scores[id])
+		}
+	}
+}
+
+func TestClosenessCentrality_Star(t *testing.T) {
+	g := storage.NewGraph()
+
+	center, _ := g.AddNode("Center", nil)
+	var leaves []graph.NodeID
+	for i := 0; i < 4; i++ {
+		leaf, _ := g.AddNode("Leaf", nil)
+		addUndirected(g, center.ID, leaf.ID)
+		leaves = append(leaves, leaf.ID)
+	}
+
+	scores, err := ClosenessCentrality(g, DefaultClosenessConfig())
+	require.NoError(t, err)
+
+	// The center reaches every leaf in one hop; a leaf needs 1 + 3*2 = 7
+	assert.InDelta(t, 1.0, scores[center.ID], 1e-9)
+	for _, leaf := range leaves {
+		assert.InDelta(t, 4.0/7.0, scores[leaf], 1e-9)
+	}
+}
+
+func TestClosenessCentrality_Disconnected(t *testing.T) {
+	g := storage.NewGraph()
+
+	// A - B plus an isolated node C
+	a, _ := g.AddNode("Node", nil)
+	b, _ := g.AddNode("Node", nil)
+	c, _ := g.AddNode("Node", nil)
+	addUndirected(g, a.ID, b.ID)
+
+	normalized, err := ClosenessCentrality(g, DefaultClosenessConfig())
+	require.NoError(t, err)
+
+	// A reaches 1 of 2 other nodes at distance 1: 1^2 / (2*1)
+	assert.InDelta(t, 0.5, normalized[a.ID], 1e-9)
+	assert.InDelta(t, 0.5, normalized[b.ID], 1e-9)
+	assert.Equal(t, 0.0, normalized[c.ID])
+
+	raw, err := ClosenessCentrality(g, ClosenessConfig{})
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, raw[a.ID], 1e-9)
+	assert.Equal(t, 0.0, raw[c.ID])
+
+	empty, err := ClosenessCentrality(storage.NewGraph(), DefaultClosenessConfig())
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestDegreeCentrality_Star(t *testing.T) {
+	g := storage.NewGraph()
+
+	center, _ := g.AddNode("Center", nil)
+	var leaves []graph.NodeID
+	for i := 0; i < 4; i++ {
+		leaf, _ := g.AddNode("Leaf", nil)
+		g.AddEdge(center.ID, leaf.ID, "LINK", nil)
+		leaves = append(leaves, leaf.ID)
+	}
+
+	out, err := DegreeCentrality(g, DegreeOut)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, out[center.ID])
+	assert.Equal(t, 0.0, out[leaves[0]])
+
+	in, err := DegreeCentrality(g, DegreeIn)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, in[center.ID])
+	assert.Equal(t, 0.25, in[leaves[0]])
+
+	all, err := DegreeCentrality(g, DegreeAll)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, all[center.ID])
+	assert.Equal(t, 0.25, all[leaves[0]])
+
+	_, err = DegreeCentrality(g, DegreeMode(7))
+	assert.Error(t, err)
+}
+
+func TestDegreeCentrality_Path(t *testing.T) {
+	g := storage.NewGraph()
+
+	// A -> B -> C -> D
+	var ids []graph.NodeID
+	for i := 0; i < 4; i++ {
+		n, _ := g.AddNode("Node", nil)
+		ids = append(ids, n.ID)
+	}
+	for i := 0; i < len(ids)-1; i++ {
+		g.AddEdge(ids[i], ids[i+1], "LINK", nil)
+	}
+
+	scores, err := DegreeCentrality(g, DegreeAll)
+	require.NoError(t, err)
+
+	ends := []graph.NodeID{ids[0], ids[3]}
+	for _, end := range ends {
+		assert.InDelta(t, 1.0/3.0, scores[end], 1e-9)
+		for _, inner := range ids[1:3] {
+			assert.Less(t, scores[end], scores[inner])
+		}
+	}
+
+	ranked, err := DegreeRank(g, DegreeAll, 3)
+	require.NoError(t, err)
+	require.Len(t, ranked, 3)
+	assert.Equal(t, RankedNode{ID: ids[1], Degree: 2.0 / 3.0, Rank: 1}, ranked[0])
+	assert.Equal(t, ids[2], ranked[1].ID)
+	assert.Equal(t, ids[0], ranked[2].ID)
+	assert.Equal(t, 3, ranked[2].Rank)
+
+	all, err := DegreeRank(g, DegreeOut, 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+	assert.Equal(t, ids[3], all[3].ID)
+}
+
+func TestDegreeCentrality_SingleNode(t *testing.T) {
+	g := storage.NewGraph()
+	n, _ := g.AddNode("Node", nil)
+
+	scores, err := DegreeCentrality(g, DegreeAll)
+	require.NoError(t, err)
+	assert.Equal(t, map[graph.NodeID]float64{n.ID: 0}, scores)
+}
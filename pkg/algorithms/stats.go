@@ -0,0 +1,44 @@
+package algorithms
+
+import (
+	"fmt"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// Register algorithms usable with PersistentGraph.MaterializeStats
+func init() {
+	storage.RegisterStatAlgorithm("pagerank", pageRankStat)
+	storage.RegisterStatAlgorithm("betweenness", betweennessStat)
+	storage.RegisterStatAlgorithm("degree", degreeStat)
+}
+
+func pageRankStat(g *storage.Graph, config interface{}) (map[graph.NodeID]float64, error) {
+	switch c := config.(type) {
+	case nil:
+		return PageRank(g, DefaultPageRankConfig())
+	case PageRankConfig:
+		return PageRank(g, c)
+	case *PageRankConfig:
+		return PageRank(g, *c)
+	}
+	return nil, fmt.Errorf("pagerank: unsupported config type %T", config)
+}
+
+func betweennessStat(g *storage.Graph, config interface{}) (map[graph.NodeID]float64, error) {
+	return BetweennessCentrality(g)
+}
+
+// degreeStat scores each node by its total (in + out) degree
+func degreeStat(g *storage.Graph, config interface{}) (map[graph.NodeID]float64, error) {
+	scores := make(map[graph.NodeID]float64)
+	iter := g.Nodes()
+	for n, ok := iter.Next(); ok; n, ok = iter.Next() {
+		n.Mu.RLock()
+		scores[n.ID] = float64(len(n.OutEdges) + len(n.InEdges))
+		n.Mu.RUnlock()
+	}
+	iter.Close()
+	return scores, nil
+}
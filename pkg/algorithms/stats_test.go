@@ -0,0 +1,50 @@
+package algorithms
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaterializeStats_Algorithms(t *testing.T) {
+	pg, err := storage.NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+
+	center, _ := pg.AddNode("Center", nil)
+	leaf, _ := pg.AddNode("Leaf", nil)
+	pg.AddEdge(leaf.ID, center.ID, "LINK", nil)
+	for i := 0; i < 2; i++ {
+		other, _ := pg.AddNode("Leaf", nil)
+		pg.AddEdge(other.ID, center.ID, "LINK", nil)
+	}
+
+	config := DefaultPageRankConfig()
+	require.NoError(t, pg.MaterializeStats("pagerank", config))
+	require.NoError(t, pg.MaterializeStats("betweenness", nil))
+	require.NoError(t, pg.MaterializeStats("degree", nil))
+	require.NoError(t, pg.WaitForStats())
+
+	centerRank, ok := center.GetProperty("_stat_pagerank")
+	require.True(t, ok)
+	leafRank, _ := leaf.GetProperty("_stat_pagerank")
+	assert.Greater(t, centerRank.(float64), leafRank.(float64))
+
+	_, ok = center.GetProperty("_stat_betweenness")
+	assert.True(t, ok)
+
+	degree, _ := center.GetProperty("_stat_degree")
+	assert.Equal(t, 3.0, degree)
+}
+
+func TestMaterializeStats_BadConfig(t *testing.T) {
+	pg, err := storage.NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+
+	pg.AddNode("Node", nil)
+	require.NoError(t, pg.MaterializeStats("pagerank", "not a config"))
+	assert.Error(t, pg.WaitForStats())
+}
@@ -0,0 +1,132 @@
+package algorithms
+
+import (
+	"sort"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// DegreeSummary describes the distribution of total (in + out) degree
+type DegreeSummary struct {
+	Average float64
+	Max     int
+	MaxNode graph.NodeID // A node with the maximum degree, lowest ID first
+}
+
+// DegreeStats returns the average and maximum total degree. An empty
+// graph has a zero summary.
+func DegreeStats(g *storage.Graph) DegreeSummary {
+	var summary DegreeSummary
+	total, count := 0, 0
+
+	iter := g.Nodes()
+	for n, ok := iter.Next(); ok; n, ok = iter.Next() {
+		n.Mu.RLock()
+		degree := len(n.OutEdges) + len(n.InEdges)
+		n.Mu.RUnlock()
+
+		total += degree
+		count++
+		if degree > summary.Max || degree == summary.Max && (count == 1 || n.ID < summary.MaxNode) {
+			summary.Max = degree
+			summary.MaxNode = n.ID
+		}
+	}
+	iter.Close()
+
+	if count > 0 {
+		summary.Average = float64(total) / float64(count)
+	}
+	return summary
+}
+
+// Density returns the fraction of possible directed edges present,
+// E / (N * (N-1)). Graphs with fewer than two nodes have density 0.
+func Density(g *storage.Graph) float64 {
+	n := g.NodeCount()
+	if n < 2 {
+		return 0
+	}
+	return float64(g.EdgeCount()) / (float64(n) * float64(n-1))
+}
+
+// IsDAG reports whether the graph has no directed cycles, using Kahn's
+// algorithm. Self-loops count as cycles.
+func IsDAG(g *storage.Graph) bool {
+	inDegree := make(map[graph.NodeID]int)
+	var queue []graph.NodeID
+	iter := g.Nodes()
+	for n, ok := iter.Next(); ok; n, ok = iter.Next() {
+		n.Mu.RLock()
+		inDegree[n.ID] = len(n.InEdges)
+		n.Mu.RUnlock()
+		if inDegree[n.ID] == 0 {
+			queue = append(queue, n.ID)
+		}
+	}
+	iter.Close()
+
+	visited := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		visited++
+
+		adjacent, err := g.GetAdjacent(id, graph.Outgoing)
+		if err != nil {
+			continue
+		}
+		for _, adj := range adjacent {
+			target := adj.Node.ID
+			inDegree[target]--
+			if inDegree[target] == 0 {
+				queue = append(queue, target)
+			}
+		}
+	}
+	return visited == len(inDegree)
+}
+
+// WeaklyConnectedComponents groups nodes connected when edge direction is
+// ignored. Components are ordered largest first, ties broken by their
+// lowest node ID, and each lists its node IDs in ascending order.
+func WeaklyConnectedComponents(g *storage.Graph) [][]graph.NodeID {
+	var nodes []graph.NodeID
+	iter := g.Nodes()
+	for n, ok := iter.Next(); ok; n, ok = iter.Next() {
+		nodes = append(nodes, n.ID)
+	}
+	iter.Close()
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+
+	seen := make(map[graph.NodeID]bool, len(nodes))
+	var components [][]graph.NodeID
+
+	for _, start := range nodes {
+		if seen[start] {
+			continue
+		}
+		seen[start] = true
+		component := []graph.NodeID{start}
+
+		for i := 0; i < len(component); i++ {
+			adjacent, _ := g.GetAdjacent(component[i], graph.Both)
+			for _, adj := range adjacent {
+				neighbor := adj.Node
+				if !seen[neighbor.ID] {
+					seen[neighbor.ID] = true
+					component = append(component, neighbor.ID)
+				}
+			}
+		}
+
+		sort.Slice(component, func(i, j int) bool { return component[i] < component[j] })
+		components = append(components, component)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool {
+		return len(components[i]) > len(components[j])
+	})
+	return components
+}
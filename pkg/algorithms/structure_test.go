@@ -0,0 +1,74 @@
+package algorithms
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDegreeStatsAndDensity(t *testing.T) {
+	g := storage.NewGraph()
+	assert.Equal(t, DegreeSummary{}, DegreeStats(g))
+	assert.Equal(t, 0.0, Density(g))
+
+	// A -> B, A -> C, B -> C
+	a, _ := g.AddNode("Node", nil)
+	b, _ := g.AddNode("Node", nil)
+	c, _ := g.AddNode("Node", nil)
+	g.AddEdge(a.ID, b.ID, "LINK", nil)
+	g.AddEdge(a.ID, c.ID, "LINK", nil)
+	g.AddEdge(b.ID, c.ID, "LINK", nil)
+
+	stats := DegreeStats(g)
+	assert.Equal(t, 2.0, stats.Average)
+	assert.Equal(t, 2, stats.Max)
+	assert.Equal(t, a.ID, stats.MaxNode)
+
+	// 3 of 6 possible directed edges
+	assert.Equal(t, 0.5, Density(g))
+}
+
+func TestIsDAG(t *testing.T) {
+	g := storage.NewGraph()
+	assert.True(t, IsDAG(g))
+
+	a, _ := g.AddNode("Node", nil)
+	b, _ := g.AddNode("Node", nil)
+	c, _ := g.AddNode("Node", nil)
+	g.AddEdge(a.ID, b.ID, "LINK", nil)
+	g.AddEdge(a.ID, c.ID, "LINK", nil)
+	g.AddEdge(b.ID, c.ID, "LINK", nil)
+	assert.True(t, IsDAG(g))
+
+	back, _ := g.AddEdge(c.ID, a.ID, "LINK", nil)
+	assert.False(t, IsDAG(g))
+
+	g.DeleteEdge(back.ID)
+	g.AddEdge(b.ID, b.ID, "LINK", nil)
+	assert.False(t, IsDAG(g))
+}
+
+func TestWeaklyConnectedComponents(t *testing.T) {
+	g := storage.NewGraph()
+
+	// {A, B, C} joined regardless of direction, {D, E}, and isolated F
+	var ids []graph.NodeID
+	for i := 0; i < 6; i++ {
+		n, _ := g.AddNode("Node", nil)
+		ids = append(ids, n.ID)
+	}
+	g.AddEdge(ids[0], ids[1], "LINK", nil)
+	g.AddEdge(ids[2], ids[1], "LINK", nil)
+	g.AddEdge(ids[4], ids[3], "LINK", nil)
+
+	components := WeaklyConnectedComponents(g)
+	assert.Equal(t, [][]graph.NodeID{
+		{ids[0], ids[1], ids[2]},
+		{ids[3], ids[4]},
+		{ids[5]},
+	}, components)
+
+	assert.Empty(t, WeaklyConnectedComponents(storage.NewGraph()))
+}
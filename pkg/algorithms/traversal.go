@@ -55,19 +55,15 @@ func BFS(g *storage.Graph, startNode graph.NodeID, targetNode *graph.NodeID, max
 			return result, nil
 		}
 
-		neighbors, err := g.GetNeighbors(current)
-		if err != nil {
-			continue
-		}
-
-		for _, neighbor := range neighbors {
+		g.IterateNeighbors(current, graph.Outgoing, func(_ *graph.Edge, neighbor *graph.Node) bool {
 			if !visited[neighbor.ID] {
 				visited[neighbor.ID] = true
 				parentMap[neighbor.ID] = current
 				distanceMap[neighbor.ID] = dist + 1
 				queue = append(queue, neighbor.ID)
 			}
-		}
+			return true
+		})
 	}
 
 	return result, nil
@@ -119,12 +115,13 @@ func dfsRecursive(
 		return false
 	}
 
-	neighbors, err := g.GetNeighbors(current)
+	adjacent, err := g.GetAdjacent(current, graph.Outgoing)
 	if err != nil {
 		return false
 	}
 
-	for _, neighbor := range neighbors {
+	for _, adj := range adjacent {
+		neighbor := adj.Node
 		if !visited[neighbor.ID] {
 			parentMap[neighbor.ID] = current
 			if dfsRecursive(g, neighbor.ID, target, maxDepth, currentDepth+1, visited, parentMap, result) {
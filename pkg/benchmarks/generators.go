@@ -0,0 +1,160 @@
+// Package benchmarks provides standardized workloads for measuring graph
+// storage, query and algorithm performance
+package benchmarks
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// DefaultSeed is the seed benchmark suites generate graphs with unless
+// told otherwise, so repeated runs benchmark the same graph
+const DefaultSeed int64 = 42
+
+// Labels used by generated graphs
+const (
+	NodeLabel = "Node"
+	EdgeLabel = "LINKS"
+)
+
+// GenerateScaleFreeGraph builds an n-node graph with the Barabási-Albert
+// model: it starts from m+1 fully-connected nodes, and every further node
+// links to m distinct existing nodes chosen with probability proportional
+// to their degree, giving a power-law degree distribution. Edges point
+// from newer to older nodes. Each node has an "id" (its insertion order)
+// and a "group" (id mod 10) property. The same seed always produces the
+// same graph.
+func GenerateScaleFreeGraph(n, m int, seed int64) (*storage.Graph, error) {
+	if m < 1 || m >= n {
+		return nil, fmt.Errorf("scale-free graph needs 1 <= m < n, got n=%d m=%d", n, m)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	g, ids := addGeneratedNodes(n, nodeProperties)
+
+	// Every edge endpoint is listed once, so sampling this slice picks
+	// nodes in proportion to their degree
+	endpoints := make([]int, 0, m*(m+1)+2*m*(n-m-1))
+	link := func(from, to int) error {
+		if _, err := g.AddEdge(ids[from], ids[to], EdgeLabel, nil); err != nil {
+			return fmt.Errorf("failed to add edge: %w", err)
+		}
+		endpoints = append(endpoints, from, to)
+		return nil
+	}
+
+	for i := 1; i <= m; i++ {
+		for j := 0; j < i; j++ {
+			if err := link(i, j); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	chosen := make(map[int]struct{}, m)
+	targets := make([]int, 0, m)
+	for i := m + 1; i < n; i++ {
+		for k := range chosen {
+			delete(chosen, k)
+		}
+		targets = targets[:0]
+		for len(targets) < m {
+			t := endpoints[rng.Intn(len(endpoints))]
+			if _, dup := chosen[t]; dup {
+				continue
+			}
+			chosen[t] = struct{}{}
+			targets = append(targets, t)
+		}
+		for _, t := range targets {
+			if err := link(i, t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// GenerateERGraph builds an n-node graph with the Erdős-Rényi model: each
+// pair of nodes is linked with probability p, independently of the rest,
+// so degrees cluster around p*(n-1). Edges point from the older node of a
+// pair to the newer one. Nodes have the same properties as in
+// GenerateScaleFreeGraph, and the same seed always produces the same graph.
+func GenerateERGraph(n int, p float64, seed int64) (*storage.Graph, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("random graph needs n >= 0, got %d", n)
+	}
+	if p < 0 || p > 1 {
+		return nil, fmt.Errorf("random graph needs 0 <= p <= 1, got %g", p)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	g, ids := addGeneratedNodes(n, nodeProperties)
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if rng.Float64() >= p {
+				continue
+			}
+			if _, err := g.AddEdge(ids[i], ids[j], EdgeLabel, nil); err != nil {
+				return nil, fmt.Errorf("failed to add edge: %w", err)
+			}
+		}
+	}
+	return g, nil
+}
+
+// GenerateGridGraph builds a rows x cols lattice, with an edge from each
+// node to the node on its right and the node below it. Nodes are added row
+// by row and have "row" and "col" properties besides those of
+// GenerateScaleFreeGraph, so the shortest path between two nodes is their
+// Manhattan distance when they can be reached at all.
+func GenerateGridGraph(rows, cols int) (*storage.Graph, error) {
+	if rows < 1 || cols < 1 {
+		return nil, fmt.Errorf("grid graph needs at least one row and column, got %dx%d", rows, cols)
+	}
+
+	g, ids := addGeneratedNodes(rows*cols, func(i int) graph.Properties {
+		props := nodeProperties(i)
+		props["row"] = i / cols
+		props["col"] = i % cols
+		return props
+	})
+
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			i := r*cols + c
+			if c+1 < cols {
+				if _, err := g.AddEdge(ids[i], ids[i+1], EdgeLabel, nil); err != nil {
+					return nil, fmt.Errorf("failed to add edge: %w", err)
+				}
+			}
+			if r+1 < rows {
+				if _, err := g.AddEdge(ids[i], ids[i+cols], EdgeLabel, nil); err != nil {
+					return nil, fmt.Errorf("failed to add edge: %w", err)
+				}
+			}
+		}
+	}
+	return g, nil
+}
+
+// addGeneratedNodes creates a graph of n nodes, the i-th with the given
+// properties, and returns their IDs in insertion order
+func addGeneratedNodes(n int, properties func(i int) graph.Properties) (*storage.Graph, []graph.NodeID) {
+	g := storage.NewGraph()
+	ids := make([]graph.NodeID, n)
+	for i := 0; i < n; i++ {
+		node, _ := g.AddNode(NodeLabel, properties(i))
+		ids[i] = node.ID
+	}
+	return g, ids
+}
+
+// nodeProperties returns the properties of the i-th generated node
+func nodeProperties(i int) graph.Properties {
+	return graph.Properties{"id": i, "group": i % 10}
+}
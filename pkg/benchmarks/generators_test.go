@@ -0,0 +1,163 @@
+package benchmarks
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/algorithms"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// edgeList returns the source and target of every edge ordered by edge ID
+func edgeList(g *storage.Graph) [][2]graph.NodeID {
+	edges := make([][2]graph.NodeID, g.EdgeCount())
+	g.IterateEdges(func(e *graph.Edge) bool {
+		edges[e.ID-1] = [2]graph.NodeID{e.Source, e.Target}
+		return true
+	})
+	return edges
+}
+
+// degreeStats returns the mean, median and maximum number of edges per node
+func degreeStats(g *storage.Graph) (mean float64, median, max int) {
+	var degrees []int
+	g.IterateNodes(func(n *graph.Node) bool {
+		n.Mu.RLock()
+		degrees = append(degrees, len(n.OutEdges)+len(n.InEdges))
+		n.Mu.RUnlock()
+		return true
+	})
+	sort.Ints(degrees)
+	return 2 * float64(g.EdgeCount()) / float64(len(degrees)), degrees[len(degrees)/2], degrees[len(degrees)-1]
+}
+
+func TestGenerateScaleFreeGraph(t *testing.T) {
+	g, err := GenerateScaleFreeGraph(200, 3, DefaultSeed)
+	require.NoError(t, err)
+	assert.Equal(t, 200, g.NodeCount())
+	assert.Equal(t, 3*4/2+3*(200-4), g.EdgeCount())
+
+	// The first m+1 nodes are fully connected, each linking to all older
+	// ones
+	for i := graph.NodeID(1); i <= 4; i++ {
+		neighbors, err := g.GetNeighbors(i)
+		require.NoError(t, err)
+		ids := make([]graph.NodeID, 0, len(neighbors))
+		for _, n := range neighbors {
+			ids = append(ids, n.ID)
+		}
+		assert.ElementsMatch(t, []graph.NodeID{1, 2, 3}[:i-1], ids)
+	}
+
+	// Each later node links to distinct targets
+	for i := graph.NodeID(5); i <= 200; i++ {
+		neighbors, err := g.GetNeighbors(i)
+		require.NoError(t, err)
+		seen := make(map[graph.NodeID]bool)
+		for _, n := range neighbors {
+			assert.False(t, seen[n.ID])
+			seen[n.ID] = true
+		}
+		assert.Len(t, neighbors, 3)
+	}
+}
+
+func TestGenerateScaleFreeGraph_DegreeDistribution(t *testing.T) {
+	// Preferential attachment concentrates links on a few hubs, so degrees
+	// are right-skewed: the median sits below the mean and the largest
+	// degree is far above it
+	ba, err := GenerateScaleFreeGraph(2000, 2, DefaultSeed)
+	require.NoError(t, err)
+	mean, median, max := degreeStats(ba)
+	assert.Less(t, float64(median), mean)
+	assert.Greater(t, float64(max), 10*mean)
+
+	// A random graph of the same density has no hubs
+	er, err := GenerateERGraph(2000, mean/1999, DefaultSeed)
+	require.NoError(t, err)
+	erMean, erMedian, erMax := degreeStats(er)
+	assert.InDelta(t, mean, erMean, 0.5)
+	assert.InDelta(t, erMean, float64(erMedian), 1)
+	assert.Less(t, float64(erMax), 5*erMean)
+}
+
+func TestGenerateERGraph(t *testing.T) {
+	g, err := GenerateERGraph(400, 0.05, DefaultSeed)
+	require.NoError(t, err)
+	assert.Equal(t, 400, g.NodeCount())
+	assert.InEpsilon(t, 0.05*400*399/2, g.EdgeCount(), 0.1)
+
+	// Edges run from older to newer nodes, never twice between a pair
+	pairs := make(map[[2]graph.NodeID]bool)
+	for _, edge := range edgeList(g) {
+		assert.Less(t, edge[0], edge[1])
+		assert.False(t, pairs[edge])
+		pairs[edge] = true
+	}
+
+	empty, err := GenerateERGraph(10, 0, DefaultSeed)
+	require.NoError(t, err)
+	assert.Equal(t, 0, empty.EdgeCount())
+	complete, err := GenerateERGraph(10, 1, DefaultSeed)
+	require.NoError(t, err)
+	assert.Equal(t, 45, complete.EdgeCount())
+}
+
+func TestGenerateGridGraph(t *testing.T) {
+	g, err := GenerateGridGraph(3, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 12, g.NodeCount())
+	assert.Equal(t, 3*3+4*2, g.EdgeCount())
+
+	corner, _ := g.GetNode(12)
+	row, _ := corner.GetProperty("row")
+	col, _ := corner.GetProperty("col")
+	assert.Equal(t, 2, row)
+	assert.Equal(t, 3, col)
+
+	// The far corner is as many hops away as it is rows and columns away
+	target := corner.ID
+	result, err := algorithms.BFS(g, 1, &target, 0)
+	require.NoError(t, err)
+	assert.True(t, result.Found)
+	assert.Equal(t, 5, result.Distance)
+
+	// Edges only run right and down
+	result, err = algorithms.BFS(g, corner.ID, nil, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []graph.NodeID{corner.ID}, result.VisitedOrder)
+}
+
+func TestGenerators_Deterministic(t *testing.T) {
+	generate := func(seed int64) ([][2]graph.NodeID, [][2]graph.NodeID) {
+		ba, err := GenerateScaleFreeGraph(100, 2, seed)
+		require.NoError(t, err)
+		er, err := GenerateERGraph(100, 0.1, seed)
+		require.NoError(t, err)
+		return edgeList(ba), edgeList(er)
+	}
+	ba, er := generate(7)
+	baAgain, erAgain := generate(7)
+	baOther, erOther := generate(8)
+
+	assert.Equal(t, ba, baAgain)
+	assert.Equal(t, er, erAgain)
+	assert.NotEqual(t, ba, baOther)
+	assert.NotEqual(t, er, erOther)
+}
+
+func TestGenerators_InvalidArgs(t *testing.T) {
+	_, err := GenerateScaleFreeGraph(5, 0, DefaultSeed)
+	assert.EqualError(t, err, "scale-free graph needs 1 <= m < n, got n=5 m=0")
+	_, err = GenerateScaleFreeGraph(3, 3, DefaultSeed)
+	assert.Error(t, err)
+	_, err = GenerateERGraph(-1, 0.5, DefaultSeed)
+	assert.EqualError(t, err, "random graph needs n >= 0, got -1")
+	_, err = GenerateERGraph(10, 1.5, DefaultSeed)
+	assert.EqualError(t, err, "random graph needs 0 <= p <= 1, got 1.5")
+	_, err = GenerateGridGraph(0, 4)
+	assert.EqualError(t, err, "grid graph needs at least one row and column, got 0x4")
+}
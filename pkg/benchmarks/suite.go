@@ -0,0 +1,134 @@
+package benchmarks
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/algorithms"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// DefaultEdgesPerNode is the Barabási-Albert m used when a suite sets none
+const DefaultEdgesPerNode = 3
+
+// BenchmarkSuite runs standard workloads against a generated scale-free
+// graph of N nodes. Each Run method regenerates G before timing starts,
+// then reports throughput as ops/s and the live heap as heap-MB alongside
+// the usual ns/op and allocation figures.
+type BenchmarkSuite struct {
+	G *storage.Graph
+	N int
+
+	M    int   // Edges per new node; 0 means DefaultEdgesPerNode
+	Seed int64 // 0 means DefaultSeed
+}
+
+// NewBenchmarkSuite returns a suite over graphs of n nodes
+func NewBenchmarkSuite(n int) *BenchmarkSuite {
+	return &BenchmarkSuite{N: n}
+}
+
+// setup generates a fresh graph and resets the benchmark timer
+func (s *BenchmarkSuite) setup(b *testing.B) {
+	b.Helper()
+	m := s.M
+	if m == 0 {
+		m = DefaultEdgesPerNode
+	}
+	seed := s.Seed
+	if seed == 0 {
+		seed = DefaultSeed
+	}
+
+	b.StopTimer()
+	g, err := GenerateScaleFreeGraph(s.N, m, seed)
+	if err != nil {
+		b.Fatal(err)
+	}
+	s.G = g
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.StartTimer()
+}
+
+// report records throughput and current heap usage
+func (s *BenchmarkSuite) report(b *testing.B) {
+	b.StopTimer()
+	if secs := b.Elapsed().Seconds(); secs > 0 {
+		b.ReportMetric(float64(b.N)/secs, "ops/s")
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	b.ReportMetric(float64(mem.HeapAlloc)/(1<<20), "heap-MB")
+}
+
+// RunInsertBenchmark measures adding a node linked to an existing node
+func (s *BenchmarkSuite) RunInsertBenchmark(b *testing.B) {
+	s.setup(b)
+	for i := 0; i < b.N; i++ {
+		node, err := s.G.AddNode(NodeLabel, graph.Properties{"id": s.N + i, "group": i % 10})
+		if err != nil {
+			b.Fatal(err)
+		}
+		target := graph.NodeID(i%s.N + 1)
+		if _, err := s.G.AddEdge(node.ID, target, EdgeLabel, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+	s.report(b)
+}
+
+// RunQueryBenchmark measures executing queryStr. The query is parsed once
+// before timing starts.
+func (s *BenchmarkSuite) RunQueryBenchmark(b *testing.B, queryStr string) {
+	q, err := query.NewParser(queryStr).Parse()
+	if err != nil {
+		b.Fatalf("failed to parse query: %v", err)
+	}
+
+	s.setup(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := q.Execute(s.G); err != nil {
+			b.Fatal(err)
+		}
+	}
+	s.report(b)
+}
+
+// RunTraversalBenchmark measures a full traversal with algo ("bfs" or
+// "dfs"), cycling through start nodes from the newest down. Generated
+// edges point from newer to older nodes, so new nodes reach the most.
+func (s *BenchmarkSuite) RunTraversalBenchmark(b *testing.B, algo string) {
+	var traverse func(*storage.Graph, graph.NodeID, *graph.NodeID, int) (*algorithms.TraversalResult, error)
+	switch algo {
+	case "bfs":
+		traverse = algorithms.BFS
+	case "dfs":
+		traverse = algorithms.DFS
+	default:
+		b.Fatalf("unknown traversal algorithm: %s", algo)
+	}
+
+	s.setup(b)
+	for i := 0; i < b.N; i++ {
+		start := graph.NodeID(s.N - i%s.N)
+		if _, err := traverse(s.G, start, nil, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+	s.report(b)
+}
+
+// RunPageRankBenchmark measures PageRank with the default configuration
+func (s *BenchmarkSuite) RunPageRankBenchmark(b *testing.B) {
+	s.setup(b)
+	for i := 0; i < b.N; i++ {
+		if _, err := algorithms.PageRank(s.G, algorithms.DefaultPageRankConfig()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	s.report(b)
+}
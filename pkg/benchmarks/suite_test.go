@@ -0,0 +1,31 @@
+package benchmarks
+
+import "testing"
+
+// Run with: go test ./pkg/benchmarks -run '^$' -bench .
+
+const benchmarkNodes = 10000
+
+func BenchmarkInsert(b *testing.B) {
+	NewBenchmarkSuite(benchmarkNodes).RunInsertBenchmark(b)
+}
+
+func BenchmarkQuery_LabelScan(b *testing.B) {
+	NewBenchmarkSuite(benchmarkNodes).RunQueryBenchmark(b, `MATCH (n:Node) WHERE n.group = 3 RETURN n.id`)
+}
+
+func BenchmarkQuery_Expand(b *testing.B) {
+	NewBenchmarkSuite(benchmarkNodes).RunQueryBenchmark(b, `MATCH (a:Node)-[:LINKS]->(b) WHERE a.id = 500 RETURN b.id`)
+}
+
+func BenchmarkTraversal_BFS(b *testing.B) {
+	NewBenchmarkSuite(benchmarkNodes).RunTraversalBenchmark(b, "bfs")
+}
+
+func BenchmarkTraversal_DFS(b *testing.B) {
+	NewBenchmarkSuite(benchmarkNodes).RunTraversalBenchmark(b, "dfs")
+}
+
+func BenchmarkPageRank(b *testing.B) {
+	NewBenchmarkSuite(benchmarkNodes).RunPageRankBenchmark(b)
+}
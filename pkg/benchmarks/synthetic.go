@@ -0,0 +1,92 @@
+package benchmarks
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// DegreeDistribution decides how GenerateSynthetic picks edge targets
+type DegreeDistribution string
+
+const (
+	// Uniform picks every target with equal probability
+	Uniform DegreeDistribution = "uniform"
+
+	// PowerLaw picks targets from a Zipf distribution over node order, so
+	// a few early nodes become hubs with most of the in-degree
+	PowerLaw DegreeDistribution = "powerlaw"
+)
+
+// DefaultSkew is the Zipf exponent used when SyntheticConfig.Skew is unset
+const DefaultSkew = 1.5
+
+// SyntheticConfig describes a generated graph
+type SyntheticConfig struct {
+	Nodes        int
+	Edges        int
+	Distribution DegreeDistribution // Empty means Uniform
+	Skew         float64            // Zipf exponent for PowerLaw, > 1; 0 means DefaultSkew
+	Seed         int64              // 0 means DefaultSeed
+}
+
+// GraphWriter is the part of a graph GenerateSynthetic writes to.
+// *storage.Graph and *storage.PersistentGraph both implement it.
+type GraphWriter interface {
+	AddNode(label string, properties graph.Properties) (*graph.Node, error)
+	AddEdge(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error)
+}
+
+// GenerateSynthetic adds cfg.Nodes nodes and cfg.Edges edges to w. Nodes
+// get the same "id" and "group" properties as GenerateScaleFreeGraph.
+// Edge sources are uniform and targets follow cfg.Distribution; there
+// are no self-loops. The same config always produces the same graph.
+func GenerateSynthetic(w GraphWriter, cfg SyntheticConfig) error {
+	if cfg.Nodes < 2 && cfg.Edges > 0 {
+		return fmt.Errorf("edges need at least 2 nodes, got %d", cfg.Nodes)
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = DefaultSeed
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	var target func() int
+	switch cfg.Distribution {
+	case Uniform, "":
+		target = func() int { return rng.Intn(cfg.Nodes) }
+	case PowerLaw:
+		skew := cfg.Skew
+		if skew == 0 {
+			skew = DefaultSkew
+		}
+		if skew <= 1 {
+			return fmt.Errorf("power-law skew must be greater than 1, got %v", skew)
+		}
+		zipf := rand.NewZipf(rng, skew, 1, uint64(cfg.Nodes-1))
+		target = func() int { return int(zipf.Uint64()) }
+	default:
+		return fmt.Errorf("unknown degree distribution %q", cfg.Distribution)
+	}
+
+	ids := make([]graph.NodeID, cfg.Nodes)
+	for i := range ids {
+		node, err := w.AddNode(NodeLabel, graph.Properties{"id": i, "group": i % 10})
+		if err != nil {
+			return fmt.Errorf("failed to add node %d: %w", i, err)
+		}
+		ids[i] = node.ID
+	}
+
+	for i := 0; i < cfg.Edges; i++ {
+		from, to := rng.Intn(cfg.Nodes), target()
+		if from == to {
+			to = (to + 1) % cfg.Nodes
+		}
+		if _, err := w.AddEdge(ids[from], ids[to], EdgeLabel, nil); err != nil {
+			return fmt.Errorf("failed to add edge %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package benchmarks
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSynthetic(t *testing.T) {
+	g := storage.NewGraph()
+	require.NoError(t, GenerateSynthetic(g, SyntheticConfig{Nodes: 100, Edges: 500}))
+	assert.Equal(t, 100, g.NodeCount())
+	assert.Equal(t, 500, g.EdgeCount())
+
+	g.IterateEdges(func(e *graph.Edge) bool {
+		assert.NotEqual(t, e.Source, e.Target)
+		return true
+	})
+
+	// The same config generates the same graph
+	again := storage.NewGraph()
+	require.NoError(t, GenerateSynthetic(again, SyntheticConfig{Nodes: 100, Edges: 500}))
+	assert.Equal(t, edgeList(g), edgeList(again))
+}
+
+func TestGenerateSynthetic_PowerLaw(t *testing.T) {
+	maxInDegree := func(dist DegreeDistribution) int {
+		g := storage.NewGraph()
+		require.NoError(t, GenerateSynthetic(g, SyntheticConfig{Nodes: 1000, Edges: 5000, Distribution: dist, Seed: 3}))
+		most := 0
+		g.IterateNodes(func(n *graph.Node) bool {
+			if len(n.InEdges) > most {
+				most = len(n.InEdges)
+			}
+			return true
+		})
+		return most
+	}
+
+	// Uniform targets average 5 in-edges; power-law ones form hubs
+	assert.Less(t, maxInDegree(Uniform), 20)
+	assert.Greater(t, maxInDegree(PowerLaw), 500)
+}
+
+func TestGenerateSynthetic_InvalidConfig(t *testing.T) {
+	for _, cfg := range []SyntheticConfig{
+		{Nodes: 1, Edges: 1},
+		{Nodes: 10, Edges: 10, Distribution: "normal"},
+		{Nodes: 10, Edges: 10, Distribution: PowerLaw, Skew: 1},
+	} {
+		assert.Error(t, GenerateSynthetic(storage.NewGraph(), cfg), "%+v", cfg)
+	}
+}
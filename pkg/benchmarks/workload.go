@@ -0,0 +1,308 @@
+package benchmarks
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/fnuworsu/rdgDB/pkg/server"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// ErrWritesUnsupported is returned by targets that can only run queries
+var ErrWritesUnsupported = errors.New("target does not support writes")
+
+// DefaultWorkload is run when a workload lists no queries. $id is bound
+// to a random node "id" for every execution.
+var DefaultWorkload = []string{
+	`MATCH (n:Node) WHERE n.id = $id RETURN n.group`,
+	`MATCH (a:Node)-[:LINKS]->(b) WHERE a.id = $id RETURN b.id`,
+}
+
+// Target is a graph a workload runs against
+type Target interface {
+	// Query runs an RQL query with the given parameters
+	Query(input string, parameters map[string]interface{}) error
+
+	// Write adds a node with the given "id" and links it to the node
+	// with ID target
+	Write(id int, target graph.NodeID) error
+}
+
+// EmbeddedTarget runs a workload in-process against a graph
+type EmbeddedTarget struct {
+	Graph *storage.PersistentGraph
+}
+
+// Query implements Target
+func (t *EmbeddedTarget) Query(input string, parameters map[string]interface{}) error {
+	q, err := query.NewParser(input).Parse()
+	if err != nil {
+		return err
+	}
+	q.Parameters = parameters
+	_, err = q.Execute(t.Graph)
+	return err
+}
+
+// Write implements Target
+func (t *EmbeddedTarget) Write(id int, target graph.NodeID) error {
+	node, err := t.Graph.AddNode(NodeLabel, graph.Properties{"id": id, "group": id % 10})
+	if err != nil {
+		return err
+	}
+	_, err = t.Graph.AddEdge(node.ID, target, EdgeLabel, nil)
+	return err
+}
+
+// RemoteTarget runs a workload against a server's POST /query endpoint.
+// The server has no write endpoint, so only read-only workloads can run.
+type RemoteTarget struct {
+	URL    string       // Base URL, e.g. http://127.0.0.1:7474
+	Client *http.Client // Nil means http.DefaultClient
+}
+
+// Query implements Target
+func (t *RemoteTarget) Query(input string, parameters map[string]interface{}) error {
+	body, err := json.Marshal(server.QueryRequest{Query: input, Parameters: parameters})
+	if err != nil {
+		return err
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Post(strings.TrimSuffix(t.URL, "/")+"/query", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// Write implements Target
+func (t *RemoteTarget) Write(int, graph.NodeID) error {
+	return ErrWritesUnsupported
+}
+
+// WorkloadConfig describes a mixed read/write workload
+type WorkloadConfig struct {
+	Queries     []string // Read queries, picked at random; empty means DefaultWorkload
+	Operations  int      // Total reads and writes
+	Concurrency int      // Parallel workers; 0 means 1
+	WriteRatio  float64  // Fraction of operations that are writes, 0-1
+
+	// Nodes is the number of generated nodes. $id and write targets are
+	// drawn from them, assuming node IDs 1..Nodes as GenerateSynthetic
+	// assigns them on an empty graph.
+	Nodes int
+
+	Seed int64 // 0 means DefaultSeed
+}
+
+// LatencyStats summarizes the latencies of one kind of operation
+type LatencyStats struct {
+	Count               int
+	Min, Mean, Max      time.Duration
+	P50, P90, P99, P999 time.Duration
+}
+
+// WorkloadReport is the outcome of RunWorkload
+type WorkloadReport struct {
+	Duration time.Duration
+	Reads    LatencyStats
+	Writes   LatencyStats
+}
+
+// Throughput returns operations per second
+func (r *WorkloadReport) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Reads.Count+r.Writes.Count) / r.Duration.Seconds()
+}
+
+// RunWorkload runs cfg against t and reports latencies. Worker i draws
+// its operations from a generator seeded with Seed+i, so the same config
+// issues the same operations, although their interleaving varies. The
+// run stops at the first failed operation.
+func RunWorkload(t Target, cfg WorkloadConfig) (*WorkloadReport, error) {
+	if cfg.Nodes < 1 {
+		return nil, fmt.Errorf("workload needs at least 1 node, got %d", cfg.Nodes)
+	}
+	if cfg.WriteRatio < 0 || cfg.WriteRatio > 1 {
+		return nil, fmt.Errorf("write ratio must be between 0 and 1, got %v", cfg.WriteRatio)
+	}
+	queries := cfg.Queries
+	if len(queries) == 0 {
+		queries = DefaultWorkload
+	}
+	workers := cfg.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = DefaultSeed
+	}
+
+	var (
+		mu       sync.Mutex
+		reads    []time.Duration
+		writes   []time.Duration
+		firstErr error
+		failed   = make(chan struct{})
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			close(failed)
+		}
+	}
+
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		// Spread the remainder over the first workers
+		ops := cfg.Operations / workers
+		if w < cfg.Operations%workers {
+			ops++
+		}
+
+		wg.Add(1)
+		go func(w, ops int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed + int64(w)))
+			var myReads, myWrites []time.Duration
+
+			for i := 0; i < ops; i++ {
+				select {
+				case <-failed:
+					return
+				default:
+				}
+
+				var err error
+				opStart := time.Now()
+				if rng.Float64() < cfg.WriteRatio {
+					// New nodes get ids past the generated ones
+					id := cfg.Nodes + w + i*workers
+					err = t.Write(id, graph.NodeID(rng.Intn(cfg.Nodes)+1))
+					myWrites = append(myWrites, time.Since(opStart))
+				} else {
+					input := queries[rng.Intn(len(queries))]
+					err = t.Query(input, map[string]interface{}{"id": rng.Intn(cfg.Nodes)})
+					myReads = append(myReads, time.Since(opStart))
+				}
+				if err != nil {
+					fail(err)
+					return
+				}
+			}
+
+			mu.Lock()
+			reads = append(reads, myReads...)
+			writes = append(writes, myWrites...)
+			mu.Unlock()
+		}(w, ops)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return &WorkloadReport{
+		Duration: time.Since(start),
+		Reads:    summarizeLatencies(reads),
+		Writes:   summarizeLatencies(writes),
+	}, nil
+}
+
+// summarizeLatencies computes LatencyStats from unsorted durations
+func summarizeLatencies(durations []time.Duration) LatencyStats {
+	stats := LatencyStats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	stats.Min = durations[0]
+	stats.Max = durations[len(durations)-1]
+	stats.Mean = total / time.Duration(len(durations))
+	stats.P50 = percentile(durations, 50)
+	stats.P90 = percentile(durations, 90)
+	stats.P99 = percentile(durations, 99)
+	stats.P999 = percentile(durations, 99.9)
+	return stats
+}
+
+// percentile returns the p-th percentile (0-100) of sorted durations
+// using the nearest-rank method
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// ParseWorkload reads the queries of a workload file. A query may span
+// lines and ends at a line ending in a semicolon or at the end of the
+// file. Blank lines and lines starting with // are skipped.
+func ParseWorkload(r io.Reader) ([]string, error) {
+	var queries, lines []string
+	flush := func() {
+		if len(lines) > 0 {
+			queries = append(queries, strings.Join(lines, " "))
+			lines = lines[:0]
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if strings.HasSuffix(line, ";") {
+			if line = strings.TrimSpace(strings.TrimSuffix(line, ";")); line != "" {
+				lines = append(lines, line)
+			}
+			flush()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read workload: %w", err)
+	}
+	flush()
+	return queries, nil
+}
@@ -0,0 +1,78 @@
+package benchmarks
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/pkg/server"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createWorkloadGraph returns a persistent graph with a generated graph
+func createWorkloadGraph(t *testing.T, nodes, edges int) *storage.PersistentGraph {
+	g, err := storage.NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { g.Close() })
+	require.NoError(t, GenerateSynthetic(g.Graph, SyntheticConfig{Nodes: nodes, Edges: edges}))
+	return g
+}
+
+func TestRunWorkload_Embedded(t *testing.T) {
+	g := createWorkloadGraph(t, 50, 200)
+
+	report, err := RunWorkload(&EmbeddedTarget{Graph: g}, WorkloadConfig{
+		Operations:  200,
+		Concurrency: 4,
+		WriteRatio:  0.25,
+		Nodes:       50,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 200, report.Reads.Count+report.Writes.Count)
+	assert.InDelta(t, 50, report.Writes.Count, 25)
+	assert.Equal(t, 50+report.Writes.Count, g.NodeCount())
+	assert.Equal(t, 200+report.Writes.Count, g.EdgeCount())
+
+	reads := report.Reads
+	assert.LessOrEqual(t, reads.Min, reads.P50)
+	assert.LessOrEqual(t, reads.P50, reads.P99)
+	assert.LessOrEqual(t, reads.P99, reads.Max)
+	assert.Greater(t, report.Throughput(), 0.0)
+}
+
+func TestRunWorkload_Remote(t *testing.T) {
+	g := createWorkloadGraph(t, 20, 40)
+	srv := httptest.NewServer(server.New(g))
+	defer srv.Close()
+
+	target := &RemoteTarget{URL: srv.URL}
+	report, err := RunWorkload(target, WorkloadConfig{Operations: 20, Concurrency: 2, Nodes: 20})
+	require.NoError(t, err)
+	assert.Equal(t, 20, report.Reads.Count)
+
+	_, err = RunWorkload(target, WorkloadConfig{Operations: 20, WriteRatio: 1, Nodes: 20})
+	assert.ErrorIs(t, err, ErrWritesUnsupported)
+
+	_, err = RunWorkload(target, WorkloadConfig{Operations: 1, Nodes: 20, Queries: []string{"MATCH"}})
+	assert.Error(t, err)
+}
+
+func TestParseWorkload(t *testing.T) {
+	queries, err := ParseWorkload(strings.NewReader(`
+// Point lookup
+MATCH (n:Node) WHERE n.id = $id RETURN n.group;
+
+MATCH (a:Node)-[:LINKS]->(b)
+WHERE a.id = $id
+RETURN b.id;
+MATCH (n:Node) RETURN n.id LIMIT 1
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`MATCH (n:Node) WHERE n.id = $id RETURN n.group`,
+		`MATCH (a:Node)-[:LINKS]->(b) WHERE a.id = $id RETURN b.id`,
+		`MATCH (n:Node) RETURN n.id LIMIT 1`,
+	}, queries)
+}
@@ -0,0 +1,280 @@
+// Package client is a Go client for the rdgDB HTTP API served by
+// pkg/server, speaking HTTP with JSON bodies. Its types are its own, so
+// programs outside this module can use it without importing rdgDB's
+// internal packages.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/fnuworsu/rdgDB/pkg/server"
+)
+
+const (
+	// DefaultPageSize is the number of rows QueryStream fetches at a time
+	DefaultPageSize = 1000
+
+	// DefaultMaxRetries is the number of times a request is retried while
+	// the server is unavailable
+	DefaultMaxRetries = 5
+
+	// DefaultRetryBackoff is the wait before the first retry; it doubles
+	// with each retry up to DefaultMaxRetryBackoff
+	DefaultRetryBackoff    = 100 * time.Millisecond
+	DefaultMaxRetryBackoff = 5 * time.Second
+)
+
+// Options configures a Client
+type Options struct {
+	// HTTPClient sends the requests. Nil uses a client of its own.
+	HTTPClient *http.Client
+
+	// PageSize is the number of rows QueryStream fetches at a time
+	PageSize int
+
+	// MaxRetries is how many times a request is retried when the server
+	// cannot be reached or answers 503 Service Unavailable. Zero turns
+	// retries off.
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry. It doubles with
+	// each retry, up to MaxRetryBackoff.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+}
+
+// DefaultOptions returns the options used by NewClient
+func DefaultOptions() Options {
+	return Options{
+		PageSize:        DefaultPageSize,
+		MaxRetries:      DefaultMaxRetries,
+		RetryBackoff:    DefaultRetryBackoff,
+		MaxRetryBackoff: DefaultMaxRetryBackoff,
+	}
+}
+
+// Properties holds the property values of a node by key
+type Properties map[string]interface{}
+
+// Node is a node as stored by the server
+type Node struct {
+	ID         uint64
+	Label      string
+	Properties Properties
+}
+
+// Error is a request the server answered with an error status
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("server returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Client sends queries and writes to an rdgDB server. It is safe for
+// concurrent use.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	opts    Options
+}
+
+// NewClient creates a client for the server at addr, given as host:port
+// or as an http:// or https:// URL
+func NewClient(addr string) (*Client, error) {
+	return NewClientWithOptions(addr, DefaultOptions())
+}
+
+// NewClientWithOptions creates a client for the server at addr configured
+// by opts
+func NewClientWithOptions(addr string, opts Options) (*Client, error) {
+	if addr == "" {
+		return nil, errors.New("server address is empty")
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+		return nil, fmt.Errorf("unsupported server address %q (want host:port or an http URL)", addr)
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	}
+	return &Client{baseURL: strings.TrimSuffix(addr, "/"), http: httpClient, opts: opts}, nil
+}
+
+// Close releases the client's idle connections
+func (c *Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// Query runs a query and returns every row of its result. Row values are
+// as the server writes them in JSON: numbers are float64, nodes are
+// objects with id, label and properties, and so on.
+func (c *Client) Query(ctx context.Context, queryStr string) (*query.Result, error) {
+	var page server.Page
+	if err := c.post(ctx, "/query", server.QueryRequest{Query: queryStr}, &page); err != nil {
+		return nil, err
+	}
+	return &query.Result{Columns: page.Columns, Rows: page.Rows}, nil
+}
+
+// QueryStream runs a query and sends its rows on the returned channel,
+// fetching them a page at a time rather than holding the whole result.
+// The row channel is closed after the last row or on error; the error
+// channel then receives the error, if any, and is closed. Cancelling ctx
+// stops the stream and releases the server's cursor.
+func (c *Client) QueryStream(ctx context.Context, queryStr string) (<-chan query.Row, <-chan error) {
+	rows := make(chan query.Row)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(rows)
+		if err := c.stream(ctx, queryStr, rows); err != nil {
+			errs <- err
+		}
+	}()
+	return rows, errs
+}
+
+func (c *Client) stream(ctx context.Context, queryStr string, rows chan<- query.Row) error {
+	var page server.Page
+	req := server.QueryRequest{Query: queryStr, PageSize: c.opts.PageSize}
+	if err := c.post(ctx, "/query", req, &page); err != nil {
+		return err
+	}
+	for {
+		for _, row := range page.Rows {
+			select {
+			case rows <- row:
+			case <-ctx.Done():
+				c.closeCursor(page.Cursor)
+				return ctx.Err()
+			}
+		}
+		if page.Cursor == "" {
+			return nil
+		}
+
+		cursor := page.Cursor
+		page = server.Page{}
+		if err := c.post(ctx, "/query/next", server.CursorRequest{Cursor: cursor}, &page); err != nil {
+			c.closeCursor(cursor)
+			return err
+		}
+	}
+}
+
+// closeCursor releases a cursor left open by a stream that stopped early.
+// It runs without the stream's context, which may be cancelled already.
+func (c *Client) closeCursor(cursor string) {
+	if cursor == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// A cursor that cannot be closed is dropped by the server's idle timeout
+	_ = c.post(ctx, "/query/close", server.CursorRequest{Cursor: cursor}, nil)
+}
+
+// AddNode adds a node and returns it with its assigned ID. Property values
+// keep their types, such as ints and times, both ways.
+func (c *Client) AddNode(ctx context.Context, label string, props Properties) (*Node, error) {
+	req := server.NodeRequest{Label: label, Properties: make(graph.Properties, len(props))}
+	for k, v := range props {
+		req.Properties[k] = v
+	}
+	var resp server.NodeResponse
+	if err := c.post(ctx, "/nodes", req, &resp); err != nil {
+		return nil, err
+	}
+	node := &Node{ID: uint64(resp.ID), Label: resp.Label, Properties: make(Properties, len(resp.Properties))}
+	for k, v := range resp.Properties {
+		node.Properties[k] = v
+	}
+	return node, nil
+}
+
+// post sends body as JSON to path and decodes the response into out, if
+// given, retrying while the server is unavailable
+func (c *Client) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	backoff := c.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err = c.send(ctx, path, data, out)
+		if err == nil || attempt >= c.opts.MaxRetries || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+		if c.opts.MaxRetryBackoff > 0 && backoff > c.opts.MaxRetryBackoff {
+			backoff = c.opts.MaxRetryBackoff
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, path string, data []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// retryable reports whether err means the server could not take the
+// request, so that sending it again cannot apply it twice: the connection
+// was refused or the server answered 503 Service Unavailable
+func retryable(err error) bool {
+	var statusErr *Error
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusServiceUnavailable
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
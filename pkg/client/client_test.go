@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/server"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer serves a graph shared by the tests; each test uses labels of
+// its own
+var (
+	testServer *httptest.Server
+	testGraph  *storage.PersistentGraph
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "rdgdb-client")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	testGraph, err = storage.NewPersistentGraph(filepath.Join(dir, storage.WALSubdir), filepath.Join(dir, storage.SnapshotSubdir))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	srv := server.New(testGraph)
+	testServer = httptest.NewServer(srv)
+
+	code := m.Run()
+
+	testServer.Close()
+	srv.Close()
+	testGraph.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestClient(t *testing.T, opts Options) *Client {
+	c, err := NewClientWithOptions(testServer.URL, opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestQuery(t *testing.T) {
+	c := newTestClient(t, DefaultOptions())
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := c.AddNode(ctx, "QueryPerson", Properties{"age": i})
+		require.NoError(t, err)
+	}
+
+	result, err := c.Query(ctx, `MATCH (p:QueryPerson) RETURN p.age ORDER BY p.age`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p.age"}, result.Columns)
+	require.Len(t, result.Rows, 3)
+	for i, row := range result.Rows {
+		assert.Equal(t, float64(i), row["p.age"])
+	}
+
+	_, err = c.Query(ctx, `MATCH (p:QueryPerson RETURN p`)
+	var statusErr *Error
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusBadRequest, statusErr.StatusCode)
+}
+
+func TestQueryStream(t *testing.T) {
+	opts := DefaultOptions()
+	opts.PageSize = 2
+	c := newTestClient(t, opts)
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := c.AddNode(ctx, "StreamPerson", Properties{"age": i})
+		require.NoError(t, err)
+	}
+
+	// Rows arrive in order across pages
+	rows, errs := c.QueryStream(ctx, `MATCH (p:StreamPerson) RETURN p.age ORDER BY p.age`)
+	var ages []float64
+	for row := range rows {
+		ages = append(ages, row["p.age"].(float64))
+	}
+	require.NoError(t, <-errs)
+	assert.Equal(t, []float64{0, 1, 2, 3, 4}, ages)
+
+	// Stopping early closes the cursor on the server
+	cancelCtx, cancel := context.WithCancel(ctx)
+	rows, errs = c.QueryStream(cancelCtx, `MATCH (p:StreamPerson) RETURN p.age`)
+	<-rows
+	cancel()
+	for range rows {
+	}
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.Eventually(t, func() bool {
+		return openCursors(t) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A bad query ends the stream with its error
+	rows, errs = c.QueryStream(ctx, `MATCH`)
+	_, ok := <-rows
+	assert.False(t, ok)
+	assert.Error(t, <-errs)
+}
+
+// openCursors reads the server's open cursor count from /metrics
+func openCursors(t *testing.T) int {
+	resp, err := http.Get(testServer.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var metrics server.Metrics
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&metrics))
+	return metrics.Open
+}
+
+func TestAddNode(t *testing.T) {
+	c := newTestClient(t, DefaultOptions())
+	born := time.Date(1990, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	node, err := c.AddNode(context.Background(), "AddPerson", Properties{"name": "Alice", "age": 30, "born": born})
+	require.NoError(t, err)
+	assert.Equal(t, "AddPerson", node.Label)
+	assert.Equal(t, 30, node.Properties["age"])
+	assert.True(t, born.Equal(node.Properties["born"].(time.Time)))
+
+	stored, err := testGraph.GetNode(graph.NodeID(node.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", stored.Properties.Map()["name"])
+
+	_, err = c.AddNode(context.Background(), "", nil)
+	assert.Error(t, err)
+}
+
+func TestRetry(t *testing.T) {
+	// The server is unavailable for the first two requests
+	var requests atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			http.Error(w, "starting up", http.StatusServiceUnavailable)
+			return
+		}
+		testServer.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer flaky.Close()
+
+	opts := DefaultOptions()
+	opts.RetryBackoff = time.Millisecond
+	c, err := NewClientWithOptions(flaky.URL, opts)
+	require.NoError(t, err)
+	defer c.Close()
+	_, err = c.Query(context.Background(), `MATCH (p:RetryPerson) RETURN p`)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), requests.Load())
+
+	// Errors other than unavailability are not retried
+	_, err = c.Query(context.Background(), `MATCH`)
+	assert.Error(t, err)
+	assert.Equal(t, int32(4), requests.Load())
+
+	// A server that cannot be reached fails once the retries run out
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	opts.MaxRetries = 2
+	c, err = NewClientWithOptions(addr, opts)
+	require.NoError(t, err)
+	defer c.Close()
+	_, err = c.Query(context.Background(), `MATCH (n) RETURN n`)
+	var opErr *net.OpError
+	assert.ErrorAs(t, err, &opErr)
+}
+
+func TestNewClient(t *testing.T) {
+	for _, addr := range []string{"", "ftp://localhost:1"} {
+		_, err := NewClient(addr)
+		assert.Error(t, err, addr)
+	}
+	c, err := NewClient("localhost:8080")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080", c.baseURL)
+}
@@ -0,0 +1,384 @@
+package graphio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+const defaultBatchSize = 1000
+
+// DanglingPolicy controls what happens when an edge references an unknown node ID
+type DanglingPolicy int
+
+const (
+	DanglingSkip  DanglingPolicy = iota // Skip the edge and record it in the report
+	DanglingError                       // Abort the import
+)
+
+// ImportOptions configures a CSV import
+type ImportOptions struct {
+	BatchSize      int            // Rows applied per batch (default 1000)
+	OnDangling     DanglingPolicy // Behavior for edges referencing unknown IDs
+	Delimiter      rune           // Field delimiter (default ',')
+	Progress       func(ImportProgress)
+	SkipEmptyValue bool // Omit properties whose cell is empty instead of storing ""
+}
+
+// ImportProgress is passed to the progress callback after each batch
+type ImportProgress struct {
+	File  string
+	Lines int // Data lines processed so far in File
+	Nodes int // Total nodes imported so far
+	Edges int // Total edges imported so far
+}
+
+// LineError records a problem with a single CSV line
+type LineError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e LineError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+// ImportReport summarizes a completed import
+type ImportReport struct {
+	NodesImported int
+	EdgesImported int
+	NodesSkipped  int
+	EdgesSkipped  int
+	Errors        []LineError
+
+	// IDMap maps external IDs from the node file to assigned NodeIDs
+	IDMap map[string]graph.NodeID
+}
+
+// DefaultImportOptions returns default import options
+func DefaultImportOptions() ImportOptions {
+	return ImportOptions{
+		BatchSize:  defaultBatchSize,
+		OnDangling: DanglingSkip,
+		Delimiter:  ',',
+	}
+}
+
+// column describes a single header field, e.g. "age:int" or ":LABEL"
+type column struct {
+	name string
+	kind string // ID, LABEL, START_ID, END_ID, TYPE, or a value type
+}
+
+func parseHeader(fields []string) []column {
+	cols := make([]column, len(fields))
+	for i, f := range fields {
+		f = strings.TrimSpace(f)
+		name, kind := f, "string"
+		if idx := strings.LastIndex(f, ":"); idx >= 0 {
+			name, kind = f[:idx], f[idx+1:]
+		}
+		switch upper := strings.ToUpper(kind); upper {
+		case "ID", "LABEL", "START_ID", "END_ID", "TYPE":
+			kind = upper
+		default:
+			kind = strings.ToLower(kind)
+		}
+		cols[i] = column{name: name, kind: kind}
+	}
+	return cols
+}
+
+// parseValue converts a CSV cell into a typed property value
+func parseValue(kind, raw string) (graph.PropertyValue, error) {
+	switch kind {
+	case "string", "":
+		return raw, nil
+	case "int", "long", "integer":
+		v, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %q", raw)
+		}
+		return v, nil
+	case "float", "double":
+		v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", raw)
+		}
+		return v, nil
+	case "bool", "boolean":
+		v, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool %q", raw)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported column type %q", kind)
+}
+
+// ImportCSV loads nodes and edges from CSV files into g.
+//
+// The node file header must contain an ID column (":ID" or "name:ID") and
+// may contain a ":LABEL" column. The edge file header must contain
+// ":START_ID" and ":END_ID" columns and may contain a ":TYPE" column.
+// Remaining columns are properties, typed with a suffix such as "age:int".
+// edgesPath may be empty to import nodes only.
+func ImportCSV(g GraphWriter, nodesPath, edgesPath string, opts ImportOptions) (*ImportReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+
+	report := &ImportReport{IDMap: make(map[string]graph.NodeID)}
+
+	if nodesPath != "" {
+		f, err := os.Open(nodesPath)
+		if err != nil {
+			return report, fmt.Errorf("failed to open node file: %w", err)
+		}
+		err = importNodes(g, f, nodesPath, opts, report)
+		f.Close()
+		if err != nil {
+			return report, err
+		}
+	}
+
+	if edgesPath != "" {
+		f, err := os.Open(edgesPath)
+		if err != nil {
+			return report, fmt.Errorf("failed to open edge file: %w", err)
+		}
+		err = importEdges(g, f, edgesPath, opts, report)
+		f.Close()
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// csvRow is a parsed data line waiting to be applied
+type csvRow struct {
+	line   int
+	fields []string
+}
+
+func newCSVReader(r io.Reader, opts ImportOptions) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = opts.Delimiter
+	reader.FieldsPerRecord = -1
+	return reader
+}
+
+// readBatches reads data rows in batches and calls apply for each batch
+func readBatches(reader *csv.Reader, name string, opts ImportOptions, report *ImportReport, apply func([]csvRow) error) error {
+	batch := make([]csvRow, 0, opts.BatchSize)
+	line := 1 // header
+	lines := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := apply(batch); err != nil {
+			return err
+		}
+		lines += len(batch)
+		batch = batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(ImportProgress{
+				File:  name,
+				Lines: lines,
+				Nodes: report.NodesImported,
+				Edges: report.EdgesImported,
+			})
+		}
+		return nil
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			report.Errors = append(report.Errors, LineError{File: name, Line: line, Err: err})
+			continue
+		}
+		batch = append(batch, csvRow{line: line, fields: record})
+		if len(batch) >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return flush()
+}
+
+func importNodes(g GraphWriter, r io.Reader, name string, opts ImportOptions, report *ImportReport) error {
+	reader := newCSVReader(r, opts)
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read node header: %w", err)
+	}
+	cols := parseHeader(header)
+
+	idCol, labelCol := -1, -1
+	for i, c := range cols {
+		switch c.kind {
+		case "ID":
+			idCol = i
+		case "LABEL":
+			labelCol = i
+		}
+	}
+	if idCol < 0 {
+		return fmt.Errorf("node file %s has no ID column", name)
+	}
+
+	return readBatches(reader, name, opts, report, func(rows []csvRow) error {
+		for _, row := range rows {
+			if len(row.fields) != len(cols) {
+				report.NodesSkipped++
+				report.Errors = append(report.Errors, LineError{name, row.line,
+					fmt.Errorf("expected %d fields, got %d", len(cols), len(row.fields))})
+				continue
+			}
+
+			extID := row.fields[idCol]
+			if _, dup := report.IDMap[extID]; dup {
+				report.NodesSkipped++
+				report.Errors = append(report.Errors, LineError{name, row.line,
+					fmt.Errorf("duplicate node ID %q", extID)})
+				continue
+			}
+
+			label := ""
+			if labelCol >= 0 {
+				label = row.fields[labelCol]
+			}
+
+			props, err := rowProperties(cols, row.fields, opts)
+			if err != nil {
+				report.NodesSkipped++
+				report.Errors = append(report.Errors, LineError{name, row.line, err})
+				continue
+			}
+			// Keep a named ID column ("personId:ID") as a property
+			if cols[idCol].name != "" {
+				props[cols[idCol].name] = extID
+			}
+
+			node, err := g.AddNode(label, props)
+			if err != nil {
+				return fmt.Errorf("%s:%d: failed to add node: %w", name, row.line, err)
+			}
+			report.IDMap[extID] = node.ID
+			report.NodesImported++
+		}
+		return nil
+	})
+}
+
+func importEdges(g GraphWriter, r io.Reader, name string, opts ImportOptions, report *ImportReport) error {
+	reader := newCSVReader(r, opts)
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read edge header: %w", err)
+	}
+	cols := parseHeader(header)
+
+	startCol, endCol, typeCol := -1, -1, -1
+	for i, c := range cols {
+		switch c.kind {
+		case "START_ID":
+			startCol = i
+		case "END_ID":
+			endCol = i
+		case "TYPE":
+			typeCol = i
+		}
+	}
+	if startCol < 0 || endCol < 0 {
+		return fmt.Errorf("edge file %s needs START_ID and END_ID columns", name)
+	}
+
+	return readBatches(reader, name, opts, report, func(rows []csvRow) error {
+		for _, row := range rows {
+			if len(row.fields) != len(cols) {
+				report.EdgesSkipped++
+				report.Errors = append(report.Errors, LineError{name, row.line,
+					fmt.Errorf("expected %d fields, got %d", len(cols), len(row.fields))})
+				continue
+			}
+
+			source, okSrc := report.IDMap[row.fields[startCol]]
+			target, okTgt := report.IDMap[row.fields[endCol]]
+			if !okSrc || !okTgt {
+				missing := row.fields[startCol]
+				if okSrc {
+					missing = row.fields[endCol]
+				}
+				lineErr := LineError{name, row.line, fmt.Errorf("unknown node ID %q", missing)}
+				if opts.OnDangling == DanglingError {
+					return lineErr
+				}
+				report.EdgesSkipped++
+				report.Errors = append(report.Errors, lineErr)
+				continue
+			}
+
+			label := ""
+			if typeCol >= 0 {
+				label = row.fields[typeCol]
+			}
+
+			props, err := rowProperties(cols, row.fields, opts)
+			if err != nil {
+				report.EdgesSkipped++
+				report.Errors = append(report.Errors, LineError{name, row.line, err})
+				continue
+			}
+
+			if _, err := g.AddEdge(source, target, label, props); err != nil {
+				return fmt.Errorf("%s:%d: failed to add edge: %w", name, row.line, err)
+			}
+			report.EdgesImported++
+		}
+		return nil
+	})
+}
+
+// rowProperties extracts the typed property columns of a row
+func rowProperties(cols []column, fields []string, opts ImportOptions) (graph.Properties, error) {
+	props := graph.Properties{}
+	for i, c := range cols {
+		switch c.kind {
+		case "ID", "LABEL", "START_ID", "END_ID", "TYPE":
+			continue
+		}
+		if c.name == "" {
+			continue
+		}
+		if fields[i] == "" && (opts.SkipEmptyValue || c.kind != "string") {
+			continue
+		}
+		v, err := parseValue(c.kind, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", c.name, err)
+		}
+		props[c.name] = v
+	}
+	return props, nil
+}
@@ -0,0 +1,130 @@
+package graphio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestImportCSV(t *testing.T) {
+	dir := t.TempDir()
+	nodes := writeFile(t, dir, "nodes.csv", `:ID,:LABEL,name,age:int,score:float,active:bool
+p1,Person,Alice,30,1.5,true
+p2,Person,Bob,25,2.0,false
+c1,Company,Google,,,
+`)
+	edges := writeFile(t, dir, "edges.csv", `:START_ID,:END_ID,:TYPE,since:int
+p1,p2,KNOWS,2020
+p1,c1,WORKS_AT,
+p2,c1,WORKS_AT,2019
+`)
+
+	g := storage.NewGraph()
+	report, err := ImportCSV(g, nodes, edges, DefaultImportOptions())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, report.NodesImported)
+	assert.Equal(t, 3, report.EdgesImported)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, 3, g.NodeCount())
+	assert.Equal(t, 3, g.EdgeCount())
+
+	alice, err := g.GetNode(report.IDMap["p1"])
+	require.NoError(t, err)
+	assert.Equal(t, "Person", alice.Label)
+	age, _ := alice.GetProperty("age")
+	assert.Equal(t, 30, age)
+	score, _ := alice.GetProperty("score")
+	assert.Equal(t, 1.5, score)
+	active, _ := alice.GetProperty("active")
+	assert.Equal(t, true, active)
+
+	google, _ := g.GetNode(report.IDMap["c1"])
+	_, hasAge := google.GetProperty("age")
+	assert.False(t, hasAge, "empty typed cells should be omitted")
+}
+
+func TestImportCSV_NamedIDColumn(t *testing.T) {
+	dir := t.TempDir()
+	nodes := writeFile(t, dir, "nodes.csv", "personId:ID,name\n7,Alice\n")
+
+	g := storage.NewGraph()
+	report, err := ImportCSV(g, nodes, "", DefaultImportOptions())
+	require.NoError(t, err)
+
+	node, _ := g.GetNode(report.IDMap["7"])
+	id, _ := node.GetProperty("personId")
+	assert.Equal(t, "7", id)
+}
+
+func TestImportCSV_LineErrors(t *testing.T) {
+	dir := t.TempDir()
+	nodes := writeFile(t, dir, "nodes.csv", `:ID,age:int
+a,1
+b,notanumber
+a,3
+`)
+
+	g := storage.NewGraph()
+	report, err := ImportCSV(g, nodes, "", DefaultImportOptions())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.NodesImported)
+	assert.Equal(t, 2, report.NodesSkipped)
+	require.Len(t, report.Errors, 2)
+	assert.Equal(t, 3, report.Errors[0].Line)
+	assert.Equal(t, 4, report.Errors[1].Line)
+}
+
+func TestImportCSV_DanglingEdges(t *testing.T) {
+	dir := t.TempDir()
+	nodes := writeFile(t, dir, "nodes.csv", ":ID\na\nb\n")
+	edges := writeFile(t, dir, "edges.csv", ":START_ID,:END_ID,:TYPE\na,b,LINK\na,zzz,LINK\n")
+
+	// Skip policy records the bad line and continues
+	g := storage.NewGraph()
+	report, err := ImportCSV(g, nodes, edges, DefaultImportOptions())
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.EdgesImported)
+	assert.Equal(t, 1, report.EdgesSkipped)
+	require.Len(t, report.Errors, 1)
+	assert.Equal(t, 3, report.Errors[0].Line)
+
+	// Error policy aborts
+	opts := DefaultImportOptions()
+	opts.OnDangling = DanglingError
+	_, err = ImportCSV(storage.NewGraph(), nodes, edges, opts)
+	assert.Error(t, err)
+}
+
+func TestImportCSV_BatchProgress(t *testing.T) {
+	dir := t.TempDir()
+	content := ":ID\n"
+	for i := 0; i < 25; i++ {
+		content += string(rune('a'+i)) + "\n"
+	}
+	nodes := writeFile(t, dir, "nodes.csv", content)
+
+	var calls []ImportProgress
+	opts := DefaultImportOptions()
+	opts.BatchSize = 10
+	opts.Progress = func(p ImportProgress) { calls = append(calls, p) }
+
+	report, err := ImportCSV(storage.NewGraph(), nodes, "", opts)
+	require.NoError(t, err)
+	assert.Equal(t, 25, report.NodesImported)
+
+	require.Len(t, calls, 3)
+	assert.Equal(t, 10, calls[0].Lines)
+	assert.Equal(t, 25, calls[2].Nodes)
+}
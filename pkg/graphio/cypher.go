@@ -0,0 +1,372 @@
+package graphio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// cypherImportLabel is added to every exported node so that relationship
+// statements can find their endpoints through a single index. It is
+// removed once all relationships exist.
+const cypherImportLabel = "_RdgImport"
+
+// errNotStorable marks property values Neo4j cannot store, such as maps.
+// ExportCypher leaves such properties out.
+var errNotStorable = errors.New("Neo4j cannot store it as a property")
+
+// CypherOptions configures a Cypher export
+type CypherOptions struct {
+	BatchSize  int    // Statements per :begin/:commit block; 0 writes no transaction markers
+	IncludeIDs bool   // Keep rdgDB node IDs as IDProperty after import
+	IDProperty string // Property holding rdgDB node IDs (default "_rdg_id")
+
+	// CreateOnly writes only CREATE statements, in the subset ImportCypher
+	// reads. Each node is bound to a variable that later statements use
+	// to connect it, which works with ImportCypher but not cypher-shell,
+	// where variables do not outlive their statement.
+	CreateOnly bool
+
+	// Warn, when set, is told of each property left out because Neo4j
+	// cannot store its value
+	Warn func(msg string)
+}
+
+// DefaultCypherOptions returns default Cypher export options
+func DefaultCypherOptions() CypherOptions {
+	return CypherOptions{BatchSize: defaultBatchSize, IDProperty: "_rdg_id"}
+}
+
+// ExportCypher writes g as a cypher-shell script. Every node becomes a
+// CREATE statement, followed by one MATCH+CREATE statement per edge.
+// Nodes are matched by their rdgDB ID, which is dropped at the end unless
+// opts.IncludeIDs is set. If a node already has a property named
+// opts.IDProperty, a free name is used for the IDs instead, or, when they
+// are kept, the export fails. Output is ordered by ID.
+func ExportCypher(g *storage.Graph, w io.Writer, opts CypherOptions) error {
+	if opts.IDProperty == "" {
+		opts.IDProperty = DefaultCypherOptions().IDProperty
+	}
+	idProperty, err := importIDProperty(g, opts)
+	if err != nil {
+		return err
+	}
+	idKey := cypherName(idProperty)
+	importLabel := cypherName(cypherImportLabel)
+
+	bw := bufio.NewWriter(w)
+	batch := newCypherBatcher(bw, opts.BatchSize)
+
+	if opts.CreateOnly {
+		if !opts.IncludeIDs {
+			idKey = ""
+		}
+		if err := exportCypherCreates(g, batch, idKey, opts); err != nil {
+			return err
+		}
+		batch.flush()
+		return bw.Flush()
+	}
+
+	// Schema changes cannot share a transaction with writes
+	fmt.Fprintf(bw, "CREATE INDEX rdg_import_id IF NOT EXISTS FOR (n:%s) ON (n.%s);\n", importLabel, idKey)
+
+	for _, id := range sortedNodeIDs(g) {
+		n, err := g.GetNode(id)
+		if err != nil {
+			continue // deleted while exporting
+		}
+		n.Mu.RLock()
+		props, err := cypherProperties(n.Properties.Map(), idKey, uint64(n.ID), opts.skipper("node", uint64(id)))
+		labels := ":" + importLabel
+		if n.Label != "" {
+			labels = ":" + cypherName(n.Label) + labels
+		}
+		n.Mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("node %d: %w", id, err)
+		}
+		batch.statement(fmt.Sprintf("CREATE (%s %s);", labels, props))
+	}
+
+	for _, id := range sortedEdgeIDs(g) {
+		e, err := g.GetEdge(id)
+		if err != nil {
+			continue
+		}
+		e.Mu.RLock()
+		props, err := cypherProperties(e.Properties.Map(), "", 0, opts.skipper("edge", uint64(id)))
+		stmt := fmt.Sprintf("MATCH (a:%s {%s: %d}), (b:%s {%s: %d}) CREATE (a)-[:%s",
+			importLabel, idKey, e.Source, importLabel, idKey, e.Target, cypherName(e.Label))
+		e.Mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("edge %d: %w", id, err)
+		}
+		if props != "{}" {
+			stmt += " " + props
+		}
+		batch.statement(stmt + "]->(b);")
+	}
+	batch.flush()
+
+	cleanup := fmt.Sprintf("MATCH (n:%s) REMOVE n:%s", importLabel, importLabel)
+	if !opts.IncludeIDs {
+		cleanup += ", n." + idKey
+	}
+	fmt.Fprintf(bw, "%s;\n", cleanup)
+	fmt.Fprintln(bw, "DROP INDEX rdg_import_id IF EXISTS;")
+
+	return bw.Flush()
+}
+
+// exportCypherCreates writes the statements of a CreateOnly export: a
+// CREATE per node binding it to n<ID>, then a CREATE per edge between the
+// bound nodes. idKey, when set, is the property that keeps node IDs.
+func exportCypherCreates(g *storage.Graph, batch *cypherBatcher, idKey string, opts CypherOptions) error {
+	for _, id := range sortedNodeIDs(g) {
+		n, err := g.GetNode(id)
+		if err != nil {
+			continue // deleted while exporting
+		}
+		n.Mu.RLock()
+		props, err := cypherProperties(n.Properties.Map(), idKey, uint64(n.ID), opts.skipper("node", uint64(id)))
+		label := ""
+		if n.Label != "" {
+			label = ":" + cypherName(n.Label)
+		}
+		n.Mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("node %d: %w", id, err)
+		}
+		stmt := fmt.Sprintf("CREATE (n%d%s", id, label)
+		if props != "{}" {
+			stmt += " " + props
+		}
+		batch.statement(stmt + ");")
+	}
+
+	for _, id := range sortedEdgeIDs(g) {
+		e, err := g.GetEdge(id)
+		if err != nil {
+			continue
+		}
+		e.Mu.RLock()
+		props, err := cypherProperties(e.Properties.Map(), "", 0, opts.skipper("edge", uint64(id)))
+		stmt := fmt.Sprintf("CREATE (n%d)-[:%s", e.Source, cypherName(e.Label))
+		target := e.Target
+		e.Mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("edge %d: %w", id, err)
+		}
+		if props != "{}" {
+			stmt += " " + props
+		}
+		batch.statement(fmt.Sprintf("%s]->(n%d);", stmt, target))
+	}
+	return nil
+}
+
+// importIDProperty returns the property that holds node IDs during import:
+// opts.IDProperty, unless a node already has it. IDs dropped after import
+// then move to the first free name with a numeric suffix, while kept ones
+// would clash with the node's own value, which is an error.
+func importIDProperty(g *storage.Graph, opts CypherOptions) (string, error) {
+	used := make(map[string]graph.NodeID)
+	g.IterateNodes(func(n *graph.Node) bool {
+		n.Mu.RLock()
+		for _, key := range n.Properties.Keys() {
+			if _, ok := used[key]; !ok {
+				used[key] = n.ID
+			}
+		}
+		n.Mu.RUnlock()
+		return true
+	})
+
+	owner, taken := used[opts.IDProperty]
+	if !taken {
+		return opts.IDProperty, nil
+	}
+	if opts.IncludeIDs {
+		return "", fmt.Errorf("node %d already has a property %s to hold its ID; choose another ID property", owner, opts.IDProperty)
+	}
+	for i := 2; ; i++ {
+		name := fmt.Sprintf("%s_%d", opts.IDProperty, i)
+		if _, taken := used[name]; !taken {
+			return name, nil
+		}
+	}
+}
+
+// skipper returns the function cypherProperties reports the properties it
+// leaves out of an element to
+func (opts CypherOptions) skipper(kind string, id uint64) func(key string, err error) {
+	return func(key string, err error) {
+		if opts.Warn != nil {
+			opts.Warn(fmt.Sprintf("%s %d: skipped property %s: %v", kind, id, key, err))
+		}
+	}
+}
+
+// cypherBatcher groups statements into :begin/:commit blocks
+type cypherBatcher struct {
+	w     *bufio.Writer
+	size  int
+	count int
+}
+
+func newCypherBatcher(w *bufio.Writer, size int) *cypherBatcher {
+	return &cypherBatcher{w: w, size: size}
+}
+
+func (b *cypherBatcher) statement(stmt string) {
+	if b.size > 0 && b.count == 0 {
+		b.w.WriteString(":begin\n")
+	}
+	b.w.WriteString(stmt)
+	b.w.WriteByte('\n')
+	b.count++
+	if b.size > 0 && b.count == b.size {
+		b.flush()
+	}
+}
+
+func (b *cypherBatcher) flush() {
+	if b.size > 0 && b.count > 0 {
+		b.w.WriteString(":commit\n")
+	}
+	b.count = 0
+}
+
+// cypherProperties formats props as a Cypher map literal with sorted keys.
+// When idKey is set the ID is included first under that key. Null values
+// are omitted since Neo4j does not store them, and values it cannot store
+// at all are passed to skip and omitted.
+func cypherProperties(props graph.Properties, idKey string, id uint64, skip func(key string, err error)) (string, error) {
+	keys := make([]string, 0, len(props))
+	for k, v := range props {
+		if v != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys)+1)
+	if idKey != "" {
+		parts = append(parts, fmt.Sprintf("%s: %d", idKey, id))
+	}
+	for _, k := range keys {
+		v, err := cypherValue(props[k])
+		if errors.Is(err, errNotStorable) {
+			skip(k, err)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("property %s: %w", k, err)
+		}
+		parts = append(parts, cypherName(k)+": "+v)
+	}
+	return "{" + strings.Join(parts, ", ") + "}", nil
+}
+
+// cypherValue formats a property value as a Cypher literal
+func cypherValue(v graph.PropertyValue) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return cypherString(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", val), nil
+	case float32:
+		return cypherFloat(float64(val))
+	case float64:
+		return cypherFloat(val)
+	case graph.Point:
+		return fmt.Sprintf("point({latitude: %s, longitude: %s})",
+			strconv.FormatFloat(val.Lat, 'f', -1, 64), strconv.FormatFloat(val.Lon, 'f', -1, 64)), nil
+	case time.Time:
+		return "datetime(" + cypherString(val.Format(time.RFC3339Nano)) + ")", nil
+	case []graph.PropertyValue:
+		items := make([]string, len(val))
+		for i, item := range val {
+			text, err := cypherValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case graph.Properties, map[string]interface{}:
+		return "", fmt.Errorf("map value: %w", errNotStorable)
+	case graph.Histogram:
+		return "", fmt.Errorf("histogram value: %w", errNotStorable)
+	}
+	return "", fmt.Errorf("unsupported property type %T", v)
+}
+
+// cypherFloat formats f so that Cypher reads it back as a float
+func cypherFloat(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("cannot export non-finite float %v", f)
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s, nil
+}
+
+// cypherString quotes s as a single-quoted Cypher string literal
+func cypherString(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// cypherName returns name as a Cypher identifier, backtick-quoted unless
+// it is a plain identifier
+func cypherName(name string) string {
+	plain := name != ""
+	for i, r := range name {
+		isLetter := r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+		if !isLetter && (i == 0 || r < '0' || r > '9') {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return name
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,159 @@
+package graphio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportCypher(t *testing.T) {
+	g := createTypedGraph(t)
+
+	var buf bytes.Buffer
+	opts := DefaultCypherOptions()
+	opts.BatchSize = 0
+	require.NoError(t, ExportCypher(g, &buf, opts))
+
+	expected := strings.Join([]string{
+		"CREATE INDEX rdg_import_id IF NOT EXISTS FOR (n:_RdgImport) ON (n._rdg_id);",
+		"CREATE (:Person:_RdgImport {_rdg_id: 1, active: true, age: 30, name: 'Alice', score: 1.5, weight: 70.0});",
+		"CREATE (:Person:_RdgImport {_rdg_id: 2, name: 'Bob'});",
+		"MATCH (a:_RdgImport {_rdg_id: 1}), (b:_RdgImport {_rdg_id: 2}) CREATE (a)-[:KNOWS {close: false, since: 2020}]->(b);",
+		"MATCH (n:_RdgImport) REMOVE n:_RdgImport, n._rdg_id;",
+		"DROP INDEX rdg_import_id IF EXISTS;",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestExportCypher_IncludeIDs(t *testing.T) {
+	g := createTypedGraph(t)
+
+	var buf bytes.Buffer
+	opts := DefaultCypherOptions()
+	opts.IncludeIDs = true
+	opts.IDProperty = "rdg id"
+	require.NoError(t, ExportCypher(g, &buf, opts))
+
+	out := buf.String()
+	assert.Contains(t, out, "CREATE (:Person:_RdgImport {`rdg id`: 2, name: 'Bob'});")
+	assert.Contains(t, out, "MATCH (n:_RdgImport) REMOVE n:_RdgImport;\n")
+}
+
+func TestExportCypher_CreateOnly(t *testing.T) {
+	g := createTypedGraph(t)
+
+	var buf bytes.Buffer
+	opts := DefaultCypherOptions()
+	opts.BatchSize = 0
+	opts.CreateOnly = true
+	require.NoError(t, ExportCypher(g, &buf, opts))
+
+	expected := strings.Join([]string{
+		"CREATE (n1:Person {active: true, age: 30, name: 'Alice', score: 1.5, weight: 70.0});",
+		"CREATE (n2:Person {name: 'Bob'});",
+		"CREATE (n1)-[:KNOWS {close: false, since: 2020}]->(n2);",
+		"",
+	}, "\n")
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestExportCypher_Batches(t *testing.T) {
+	g := storage.NewGraph()
+	for i := 0; i < 5; i++ {
+		g.AddNode("N", nil)
+	}
+	g.AddEdge(1, 2, "E", nil)
+
+	var buf bytes.Buffer
+	opts := DefaultCypherOptions()
+	opts.BatchSize = 2
+	require.NoError(t, ExportCypher(g, &buf, opts))
+
+	out := buf.String()
+	// 6 statements in blocks of 2
+	assert.Equal(t, 3, strings.Count(out, ":begin\n"))
+	assert.Equal(t, 3, strings.Count(out, ":commit\n"))
+	assert.Contains(t, out, "CREATE (a)-[:E]->(b);\n:commit\n")
+
+	// Schema statements stay outside transactions
+	assert.True(t, strings.HasPrefix(out, "CREATE INDEX"))
+	assert.True(t, strings.HasSuffix(out, "DROP INDEX rdg_import_id IF EXISTS;\n"))
+}
+
+func TestExportCypher_SkipsMaps(t *testing.T) {
+	g := storage.NewGraph()
+	_, err := g.AddNode("Person", graph.Properties{"name": "Alice", "address": graph.Properties{"city": "SF"}})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	var warnings []string
+	opts := DefaultCypherOptions()
+	opts.Warn = func(msg string) { warnings = append(warnings, msg) }
+	require.NoError(t, ExportCypher(g, &buf, opts))
+
+	assert.Contains(t, buf.String(), "CREATE (:Person:_RdgImport {_rdg_id: 1, name: 'Alice'});")
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "node 1: skipped property address")
+}
+
+func TestExportCypher_IDPropertyInUse(t *testing.T) {
+	g := storage.NewGraph()
+	g.AddNode("Legacy", graph.Properties{"_rdg_id": "old-7", "_rdg_id_2": 1})
+	g.AddNode("Legacy", nil)
+	g.AddEdge(1, 2, "E", nil)
+
+	// Dropped IDs move to a free property, keeping the node's own value
+	var buf bytes.Buffer
+	require.NoError(t, ExportCypher(g, &buf, DefaultCypherOptions()))
+	out := buf.String()
+	assert.Contains(t, out, "CREATE (:Legacy:_RdgImport {_rdg_id_3: 1, _rdg_id: 'old-7', _rdg_id_2: 1});")
+	assert.Contains(t, out, "MATCH (a:_RdgImport {_rdg_id_3: 1}), (b:_RdgImport {_rdg_id_3: 2})")
+	assert.Contains(t, out, "REMOVE n:_RdgImport, n._rdg_id_3;")
+
+	// Kept IDs would overwrite it
+	opts := DefaultCypherOptions()
+	opts.IncludeIDs = true
+	assert.ErrorContains(t, ExportCypher(g, &bytes.Buffer{}, opts), "node 1 already has a property _rdg_id")
+}
+
+func TestCypherFormatting(t *testing.T) {
+	values := []struct {
+		in   graph.PropertyValue
+		want string
+	}{
+		{"it's", `'it\'s'`},
+		{"a\\b\nc", `'a\\b\nc'`},
+		{int64(-7), "-7"},
+		{2.0, "2.0"},
+		{1e21, "1e+21"},
+		{float32(0.5), "0.5"},
+		{graph.Point{Lat: 51.5, Lon: -0.12}, "point({latitude: 51.5, longitude: -0.12})"},
+		{time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), "datetime('2024-03-01T12:00:00Z')"},
+		{[]graph.PropertyValue{"go", 2}, "['go', 2]"},
+	}
+	for _, v := range values {
+		got, err := cypherValue(v.in)
+		require.NoError(t, err)
+		assert.Equal(t, v.want, got)
+	}
+
+	_, err := cypherValue([]int{1})
+	assert.Error(t, err)
+
+	// Neo4j cannot store maps as property values
+	_, err = cypherValue(graph.Properties{"city": "SF"})
+	assert.Error(t, err)
+
+	assert.Equal(t, "Person", cypherName("Person"))
+	assert.Equal(t, "_x1", cypherName("_x1"))
+	assert.Equal(t, "`first name`", cypherName("first name"))
+	assert.Equal(t, "`1st`", cypherName("1st"))
+	assert.Equal(t, "`a``b`", cypherName("a`b"))
+}
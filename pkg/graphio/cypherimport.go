@@ -0,0 +1,309 @@
+package graphio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+)
+
+// cypherFile names the input of ImportCypher in LineErrors
+const cypherFile = "cypher"
+
+// cypherStatement is one statement of a Cypher file and the line it starts on
+type cypherStatement struct {
+	text string
+	line int
+}
+
+// ImportCypher applies the CREATE statements of a Cypher file to g, such as
+// the sample datasets shipped with Neo4j. Statements end at a semicolon or
+// where a line starting with CREATE begins the next one. Nodes may carry
+// one label and literal properties, including point({...}) and
+// datetime('...') values; relationships need a type and a
+// direction. A variable bound to a node stays bound for the rest of the
+// file, so later statements can connect nodes created earlier:
+//
+//	CREATE (keanu:Person {name: 'Keanu Reeves'});
+//	CREATE (matrix:Movie {title: 'The Matrix'});
+//	CREATE (keanu)-[:ACTED_IN {roles: ['Neo']}]->(matrix);
+//
+// cypher-shell commands such as :begin are skipped. A statement that does
+// not parse or refers to an unbound variable is skipped and recorded in the
+// report. Statements are checked before they are applied, but one that
+// fails part way, e.g. on a schema violation, keeps what it created. The
+// report's IDMap maps each variable to the node it is bound to.
+func ImportCypher(g GraphWriter, r io.Reader) (*ImportReport, error) {
+	report := &ImportReport{IDMap: make(map[string]graph.NodeID)}
+
+	statements, err := splitCypher(r)
+	if err != nil {
+		return report, err
+	}
+	for _, stmt := range statements {
+		if err := applyCypher(g, stmt, report); err != nil {
+			report.Errors = append(report.Errors, LineError{File: cypherFile, Line: stmt.line, Err: err})
+		}
+	}
+	return report, nil
+}
+
+// applyCypher parses and applies one statement. Variables are bound in
+// report.IDMap.
+func applyCypher(g GraphWriter, stmt cypherStatement, report *ImportReport) error {
+	q, err := query.NewParserAt(stmt.text, stmt.line).Parse()
+	if err != nil {
+		return err
+	}
+	if q.Create == nil {
+		return fmt.Errorf("only CREATE statements can be imported")
+	}
+
+	// Resolve every node first so that a bad statement creates nothing.
+	// Each pattern node refers to a node bound earlier in the file or to
+	// one of the nodes to create.
+	type nodeRef struct {
+		created int // Index into created, or -1 for a bound node
+		id      graph.NodeID
+	}
+	type newNode struct {
+		label string
+		props graph.Properties
+	}
+	var created []newNode
+	bound := make(map[string]int) // Variable -> index into created
+	refs := make([][]nodeRef, len(q.Create.Patterns))
+
+	for i, pattern := range q.Create.Patterns {
+		for _, np := range pattern.Nodes {
+			if np.Variable != "" {
+				idx, inStatement := bound[np.Variable]
+				id, inFile := report.IDMap[np.Variable]
+				if inStatement || inFile {
+					if np.Label != "" || len(np.Properties) > 0 {
+						return fmt.Errorf("variable %s is already bound", np.Variable)
+					}
+					if inFile {
+						idx = -1
+					}
+					refs[i] = append(refs[i], nodeRef{created: idx, id: id})
+					continue
+				}
+			}
+
+			props, err := importProperties(np.Properties)
+			if err != nil {
+				return err
+			}
+			if np.Variable != "" {
+				bound[np.Variable] = len(created)
+			}
+			refs[i] = append(refs[i], nodeRef{created: len(created)})
+			created = append(created, newNode{label: np.Label, props: props})
+		}
+	}
+
+	edgeProps := make([][]graph.Properties, len(q.Create.Patterns))
+	for i, pattern := range q.Create.Patterns {
+		for _, ep := range pattern.Edges {
+			props, err := importProperties(ep.Properties)
+			if err != nil {
+				return err
+			}
+			edgeProps[i] = append(edgeProps[i], props)
+		}
+	}
+
+	ids := make([]graph.NodeID, len(created))
+	for i, n := range created {
+		node, err := g.AddNode(n.label, n.props)
+		if err != nil {
+			return err
+		}
+		ids[i] = node.ID
+		report.NodesImported++
+	}
+	for variable, idx := range bound {
+		report.IDMap[variable] = ids[idx]
+	}
+	nodeID := func(ref nodeRef) graph.NodeID {
+		if ref.created < 0 {
+			return ref.id
+		}
+		return ids[ref.created]
+	}
+
+	for i, pattern := range q.Create.Patterns {
+		for j, ep := range pattern.Edges {
+			source, target := nodeID(refs[i][j]), nodeID(refs[i][j+1])
+			if ep.Direction == query.DirectionIn {
+				source, target = target, source
+			}
+			if _, err := g.AddEdge(source, target, ep.Type, edgeProps[i][j]); err != nil {
+				return err
+			}
+			report.EdgesImported++
+		}
+	}
+	return nil
+}
+
+// importProperties converts the inline properties of a pattern
+func importProperties(props map[string]interface{}) (graph.Properties, error) {
+	if len(props) == 0 {
+		return nil, nil
+	}
+	converted := make(graph.Properties, len(props))
+	for k, v := range props {
+		value, err := importValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", k, err)
+		}
+		// Like Neo4j, a null property is not stored
+		if value != nil {
+			converted[k] = value
+		}
+	}
+	return converted, nil
+}
+
+// importValue converts a property value: a literal, a point, a datetime
+// with an ISO-8601 argument, or a list of these
+func importValue(v interface{}) (graph.PropertyValue, error) {
+	switch v := v.(type) {
+	case *query.Literal:
+		return v.Value, nil
+	case *query.PointLiteral:
+		return graph.Point{Lat: v.Lat, Lon: v.Lon}, nil
+	case *query.FunctionCall:
+		if v.Name != "datetime" || len(v.Args) != 1 {
+			return nil, fmt.Errorf("only literal values can be imported")
+		}
+		var s string
+		lit, ok := v.Args[0].(*query.Literal)
+		if ok {
+			s, ok = lit.Value.(string)
+		}
+		if !ok {
+			return nil, fmt.Errorf("datetime expects a string literal")
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid datetime %q", s)
+		}
+		return t, nil
+	case *query.ListLiteral:
+		list := make([]graph.PropertyValue, len(v.Items))
+		for i, item := range v.Items {
+			switch item.(type) {
+			case *query.Literal, *query.PointLiteral, *query.FunctionCall:
+			default:
+				return nil, fmt.Errorf("lists may only hold literals")
+			}
+			value, err := importValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	case query.Expression:
+		return nil, fmt.Errorf("only literal values can be imported")
+	}
+	return v, nil
+}
+
+// splitCypher reads the statements of a Cypher file. Semicolons and line
+// starts inside strings and comments do not end a statement.
+func splitCypher(r io.Reader) ([]cypherStatement, error) {
+	var (
+		statements []cypherStatement
+		current    strings.Builder
+		start      int  // Line the current statement starts on
+		content    bool // Whether the current statement has more than comments
+		quote      byte // Open string quote, or 0
+		inBlock    bool // Inside /* */
+	)
+	// begin starts the statement's text at its first character, keeping
+	// its column, once something other than whitespace and comments appears
+	begin := func(lineNo, column int) {
+		if !content {
+			current.Reset()
+			current.WriteString(strings.Repeat(" ", column))
+			start = lineNo
+			content = true
+		}
+	}
+	flush := func() {
+		if content {
+			statements = append(statements, cypherStatement{text: current.String(), line: start})
+		}
+		current.Reset()
+		content = false
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if quote == 0 && !inBlock {
+			trimmed := strings.TrimSpace(line)
+			if content && len(trimmed) >= 6 && strings.EqualFold(trimmed[:6], "CREATE") {
+				flush()
+			}
+			if !content && strings.HasPrefix(trimmed, ":") {
+				line = "" // cypher-shell command
+			}
+		}
+
+		for i := 0; i < len(line); i++ {
+			ch := line[i]
+			switch {
+			case inBlock:
+				if ch == '*' && i+1 < len(line) && line[i+1] == '/' {
+					inBlock = false
+					current.WriteString("*/")
+					i++
+					continue
+				}
+			case quote != 0:
+				if ch == '\\' && i+1 < len(line) {
+					current.WriteByte(ch)
+					i++
+					ch = line[i]
+				} else if ch == quote {
+					quote = 0
+				}
+			case ch == '\'' || ch == '"':
+				begin(lineNo, i)
+				quote = ch
+			case ch == '/' && i+1 < len(line) && line[i+1] == '*':
+				inBlock = true
+				current.WriteString("/*")
+				i++
+				continue
+			case (ch == '/' || ch == '-') && i+1 < len(line) && line[i+1] == ch:
+				// Line comment: keep it so positions stay the same
+				current.WriteString(line[i:])
+				i = len(line)
+				continue
+			case ch == ';':
+				flush()
+				continue
+			case ch != ' ' && ch != '\t' && ch != '\r':
+				begin(lineNo, i)
+			}
+			current.WriteByte(ch)
+		}
+		current.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Cypher: %w", err)
+	}
+	flush()
+	return statements, nil
+}
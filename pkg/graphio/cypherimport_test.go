@@ -0,0 +1,184 @@
+package graphio
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const movies = `// A few movies
+:begin
+CREATE (keanu:Person {name: 'Keanu Reeves', born: 1964});
+CREATE (carrie:Person {name: "Carrie-Anne Moss; \"Trinity\"", born: 1967}),
+       (matrix:Movie {title: 'The Matrix', released: 1999})
+CREATE (keanu)-[:ACTED_IN {roles: ['Neo']}]->(matrix),
+       (matrix)<-[:ACTED_IN {roles: ['Trinity']}]-(carrie);
+:commit
+/* a second session
+   of statements */
+CREATE (lana:Person {name: 'Lana Wachowski'})-[:DIRECTED]->(matrix)
+`
+
+func TestImportCypher(t *testing.T) {
+	g := storage.NewGraph()
+	report, err := ImportCypher(g, strings.NewReader(movies))
+	require.NoError(t, err)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, 4, report.NodesImported)
+	assert.Equal(t, 3, report.EdgesImported)
+	assert.Equal(t, 4, g.NodeCount())
+	assert.Equal(t, 3, g.EdgeCount())
+
+	matrix, err := g.GetNode(report.IDMap["matrix"])
+	require.NoError(t, err)
+	assert.Equal(t, "Movie", matrix.Label)
+	title, _ := matrix.GetProperty("title")
+	assert.Equal(t, "The Matrix", title)
+
+	carrie, err := g.GetNode(report.IDMap["carrie"])
+	require.NoError(t, err)
+	name, _ := carrie.GetProperty("name")
+	assert.Contains(t, name, "Carrie-Anne Moss;")
+
+	// Both ACTED_IN relationships point at the movie
+	incoming, err := g.GetAdjacent(matrix.ID, graph.Incoming, "ACTED_IN")
+	require.NoError(t, err)
+	require.Len(t, incoming, 2)
+	for _, adj := range incoming {
+		roles, _ := adj.Edge.GetProperty("roles")
+		assert.Len(t, roles, 1)
+	}
+	directors, err := g.GetAdjacent(matrix.ID, graph.Incoming, "DIRECTED")
+	require.NoError(t, err)
+	require.Len(t, directors, 1)
+	assert.Equal(t, report.IDMap["lana"], directors[0].Node.ID)
+}
+
+func TestImportCypher_Errors(t *testing.T) {
+	input := strings.Join([]string{
+		`CREATE (a:Person {name: 'A'});`,
+		`CREATE (a)-[:KNOWS]->(ghost);`,
+		`CREATE (a)-[:KNOWS]->(b:Person {name: 'B';`,
+		`MATCH (n) RETURN n;`,
+		`CREATE (a:Person {name: 'again'});`,
+		`CREATE (c:Person {tags: [c.name]});`,
+		`CREATE (a)-[:KNOWS]->(d:Person {name: 'D'})`,
+	}, "\n")
+
+	g := storage.NewGraph()
+	report, err := ImportCypher(g, strings.NewReader(input))
+	require.NoError(t, err)
+
+	// (ghost) is an unbound variable without a label, so it is created
+	assert.Equal(t, 3, report.NodesImported)
+	assert.Equal(t, 2, report.EdgesImported)
+
+	require.Len(t, report.Errors, 4)
+	lines := make([]int, len(report.Errors))
+	for i, e := range report.Errors {
+		lines[i] = e.Line
+	}
+	assert.Equal(t, []int{3, 4, 5, 6}, lines)
+	assert.Contains(t, report.Errors[0].Error(), "cypher:3:")
+	assert.Contains(t, report.Errors[0].Error(), "at line 3")
+	assert.Contains(t, report.Errors[1].Error(), "only CREATE statements")
+	assert.Contains(t, report.Errors[2].Error(), "variable a is already bound")
+	assert.Contains(t, report.Errors[3].Error(), "lists may only hold literals")
+}
+
+func TestImportCypher_Null(t *testing.T) {
+	g := storage.NewGraph()
+	report, err := ImportCypher(g, strings.NewReader(`CREATE (a:Person {name: 'A', nick: null, tags: ['x', NULL]});`))
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+
+	a, err := g.GetNode(report.IDMap["a"])
+	require.NoError(t, err)
+	assert.Equal(t, graph.Properties{"name": "A", "tags": []graph.PropertyValue{"x", nil}}, a.Properties.Map())
+}
+
+func TestImportCypher_RoundTrip(t *testing.T) {
+	src := createTypedGraph(t)
+	at := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+	carol, err := src.AddNode("Person", graph.Properties{
+		"name":  "Carol 'C'",
+		"home":  graph.Point{Lat: 37.77, Lon: -122.42},
+		"since": at,
+		"tags":  []graph.PropertyValue{"a", "b"},
+	})
+	require.NoError(t, err)
+	_, err = src.AddEdge(carol.ID, 1, "KNOWS", graph.Properties{"at": at})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	opts := DefaultCypherOptions()
+	opts.BatchSize = 2
+	opts.CreateOnly = true
+	require.NoError(t, ExportCypher(src, &buf, opts))
+
+	dst := storage.NewGraph()
+	report, err := ImportCypher(dst, &buf)
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+	assert.Equal(t, src.NodeCount(), dst.NodeCount())
+	assert.Equal(t, src.EdgeCount(), dst.EdgeCount())
+
+	for variable, id := range report.IDMap {
+		want, err := src.GetNode(graph.NodeID(mustAtoi(t, strings.TrimPrefix(variable, "n"))))
+		require.NoError(t, err)
+		got, err := dst.GetNode(id)
+		require.NoError(t, err)
+		assert.Equal(t, want.Label, got.Label)
+		assert.Len(t, got.OutEdges, len(want.OutEdges))
+		for k, v := range want.Properties.Map() {
+			if v == nil {
+				continue
+			}
+			actual, ok := got.GetProperty(k)
+			require.True(t, ok, "property %s of %s", k, variable)
+			if wantTime, isTime := v.(time.Time); isTime {
+				assert.True(t, wantTime.Equal(actual.(time.Time)), "property %s of %s", k, variable)
+			} else {
+				assert.EqualValues(t, v, actual, "property %s of %s", k, variable)
+			}
+		}
+	}
+
+	knows, err := dst.GetAdjacent(report.IDMap[fmt.Sprintf("n%d", carol.ID)], graph.Outgoing, "KNOWS")
+	require.NoError(t, err)
+	require.Len(t, knows, 1)
+	edgeAt, _ := knows[0].Edge.GetProperty("at")
+	assert.True(t, at.Equal(edgeAt.(time.Time)))
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	t.Helper()
+	n, err := strconv.Atoi(s)
+	require.NoError(t, err)
+	return n
+}
+
+func TestImportCypher_Persistent(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg, err := storage.NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	report, err := ImportCypher(pg, strings.NewReader(movies))
+	require.NoError(t, err)
+	assert.Empty(t, report.Errors)
+	require.NoError(t, pg.Close())
+
+	pg, err = storage.NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+	assert.Equal(t, 4, pg.NodeCount())
+	assert.Equal(t, 3, pg.EdgeCount())
+}
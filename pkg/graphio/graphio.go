@@ -0,0 +1,46 @@
+// Package graphio implements bulk import and export of graph data
+package graphio
+
+import (
+	"sort"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// GraphWriter is the subset of graph storage needed to load data.
+// Both storage.Graph and storage.PersistentGraph satisfy it.
+type GraphWriter interface {
+	AddNode(label string, properties graph.Properties) (*graph.Node, error)
+	AddEdge(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error)
+}
+
+// IDWriter is implemented by graphs that can store nodes and edges under
+// caller-chosen IDs, which lets a restore preserve the original IDs
+type IDWriter interface {
+	GraphWriter
+	AddNodeWithID(id graph.NodeID, label string, properties graph.Properties) (*graph.Node, error)
+	AddEdgeWithID(id graph.EdgeID, source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error)
+}
+
+// sortedNodeIDs returns the IDs of all nodes in g in ascending order
+func sortedNodeIDs(g *storage.Graph) []graph.NodeID {
+	ids := make([]graph.NodeID, 0, g.NodeCount())
+	g.IterateNodes(func(n *graph.Node) bool {
+		ids = append(ids, n.ID)
+		return true
+	})
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// sortedEdgeIDs returns the IDs of all edges in g in ascending order
+func sortedEdgeIDs(g *storage.Graph) []graph.EdgeID {
+	ids := make([]graph.EdgeID, 0, g.EdgeCount())
+	g.IterateEdges(func(e *graph.Edge) bool {
+		ids = append(ids, e.ID)
+		return true
+	})
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
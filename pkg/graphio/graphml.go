@@ -0,0 +1,424 @@
+package graphio
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// labelKeyID is the reserved GraphML key holding node and edge labels
+const labelKeyID = "label"
+
+// typedJSON is the internal key type of values GraphML has no type for,
+// such as lists, datetimes and points. They are written as the JSON of
+// their graph.TypedValue in a string key marked with rdg.type="json", so
+// other tools still see a string and ImportGraphML restores the value.
+const typedJSON = "json"
+
+// graphMLKey describes a <key> declaration
+type graphMLKey struct {
+	id      string
+	domain  string // "node" or "edge"
+	name    string
+	valType string // boolean, long, double, string, or typedJSON
+}
+
+// graphMLType maps a property value to its GraphML attr.type, or to
+// typedJSON when GraphML has none
+func graphMLType(v graph.PropertyValue) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "long"
+	case float32, float64:
+		return "double"
+	}
+	return typedJSON
+}
+
+// collectKeys scans property types so <key> declarations can be written
+// before any element. Keys seen with conflicting scalar types fall back to
+// string, and to typedJSON if any of the values needs it. Null values are
+// left out of the export and do not declare keys.
+func collectKeys(domain string, props graph.Properties, keys map[string]*graphMLKey) {
+	for name, v := range props {
+		if v == nil {
+			continue
+		}
+		t := graphMLType(v)
+		if k, ok := keys[name]; ok {
+			switch {
+			case k.valType == t:
+			case k.valType == typedJSON || t == typedJSON:
+				k.valType = typedJSON
+			default:
+				k.valType = "string"
+			}
+			continue
+		}
+		keys[name] = &graphMLKey{
+			id:      domain[:1] + "_" + name,
+			domain:  domain,
+			name:    name,
+			valType: t,
+		}
+	}
+}
+
+func sortedKeys(keys map[string]*graphMLKey) []*graphMLKey {
+	list := make([]*graphMLKey, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, k)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].id < list[j].id })
+	return list
+}
+
+// ExportGraphML writes g as a GraphML document. The first pass only scans
+// property types; elements are then streamed to w one at a time.
+func ExportGraphML(g *storage.Graph, w io.Writer) error {
+	nodeKeys := make(map[string]*graphMLKey)
+	edgeKeys := make(map[string]*graphMLKey)
+
+	g.IterateNodes(func(n *graph.Node) bool {
+		n.Mu.RLock()
+		collectKeys("node", n.Properties.Map(), nodeKeys)
+		n.Mu.RUnlock()
+		return true
+	})
+	g.IterateEdges(func(e *graph.Edge) bool {
+		e.Mu.RLock()
+		collectKeys("edge", e.Properties.Map(), edgeKeys)
+		e.Mu.RUnlock()
+		return true
+	})
+
+	bw := bufio.NewWriter(w)
+	enc := xml.NewEncoder(bw)
+	enc.Indent("", "  ")
+
+	if _, err := bw.WriteString(xml.Header); err != nil {
+		return err
+	}
+
+	root := xml.StartElement{
+		Name: xml.Name{Local: "graphml"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: graphMLNamespace}},
+	}
+	if err := enc.EncodeToken(root); err != nil {
+		return err
+	}
+
+	// Key declarations
+	keys := []*graphMLKey{{id: labelKeyID, domain: "all", name: "label", valType: "string"}}
+	keys = append(keys, sortedKeys(nodeKeys)...)
+	keys = append(keys, sortedKeys(edgeKeys)...)
+	for _, k := range keys {
+		valType := k.valType
+		if valType == typedJSON {
+			valType = "string"
+		}
+		el := xml.StartElement{
+			Name: xml.Name{Local: "key"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "id"}, Value: k.id},
+				{Name: xml.Name{Local: "for"}, Value: k.domain},
+				{Name: xml.Name{Local: "attr.name"}, Value: k.name},
+				{Name: xml.Name{Local: "attr.type"}, Value: valType},
+			},
+		}
+		if k.valType == typedJSON {
+			el.Attr = append(el.Attr, xml.Attr{Name: xml.Name{Local: "rdg.type"}, Value: typedJSON})
+		}
+		if err := enc.EncodeToken(el); err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(el.End()); err != nil {
+			return err
+		}
+	}
+
+	graphEl := xml.StartElement{
+		Name: xml.Name{Local: "graph"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "id"}, Value: "G"},
+			{Name: xml.Name{Local: "edgedefault"}, Value: "directed"},
+		},
+	}
+	if err := enc.EncodeToken(graphEl); err != nil {
+		return err
+	}
+
+	var writeErr error
+	g.IterateNodes(func(n *graph.Node) bool {
+		n.Mu.RLock()
+		el := xml.StartElement{
+			Name: xml.Name{Local: "node"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "id"}, Value: fmt.Sprintf("n%d", n.ID)}},
+		}
+		writeErr = writeElement(enc, el, n.Label, n.Properties.Map(), nodeKeys)
+		n.Mu.RUnlock()
+		if writeErr == nil {
+			writeErr = enc.Flush()
+		}
+		return writeErr == nil
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+
+	g.IterateEdges(func(e *graph.Edge) bool {
+		e.Mu.RLock()
+		el := xml.StartElement{
+			Name: xml.Name{Local: "edge"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "id"}, Value: fmt.Sprintf("e%d", e.ID)},
+				{Name: xml.Name{Local: "source"}, Value: fmt.Sprintf("n%d", e.Source)},
+				{Name: xml.Name{Local: "target"}, Value: fmt.Sprintf("n%d", e.Target)},
+			},
+		}
+		writeErr = writeElement(enc, el, e.Label, e.Properties.Map(), edgeKeys)
+		e.Mu.RUnlock()
+		if writeErr == nil {
+			writeErr = enc.Flush()
+		}
+		return writeErr == nil
+	})
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if err := enc.EncodeToken(graphEl.End()); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+	if _, err := bw.WriteString("\n"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeElement writes a node or edge element with its label and data children
+func writeElement(enc *xml.Encoder, el xml.StartElement, label string, props graph.Properties, keys map[string]*graphMLKey) error {
+	if err := enc.EncodeToken(el); err != nil {
+		return err
+	}
+
+	if label != "" {
+		if err := writeData(enc, labelKeyID, label); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(props))
+	for name, v := range props {
+		if v != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		key := keys[name]
+		value, err := formatGraphMLValue(key.valType, props[name])
+		if err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+		if err := writeData(enc, key.id, value); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(el.End())
+}
+
+// formatGraphMLValue formats a value for a key of the given type
+func formatGraphMLValue(valType string, v graph.PropertyValue) (string, error) {
+	if valType != typedJSON {
+		return fmt.Sprint(v), nil
+	}
+	tv, err := graph.EncodeValue(v)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(tv)
+	return string(data), err
+}
+
+func writeData(enc *xml.Encoder, key, value string) error {
+	el := xml.StartElement{
+		Name: xml.Name{Local: "data"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}},
+	}
+	return enc.EncodeElement(value, el)
+}
+
+// parseGraphMLValue converts a <data> value according to its key type
+func parseGraphMLValue(valType, raw string) (graph.PropertyValue, error) {
+	switch valType {
+	case "boolean":
+		return strconv.ParseBool(strings.TrimSpace(raw))
+	case "int", "long":
+		v, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		return int(v), err
+	case "float", "double":
+		return strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	case typedJSON:
+		var tv graph.TypedValue
+		if err := json.Unmarshal([]byte(raw), &tv); err != nil {
+			return nil, err
+		}
+		return tv.Decode()
+	}
+	return raw, nil
+}
+
+// graphMLElement accumulates a node or edge while it is being decoded
+type graphMLElement struct {
+	id     string
+	source string
+	target string
+	label  string
+	props  graph.Properties
+}
+
+// ImportGraphML reads a GraphML document from r into g. Labels are taken
+// from the "label" key (or a Neo4j-style labels attribute); other data
+// values are converted according to their key's attr.type, or decoded from
+// JSON for keys ExportGraphML marked with rdg.type="json".
+func ImportGraphML(g GraphWriter, r io.Reader) (*ImportReport, error) {
+	report := &ImportReport{IDMap: make(map[string]graph.NodeID)}
+	keys := make(map[string]*graphMLKey)
+	var pendingEdges []*graphMLElement
+
+	dec := xml.NewDecoder(r)
+	var current *graphMLElement
+	var dataKey string
+	var dataText strings.Builder
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to decode GraphML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			attrs := make(map[string]string, len(t.Attr))
+			for _, a := range t.Attr {
+				attrs[a.Name.Local] = a.Value
+			}
+
+			switch t.Name.Local {
+			case "key":
+				valType := attrs["attr.type"]
+				if attrs["rdg.type"] == typedJSON {
+					valType = typedJSON
+				}
+				keys[attrs["id"]] = &graphMLKey{
+					id:      attrs["id"],
+					domain:  attrs["for"],
+					name:    attrs["attr.name"],
+					valType: valType,
+				}
+			case "node", "edge":
+				current = &graphMLElement{
+					id:     attrs["id"],
+					source: attrs["source"],
+					target: attrs["target"],
+					label:  strings.TrimPrefix(attrs["labels"], ":"),
+					props:  graph.Properties{},
+				}
+			case "data":
+				dataKey = attrs["key"]
+				dataText.Reset()
+			}
+
+		case xml.CharData:
+			if dataKey != "" {
+				dataText.Write(t)
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "data":
+				if current != nil {
+					if err := current.setData(keys, dataKey, dataText.String()); err != nil {
+						line, _ := dec.InputPos()
+						report.Errors = append(report.Errors, LineError{File: "graphml", Line: line,
+							Err: fmt.Errorf("element %s: %w", current.id, err)})
+					}
+				}
+				dataKey = ""
+			case "node":
+				node, err := g.AddNode(current.label, current.props)
+				if err != nil {
+					return report, fmt.Errorf("failed to add node %s: %w", current.id, err)
+				}
+				report.IDMap[current.id] = node.ID
+				report.NodesImported++
+				current = nil
+			case "edge":
+				// Edges may precede their endpoints; resolve at the end
+				pendingEdges = append(pendingEdges, current)
+				current = nil
+			}
+		}
+	}
+
+	for _, e := range pendingEdges {
+		source, okSrc := report.IDMap[e.source]
+		target, okTgt := report.IDMap[e.target]
+		if !okSrc || !okTgt {
+			report.EdgesSkipped++
+			report.Errors = append(report.Errors, LineError{File: "graphml",
+				Err: fmt.Errorf("edge %s references unknown node", e.id)})
+			continue
+		}
+		if _, err := g.AddEdge(source, target, e.label, e.props); err != nil {
+			return report, fmt.Errorf("failed to add edge %s: %w", e.id, err)
+		}
+		report.EdgesImported++
+	}
+
+	return report, nil
+}
+
+func (el *graphMLElement) setData(keys map[string]*graphMLKey, keyID, raw string) error {
+	key, ok := keys[keyID]
+	if !ok {
+		return fmt.Errorf("undeclared key %q", keyID)
+	}
+	if key.id == labelKeyID {
+		el.label = raw
+		return nil
+	}
+
+	v, err := parseGraphMLValue(key.valType, raw)
+	if err != nil {
+		return fmt.Errorf("key %s: %w", key.name, err)
+	}
+	el.props[key.name] = v
+	return nil
+}
@@ -0,0 +1,161 @@
+package graphio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createSampleGraph(t *testing.T) *storage.Graph {
+	g := storage.NewGraph()
+
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice", "age": 30, "score": 1.5, "active": true})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob <b>", "age": 25})
+	google, _ := g.AddNode("Company", graph.Properties{"name": "Google"})
+
+	g.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+	g.AddEdge(alice.ID, google.ID, "WORKS_AT", graph.Properties{"role": "Engineer"})
+	return g
+}
+
+// findNode returns the first node with the given name property
+func findNode(g *storage.Graph, name string) *graph.Node {
+	var found *graph.Node
+	g.IterateNodes(func(n *graph.Node) bool {
+		if v, _ := n.GetProperty("name"); v == name {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func TestGraphML_RoundTrip(t *testing.T) {
+	src := createSampleGraph(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, ExportGraphML(src, &buf))
+	assert.Contains(t, buf.String(), `attr.name="age" attr.type="long"`)
+
+	dst := storage.NewGraph()
+	report, err := ImportGraphML(dst, &buf)
+	require.NoError(t, err)
+	assert.Empty(t, report.Errors)
+
+	assert.Equal(t, src.NodeCount(), dst.NodeCount())
+	assert.Equal(t, src.EdgeCount(), dst.EdgeCount())
+
+	alice := findNode(dst, "Alice")
+	require.NotNil(t, alice)
+	assert.Equal(t, "Person", alice.Label)
+	age, _ := alice.GetProperty("age")
+	assert.Equal(t, 30, age)
+	score, _ := alice.GetProperty("score")
+	assert.Equal(t, 1.5, score)
+	active, _ := alice.GetProperty("active")
+	assert.Equal(t, true, active)
+
+	// Escaped content survives
+	assert.NotNil(t, findNode(dst, "Bob <b>"))
+
+	neighbors, _ := dst.GetNeighbors(alice.ID)
+	assert.Len(t, neighbors, 2)
+
+	var knows *graph.Edge
+	dst.IterateEdges(func(e *graph.Edge) bool {
+		if e.Label == "KNOWS" {
+			knows = e
+		}
+		return true
+	})
+	require.NotNil(t, knows)
+	since, _ := knows.GetProperty("since")
+	assert.Equal(t, 2020, since)
+}
+
+func TestGraphML_MixedTypesFallBackToString(t *testing.T) {
+	g := storage.NewGraph()
+	g.AddNode("Thing", graph.Properties{"code": 1})
+	g.AddNode("Thing", graph.Properties{"code": "A1"})
+
+	var buf bytes.Buffer
+	require.NoError(t, ExportGraphML(g, &buf))
+	assert.Contains(t, buf.String(), `attr.name="code" attr.type="string"`)
+}
+
+func TestGraphML_RoundTripStructuredValues(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+	props := graph.Properties{
+		"name":  "Carol",
+		"tags":  []graph.PropertyValue{"a", "b"},
+		"home":  graph.Point{Lat: 37.77, Lon: -122.42},
+		"since": at,
+		"meta":  graph.Properties{"level": 2, "ratio": 0.5},
+		"code":  7,
+	}
+	src := storage.NewGraph()
+	_, err := src.AddNode("Person", props)
+	require.NoError(t, err)
+	// A key holding a list on one node and an int on another
+	_, err = src.AddNode("Person", graph.Properties{"name": "Dave", "code": []graph.PropertyValue{1, 2}})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, ExportGraphML(src, &buf))
+	assert.Contains(t, buf.String(), `attr.name="tags" attr.type="string" rdg.type="json"`)
+
+	dst := storage.NewGraph()
+	report, err := ImportGraphML(dst, &buf)
+	require.NoError(t, err)
+	assert.Empty(t, report.Errors)
+
+	carol := findNode(dst, "Carol")
+	require.NotNil(t, carol)
+	got := carol.Properties.Map()
+	since, ok := got["since"].(time.Time)
+	require.True(t, ok)
+	assert.True(t, at.Equal(since))
+	delete(got, "since")
+	delete(props, "since")
+	assert.Equal(t, props, got)
+
+	dave := findNode(dst, "Dave")
+	require.NotNil(t, dave)
+	code, _ := dave.GetProperty("code")
+	assert.Equal(t, []graph.PropertyValue{1, 2}, code)
+}
+
+func TestImportGraphML_ForeignDocument(t *testing.T) {
+	// Edges before nodes and no label key, as other tools may produce
+	doc := `<?xml version="1.0"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="node" attr.name="weight" attr.type="double"/>
+  <graph edgedefault="directed">
+    <edge source="a" target="b"/>
+    <edge source="a" target="missing"/>
+    <node id="a" labels=":City"><data key="d0">2.5</data></node>
+    <node id="b"><data key="d0">oops</data></node>
+  </graph>
+</graphml>`
+
+	g := storage.NewGraph()
+	report, err := ImportGraphML(g, strings.NewReader(doc))
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, report.NodesImported)
+	assert.Equal(t, 1, report.EdgesImported)
+	assert.Equal(t, 1, report.EdgesSkipped)
+	assert.Len(t, report.Errors, 2)
+
+	a, _ := g.GetNode(report.IDMap["a"])
+	assert.Equal(t, "City", a.Label)
+	w, _ := a.GetProperty("weight")
+	assert.Equal(t, 2.5, w)
+}
@@ -0,0 +1,215 @@
+package graphio
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// dumpFile is the file name used in LineErrors reported by Restore
+const dumpFile = "dump"
+
+// dumpRecord is one line of a JSON Lines dump. Property values carry
+// explicit type tags so that ints and floats survive the round trip.
+type dumpRecord struct {
+	Type       string                      `json:"type"` // "node" or "edge"
+	ID         uint64                      `json:"id"`
+	Label      string                      `json:"label,omitempty"`
+	Source     uint64                      `json:"source,omitempty"`
+	Target     uint64                      `json:"target,omitempty"`
+	Properties map[string]graph.TypedValue `json:"properties,omitempty"`
+}
+
+// RestoreOptions configures a JSON Lines restore
+type RestoreOptions struct {
+	BatchSize int  // Records applied per batch (default 1000)
+	RemapIDs  bool // Assign fresh IDs instead of keeping the dumped ones
+	Progress  func(ImportProgress)
+}
+
+// DefaultRestoreOptions returns default restore options
+func DefaultRestoreOptions() RestoreOptions {
+	return RestoreOptions{BatchSize: defaultBatchSize}
+}
+
+// Dump writes g as JSON Lines: every node ordered by ID, then every edge
+// ordered by ID, one JSON object per line
+func Dump(g *storage.Graph, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	for _, id := range sortedNodeIDs(g) {
+		n, err := g.GetNode(id)
+		if err != nil {
+			continue // deleted while dumping
+		}
+		n.Mu.RLock()
+		props, err := graph.EncodeProperties(n.Properties.Map())
+		rec := dumpRecord{Type: "node", ID: uint64(n.ID), Label: n.Label, Properties: props}
+		n.Mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("node %d: %w", id, err)
+		}
+		if err := enc.Encode(&rec); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range sortedEdgeIDs(g) {
+		e, err := g.GetEdge(id)
+		if err != nil {
+			continue
+		}
+		e.Mu.RLock()
+		props, err := graph.EncodeProperties(e.Properties.Map())
+		rec := dumpRecord{
+			Type:       "edge",
+			ID:         uint64(e.ID),
+			Label:      e.Label,
+			Source:     uint64(e.Source),
+			Target:     uint64(e.Target),
+			Properties: props,
+		}
+		e.Mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("edge %d: %w", id, err)
+		}
+		if err := enc.Encode(&rec); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Restore loads a dump written by Dump into g. Gzip-compressed input is
+// detected automatically. Unless opts.RemapIDs is set, g must implement
+// IDWriter so the dumped IDs can be kept.
+func Restore(g GraphWriter, r io.Reader, opts RestoreOptions) (*ImportReport, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	report := &ImportReport{IDMap: make(map[string]graph.NodeID)}
+
+	idw, canKeepIDs := g.(IDWriter)
+	if !opts.RemapIDs && !canKeepIDs {
+		return report, fmt.Errorf("graph cannot preserve IDs; restore with RemapIDs")
+	}
+
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return report, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	type pending struct {
+		line int
+		rec  dumpRecord
+	}
+	batch := make([]pending, 0, opts.BatchSize)
+	lines := 0
+
+	apply := func(p pending) error {
+		props, err := graph.DecodeProperties(p.rec.Properties)
+		if err != nil {
+			return err
+		}
+
+		switch p.rec.Type {
+		case "node":
+			var node *graph.Node
+			if opts.RemapIDs {
+				node, err = g.AddNode(p.rec.Label, props)
+			} else {
+				node, err = idw.AddNodeWithID(graph.NodeID(p.rec.ID), p.rec.Label, props)
+			}
+			if err != nil {
+				report.NodesSkipped++
+				return err
+			}
+			report.IDMap[strconv.FormatUint(p.rec.ID, 10)] = node.ID
+			report.NodesImported++
+
+		case "edge":
+			source, target := graph.NodeID(p.rec.Source), graph.NodeID(p.rec.Target)
+			if opts.RemapIDs {
+				var okSrc, okTgt bool
+				source, okSrc = report.IDMap[strconv.FormatUint(p.rec.Source, 10)]
+				target, okTgt = report.IDMap[strconv.FormatUint(p.rec.Target, 10)]
+				if !okSrc || !okTgt {
+					report.EdgesSkipped++
+					return fmt.Errorf("edge %d references unknown node", p.rec.ID)
+				}
+				_, err = g.AddEdge(source, target, p.rec.Label, props)
+			} else {
+				_, err = idw.AddEdgeWithID(graph.EdgeID(p.rec.ID), source, target, p.rec.Label, props)
+			}
+			if err != nil {
+				report.EdgesSkipped++
+				return err
+			}
+			report.EdgesImported++
+
+		default:
+			return fmt.Errorf("unknown record type %q", p.rec.Type)
+		}
+		return nil
+	}
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for _, p := range batch {
+			if err := apply(p); err != nil {
+				report.Errors = append(report.Errors, LineError{File: dumpFile, Line: p.line, Err: err})
+			}
+		}
+		lines += len(batch)
+		batch = batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(ImportProgress{
+				File:  dumpFile,
+				Lines: lines,
+				Nodes: report.NodesImported,
+				Edges: report.EdgesImported,
+			})
+		}
+	}
+
+	for line := 1; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			var rec dumpRecord
+			if jsonErr := json.Unmarshal(data, &rec); jsonErr != nil {
+				report.Errors = append(report.Errors, LineError{File: dumpFile, Line: line, Err: jsonErr})
+			} else {
+				batch = append(batch, pending{line: line, rec: rec})
+				if len(batch) >= opts.BatchSize {
+					flush()
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to read dump: %w", err)
+		}
+	}
+	flush()
+
+	return report, nil
+}
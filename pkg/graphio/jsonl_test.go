@@ -0,0 +1,135 @@
+package graphio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTypedGraph builds a graph using every supported property type
+func createTypedGraph(t *testing.T) *storage.Graph {
+	g := storage.NewGraph()
+
+	alice, _ := g.AddNode("Person", graph.Properties{
+		"name":   "Alice",
+		"age":    30,
+		"score":  1.5,
+		"weight": 70.0,
+		"active": true,
+		"nick":   nil,
+	})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	_, err := g.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020, "close": false})
+	require.NoError(t, err)
+	return g
+}
+
+func assertTypedGraph(t *testing.T, g *storage.Graph, aliceID graph.NodeID) {
+	alice, err := g.GetNode(aliceID)
+	require.NoError(t, err)
+
+	assert.Equal(t, graph.Properties{
+		"name":   "Alice",
+		"age":    30,
+		"score":  1.5,
+		"weight": 70.0,
+		"active": true,
+		"nick":   nil,
+	}, alice.Properties.Map())
+
+	require.Len(t, alice.OutEdges, 1)
+	edge, err := g.GetEdge(alice.OutEdges[0])
+	require.NoError(t, err)
+	assert.Equal(t, "KNOWS", edge.Label)
+	assert.Equal(t, graph.Properties{"since": 2020, "close": false}, edge.Properties.Map())
+}
+
+func TestJSONL_RoundTrip(t *testing.T) {
+	src := createTypedGraph(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Dump(src, &buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"type":"node"`)
+	assert.Contains(t, lines[0], `"age":{"type":"int","value":30}`)
+	assert.Contains(t, lines[2], `"type":"edge"`)
+
+	dst := storage.NewGraph()
+	report, err := Restore(dst, &buf, DefaultRestoreOptions())
+	require.NoError(t, err)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, 2, report.NodesImported)
+	assert.Equal(t, 1, report.EdgesImported)
+
+	// IDs are preserved by default
+	assertTypedGraph(t, dst, 1)
+
+	// New nodes do not collide with restored IDs
+	n, err := dst.AddNode("Person", nil)
+	require.NoError(t, err)
+	assert.Equal(t, graph.NodeID(3), n.ID)
+}
+
+func TestJSONL_RemapIDs(t *testing.T) {
+	src := createTypedGraph(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, Dump(src, &buf))
+
+	// Restoring into a graph that already uses the IDs only works with remapping
+	dst := createTypedGraph(t)
+	report, err := Restore(dst, bytes.NewReader(buf.Bytes()), DefaultRestoreOptions())
+	require.NoError(t, err)
+	assert.Len(t, report.Errors, 3)
+	assert.Equal(t, 2, dst.NodeCount())
+
+	opts := DefaultRestoreOptions()
+	opts.RemapIDs = true
+	report, err = Restore(dst, bytes.NewReader(buf.Bytes()), opts)
+	require.NoError(t, err)
+	assert.Empty(t, report.Errors)
+	assert.Equal(t, 4, dst.NodeCount())
+	assert.Equal(t, 2, dst.EdgeCount())
+
+	assertTypedGraph(t, dst, report.IDMap["1"])
+}
+
+func TestJSONL_Gzip(t *testing.T) {
+	src := createTypedGraph(t)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	require.NoError(t, Dump(src, gz))
+	require.NoError(t, gz.Close())
+
+	dst := storage.NewGraph()
+	_, err := Restore(dst, &buf, DefaultRestoreOptions())
+	require.NoError(t, err)
+	assertTypedGraph(t, dst, 1)
+}
+
+func TestJSONL_BadLines(t *testing.T) {
+	input := `{"type":"node","id":1,"label":"A"}
+not json
+{"type":"edge","id":1,"source":1,"target":9,"label":"X"}
+{"type":"widget","id":2}
+`
+	dst := storage.NewGraph()
+	report, err := Restore(dst, strings.NewReader(input), DefaultRestoreOptions())
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.NodesImported)
+	assert.Equal(t, 1, report.EdgesSkipped)
+	require.Len(t, report.Errors, 3)
+	assert.Equal(t, 2, report.Errors[0].Line)
+	assert.Equal(t, 3, report.Errors[1].Line)
+	assert.Equal(t, 4, report.Errors[2].Line)
+}
@@ -0,0 +1,141 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// AggregateFunction reduces the values its argument takes over the matches
+// of a group, e.g. collect(f.name)
+type AggregateFunction func(values []interface{}) (interface{}, error)
+
+// aggregateFunctions maps lowercased function names to aggregates. They
+// may appear in RETURN and WITH items, which are then grouped by the items
+// without one.
+var aggregateFunctions = map[string]AggregateFunction{
+	"collect": fnCollect,
+}
+
+// fnCollect gathers the values of a group into a list, leaving out nulls
+func fnCollect(values []interface{}) (interface{}, error) {
+	list := make([]graph.PropertyValue, 0, len(values))
+	for _, v := range values {
+		if v != nil {
+			list = append(list, v)
+		}
+	}
+	return list, nil
+}
+
+// aggregateVariable names the variable the i-th aggregate of an
+// AggregateOperator is bound to
+func aggregateVariable(i int) string {
+	return "_agg" + strconv.Itoa(i)
+}
+
+// planAggregation returns an AggregateOperator for items that call
+// aggregate functions, and the items rewritten to read the aggregates from
+// its variables, keeping their column names. Items without aggregates are
+// returned as they are, with no operator.
+func planAggregation(items []ReturnItem) (*AggregateOperator, []ReturnItem, error) {
+	agg := &AggregateOperator{}
+	rewritten := make([]ReturnItem, len(items))
+	for i, item := range items {
+		expr, err := rewriteAggregates(item.Expr, agg, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if expr == item.Expr {
+			agg.Keys = append(agg.Keys, item.Expr)
+			rewritten[i] = item
+			continue
+		}
+		rewritten[i] = ReturnItem{Expr: expr, Alias: item.columnName()}
+	}
+	if len(agg.Aggregates) == 0 {
+		return nil, items, nil
+	}
+	return agg, rewritten, nil
+}
+
+// rewriteAggregates replaces the aggregate calls in expr with identifiers
+// of the variables agg binds them to. expr itself is returned if it has
+// none; otherwise the expressions around the calls are copied.
+func rewriteAggregates(expr Expression, agg *AggregateOperator, inAggregate bool) (Expression, error) {
+	switch e := expr.(type) {
+	case *FunctionCall:
+		if _, ok := aggregateFunctions[e.Name]; ok {
+			if inAggregate {
+				return nil, fmt.Errorf("aggregate function %s cannot be nested in another", e.Name)
+			}
+			if len(e.Args) != 1 {
+				return nil, fmt.Errorf("%s expects 1 argument, got %d", e.Name, len(e.Args))
+			}
+			if _, err := rewriteAggregates(e.Args[0], agg, true); err != nil {
+				return nil, err
+			}
+			agg.Aggregates = append(agg.Aggregates, e)
+			return &Identifier{Name: aggregateVariable(len(agg.Aggregates) - 1)}, nil
+		}
+		args, changed, err := rewriteAll(e.Args, agg, inAggregate)
+		if err != nil || !changed {
+			return e, err
+		}
+		return &FunctionCall{Name: e.Name, Spelling: e.Spelling, Args: args}, nil
+
+	case *BinaryExpr:
+		operands, changed, err := rewriteAll([]Expression{e.Left, e.Right}, agg, inAggregate)
+		if err != nil || !changed {
+			return e, err
+		}
+		return &BinaryExpr{Left: operands[0], Operator: e.Operator, Right: operands[1]}, nil
+
+	case *UnaryExpr:
+		operand, err := rewriteAggregates(e.Operand, agg, inAggregate)
+		if err != nil || operand == e.Operand {
+			return e, err
+		}
+		return &UnaryExpr{Operator: e.Operator, Operand: operand}, nil
+
+	case *ListLiteral:
+		items, changed, err := rewriteAll(e.Items, agg, inAggregate)
+		if err != nil || !changed {
+			return e, err
+		}
+		return &ListLiteral{Items: items}, nil
+
+	case *MapLiteral:
+		entries := make(map[string]Expression, len(e.Entries))
+		changed := false
+		for key, value := range e.Entries {
+			rewritten, err := rewriteAggregates(value, agg, inAggregate)
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = rewritten
+			changed = changed || rewritten != value
+		}
+		if !changed {
+			return e, nil
+		}
+		return &MapLiteral{Entries: entries}, nil
+	}
+	return expr, nil
+}
+
+// rewriteAll rewrites each of exprs, reporting whether any changed
+func rewriteAll(exprs []Expression, agg *AggregateOperator, inAggregate bool) ([]Expression, bool, error) {
+	rewritten := make([]Expression, len(exprs))
+	changed := false
+	for i, expr := range exprs {
+		r, err := rewriteAggregates(expr, agg, inAggregate)
+		if err != nil {
+			return nil, false, err
+		}
+		rewritten[i] = r
+		changed = changed || r != expr
+	}
+	return rewritten, changed, nil
+}
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createFriendsGraph creates Alice, who knows Bob, Charlie and a nameless
+// person, Bob, who knows Charlie, and Charlie, who knows nobody
+func createFriendsGraph(t *testing.T) *storage.Graph {
+	g := storage.NewGraph()
+	alice, err := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	charlie, _ := g.AddNode("Person", graph.Properties{"name": "Charlie"})
+	nameless, _ := g.AddNode("Person", nil)
+	for _, edge := range [][2]graph.NodeID{
+		{alice.ID, bob.ID}, {alice.ID, charlie.ID}, {alice.ID, nameless.ID}, {bob.ID, charlie.ID},
+	} {
+		_, err := g.AddEdge(edge[0], edge[1], "KNOWS", nil)
+		require.NoError(t, err)
+	}
+	return g
+}
+
+func TestExecute_Collect(t *testing.T) {
+	g := createFriendsGraph(t)
+
+	// One row per person, with nulls left out of the list
+	result := run(t, g, `MATCH (p:Person)-[:KNOWS]->(f) RETURN p.name, collect(f.name) AS friends ORDER BY p.name`)
+	assert.Equal(t, []string{"p.name", "friends"}, result.Columns)
+	assert.Equal(t, []Row{
+		{"p.name": "Alice", "friends": []graph.PropertyValue{"Bob", "Charlie"}},
+		{"p.name": "Bob", "friends": []graph.PropertyValue{"Charlie"}},
+	}, result.Rows)
+
+	// Aggregates may be nested in expressions, which name their columns
+	result = run(t, g, `MATCH (p:Person)-[:KNOWS]->(f) WHERE p.name = "Alice" RETURN size(collect(f)), head(collect(f.name))`)
+	assert.Equal(t, []string{"size(collect(f))", "head(collect(f.name))"}, result.Columns)
+	assert.Equal(t, []Row{{"size(collect(f))": 3, "head(collect(f.name))": "Bob"}}, result.Rows)
+	result = run(t, g, `MATCH (p:Person)-[:KNOWS]->(f) WHERE p.name = "Bob" RETURN COLLECT(f.name)`)
+	assert.Equal(t, []Row{{"COLLECT(f.name)": []graph.PropertyValue{"Charlie"}}}, result.Rows)
+
+	// Without grouping keys there is a single row, even with no matches
+	result = run(t, g, `MATCH (p:Person) RETURN collect(p.name) AS names`)
+	require.Len(t, result.Rows, 1)
+	assert.ElementsMatch(t, []graph.PropertyValue{"Alice", "Bob", "Charlie"}, result.Rows[0]["names"])
+	result = run(t, g, `MATCH (p:Robot) RETURN collect(p.name) AS names`)
+	assert.Equal(t, []Row{{"names": []graph.PropertyValue{}}}, result.Rows)
+
+	// WITH groups too, and its aggregates can be filtered on
+	result = run(t, g, `MATCH (p:Person)-[:KNOWS]->(f) WITH p, collect(f.name) AS friends WHERE size(friends) > 1 RETURN p.name, friends`)
+	assert.Equal(t, []Row{{"p.name": "Alice", "friends": []graph.PropertyValue{"Bob", "Charlie"}}}, result.Rows)
+}
+
+func TestExecute_CollectErrors(t *testing.T) {
+	g := createFriendsGraph(t)
+	for input, msg := range map[string]string{
+		`MATCH (p:Person) RETURN collect(p.name, p.age)`:            "collect expects 1 argument, got 2",
+		`MATCH (p:Person) RETURN collect(collect(p.name))`:          "aggregate function collect cannot be nested in another",
+		`MATCH (p:Person) WHERE size(collect(p.name)) > 1 RETURN p`: "aggregate function collect is only allowed in RETURN and WITH",
+	} {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		_, err = q.Execute(g)
+		assert.ErrorContains(t, err, msg, input)
+	}
+}
+
+func TestExecute_CollectWithLimit(t *testing.T) {
+	g := storage.NewGraph()
+	for i, city := range []string{"SF", "SF", "NY", "SF", "LA"} {
+		_, err := g.AddNode("Person", graph.Properties{"name": fmt.Sprintf("p%d", i), "city": city})
+		require.NoError(t, err)
+	}
+
+	// LIMIT applies to the groups, after every match is aggregated
+	result := run(t, g, `MATCH (n:Person) RETURN collect(n.name) AS names LIMIT 1`)
+	require.Len(t, result.Rows, 1)
+	assert.ElementsMatch(t, []graph.PropertyValue{"p0", "p1", "p2", "p3", "p4"}, result.Rows[0]["names"])
+
+	result = run(t, g, `MATCH (n:Person) RETURN n.city AS city, collect(n.name) AS names LIMIT 2`)
+	assert.Equal(t, []Row{
+		{"city": "SF", "names": []graph.PropertyValue{"p0", "p1", "p3"}},
+		{"city": "NY", "names": []graph.PropertyValue{"p2"}},
+	}, result.Rows)
+}
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// isArithmetic reports whether op is an arithmetic operator
+func isArithmetic(op string) bool {
+	switch op {
+	case "+", "-", "*", "/", "%":
+		return true
+	}
+	return false
+}
+
+// arithmetic applies an arithmetic operator. Integers stay integers, with
+// / truncating, and any float operand makes the result a float. + also
+// concatenates strings and lists. A null operand gives null.
+func arithmetic(left interface{}, op string, right interface{}) (interface{}, error) {
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	if op == "+" {
+		switch l := left.(type) {
+		case string:
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		case []graph.PropertyValue:
+			if r, ok := right.([]graph.PropertyValue); ok {
+				list := make([]graph.PropertyValue, 0, len(l)+len(r))
+				return append(append(list, l...), r...), nil
+			}
+		}
+	}
+	if !isNumber(left) || !isNumber(right) {
+		return nil, fmt.Errorf("%s requires numeric operands, got %T and %T", op, left, right)
+	}
+
+	l, lInt := toInteger(left)
+	r, rInt := toInteger(right)
+	if lInt && rInt {
+		switch op {
+		case "+":
+			return int(l + r), nil
+		case "-":
+			return int(l - r), nil
+		case "*":
+			return int(l * r), nil
+		case "/", "%":
+			if r == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				return int(l / r), nil
+			}
+			return int(l % r), nil
+		}
+	}
+
+	lf, rf := toFloat(left), toFloat(right)
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		return lf / rf, nil
+	case "%":
+		return math.Mod(lf, rf), nil
+	}
+	return nil, fmt.Errorf("unknown arithmetic operator %s", op)
+}
+
+// toInteger returns v as an int64 if it is an integer type
+func toInteger(v interface{}) (int64, bool) {
+	switch i := v.(type) {
+	case int:
+		return int64(i), true
+	case int8:
+		return int64(i), true
+	case int16:
+		return int64(i), true
+	case int32:
+		return int64(i), true
+	case int64:
+		return i, true
+	case uint:
+		return int64(i), true
+	case uint8:
+		return int64(i), true
+	case uint16:
+		return int64(i), true
+	case uint32:
+		return int64(i), true
+	case uint64:
+		return int64(i), true
+	}
+	return 0, false
+}
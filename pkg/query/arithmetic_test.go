@@ -0,0 +1,71 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArithmetic(t *testing.T) {
+	for _, tc := range []struct {
+		left  interface{}
+		op    string
+		right interface{}
+		want  interface{}
+	}{
+		{1, "+", 1, 2},
+		{int64(7), "-", 10, -3},
+		{6, "*", 7, 42},
+		{7, "/", 2, 3},
+		{7, "%", 3, 1},
+		{7, "/", 2.0, 3.5},
+		{1.5, "+", 1, 2.5},
+		{7.5, "%", 2, 1.5},
+		{"rdg", "+", "DB", "rdgDB"},
+		{[]graph.PropertyValue{1}, "+", []graph.PropertyValue{2, 3}, []graph.PropertyValue{1, 2, 3}},
+		{nil, "+", 1, nil},
+		{1, "*", nil, nil},
+	} {
+		got, err := arithmetic(tc.left, tc.op, tc.right)
+		require.NoError(t, err, "%v %s %v", tc.left, tc.op, tc.right)
+		assert.Equal(t, tc.want, got, "%v %s %v", tc.left, tc.op, tc.right)
+	}
+
+	_, err := arithmetic(1, "/", 0)
+	assert.EqualError(t, err, "division by zero")
+	_, err = arithmetic("a", "-", 1)
+	assert.Error(t, err)
+	_, err = arithmetic("a", "+", 1)
+	assert.Error(t, err)
+}
+
+func TestParser_Arithmetic(t *testing.T) {
+	for input, want := range map[string]string{
+		`RETURN 1 + 2 * 3`:        "1 + 2 * 3",
+		`RETURN (1 + 2) * 3`:      "(1 + 2) * 3",
+		`RETURN 10 - (4 - 1)`:     "10 - (4 - 1)",
+		`RETURN 10 - 4 - 1`:       "10 - 4 - 1",
+		`RETURN 2 * -3`:           "2 * -3",
+		`RETURN n.age + 1 > 30`:   "n.age + 1 > 30",
+		`RETURN size(n.tags) % 2`: "size(n.tags) % 2",
+	} {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		assert.Equal(t, want, q.Return.Items[0].columnName(), input)
+	}
+}
+
+func TestExecute_Arithmetic(t *testing.T) {
+	result := run(t, storage.NewGraph(), `RETURN 1 + 2 * 3 AS a, (1 + 2) * 3 AS b, 10 - 4 - 1 AS c, 7 / 2 AS d`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, Row{"a": 7, "b": 9, "c": 5, "d": 3}, result.Rows[0])
+
+	g := createTestGraph(t)
+	result = run(t, g, `MATCH (p:Person) WHERE p.age + 5 > 35 RETURN p.name, p.age * 2 AS double`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Charlie", result.Rows[0]["p.name"])
+	assert.Equal(t, 70, result.Rows[0]["double"])
+}
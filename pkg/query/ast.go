@@ -1,13 +1,128 @@
 // Package query - AST (Abstract Syntax Tree) type definitions
 package query
 
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // Query represents a complete RQL query
 type Query struct {
 	Match   *MatchClause
 	Where   *WhereClause
 	Return  *ReturnClause
 	OrderBy *OrderByClause
-	Limit   *int
+	Limit   *int // Without ORDER BY, the start node is scanned in ID order
+	Call    *CallClause
+	Show    *ShowClause
+	Create  *CreateClause
+
+	// A query with WITH is a chain of parts. Input is the part before the
+	// WITH, whose projected rows seed this part's matches, and With is set
+	// on the part the WITH clause ends. Execution settings such as
+	// HopLimit and Parameters are taken from the last part.
+	Input *Query
+	With  *WithClause
+
+	// Union, when set, makes this query the combination of two others;
+	// its other clauses are unused
+	Union *UnionQuery
+
+	// HopLimit overrides DefaultHopLimit for unbounded variable-length patterns
+	HopLimit *HopLimit
+
+	// ScanParallelism overrides DefaultScanParallelism
+	ScanParallelism *ScanParallelism
+
+	// Hints override the planner's choice of start node
+	Hints *PlannerHints
+
+	// Temporal restricts matched edges to those valid at a point in time
+	Temporal *TemporalFilter
+
+	// Parameters supplies the values of $name references, keyed by name
+	Parameters map[string]interface{}
+
+	// StableOrder makes label scans visit nodes in ID order and expansions
+	// follow edges in ID order, so repeated executions over the same graph
+	// return rows in the same order. Without it or ORDER BY, row order is
+	// unspecified: scans follow the storage's map iteration, which changes
+	// between runs, unless a LIMIT is present.
+	StableOrder bool
+}
+
+// UnionQuery is Left UNION Right, or Left UNION ALL Right. Both sides
+// must return the same columns in the same order. A chain of unions
+// nests to the left.
+type UnionQuery struct {
+	Left     *Query
+	Right    *Query
+	Distinct bool // UNION removes duplicate rows; UNION ALL keeps them
+}
+
+// TemporalFilter is an AS OF TIMESTAMP clause. An edge matches when its
+// "from" property is at or before At and its "to" property is absent or
+// at or after At.
+type TemporalFilter struct {
+	At time.Time
+}
+
+// PlannerHints carries planner directives such as USING INDEX n:Person(name)
+type PlannerHints struct {
+	StartVariable string // Pattern variable the scan starts from
+
+	// NoHashJoin joins independent patterns by extending every match with
+	// each match of the next pattern, then filtering, as a baseline
+	NoHashJoin bool
+}
+
+// CallClause represents a procedure call like CALL db.refreshStats("pagerank").
+// After MATCH, as in MATCH (n) CALL db.histogram(n, "latency_ms"), the
+// procedure is called once per match and the query returns its rows.
+type CallClause struct {
+	Procedure string // Dotted name, e.g. "db.refreshStats"
+	Args      []Expression
+}
+
+// ShowTarget is the kind of metadata a SHOW statement lists
+type ShowTarget int
+
+const (
+	ShowLabels            ShowTarget = iota // SHOW LABELS
+	ShowRelationshipTypes                   // SHOW RELATIONSHIP TYPES
+	ShowIndexes                             // SHOW INDEXES
+	ShowConstraints                         // SHOW CONSTRAINTS
+)
+
+// String returns the statement's target as written in RQL
+func (t ShowTarget) String() string {
+	switch t {
+	case ShowLabels:
+		return "LABELS"
+	case ShowRelationshipTypes:
+		return "RELATIONSHIP TYPES"
+	case ShowIndexes:
+		return "INDEXES"
+	case ShowConstraints:
+		return "CONSTRAINTS"
+	}
+	return fmt.Sprintf("ShowTarget(%d)", int(t))
+}
+
+// ShowClause represents a standalone SHOW statement like SHOW INDEXES
+type ShowClause struct {
+	Target ShowTarget
+}
+
+// CreateClause represents a standalone CREATE statement such as
+// CREATE (a:Person {name: "Alice"})-[:KNOWS]->(b:Person). Executing it
+// needs a graph that can add nodes and edges; graphio.ImportCypher applies
+// a file of CREATE statements.
+type CreateClause struct {
+	Patterns []Pattern
 }
 
 // MatchClause represents the MATCH part of a query
@@ -17,8 +132,9 @@ type MatchClause struct {
 
 // Pattern represents a graph pattern like (a)-[r]->(b)
 type Pattern struct {
-	Nodes []NodePattern
-	Edges []EdgePattern
+	Variable string // Path variable, as in p = (a)-->(b); optional
+	Nodes    []NodePattern
+	Edges    []EdgePattern
 }
 
 // NodePattern represents a node in a pattern
@@ -32,9 +148,10 @@ type NodePattern struct {
 type Direction int
 
 const (
-	DirectionOut  Direction = iota // ->
-	DirectionIn                    // <-
-	DirectionBoth                  // - (undirected)
+	DirectionUnset Direction = iota - 1 // Not yet known; the parser never leaves it set
+	DirectionOut                        // ->
+	DirectionIn                         // <-
+	DirectionBoth                       // - (undirected)
 )
 
 // EdgePattern represents an edge/relationship
@@ -42,8 +159,10 @@ type EdgePattern struct {
 	Variable  string    // e.g., "r"
 	Type      string    // e.g., "KNOWS"
 	Direction Direction // OUT, IN, BOTH
-	MinHops   *int      // For variable-length paths [*1..3]
-	MaxHops   *int
+	MinHops   *int      // For variable-length paths [*1..3]; nil for a single hop
+	MaxHops   *int      // nil when unbounded ([*] or [*2..])
+
+	Properties map[string]interface{} // Inline properties [:KNOWS {since: 2020}]
 }
 
 // WhereClause represents filter conditions
@@ -59,16 +178,25 @@ type Expression interface {
 // BinaryExpr represents binary operations (AND, OR, =, <, >, etc.)
 type BinaryExpr struct {
 	Left     Expression
-	Operator string // "AND", "OR", "=", "!=", "<", ">", "<=", ">="
+	Operator string // "AND", "OR", "=", "!=", "<", ">", "<=", ">=", "CONTAINS", "STARTS WITH", "ENDS WITH", "IN"
 	Right    Expression
 }
 
 func (b *BinaryExpr) expressionNode() {}
 
+// UnaryExpr represents a prefix operation such as NOT
+type UnaryExpr struct {
+	Operator string // "NOT"
+	Operand  Expression
+}
+
+func (u *UnaryExpr) expressionNode() {}
+
 // PropertyAccess represents property access like p.name
 type PropertyAccess struct {
-	Variable string // "p", "friend"
-	Property string // "name", "age"
+	Variable string   // "p", "friend"
+	Property string   // "name", "age"
+	Path     []string // Keys into a map property, e.g. ["city"] for p.address.city
 }
 
 func (p *PropertyAccess) expressionNode() {}
@@ -80,6 +208,20 @@ type Literal struct {
 
 func (l *Literal) expressionNode() {}
 
+// ListLiteral represents a list such as ["a", "b"]
+type ListLiteral struct {
+	Items []Expression
+}
+
+func (l *ListLiteral) expressionNode() {}
+
+// MapLiteral represents a map such as {city: "SF", zip: 94107}
+type MapLiteral struct {
+	Entries map[string]Expression
+}
+
+func (m *MapLiteral) expressionNode() {}
+
 // Identifier represents a variable reference
 type Identifier struct {
 	Name string
@@ -87,10 +229,67 @@ type Identifier struct {
 
 func (i *Identifier) expressionNode() {}
 
+// Parameter represents a $name reference to a query parameter
+type Parameter struct {
+	Name string // Without the leading $
+}
+
+func (p *Parameter) expressionNode() {}
+
+// parameterKey is the binding table key holding a parameter's value
+func parameterKey(name string) string {
+	return "$" + name
+}
+
+// FunctionCall represents a function invocation like distance(a, b)
+type FunctionCall struct {
+	Name     string // Lowercased function name
+	Spelling string // Name as written in the query, for column names
+	Args     []Expression
+}
+
+func (f *FunctionCall) expressionNode() {}
+
+// PointLiteral represents point({lat: 40.7, lon: -74.0}) or
+// point({latitude: 40.7, longitude: -74.0})
+type PointLiteral struct {
+	Lat, Lon float64
+}
+
+func (p *PointLiteral) expressionNode() {}
+
+// PatternExpression is a pattern used as a value, as in
+// size((p)-[:KNOWS]->())
+type PatternExpression struct {
+	Pattern Pattern
+}
+
+func (p *PatternExpression) expressionNode() {}
+
 // ReturnClause specifies what to return
 type ReturnClause struct {
 	Items    []ReturnItem
 	Distinct bool
+	Star     bool // RETURN *: every bound variable, with Items empty
+}
+
+// WithClause projects the matches of a query part into the variables the
+// next part starts with, as in MATCH (a)-->(b) WITH DISTINCT b MATCH ...
+type WithClause struct {
+	Items    []ReturnItem // Each names a variable or has an alias
+	Distinct bool         // Drop rows whose values repeat an earlier row's
+	Where    *WhereClause // Optional filter on the projected rows
+}
+
+// variable returns the name a WITH item binds
+func (r ReturnItem) variable() string {
+	if r.Alias != "" {
+		return r.Alias
+	}
+	if id, ok := r.Expr.(*Identifier); ok {
+		return id.Name
+	}
+	return ""
 }
 
 // ReturnItem represents a single return expression
@@ -99,6 +298,351 @@ type ReturnItem struct {
 	Alias string // Optional alias
 }
 
+// columnName returns the alias, or the expression's text if there is none
+func (r ReturnItem) columnName() string {
+	if r.Alias != "" {
+		return r.Alias
+	}
+	return expressionText(r.Expr)
+}
+
+// expressionText renders an expression back into query syntax
+func expressionText(expr Expression) string {
+	switch e := expr.(type) {
+	case *Identifier:
+		return quoteIdentifier(e.Name)
+	case *Parameter:
+		return parameterKey(e.Name)
+	case *PropertyAccess:
+		text := quoteIdentifier(e.Variable) + "." + quoteIdentifier(e.Property)
+		for _, key := range e.Path {
+			text += "." + quoteIdentifier(key)
+		}
+		return text
+	case *ListLiteral:
+		items := make([]string, len(e.Items))
+		for i, item := range e.Items {
+			items[i] = expressionText(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case *MapLiteral:
+		keys := make([]string, 0, len(e.Entries))
+		for k := range e.Entries {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		entries := make([]string, len(keys))
+		for i, k := range keys {
+			entries[i] = quoteIdentifier(k) + ": " + expressionText(e.Entries[k])
+		}
+		return "{" + strings.Join(entries, ", ") + "}"
+	case *Literal:
+		if s, ok := e.Value.(string); ok {
+			return strconv.Quote(s)
+		}
+		if e.Value == nil {
+			return "null"
+		}
+		return fmt.Sprint(e.Value)
+	case *PointLiteral:
+		return fmt.Sprintf("point({lat: %v, lon: %v})", e.Lat, e.Lon)
+	case *FunctionCall:
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = expressionText(arg)
+		}
+		name := e.Spelling
+		if name == "" {
+			name = e.Name
+		}
+		return name + "(" + strings.Join(args, ", ") + ")"
+	case *BinaryExpr:
+		if isArithmetic(e.Operator) {
+			return operandText(e.Left, e.Operator, false) + " " + e.Operator + " " + operandText(e.Right, e.Operator, true)
+		}
+		return expressionText(e.Left) + " " + e.Operator + " " + expressionText(e.Right)
+	case *UnaryExpr:
+		if _, ok := e.Operand.(*BinaryExpr); ok {
+			return e.Operator + " (" + expressionText(e.Operand) + ")"
+		}
+		return e.Operator + " " + expressionText(e.Operand)
+	case *PatternExpression:
+		return patternText(e.Pattern)
+	}
+	return "expr"
+}
+
+// quoteIdentifier returns name as written in a query: in backticks, with
+// backticks doubled, unless it is a plain identifier
+func quoteIdentifier(name string) string {
+	plain := name != "" && !isDigit(name[0])
+	for i := 0; i < len(name) && plain; i++ {
+		plain = isLetter(name[i]) || isDigit(name[i]) || name[i] == '_'
+	}
+	if plain {
+		return name
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// operandText renders an operand of the arithmetic operator op, adding
+// parentheses where precedence needs them: (a + b) * c, and a - (b - c)
+// on the right
+func operandText(operand Expression, op string, right bool) string {
+	text := expressionText(operand)
+	inner, ok := operand.(*BinaryExpr)
+	if !ok {
+		return text
+	}
+	innerRank, opRank := arithmeticRank(inner.Operator), arithmeticRank(op)
+	if innerRank < opRank || right && innerRank == opRank {
+		return "(" + text + ")"
+	}
+	return text
+}
+
+// arithmeticRank orders operators by precedence: comparisons and boolean
+// operators, then + and -, then *, / and %
+func arithmeticRank(op string) int {
+	switch op {
+	case "+", "-":
+		return 1
+	case "*", "/", "%":
+		return 2
+	}
+	return 0
+}
+
+// referencedVariables calls fn with every variable name expr refers to
+func referencedVariables(expr Expression, fn func(string)) {
+	switch e := expr.(type) {
+	case *Identifier:
+		fn(e.Name)
+	case *PropertyAccess:
+		fn(e.Variable)
+	case *ListLiteral:
+		for _, item := range e.Items {
+			referencedVariables(item, fn)
+		}
+	case *MapLiteral:
+		for _, value := range e.Entries {
+			referencedVariables(value, fn)
+		}
+	case *FunctionCall:
+		for _, arg := range e.Args {
+			referencedVariables(arg, fn)
+		}
+	case *BinaryExpr:
+		referencedVariables(e.Left, fn)
+		referencedVariables(e.Right, fn)
+	case *UnaryExpr:
+		referencedVariables(e.Operand, fn)
+	case *PatternExpression:
+		for _, node := range e.Pattern.Nodes {
+			if node.Variable != "" {
+				fn(node.Variable)
+			}
+		}
+	}
+}
+
+// ReadsClock reports whether q, or any part or UNION branch of it, calls
+// datetime() without arguments. Its results then depend on when it runs
+// as well as on the graph.
+func (q *Query) ReadsClock() bool {
+	found := false
+	q.eachExpression(func(expr Expression) {
+		walkExpression(expr, func(e Expression) {
+			if call, ok := e.(*FunctionCall); ok && call.Name == "datetime" && len(call.Args) == 0 {
+				found = true
+			}
+		})
+	})
+	return found
+}
+
+// eachExpression calls fn with every top-level expression in q and in
+// the parts and UNION branches it is built from
+func (q *Query) eachExpression(fn func(Expression)) {
+	if q == nil {
+		return
+	}
+	if q.Union != nil {
+		q.Union.Left.eachExpression(fn)
+		q.Union.Right.eachExpression(fn)
+	}
+	q.Input.eachExpression(fn)
+
+	patterns := func(patterns []Pattern) {
+		for _, pattern := range patterns {
+			patternExpressions(pattern, fn)
+		}
+	}
+	if q.Match != nil {
+		patterns(q.Match.Patterns)
+	}
+	if q.Create != nil {
+		patterns(q.Create.Patterns)
+	}
+	if q.Where != nil {
+		fn(q.Where.Expr)
+	}
+	if q.With != nil {
+		for _, item := range q.With.Items {
+			fn(item.Expr)
+		}
+		if q.With.Where != nil {
+			fn(q.With.Where.Expr)
+		}
+	}
+	if q.Return != nil {
+		for _, item := range q.Return.Items {
+			fn(item.Expr)
+		}
+	}
+	if q.OrderBy != nil {
+		for _, field := range q.OrderBy.Fields {
+			fn(field.Expr)
+		}
+	}
+	if q.Call != nil {
+		for _, arg := range q.Call.Args {
+			fn(arg)
+		}
+	}
+}
+
+// patternExpressions calls fn with the inline property values of pattern
+// that are expressions rather than constants
+func patternExpressions(pattern Pattern, fn func(Expression)) {
+	properties := func(props map[string]interface{}) {
+		for _, value := range props {
+			if expr, ok := value.(Expression); ok {
+				fn(expr)
+			}
+		}
+	}
+	for _, node := range pattern.Nodes {
+		properties(node.Properties)
+	}
+	for _, edge := range pattern.Edges {
+		properties(edge.Properties)
+	}
+}
+
+// walkExpression calls fn with expr and each expression nested in it
+func walkExpression(expr Expression, fn func(Expression)) {
+	if expr == nil {
+		return
+	}
+	fn(expr)
+	switch e := expr.(type) {
+	case *ListLiteral:
+		for _, item := range e.Items {
+			walkExpression(item, fn)
+		}
+	case *MapLiteral:
+		for _, value := range e.Entries {
+			walkExpression(value, fn)
+		}
+	case *FunctionCall:
+		for _, arg := range e.Args {
+			walkExpression(arg, fn)
+		}
+	case *BinaryExpr:
+		walkExpression(e.Left, fn)
+		walkExpression(e.Right, fn)
+	case *UnaryExpr:
+		walkExpression(e.Operand, fn)
+	case *PatternExpression:
+		patternExpressions(e.Pattern, func(inner Expression) {
+			walkExpression(inner, fn)
+		})
+	}
+}
+
+// patternText renders a pattern back into query syntax
+func patternText(p Pattern) string {
+	var b strings.Builder
+	for i, node := range p.Nodes {
+		inner := ""
+		if node.Variable != "" {
+			inner = quoteIdentifier(node.Variable)
+		}
+		if node.Label != "" {
+			inner += ":" + quoteIdentifier(node.Label)
+		}
+		b.WriteString("(" + withProperties(inner, node.Properties) + ")")
+
+		if i < len(p.Edges) {
+			edge := p.Edges[i]
+			if edge.Direction == DirectionIn {
+				b.WriteString("<-[")
+			} else {
+				b.WriteString("-[")
+			}
+			inner := ""
+			if edge.Variable != "" {
+				inner = quoteIdentifier(edge.Variable)
+			}
+			if edge.Type != "" {
+				inner += ":" + quoteIdentifier(edge.Type)
+			}
+			inner += hopRangeText(edge)
+			b.WriteString(withProperties(inner, edge.Properties))
+			if edge.Direction == DirectionOut {
+				b.WriteString("]->")
+			} else {
+				b.WriteString("]-")
+			}
+		}
+	}
+	return b.String()
+}
+
+// hopRangeText renders the *min..max range of a variable-length edge, or
+// "" for a single hop
+func hopRangeText(edge EdgePattern) string {
+	switch {
+	case edge.MinHops == nil:
+		return ""
+	case edge.MaxHops != nil && *edge.MaxHops == *edge.MinHops:
+		return fmt.Sprintf("*%d", *edge.MinHops)
+	case edge.MaxHops != nil:
+		return fmt.Sprintf("*%d..%d", *edge.MinHops, *edge.MaxHops)
+	case *edge.MinHops == 1:
+		return "*"
+	}
+	return fmt.Sprintf("*%d..", *edge.MinHops)
+}
+
+// withProperties appends inline pattern properties, with sorted keys, to
+// the variable and label text of a node or edge
+func withProperties(inner string, props map[string]interface{}) string {
+	if len(props) == 0 {
+		return inner
+	}
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		value, ok := props[k].(Expression)
+		if !ok {
+			value = &Literal{Value: props[k]}
+		}
+		parts[i] = quoteIdentifier(k) + ": " + expressionText(value)
+	}
+	text := "{" + strings.Join(parts, ", ") + "}"
+	if inner == "" {
+		return text
+	}
+	return inner + " " + text
+}
+
 // OrderByClause for sorting results
 type OrderByClause struct {
 	Fields []OrderByField
@@ -125,6 +669,7 @@ type QueryContext struct {
 	Variables  map[string]interface{}
 	ResultRows []Row
 	Matches    []BindingTable // Intermediate matches
+	Columns    []string       // Result columns chosen at run time, for RETURN *
 }
 
 // NewQuery creates a new query
@@ -179,30 +724,179 @@ type Operator interface {
 type ScanOperator struct {
 	Variable string
 	Label    string // Optional
+
+	// Filter and Limit let a scan that feeds nothing but a LIMIT stop as
+	// soon as enough nodes pass the filter
+	Filter Expression // Optional
+	Limit  int        // 0 means no limit
+
+	// Parallelism splits scans without a Limit across goroutines
+	Parallelism ScanParallelism
+
+	// Ordered visits nodes in ID order
+	Ordered bool
+
+	compiled compiledPredicate // Filter, compiled when the plan is built
+}
+
+// IndexSeekOperator looks up nodes through a property index. The lookup
+// may return extra candidates, so it is followed by an equality filter.
+type IndexSeekOperator struct {
+	Variable string
+	Label    string
+	Property string
+	Value    interface{}
+}
+
+// IndexPrefixScanOperator looks up nodes whose indexed string property
+// starts with Prefix through the sorted values of a property index
+type IndexPrefixScanOperator struct {
+	Variable string
+	Label    string
+	Property string
+	Prefix   string
+}
+
+// FullTextScanOperator scans the nodes a full-text index finds may
+// contain Query, ordered by relevance. It only narrows the candidates for
+// the CONTAINS filter it was planned from.
+type FullTextScanOperator struct {
+	Variable string
+	Label    string
+	Property string
+	Query    string
+}
+
+// SpatialScanOperator scans nodes whose indexed point lies within
+// RadiusMeters of Center, nearest first. Center is evaluated against each
+// incoming match, so it may be a parameter.
+type SpatialScanOperator struct {
+	Variable     string
+	Label        string
+	Property     string
+	Center       Expression
+	RadiusMeters float64
 }
 
 // FilterOperator applies WHERE predicates
 type FilterOperator struct {
 	Predicate Expression
+
+	compiled compiledPredicate // Predicate, compiled when the plan is built
 }
 
 // ExpandOperator traverses from nodes to neighbors
+//
+// DirectionBoth follows every edge of a node once, whichever end it is on,
+// so a self-loop yields one row rather than two. A reciprocal pair, a->b
+// and b->a, is two edges and yields a row for each when the edge is
+// bound; with Distinct set it yields a single row for b.
 type ExpandOperator struct {
 	SourceVar string
 	TargetVar string
 	EdgeVar   string
 	Direction Direction
 	EdgeType  string
-	MinHops   int
-	MaxHops   int
+
+	// Variable-length expansion. The edge variable is bound to []*graph.Edge.
+	VarLength       bool
+	MinHops         int
+	MaxHops         int // -1 means unbounded, capped at HopLimit
+	HopLimit        int
+	ErrorOnHopLimit bool
+
+	// Reversed is set when the expansion runs from the pattern's right end
+	// back towards its left, so the edges of a variable-length edge
+	// variable are bound in reverse to keep them in pattern order
+	Reversed bool
+
+	// AsOf, when set, skips edges that are not valid at that time
+	AsOf *time.Time
+
+	// Distinct binds each target at most once per source match, so
+	// parallel edges to the same neighbor yield one row. Only valid for
+	// single-hop expansions whose edge is not referenced downstream.
+	Distinct bool
+
+	// Ordered follows each node's edges in ID order
+	Ordered bool
+
+	// Limit stops a single-hop expansion once it has produced that many
+	// matches, when nothing but a LIMIT consumes them. 0 means no limit.
+	Limit int
 }
 
 // ProjectOperator extracts RETURN values
 type ProjectOperator struct {
 	Items []ReturnItem
+	Star  bool // Return every visible variable of the matches instead
+
+	compiled []compiledExpr // Item expressions, compiled when the plan is built
+}
+
+// SortOperator orders results by ORDER BY fields
+type SortOperator struct {
+	Fields  []OrderByField
+	Columns []string // RETURN column names that fields may refer to
 }
 
 // LimitOperator limits result count
 type LimitOperator struct {
 	Count int
 }
+
+// WithOperator replaces each match with the values of a WITH clause's
+// items, keeping query parameters, and optionally drops repeated rows
+type WithOperator struct {
+	Items    []ReturnItem
+	Distinct bool
+}
+
+// AggregateOperator groups the matches by the values of Keys and computes
+// each of Aggregates over every group, binding it to aggregateVariable(i).
+// Each group is left as a single match: its first, with the aggregates
+// added. With no keys there is one group, even when nothing matched.
+type AggregateOperator struct {
+	Keys       []Expression
+	Aggregates []*FunctionCall // Calls of aggregateFunctions
+}
+
+// BoundNodeOperator starts a pattern from a node an earlier query part
+// bound, keeping the matches where it has Label
+type BoundNodeOperator struct {
+	Variable string
+	Label    string // Optional
+}
+
+// CreateOperator creates the nodes and edges of its patterns once for
+// every match, binding their variables
+type CreateOperator struct {
+	Patterns []Pattern
+}
+
+// CallOperator calls a procedure once for every match, with the arguments
+// evaluated against it, and returns the rows of all the calls
+type CallOperator struct {
+	Call *CallClause
+}
+
+// HashJoinOperator joins the matches so far with those of a pattern that
+// shares no variable with them, keeping the pairs whose Left and Right
+// keys are equal. The pattern is matched once, on its own, by Operators;
+// the incoming matches are hashed on Left and probed with each pattern
+// match's Right key, so rows come out in the order a nested loop would
+// produce them.
+type HashJoinOperator struct {
+	Operators []Operator
+	Left      Expression // Key of the incoming matches
+	Right     Expression // Key of the pattern's matches
+}
+
+// PathOperator binds a path variable to the nodes and edges a pattern
+// matched. A variable-length edge variable holds several edges, whose
+// intermediate nodes are looked up.
+type PathOperator struct {
+	Variable string
+	Nodes    []string // Variables of the pattern's nodes, in pattern order
+	Edges    []string // Variables of the pattern's edges, in pattern order
+}
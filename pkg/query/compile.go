@@ -0,0 +1,316 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// compiledExpr evaluates an expression compiled by compileExpression
+// against one row of bindings
+type compiledExpr func(match BindingTable, g GraphStorage) (interface{}, error)
+
+// compiledPredicate reports whether a compiled condition holds for a row.
+// A value other than true, including null, does not hold.
+type compiledPredicate func(match BindingTable, g GraphStorage) (bool, error)
+
+// compileExpression turns expr into a closure that evaluates it as
+// evaluateExpression would, without walking the AST or re-resolving names
+// on every row. Comparisons against a literal convert the literal once and
+// compare values of the expected type directly. Expressions with no
+// compiled form are evaluated by evaluateExpression.
+func compileExpression(expr Expression) compiledExpr {
+	switch e := expr.(type) {
+	case *Literal:
+		value := e.Value
+		return func(BindingTable, GraphStorage) (interface{}, error) {
+			return value, nil
+		}
+	case *Identifier:
+		name := e.Name
+		return func(match BindingTable, _ GraphStorage) (interface{}, error) {
+			val, ok := match[name]
+			if !ok {
+				return nil, unboundVariableError(name)
+			}
+			return val, nil
+		}
+	case *Parameter:
+		key, name := parameterKey(e.Name), e.Name
+		return func(match BindingTable, _ GraphStorage) (interface{}, error) {
+			val, ok := match[key]
+			if !ok {
+				return nil, fmt.Errorf("parameter $%s not supplied", name)
+			}
+			return val, nil
+		}
+	case *PropertyAccess:
+		return compilePropertyAccess(e)
+	case *BinaryExpr:
+		return compileBinary(e)
+	case *UnaryExpr:
+		operand := compileExpression(e.Operand)
+		return func(match BindingTable, g GraphStorage) (interface{}, error) {
+			val, err := operand(match, g)
+			if err != nil || val == nil {
+				return nil, err
+			}
+			b, ok := val.(bool)
+			if !ok {
+				return nil, fmt.Errorf("NOT requires a boolean operand, got %T", val)
+			}
+			return !b, nil
+		}
+	}
+	return func(match BindingTable, g GraphStorage) (interface{}, error) {
+		return evaluateExpression(expr, match, g)
+	}
+}
+
+// compilePredicate compiles expr as a condition
+func compilePredicate(expr Expression) compiledPredicate {
+	eval := compileExpression(expr)
+	return func(match BindingTable, g GraphStorage) (bool, error) {
+		val, err := eval(match, g)
+		b, ok := val.(bool)
+		return ok && b, err
+	}
+}
+
+// compilePropertyAccess reads the property straight from the bound node
+// or edge
+func compilePropertyAccess(e *PropertyAccess) compiledExpr {
+	variable, property, path := e.Variable, e.Property, e.Path
+	return func(match BindingTable, _ GraphStorage) (interface{}, error) {
+		var val interface{}
+		var exists bool
+		switch obj := match[variable].(type) {
+		case *graph.Node:
+			val, exists = obj.GetProperty(property)
+		case *graph.Edge:
+			val, exists = obj.GetProperty(property)
+		default:
+			if _, ok := match[variable]; !ok {
+				return nil, unboundVariableError(variable)
+			}
+			return nil, fmt.Errorf("variable %s is not a node or edge", variable)
+		}
+		if !exists {
+			return builtinProperty(match[variable], property)
+		}
+		for _, key := range path {
+			m, ok := val.(graph.Properties)
+			if !ok {
+				return nil, nil
+			}
+			val = m[key]
+		}
+		return val, nil
+	}
+}
+
+// compileBinary compiles an operator and its operands. A comparison with
+// a number or string literal on either side gets a fast path for operands
+// of the same kind, and falls back to compareValues for anything else.
+func compileBinary(e *BinaryExpr) compiledExpr {
+	left, right := compileExpression(e.Left), compileExpression(e.Right)
+	op := e.Operator
+
+	if isArithmetic(op) {
+		return func(match BindingTable, g GraphStorage) (interface{}, error) {
+			l, r, err := evalOperands(left, right, match, g)
+			if err != nil {
+				return nil, err
+			}
+			return arithmetic(l, op, r)
+		}
+	}
+
+	if lit, ok := e.Right.(*Literal); ok {
+		if cmp := literalComparison(op, lit.Value, false); cmp != nil {
+			return func(match BindingTable, g GraphStorage) (interface{}, error) {
+				val, err := left(match, g)
+				if err != nil {
+					return nil, err
+				}
+				return cmp(val)
+			}
+		}
+	}
+	if lit, ok := e.Left.(*Literal); ok {
+		if cmp := literalComparison(op, lit.Value, true); cmp != nil {
+			return func(match BindingTable, g GraphStorage) (interface{}, error) {
+				val, err := right(match, g)
+				if err != nil {
+					return nil, err
+				}
+				return cmp(val)
+			}
+		}
+	}
+
+	return func(match BindingTable, g GraphStorage) (interface{}, error) {
+		l, r, err := evalOperands(left, right, match, g)
+		if err != nil {
+			return nil, err
+		}
+		return compareValues(l, op, r)
+	}
+}
+
+// evalOperands evaluates both operands, left first
+func evalOperands(left, right compiledExpr, match BindingTable, g GraphStorage) (interface{}, interface{}, error) {
+	l, err := left(match, g)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := right(match, g)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+// literalComparison returns a function comparing a value against the
+// literal lit with op, or nil if op has no fast path for lit. With
+// literalLeft the literal is the left operand.
+func literalComparison(op string, lit interface{}, literalLeft bool) func(interface{}) (interface{}, error) {
+	fallback := func(val interface{}) (interface{}, error) {
+		if literalLeft {
+			return compareValues(lit, op, val)
+		}
+		return compareValues(val, op, lit)
+	}
+
+	switch op {
+	case "=", "!=":
+		equal := op == "="
+		switch {
+		case isNumber(lit):
+			f := toFloat(lit)
+			return func(val interface{}) (interface{}, error) {
+				if isNumber(val) {
+					return (toFloat(val) == f) == equal, nil
+				}
+				return fallback(val)
+			}
+		case isString(lit):
+			s := lit.(string)
+			return func(val interface{}) (interface{}, error) {
+				if v, ok := val.(string); ok {
+					return (v == s) == equal, nil
+				}
+				return fallback(val)
+			}
+		}
+
+	case "<", "<=", ">", ">=":
+		// With the literal on the left, a < x is x > a
+		valueOp := op
+		if literalLeft {
+			valueOp = mirrorComparison(op)
+		}
+		switch {
+		case isNumber(lit):
+			f := toFloat(lit)
+			return func(val interface{}) (interface{}, error) {
+				if isNumber(val) {
+					return orderedResult(valueOp, compareFloats(toFloat(val), f)), nil
+				}
+				return fallback(val)
+			}
+		case isString(lit):
+			s := lit.(string)
+			return func(val interface{}) (interface{}, error) {
+				if v, ok := val.(string); ok {
+					return orderedResult(valueOp, strings.Compare(v, s)), nil
+				}
+				return fallback(val)
+			}
+		}
+
+	case "STARTS WITH", "ENDS WITH", "CONTAINS":
+		s, ok := lit.(string)
+		if !ok || literalLeft {
+			return nil
+		}
+		match := map[string]func(string, string) bool{
+			"STARTS WITH": strings.HasPrefix,
+			"ENDS WITH":   strings.HasSuffix,
+			"CONTAINS":    strings.Contains,
+		}[op]
+		return func(val interface{}) (interface{}, error) {
+			v, ok := val.(string)
+			return ok && match(v, s), nil
+		}
+	}
+	return nil
+}
+
+func isString(v interface{}) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+// mirrorComparison returns the operator that holds for b op' a when
+// a op b holds
+func mirrorComparison(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+func compareFloats(a, b float64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// orderedResult applies a comparison operator to the result of a
+// three-way comparison
+func orderedResult(op string, cmp int) bool {
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// compileOperators compiles the predicates and projections of a plan's
+// operators, including those of hash joins
+func compileOperators(ops []Operator) {
+	for _, op := range ops {
+		switch o := op.(type) {
+		case *FilterOperator:
+			o.compiled = compilePredicate(o.Predicate)
+		case *ScanOperator:
+			if o.Filter != nil {
+				o.compiled = compilePredicate(o.Filter)
+			}
+		case *ProjectOperator:
+			o.compiled = make([]compiledExpr, len(o.Items))
+			for i, item := range o.Items {
+				o.compiled[i] = compileExpression(item.Expr)
+			}
+		case *HashJoinOperator:
+			compileOperators(o.Operators)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package query
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fnToInteger converts a value to an integer. Floats are truncated toward
+// zero and strings are parsed, so toInteger("3.9") is 3. Values that cannot
+// be converted give null rather than an error.
+func fnToInteger(args []interface{}) (interface{}, error) {
+	if err := checkArgCount("toInteger", args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return int(i), nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return truncateFloat(f), nil
+		}
+		return nil, nil
+	case float32:
+		return truncateFloat(float64(v)), nil
+	case float64:
+		return truncateFloat(v), nil
+	}
+	if i, ok := toInteger(args[0]); ok {
+		return int(i), nil
+	}
+	return nil, nil
+}
+
+// truncateFloat truncates f toward zero, or returns nil if it is not a
+// finite number in the range of an int64
+func truncateFloat(f float64) interface{} {
+	f = math.Trunc(f)
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return nil
+	}
+	return int(f)
+}
+
+// fnToFloat converts a number, or a string holding one, to a float. Values
+// that cannot be converted give null.
+func fnToFloat(args []interface{}) (interface{}, error) {
+	if err := checkArgCount("toFloat", args, 1, 1); err != nil {
+		return nil, err
+	}
+	if isNumber(args[0]) {
+		return toFloat(args[0]), nil
+	}
+	if s, ok := args[0].(string); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, nil
+}
+
+// fnToString converts a number, boolean, string or datetime to a string.
+// Datetimes are written as RFC 3339. Other values give null.
+func fnToString(args []interface{}) (interface{}, error) {
+	if err := checkArgCount("toString", args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	}
+	if i, ok := toInteger(args[0]); ok {
+		return strconv.FormatInt(i, 10), nil
+	}
+	return nil, nil
+}
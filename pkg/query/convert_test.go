@@ -0,0 +1,94 @@
+package query
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversionFunctions(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []interface{}
+		want interface{}
+	}{
+		{"tointeger", []interface{}{42}, 42},
+		{"tointeger", []interface{}{int64(7)}, 7},
+		{"tointeger", []interface{}{3.9}, 3},
+		{"tointeger", []interface{}{-3.9}, -3},
+		{"tointeger", []interface{}{"30"}, 30},
+		{"tointeger", []interface{}{" -12 "}, -12},
+		{"tointeger", []interface{}{"2.7"}, 2},
+		{"tointeger", []interface{}{true}, 1},
+		{"tofloat", []interface{}{3}, 3.0},
+		{"tofloat", []interface{}{2.5}, 2.5},
+		{"tofloat", []interface{}{"1.5e3"}, 1500.0},
+		{"tofloat", []interface{}{"42"}, 42.0},
+		{"tostring", []interface{}{"already"}, "already"},
+		{"tostring", []interface{}{42}, "42"},
+		{"tostring", []interface{}{uint64(7)}, "7"},
+		{"tostring", []interface{}{2.5}, "2.5"},
+		{"tostring", []interface{}{false}, "false"},
+		{"tostring", []interface{}{time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)}, "2021-06-01T12:00:00Z"},
+
+		// Null in, null out
+		{"tointeger", []interface{}{nil}, nil},
+		{"tofloat", []interface{}{nil}, nil},
+		{"tostring", []interface{}{nil}, nil},
+
+		// Values that cannot be converted give null
+		{"tointeger", []interface{}{"thirty"}, nil},
+		{"tointeger", []interface{}{""}, nil},
+		{"tointeger", []interface{}{math.NaN()}, nil},
+		{"tointeger", []interface{}{math.Inf(1)}, nil},
+		{"tointeger", []interface{}{1e300}, nil},
+		{"tointeger", []interface{}{[]graph.PropertyValue{1}}, nil},
+		{"tofloat", []interface{}{"1.5kg"}, nil},
+		{"tofloat", []interface{}{true}, nil},
+		{"tostring", []interface{}{graph.NewNode(1, "Person")}, nil},
+		{"tostring", []interface{}{[]graph.PropertyValue{"a"}}, nil},
+	} {
+		got, err := functions[tc.name](tc.args)
+		require.NoError(t, err, "%s%v", tc.name, tc.args)
+		assert.Equal(t, tc.want, got, "%s%v", tc.name, tc.args)
+	}
+}
+
+func TestConversionFunctions_Errors(t *testing.T) {
+	_, err := fnToInteger(nil)
+	assert.EqualError(t, err, "toInteger expects 1 argument, got 0")
+	_, err = fnToFloat([]interface{}{1, 2})
+	assert.EqualError(t, err, "toFloat expects 1 argument, got 2")
+	_, err = fnToString(nil)
+	assert.EqualError(t, err, "toString expects 1 argument, got 0")
+}
+
+func TestExecute_ConversionFunctions(t *testing.T) {
+	g := storage.NewGraph()
+	g.AddNode("Person", graph.Properties{"name": "Alice", "age": "30", "score": "4.5"})
+	g.AddNode("Person", graph.Properties{"name": "Bob", "age": 30.7, "score": 3})
+	g.AddNode("Person", graph.Properties{"name": "Charlie", "age": "unknown"})
+
+	run := func(input string) *Result {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		result, err := q.Execute(g)
+		require.NoError(t, err, input)
+		return result
+	}
+
+	result := run(`MATCH (p:Person) WHERE toInteger(p.age) = 30 RETURN p.name AS name ORDER BY name`)
+	assert.Equal(t, []Row{{"name": "Alice"}, {"name": "Bob"}}, result.Rows)
+
+	result = run(`MATCH (p:Person) RETURN p.name AS name, toFloat(p.score) AS score, toString(toInteger(p.age)) AS age ORDER BY name`)
+	assert.Equal(t, []Row{
+		{"name": "Alice", "score": 4.5, "age": "30"},
+		{"name": "Bob", "score": 3.0, "age": "30"},
+		{"name": "Charlie", "score": nil, "age": nil},
+	}, result.Rows)
+}
@@ -1,47 +1,184 @@
 package query
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
-	"github.com/fnuworsu/rdgDB/pkg/storage"
 )
 
 // GraphStorage interface defines what the executor needs from the storage layer
 type GraphStorage interface {
 	IterateNodes(callback func(*graph.Node) bool)
 	GetNode(id graph.NodeID) (*graph.Node, error)
+	GetEdge(id graph.EdgeID) (*graph.Edge, error)
 	GetNeighbors(nodeID graph.NodeID) ([]*graph.Node, error)
 	GetIncomingNeighbors(nodeID graph.NodeID) ([]*graph.Node, error)
 }
 
-// Execute runs the query against the graph
-func (q *Query) Execute(g *storage.Graph) (*Result, error) {
+// neighborIterator is implemented by storage backends that stream a
+// node's edges and neighbors without collecting them first
+type neighborIterator interface {
+	IterateNeighbors(nodeID graph.NodeID, direction graph.Direction, callback func(edge *graph.Edge, node *graph.Node) bool) error
+}
+
+// adjacencyReader is implemented by storage backends that resolve a
+// node's edges and neighbors in one batch
+type adjacencyReader interface {
+	GetAdjacent(nodeID graph.NodeID, direction graph.Direction, labels ...string) ([]graph.Adjacency, error)
+}
+
+// labelIndex is implemented by storage backends that can iterate nodes
+// of a single label without a full scan
+type labelIndex interface {
+	IterateNodesByLabel(label string, callback func(*graph.Node) bool)
+}
+
+// sortedIterator is implemented by storage backends that can iterate
+// nodes in ascending ID order
+type sortedIterator interface {
+	IterateNodesSorted(callback func(*graph.Node) bool)
+	IterateNodesByLabelSorted(label string, callback func(*graph.Node) bool)
+}
+
+// expiryChecker is implemented by storage backends with time-to-live
+// support. Expired nodes and edges are invisible to queries even before
+// they are swept.
+type expiryChecker interface {
+	NodeExpired(node *graph.Node) bool
+	EdgeExpired(edge *graph.Edge) bool
+}
+
+// nodeExpired reports whether g considers node expired
+func nodeExpired(g interface{}, node *graph.Node) bool {
+	ec, ok := g.(expiryChecker)
+	return ok && ec.NodeExpired(node)
+}
+
+// edgeExpired reports whether g considers edge expired
+func edgeExpired(g interface{}, edge *graph.Edge) bool {
+	ec, ok := g.(expiryChecker)
+	return ok && ec.EdgeExpired(edge)
+}
+
+// ErrUnboundVariable is returned, wrapped, when an expression refers to a
+// variable that no clause of the query binds
+var ErrUnboundVariable = errors.New("unbound variable")
+
+// unboundVariableError names the variable behind an ErrUnboundVariable
+type unboundVariableError string
+
+func (e unboundVariableError) Error() string {
+	return fmt.Sprintf("variable %s not found", string(e))
+}
+
+func (e unboundVariableError) Is(target error) bool {
+	return target == ErrUnboundVariable
+}
+
+// ErrHopLimitExceeded is returned when an unbounded variable-length pattern
+// would expand past its hop limit and the limit is configured to error
+var ErrHopLimitExceeded = errors.New("variable-length hop limit exceeded")
+
+// HopLimit caps unbounded variable-length patterns such as [*] or [*2..]
+type HopLimit struct {
+	MaxHops      int  // Maximum hops for unbounded patterns
+	ErrorOnLimit bool // Return ErrHopLimitExceeded instead of truncating
+}
+
+// DefaultHopLimit applies to queries that do not set Query.HopLimit
+var DefaultHopLimit = HopLimit{MaxHops: 10}
+
+// hopLimit returns the query's hop limit or the package default
+func (q *Query) hopLimit() HopLimit {
+	if q.HopLimit != nil && q.HopLimit.MaxHops > 0 {
+		return *q.HopLimit
+	}
+	return DefaultHopLimit
+}
+
+// ScanParallelism controls when a scan is split across goroutines. Only
+// scans without an early LIMIT are split, since a LIMIT lets a serial
+// scan stop early.
+type ScanParallelism struct {
+	Threshold int // Minimum candidate nodes for a parallel scan; 0 disables
+	Workers   int // Goroutines per scan; 0 means runtime.GOMAXPROCS(0)
+}
+
+// DefaultScanParallelism applies to queries that do not set
+// Query.ScanParallelism
+var DefaultScanParallelism = ScanParallelism{Threshold: 10000}
+
+// scanParallelism returns the query's scan parallelism or the package default
+func (q *Query) scanParallelism() ScanParallelism {
+	if q.ScanParallelism != nil {
+		return *q.ScanParallelism
+	}
+	return DefaultScanParallelism
+}
+
+// workers returns the number of goroutines to use
+func (p ScanParallelism) workers() int {
+	if p.Workers > 0 {
+		return p.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// Execute runs the query against the graph.
+// g is typically a *storage.Graph or *storage.PersistentGraph. Pass a
+// PersistentGraph itself rather than its embedded Graph: CREATE writes
+// through g's own methods, and only the PersistentGraph ones log to the
+// WAL and enforce read-only mode.
+func (q *Query) Execute(g GraphStorage) (*Result, error) {
+	if q.Show != nil {
+		return executeShow(q.Show, g)
+	}
+	if q.standaloneCall() {
+		return executeCall(q.Call, g)
+	}
+	if q.Union != nil {
+		return q.executeUnion(g)
+	}
 	// 1. Build Execution Plan
-	plan, err := BuildExecutionPlan(q)
+	plan, err := BuildExecutionPlanWithStats(q, collectOptimizerStats(q, g))
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. Initialize Context
+	// 2. Initialize Context. Parameters are bound under "$name", which no
+	// pattern variable can shadow.
+	initial := make(BindingTable, len(q.Parameters))
+	for name, value := range q.Parameters {
+		initial[parameterKey(name)] = value
+	}
 	ctx := &QueryContext{
 		Graph:      g,
 		Variables:  make(map[string]interface{}),
 		ResultRows: make([]Row, 0),
-		// Initialize with one empty match to start the pipeline
-		Matches: []BindingTable{make(BindingTable)},
+		// Initialize with one match to start the pipeline
+		Matches: []BindingTable{initial},
 	}
 
 	// 3. Execute Operators
 	for _, op := range plan.Operators {
+		// Once nothing matches, only an aggregation without grouping keys
+		// has a row to produce
+		if len(ctx.Matches) == 0 {
+			if agg, ok := op.(*AggregateOperator); !ok || len(agg.Keys) > 0 {
+				continue
+			}
+		}
 		if err := op.Execute(ctx); err != nil {
 			return nil, err
 		}
-		// If no matches left, stop early
-		if len(ctx.Matches) == 0 {
-			break
-		}
 	}
 
 	// 4. Return Results
@@ -52,18 +189,14 @@ func (q *Query) Execute(g *storage.Graph) (*Result, error) {
 	columns := []string{}
 	if q.Return != nil {
 		for _, item := range q.Return.Items {
-			name := item.Alias
-			if name == "" {
-				// Try to infer name from expression
-				if id, ok := item.Expr.(*Identifier); ok {
-					name = id.Name
-				} else if prop, ok := item.Expr.(*PropertyAccess); ok {
-					name = prop.Variable + "." + prop.Property
-				} else {
-					name = "expr"
-				}
-			}
-			columns = append(columns, name)
+			columns = append(columns, item.columnName())
+		}
+	}
+	if (q.Return != nil && q.Return.Star) || q.Call != nil {
+		columns = ctx.Columns
+		if columns == nil {
+			// Nothing matched; fall back to the pattern's named variables
+			columns = q.patternVariables()
 		}
 	}
 
@@ -75,301 +208,1937 @@ func (q *Query) Execute(g *storage.Graph) (*Result, error) {
 
 // BuildExecutionPlan converts AST to a linear sequence of operators
 func BuildExecutionPlan(q *Query) (*ExecutionPlan, error) {
+	return BuildExecutionPlanWithStats(q, nil)
+}
+
+// BuildExecutionPlanWithStats converts AST to a linear sequence of operators,
+// starting the pattern scan from the most selective node when stats are given
+func BuildExecutionPlanWithStats(q *Query, stats *OptimizerStats) (*ExecutionPlan, error) {
 	plan := &ExecutionPlan{
 		Operators: make([]Operator, 0),
 	}
 
-	if q.Match == nil {
-		return nil, fmt.Errorf("MATCH clause is required")
+	// Without MATCH the pipeline runs once, against the single initial
+	// binding: RETURN 1 + 1 yields one row and CREATE creates its pattern
+	// once. Expressions referring to variables nothing binds fail with
+	// ErrUnboundVariable.
+	if q.Match == nil && q.Return == nil && q.Create == nil && q.With == nil {
+		return nil, fmt.Errorf("query needs a MATCH, CREATE or RETURN clause")
+	}
+
+	// A part after WITH runs on the rows of the part before it, and its
+	// patterns start from the variables the WITH bound
+	var bound map[string]bool
+	if q.Input != nil {
+		input, err := BuildExecutionPlanWithStats(q.inputPart(), stats)
+		if err != nil {
+			return nil, err
+		}
+		plan.Operators = append(plan.Operators, input.Operators...)
+		bound = make(map[string]bool)
+		for _, item := range q.Input.With.Items {
+			bound[item.variable()] = true
+		}
+	}
+
+	patternStart := len(plan.Operators)
+	var where []Expression
+	if q.Where != nil {
+		where = splitConjuncts(q.Where.Expr)
+	}
+	// Each pattern extends the matches of those before it. A pattern
+	// sharing no variable with them is matched on its own and hash joined
+	// to them on a WHERE equality between the two sides, when there is one.
+	if q.Match != nil {
+		if bound == nil {
+			bound = make(map[string]bool)
+		}
+		offset := 0
+		for _, pattern := range q.Match.Patterns {
+			var join *HashJoinOperator
+			var err error
+			if len(bound) > 0 && !pattern.bindsAny(bound) && q.hashJoins() {
+				join, where, err = q.planHashJoin(pattern, offset, where, bound, stats)
+			}
+			if join != nil {
+				plan.Operators = append(plan.Operators, join)
+			} else if err == nil {
+				plan.Operators, where, err = q.planPattern(plan.Operators, pattern, offset, where, bound, len(q.Match.Patterns) == 1, stats)
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range pattern.variables() {
+				bound[name] = true
+			}
+			offset += len(pattern.Nodes)
+		}
+	}
+
+	// 4. Apply WHERE clause
+	if predicate := joinConjuncts(where); predicate != nil {
+		plan.Operators = append(plan.Operators, &FilterOperator{
+			Predicate: predicate,
+		})
+	}
+	plan.Operators = append(plan.Operators[:patternStart], reorderFilters(plan.Operators[patternStart:], q, stats)...)
+
+	// 5. Apply WITH, which ends this part of the query
+	if q.With != nil {
+		agg, items, err := planAggregation(q.With.Items)
+		if err != nil {
+			return nil, err
+		}
+		if agg != nil {
+			plan.Operators = append(plan.Operators, agg)
+		}
+		plan.Operators = append(plan.Operators, &WithOperator{Items: items, Distinct: q.With.Distinct})
+		if q.With.Where != nil {
+			plan.Operators = append(plan.Operators, &FilterOperator{Predicate: q.With.Where.Expr})
+		}
+	}
+
+	// 6. Apply CREATE
+	if q.Create != nil {
+		plan.Operators = append(plan.Operators, &CreateOperator{Patterns: q.Create.Patterns})
+	}
+
+	// Apply CALL, which ends the query in place of RETURN
+	if q.Call != nil {
+		plan.Operators = append(plan.Operators, &CallOperator{Call: q.Call})
+	}
+
+	// 7. Apply RETURN clause (Projection)
+	if q.Return != nil {
+		agg, items, err := planAggregation(q.Return.Items)
+		if err != nil {
+			return nil, err
+		}
+		if agg != nil {
+			plan.Operators = append(plan.Operators, agg)
+		}
+		plan.Operators = append(plan.Operators, &ProjectOperator{
+			Items: items,
+			Star:  q.Return.Star,
+		})
+	}
+
+	// 8. Apply ORDER BY
+	if q.OrderBy != nil && len(q.OrderBy.Fields) > 0 {
+		sortOp := &SortOperator{Fields: q.OrderBy.Fields}
+		if q.Return != nil {
+			for _, item := range q.Return.Items {
+				sortOp.Columns = append(sortOp.Columns, item.columnName())
+			}
+		}
+		plan.Operators = append(plan.Operators, sortOp)
+	}
+
+	// 9. Apply LIMIT
+	if q.Limit != nil {
+		plan.Operators = append(plan.Operators, &LimitOperator{
+			Count: *q.Limit,
+		})
+	}
+
+	compileOperators(plan.Operators)
+	return plan, nil
+}
+
+// standaloneCall reports whether the query is just a CALL, run once
+// rather than for each match
+func (q *Query) standaloneCall() bool {
+	return q.Call != nil && q.Match == nil && q.Input == nil
+}
+
+// inputPart returns the part of the query before its WITH clause, with
+// this part's execution settings
+func (q *Query) inputPart() *Query {
+	input := *q.Input
+	input.HopLimit = q.HopLimit
+	input.ScanParallelism = q.ScanParallelism
+	input.Temporal = q.Temporal
+	input.Parameters = q.Parameters
+	input.StableOrder = q.StableOrder
+	return &input
+}
+
+// planPattern appends the operators matching pattern to ops, starting from
+// a variable in bound when the pattern has one. offset numbers the
+// internal variables of its anonymous nodes and edges apart from those of
+// other patterns, and only is set when it is the query's only pattern. It
+// returns the WHERE conjuncts it did not use up.
+func (q *Query) planPattern(ops []Operator, pattern Pattern, offset int, where []Expression, bound map[string]bool, only bool, stats *OptimizerStats) ([]Operator, []Expression, error) {
+	// Anonymous nodes get internal variables so expansion can start
+	// from any position in the pattern
+	vars := make([]string, len(pattern.Nodes))
+	for i, node := range pattern.Nodes {
+		vars[i] = node.Variable
+		if vars[i] == "" {
+			vars[i] = fmt.Sprintf("_anon%d", offset+i)
+		}
+	}
+
+	// Edges with inline properties need a variable to filter on, and
+	// a path needs the variables of all its edges
+	edgeVars := make([]string, len(pattern.Edges))
+	for i, edge := range pattern.Edges {
+		edgeVars[i] = edge.Variable
+		if len(edge.Properties) > 0 && edge.MinHops != nil {
+			return nil, nil, fmt.Errorf("properties on variable-length relationships are not supported")
+		}
+		if edgeVars[i] == "" && (len(edge.Properties) > 0 || pattern.Variable != "") {
+			edgeVars[i] = fmt.Sprintf("_edge%d", offset+i)
+		}
+	}
+
+	// A CONTAINS filter on a full-text indexed property becomes the
+	// access path instead of the label scan. The filter stays in place,
+	// as the index ignores case and only narrows the candidates.
+	var ftScan *FullTextScanOperator
+	for _, expr := range where {
+		variable, property, text, ok := fullTextPredicate(expr)
+		if !ok || !pattern.hasNode(variable) {
+			continue
+		}
+		label := q.patternLabel(variable)
+		if label != "" && stats.hasFullTextIndex(label, property) {
+			ftScan = &FullTextScanOperator{Variable: variable, Label: label, Property: property, Query: text}
+			break
+		}
+	}
+
+	// Otherwise a distance(...) < radius filter on a spatially indexed
+	// property narrows the scan to the index's candidate buckets. The
+	// filter stays in place since the index search is inclusive.
+	var spatialScan *SpatialScanOperator
+	for _, expr := range where {
+		if ftScan != nil {
+			break
+		}
+		variable, property, center, radius, ok := distancePredicate(expr)
+		if !ok || !pattern.hasNode(variable) {
+			continue
+		}
+		label := q.patternLabel(variable)
+		if label != "" && stats.hasSpatialIndex(label, property) {
+			spatialScan = &SpatialScanOperator{
+				Variable:     variable,
+				Label:        label,
+				Property:     property,
+				Center:       center,
+				RadiusMeters: radius,
+			}
+			break
+		}
+	}
+
+	// A STARTS WITH filter on a property index becomes a scan of the
+	// matching range of its sorted values. The filter stays in place,
+	// which costs little as every candidate passes it.
+	var prefixScan *IndexPrefixScanOperator
+	for _, expr := range where {
+		variable, property, prefix, ok := prefixPredicate(expr)
+		if !ok || !pattern.hasNode(variable) {
+			continue
+		}
+		label := q.patternLabel(variable)
+		if label != "" && stats.hasPrefixIndex(label, property) {
+			prefixScan = &IndexPrefixScanOperator{Variable: variable, Label: label, Property: property, Prefix: prefix}
+			break
+		}
+	}
+
+	startVar := ""
+	for _, node := range pattern.Nodes {
+		if bound[node.Variable] {
+			startVar = node.Variable
+			break
+		}
+	}
+	if startVar == "" && q.Hints != nil && pattern.hasNode(q.Hints.StartVariable) {
+		startVar = q.Hints.StartVariable
+	}
+	if startVar == "" && ftScan != nil {
+		startVar = ftScan.Variable
+	}
+	if startVar == "" && spatialScan != nil {
+		startVar = spatialScan.Variable
+	}
+	start := 0
+	seekable := false
+	if startVar == "" {
+		// An inline equality on an indexed property is the best anchor
+		for i, node := range pattern.Nodes {
+			if _, ok := stats.indexSeekProperty(node); ok {
+				start, seekable = i, true
+				break
+			}
+		}
+	}
+	if startVar == "" && !seekable && prefixScan != nil {
+		startVar = prefixScan.Variable
+	}
+	if startVar == "" && !seekable {
+		startVar = selectMostSelectiveStartNode([]Pattern{pattern}, where, stats)
+	}
+	for i, node := range pattern.Nodes {
+		if startVar != "" && node.Variable == startVar {
+			start = i
+			break
+		}
+	}
+
+	// 1. Scan start node
+	if len(pattern.Nodes) > 0 {
+		startNode := pattern.Nodes[start]
+		seekProperty, canSeek := stats.indexSeekProperty(startNode)
+		switch {
+		case bound[startNode.Variable]:
+			ops = append(ops, &BoundNodeOperator{Variable: vars[start], Label: startNode.Label})
+			ops = append(ops, propertyFilters(vars[start], startNode.Properties)...)
+		case ftScan != nil && ftScan.Variable == startNode.Variable:
+			ops = append(ops, ftScan)
+			ops = append(ops, propertyFilters(vars[start], startNode.Properties)...)
+		case canSeek:
+			ops = append(ops, &IndexSeekOperator{
+				Variable: vars[start],
+				Label:    startNode.Label,
+				Property: seekProperty,
+				Value:    startNode.Properties[seekProperty],
+			})
+			ops = append(ops, propertyFilters(vars[start], startNode.Properties)...)
+		case prefixScan != nil && prefixScan.Variable == startNode.Variable:
+			ops = append(ops, prefixScan)
+			ops = append(ops, propertyFilters(vars[start], startNode.Properties)...)
+		case spatialScan != nil && spatialScan.Variable == startNode.Variable:
+			ops = append(ops, spatialScan)
+			ops = append(ops, propertyFilters(vars[start], startNode.Properties)...)
+		case len(pattern.Edges) == 0 && pattern.Variable == "":
+			// Filters run inside the scan, so it can stop early when
+			// nothing but a LIMIT consumes it, or filter in parallel.
+			// Filters on variables of later patterns wait for them.
+			scanned, rest := conjunctsOn(where, func(name string) bool {
+				return name == vars[start] || bound[name]
+			})
+			scan := &ScanOperator{
+				Variable:    vars[start],
+				Label:       startNode.Label,
+				Filter:      joinConjuncts(append(propertyPredicates(vars[start], startNode.Properties), scanned...)),
+				Parallelism: q.scanParallelism(),
+				Ordered:     q.orderedScan(),
+			}
+			if only && len(rest) == 0 && q.limitsMatches() {
+				scan.Limit = *q.Limit
+			}
+			ops = append(ops, scan)
+			where = rest
+		default:
+			ops = append(ops, &ScanOperator{
+				Variable:    vars[start],
+				Label:       startNode.Label,
+				Parallelism: q.scanParallelism(),
+				Ordered:     q.orderedScan(),
+			})
+			ops = append(ops, propertyFilters(vars[start], startNode.Properties)...)
+		}
+	}
+
+	// Parallel edges are distinct paths
+	planExpand := func(edge EdgePattern, edgeVar, source, target string, dir Direction) *ExpandOperator {
+		expand := q.planExpand(edge, edgeVar, source, target, dir)
+		expand.Distinct = expand.Distinct && pattern.Variable == ""
+		return expand
+	}
+
+	// 2. Expand towards the end of the pattern
+	for i := start; i < len(pattern.Edges); i++ {
+		edge := pattern.Edges[i]
+		ops = append(ops, planExpand(edge, edgeVars[i], vars[i], vars[i+1], edge.Direction))
+		ops = append(ops, propertyFilters(edgeVars[i], edge.Properties)...)
+		ops = append(ops, propertyFilters(vars[i+1], pattern.Nodes[i+1].Properties)...)
+	}
+
+	// 3. Expand back towards the beginning, traversing edges in reverse
+	for i := start - 1; i >= 0; i-- {
+		edge := pattern.Edges[i]
+		expand := planExpand(edge, edgeVars[i], vars[i+1], vars[i], reverseDirection(edge.Direction))
+		expand.Reversed = true
+		ops = append(ops, expand)
+		ops = append(ops, propertyFilters(edgeVars[i], edge.Properties)...)
+		ops = append(ops, propertyFilters(vars[i], pattern.Nodes[i].Properties)...)
+	}
+
+	// An expansion that ends the only pattern can stop, like the scan,
+	// once it has enough matches for a LIMIT
+	if last, ok := ops[len(ops)-1].(*ExpandOperator); ok && only && !last.VarLength &&
+		pattern.Variable == "" && len(where) == 0 && q.limitsMatches() {
+		last.Limit = *q.Limit
+	}
+
+	if pattern.Variable != "" {
+		ops = append(ops, &PathOperator{Variable: pattern.Variable, Nodes: vars, Edges: edgeVars})
+	}
+	return ops, where, nil
+}
+
+// planHashJoin plans pattern, which shares no variable with the patterns
+// before it, as a hash join when a WHERE conjunct equates an expression on
+// variables bound before it with one on its own variables. The conjuncts
+// on its variables alone filter its matches before the join. It returns
+// nil if there is no such conjunct.
+func (q *Query) planHashJoin(pattern Pattern, offset int, where []Expression, bound map[string]bool, stats *OptimizerStats) (*HashJoinOperator, []Expression, error) {
+	own := make(map[string]bool)
+	for _, name := range pattern.variables() {
+		own[name] = true
+	}
+	for i, expr := range where {
+		left, right, ok := joinPredicate(expr, bound, own)
+		if !ok {
+			continue
+		}
+		local, rest := conjunctsOn(append(where[:i:i], where[i+1:]...), func(name string) bool {
+			return own[name]
+		})
+		ops, local, err := q.planPattern(nil, pattern, offset, local, nil, false, stats)
+		if err != nil {
+			return nil, nil, err
+		}
+		if predicate := joinConjuncts(local); predicate != nil {
+			ops = append(ops, &FilterOperator{Predicate: predicate})
+		}
+		return &HashJoinOperator{Operators: reorderFilters(ops, q, stats), Left: left, Right: right}, rest, nil
+	}
+	return nil, where, nil
+}
+
+// hashJoins reports whether independent patterns may be hash joined
+func (q *Query) hashJoins() bool {
+	return q.Hints == nil || !q.Hints.NoHashJoin
+}
+
+// aggregates reports whether the RETURN or WITH items call aggregate
+// functions, which must see every match before a LIMIT applies
+func (q *Query) aggregates() bool {
+	var items []ReturnItem
+	if q.Return != nil {
+		items = append(items, q.Return.Items...)
+	}
+	if q.With != nil {
+		items = append(items, q.With.Items...)
+	}
+	agg, _, err := planAggregation(items)
+	return err == nil && agg != nil
+}
+
+// limitsMatches reports whether the matches feed nothing but a LIMIT, so
+// the operator producing them may stop once it has that many. Aggregates,
+// DISTINCT, WITH and CREATE must see every match first.
+func (q *Query) limitsMatches() bool {
+	if q.Limit == nil || q.OrderBy != nil || q.aggregates() {
+		return false
+	}
+	if q.With != nil || q.Create != nil || q.Call != nil {
+		return false
+	}
+	return q.Return == nil || !q.Return.Distinct
+}
+
+// orderedScan reports whether the start node scan must visit nodes in ID
+// order: with StableOrder, or when a LIMIT without ORDER BY would
+// otherwise keep whichever nodes map iteration happens to reach first
+func (q *Query) orderedScan() bool {
+	return q.StableOrder || q.Limit != nil && q.OrderBy == nil
+}
+
+// planExpand builds the operator traversing edge from source to target,
+// binding the edge to edgeVar
+func (q *Query) planExpand(edge EdgePattern, edgeVar, source, target string, dir Direction) *ExpandOperator {
+	expand := &ExpandOperator{
+		SourceVar: source,
+		TargetVar: target,
+		EdgeVar:   edgeVar,
+		Direction: dir,
+		EdgeType:  edge.Type,
+		Ordered:   q.StableOrder,
+	}
+	if edge.MinHops != nil {
+		expand.VarLength = true
+		expand.MinHops = *edge.MinHops
+		expand.MaxHops = -1
+		if edge.MaxHops != nil {
+			expand.MaxHops = *edge.MaxHops
+		}
+		hopLimit := q.hopLimit()
+		expand.HopLimit = hopLimit.MaxHops
+		expand.ErrorOnHopLimit = hopLimit.ErrorOnLimit
+	} else if len(edge.Properties) == 0 && (edgeVar == "" || !q.references(edgeVar)) {
+		// Nothing downstream can tell parallel edges apart
+		expand.Distinct = true
+	}
+	if q.Temporal != nil {
+		at := q.Temporal.At
+		expand.AsOf = &at
+	}
+	return expand
+}
+
+// references reports whether the WHERE, RETURN or ORDER BY clauses refer
+// to variable. RETURN * refers to every variable.
+func (q *Query) references(variable string) bool {
+	if q.Return != nil && q.Return.Star {
+		return true
+	}
+	exprs := make([]Expression, 0)
+	if q.Where != nil {
+		exprs = append(exprs, q.Where.Expr)
+	}
+	if q.Return != nil {
+		for _, item := range q.Return.Items {
+			exprs = append(exprs, item.Expr)
+		}
+	}
+	if q.OrderBy != nil {
+		for _, field := range q.OrderBy.Fields {
+			exprs = append(exprs, field.Expr)
+		}
+	}
+	if q.With != nil {
+		for _, item := range q.With.Items {
+			exprs = append(exprs, item.Expr)
+		}
+		if q.With.Where != nil {
+			exprs = append(exprs, q.With.Where.Expr)
+		}
+	}
+
+	found := false
+	for _, expr := range exprs {
+		referencedVariables(expr, func(name string) {
+			if name == variable {
+				found = true
+			}
+		})
+	}
+	return found
+}
+
+// internalVariable reports whether a binding name is not a user variable:
+// a parameter ($name) or a variable the planner gave an anonymous node
+// (_anonN) or filtered edge (_edgeN)
+func internalVariable(name string) bool {
+	if strings.HasPrefix(name, "$") {
+		return true
+	}
+	for _, prefix := range []string{"_anon", "_edge", "_agg"} {
+		if rest := strings.TrimPrefix(name, prefix); rest != name && rest != "" && strings.Trim(rest, "0123456789") == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// variables returns the named path, node and edge variables of a pattern
+func (p Pattern) variables() []string {
+	names := make([]string, 0, len(p.Nodes)+len(p.Edges)+1)
+	if p.Variable != "" {
+		names = append(names, p.Variable)
+	}
+	for _, node := range p.Nodes {
+		if node.Variable != "" {
+			names = append(names, node.Variable)
+		}
+	}
+	for _, edge := range p.Edges {
+		if edge.Variable != "" {
+			names = append(names, edge.Variable)
+		}
+	}
+	return names
+}
+
+// hasNode reports whether variable names one of the pattern's nodes
+func (p Pattern) hasNode(variable string) bool {
+	for _, node := range p.Nodes {
+		if variable != "" && node.Variable == variable {
+			return true
+		}
+	}
+	return false
+}
+
+// bindsAny reports whether the pattern has a variable in names
+func (p Pattern) bindsAny(names map[string]bool) bool {
+	for _, name := range p.variables() {
+		if names[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// patternVariables returns the named node and edge variables of the MATCH
+// patterns, and those a preceding WITH bound, in sorted order
+func (q *Query) patternVariables() []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0)
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if q.Match != nil {
+		for _, pattern := range q.Match.Patterns {
+			add(pattern.Variable)
+			for _, node := range pattern.Nodes {
+				add(node.Variable)
+			}
+			for _, edge := range pattern.Edges {
+				add(edge.Variable)
+			}
+		}
+	}
+	if q.Input != nil {
+		for _, item := range q.Input.With.Items {
+			add(item.variable())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// propertyFilters turns inline pattern properties into equality filters
+func propertyFilters(variable string, props map[string]interface{}) []Operator {
+	predicates := propertyPredicates(variable, props)
+	filters := make([]Operator, 0, len(predicates))
+	for _, predicate := range predicates {
+		filters = append(filters, &FilterOperator{Predicate: predicate})
+	}
+	return filters
+}
+
+// propertyPredicates turns inline pattern properties into equality
+// expressions, ordered by property name
+func propertyPredicates(variable string, props map[string]interface{}) []Expression {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	predicates := make([]Expression, 0, len(keys))
+	for _, k := range keys {
+		value, ok := props[k].(Expression)
+		if !ok {
+			value = &Literal{Value: props[k]}
+		}
+		predicates = append(predicates, &BinaryExpr{
+			Left:     &PropertyAccess{Variable: variable, Property: k},
+			Operator: "=",
+			Right:    value,
+		})
+	}
+	return predicates
+}
+
+// reverseDirection returns the direction of an edge traversed backwards
+func reverseDirection(dir Direction) Direction {
+	switch dir {
+	case DirectionOut:
+		return DirectionIn
+	case DirectionIn:
+		return DirectionOut
+	}
+	return dir
+}
+
+// graphDirection converts d to the storage traversal direction
+func (d Direction) graphDirection() graph.Direction {
+	switch d {
+	case DirectionIn:
+		return graph.Incoming
+	case DirectionBoth:
+		return graph.Both
+	}
+	return graph.Outgoing
+}
+
+// --- Operator Implementations ---
+
+// ScanOperator implementation
+func (s *ScanOperator) Execute(ctx *QueryContext) error {
+	g, ok := ctx.Graph.(GraphStorage)
+	if !ok {
+		return fmt.Errorf("invalid graph storage")
+	}
+
+	iterate := g.IterateNodes
+	if idx, ok := g.(labelIndex); ok && s.Label != "" {
+		iterate = func(cb func(*graph.Node) bool) { idx.IterateNodesByLabel(s.Label, cb) }
+	}
+	if s.Ordered {
+		if si, ok := g.(sortedIterator); ok {
+			iterate = si.IterateNodesSorted
+			if s.Label != "" {
+				iterate = func(cb func(*graph.Node) bool) { si.IterateNodesByLabelSorted(s.Label, cb) }
+			}
+		} else {
+			iterate = orderedIteration(iterate, s.candidates(g))
+		}
+	}
+
+	if s.Limit == 0 && s.Parallelism.Threshold > 0 && s.candidates(g) >= s.Parallelism.Threshold {
+		return s.parallelScan(ctx, g, iterate)
+	}
+
+	newMatches := make([]BindingTable, 0)
+	var filterErr error
+	filter := s.filter()
+	iterate(func(node *graph.Node) bool {
+		newMatches, filterErr = s.bind(g, node, filter, ctx.Matches, newMatches)
+		return filterErr == nil && (s.Limit == 0 || len(newMatches) < s.Limit)
+	})
+	if filterErr != nil {
+		return filterErr
+	}
+	if s.Limit > 0 && len(newMatches) > s.Limit {
+		newMatches = newMatches[:s.Limit]
+	}
+
+	ctx.Matches = newMatches
+	return nil
+}
+
+// orderedIteration wraps iterate to visit nodes in ID order, for storage
+// backends without sorted iteration. It collects
+// every node before the first callback, so an ordered scan cannot stop
+// early without visiting them all.
+func orderedIteration(iterate func(func(*graph.Node) bool), sizeHint int) func(func(*graph.Node) bool) {
+	return func(cb func(*graph.Node) bool) {
+		nodes := make([]*graph.Node, 0, sizeHint)
+		iterate(func(node *graph.Node) bool {
+			nodes = append(nodes, node)
+			return true
+		})
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+		for _, node := range nodes {
+			if !cb(node) {
+				return
+			}
+		}
+	}
+}
+
+// nodeCounter is implemented by storage backends that can count nodes
+// without iterating them
+type nodeCounter interface {
+	NodeCount() int
+	LabelCount(label string) int
+}
+
+// candidates returns the number of nodes the scan will visit, or 0 when
+// the storage cannot tell cheaply
+func (s *ScanOperator) candidates(g GraphStorage) int {
+	nc, ok := g.(nodeCounter)
+	if !ok {
+		return 0
+	}
+	if s.Label != "" {
+		return nc.LabelCount(s.Label)
+	}
+	return nc.NodeCount()
+}
+
+// filter returns the compiled Filter, compiling it if the plan did not,
+// or nil if there is none
+func (s *ScanOperator) filter() compiledPredicate {
+	if s.compiled != nil || s.Filter == nil {
+		return s.compiled
+	}
+	return compilePredicate(s.Filter)
+}
+
+// bind appends a copy of each existing match extended with node, unless
+// filter rejects it
+func (s *ScanOperator) bind(g GraphStorage, node *graph.Node, filter compiledPredicate, existing, out []BindingTable) ([]BindingTable, error) {
+	if s.Label != "" && node.Label != s.Label {
+		return out, nil
+	}
+	if nodeExpired(g, node) {
+		return out, nil
+	}
+
+	// Cartesian product with existing matches (which is just [{}] initially)
+	for _, existingMatch := range existing {
+		newMatch := copyBindingTable(existingMatch)
+		if s.Variable != "" {
+			newMatch[s.Variable] = node
+		}
+		if filter != nil {
+			ok, err := filter(newMatch, g)
+			if err != nil {
+				return out, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		out = append(out, newMatch)
+	}
+	return out, nil
+}
+
+// parallelScan partitions the scanned nodes into contiguous chunks, binds
+// and filters each chunk in its own goroutine and concatenates the results
+// in chunk order, so the output order matches a serial scan's
+func (s *ScanOperator) parallelScan(ctx *QueryContext, g GraphStorage, iterate func(func(*graph.Node) bool)) error {
+	nodes := make([]*graph.Node, 0, s.candidates(g))
+	iterate(func(node *graph.Node) bool {
+		nodes = append(nodes, node)
+		return true
+	})
+
+	workers := s.Parallelism.workers()
+	if workers > len(nodes) {
+		workers = len(nodes)
+	}
+	if workers == 0 {
+		ctx.Matches = make([]BindingTable, 0)
+		return nil
+	}
+	chunk := (len(nodes) + workers - 1) / workers
+
+	results := make([][]BindingTable, workers)
+	errs := make([]error, workers)
+	filter := s.filter()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		lo := w * chunk
+		hi := lo + chunk
+		if hi > len(nodes) {
+			hi = len(nodes)
+		}
+		if lo >= hi {
+			break
+		}
+
+		wg.Add(1)
+		go func(w int, part []*graph.Node) {
+			defer wg.Done()
+			for _, node := range part {
+				results[w], errs[w] = s.bind(g, node, filter, ctx.Matches, results[w])
+				if errs[w] != nil {
+					return
+				}
+			}
+		}(w, nodes[lo:hi])
+	}
+	wg.Wait()
+
+	total := 0
+	for w := range results {
+		if errs[w] != nil {
+			return errs[w]
+		}
+		total += len(results[w])
+	}
+
+	newMatches := make([]BindingTable, 0, total)
+	for _, part := range results {
+		newMatches = append(newMatches, part...)
+	}
+	ctx.Matches = newMatches
+	return nil
+}
+
+// IndexSeekOperator implementation
+func (s *IndexSeekOperator) Execute(ctx *QueryContext) error {
+	pi, ok := ctx.Graph.(propertyIndexer)
+	if !ok {
+		return fmt.Errorf("storage does not support property indexes")
+	}
+
+	nodes, err := pi.PropertyLookup(s.Label, s.Property, s.Value)
+	if err != nil {
+		return err
+	}
+
+	newMatches := make([]BindingTable, 0, len(nodes))
+	for _, node := range nodes {
+		if nodeExpired(ctx.Graph, node) {
+			continue
+		}
+		for _, existingMatch := range ctx.Matches {
+			newMatch := copyBindingTable(existingMatch)
+			newMatch[s.Variable] = node
+			newMatches = append(newMatches, newMatch)
+		}
+	}
+
+	ctx.Matches = newMatches
+	return nil
+}
+
+// IndexPrefixScanOperator implementation
+func (s *IndexPrefixScanOperator) Execute(ctx *QueryContext) error {
+	pi, ok := ctx.Graph.(prefixIndexer)
+	if !ok {
+		return fmt.Errorf("storage does not support property prefix scans")
+	}
+
+	nodes, err := pi.PropertyPrefixLookup(s.Label, s.Property, s.Prefix)
+	if err != nil {
+		return err
+	}
+
+	newMatches := make([]BindingTable, 0, len(nodes))
+	for _, node := range nodes {
+		if nodeExpired(ctx.Graph, node) {
+			continue
+		}
+		for _, existingMatch := range ctx.Matches {
+			newMatch := copyBindingTable(existingMatch)
+			newMatch[s.Variable] = node
+			newMatches = append(newMatches, newMatch)
+		}
+	}
+
+	ctx.Matches = newMatches
+	return nil
+}
+
+// FullTextScanOperator implementation
+func (s *FullTextScanOperator) Execute(ctx *QueryContext) error {
+	ft, ok := ctx.Graph.(fullTextIndexer)
+	if !ok {
+		return fmt.Errorf("storage does not support full-text indexes")
+	}
+
+	nodes, err := ft.FullTextContains(s.Label, s.Property, s.Query)
+	if err != nil {
+		return err
+	}
+
+	newMatches := make([]BindingTable, 0, len(nodes))
+	for _, node := range nodes {
+		if nodeExpired(ctx.Graph, node) {
+			continue
+		}
+		for _, existingMatch := range ctx.Matches {
+			newMatch := copyBindingTable(existingMatch)
+			newMatch[s.Variable] = node
+			newMatches = append(newMatches, newMatch)
+		}
+	}
+
+	ctx.Matches = newMatches
+	return nil
+}
+
+// SpatialScanOperator implementation
+func (s *SpatialScanOperator) Execute(ctx *QueryContext) error {
+	si, ok := ctx.Graph.(spatialIndexer)
+	if !ok {
+		return fmt.Errorf("storage does not support spatial indexes")
+	}
+
+	g, _ := ctx.Graph.(GraphStorage)
+	newMatches := make([]BindingTable, 0)
+	for _, existingMatch := range ctx.Matches {
+		value, err := evaluateExpression(s.Center, existingMatch, g)
+		if err != nil {
+			return err
+		}
+		if value == nil {
+			continue // distance to null is null, which never passes
+		}
+		center, ok := value.(graph.Point)
+		if !ok {
+			return fmt.Errorf("distance expects point arguments")
+		}
+
+		nodes, err := si.RadiusSearch(s.Label, s.Property, center, s.RadiusMeters/1000)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if nodeExpired(ctx.Graph, node) {
+				continue
+			}
+			newMatch := copyBindingTable(existingMatch)
+			newMatch[s.Variable] = node
+			newMatches = append(newMatches, newMatch)
+		}
+	}
+
+	ctx.Matches = newMatches
+	return nil
+}
+
+// FilterOperator implementation
+func (f *FilterOperator) Execute(ctx *QueryContext) error {
+	filteredMatches := make([]BindingTable, 0)
+
+	predicate := f.compiled
+	if predicate == nil {
+		predicate = compilePredicate(f.Predicate)
+	}
+	g, _ := ctx.Graph.(GraphStorage)
+	for _, match := range ctx.Matches {
+		ok, err := predicate(match, g)
+		if err != nil {
+			return err
+		}
+		if ok {
+			filteredMatches = append(filteredMatches, match)
+		}
+	}
+
+	ctx.Matches = filteredMatches
+	return nil
+}
+
+// ExpandOperator implementation
+func (e *ExpandOperator) Execute(ctx *QueryContext) error {
+	g, ok := ctx.Graph.(GraphStorage)
+	if !ok {
+		return fmt.Errorf("invalid graph storage")
+	}
+
+	newMatches := make([]BindingTable, 0)
+
+	for _, match := range ctx.Matches {
+		sourceNodeObj, ok := match[e.SourceVar]
+		if !ok {
+			return unboundVariableError(e.SourceVar)
+		}
+		sourceNode, ok := sourceNodeObj.(*graph.Node)
+		if !ok {
+			return fmt.Errorf("variable %s is not a node", e.SourceVar)
+		}
+
+		if e.VarLength {
+			expanded, err := e.expandVarLength(g, match, sourceNode)
+			if err != nil {
+				return err
+			}
+			newMatches = append(newMatches, expanded...)
+			continue
+		}
+
+		bound := e.boundTarget(match)
+		var seen map[graph.NodeID]bool
+		if e.Distinct {
+			seen = make(map[graph.NodeID]bool)
+		}
+		e.eachStep(g, sourceNode, func(step expandStep) bool {
+			if bound != nil && step.node.ID != bound.ID {
+				return true
+			}
+			if seen != nil {
+				if seen[step.node.ID] {
+					return true
+				}
+				seen[step.node.ID] = true
+			}
+			newMatch := copyBindingTable(match)
+			if e.TargetVar != "" {
+				newMatch[e.TargetVar] = step.node
+			}
+			if e.EdgeVar != "" {
+				newMatch[e.EdgeVar] = step.edge
+			}
+			newMatches = append(newMatches, newMatch)
+			return e.Limit == 0 || len(newMatches) < e.Limit
+		})
+		if e.Limit > 0 && len(newMatches) >= e.Limit {
+			break
+		}
+	}
+
+	ctx.Matches = newMatches
+	return nil
+}
+
+// boundTarget returns the node already bound to the target variable, as
+// when a pattern closes a cycle like (a)-->(b)-->(a). Expansions must then
+// reach that node instead of binding a new one.
+func (e *ExpandOperator) boundTarget(match BindingTable) *graph.Node {
+	if e.TargetVar == "" {
+		return nil
+	}
+	node, _ := match[e.TargetVar].(*graph.Node)
+	return node
+}
+
+// expandStep is one edge traversal from a node to a neighbor
+type expandStep struct {
+	edge *graph.Edge
+	node *graph.Node
+}
+
+// adjacent returns the edges leaving node in the operator's direction
+// that match its edge type, together with the node on the other end
+func (e *ExpandOperator) adjacent(g GraphStorage, node *graph.Node) []expandStep {
+	var steps []expandStep
+	e.eachStep(g, node, func(step expandStep) bool {
+		steps = append(steps, step)
+		return true
+	})
+	return steps
+}
+
+// eachStep calls fn for each step adjacent would return, in the same
+// order, until fn returns false. Unless the steps must be ordered, they
+// are streamed from storage backends that support it, so a consumer that
+// stops early never resolves the rest of a supernode's edges.
+func (e *ExpandOperator) eachStep(g GraphStorage, node *graph.Node, fn func(expandStep) bool) {
+	// A self-loop is both an outgoing and an incoming edge of its node;
+	// undirected traversal follows it once
+	var loops map[graph.EdgeID]bool
+	visit := func(edge *graph.Edge, other *graph.Node) bool {
+		if source, target := edge.Endpoints(); e.Direction == DirectionBoth && source == target {
+			if loops[edge.ID] {
+				return true
+			}
+			if loops == nil {
+				loops = make(map[graph.EdgeID]bool)
+			}
+			loops[edge.ID] = true
+		}
+		if e.AsOf != nil && !edgeActiveAt(edge, *e.AsOf) {
+			return true
+		}
+		if edgeExpired(g, edge) || nodeExpired(g, other) {
+			return true
+		}
+		return fn(expandStep{edge: edge, node: other})
+	}
+
+	if ni, ok := g.(neighborIterator); ok && !e.Ordered {
+		ni.IterateNeighbors(node.ID, e.Direction.graphDirection(), func(edge *graph.Edge, other *graph.Node) bool {
+			if e.EdgeType != "" && edge.Label != e.EdgeType {
+				return true
+			}
+			return visit(edge, other)
+		})
+		return
+	}
+
+	var labels []string
+	if e.EdgeType != "" {
+		labels = []string{e.EdgeType}
+	}
+	var adjacent []graph.Adjacency
+	if ar, ok := g.(adjacencyReader); ok {
+		adjacent, _ = ar.GetAdjacent(node.ID, e.Direction.graphDirection(), labels...)
+	} else {
+		adjacent = scanAdjacent(g, node, e.Direction, labels)
+	}
+	if e.Ordered {
+		sort.Slice(adjacent, func(i, j int) bool { return adjacent[i].Edge.ID < adjacent[j].Edge.ID })
+	}
+	for _, adj := range adjacent {
+		if !visit(adj.Edge, adj.Node) {
+			return
+		}
+	}
+}
+
+// scanAdjacent resolves node's edges one at a time for storage backends
+// without GetAdjacent
+func scanAdjacent(g GraphStorage, node *graph.Node, direction Direction, labels []string) []graph.Adjacency {
+	node.Mu.RLock()
+	var outEdges, inEdges []graph.EdgeID
+	if direction != DirectionIn {
+		outEdges = append(outEdges, node.OutEdges...)
+	}
+	if direction != DirectionOut {
+		inEdges = append(inEdges, node.InEdges...)
+	}
+	node.Mu.RUnlock()
+
+	adjacent := make([]graph.Adjacency, 0, len(outEdges)+len(inEdges))
+	resolve := func(edgeIDs []graph.EdgeID, outgoing bool) {
+		for _, edgeID := range edgeIDs {
+			edge, err := g.GetEdge(edgeID)
+			if err != nil {
+				continue
+			}
+			if len(labels) > 0 && edge.Label != labels[0] {
+				continue
+			}
+			other, target := edge.Endpoints()
+			if outgoing {
+				other = target
+			}
+			otherNode, err := g.GetNode(other)
+			if err != nil {
+				continue
+			}
+			adjacent = append(adjacent, graph.Adjacency{Edge: edge, Node: otherNode})
+		}
+	}
+	resolve(outEdges, true)
+	resolve(inEdges, false)
+	return adjacent
+}
+
+// expandVarLength performs a depth-first expansion for patterns like
+// [*1..3]. An edge is never traversed twice within one path, and
+// unbounded patterns are capped at HopLimit hops.
+func (e *ExpandOperator) expandVarLength(g GraphStorage, match BindingTable, source *graph.Node) ([]BindingTable, error) {
+	maxHops := e.MaxHops
+	capped := false
+	if maxHops < 0 {
+		maxHops = e.HopLimit
+		capped = true
+	}
+
+	results := make([]BindingTable, 0)
+	path := make([]*graph.Edge, 0)
+	used := make(map[graph.EdgeID]bool)
+
+	bound := e.boundTarget(match)
+
+	var walk func(node *graph.Node, depth int) error
+	walk = func(node *graph.Node, depth int) error {
+		if depth >= e.MinHops && (bound == nil || node.ID == bound.ID) {
+			newMatch := copyBindingTable(match)
+			if e.TargetVar != "" {
+				newMatch[e.TargetVar] = node
+			}
+			if e.EdgeVar != "" {
+				edges := make([]*graph.Edge, len(path))
+				copy(edges, path)
+				if e.Reversed {
+					for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+						edges[i], edges[j] = edges[j], edges[i]
+					}
+				}
+				newMatch[e.EdgeVar] = edges
+			}
+			results = append(results, newMatch)
+		}
+
+		steps := e.adjacent(g, node)
+		if depth == maxHops {
+			if capped && e.ErrorOnHopLimit {
+				for _, step := range steps {
+					if !used[step.edge.ID] {
+						return fmt.Errorf("%w: pattern from %s exceeds %d hops",
+							ErrHopLimitExceeded, e.SourceVar, maxHops)
+					}
+				}
+			}
+			return nil
+		}
+
+		for _, step := range steps {
+			if used[step.edge.ID] {
+				continue
+			}
+			used[step.edge.ID] = true
+			path = append(path, step.edge)
+			if err := walk(step.node, depth+1); err != nil {
+				return err
+			}
+			path = path[:len(path)-1]
+			delete(used, step.edge.ID)
+		}
+		return nil
+	}
+
+	if err := walk(source, 0); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ProjectOperator implementation
+func (p *ProjectOperator) Execute(ctx *QueryContext) error {
+	ctx.ResultRows = make([]Row, 0, len(ctx.Matches))
+
+	if p.Star {
+		// Every match of a plan binds the same variables
+		ctx.Columns = make([]string, 0)
+		if len(ctx.Matches) > 0 {
+			for name := range ctx.Matches[0] {
+				if !internalVariable(name) {
+					ctx.Columns = append(ctx.Columns, name)
+				}
+			}
+		}
+		sort.Strings(ctx.Columns)
+
+		for _, match := range ctx.Matches {
+			row := make(Row, len(ctx.Columns))
+			for _, name := range ctx.Columns {
+				row[name] = match[name]
+			}
+			ctx.ResultRows = append(ctx.ResultRows, row)
+		}
+		return nil
+	}
+
+	exprs := p.compiled
+	if exprs == nil {
+		exprs = make([]compiledExpr, len(p.Items))
+		for i, item := range p.Items {
+			exprs[i] = compileExpression(item.Expr)
+		}
+	}
+	columns := make([]string, len(p.Items))
+	for i, item := range p.Items {
+		columns[i] = item.columnName()
+	}
+
+	g, _ := ctx.Graph.(GraphStorage)
+	for _, match := range ctx.Matches {
+		row := make(Row, len(p.Items))
+		for i, eval := range exprs {
+			val, err := eval(match, g)
+			if err != nil {
+				return err
+			}
+
+			row[columns[i]] = val
+		}
+		ctx.ResultRows = append(ctx.ResultRows, row)
+	}
+	return nil
+}
+
+// SortOperator implementation. A field naming a RETURN column sorts by
+// that column; any other expression is evaluated against the match. Each
+// key falls through to the next only on ties, and nulls sort last for
+// every key regardless of direction.
+func (s *SortOperator) Execute(ctx *QueryContext) error {
+	columns := make(map[string]bool, len(s.Columns))
+	for _, col := range s.Columns {
+		columns[col] = true
+	}
+	hasRows := len(ctx.ResultRows) == len(ctx.Matches)
+
+	g, _ := ctx.Graph.(GraphStorage)
+	keys := make([][]interface{}, len(ctx.Matches))
+	for i, match := range ctx.Matches {
+		keys[i] = make([]interface{}, len(s.Fields))
+		for j, field := range s.Fields {
+			if text := expressionText(field.Expr); hasRows && columns[text] {
+				keys[i][j] = ctx.ResultRows[i][text]
+				continue
+			}
+			val, err := evaluateExpression(field.Expr, match, g)
+			if err != nil {
+				return err
+			}
+			keys[i][j] = val
+		}
+	}
+
+	order := make([]int, len(ctx.Matches))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ka, kb := keys[order[a]], keys[order[b]]
+		for j, field := range s.Fields {
+			c := sortCompare(ka[j], kb[j], field.Descending)
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	matches := make([]BindingTable, len(order))
+	for i, idx := range order {
+		matches[i] = ctx.Matches[idx]
+	}
+	ctx.Matches = matches
+	if hasRows {
+		rows := make([]Row, len(order))
+		for i, idx := range order {
+			rows[i] = ctx.ResultRows[idx]
+		}
+		ctx.ResultRows = rows
+	}
+	return nil
+}
+
+// sortCompare orders two sort keys. Nulls come last in either direction;
+// values that cannot be compared with each other are grouped by type.
+func sortCompare(a, b interface{}, descending bool) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	var c int
+	if ra, rb := sortRank(a), sortRank(b); ra != rb {
+		c = ra - rb
+	} else if ba, ok := a.(bool); ok {
+		bb := b.(bool)
+		switch {
+		case ba == bb:
+			c = 0
+		case !ba:
+			c = -1
+		default:
+			c = 1
+		}
+	} else if ra != sortRankOther {
+		c = compareOrdered(a, b)
+	}
+
+	if descending {
+		return -c
+	}
+	return c
+}
+
+// Sort ranks group values of different types
+const (
+	sortRankTime = iota
+	sortRankString
+	sortRankBool
+	sortRankNumber
+	sortRankOther
+)
+
+func sortRank(v interface{}) int {
+	switch v.(type) {
+	case time.Time:
+		return sortRankTime
+	case string:
+		return sortRankString
+	case bool:
+		return sortRankBool
+	}
+	if isNumber(v) {
+		return sortRankNumber
+	}
+	return sortRankOther
+}
+
+// LimitOperator implementation
+func (l *LimitOperator) Execute(ctx *QueryContext) error {
+	if len(ctx.ResultRows) > l.Count {
+		ctx.ResultRows = ctx.ResultRows[:l.Count]
+	}
+	// Also limit matches if we are not at the end?
+	// Usually Limit is last, so ResultRows is what matters.
+	if len(ctx.Matches) > l.Count {
+		ctx.Matches = ctx.Matches[:l.Count]
+	}
+	return nil
+}
+
+// WithOperator implementation
+func (w *WithOperator) Execute(ctx *QueryContext) error {
+	g, _ := ctx.Graph.(GraphStorage)
+	projected := make([]BindingTable, 0, len(ctx.Matches))
+	var seen map[string]bool
+	if w.Distinct {
+		seen = make(map[string]bool)
+	}
+	values := make([]interface{}, len(w.Items))
+	for _, match := range ctx.Matches {
+		bt := make(BindingTable, len(w.Items))
+		for name, value := range match {
+			if strings.HasPrefix(name, "$") {
+				bt[name] = value
+			}
+		}
+		for i, item := range w.Items {
+			val, err := evaluateExpression(item.Expr, match, g)
+			if err != nil {
+				return err
+			}
+			values[i] = val
+			bt[item.variable()] = val
+		}
+		if seen != nil {
+			key := distinctKey(values)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		projected = append(projected, bt)
+	}
+
+	ctx.Matches = projected
+	return nil
+}
+
+// AggregateOperator implementation. Groups keep the order in which their
+// first match arrived.
+func (a *AggregateOperator) Execute(ctx *QueryContext) error {
+	g, _ := ctx.Graph.(GraphStorage)
+	type group struct {
+		first  BindingTable
+		values [][]interface{} // Argument values of each aggregate
+	}
+	var groups []*group
+	index := make(map[string]*group)
+	keys := make([]interface{}, len(a.Keys))
+	for _, match := range ctx.Matches {
+		for i, expr := range a.Keys {
+			val, err := evaluateExpression(expr, match, g)
+			if err != nil {
+				return err
+			}
+			keys[i] = val
+		}
+		key := distinctKey(keys)
+		grp, ok := index[key]
+		if !ok {
+			grp = &group{first: match, values: make([][]interface{}, len(a.Aggregates))}
+			index[key] = grp
+			groups = append(groups, grp)
+		}
+		for i, call := range a.Aggregates {
+			val, err := evaluateExpression(call.Args[0], match, g)
+			if err != nil {
+				return err
+			}
+			grp.values[i] = append(grp.values[i], val)
+		}
+	}
+	if len(a.Keys) == 0 && len(groups) == 0 {
+		groups = append(groups, &group{first: BindingTable{}, values: make([][]interface{}, len(a.Aggregates))})
+	}
+
+	matches := make([]BindingTable, len(groups))
+	for i, grp := range groups {
+		bt := make(BindingTable, len(grp.first)+len(a.Aggregates))
+		for name, value := range grp.first {
+			bt[name] = value
+		}
+		for j, call := range a.Aggregates {
+			val, err := aggregateFunctions[call.Name](grp.values[j])
+			if err != nil {
+				return err
+			}
+			bt[aggregateVariable(j)] = val
+		}
+		matches[i] = bt
+	}
+	ctx.Matches = matches
+	return nil
+}
+
+// BoundNodeOperator implementation
+func (b *BoundNodeOperator) Execute(ctx *QueryContext) error {
+	kept := make([]BindingTable, 0, len(ctx.Matches))
+	for _, match := range ctx.Matches {
+		value, ok := match[b.Variable]
+		if !ok {
+			return unboundVariableError(b.Variable)
+		}
+		if value == nil {
+			continue // A null cannot match a node pattern
+		}
+		node, ok := value.(*graph.Node)
+		if !ok {
+			return fmt.Errorf("variable %s is not a node", b.Variable)
+		}
+		if b.Label != "" && node.Label != b.Label {
+			continue
+		}
+		kept = append(kept, match)
+	}
+
+	ctx.Matches = kept
+	return nil
+}
+
+// HashJoinOperator implementation
+func (h *HashJoinOperator) Execute(ctx *QueryContext) error {
+	if len(ctx.Matches) == 0 {
+		return nil
+	}
+	g, _ := ctx.Graph.(GraphStorage)
+
+	// The pattern sees the query parameters and nothing else
+	params := make(BindingTable)
+	for name, value := range ctx.Matches[0] {
+		if strings.HasPrefix(name, "$") {
+			params[name] = value
+		}
+	}
+	sub := &QueryContext{Graph: ctx.Graph, Variables: ctx.Variables, Matches: []BindingTable{params}}
+	for _, op := range h.Operators {
+		if err := op.Execute(sub); err != nil {
+			return err
+		}
+		if len(sub.Matches) == 0 {
+			break
+		}
+	}
+
+	table := &joinTable{buckets: make(map[interface{}][]int)}
+	for _, match := range ctx.Matches {
+		key, err := evaluateExpression(h.Left, match, g)
+		if err != nil {
+			return err
+		}
+		table.add(key)
 	}
 
-	// Simple planner: handle first pattern
-	// TODO: Handle multiple patterns and joins
-	if len(q.Match.Patterns) > 0 {
-		pattern := q.Match.Patterns[0]
-
-		// 1. Scan first node
-		if len(pattern.Nodes) > 0 {
-			startNode := pattern.Nodes[0]
-			plan.Operators = append(plan.Operators, &ScanOperator{
-				Variable: startNode.Variable,
-				Label:    startNode.Label,
-			})
-
-			// Filter start node properties
-			if len(startNode.Properties) > 0 {
-				for k, v := range startNode.Properties {
-					// Create a filter: variable.k = v
-					plan.Operators = append(plan.Operators, &FilterOperator{
-						Predicate: &BinaryExpr{
-							Left:     &PropertyAccess{Variable: startNode.Variable, Property: k},
-							Operator: "=",
-							Right:    &Literal{Value: v},
-						},
-					})
-				}
+	joined := make([]BindingTable, 0)
+	for _, right := range sub.Matches {
+		key, err := evaluateExpression(h.Right, right, g)
+		if err != nil {
+			return err
+		}
+		for _, i := range table.probe(key) {
+			newMatch := copyBindingTable(ctx.Matches[i])
+			for name, value := range right {
+				newMatch[name] = value
 			}
+			joined = append(joined, newMatch)
 		}
+	}
 
-		// 2. Expand to subsequent nodes
-		for i := 0; i < len(pattern.Edges); i++ {
-			edge := pattern.Edges[i]
-			targetNode := pattern.Nodes[i+1]
-			sourceVar := pattern.Nodes[i].Variable
+	ctx.Matches = joined
+	return nil
+}
 
-			plan.Operators = append(plan.Operators, &ExpandOperator{
-				SourceVar: sourceVar,
-				TargetVar: targetNode.Variable,
-				EdgeVar:   edge.Variable,
-				Direction: edge.Direction,
-				EdgeType:  edge.Type,
-			})
+// joinTable indexes the join keys of a hash join's incoming matches by
+// position. Keys that cannot be hashed consistently with valuesEqual, such
+// as times and lists, are compared one by one.
+type joinTable struct {
+	keys    []interface{}
+	buckets map[interface{}][]int
+	other   []int
+}
 
-			// Filter target node properties
-			if len(targetNode.Properties) > 0 {
-				for k, v := range targetNode.Properties {
-					plan.Operators = append(plan.Operators, &FilterOperator{
-						Predicate: &BinaryExpr{
-							Left:     &PropertyAccess{Variable: targetNode.Variable, Property: k},
-							Operator: "=",
-							Right:    &Literal{Value: v},
-						},
-					})
-				}
+func (t *joinTable) add(key interface{}) {
+	i := len(t.keys)
+	t.keys = append(t.keys, key)
+	if k, ok := hashKey(key); ok {
+		t.buckets[k] = append(t.buckets[k], i)
+	} else {
+		t.other = append(t.other, i)
+	}
+}
+
+// probe returns the positions of the keys equal to key, in ascending order
+func (t *joinTable) probe(key interface{}) []int {
+	k, ok := hashKey(key)
+	if !ok {
+		var found []int
+		for i, candidate := range t.keys {
+			if valuesEqual(candidate, key) {
+				found = append(found, i)
 			}
 		}
+		return found
 	}
 
-	// 3. Apply WHERE clause
-	if q.Where != nil {
-		plan.Operators = append(plan.Operators, &FilterOperator{
-			Predicate: q.Where.Expr,
-		})
+	bucket := t.buckets[k]
+	if len(t.other) == 0 {
+		return bucket
 	}
-
-	// 4. Apply RETURN clause (Projection)
-	if q.Return != nil {
-		plan.Operators = append(plan.Operators, &ProjectOperator{
-			Items: q.Return.Items,
-		})
+	found := make([]int, 0, len(bucket))
+	next := 0
+	for _, i := range t.other {
+		if !valuesEqual(t.keys[i], key) {
+			continue
+		}
+		for next < len(bucket) && bucket[next] < i {
+			found = append(found, bucket[next])
+			next++
+		}
+		found = append(found, i)
 	}
+	return append(found, bucket[next:]...)
+}
 
-	// 5. Apply LIMIT
-	if q.Limit != nil {
-		plan.Operators = append(plan.Operators, &LimitOperator{
-			Count: *q.Limit,
-		})
+// hashKey returns a map key for v that values equal to it under
+// valuesEqual share, or false if there is none
+func hashKey(v interface{}) (interface{}, bool) {
+	if isNumber(v) {
+		return toFloat(v), true
 	}
-
-	return plan, nil
+	switch v.(type) {
+	case nil, string, bool, graph.Point:
+		return v, true
+	}
+	return nil, false
 }
 
-// --- Operator Implementations ---
-
-// ScanOperator implementation
-func (s *ScanOperator) Execute(ctx *QueryContext) error {
+// PathOperator implementation
+func (o *PathOperator) Execute(ctx *QueryContext) error {
 	g, ok := ctx.Graph.(GraphStorage)
 	if !ok {
 		return fmt.Errorf("invalid graph storage")
 	}
 
-	newMatches := make([]BindingTable, 0)
-
-	// Iterate all nodes
-	// In a real system, we would use an index if Label is present
-	g.IterateNodes(func(node *graph.Node) bool {
-		// Filter by label if specified
-		if s.Label != "" && node.Label != s.Label {
-			return true // continue
+	for _, match := range ctx.Matches {
+		start, ok := match[o.Nodes[0]].(*graph.Node)
+		if !ok {
+			return fmt.Errorf("variable %s is not a node", o.Nodes[0])
 		}
-
-		// Create a new match for each node
-		// Cartesian product with existing matches (which is just [{}] initially)
-		for _, existingMatch := range ctx.Matches {
-			newMatch := copyBindingTable(existingMatch)
-			if s.Variable != "" {
-				newMatch[s.Variable] = node
+		path := &Path{Nodes: []*graph.Node{start}, Edges: make([]*graph.Edge, 0, len(o.Edges))}
+		for i, edgeVar := range o.Edges {
+			end, ok := match[o.Nodes[i+1]].(*graph.Node)
+			if !ok {
+				return fmt.Errorf("variable %s is not a node", o.Nodes[i+1])
+			}
+			switch edges := match[edgeVar].(type) {
+			case *graph.Edge:
+				path.Edges = append(path.Edges, edges)
+				path.Nodes = append(path.Nodes, end)
+			case []*graph.Edge:
+				// A zero-length hop adds nothing, since end is the last node
+				for j, edge := range edges {
+					next := end
+					if j < len(edges)-1 {
+						var err error
+						if next, err = g.GetNode(otherEnd(edge, path.Nodes[len(path.Nodes)-1].ID)); err != nil {
+							return fmt.Errorf("failed to build path %s: %w", o.Variable, err)
+						}
+					}
+					path.Edges = append(path.Edges, edge)
+					path.Nodes = append(path.Nodes, next)
+				}
+			default:
+				return fmt.Errorf("variable %s is not a relationship", edgeVar)
 			}
-			newMatches = append(newMatches, newMatch)
 		}
-		return true
-	})
-
-	ctx.Matches = newMatches
+		match[o.Variable] = path
+	}
 	return nil
 }
 
-// FilterOperator implementation
-func (f *FilterOperator) Execute(ctx *QueryContext) error {
-	filteredMatches := make([]BindingTable, 0)
+// distinctKey encodes a tuple of values so that tuples get the same key
+// exactly when valuesEqual holds for every pair. Nodes and edges are keyed
+// by ID.
+func distinctKey(values []interface{}) string {
+	var b strings.Builder
+	for _, v := range values {
+		writeValueKey(&b, v)
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func writeValueKey(b *strings.Builder, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case *graph.Node:
+		fmt.Fprintf(b, "node:%d", v.ID)
+	case *graph.Edge:
+		fmt.Fprintf(b, "edge:%d", v.ID)
+	case *Path:
+		fmt.Fprintf(b, "path:%d", v.Nodes[0].ID)
+		for _, edge := range v.Edges {
+			fmt.Fprintf(b, ",%d", edge.ID)
+		}
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case time.Time:
+		b.WriteString("time:" + v.UTC().Format(time.RFC3339Nano))
+	case []graph.PropertyValue:
+		b.WriteByte('[')
+		for _, item := range v {
+			writeValueKey(b, item)
+			b.WriteByte(',')
+		}
+		b.WriteByte(']')
+	case graph.Properties:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b.WriteByte('{')
+		for _, k := range keys {
+			b.WriteString(strconv.Quote(k) + ":")
+			writeValueKey(b, v[k])
+			b.WriteByte(',')
+		}
+		b.WriteByte('}')
+	default:
+		if isNumber(v) {
+			b.WriteString(strconv.FormatFloat(toFloat(v), 'g', -1, 64))
+			return
+		}
+		fmt.Fprintf(b, "%T:%v", v, v)
+	}
+}
+
+// patternWriter is implemented by graphs that CREATE can add to
+type patternWriter interface {
+	AddNode(label string, properties graph.Properties) (*graph.Node, error)
+	AddEdge(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error)
+}
 
+// CallOperator implementation. The columns are those of the first call.
+func (c *CallOperator) Execute(ctx *QueryContext) error {
+	g, _ := ctx.Graph.(GraphStorage)
 	for _, match := range ctx.Matches {
-		result, err := evaluateExpression(f.Predicate, match)
+		result, err := callProcedure(c.Call, match, g)
 		if err != nil {
 			return err
 		}
-		if b, ok := result.(bool); ok && b {
-			filteredMatches = append(filteredMatches, match)
+		if ctx.Columns == nil {
+			ctx.Columns = result.Columns
 		}
+		ctx.ResultRows = append(ctx.ResultRows, result.Rows...)
 	}
-
-	ctx.Matches = filteredMatches
+	ctx.Matches = nil
 	return nil
 }
 
-// ExpandOperator implementation
-func (e *ExpandOperator) Execute(ctx *QueryContext) error {
-	// g is not used directly, we cast to *storage.Graph later
-	// but we should verify it implements GraphStorage at least
-	if _, ok := ctx.Graph.(GraphStorage); !ok {
-		return fmt.Errorf("invalid graph storage")
+// CreateOperator implementation
+func (c *CreateOperator) Execute(ctx *QueryContext) error {
+	w, ok := ctx.Graph.(patternWriter)
+	if !ok {
+		return fmt.Errorf("CREATE requires a writable graph")
 	}
-
-	newMatches := make([]BindingTable, 0)
-
+	g, _ := ctx.Graph.(GraphStorage)
 	for _, match := range ctx.Matches {
-		sourceNodeObj, ok := match[e.SourceVar]
-		if !ok {
-			return fmt.Errorf("variable %s not found", e.SourceVar)
-		}
-		sourceNode, ok := sourceNodeObj.(*graph.Node)
-		if !ok {
-			return fmt.Errorf("variable %s is not a node", e.SourceVar)
+		if err := c.create(w, match, g); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		// Handle direction
-		// TODO: This simple implementation doesn't capture the Edge object itself in the binding
-		// We need to iterate edges to capture edge variables and properties
-		// For now, let's just get neighbors and assume we don't need edge details unless requested
-		// But wait, if we need to filter on edge type, we need to check edges.
-
-		// Let's use the low-level adjacency list to filter by type and direction
-
-		// Outgoing
-		if e.Direction == DirectionOut || e.Direction == DirectionBoth {
-			sourceNode.Mu.RLock()
-			outEdges := make([]graph.EdgeID, len(sourceNode.OutEdges))
-			copy(outEdges, sourceNode.OutEdges)
-			sourceNode.Mu.RUnlock()
-
-			realGraph, ok := ctx.Graph.(*storage.Graph)
-			if !ok {
-				// Fallback or error
-				continue
-			}
-
-			for _, edgeID := range outEdges {
-				edge, err := realGraph.GetEdge(edgeID)
-				if err != nil {
-					continue
-				}
-
-				// Filter by type
-				if e.EdgeType != "" && edge.Label != e.EdgeType {
-					continue
-				}
-
-				targetNode, err := realGraph.GetNode(edge.Target)
-				if err != nil {
+// create adds the patterns' nodes and edges for one match. Every pattern
+// node either refers to a node bound earlier or is created, and all
+// property values are evaluated before anything is written, so a bad
+// reference creates nothing.
+func (c *CreateOperator) create(w patternWriter, match BindingTable, g GraphStorage) error {
+	type nodeRef struct {
+		created int // Index into created, or -1 for a bound node
+		node    *graph.Node
+	}
+	type newNode struct {
+		label string
+		props graph.Properties
+	}
+	var created []newNode
+	bound := make(map[string]int) // Variable -> index into created
+	refs := make([][]nodeRef, len(c.Patterns))
+
+	for i, pattern := range c.Patterns {
+		for _, np := range pattern.Nodes {
+			if np.Variable != "" {
+				idx, inStatement := bound[np.Variable]
+				value, inMatch := match[np.Variable]
+				if inStatement || inMatch {
+					if np.Label != "" || len(np.Properties) > 0 {
+						return fmt.Errorf("variable %s is already bound", np.Variable)
+					}
+					ref := nodeRef{created: idx}
+					if !inStatement {
+						node, ok := value.(*graph.Node)
+						if !ok {
+							return fmt.Errorf("variable %s is not a node", np.Variable)
+						}
+						ref = nodeRef{created: -1, node: node}
+					}
+					refs[i] = append(refs[i], ref)
 					continue
 				}
-
-				// Create match
-				newMatch := copyBindingTable(match)
-				if e.TargetVar != "" {
-					newMatch[e.TargetVar] = targetNode
-				}
-				if e.EdgeVar != "" {
-					newMatch[e.EdgeVar] = edge
-				}
-				newMatches = append(newMatches, newMatch)
 			}
-		}
-
-		// Incoming (similar logic)
-		if e.Direction == DirectionIn || e.Direction == DirectionBoth {
-			sourceNode.Mu.RLock()
-			inEdges := make([]graph.EdgeID, len(sourceNode.InEdges))
-			copy(inEdges, sourceNode.InEdges)
-			sourceNode.Mu.RUnlock()
 
-			realGraph, ok := ctx.Graph.(*storage.Graph)
-			if !ok {
-				continue
+			props, err := createProperties(np.Properties, match, g)
+			if err != nil {
+				return err
 			}
+			if np.Variable != "" {
+				bound[np.Variable] = len(created)
+			}
+			refs[i] = append(refs[i], nodeRef{created: len(created)})
+			created = append(created, newNode{label: np.Label, props: props})
+		}
+	}
 
-			for _, edgeID := range inEdges {
-				edge, err := realGraph.GetEdge(edgeID)
-				if err != nil {
-					continue
-				}
-
-				if e.EdgeType != "" && edge.Label != e.EdgeType {
-					continue
-				}
-
-				targetNode, err := realGraph.GetNode(edge.Source)
-				if err != nil {
-					continue
-				}
-
-				newMatch := copyBindingTable(match)
-				if e.TargetVar != "" {
-					newMatch[e.TargetVar] = targetNode
+	edgeProps := make([][]graph.Properties, len(c.Patterns))
+	for i, pattern := range c.Patterns {
+		for _, ep := range pattern.Edges {
+			if ep.Variable != "" {
+				if _, ok := match[ep.Variable]; ok {
+					return fmt.Errorf("variable %s is already bound", ep.Variable)
 				}
-				if e.EdgeVar != "" {
-					newMatch[e.EdgeVar] = edge
-				}
-				newMatches = append(newMatches, newMatch)
 			}
+			props, err := createProperties(ep.Properties, match, g)
+			if err != nil {
+				return err
+			}
+			edgeProps[i] = append(edgeProps[i], props)
 		}
 	}
 
-	ctx.Matches = newMatches
-	return nil
-}
-
-// ProjectOperator implementation
-func (p *ProjectOperator) Execute(ctx *QueryContext) error {
-	ctx.ResultRows = make([]Row, 0, len(ctx.Matches))
+	nodes := make([]*graph.Node, len(created))
+	for i, n := range created {
+		node, err := w.AddNode(n.label, n.props)
+		if err != nil {
+			return fmt.Errorf("failed to create node: %w", err)
+		}
+		nodes[i] = node
+	}
+	for variable, idx := range bound {
+		match[variable] = nodes[idx]
+	}
+	nodeID := func(ref nodeRef) graph.NodeID {
+		if ref.created < 0 {
+			return ref.node.ID
+		}
+		return nodes[ref.created].ID
+	}
 
-	for _, match := range ctx.Matches {
-		row := make(Row)
-		for _, item := range p.Items {
-			val, err := evaluateExpression(item.Expr, match)
+	for i, pattern := range c.Patterns {
+		for j, ep := range pattern.Edges {
+			source, target := nodeID(refs[i][j]), nodeID(refs[i][j+1])
+			if ep.Direction == DirectionIn {
+				source, target = target, source
+			}
+			edge, err := w.AddEdge(source, target, ep.Type, edgeProps[i][j])
 			if err != nil {
-				return err
+				return fmt.Errorf("failed to create relationship: %w", err)
 			}
-
-			name := item.Alias
-			if name == "" {
-				// Simple alias generation
-				if id, ok := item.Expr.(*Identifier); ok {
-					name = id.Name
-				} else if prop, ok := item.Expr.(*PropertyAccess); ok {
-					name = prop.Variable + "." + prop.Property
-				} else {
-					name = fmt.Sprintf("col_%d", len(row))
-				}
+			if ep.Variable != "" {
+				match[ep.Variable] = edge
 			}
-			row[name] = val
 		}
-		ctx.ResultRows = append(ctx.ResultRows, row)
 	}
 	return nil
 }
 
-// LimitOperator implementation
-func (l *LimitOperator) Execute(ctx *QueryContext) error {
-	if len(ctx.ResultRows) > l.Count {
-		ctx.ResultRows = ctx.ResultRows[:l.Count]
+// createProperties evaluates the inline properties of a CREATE pattern.
+// Null values are left out.
+func createProperties(props map[string]interface{}, match BindingTable, g GraphStorage) (graph.Properties, error) {
+	if len(props) == 0 {
+		return nil, nil
 	}
-	// Also limit matches if we are not at the end?
-	// Usually Limit is last, so ResultRows is what matters.
-	if len(ctx.Matches) > l.Count {
-		ctx.Matches = ctx.Matches[:l.Count]
+	converted := make(graph.Properties, len(props))
+	for k, v := range props {
+		if expr, ok := v.(Expression); ok {
+			value, err := evaluateExpression(expr, match, g)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", k, err)
+			}
+			v = value
+		}
+		if v != nil {
+			converted[k] = v
+		}
 	}
-	return nil
+	return converted, nil
 }
 
 // --- Helpers ---
@@ -382,62 +2151,144 @@ func copyBindingTable(bt BindingTable) BindingTable {
 	return newBt
 }
 
-func evaluateExpression(expr Expression, match BindingTable) (interface{}, error) {
+// evaluateExpression evaluates expr against one row of bindings. g is used
+// by expressions that read the graph, such as pattern expressions.
+func evaluateExpression(expr Expression, match BindingTable, g GraphStorage) (interface{}, error) {
 	switch e := expr.(type) {
 	case *Literal:
 		return e.Value, nil
 	case *Identifier:
 		val, ok := match[e.Name]
 		if !ok {
-			return nil, fmt.Errorf("variable %s not found", e.Name)
+			return nil, unboundVariableError(e.Name)
+		}
+		return val, nil
+	case *Parameter:
+		val, ok := match[parameterKey(e.Name)]
+		if !ok {
+			return nil, fmt.Errorf("parameter $%s not supplied", e.Name)
 		}
 		return val, nil
 	case *PropertyAccess:
 		obj, ok := match[e.Variable]
 		if !ok {
-			return nil, fmt.Errorf("variable %s not found", e.Variable)
+			return nil, unboundVariableError(e.Variable)
 		}
 
 		// Check if obj is Node or Edge
+		var val interface{}
 		if node, ok := obj.(*graph.Node); ok {
-			val, exists := node.GetProperty(e.Property)
+			v, exists := node.GetProperty(e.Property)
 			if !exists {
-				return nil, nil // Property not found is null
+				return builtinProperty(obj, e.Property) // Property not found is null
 			}
-			return val, nil
+			val = v
 		} else if edge, ok := obj.(*graph.Edge); ok {
-			val, exists := edge.GetProperty(e.Property)
+			v, exists := edge.GetProperty(e.Property)
 			if !exists {
+				return builtinProperty(obj, e.Property)
+			}
+			val = v
+		} else {
+			return nil, fmt.Errorf("variable %s is not a node or edge", e.Variable)
+		}
+
+		// Walk nested map keys; a missing key or non-map value is null
+		for _, key := range e.Path {
+			m, ok := val.(graph.Properties)
+			if !ok {
 				return nil, nil
 			}
-			return val, nil
+			val = m[key]
+		}
+		return val, nil
+
+	case *ListLiteral:
+		list := make([]graph.PropertyValue, len(e.Items))
+		for i, item := range e.Items {
+			v, err := evaluateExpression(item, match, g)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
 		}
-		return nil, fmt.Errorf("variable %s is not a node or edge", e.Variable)
+		return list, nil
+
+	case *MapLiteral:
+		m := make(graph.Properties, len(e.Entries))
+		for k, item := range e.Entries {
+			v, err := evaluateExpression(item, match, g)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+
+	case *PointLiteral:
+		return graph.Point{Lat: e.Lat, Lon: e.Lon}, nil
+
+	case *FunctionCall:
+		return callFunction(e, match, g)
 
 	case *BinaryExpr:
-		left, err := evaluateExpression(e.Left, match)
+		left, err := evaluateExpression(e.Left, match, g)
 		if err != nil {
 			return nil, err
 		}
-		right, err := evaluateExpression(e.Right, match)
+		right, err := evaluateExpression(e.Right, match, g)
 		if err != nil {
 			return nil, err
 		}
 
+		if isArithmetic(e.Operator) {
+			return arithmetic(left, e.Operator, right)
+		}
 		return compareValues(left, e.Operator, right)
+
+	case *UnaryExpr:
+		operand, err := evaluateExpression(e.Operand, match, g)
+		if err != nil {
+			return nil, err
+		}
+		// NOT of a missing value stays null, so it filters like one
+		if operand == nil {
+			return nil, nil
+		}
+		b, ok := operand.(bool)
+		if !ok {
+			return nil, fmt.Errorf("NOT requires a boolean operand, got %T", operand)
+		}
+		return !b, nil
 	}
 	return nil, fmt.Errorf("unknown expression type: %T", expr)
 }
 
+// builtinProperty resolves the created_at and updated_at accessors, which
+// apply when the entity has no stored property of that name
+func builtinProperty(obj interface{}, property string) (interface{}, error) {
+	switch property {
+	case "created_at":
+		return fnCreated([]interface{}{obj})
+	case "updated_at":
+		return fnUpdated([]interface{}{obj})
+	}
+	return nil, nil
+}
+
 func compareValues(left interface{}, op string, right interface{}) (bool, error) {
 	// Simple comparison logic for MVP
-	// TODO: Handle type coercion and more types
+	// Numbers compare by value regardless of type, so an int property
+	// matches a float literal. Times compare chronologically, and an
+	// ISO-8601 string compared against a time is parsed first. Ordering
+	// a null is false; ordering other mismatched types, such as a string
+	// against a number, is an error.
 
 	switch op {
 	case "=":
-		return reflect.DeepEqual(left, right), nil
+		return valuesEqual(left, right), nil
 	case "!=":
-		return !reflect.DeepEqual(left, right), nil
+		return !valuesEqual(left, right), nil
 	case "AND":
 		l, ok1 := left.(bool)
 		r, ok2 := right.(bool)
@@ -452,19 +2303,169 @@ func compareValues(left interface{}, op string, right interface{}) (bool, error)
 			return false, fmt.Errorf("OR requires boolean operands")
 		}
 		return l || r, nil
-	case ">":
-		return compareNumbers(left, right) > 0, nil
-	case "<":
-		return compareNumbers(left, right) < 0, nil
-	case ">=":
-		return compareNumbers(left, right) >= 0, nil
-	case "<=":
-		return compareNumbers(left, right) <= 0, nil
+	case ">", "<", ">=", "<=":
+		if left == nil || right == nil || !orderable(left, right) {
+			return false, nil
+		}
+		if !sameOrderedType(left, right) {
+			return false, fmt.Errorf("cannot compare %s %s %s", typeName(left), op, typeName(right))
+		}
+		cmp := compareOrdered(left, right)
+		switch op {
+		case ">":
+			return cmp > 0, nil
+		case "<":
+			return cmp < 0, nil
+		case ">=":
+			return cmp >= 0, nil
+		default:
+			return cmp <= 0, nil
+		}
+	case "IN":
+		if right == nil {
+			return false, nil
+		}
+		list, ok := right.([]graph.PropertyValue)
+		if !ok {
+			return false, fmt.Errorf("IN requires a list, got %T", right)
+		}
+		for _, item := range list {
+			if valuesEqual(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "CONTAINS":
+		l, ok1 := left.(string)
+		r, ok2 := right.(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		return strings.Contains(l, r), nil
+	case "STARTS WITH":
+		l, ok1 := left.(string)
+		r, ok2 := right.(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		return strings.HasPrefix(l, r), nil
+	case "ENDS WITH":
+		l, ok1 := left.(string)
+		r, ok2 := right.(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		return strings.HasSuffix(l, r), nil
 	}
 
 	return false, fmt.Errorf("unknown operator: %s", op)
 }
 
+// valuesEqual compares numbers numerically, times chronologically and
+// everything else deeply
+func valuesEqual(left, right interface{}) bool {
+	if isNumber(left) && isNumber(right) {
+		return toFloat(left) == toFloat(right)
+	}
+	if t1, t2, ok := timePair(left, right); ok {
+		return t1.Equal(t2)
+	}
+
+	switch l := left.(type) {
+	case []graph.PropertyValue:
+		r, ok := right.([]graph.PropertyValue)
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !valuesEqual(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	case graph.Properties:
+		r, ok := right.(graph.Properties)
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for k, v := range l {
+			rv, exists := r[k]
+			if !exists || !valuesEqual(v, rv) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// compareOrdered orders two strings lexically, times chronologically and
+// anything else numerically
+func compareOrdered(a, b interface{}) int {
+	if t1, t2, ok := timePair(a, b); ok {
+		return t1.Compare(t2)
+	}
+	if s1, ok := a.(string); ok {
+		if s2, ok := b.(string); ok {
+			return strings.Compare(s1, s2)
+		}
+	}
+	return compareNumbers(a, b)
+}
+
+// timePair converts a and b to times when at least one of them is a time
+// and the other is a time or a timestamp string
+func timePair(a, b interface{}) (time.Time, time.Time, bool) {
+	_, aIsTime := a.(time.Time)
+	_, bIsTime := b.(time.Time)
+	if !aIsTime && !bIsTime {
+		return time.Time{}, time.Time{}, false
+	}
+	t1, ok1 := timeValue(a)
+	t2, ok2 := timeValue(b)
+	return t1, t2, ok1 && ok2
+}
+
+// orderable reports false for lists and maps, which have no order, and
+// for a time compared against a value that is neither a time nor a
+// timestamp string
+func orderable(a, b interface{}) bool {
+	if isComposite(a) || isComposite(b) {
+		return false
+	}
+	_, aIsTime := a.(time.Time)
+	_, bIsTime := b.(time.Time)
+	if !aIsTime && !bIsTime {
+		return true
+	}
+	_, _, ok := timePair(a, b)
+	return ok
+}
+
+// sameOrderedType reports whether a and b can be ordered against each other:
+// two numbers, two strings, or a time and a time or timestamp string.
+// Other pairs, such as a string and a number, are query mistakes rather
+// than values to coerce.
+func sameOrderedType(a, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		return true
+	}
+	if _, _, ok := timePair(a, b); ok {
+		return true
+	}
+	_, aIsString := a.(string)
+	_, bIsString := b.(string)
+	return aIsString && bIsString
+}
+
+func isComposite(v interface{}) bool {
+	switch v.(type) {
+	case []graph.PropertyValue, graph.Properties:
+		return true
+	}
+	return false
+}
+
 func compareNumbers(a, b interface{}) int {
 	// Convert to float64 for comparison
 	v1 := toFloat(a)
@@ -479,12 +2480,36 @@ func compareNumbers(a, b interface{}) int {
 	return 0
 }
 
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
+
 func toFloat(v interface{}) float64 {
 	switch i := v.(type) {
 	case int:
 		return float64(i)
+	case int8:
+		return float64(i)
+	case int16:
+		return float64(i)
+	case int32:
+		return float64(i)
 	case int64:
 		return float64(i)
+	case uint:
+		return float64(i)
+	case uint8:
+		return float64(i)
+	case uint16:
+		return float64(i)
+	case uint32:
+		return float64(i)
+	case uint64:
+		return float64(i)
 	case float64:
 		return i
 	case float32:
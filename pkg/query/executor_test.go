@@ -1,7 +1,10 @@
 package query
 
 import (
+	"fmt"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
 	"github.com/fnuworsu/rdgDB/pkg/storage"
@@ -34,6 +37,15 @@ func createTestGraph(t *testing.T) *storage.Graph {
 	return g
 }
 
+// run parses and executes input
+func run(t *testing.T, g GraphStorage, input string) *Result {
+	q, err := NewParser(input).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	return result
+}
+
 func TestExecute_Scan(t *testing.T) {
 	g := createTestGraph(t)
 
@@ -95,6 +107,45 @@ func TestExecute_Filter(t *testing.T) {
 	assert.False(t, names["Bob"])
 }
 
+func TestExecute_ComparisonTypes(t *testing.T) {
+	g := createTestGraph(t)
+
+	// Strings order lexically and numbers numerically; a missing
+	// property is null and orders against nothing
+	names := func(where string) []interface{} {
+		var out []interface{}
+		for _, row := range run(t, g, `MATCH (p:Person) WHERE `+where+` RETURN p.name ORDER BY p.name`).Rows {
+			out = append(out, row["p.name"])
+		}
+		return out
+	}
+	assert.Equal(t, []interface{}{"Bob", "Charlie"}, names(`p.name > "Alice"`))
+	assert.Equal(t, []interface{}{"Alice", "Charlie"}, names(`p.age >= 29.5`))
+	assert.Empty(t, names(`p.missing < 5`))
+	assert.Empty(t, names(`p.missing >= "a"`))
+
+	for _, where := range []string{
+		`p.name > 5`,
+		`p.name <= 5.5`,
+		`5 < p.name`,
+		`p.age < "30"`,
+		`p.age >= true`,
+		`p.name > false`,
+		`true > false`,
+		`p > 5`,
+	} {
+		q, err := NewParser(`MATCH (p:Person) WHERE ` + where + ` RETURN p.name`).Parse()
+		require.NoError(t, err, where)
+		_, err = q.Execute(g)
+		assert.ErrorContains(t, err, "cannot compare", where)
+	}
+
+	q, err := NewParser(`MATCH (p:Person) WHERE p.name > 5 RETURN p`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.ErrorContains(t, err, "cannot compare string > int")
+}
+
 func TestExecute_Expand(t *testing.T) {
 	g := createTestGraph(t)
 
@@ -136,6 +187,113 @@ func TestExecute_Expand(t *testing.T) {
 	assert.True(t, foundBobCharlie)
 }
 
+func TestExecute_EdgeProperties(t *testing.T) {
+	g := storage.NewGraph()
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	charlie, _ := g.AddNode("Person", graph.Properties{"name": "Charlie"})
+	g.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+	g.AddEdge(bob.ID, charlie.ID, "KNOWS", graph.Properties{"since": 2015})
+
+	q, err := NewParser(`MATCH (a {name: "Alice"})-[r:KNOWS]->(b) RETURN r.since, r`).Parse()
+	require.NoError(t, err)
+
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+
+	row := result.Rows[0]
+	assert.Equal(t, 2020, row["r.since"])
+
+	edge, ok := row["r"].(*graph.Edge)
+	require.True(t, ok, "RETURN r should yield the edge")
+	assert.Equal(t, "KNOWS", edge.Label)
+	assert.Equal(t, fmt.Sprintf("(%d)-[:KNOWS {since: 2020}]->(%d)", alice.ID, bob.ID), fmt.Sprint(row["r"]))
+
+	// Inline edge properties filter without a named edge variable
+	q, err = NewParser(`MATCH (a)-[:KNOWS {since: 2015}]->(b) RETURN a.name, b.name`).Parse()
+	require.NoError(t, err)
+
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Bob", result.Rows[0]["a.name"])
+	assert.Equal(t, "Charlie", result.Rows[0]["b.name"])
+}
+
+func TestExecute_ReturnBoolean(t *testing.T) {
+	g := createTestGraph(t)
+
+	q, err := NewParser(`MATCH (p:Person) RETURN p.name, p.age >= 30 AS senior, p.city = "SF" ORDER BY p.name`).Parse()
+	require.NoError(t, err)
+
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p.name", "senior", `p.city = "SF"`}, result.Columns)
+	assert.Equal(t, []Row{
+		{"p.name": "Alice", "senior": true, `p.city = "SF"`: true},
+		{"p.name": "Bob", "senior": false, `p.city = "SF"`: false},
+		{"p.name": "Charlie", "senior": true, `p.city = "SF"`: true},
+	}, result.Rows)
+}
+
+func TestExecute_Not(t *testing.T) {
+	g := createTestGraph(t)
+
+	names := func(input string) []string {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+		names := make([]string, len(result.Rows))
+		for i, row := range result.Rows {
+			names[i] = row["p.name"].(string)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"Bob"}, names(`MATCH (p:Person) WHERE NOT p.city = "SF" RETURN p.name`))
+	assert.Equal(t, []string{"Alice", "Bob"},
+		names(`MATCH (p:Person) WHERE NOT (p.age > 30 AND p.city = "SF") RETURN p.name ORDER BY p.name`))
+	assert.Equal(t, []string{"Alice", "Charlie"},
+		names(`MATCH (p:Person) WHERE NOT NOT p.city = "SF" RETURN p.name ORDER BY p.name`))
+
+	// A missing property stays null under NOT and matches nothing
+	assert.Empty(t, names(`MATCH (p:Person) WHERE NOT p.active RETURN p.name`))
+
+	q, err := NewParser(`MATCH (p:Person) WHERE NOT p.name RETURN p.name`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.Error(t, err)
+}
+
+func TestExecute_ReturnStar(t *testing.T) {
+	g := createTestGraph(t)
+
+	q, err := NewParser(`MATCH (a:Person)-[:KNOWS]->(b:Person)-[r:KNOWS]->(c) RETURN *`).Parse()
+	require.NoError(t, err)
+	q.Parameters = map[string]interface{}{"unused": 1}
+
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "r"}, result.Columns)
+	require.Len(t, result.Rows, 1)
+	row := result.Rows[0]
+	assert.Len(t, row, 4)
+	assert.Equal(t, "Alice", row["a"].(*graph.Node).Properties.Map()["name"])
+	assert.Equal(t, "Bob", row["b"].(*graph.Node).Properties.Map()["name"])
+	assert.Equal(t, "Charlie", row["c"].(*graph.Node).Properties.Map()["name"])
+	assert.Equal(t, "KNOWS", row["r"].(*graph.Edge).Label)
+
+	// Anonymous nodes are not returned, and columns survive an empty result
+	q, err = NewParser(`MATCH (a)-[r:KNOWS]->() WHERE a.name = "Nobody" RETURN *`).Parse()
+	require.NoError(t, err)
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "r"}, result.Columns)
+	assert.Empty(t, result.Rows)
+}
+
 func TestExecute_Limit(t *testing.T) {
 	g := createTestGraph(t)
 
@@ -155,6 +313,81 @@ func TestExecute_Limit(t *testing.T) {
 	assert.Len(t, result.Rows, 2)
 }
 
+func TestExecute_OrderByMultipleKeys(t *testing.T) {
+	g := createTestGraph(t)
+	g.AddNode("Person", graph.Properties{"name": "Dave", "city": "NY"})
+	g.AddNode("Person", graph.Properties{"name": "Erin", "age": 40})
+
+	names := func(result *Result) []interface{} {
+		var out []interface{}
+		for _, row := range result.Rows {
+			out = append(out, row["name"])
+		}
+		return out
+	}
+
+	// Alice and Charlie tie on SF; age DESC puts Charlie first. Nulls
+	// sort last for each key, in either direction.
+	q, err := NewParser(`MATCH (p:Person) RETURN p.name AS name ORDER BY p.city ASC, p.age DESC`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"Bob", "Dave", "Charlie", "Alice", "Erin"}, names(result))
+
+	q, err = NewParser(`MATCH (p:Person) RETURN p.name AS name ORDER BY p.city DESC, p.age ASC`).Parse()
+	require.NoError(t, err)
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"Alice", "Charlie", "Bob", "Dave", "Erin"}, names(result))
+
+	// Aliases can be sorted on, and LIMIT applies after sorting
+	q, err = NewParser(`MATCH (p:Person) RETURN p.name AS name ORDER BY name DESC LIMIT 2`).Parse()
+	require.NoError(t, err)
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"Erin", "Dave"}, names(result))
+}
+
+func TestExecute_ParallelEdgesDistinct(t *testing.T) {
+	g := storage.NewGraph()
+	a, _ := g.AddNode("Person", graph.Properties{"name": "A"})
+	b, _ := g.AddNode("Person", graph.Properties{"name": "B"})
+	g.AddEdge(a.ID, b.ID, "KNOWS", graph.Properties{"since": 2019})
+	g.AddEdge(a.ID, b.ID, "KNOWS", graph.Properties{"since": 2021})
+
+	tests := []struct {
+		input    string
+		distinct bool
+		rows     int
+	}{
+		{`MATCH (a {name: "A"})-[:KNOWS]->(b) RETURN b`, true, 1},
+		{`MATCH (a {name: "A"})-[r:KNOWS]->(b) RETURN b`, true, 1},
+		{`MATCH (a {name: "A"})-[r:KNOWS]->(b) RETURN r`, false, 2},
+		{`MATCH (a {name: "A"})-[r:KNOWS]->(b) WHERE r.since > 2000 RETURN b`, false, 2},
+		{`MATCH (a {name: "A"})-[r:KNOWS]->(b) RETURN b ORDER BY r.since`, false, 2},
+		{`MATCH (a {name: "A"})-[:KNOWS {since: 2021}]->(b) RETURN b`, false, 1},
+	}
+	for _, tt := range tests {
+		q, err := NewParser(tt.input).Parse()
+		require.NoError(t, err)
+
+		plan, err := BuildExecutionPlan(q)
+		require.NoError(t, err)
+		var expand *ExpandOperator
+		for _, op := range plan.Operators {
+			if e, ok := op.(*ExpandOperator); ok {
+				expand = e
+			}
+		}
+		require.NotNil(t, expand, tt.input)
+		assert.Equal(t, tt.distinct, expand.Distinct, tt.input)
+
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+		assert.Len(t, result.Rows, tt.rows, tt.input)
+	}
+}
+
 func TestExecute_InlineProperties(t *testing.T) {
 	g := createTestGraph(t)
 
@@ -177,3 +410,806 @@ func TestExecute_InlineProperties(t *testing.T) {
 	assert.Len(t, result.Rows, 1)
 	assert.Equal(t, 30, result.Rows[0]["n.age"])
 }
+
+func createCycleGraph(t *testing.T, size int) *storage.Graph {
+	g := storage.NewGraph()
+	nodes := make([]*graph.Node, size)
+	for i := range nodes {
+		nodes[i], _ = g.AddNode("Node", graph.Properties{"idx": i})
+	}
+	for i := range nodes {
+		g.AddEdge(nodes[i].ID, nodes[(i+1)%size].ID, "NEXT", nil)
+	}
+	return g
+}
+
+func TestExecute_VariableLength(t *testing.T) {
+	g := createTestGraph(t)
+
+	// Alice -KNOWS-> Bob -KNOWS-> Charlie
+	q, err := NewParser(`MATCH (a:Person {name: "Alice"})-[:KNOWS*1..2]->(b) RETURN b.name`).Parse()
+	require.NoError(t, err)
+
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+
+	q, err = NewParser(`MATCH (a:Person {name: "Alice"})-[:KNOWS*2]->(b) RETURN b.name`).Parse()
+	require.NoError(t, err)
+
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Charlie", result.Rows[0]["b.name"])
+}
+
+func TestExecute_VariableLengthHopLimit(t *testing.T) {
+	// A 50-node cycle: unbounded [*] would walk the whole ring from every start
+	g := createCycleGraph(t, 50)
+
+	q, err := NewParser(`MATCH (a:Node {idx: 0})-[*]->(b) RETURN b.idx`).Parse()
+	require.NoError(t, err)
+	q.HopLimit = &HopLimit{MaxHops: 5}
+
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 5, "expansion should stop at the hop cap")
+
+	// Explicit upper bounds are not subject to the cap
+	q, err = NewParser(`MATCH (a:Node {idx: 0})-[*1..8]->(b) RETURN b.idx`).Parse()
+	require.NoError(t, err)
+	q.HopLimit = &HopLimit{MaxHops: 5}
+
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 8)
+
+	// Error mode reports the overflow instead of truncating
+	q, err = NewParser(`MATCH (a:Node {idx: 0})-[*]->(b) RETURN b.idx`).Parse()
+	require.NoError(t, err)
+	q.HopLimit = &HopLimit{MaxHops: 5, ErrorOnLimit: true}
+
+	_, err = q.Execute(g)
+	assert.ErrorIs(t, err, ErrHopLimitExceeded)
+}
+
+func TestExecute_VariableLengthShortCycle(t *testing.T) {
+	// Edges are never reused within a path, so a small cycle terminates
+	// even with the default limit
+	g := createCycleGraph(t, 3)
+
+	q, err := NewParser(`MATCH (a:Node {idx: 0})-[*]->(b) RETURN b.idx`).Parse()
+	require.NoError(t, err)
+
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 3)
+}
+
+func TestExecute_CrossVariablePredicates(t *testing.T) {
+	dir := t.TempDir()
+	pg, err := storage.NewPersistentGraph(dir+"/wal", dir+"/snapshots")
+	require.NoError(t, err)
+
+	alice, _ := pg.AddNode("Person", graph.Properties{"name": "Alice", "age": 30})
+	bob, _ := pg.AddNode("Person", graph.Properties{"name": "Bob", "age": 25})
+	carol, _ := pg.AddNode("Person", graph.Properties{"name": "Carol", "age": 25})
+	pg.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	pg.AddEdge(bob.ID, carol.ID, "KNOWS", nil)
+	pg.AddEdge(carol.ID, alice.ID, "KNOWS", nil)
+
+	run := func(t *testing.T, g GraphStorage, input string) []Row {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+		return result.Rows
+	}
+
+	check := func(t *testing.T, g GraphStorage) {
+		rows := run(t, g, `MATCH (a:Person)-[:KNOWS]->(b:Person) WHERE a.age > b.age RETURN a.name, b.name`)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "Alice", rows[0]["a.name"])
+		assert.Equal(t, "Bob", rows[0]["b.name"])
+
+		rows = run(t, g, `MATCH (a:Person)-[:KNOWS]->(b:Person) WHERE a.age = b.age RETURN a.name, b.name`)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "Bob", rows[0]["a.name"])
+		assert.Equal(t, "Carol", rows[0]["b.name"])
+
+		rows = run(t, g, `MATCH (a:Person)-[:KNOWS]->(b:Person) WHERE a.age != b.age RETURN a.name`)
+		assert.Len(t, rows, 2)
+
+		// Int literals still match after recovery
+		rows = run(t, g, `MATCH (a:Person) WHERE a.age = 30 RETURN a.name`)
+		require.Len(t, rows, 1)
+		assert.Equal(t, "Alice", rows[0]["a.name"])
+	}
+
+	t.Run("live", func(t *testing.T) { check(t, pg) })
+
+	require.NoError(t, pg.Close())
+	recovered, err := storage.NewPersistentGraph(dir+"/wal", dir+"/snapshots")
+	require.NoError(t, err)
+	defer recovered.Close()
+
+	t.Run("recovered", func(t *testing.T) { check(t, recovered) })
+}
+
+func TestExecute_LimitKeepsLowestIDs(t *testing.T) {
+	g := storage.NewGraph()
+	for i := 0; i < 200; i++ {
+		parity := "even"
+		if i%2 == 1 {
+			parity = "odd"
+		}
+		g.AddNode("Person", graph.Properties{"id": i, "parity": parity})
+	}
+
+	for i := 0; i < 10; i++ {
+		query, err := NewParser(`MATCH (p:Person) WHERE p.parity = "odd" RETURN p.id LIMIT 3`).Parse()
+		require.NoError(t, err)
+		result, err := query.Execute(g)
+		require.NoError(t, err)
+		assert.Equal(t, []Row{{"p.id": 1}, {"p.id": 3}, {"p.id": 5}}, result.Rows)
+
+		query, err = NewParser(`MATCH (p) RETURN p.id LIMIT 2`).Parse()
+		require.NoError(t, err)
+		result, err = query.Execute(g)
+		require.NoError(t, err)
+		assert.Equal(t, []Row{{"p.id": 0}, {"p.id": 1}}, result.Rows)
+	}
+}
+
+func TestExecute_StableOrder(t *testing.T) {
+	g := storage.NewGraph()
+	people := make([]*graph.Node, 50)
+	for i := range people {
+		people[i], _ = g.AddNode("Person", graph.Properties{"id": i})
+	}
+	for i := range people {
+		for _, j := range []int{(i * 7) % 50, (i * 13) % 50, (i + 1) % 50} {
+			_, err := g.AddEdge(people[i].ID, people[j].ID, "KNOWS", nil)
+			require.NoError(t, err)
+		}
+	}
+
+	run := func(input string) []Row {
+		query, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		query.StableOrder = true
+		result, err := query.Execute(g)
+		require.NoError(t, err)
+		return result.Rows
+	}
+
+	for _, input := range []string{
+		`MATCH (p:Person) RETURN p.id`,
+		`MATCH (p) RETURN p.id LIMIT 5`,
+		`MATCH (a:Person)-[r:KNOWS]-(b) RETURN a.id, b.id`,
+		`MATCH (a:Person)-[:KNOWS*1..2]->(b) WHERE a.id < 5 RETURN a.id, b.id`,
+	} {
+		first := run(input)
+		require.NotEmpty(t, first)
+		for i := 0; i < 20; i++ {
+			require.Equal(t, first, run(input), input)
+		}
+	}
+
+	// Scans follow node IDs, so LIMIT without ORDER BY keeps the first nodes
+	rows := run(`MATCH (p:Person) RETURN p.id LIMIT 3`)
+	assert.Equal(t, []Row{{"p.id": 0}, {"p.id": 1}, {"p.id": 2}}, rows)
+
+	// Expansions follow edge IDs
+	rows = run(`MATCH (a:Person {id: 3})-[:KNOWS]->(b) RETURN b.id`)
+	assert.Equal(t, []Row{{"b.id": 21}, {"b.id": 39}, {"b.id": 4}}, rows)
+}
+
+func TestExecute_StringPredicates(t *testing.T) {
+	g := storage.NewGraph()
+	for _, name := range []string{"Alice", "Alma", "Grace"} {
+		g.AddNode("Person", graph.Properties{"name": name})
+	}
+	g.AddNode("Person", graph.Properties{"name": 42})
+
+	tests := []struct {
+		where string
+		want  []interface{}
+	}{
+		{`p.name STARTS WITH "Al"`, []interface{}{"Alice", "Alma"}},
+		{`p.name ENDS WITH "ce"`, []interface{}{"Alice", "Grace"}},
+		{`p.name STARTS WITH "Al" AND p.name ENDS WITH "ce"`, []interface{}{"Alice"}},
+		{`p.name STARTS WITH ""`, []interface{}{"Alice", "Alma", "Grace"}},
+		{`p.name ENDS WITH 2`, nil},
+	}
+	for _, tt := range tests {
+		query, err := NewParser(`MATCH (p:Person) WHERE ` + tt.where + ` RETURN p.name ORDER BY p.name`).Parse()
+		require.NoError(t, err)
+		result, err := query.Execute(g)
+		require.NoError(t, err)
+		var names []interface{}
+		for _, row := range result.Rows {
+			names = append(names, row["p.name"])
+		}
+		assert.Equal(t, tt.want, names, tt.where)
+	}
+}
+
+func TestExecute_ReadOnlyGraph(t *testing.T) {
+	dir := t.TempDir()
+	pg, err := storage.NewPersistentGraph(dir+"/wal", dir+"/snapshots")
+	require.NoError(t, err)
+	alice, _ := pg.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := pg.AddNode("Person", graph.Properties{"name": "Bob"})
+	pg.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	require.NoError(t, pg.Close())
+
+	opts := storage.DefaultOptions()
+	opts.ReadOnly = true
+	ro, err := storage.NewPersistentGraphWithOptions(dir+"/wal", dir+"/snapshots", opts)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	q, err := NewParser(`MATCH (a:Person)-[:KNOWS]->(b:Person) RETURN a.name, b.name`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(ro)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Alice", result.Rows[0]["a.name"])
+	assert.Equal(t, "Bob", result.Rows[0]["b.name"])
+}
+
+func TestExecute_CompositeProperties(t *testing.T) {
+	g := storage.NewGraph()
+	g.AddNode("Person", graph.Properties{
+		"name":    "Alice",
+		"skills":  []string{"golang", "sql"},
+		"address": map[string]interface{}{"city": "SF", "geo": map[string]interface{}{"zip": 94103}},
+	})
+	g.AddNode("Person", graph.Properties{
+		"name":    "Bob",
+		"skills":  []string{"rust"},
+		"address": map[string]interface{}{"city": "NY"},
+	})
+	g.AddNode("Person", graph.Properties{"name": "Carol"})
+
+	run := func(input string) []Row {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+		return result.Rows
+	}
+
+	rows := run(`MATCH (p:Person) WHERE "golang" IN p.skills RETURN p.name`)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Alice", rows[0]["p.name"])
+
+	rows = run(`MATCH (p:Person) WHERE p.name IN ["Bob", "Carol"] RETURN p.name`)
+	assert.Len(t, rows, 2)
+
+	rows = run(`MATCH (p:Person) WHERE p.address.city = "NY" RETURN p.name, p.address.city, p.address.geo.zip, size(p.skills)`)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "NY", rows[0]["p.address.city"])
+	assert.Nil(t, rows[0]["p.address.geo.zip"])
+	assert.Equal(t, 1, rows[0]["size(p.skills)"])
+
+	rows = run(`MATCH (p:Person) WHERE p.address.geo.zip = 94103 AND p.skills = ["golang", "sql"] RETURN p.name`)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Alice", rows[0]["p.name"])
+
+	// A path through a missing property or a value that is not a map is
+	// null at every level
+	rows = run(`MATCH (p:Person) RETURN p.name, p.address.geo.zip, p.name.first ORDER BY p.name`)
+	require.Len(t, rows, 3)
+	assert.Equal(t, 94103, rows[0]["p.address.geo.zip"])
+	for _, row := range rows {
+		assert.Nil(t, row["p.name.first"])
+	}
+	assert.Nil(t, rows[1]["p.address.geo.zip"])
+	assert.Nil(t, rows[2]["p.address.geo.zip"])
+
+	// Lists have no order
+	rows = run(`MATCH (p:Person) WHERE p.skills > ["a"] RETURN p.name`)
+	assert.Empty(t, rows)
+
+	q, err := NewParser(`MATCH (p:Person) WHERE "x" IN p.name RETURN p.name`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.ErrorContains(t, err, "IN requires a list")
+}
+
+func TestExecute_SkipsExpired(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := storage.NewGraph()
+	g.SetClock(func() time.Time { return now })
+
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	carol, _ := g.AddNode("Person", graph.Properties{"name": "Carol"})
+	g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	knowsCarol, _ := g.AddEdge(alice.ID, carol.ID, "KNOWS", nil)
+
+	// Bob has expired but has not been swept; the edge to Carol is still live
+	require.NoError(t, g.SetExpiry(bob.ID, now))
+	require.NoError(t, g.SetEdgeExpiry(knowsCarol.ID, now.Add(time.Hour)))
+
+	run := func(src string) []string {
+		q, err := NewParser(src).Parse()
+		require.NoError(t, err)
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+		var names []string
+		for _, row := range result.Rows {
+			names = append(names, row["b.name"].(string))
+		}
+		return names
+	}
+
+	assert.ElementsMatch(t, []string{"Alice", "Carol"}, run(`MATCH (b:Person) RETURN b.name`))
+	assert.Equal(t, []string{"Carol"}, run(`MATCH (a:Person)-[:KNOWS]->(b) RETURN b.name`))
+
+	now = now.Add(time.Hour)
+	assert.Empty(t, run(`MATCH (a:Person)-[:KNOWS]->(b) RETURN b.name`))
+}
+
+func TestExecute_RepeatedVariableClosesCycle(t *testing.T) {
+	g := storage.NewGraph()
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	carol, _ := g.AddNode("Person", graph.Properties{"name": "Carol"})
+
+	// Alice and Bob, and Bob and Carol, know each other; Carol -> Alice is one-way
+	g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	g.AddEdge(bob.ID, alice.ID, "KNOWS", nil)
+	g.AddEdge(bob.ID, carol.ID, "KNOWS", nil)
+	g.AddEdge(carol.ID, bob.ID, "KNOWS", nil)
+	g.AddEdge(carol.ID, alice.ID, "KNOWS", nil)
+
+	run := func(src string, columns ...string) []string {
+		q, err := NewParser(src).Parse()
+		require.NoError(t, err)
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+		var rows []string
+		for _, row := range result.Rows {
+			var names []string
+			for _, c := range columns {
+				names = append(names, row[c].(string))
+			}
+			rows = append(rows, fmt.Sprint(names))
+		}
+		return rows
+	}
+
+	assert.ElementsMatch(t, []string{"[Alice Bob]", "[Bob Alice]", "[Bob Carol]", "[Carol Bob]"},
+		run(`MATCH (a)-[:KNOWS]->(b)-[:KNOWS]->(a) RETURN a.name, b.name`, "a.name", "b.name"))
+
+	assert.ElementsMatch(t, []string{"[Alice Bob Carol]", "[Bob Carol Alice]", "[Carol Alice Bob]"},
+		run(`MATCH (a)-[:KNOWS]->(b)-[:KNOWS]->(c)-[:KNOWS]->(a) RETURN a.name, b.name, c.name`, "a.name", "b.name", "c.name"))
+
+	// Variable-length expansions must end on the bound node too
+	assert.ElementsMatch(t, []string{"[Alice]", "[Bob]", "[Bob]", "[Carol]"},
+		run(`MATCH (a)-[:KNOWS*2]->(a) RETURN a.name`, "a.name"))
+}
+
+func TestExecute_MultiplePatterns(t *testing.T) {
+	g := createTestGraph(t)
+
+	// Patterns sharing a variable extend the same matches
+	result := run(t, g, `MATCH (a:Person)-[:KNOWS]->(b), (b)-[:KNOWS]->(c), (a)-[:WORKS_AT]->(w) RETURN a.name, c.name, w.name`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, Row{"a.name": "Alice", "c.name": "Charlie", "w.name": "Google"}, result.Rows[0])
+
+	// Independent patterns without a join condition pair every match
+	result = run(t, g, `MATCH (a:Person), (c:Company), (:Company) RETURN a.name, c.name`)
+	assert.Len(t, result.Rows, 3)
+
+	result = run(t, g, `MATCH (a:Person), (b:Person) WHERE a.city = b.city AND a.name < b.name RETURN a.name, b.name`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, Row{"a.name": "Alice", "b.name": "Charlie"}, result.Rows[0])
+}
+
+func TestExecute_SkipsTombstoned(t *testing.T) {
+	g := createTestGraph(t)
+	g.SetSoftDelete(true)
+
+	q, err := NewParser(`MATCH (a:Person)-[:KNOWS]->(b) RETURN a.name, b.name`).Parse()
+	require.NoError(t, err)
+
+	var bob graph.NodeID
+	g.IterateNodes(func(n *graph.Node) bool {
+		if n.Properties.Map()["name"] == "Bob" {
+			bob = n.ID
+		}
+		return true
+	})
+	require.NoError(t, g.DeleteNode(bob))
+
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Empty(t, result.Rows)
+
+	require.NoError(t, g.RestoreNode(bob))
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+}
+
+// createLargeLabeledGraph adds n Person nodes with a bio property and as
+// many unlabeled filler nodes
+func createLargeLabeledGraph(n int) *storage.Graph {
+	g := storage.NewGraph()
+	for i := 0; i < n; i++ {
+		g.AddNode("Person", graph.Properties{
+			"id":  i,
+			"bio": fmt.Sprintf("person %d likes graphs, databases and long walks %d", i, i%97),
+		})
+		g.AddNode("Thing", graph.Properties{"id": i})
+	}
+	return g
+}
+
+func TestExecute_ParallelScan(t *testing.T) {
+	g := createLargeLabeledGraph(2000)
+
+	run := func(p ScanParallelism, src string) ([]Row, error) {
+		q, err := NewParser(src).Parse()
+		require.NoError(t, err)
+		q.ScanParallelism = &p
+		result, err := q.Execute(g)
+		if err != nil {
+			return nil, err
+		}
+		return result.Rows, nil
+	}
+
+	const src = `MATCH (p:Person) WHERE p.bio CONTAINS "walks 42" AND p.id > 100 RETURN p.id`
+	serial, err := run(ScanParallelism{}, src)
+	require.NoError(t, err)
+	parallel, err := run(ScanParallelism{Threshold: 1, Workers: 7}, src)
+	require.NoError(t, err)
+	assert.NotEmpty(t, serial)
+	assert.ElementsMatch(t, serial, parallel)
+
+	// Below the threshold the scan stays serial and still works
+	rows, err := run(ScanParallelism{Threshold: 1000000, Workers: 7}, src)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, serial, rows)
+
+	// Filter errors from any worker are reported
+	_, err = run(ScanParallelism{Threshold: 1, Workers: 4}, `MATCH (p:Person) WHERE "x" IN p.bio RETURN p.id`)
+	assert.ErrorContains(t, err, "IN requires a list")
+}
+
+// BenchmarkScanFilter compares a serial and a parallel scan with a
+// CONTAINS filter over 200k Person nodes (400k nodes in total)
+func BenchmarkScanFilter(b *testing.B) {
+	g := createLargeLabeledGraph(200000)
+
+	query, err := NewParser(`MATCH (p:Person) WHERE p.bio CONTAINS "walks 42" RETURN p.id`).Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+	run := func(p ScanParallelism) func(b *testing.B) {
+		return func(b *testing.B) {
+			query.ScanParallelism = &p
+			for i := 0; i < b.N; i++ {
+				if _, err := query.Execute(g); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+
+	b.Run("serial", run(ScanParallelism{}))
+	b.Run("parallel", run(ScanParallelism{Threshold: 1}))
+}
+
+func TestExecute_ReturnWithoutMatch(t *testing.T) {
+	q, err := NewParser(`RETURN 1 + 1 AS two, toUpper("x")`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(storage.NewGraph())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"two", `toUpper("x")`}, result.Columns)
+	require.Len(t, result.Rows, 1)
+	assert.EqualValues(t, 2, result.Rows[0]["two"])
+	assert.Equal(t, "X", result.Rows[0][`toUpper("x")`])
+
+	// Nothing binds n
+	q, err = NewParser(`RETURN n.name`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(storage.NewGraph())
+	assert.ErrorIs(t, err, ErrUnboundVariable)
+	assert.EqualError(t, err, "variable n not found")
+}
+
+func TestExecute_Create(t *testing.T) {
+	g := storage.NewGraph()
+	q, err := NewParser(`CREATE (:Person {name:"X"})`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Empty(t, result.Rows)
+	require.Equal(t, 1, g.NodeCount())
+
+	q, err = NewParser(`CREATE (a:Person {name: "A", tags: ["x", "y"]})-[:KNOWS {since: 2020}]->(b:Person {name: "B"}), (a)<-[:FOLLOWS]-(b)`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, 3, g.NodeCount())
+	assert.Equal(t, 2, g.EdgeCount())
+
+	result = run(t, g, `MATCH (a:Person)-[r:KNOWS]->(b:Person) RETURN a.name, r.since, b.name, a.tags`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "A", result.Rows[0]["a.name"])
+	assert.EqualValues(t, 2020, result.Rows[0]["r.since"])
+	assert.Equal(t, "B", result.Rows[0]["b.name"])
+	assert.Equal(t, []graph.PropertyValue{"x", "y"}, result.Rows[0]["a.tags"])
+	result = run(t, g, `MATCH (b)-[:FOLLOWS]->(a) RETURN b.name, a.name`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "B", result.Rows[0]["b.name"])
+
+	// A rebound variable fails before anything is created
+	q, err = NewParser(`CREATE (a:Person {name: "C"}), (a:Person)`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.EqualError(t, err, "variable a is already bound")
+	assert.Equal(t, 3, g.NodeCount())
+}
+
+func TestExecute_CreateReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	pg, err := storage.NewPersistentGraph(dir+"/wal", dir+"/snapshots")
+	require.NoError(t, err)
+	require.NoError(t, pg.Close())
+
+	opts := storage.DefaultOptions()
+	opts.ReadOnly = true
+	ro, err := storage.NewPersistentGraphWithOptions(dir+"/wal", dir+"/snapshots", opts)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	q, err := NewParser(`CREATE (:Person {name: "X"})`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(ro)
+	assert.ErrorIs(t, err, storage.ErrReadOnly)
+	assert.Equal(t, 0, ro.NodeCount())
+}
+
+// PersistentGraph is queried directly, so that writes go through its
+// WAL-logged methods
+var (
+	_ GraphStorage  = (*storage.PersistentGraph)(nil)
+	_ patternWriter = (*storage.PersistentGraph)(nil)
+)
+
+func TestExecute_CreateSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	pg, err := storage.NewPersistentGraph(dir+"/wal", dir+"/snapshots")
+	require.NoError(t, err)
+
+	q, err := NewParser(`CREATE (a:Person {name: "Alice", born: 1990})-[:KNOWS {since: 2020}]->(b:Person {name: "Bob"})`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(pg)
+	require.NoError(t, err)
+
+	// Read your writes before the restart...
+	result := run(t, pg, `MATCH (a:Person)-[r:KNOWS]->(b:Person) RETURN a.name, a.born, r.since, b.name`)
+	require.Len(t, result.Rows, 1)
+	require.NoError(t, pg.Close())
+
+	// ...and after it, from the WAL
+	pg, err = storage.NewPersistentGraph(dir+"/wal", dir+"/snapshots")
+	require.NoError(t, err)
+	defer pg.Close()
+	assert.Equal(t, 2, pg.NodeCount())
+	assert.Equal(t, 1, pg.EdgeCount())
+	after := run(t, pg, `MATCH (a:Person)-[r:KNOWS]->(b:Person) RETURN a.name, a.born, r.since, b.name`)
+	assert.Equal(t, result.Rows, after.Rows)
+	assert.Equal(t, 1990, after.Rows[0]["a.born"])
+}
+
+func TestExecute_MapProperties(t *testing.T) {
+	dir := t.TempDir()
+	pg, err := storage.NewPersistentGraph(dir+"/wal", dir+"/snapshots")
+	require.NoError(t, err)
+
+	q, err := NewParser(`CREATE (a:Person {name: "Alice", address: {city: "SF", geo: {zip: 94103}, lines: ["1 Main St"]}})`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(pg)
+	require.NoError(t, err)
+	want := graph.Properties{
+		"city":  "SF",
+		"geo":   graph.Properties{"zip": 94103},
+		"lines": []graph.PropertyValue{"1 Main St"},
+	}
+
+	// The map keeps its value types through the WAL...
+	require.NoError(t, pg.Close())
+	pg, err = storage.NewPersistentGraph(dir+"/wal", dir+"/snapshots")
+	require.NoError(t, err)
+	var alice *graph.Node
+	pg.IterateNodesByLabel("Person", func(node *graph.Node) bool {
+		alice = node
+		return false
+	})
+	require.NotNil(t, alice)
+	assert.Equal(t, want, alice.Properties.Map()["address"])
+
+	// ...and through a snapshot
+	require.NoError(t, pg.Snapshot())
+	require.NoError(t, pg.Close())
+	pg, err = storage.NewPersistentGraph(dir+"/wal", dir+"/snapshots")
+	require.NoError(t, err)
+	defer pg.Close()
+	alice, err = pg.GetNode(alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, want, alice.Properties.Map()["address"])
+
+	// Maps compare equal when their entries do, and have no order
+	result := run(t, pg, `MATCH (p:Person) WHERE p.address.geo = {zip: 94103} RETURN p.name`)
+	assert.Len(t, result.Rows, 1)
+	result = run(t, pg, `MATCH (p:Person {address: {city: "SF", geo: {zip: 94103}, lines: ["1 Main St"]}}) RETURN p.name`)
+	assert.Len(t, result.Rows, 1)
+	result = run(t, pg, `MATCH (p:Person) WHERE p.address = {city: "SF"} RETURN p.name`)
+	assert.Empty(t, result.Rows)
+	result = run(t, pg, `MATCH (p:Person) WHERE p.address > {city: "A"} RETURN p.name`)
+	assert.Empty(t, result.Rows)
+
+	result = run(t, pg, `MATCH (p:Person) RETURN {name: p.name, city: p.address.city} AS summary`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, graph.Properties{"name": "Alice", "city": "SF"}, result.Rows[0]["summary"])
+}
+
+func TestExecute_WithDistinct(t *testing.T) {
+	// Alice and Bob both know Charlie, who works at Google
+	g := storage.NewGraph()
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	charlie, _ := g.AddNode("Person", graph.Properties{"name": "Charlie"})
+	google, _ := g.AddNode("Company", graph.Properties{"name": "Google"})
+	g.AddEdge(alice.ID, charlie.ID, "KNOWS", nil)
+	g.AddEdge(bob.ID, charlie.ID, "KNOWS", nil)
+	g.AddEdge(charlie.ID, google.ID, "WORKS_AT", nil)
+
+	// Without DISTINCT, Charlie is carried forward once per acquaintance
+	result := run(t, g, `MATCH (a:Person)-[:KNOWS]->(b:Person) WITH b MATCH (b)-[:WORKS_AT]->(c:Company) RETURN b.name, c.name`)
+	require.Len(t, result.Rows, 2)
+	for _, row := range result.Rows {
+		assert.Equal(t, Row{"b.name": "Charlie", "c.name": "Google"}, row)
+	}
+
+	result = run(t, g, `MATCH (a:Person)-[:KNOWS]->(b:Person) WITH DISTINCT b MATCH (b)-[:WORKS_AT]->(c:Company) RETURN b.name, c.name`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, Row{"b.name": "Charlie", "c.name": "Google"}, result.Rows[0])
+
+	// Only the WITH variables remain bound
+	q, err := NewParser(`MATCH (a:Person)-[:KNOWS]->(b:Person) WITH DISTINCT b RETURN a.name`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.ErrorIs(t, err, ErrUnboundVariable)
+}
+
+func TestExecute_WithProjection(t *testing.T) {
+	g := createTestGraph(t)
+
+	// The key is the whole tuple: the same city with different ages stays
+	result := run(t, g, `MATCH (p:Person) WITH DISTINCT p.city AS city RETURN city ORDER BY city`)
+	assert.Equal(t, []Row{{"city": "NY"}, {"city": "SF"}}, result.Rows)
+	result = run(t, g, `MATCH (p:Person) WITH DISTINCT p.city AS city, p.age AS age RETURN city`)
+	assert.Len(t, result.Rows, 3)
+
+	// WHERE after WITH filters the projected rows
+	result = run(t, g, `MATCH (p:Person) WITH p, p.age * 2 AS double WHERE double > 60 RETURN p.name ORDER BY p.name`)
+	assert.Equal(t, []Row{{"p.name": "Charlie"}}, result.Rows)
+
+	// A bound variable with a label in the next pattern is checked
+	result = run(t, g, `MATCH (a)-[:WORKS_AT]->(c) WITH c MATCH (c:Person) RETURN c.name`)
+	assert.Empty(t, result.Rows)
+	result = run(t, g, `MATCH (a)-[:WORKS_AT]->(c) WITH c MATCH (c:Company)<-[:WORKS_AT]-(e) RETURN e.name`)
+	assert.Equal(t, []Row{{"e.name": "Alice"}}, result.Rows)
+
+	// Parameters stay visible after WITH
+	q, err := NewParser(`MATCH (p:Person) WITH p WHERE p.age > $min RETURN p.name`).Parse()
+	require.NoError(t, err)
+	q.Parameters = map[string]interface{}{"min": 30}
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"p.name": "Charlie"}}, result.Rows)
+}
+
+func TestExecute_EdgeDirections(t *testing.T) {
+	g := storage.NewGraph()
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	charlie, _ := g.AddNode("Person", graph.Properties{"name": "Charlie"})
+	g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	g.AddEdge(bob.ID, alice.ID, "KNOWS", nil)
+	g.AddEdge(alice.ID, alice.ID, "KNOWS", nil)
+	g.AddEdge(charlie.ID, alice.ID, "KNOWS", nil)
+
+	neighbors := func(input string) []string {
+		result := run(t, g, input)
+		names := make([]string, 0, len(result.Rows))
+		for _, row := range result.Rows {
+			names = append(names, row["b.name"].(string))
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	// Returning r gives one row per edge; the self-loop is followed once
+	for pattern, want := range map[string][]string{
+		`-[r]->`:  {"Alice", "Bob"},
+		`<-[r]-`:  {"Alice", "Bob", "Charlie"},
+		`-[r]-`:   {"Alice", "Bob", "Bob", "Charlie"},
+		`<-[r]->`: {"Alice", "Bob", "Bob", "Charlie"},
+	} {
+		got := neighbors(`MATCH (a:Person {name: "Alice"})` + pattern + `(b) RETURN b.name, r`)
+		assert.Equal(t, want, got, pattern)
+	}
+
+	// Otherwise the reciprocal pair gives one row for Bob
+	got := neighbors(`MATCH (a:Person {name: "Alice"})-[]-(b) RETURN b.name`)
+	assert.Equal(t, []string{"Alice", "Bob", "Charlie"}, got)
+}
+
+// Run with -race: Refresh replays into the graph the queries are reading
+func TestExecute_DuringReadOnlyRefresh(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	writer, err := storage.NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer writer.Close()
+	first, _ := writer.AddNode("Person", graph.Properties{"name": "Alice"})
+
+	opts := storage.DefaultOptions()
+	opts.ReadOnly = true
+	reader, err := storage.NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	const rounds = 50
+	done := make(chan error)
+	go func() {
+		defer close(done)
+		prev := first.ID
+		for i := 0; i < rounds; i++ {
+			node, err := writer.AddNode("Person", graph.Properties{"name": "P", "i": i})
+			if err != nil {
+				done <- err
+				return
+			}
+			edge, _ := writer.AddEdge(prev, first.ID, "KNOWS", nil)
+			if err := writer.UpdateEdgeTarget(edge.ID, node.ID); err != nil {
+				done <- err
+				return
+			}
+			prev = node.ID
+			if _, err := reader.Refresh(); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+
+	query, err := NewParser(`MATCH (a:Person)-[:KNOWS]->(b:Person) RETURN a.name, b.i`).Parse()
+	require.NoError(t, err)
+	for running := true; running; {
+		select {
+		case err, ok := <-done:
+			require.NoError(t, err)
+			running = ok
+		default:
+		}
+		_, err := query.Execute(reader)
+		require.NoError(t, err)
+		reader.EdgeCount()
+	}
+
+	assert.Equal(t, rounds+1, reader.NodeCount())
+	assert.Equal(t, rounds, reader.EdgeCount())
+	result, err := query.Execute(reader)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, rounds)
+}
@@ -0,0 +1,507 @@
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// Function is a scalar function callable from queries, e.g. distance(a, b).
+// Arguments are already evaluated.
+type Function func(args []interface{}) (interface{}, error)
+
+// functions maps lowercased function names to implementations
+var functions = map[string]Function{
+	"distance":  fnDistance,
+	"datetime":  fnDatetime,
+	"created":   fnCreated,
+	"updated":   fnUpdated,
+	"size":      fnSize,
+	"toupper":   fnToUpper,
+	"tolower":   fnToLower,
+	"trim":      fnTrim,
+	"substring": fnSubstring,
+	"split":     fnSplit,
+	"type":      fnType,
+	"tointeger": fnToInteger,
+	"tofloat":   fnToFloat,
+	"tostring":  fnToString,
+	"head":      fnHead,
+	"tail":      fnTail,
+	"last":      fnLast,
+
+	"path.length":        fnPathLength,
+	"path.nodes":         fnPathNodes,
+	"path.relationships": fnPathRelationships,
+	"path.start":         fnPathStart,
+	"path.end":           fnPathEnd,
+}
+
+// GraphFunction is a function that looks up entities in the graph, e.g.
+// startNode(r). Arguments are already evaluated.
+type GraphFunction func(g GraphStorage, args []interface{}) (interface{}, error)
+
+// graphFunctions maps lowercased function names to functions that need
+// the graph
+var graphFunctions = map[string]GraphFunction{
+	"startnode": fnStartNode,
+	"endnode":   fnEndNode,
+	"outdegree": fnOutDegree,
+	"indegree":  fnInDegree,
+	"degree":    fnDegree,
+}
+
+// LazyFunction is a function that evaluates its own arguments, so that it
+// can skip those it does not need. eval evaluates argument i of n.
+type LazyFunction func(n int, eval func(i int) (interface{}, error)) (interface{}, error)
+
+// lazyFunctions maps lowercased function names to functions that evaluate
+// their own arguments
+var lazyFunctions = map[string]LazyFunction{
+	"coalesce": fnCoalesce,
+}
+
+// callFunction evaluates the arguments of call and invokes the function
+func callFunction(call *FunctionCall, match BindingTable, g GraphStorage) (interface{}, error) {
+	// size((n)-[:TYPE]->()) counts matches without materializing them
+	if call.Name == "size" && len(call.Args) == 1 {
+		if pe, ok := call.Args[0].(*PatternExpression); ok {
+			return countPattern(pe, match, g)
+		}
+	}
+	if call.Name == "shortestpath" {
+		if len(call.Args) != 1 {
+			return nil, fmt.Errorf("shortestPath expects 1 argument, got %d", len(call.Args))
+		}
+		pe, ok := call.Args[0].(*PatternExpression)
+		if !ok {
+			return nil, fmt.Errorf("shortestPath expects a pattern argument")
+		}
+		return shortestPath(pe, match, g)
+	}
+	if call.Name == "exists" {
+		if len(call.Args) != 1 {
+			return nil, fmt.Errorf("exists expects 1 argument, got %d", len(call.Args))
+		}
+		switch arg := call.Args[0].(type) {
+		case *PatternExpression:
+			return existsPattern(arg, match, g)
+		case *PropertyAccess:
+			v, err := evaluateExpression(arg, match, g)
+			if err != nil {
+				return nil, err
+			}
+			return v != nil, nil
+		}
+		return nil, fmt.Errorf("exists expects a property or pattern argument")
+	}
+
+	if fn, ok := lazyFunctions[call.Name]; ok {
+		return fn(len(call.Args), func(i int) (interface{}, error) {
+			return evaluateExpression(call.Args[i], match, g)
+		})
+	}
+	fn, ok := functions[call.Name]
+	graphFn, needsGraph := graphFunctions[call.Name]
+	if _, aggregate := aggregateFunctions[call.Name]; aggregate {
+		return nil, fmt.Errorf("aggregate function %s is only allowed in RETURN and WITH", call.Name)
+	}
+	if !ok && !needsGraph {
+		return nil, fmt.Errorf("unknown function: %s", call.Name)
+	}
+
+	args := make([]interface{}, len(call.Args))
+	for i, argExpr := range call.Args {
+		arg, err := evaluateExpression(argExpr, match, g)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = arg
+	}
+	if needsGraph {
+		if g == nil {
+			return nil, fmt.Errorf("%s requires a graph", call.Name)
+		}
+		return graphFn(g, args)
+	}
+	return fn(args)
+}
+
+// fnCoalesce returns its first non-null argument, or null when all are.
+// Arguments after it are not evaluated.
+func fnCoalesce(n int, eval func(i int) (interface{}, error)) (interface{}, error) {
+	if n == 0 {
+		return nil, fmt.Errorf("coalesce expects at least 1 argument, got 0")
+	}
+	for i := 0; i < n; i++ {
+		v, err := eval(i)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			return v, nil
+		}
+	}
+	return nil, nil
+}
+
+// fnSize returns the length of a list or string. size() of a pattern
+// expression is handled by callFunction.
+func fnSize(args []interface{}) (interface{}, error) {
+	if err := checkArgCount("size", args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case []graph.PropertyValue:
+		return len(v), nil
+	case string:
+		return len([]rune(v)), nil
+	}
+	return nil, argTypeError("size", 0, "a list, string or pattern", args[0])
+}
+
+// fnOutDegree returns the number of outgoing edges of a node
+func fnOutDegree(g GraphStorage, args []interface{}) (interface{}, error) {
+	return nodeDegree("outDegree", g, args, true, false)
+}
+
+// fnInDegree returns the number of incoming edges of a node
+func fnInDegree(g GraphStorage, args []interface{}) (interface{}, error) {
+	return nodeDegree("inDegree", g, args, false, true)
+}
+
+// fnDegree returns the number of edges of a node in either direction. A
+// self-loop counts twice.
+func fnDegree(g GraphStorage, args []interface{}) (interface{}, error) {
+	return nodeDegree("degree", g, args, true, true)
+}
+
+// nodeDegree counts the edges in a node's adjacency lists. Like
+// size((n)-->()), it leaves out edges that have expired or lead to a node
+// that has.
+func nodeDegree(name string, g GraphStorage, args []interface{}, out, in bool) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("%s expects 1 argument, got %d", name, len(args))
+	}
+	if args[0] == nil {
+		return nil, nil
+	}
+	node, ok := args[0].(*graph.Node)
+	if !ok {
+		return nil, fmt.Errorf("%s expects a node argument", name)
+	}
+
+	node.Mu.RLock()
+	var outEdges, inEdges []graph.EdgeID
+	if out {
+		outEdges = append(outEdges, node.OutEdges...)
+	}
+	if in {
+		inEdges = append(inEdges, node.InEdges...)
+	}
+	node.Mu.RUnlock()
+
+	if _, ok := g.(expiryChecker); !ok {
+		return len(outEdges) + len(inEdges), nil
+	}
+	degree := 0
+	count := func(edgeIDs []graph.EdgeID, outgoing bool) {
+		for _, id := range edgeIDs {
+			edge, err := g.GetEdge(id)
+			if err != nil || edgeExpired(g, edge) {
+				continue
+			}
+			source, target := edge.Endpoints()
+			far := target
+			if !outgoing {
+				far = source
+			}
+			if other, err := g.GetNode(far); err != nil || nodeExpired(g, other) {
+				continue
+			}
+			degree++
+		}
+	}
+	count(outEdges, true)
+	count(inEdges, false)
+	return degree, nil
+}
+
+// countPattern counts the neighbors of the pattern's bound start node that
+// are reachable over one matching edge. Only single-hop patterns whose
+// first node is bound are supported; the far node may carry a label,
+// properties or a bound variable.
+func countPattern(pe *PatternExpression, match BindingTable, g GraphStorage) (interface{}, error) {
+	return countPatternUpTo(pe, match, g, 0)
+}
+
+// countPatternUpTo is countPattern that stops counting at limit, unless
+// limit is 0
+func countPatternUpTo(pe *PatternExpression, match BindingTable, g GraphStorage, limit int) (interface{}, error) {
+	pattern := pe.Pattern
+	if len(pattern.Edges) != 1 {
+		return nil, fmt.Errorf("pattern expressions must have exactly one relationship")
+	}
+	edgePattern := pattern.Edges[0]
+	if edgePattern.MinHops != nil || edgePattern.MaxHops != nil {
+		return nil, fmt.Errorf("variable-length pattern expressions are not supported")
+	}
+	if edgePattern.Variable != "" {
+		return nil, fmt.Errorf("pattern expressions cannot introduce variable %s", edgePattern.Variable)
+	}
+
+	start := pattern.Nodes[0]
+	if start.Variable == "" || start.Label != "" || len(start.Properties) > 0 {
+		return nil, fmt.Errorf("pattern expressions must start from a bound variable")
+	}
+	obj, ok := match[start.Variable]
+	if !ok {
+		return nil, unboundVariableError(start.Variable)
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	node, ok := obj.(*graph.Node)
+	if !ok {
+		return nil, fmt.Errorf("variable %s is not a node", start.Variable)
+	}
+	if g == nil {
+		return nil, fmt.Errorf("pattern expressions require a graph")
+	}
+
+	far := pattern.Nodes[1]
+	var farNode *graph.Node
+	if far.Variable != "" {
+		bound, ok := match[far.Variable].(*graph.Node)
+		if !ok {
+			return nil, fmt.Errorf("pattern expressions cannot introduce variable %s", far.Variable)
+		}
+		farNode = bound
+	}
+
+	expand := &ExpandOperator{Direction: edgePattern.Direction, EdgeType: edgePattern.Type}
+	count := 0
+	expand.eachStep(g, node, func(step expandStep) bool {
+		if farNode != nil && step.node.ID != farNode.ID {
+			return true
+		}
+		if far.Label != "" && step.node.Label != far.Label {
+			return true
+		}
+		if !propertiesMatch(step.node.GetProperty, far.Properties) ||
+			!propertiesMatch(step.edge.GetProperty, edgePattern.Properties) {
+			return true
+		}
+		count++
+		return count != limit
+	})
+	return count, nil
+}
+
+// edgeFinder is implemented by storage backends that look up the edges
+// between two nodes directly
+type edgeFinder interface {
+	GetEdgesBetween(src, target graph.NodeID) ([]*graph.Edge, error)
+	GetEdgesBetweenByLabel(src, target graph.NodeID, label string) ([]*graph.Edge, error)
+}
+
+// existsPattern reports whether a pattern expression has any match. When
+// both of its nodes are bound and carry no constraints of their own, the
+// edges between them are looked up directly; other patterns are counted
+// as by countPattern.
+func existsPattern(pe *PatternExpression, match BindingTable, g GraphStorage) (interface{}, error) {
+	pattern := pe.Pattern
+	finder, ok := g.(edgeFinder)
+	if !ok || len(pattern.Edges) != 1 {
+		return countExists(pe, match, g)
+	}
+	edgePattern := pattern.Edges[0]
+	start, end := pattern.Nodes[0], pattern.Nodes[1]
+	if edgePattern.Variable != "" || edgePattern.MinHops != nil || edgePattern.MaxHops != nil ||
+		!plainNodePattern(start) || !plainNodePattern(end) {
+		return countExists(pe, match, g)
+	}
+	src, srcOK := match[start.Variable].(*graph.Node)
+	target, targetOK := match[end.Variable].(*graph.Node)
+	if !srcOK || !targetOK {
+		return countExists(pe, match, g)
+	}
+
+	type hop struct{ from, to graph.NodeID }
+	var hops []hop
+	if edgePattern.Direction != DirectionIn {
+		hops = append(hops, hop{src.ID, target.ID})
+	}
+	if edgePattern.Direction != DirectionOut {
+		hops = append(hops, hop{target.ID, src.ID})
+	}
+	for _, h := range hops {
+		var edges []*graph.Edge
+		var err error
+		if edgePattern.Type != "" {
+			edges, err = finder.GetEdgesBetweenByLabel(h.from, h.to, edgePattern.Type)
+		} else {
+			edges, err = finder.GetEdgesBetween(h.from, h.to)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, edge := range edges {
+			// GetEdgesBetween returns expired edges, which MATCH hides
+			if edgeExpired(g, edge) {
+				continue
+			}
+			if propertiesMatch(edge.GetProperty, edgePattern.Properties) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// countExists reports whether countPattern finds any match, stopping at
+// the first
+func countExists(pe *PatternExpression, match BindingTable, g GraphStorage) (interface{}, error) {
+	count, err := countPatternUpTo(pe, match, g, 1)
+	if count == nil || err != nil {
+		return nil, err
+	}
+	return count.(int) > 0, nil
+}
+
+// plainNodePattern reports whether a node pattern is a variable alone
+func plainNodePattern(np NodePattern) bool {
+	return np.Variable != "" && np.Label == "" && len(np.Properties) == 0
+}
+
+// propertiesMatch reports whether every inline pattern property equals the
+// value returned by get
+func propertiesMatch(get func(string) (graph.PropertyValue, bool), want map[string]interface{}) bool {
+	for k, v := range want {
+		actual, ok := get(k)
+		if !ok || !valuesEqual(actual, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// fnDistance returns the great-circle distance between two points in meters
+func fnDistance(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("distance expects 2 arguments, got %d", len(args))
+	}
+	if args[0] == nil || args[1] == nil {
+		return nil, nil
+	}
+
+	a, ok1 := args[0].(graph.Point)
+	b, ok2 := args[1].(graph.Point)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("distance expects point arguments")
+	}
+	return a.DistanceMeters(b), nil
+}
+
+// fnDatetime parses an ISO-8601 string into a time, or returns the current
+// time when called without arguments
+func fnDatetime(args []interface{}) (interface{}, error) {
+	switch len(args) {
+	case 0:
+		return time.Now().UTC(), nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("datetime expects at most 1 argument, got %d", len(args))
+	}
+
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		return v, nil
+	case string:
+		return parseTimestamp(v)
+	}
+	return nil, fmt.Errorf("datetime expects a string argument")
+}
+
+// fnCreated returns the creation time of a node or edge
+func fnCreated(args []interface{}) (interface{}, error) {
+	created, _, err := entityTimes("created", args)
+	return created, err
+}
+
+// fnUpdated returns the last modification time of a node or edge
+func fnUpdated(args []interface{}) (interface{}, error) {
+	_, updated, err := entityTimes("updated", args)
+	return updated, err
+}
+
+func entityTimes(name string, args []interface{}) (interface{}, interface{}, error) {
+	if len(args) != 1 {
+		return nil, nil, fmt.Errorf("%s expects 1 argument, got %d", name, len(args))
+	}
+
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil, nil
+	case *graph.Node:
+		v.Mu.RLock()
+		defer v.Mu.RUnlock()
+		return v.CreatedAt, v.UpdatedAt, nil
+	case *graph.Edge:
+		v.Mu.RLock()
+		defer v.Mu.RUnlock()
+		return v.CreatedAt, v.UpdatedAt, nil
+	}
+	return nil, nil, fmt.Errorf("%s expects a node or edge", name)
+}
+
+// fnType returns the type of a relationship
+func fnType(args []interface{}) (interface{}, error) {
+	if err := checkArgCount("type", args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case *graph.Edge:
+		return v.Label, nil
+	}
+	return nil, argTypeError("type", 0, "a relationship", args[0])
+}
+
+// fnStartNode returns the source node of a relationship
+func fnStartNode(g GraphStorage, args []interface{}) (interface{}, error) {
+	return edgeEndpoint("startNode", g, args, true)
+}
+
+// fnEndNode returns the target node of a relationship
+func fnEndNode(g GraphStorage, args []interface{}) (interface{}, error) {
+	return edgeEndpoint("endNode", g, args, false)
+}
+
+// edgeEndpoint looks up the source or target node of an edge argument
+func edgeEndpoint(name string, g GraphStorage, args []interface{}, source bool) (interface{}, error) {
+	if err := checkArgCount(name, args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case *graph.Edge:
+		id, target := v.Endpoints()
+		if !source {
+			id = target
+		}
+		node, err := g.GetNode(id)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return node, nil
+	}
+	return nil, argTypeError(name, 0, "a relationship", args[0])
+}
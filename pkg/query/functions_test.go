@@ -0,0 +1,416 @@
+package query
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createCityGraph(t *testing.T) *storage.Graph {
+	g := storage.NewGraph()
+	g.AddNode("City", graph.Properties{"name": "New York", "location": graph.Point{Lat: 40.7128, Lon: -74.0060}})
+	g.AddNode("City", graph.Properties{"name": "Newark", "location": graph.Point{Lat: 40.7357, Lon: -74.1724}})
+	g.AddNode("City", graph.Properties{"name": "Boston", "location": graph.Point{Lat: 42.3601, Lon: -71.0589}})
+	return g
+}
+
+func TestParser_PointLiteral(t *testing.T) {
+	query, err := NewParser(`MATCH (c:City) RETURN distance(c.location, point({lat: 40.7, lon: -74.0}))`).Parse()
+	require.NoError(t, err)
+
+	call, ok := query.Return.Items[0].Expr.(*FunctionCall)
+	require.True(t, ok)
+	assert.Equal(t, "distance", call.Name)
+	require.Len(t, call.Args, 2)
+	assert.Equal(t, &PropertyAccess{Variable: "c", Property: "location"}, call.Args[0])
+	assert.Equal(t, &PointLiteral{Lat: 40.7, Lon: -74.0}, call.Args[1])
+
+	_, err = NewParser(`MATCH (c) RETURN point({lat: 1})`).Parse()
+	assert.Error(t, err)
+
+	query, err = NewParser(`MATCH (c) RETURN point({latitude: 37.7, longitude: -122.4})`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, &PointLiteral{Lat: 37.7, Lon: -122.4}, query.Return.Items[0].Expr)
+
+	_, err = NewParser(`MATCH (c) RETURN point({lat: 1, latitude: 1, lon: 2})`).Parse()
+	assert.Error(t, err)
+}
+
+func TestExecute_Distance(t *testing.T) {
+	g := createCityGraph(t)
+
+	q, err := NewParser(`MATCH (c:City) WHERE distance(c.location, point({lat: 40.7128, lon: -74.006})) < 50000 RETURN c.name, distance(c.location, point({lat: 40.7128, lon: -74.006}))`).Parse()
+	require.NoError(t, err)
+
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 2)
+
+	col := result.Columns[1]
+	assert.Equal(t, "distance(c.location, point({lat: 40.7128, lon: -74.006}))", col)
+	for _, row := range result.Rows {
+		d, ok := row[col].(float64)
+		require.True(t, ok)
+		switch row["c.name"] {
+		case "New York":
+			assert.InDelta(t, 0, d, 0.001)
+		case "Newark":
+			assert.InDelta(t, 14000, d, 1000)
+		default:
+			t.Errorf("unexpected city %v", row["c.name"])
+		}
+	}
+}
+
+func TestExecute_DistanceParameter(t *testing.T) {
+	g := createCityGraph(t)
+
+	q, err := NewParser(`MATCH (c:City) WHERE distance(c.location, $here) < 5000 RETURN c.name, $here`).Parse()
+	require.NoError(t, err)
+	q.Parameters = map[string]interface{}{"here": graph.Point{Lat: 40.72, Lon: -74.0}}
+
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c.name", "$here"}, result.Columns)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "New York", result.Rows[0]["c.name"])
+	assert.Equal(t, graph.Point{Lat: 40.72, Lon: -74.0}, result.Rows[0]["$here"])
+
+	q.Parameters = nil
+	_, err = q.Execute(g)
+	assert.ErrorContains(t, err, "$here")
+}
+
+func TestExecute_UnknownFunction(t *testing.T) {
+	g := createCityGraph(t)
+
+	q, err := NewParser(`MATCH (c:City) RETURN nosuch(c.name)`).Parse()
+	require.NoError(t, err)
+
+	_, err = q.Execute(g)
+	assert.Error(t, err)
+}
+
+func TestParser_SizePattern(t *testing.T) {
+	query, err := NewParser(`MATCH (p:Person) RETURN p.name, size((p)-[:KNOWS]->()) AS friends`).Parse()
+	require.NoError(t, err)
+
+	item := query.Return.Items[1]
+	assert.Equal(t, "friends", item.Alias)
+	call, ok := item.Expr.(*FunctionCall)
+	require.True(t, ok)
+	assert.Equal(t, "size", call.Name)
+	pe, ok := call.Args[0].(*PatternExpression)
+	require.True(t, ok)
+	assert.Equal(t, "(p)-[:KNOWS]->()", expressionText(pe))
+
+	_, err = NewParser(`MATCH (p) RETURN size((p))`).Parse()
+	assert.Error(t, err)
+	_, err = NewParser(`MATCH (p) RETURN p.name AS`).Parse()
+	assert.Error(t, err)
+}
+
+func TestExecute_SizePattern(t *testing.T) {
+	g := createTestGraph(t)
+	alice, _ := g.GetNode(1)
+	bob, _ := g.GetNode(2)
+	g.AddEdge(alice.ID, 3, "KNOWS", nil)
+	g.AddEdge(bob.ID, alice.ID, "KNOWS", nil)
+
+	run := func(input string) map[interface{}]interface{} {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+
+		counts := make(map[interface{}]interface{})
+		for _, row := range result.Rows {
+			counts[row["p.name"]] = row[result.Columns[1]]
+		}
+		return counts
+	}
+
+	// One row per person, whatever their degree
+	counts := run(`MATCH (p:Person) RETURN p.name, size((p)-[:KNOWS]->()) AS friends`)
+	require.Len(t, counts, 3)
+	g.IterateNodesByLabel("Person", func(n *graph.Node) bool {
+		knows := 0
+		for _, id := range n.OutEdges {
+			if e, _ := g.GetEdge(id); e.Label == "KNOWS" {
+				knows++
+			}
+		}
+		name, _ := n.GetProperty("name")
+		assert.Equal(t, knows, counts[name], "out-degree of %s", name)
+		return true
+	})
+
+	counts = run(`MATCH (p:Person) RETURN p.name, size((p)<-[:KNOWS]-())`)
+	assert.Equal(t, map[interface{}]interface{}{"Alice": 1, "Bob": 1, "Charlie": 2}, counts)
+
+	counts = run(`MATCH (p:Person) RETURN p.name, size((p)-[]->(:Company))`)
+	assert.Equal(t, map[interface{}]interface{}{"Alice": 1, "Bob": 0, "Charlie": 0}, counts)
+
+	q, err := NewParser(`MATCH (p:Person) WHERE size((p)-[:KNOWS]->()) = 0 RETURN p.name`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Charlie", result.Rows[0]["p.name"])
+}
+
+func TestExecute_Degree(t *testing.T) {
+	g := createTestGraph(t)
+	alice, _ := g.GetNode(1)
+	bob, _ := g.GetNode(2)
+	g.AddEdge(bob.ID, alice.ID, "KNOWS", nil)
+	g.AddEdge(alice.ID, alice.ID, "LIKES", nil)
+
+	q, err := NewParser(`MATCH (n) RETURN n.name, outDegree(n) AS out, inDegree(n) AS incoming, degree(n) AS total`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+
+	degrees := make(map[interface{}][3]interface{})
+	for _, row := range result.Rows {
+		degrees[row["n.name"]] = [3]interface{}{row["out"], row["incoming"], row["total"]}
+	}
+	assert.Equal(t, map[interface{}][3]interface{}{
+		"Alice":   {3, 2, 5}, // KNOWS Bob, WORKS_AT Google, self-loop; from Bob, self-loop
+		"Bob":     {2, 1, 3},
+		"Charlie": {0, 1, 1},
+		"Google":  {0, 1, 1},
+	}, degrees)
+
+	q, err = NewParser(`MATCH (p:Person) WHERE degree(p) > 2 RETURN p.name ORDER BY p.name`).Parse()
+	require.NoError(t, err)
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, "Alice", result.Rows[0]["p.name"])
+	assert.Equal(t, "Bob", result.Rows[1]["p.name"])
+
+	q, err = NewParser(`MATCH (p:Person) RETURN degree(p.name)`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.Error(t, err)
+}
+
+func TestExecute_DegreeSkipsExpiredEdges(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := storage.NewGraph()
+	g.SetClock(func() time.Time { return now })
+	a, _ := g.AddNode("Person", graph.Properties{"name": "a"})
+	b, _ := g.AddNode("Person", graph.Properties{"name": "b"})
+	c, _ := g.AddNode("Person", graph.Properties{"name": "c"})
+	g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	expired, _ := g.AddEdge(a.ID, c.ID, "KNOWS", nil)
+	g.AddEdge(c.ID, a.ID, "KNOWS", nil)
+	require.NoError(t, g.SetEdgeExpiry(expired.ID, now.Add(-time.Second)))
+
+	q, err := NewParser(`MATCH (n {name: "a"}) RETURN outDegree(n) AS out, size((n)-->()) AS size, degree(n) AS total`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"out": 1, "size": 1, "total": 2}}, result.Rows)
+
+	// An edge to an expired node is left out, as size() leaves it out
+	require.NoError(t, g.SetExpiry(b.ID, now.Add(-time.Second)))
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"out": 0, "size": 0, "total": 1}}, result.Rows)
+}
+
+func TestCoalesce(t *testing.T) {
+	values := func(vs ...interface{}) func(int) (interface{}, error) {
+		return func(i int) (interface{}, error) {
+			if vs[i] == "fail" {
+				return nil, fmt.Errorf("evaluated argument %d", i+1)
+			}
+			return vs[i], nil
+		}
+	}
+
+	v, err := fnCoalesce(3, values(nil, 42, "fail"))
+	require.NoError(t, err)
+	assert.Equal(t, 42, v, "arguments after the first non-null are not evaluated")
+
+	v, err = fnCoalesce(2, values(nil, nil))
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	_, err = fnCoalesce(2, values(nil, "fail"))
+	assert.EqualError(t, err, "evaluated argument 2")
+
+	_, err = fnCoalesce(0, values())
+	assert.EqualError(t, err, "coalesce expects at least 1 argument, got 0")
+}
+
+func TestExecute_Coalesce(t *testing.T) {
+	g := storage.NewGraph()
+	g.AddNode("Person", graph.Properties{"name": "Alice", "nickname": "Al"})
+	g.AddNode("Person", graph.Properties{"name": "Bob", "age": 30})
+	g.AddNode("Person", nil)
+
+	run := func(input string) *Result {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		result, err := q.Execute(g)
+		require.NoError(t, err, input)
+		return result
+	}
+
+	result := run(`MATCH (p:Person) RETURN coalesce(p.nickname, p.name, "unknown") AS name ORDER BY name`)
+	assert.Equal(t, []Row{{"name": "Al"}, {"name": "Bob"}, {"name": "unknown"}}, result.Rows)
+
+	// Mixed types: the first non-null wins whatever its type
+	result = run(`MATCH (p:Person) WHERE p.name = "Bob" RETURN coalesce(p.nickname, p.age, p.name) AS v`)
+	assert.Equal(t, []Row{{"v": 30}}, result.Rows)
+
+	// All null
+	result = run(`MATCH (p:Person) WHERE p.name = "Alice" RETURN coalesce(p.age, p.email) AS v`)
+	assert.Equal(t, []Row{{"v": nil}}, result.Rows)
+
+	result = run(`MATCH (p:Person) WHERE coalesce(p.nickname, p.name) = "Bob" RETURN p.age`)
+	assert.Equal(t, []Row{{"p.age": 30}}, result.Rows)
+
+	// The unbound variable is only evaluated where the nickname is missing
+	result = run(`MATCH (p:Person) WHERE p.name = "Alice" RETURN coalesce(p.nickname, nosuch.name) AS v`)
+	assert.Equal(t, []Row{{"v": "Al"}}, result.Rows)
+
+	q, err := NewParser(`MATCH (p:Person) WHERE p.name = "Bob" RETURN coalesce(p.nickname, nosuch.name)`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.Error(t, err)
+}
+
+func TestExecute_Exists(t *testing.T) {
+	g := storage.NewGraph()
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	carol, _ := g.AddNode("Person", graph.Properties{"name": "Carol"})
+	g.AddEdge(alice.ID, bob.ID, "LIKES", nil)
+	g.AddEdge(bob.ID, alice.ID, "LIKES", nil)
+	g.AddEdge(carol.ID, alice.ID, "LIKES", nil)
+	g.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+
+	pairs := func(where string) []string {
+		input := `MATCH (a)-[:LIKES]->(b) WHERE ` + where + ` RETURN a.name, b.name ORDER BY a.name`
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		result, err := q.Execute(g)
+		require.NoError(t, err, input)
+		var out []string
+		for _, row := range result.Rows {
+			out = append(out, fmt.Sprintf("%v-%v", row["a.name"], row["b.name"]))
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"Alice-Bob"}, pairs("exists((a)-[:KNOWS]->(b))"))
+	assert.Equal(t, []string{"Bob-Alice"}, pairs("exists((a)<-[:KNOWS]-(b))"))
+	assert.Equal(t, []string{"Alice-Bob", "Bob-Alice"}, pairs("exists((a)-[:KNOWS]-(b))"))
+	assert.Equal(t, []string{"Alice-Bob", "Bob-Alice"}, pairs("exists((a)<-[]-(b))"))
+	assert.Equal(t, []string{"Alice-Bob"}, pairs("exists((a)-[:KNOWS {since: 2020}]->(b))"))
+	assert.Empty(t, pairs("exists((a)-[:KNOWS {since: 2021}]->(b))"))
+	assert.Equal(t, []string{"Carol-Alice"}, pairs("NOT exists((a)<-[:LIKES]-(b))"))
+
+	// Patterns with an unbound end are counted instead
+	q, err := NewParser(`MATCH (a:Person) RETURN a.name, exists((a)-[:KNOWS]->(:Person)) AS knows ORDER BY a.name`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{
+		{"a.name": "Alice", "knows": true},
+		{"a.name": "Bob", "knows": false},
+		{"a.name": "Carol", "knows": false},
+	}, result.Rows)
+
+	q, err = NewParser(`MATCH (a:Person) RETURN exists("name")`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.EqualError(t, err, "exists expects a property or pattern argument")
+}
+
+func TestExecute_ExistsSkipsExpiredEdges(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := storage.NewGraph()
+	g.SetClock(func() time.Time { return now })
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	g.AddEdge(alice.ID, bob.ID, "LIKES", nil)
+	knows, _ := g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	require.NoError(t, g.SetEdgeExpiry(knows.ID, now.Add(time.Minute)))
+
+	q, err := NewParser(`MATCH (a)-[:LIKES]->(b) RETURN exists((a)-[:KNOWS]->(b)) AS typed, exists((a)-[]-(b)) AS any, exists((a)-[:KNOWS]-(b)) AS both`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"typed": true, "any": true, "both": true}}, result.Rows)
+
+	// Once expired, the edge no longer counts, as MATCH no longer finds it
+	now = now.Add(2 * time.Minute)
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{{"typed": false, "any": true, "both": false}}, result.Rows)
+	assert.Empty(t, run(t, g, `MATCH (a)-[:KNOWS]->(b) RETURN a.name`).Rows)
+}
+
+func TestExecute_ExistsFilters(t *testing.T) {
+	g := storage.NewGraph()
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice", "email": "alice@example.com"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob", "email": nil})
+	g.AddNode("Person", graph.Properties{"name": "Carol"})
+	acme, _ := g.AddNode("Company", graph.Properties{"name": "Acme"})
+	initech, _ := g.AddNode("Company", graph.Properties{"name": "Initech"})
+	g.AddEdge(alice.ID, acme.ID, "WORKS_AT", nil)
+	g.AddEdge(alice.ID, initech.ID, "WORKS_AT", nil)
+	g.AddEdge(bob.ID, acme.ID, "WORKS_AT", nil)
+
+	names := func(input string) []interface{} {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		result, err := q.Execute(g)
+		require.NoError(t, err, input)
+		var out []interface{}
+		for _, row := range result.Rows {
+			out = append(out, row["p.name"])
+		}
+		return out
+	}
+
+	// A property set to null does not exist
+	assert.Equal(t, []interface{}{"Alice"}, names(`MATCH (p:Person) WHERE exists(p.email) RETURN p.name`))
+	assert.Equal(t, []interface{}{"Bob", "Carol"}, names(`MATCH (p:Person) WHERE NOT exists(p.email) RETURN p.name ORDER BY p.name`))
+
+	// One row per person however many jobs they have
+	assert.Equal(t, []interface{}{"Alice", "Bob"}, names(`MATCH (p:Person) WHERE exists((p)-[:WORKS_AT]->()) RETURN p.name ORDER BY p.name`))
+	assert.Equal(t, []interface{}{"Alice"}, names(`MATCH (p:Person) WHERE exists((p)-[:WORKS_AT]->(:Company {name: "Initech"})) RETURN p.name`))
+	assert.Equal(t, []interface{}{"Carol"}, names(`MATCH (p:Person) WHERE NOT exists((p)-[:WORKS_AT]->()) RETURN p.name`))
+}
+
+func TestExecute_RelationshipFunctions(t *testing.T) {
+	g := createTestGraph(t)
+
+	result := run(t, g, `MATCH (a:Person {name: "Alice"})-[r]->(b)
+		WITH type(r) AS type, startNode(r) AS src, endNode(r) AS dst
+		RETURN type, src.name AS src, dst.name AS dst ORDER BY type`)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, Row{"type": "KNOWS", "src": "Alice", "dst": "Bob"}, result.Rows[0])
+	assert.Equal(t, Row{"type": "WORKS_AT", "src": "Alice", "dst": "Google"}, result.Rows[1])
+
+	// Endpoints do not follow the direction the pattern traversed
+	result = run(t, g, `MATCH (b:Person {name: "Bob"})<-[r]-(a) RETURN startNode(r) = a AS forward`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, true, result.Rows[0]["forward"])
+
+	q, err := NewParser(`MATCH (a:Person) RETURN type(a)`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.ErrorContains(t, err, "type argument 1 must be a relationship, got node")
+}
@@ -0,0 +1,106 @@
+package query
+
+import (
+	"encoding/json"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// jsonNode is the JSON form of a node in a result
+type jsonNode struct {
+	ID         graph.NodeID           `json:"id"`
+	Label      string                 `json:"label"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// jsonRelationship is the JSON form of an edge in a result
+type jsonRelationship struct {
+	ID         graph.EdgeID           `json:"id"`
+	Type       string                 `json:"type"`
+	Source     graph.NodeID           `json:"source"`
+	Target     graph.NodeID           `json:"target"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// jsonPath is the JSON form of a path in a result
+type jsonPath struct {
+	Nodes         []jsonNode         `json:"nodes"`
+	Relationships []jsonRelationship `json:"relationships"`
+}
+
+// MarshalJSON writes the row for clients. Nodes are written as
+// {id, label, properties}, relationships as {id, type, source, target,
+// properties} and paths as {nodes, relationships}; property values are
+// plain JSON, as other values in the row are.
+func (r Row) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(r))
+	for k, v := range r {
+		out[k] = jsonValue(v)
+	}
+	return json.Marshal(out)
+}
+
+// jsonValue converts a result value to the form MarshalJSON writes
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *graph.Node:
+		return nodeJSON(v)
+	case *graph.Edge:
+		return relationshipJSON(v)
+	case *Path:
+		path := jsonPath{
+			Nodes:         make([]jsonNode, len(v.Nodes)),
+			Relationships: make([]jsonRelationship, len(v.Edges)),
+		}
+		for i, node := range v.Nodes {
+			path.Nodes[i] = nodeJSON(node)
+		}
+		for i, edge := range v.Edges {
+			path.Relationships[i] = relationshipJSON(edge)
+		}
+		return path
+	case []*graph.Edge:
+		list := make([]interface{}, len(v))
+		for i, edge := range v {
+			list[i] = relationshipJSON(edge)
+		}
+		return list
+	case []graph.PropertyValue:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = jsonValue(item)
+		}
+		return list
+	case graph.Properties:
+		return propertiesJSON(v)
+	}
+	return v
+}
+
+func nodeJSON(node *graph.Node) jsonNode {
+	node.Mu.RLock()
+	defer node.Mu.RUnlock()
+	return jsonNode{ID: node.ID, Label: node.Label, Properties: propertiesJSON(node.Properties.Map())}
+}
+
+func relationshipJSON(edge *graph.Edge) jsonRelationship {
+	edge.Mu.RLock()
+	defer edge.Mu.RUnlock()
+	return jsonRelationship{
+		ID:         edge.ID,
+		Type:       edge.Label,
+		Source:     edge.Source,
+		Target:     edge.Target,
+		Properties: propertiesJSON(edge.Properties.Map()),
+	}
+}
+
+// propertiesJSON converts property values for MarshalJSON, writing an
+// empty object rather than null for no properties
+func propertiesJSON(props graph.Properties) map[string]interface{} {
+	out := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		out[k] = jsonValue(v)
+	}
+	return out
+}
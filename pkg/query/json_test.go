@@ -0,0 +1,54 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRow_MarshalJSON(t *testing.T) {
+	g := storage.NewGraph()
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", nil)
+	_, err := g.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020, "tags": []graph.PropertyValue{"work"}})
+	require.NoError(t, err)
+
+	result := run(t, g, `MATCH p = (a:Person {name: "Alice"})-[r:KNOWS]->(b) RETURN a, r, r.since AS since, p`)
+	require.Len(t, result.Rows, 1)
+	data, err := json.Marshal(result.Rows[0])
+	require.NoError(t, err)
+
+	relationship := `{"id":1,"properties":{"since":2020,"tags":["work"]},"source":1,"target":2,"type":"KNOWS"}`
+	nodeA := `{"id":1,"label":"Person","properties":{"name":"Alice"}}`
+	nodeB := `{"id":2,"label":"Person","properties":{}}`
+	assert.JSONEq(t, `{
+		"a": `+nodeA+`,
+		"r": `+relationship+`,
+		"since": 2020,
+		"p": {"nodes": [`+nodeA+`, `+nodeB+`], "relationships": [`+relationship+`]}
+	}`, string(data))
+
+	// The output is the same every time
+	again, err := json.Marshal(result.Rows[0])
+	require.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+}
+
+func TestRow_MarshalJSONVariableLength(t *testing.T) {
+	g := createTestGraph(t)
+
+	result := run(t, g, `MATCH (a:Person {name: "Alice"})-[r:KNOWS*2]->(c) RETURN r`)
+	require.Len(t, result.Rows, 1)
+	data, err := json.Marshal(result.Rows[0])
+	require.NoError(t, err)
+
+	var row map[string][]map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &row))
+	require.Len(t, row["r"], 2)
+	assert.Equal(t, "KNOWS", row["r"][0]["type"])
+	assert.Equal(t, row["r"][0]["target"], row["r"][1]["source"])
+}
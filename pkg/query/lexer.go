@@ -3,6 +3,7 @@ package query
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -22,13 +23,29 @@ const (
 	TokenOrderBy
 	TokenAnd
 	TokenOr
+	TokenNot
+	TokenCall
+	TokenShow
+	TokenCreate
+	TokenWith
+	TokenUnion
+	TokenUsing
+	TokenIndex
+	TokenContains
+	TokenIn
+	TokenAsOf       // AS OF
+	TokenTimestamp  // TIMESTAMP, only after AS OF
+	TokenStartsWith // STARTS WITH
+	TokenEndsWith   // ENDS WITH
 
 	// Identifiers and literals
 	TokenIdentifier // variable names, labels
 	TokenString     // "string literal"
 	TokenNumber     // 123, 45.67
+	TokenParameter  // $name; the literal is the name without the $
 	TokenTrue
 	TokenFalse
+	TokenNull
 
 	// Operators
 	TokenEqual        // =
@@ -37,6 +54,9 @@ const (
 	TokenLessEqual    // <=
 	TokenGreater      // >
 	TokenGreaterEqual // >=
+	TokenPlus         // +
+	TokenSlash        // /
+	TokenPercent      // %
 
 	// Delimiters
 	TokenLeftParen    // (
@@ -55,12 +75,18 @@ const (
 	TokenDotDot       // ..
 )
 
-// Token represents a lexical token
+// Token represents a lexical token. Lines and columns count from 1, and
+// columns count bytes.
 type Token struct {
-	Type    TokenType
-	Literal string
-	Line    int
-	Column  int
+	Type        TokenType
+	Literal     string
+	Line        int // Line of the first character
+	StartColumn int // Column of the first character
+	Column      int // Column of the last character, for tokens on one line
+
+	// Quoted marks an identifier written in backticks, which is never
+	// read as a keyword
+	Quoted bool
 }
 
 // Lexer tokenizes RQL queries
@@ -71,6 +97,9 @@ type Lexer struct {
 	ch           byte // current char
 	line         int
 	column       int
+	lastType     TokenType // type of the previously returned token
+
+	commentStart *Token // Start of an unterminated block comment, once reached
 }
 
 // NewLexer creates a new lexer
@@ -111,12 +140,27 @@ func (l *Lexer) peekChar() byte {
 
 // NextToken returns the next token from the input
 func (l *Lexer) NextToken() Token {
-	var tok Token
+	// The position is taken before the token is read, since reading
+	// leaves the lexer past its end
+	if comment := l.skipWhitespace(); comment != nil {
+		l.lastType = comment.Type
+		return *comment
+	}
+	start, line, column := l.position, l.line, l.column
 
-	l.skipWhitespace()
+	tok := l.nextToken()
+	tok.Line = line
+	tok.StartColumn = column
+	tok.Column = column
+	if end := l.position - 1; end > start && tok.Type != TokenEOF {
+		tok.Column += end - start
+	}
+	l.lastType = tok.Type
+	return tok
+}
 
-	tok.Line = l.line
-	tok.Column = l.column
+func (l *Lexer) nextToken() Token {
+	var tok Token
 
 	switch l.ch {
 	case '(':
@@ -145,6 +189,12 @@ func (l *Lexer) NextToken() Token {
 		tok = l.newToken(TokenColon, string(l.ch))
 	case '*':
 		tok = l.newToken(TokenStar, string(l.ch))
+	case '+':
+		tok = l.newToken(TokenPlus, string(l.ch))
+	case '/':
+		tok = l.newToken(TokenSlash, string(l.ch))
+	case '%':
+		tok = l.newToken(TokenPercent, string(l.ch))
 	case '-':
 		if l.peekChar() == '>' {
 			ch := l.ch
@@ -183,16 +233,33 @@ func (l *Lexer) NextToken() Token {
 		} else {
 			tok = l.newToken(TokenIllegal, string(l.ch))
 		}
+	case '$':
+		if isLetter(l.peekChar()) || l.peekChar() == '_' {
+			tok = l.newToken(TokenParameter, "")
+			l.readChar()
+			tok.Literal = l.readIdentifier()
+			return tok
+		}
+		tok = l.newToken(TokenIllegal, string(l.ch))
 	case '"', '\'':
 		tok.Type = TokenString
 		tok.Literal = l.readString(l.ch)
+	case '`':
+		if name, ok := l.readQuotedIdentifier(); ok {
+			tok = Token{Type: TokenIdentifier, Literal: name, Quoted: true}
+		} else {
+			tok = l.newToken(TokenIllegal, "`"+name)
+		}
 	case 0:
 		tok.Literal = ""
 		tok.Type = TokenEOF
 	default:
-		if isLetter(l.ch) {
+		if isLetter(l.ch) || l.ch == '_' {
 			tok.Literal = l.readIdentifier()
 			tok.Type = lookupKeyword(tok.Literal)
+			if tok.Type == TokenIdentifier {
+				tok = l.contextualKeyword(tok)
+			}
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = TokenNumber
@@ -207,21 +274,137 @@ func (l *Lexer) NextToken() Token {
 	return tok
 }
 
+// contextualKeyword recognizes keywords that stay valid identifiers
+// elsewhere: AS OF (so "as" remains usable), TIMESTAMP after AS OF, and
+// STARTS WITH and ENDS WITH
+func (l *Lexer) contextualKeyword(tok Token) Token {
+	switch strings.ToUpper(tok.Literal) {
+	case "AS":
+		if l.consumeWord("OF") {
+			tok.Type = TokenAsOf
+			tok.Literal = "AS OF"
+		}
+	case "STARTS":
+		if l.consumeWord("WITH") {
+			tok.Type = TokenStartsWith
+			tok.Literal = "STARTS WITH"
+		}
+	case "ENDS":
+		if l.consumeWord("WITH") {
+			tok.Type = TokenEndsWith
+			tok.Literal = "ENDS WITH"
+		}
+	case "TIMESTAMP":
+		if l.lastType == TokenAsOf {
+			tok.Type = TokenTimestamp
+		}
+	}
+	return tok
+}
+
+// consumeWord looks past whitespace for word as a standalone,
+// case-insensitive identifier and consumes it if found
+func (l *Lexer) consumeWord(word string) bool {
+	pos := l.position
+	for pos < len(l.input) && strings.ContainsRune(" \t\r\n", rune(l.input[pos])) {
+		pos++
+	}
+	end := pos + len(word)
+	if end > len(l.input) || !strings.EqualFold(l.input[pos:end], word) ||
+		end < len(l.input) && (isLetter(l.input[end]) || isDigit(l.input[end]) || l.input[end] == '_') {
+		return false
+	}
+	for l.position < end {
+		l.readChar()
+	}
+	return true
+}
+
 func (l *Lexer) newToken(tokenType TokenType, literal string) Token {
-	return Token{
-		Type:    tokenType,
-		Literal: literal,
-		Line:    l.line,
-		Column:  l.column,
+	return Token{Type: tokenType, Literal: literal}
+}
+
+// skipWhitespace skips whitespace and comments: -- and // run to the end
+// of the line, and /* */ blocks may span lines. -- is not a comment where
+// it continues a pattern, as in (a)-->(b) or (a) -- (b); see
+// dashesContinuePattern. A block comment left open is returned as an
+// illegal "/*" token, so that the rest of the query is not silently
+// ignored; see Err.
+func (l *Lexer) skipWhitespace() *Token {
+	for {
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r':
+			l.readChar()
+		case l.ch == '-' && l.peekChar() == '-' && !l.dashesContinuePattern(), l.ch == '/' && l.peekChar() == '/':
+			l.skipLineComment()
+		case l.ch == '/' && l.peekChar() == '*':
+			start := Token{Type: TokenIllegal, Literal: "/*", Line: l.line, StartColumn: l.column, Column: l.column + 1}
+			if !l.skipBlockComment() {
+				l.commentStart = &start
+				return &start
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// dashesContinuePattern reports whether the -- at the current position
+// is part of a relationship rather than a comment: within an arrow, or
+// after a node or relationship when followed by ( or >
+func (l *Lexer) dashesContinuePattern() bool {
+	switch l.lastType {
+	case TokenDash, TokenLeftArrow:
+		return true
+	case TokenRightParen, TokenRightBracket:
+		next := l.position + 2
+		for next < len(l.input) && (l.input[next] == ' ' || l.input[next] == '\t') {
+			next++
+		}
+		return next < len(l.input) && (l.input[next] == '(' || l.input[next] == '>')
 	}
+	return false
 }
 
-func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+// Err returns an error if the input has an unterminated block comment,
+// once the lexer has reached it
+func (l *Lexer) Err() error {
+	if l.commentStart == nil {
+		return nil
+	}
+	return fmt.Errorf("unterminated block comment at line %d, column %d", l.commentStart.Line, l.commentStart.StartColumn)
+}
+
+// skipLineComment skips to the newline ending the current line
+func (l *Lexer) skipLineComment() {
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
 }
 
+// skipBlockComment skips a /* */ comment. Block comments nest, so a block
+// can be commented out even if it already contains comments. It reports
+// false if the input ends before the comment does.
+func (l *Lexer) skipBlockComment() bool {
+	depth := 0
+	for l.ch != 0 {
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				return true
+			}
+		}
+		l.readChar()
+	}
+	return false
+}
+
 func (l *Lexer) readIdentifier() string {
 	position := l.position
 	for isLetter(l.ch) || isDigit(l.ch) || l.ch == '_' {
@@ -230,6 +413,27 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
+// readQuotedIdentifier reads a backtick-quoted identifier, in which a
+// doubled backtick stands for one. It stops on the closing backtick and
+// reports false if the input ends first or the identifier is empty.
+func (l *Lexer) readQuotedIdentifier() (string, bool) {
+	var b strings.Builder
+	for {
+		l.readChar()
+		switch {
+		case l.ch == 0:
+			return b.String(), false
+		case l.ch == '`' && l.peekChar() == '`':
+			b.WriteByte('`')
+			l.readChar()
+		case l.ch == '`':
+			return b.String(), b.Len() > 0
+		default:
+			b.WriteByte(l.ch)
+		}
+	}
+}
+
 func (l *Lexer) readNumber() string {
 	position := l.position
 	for isDigit(l.ch) {
@@ -245,19 +449,53 @@ func (l *Lexer) readNumber() string {
 	return l.input[position:l.position]
 }
 
+// readString reads a string literal up to its closing quote, resolving
+// the escapes \\, \', \", \n, \r, \t and \uXXXX. Other backslashes are
+// kept as written.
 func (l *Lexer) readString(quote byte) string {
-	position := l.position + 1
+	var b strings.Builder
 	for {
 		l.readChar()
 		if l.ch == quote || l.ch == 0 {
 			break
 		}
-		// Handle escaped quotes
-		if l.ch == '\\' && l.peekChar() == quote {
+		if l.ch != '\\' {
+			b.WriteByte(l.ch)
+			continue
+		}
+		switch next := l.peekChar(); next {
+		case '\\', '\'', '"':
+			b.WriteByte(next)
+			l.readChar()
+		case 'n':
+			b.WriteByte('\n')
 			l.readChar()
+		case 'r':
+			b.WriteByte('\r')
+			l.readChar()
+		case 't':
+			b.WriteByte('\t')
+			l.readChar()
+		case 'u':
+			end := l.readPosition + 5
+			if end > len(l.input) {
+				b.WriteByte(l.ch)
+				continue
+			}
+			r, err := strconv.ParseUint(l.input[l.readPosition+1:end], 16, 32)
+			if err != nil {
+				b.WriteByte(l.ch)
+				continue
+			}
+			b.WriteRune(rune(r))
+			for l.readPosition < end {
+				l.readChar()
+			}
+		default:
+			b.WriteByte(l.ch)
 		}
 	}
-	return l.input[position:l.position]
+	return b.String()
 }
 
 func isLetter(ch byte) bool {
@@ -269,16 +507,27 @@ func isDigit(ch byte) bool {
 }
 
 var keywords = map[string]TokenType{
-	"MATCH":  TokenMatch,
-	"WHERE":  TokenWhere,
-	"RETURN": TokenReturn,
-	"LIMIT":  TokenLimit,
-	"ORDER":  TokenOrderBy,
-	"BY":     TokenOrderBy, // ORDER BY
-	"AND":    TokenAnd,
-	"OR":     TokenOr,
-	"true":   TokenTrue,
-	"false":  TokenFalse,
+	"MATCH":    TokenMatch,
+	"WHERE":    TokenWhere,
+	"RETURN":   TokenReturn,
+	"LIMIT":    TokenLimit,
+	"ORDER":    TokenOrderBy,
+	"BY":       TokenOrderBy, // ORDER BY
+	"AND":      TokenAnd,
+	"OR":       TokenOr,
+	"NOT":      TokenNot,
+	"CALL":     TokenCall,
+	"SHOW":     TokenShow,
+	"CREATE":   TokenCreate,
+	"WITH":     TokenWith,
+	"UNION":    TokenUnion,
+	"USING":    TokenUsing,
+	"INDEX":    TokenIndex,
+	"CONTAINS": TokenContains,
+	"IN":       TokenIn,
+	"TRUE":     TokenTrue,
+	"FALSE":    TokenFalse,
+	"NULL":     TokenNull,
 }
 
 func lookupKeyword(ident string) TokenType {
@@ -303,12 +552,42 @@ func (t TokenType) String() string {
 		return "RETURN"
 	case TokenLimit:
 		return "LIMIT"
+	case TokenOrderBy:
+		return "ORDER BY"
+	case TokenCall:
+		return "CALL"
+	case TokenShow:
+		return "SHOW"
+	case TokenCreate:
+		return "CREATE"
+	case TokenWith:
+		return "WITH"
+	case TokenUnion:
+		return "UNION"
+	case TokenUsing:
+		return "USING"
+	case TokenIndex:
+		return "INDEX"
+	case TokenContains:
+		return "CONTAINS"
+	case TokenIn:
+		return "IN"
+	case TokenAsOf:
+		return "AS OF"
+	case TokenTimestamp:
+		return "TIMESTAMP"
+	case TokenStartsWith:
+		return "STARTS WITH"
+	case TokenEndsWith:
+		return "ENDS WITH"
 	case TokenIdentifier:
 		return "IDENTIFIER"
 	case TokenString:
 		return "STRING"
 	case TokenNumber:
 		return "NUMBER"
+	case TokenParameter:
+		return "PARAMETER"
 	case TokenEqual:
 		return "="
 	case TokenArrow:
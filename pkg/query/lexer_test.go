@@ -92,6 +92,19 @@ func TestLexer_Strings(t *testing.T) {
 	assert.Equal(t, "Bob", tok.Literal)
 }
 
+func TestLexer_StringEscapes(t *testing.T) {
+	l := NewLexer(`'O\'Brien\n\t\\ \u00e9 \d' "say \"hi\""`)
+
+	tok := l.NextToken()
+	assert.Equal(t, TokenString, tok.Type)
+	assert.Equal(t, "O'Brien\n\t\\ é \\d", tok.Literal)
+
+	tok = l.NextToken()
+	assert.Equal(t, TokenString, tok.Type)
+	assert.Equal(t, `say "hi"`, tok.Literal)
+	assert.Equal(t, TokenEOF, l.NextToken().Type)
+}
+
 func TestLexer_Numbers(t *testing.T) {
 	input := `123 45.67 0`
 
@@ -209,3 +222,232 @@ func TestLexer_CaseInsensitiveKeywords(t *testing.T) {
 		assert.Equal(t, TokenWhere, tok.Type, "WHERE keyword %d", i)
 	}
 }
+
+func TestLexer_AsOfTimestamp(t *testing.T) {
+	l := NewLexer(`AS OF TIMESTAMP "2021-06-01" as of timestamp n.timestamp as asof`)
+
+	expected := []struct {
+		typ     TokenType
+		literal string
+	}{
+		{TokenAsOf, "AS OF"},
+		{TokenTimestamp, "TIMESTAMP"},
+		{TokenString, "2021-06-01"},
+		{TokenAsOf, "AS OF"},
+		{TokenTimestamp, "timestamp"},
+		{TokenIdentifier, "n"},
+		{TokenDot, "."},
+		{TokenIdentifier, "timestamp"},
+		{TokenIdentifier, "as"},
+		{TokenIdentifier, "asof"},
+		{TokenEOF, ""},
+	}
+
+	for i, exp := range expected {
+		tok := l.NextToken()
+		assert.Equal(t, exp.typ, tok.Type, "token %d", i)
+		assert.Equal(t, exp.literal, tok.Literal, "token %d", i)
+	}
+}
+
+func TestLexer_StartsEndsWith(t *testing.T) {
+	l := NewLexer(`STARTS WITH ends  with starts ends WITHOUT`)
+
+	expected := []struct {
+		typ     TokenType
+		literal string
+	}{
+		{TokenStartsWith, "STARTS WITH"},
+		{TokenEndsWith, "ENDS WITH"},
+		{TokenIdentifier, "starts"},
+		{TokenIdentifier, "ends"},
+		{TokenIdentifier, "WITHOUT"},
+		{TokenEOF, ""},
+	}
+
+	for i, exp := range expected {
+		tok := l.NextToken()
+		assert.Equal(t, exp.typ, tok.Type, "token %d", i)
+		assert.Equal(t, exp.literal, tok.Literal, "token %d", i)
+	}
+}
+
+func TestLexer_Parameters(t *testing.T) {
+	l := NewLexer(`$here $max_dist $ 1`)
+
+	expected := []struct {
+		typ     TokenType
+		literal string
+	}{
+		{TokenParameter, "here"},
+		{TokenParameter, "max_dist"},
+		{TokenIllegal, "$"},
+		{TokenNumber, "1"},
+		{TokenEOF, ""},
+	}
+
+	for i, exp := range expected {
+		tok := l.NextToken()
+		assert.Equal(t, exp.typ, tok.Type, "token %d", i)
+		assert.Equal(t, exp.literal, tok.Literal, "token %d", i)
+	}
+}
+
+func TestLexer_Positions(t *testing.T) {
+	l := NewLexer("MATCH (a)->(b)\n  WHERE a.age >= 10")
+
+	tests := []struct {
+		literal                   string
+		line, startColumn, column int
+	}{
+		{"MATCH", 1, 1, 5},
+		{"(", 1, 7, 7},
+		{"a", 1, 8, 8},
+		{")", 1, 9, 9},
+		{"->", 1, 10, 11},
+		{"(", 1, 12, 12},
+		{"b", 1, 13, 13},
+		{")", 1, 14, 14},
+		{"WHERE", 2, 3, 7},
+		{"a", 2, 9, 9},
+		{".", 2, 10, 10},
+		{"age", 2, 11, 13},
+		{">=", 2, 15, 16},
+		{"10", 2, 18, 19},
+	}
+	for _, tt := range tests {
+		tok := l.NextToken()
+		assert.Equal(t, tt.literal, tok.Literal)
+		assert.Equal(t, tt.line, tok.Line, tt.literal)
+		assert.Equal(t, tt.startColumn, tok.StartColumn, tt.literal)
+		assert.Equal(t, tt.column, tok.Column, tt.literal)
+	}
+}
+
+func TestLexer_Comments(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"sql style", "-- This selects all people\nMATCH (p) -- trailing\nRETURN p"},
+		{"c++ style", "// Also allowed\nMATCH (p)\nRETURN p // trailing"},
+		{"block", "MATCH /* inline */ (p) RETURN /* two\nlines */ p"},
+		{"nested block", "MATCH (p) /* outer /* inner */ still outer */ RETURN p"},
+		{"comment at end", "MATCH (p) RETURN p /* done */"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLexer(tt.input)
+			for i, typ := range []TokenType{TokenMatch, TokenLeftParen, TokenIdentifier, TokenRightParen, TokenReturn, TokenIdentifier, TokenEOF} {
+				assert.Equal(t, typ, l.NextToken().Type, "token %d", i)
+			}
+		})
+	}
+}
+
+func TestLexer_CommentLines(t *testing.T) {
+	l := NewLexer("/* one\ntwo\nthree */ MATCH -- x\n  (p)")
+
+	tok := l.NextToken()
+	assert.Equal(t, TokenMatch, tok.Type)
+	assert.Equal(t, 3, tok.Line)
+	assert.Equal(t, 10, tok.StartColumn)
+
+	tok = l.NextToken()
+	assert.Equal(t, TokenLeftParen, tok.Type)
+	assert.Equal(t, 4, tok.Line)
+	assert.Equal(t, 3, tok.StartColumn)
+}
+
+func TestLexer_CommentsKeepOperators(t *testing.T) {
+	l := NewLexer("(a)-[:X]->(b) /* unterminated -[")
+
+	for i, typ := range []TokenType{TokenLeftParen, TokenIdentifier, TokenRightParen, TokenDash, TokenLeftBracket,
+		TokenColon, TokenIdentifier, TokenRightBracket, TokenArrow, TokenLeftParen, TokenIdentifier, TokenRightParen,
+		TokenIllegal, TokenEOF} {
+		assert.Equal(t, typ, l.NextToken().Type, "token %d", i)
+	}
+}
+
+func TestLexer_DoubleDashInPatterns(t *testing.T) {
+	tests := []struct {
+		input string
+		types []TokenType
+	}{
+		{"(a)-->(b)", []TokenType{TokenLeftParen, TokenIdentifier, TokenRightParen, TokenDash, TokenArrow,
+			TokenLeftParen, TokenIdentifier, TokenRightParen, TokenEOF}},
+		{"(a)<--(b)", []TokenType{TokenLeftParen, TokenIdentifier, TokenRightParen, TokenLeftArrow, TokenDash,
+			TokenLeftParen, TokenIdentifier, TokenRightParen, TokenEOF}},
+		{"(a)--(b)", []TokenType{TokenLeftParen, TokenIdentifier, TokenRightParen, TokenDash, TokenDash,
+			TokenLeftParen, TokenIdentifier, TokenRightParen, TokenEOF}},
+		{"(a)-[r]--(b)", []TokenType{TokenLeftParen, TokenIdentifier, TokenRightParen, TokenDash,
+			TokenLeftBracket, TokenIdentifier, TokenRightBracket, TokenDash, TokenDash,
+			TokenLeftParen, TokenIdentifier, TokenRightParen, TokenEOF}},
+		{"(a) -- (b)", []TokenType{TokenLeftParen, TokenIdentifier, TokenRightParen, TokenDash, TokenDash,
+			TokenLeftParen, TokenIdentifier, TokenRightParen, TokenEOF}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			l := NewLexer(tt.input)
+			for i, typ := range tt.types {
+				assert.Equal(t, typ, l.NextToken().Type, "token %d", i)
+			}
+		})
+	}
+
+	for input, dir := range map[string]Direction{
+		"MATCH (a)-->(b) -- outgoing\nRETURN b": DirectionOut,
+		"MATCH (a)<--(b) RETURN b -- incoming":  DirectionIn,
+		"MATCH (a) -- (b) RETURN b":             DirectionBoth,
+		"MATCH (a)<-->(b) RETURN b":             DirectionBoth,
+	} {
+		q, err := NewParser(input).Parse()
+		if assert.NoError(t, err, input) && assert.Len(t, q.Match.Patterns[0].Edges, 1, input) {
+			assert.Equal(t, dir, q.Match.Patterns[0].Edges[0].Direction, input)
+		}
+	}
+}
+
+func TestLexer_UnterminatedBlockComment(t *testing.T) {
+	l := NewLexer("MATCH (p)\nRETURN p /* LIMIT 1 /* nested */")
+	for i, typ := range []TokenType{TokenMatch, TokenLeftParen, TokenIdentifier, TokenRightParen, TokenReturn, TokenIdentifier} {
+		assert.Equal(t, typ, l.NextToken().Type, "token %d", i)
+	}
+	assert.NoError(t, l.Err())
+
+	// The comment becomes an illegal token where it starts, then input ends
+	tok := l.NextToken()
+	assert.Equal(t, TokenIllegal, tok.Type)
+	assert.Equal(t, "/*", tok.Literal)
+	assert.Equal(t, 2, tok.Line)
+	assert.Equal(t, 10, tok.StartColumn)
+	assert.Equal(t, TokenEOF, l.NextToken().Type)
+	assert.EqualError(t, l.Err(), "unterminated block comment at line 2, column 10")
+}
+
+func TestLexer_QuotedIdentifiers(t *testing.T) {
+	l := NewLexer("`my label` `order` `it``s` n.`odd key` `` `open")
+
+	expected := []struct {
+		typ     TokenType
+		literal string
+		quoted  bool
+	}{
+		{TokenIdentifier, "my label", true},
+		{TokenIdentifier, "order", true},
+		{TokenIdentifier, "it`s", true},
+		{TokenIdentifier, "n", false},
+		{TokenDot, ".", false},
+		{TokenIdentifier, "odd key", true},
+		{TokenIllegal, "`", false},
+		{TokenIllegal, "`open", false},
+		{TokenEOF, "", false},
+	}
+
+	for i, exp := range expected {
+		tok := l.NextToken()
+		assert.Equal(t, exp.typ, tok.Type, "token %d", i)
+		assert.Equal(t, exp.literal, tok.Literal, "token %d", i)
+		assert.Equal(t, exp.quoted, tok.Quoted, "token %d", i)
+	}
+}
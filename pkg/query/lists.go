@@ -0,0 +1,48 @@
+package query
+
+import "github.com/fnuworsu/rdgDB/internal/graph"
+
+// fnHead returns the first element of a list, or null if it is empty
+func fnHead(args []interface{}) (interface{}, error) {
+	list, err := listArg("head", args)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+// fnLast returns the last element of a list, or null if it is empty
+func fnLast(args []interface{}) (interface{}, error) {
+	list, err := listArg("last", args)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[len(list)-1], nil
+}
+
+// fnTail returns a list without its first element. The tail of an empty
+// list is empty.
+func fnTail(args []interface{}) (interface{}, error) {
+	list, err := listArg("tail", args)
+	if err != nil || list == nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return []graph.PropertyValue{}, nil
+	}
+	return append([]graph.PropertyValue{}, list[1:]...), nil
+}
+
+// listArg returns the single list argument of a function, or nil for null
+func listArg(name string, args []interface{}) ([]graph.PropertyValue, error) {
+	if err := checkArgCount(name, args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case []graph.PropertyValue:
+		return v, nil
+	}
+	return nil, argTypeError(name, 0, "a list", args[0])
+}
@@ -0,0 +1,101 @@
+package query
+
+import (
+	"io"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListFunctions(t *testing.T) {
+	list := []graph.PropertyValue{"chess", "running", "coding"}
+	for _, tc := range []struct {
+		name string
+		args []interface{}
+		want interface{}
+	}{
+		{"head", []interface{}{list}, "chess"},
+		{"last", []interface{}{list}, "coding"},
+		{"tail", []interface{}{list}, []graph.PropertyValue{"running", "coding"}},
+		{"tail", []interface{}{[]graph.PropertyValue{1}}, []graph.PropertyValue{}},
+
+		// Empty lists have no head or last element
+		{"head", []interface{}{[]graph.PropertyValue{}}, nil},
+		{"last", []interface{}{[]graph.PropertyValue{}}, nil},
+		{"tail", []interface{}{[]graph.PropertyValue{}}, []graph.PropertyValue{}},
+
+		// Null in, null out
+		{"head", []interface{}{nil}, nil},
+		{"tail", []interface{}{nil}, nil},
+		{"last", []interface{}{nil}, nil},
+	} {
+		got, err := functions[tc.name](tc.args)
+		require.NoError(t, err, "%s%v", tc.name, tc.args)
+		assert.Equal(t, tc.want, got, "%s%v", tc.name, tc.args)
+	}
+
+	// The tail does not share the list it came from
+	tail, err := fnTail([]interface{}{list})
+	require.NoError(t, err)
+	tail.([]graph.PropertyValue)[0] = "swimming"
+	assert.Equal(t, "running", list[1])
+}
+
+func TestListFunctions_Errors(t *testing.T) {
+	_, err := fnHead(nil)
+	assert.EqualError(t, err, "head expects 1 argument, got 0")
+	_, err = fnTail([]interface{}{"chess"})
+	assert.EqualError(t, err, "tail argument 1 must be a list, got string")
+	_, err = fnLast([]interface{}{graph.NewNode(1, "Person")})
+	assert.EqualError(t, err, "last argument 1 must be a list, got node")
+}
+
+func TestExecute_ListProperties(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	opts := storage.DefaultOptions()
+	opts.RecoveryOutput = io.Discard
+	pg, err := storage.NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	_, err = pg.AddNode("Person", graph.Properties{"name": "Alice", "hobbies": []string{"chess", "running", "coding"}})
+	require.NoError(t, err)
+	_, err = pg.AddNode("Person", graph.Properties{"name": "Bob", "hobbies": []interface{}{"running"}, "scores": []float64{1.5, 2}})
+	require.NoError(t, err)
+	_, err = pg.AddNode("Person", graph.Properties{"name": "Charlie", "scores": []int64{3, 4}})
+	require.NoError(t, err)
+
+	// Elements of a stored list share a type
+	_, err = pg.AddNode("Person", graph.Properties{"name": "Dana", "hobbies": []interface{}{"chess", 7}})
+	assert.ErrorContains(t, err, "property hobbies: [1]: list mixes string and int elements")
+	require.NoError(t, pg.Close())
+
+	// Lists come back from the WAL with their elements typed as written
+	pg, err = storage.NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer pg.Close()
+
+	run := func(input string) *Result {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		result, err := q.Execute(pg)
+		require.NoError(t, err, input)
+		return result
+	}
+
+	result := run(`MATCH (p:Person) WHERE "chess" IN p.hobbies RETURN p.name AS name`)
+	assert.Equal(t, []Row{{"name": "Alice"}}, result.Rows)
+
+	result = run(`MATCH (p:Person) RETURN p.name AS name, p.hobbies AS hobbies, size(p.hobbies) AS n, head(p.hobbies) AS first, tail(p.hobbies) AS rest, last(p.hobbies) AS final ORDER BY name`)
+	assert.Equal(t, []Row{
+		{"name": "Alice", "hobbies": []graph.PropertyValue{"chess", "running", "coding"}, "n": 3,
+			"first": "chess", "rest": []graph.PropertyValue{"running", "coding"}, "final": "coding"},
+		{"name": "Bob", "hobbies": []graph.PropertyValue{"running"}, "n": 1,
+			"first": "running", "rest": []graph.PropertyValue{}, "final": "running"},
+		{"name": "Charlie", "hobbies": nil, "n": nil, "first": nil, "rest": nil, "final": nil},
+	}, result.Rows)
+
+	result = run(`MATCH (p:Person) WHERE 2 IN p.scores OR 4 IN p.scores RETURN p.name AS name, head(p.scores) AS first ORDER BY name`)
+	assert.Equal(t, []Row{{"name": "Bob", "first": 1.5}, {"name": "Charlie", "first": 3}}, result.Rows)
+}
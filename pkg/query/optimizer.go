@@ -0,0 +1,565 @@
+package query
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// OptimizerStats holds the cardinality estimates used by the planner.
+// The planner starts a pattern from the node expected to match the fewest
+// nodes, given its label and equality filters; EdgeTypeCounts is
+// collected for estimating expansions. Filters on properties in
+// PropertyIndexes are taken to be the most selective.
+type OptimizerStats struct {
+	NodeCount   int
+	LabelCounts map[string]int
+
+	// EdgeTypeCounts holds the number of edges of each relationship type
+	// in the patterns
+	EdgeTypeCounts map[string]int
+
+	// FullTextIndexes holds "Label.property" for each usable full-text index
+	FullTextIndexes map[string]bool
+
+	// PropertyIndexes holds "Label.property" for each property index on an
+	// inline property or a WHERE equality
+	PropertyIndexes map[string]bool
+
+	// PrefixIndexes holds "Label.property" for each property index usable
+	// for a STARTS WITH prefix scan
+	PrefixIndexes map[string]bool
+
+	// SpatialIndexes holds "Label.property" for each usable spatial index
+	SpatialIndexes map[string]bool
+}
+
+// labelCounter is implemented by storage backends with a label index
+type labelCounter interface {
+	NodeCount() int
+	LabelCount(label string) int
+}
+
+// edgeTypeCounter is implemented by storage backends that count edges
+// per type
+type edgeTypeCounter interface {
+	EdgeTypeCount(label string) int
+}
+
+// fullTextIndexer is implemented by storage backends with full-text indexes
+type fullTextIndexer interface {
+	HasFullTextIndex(label, property string) bool
+	FullTextContains(label, property, text string) ([]*graph.Node, error)
+}
+
+// propertyIndexer is implemented by storage backends with equality indexes
+type propertyIndexer interface {
+	HasPropertyIndex(label, property string) bool
+	PropertyLookup(label, property string, value graph.PropertyValue) ([]*graph.Node, error)
+}
+
+// prefixIndexer is implemented by storage backends whose equality indexes
+// keep string values sorted
+type prefixIndexer interface {
+	HasPropertyIndex(label, property string) bool
+	PropertyPrefixLookup(label, property, prefix string) ([]*graph.Node, error)
+}
+
+// spatialIndexer is implemented by storage backends with spatial indexes
+type spatialIndexer interface {
+	HasSpatialIndex(label, property string) bool
+	RadiusSearch(label, property string, center graph.Point, radiusKm float64) ([]*graph.Node, error)
+}
+
+// collectOptimizerStats gathers label cardinalities for the labels used in
+// the patterns of every part of q. It returns nil if g does not maintain a
+// label index.
+func collectOptimizerStats(q *Query, g GraphStorage) *OptimizerStats {
+	lc, ok := g.(labelCounter)
+	if !ok {
+		return nil
+	}
+
+	stats := &OptimizerStats{
+		NodeCount:   lc.NodeCount(),
+		LabelCounts: make(map[string]int),
+	}
+	for part := q; part != nil; part = part.Input {
+		stats.collect(part, g, lc)
+	}
+	return stats
+}
+
+// collect adds the estimates for one query part
+func (stats *OptimizerStats) collect(q *Query, g GraphStorage, lc labelCounter) {
+	if q.Match == nil {
+		return
+	}
+	ec, countsEdges := g.(edgeTypeCounter)
+	for _, pattern := range q.Match.Patterns {
+		for _, node := range pattern.Nodes {
+			if node.Label != "" {
+				stats.LabelCounts[node.Label] = lc.LabelCount(node.Label)
+			}
+		}
+		for _, edge := range pattern.Edges {
+			if countsEdges && edge.Type != "" {
+				stats.EdgeTypeCounts = addCounts(stats.EdgeTypeCounts)
+				stats.EdgeTypeCounts[edge.Type] = ec.EdgeTypeCount(edge.Type)
+			}
+		}
+	}
+
+	if pi, ok := g.(propertyIndexer); ok && len(q.Match.Patterns) > 0 {
+		stats.PropertyIndexes = addIndexes(stats.PropertyIndexes)
+		for _, pattern := range q.Match.Patterns {
+			for _, node := range pattern.Nodes {
+				for property := range node.Properties {
+					if node.Label != "" && pi.HasPropertyIndex(node.Label, property) {
+						stats.PropertyIndexes[node.Label+"."+property] = true
+					}
+				}
+			}
+		}
+		if q.Where != nil {
+			for _, expr := range splitConjuncts(q.Where.Expr) {
+				variable, property, ok := equalityPredicate(expr)
+				if !ok {
+					continue
+				}
+				label := q.patternLabel(variable)
+				if label != "" && pi.HasPropertyIndex(label, property) {
+					stats.PropertyIndexes[label+"."+property] = true
+				}
+			}
+		}
+	}
+
+	if pi, ok := g.(prefixIndexer); ok && q.Where != nil {
+		stats.PrefixIndexes = addIndexes(stats.PrefixIndexes)
+		for _, expr := range splitConjuncts(q.Where.Expr) {
+			variable, property, _, ok := prefixPredicate(expr)
+			if !ok {
+				continue
+			}
+			label := q.patternLabel(variable)
+			if label != "" && pi.HasPropertyIndex(label, property) {
+				stats.PrefixIndexes[label+"."+property] = true
+			}
+		}
+	}
+
+	if ft, ok := g.(fullTextIndexer); ok && q.Where != nil {
+		stats.FullTextIndexes = addIndexes(stats.FullTextIndexes)
+		for _, expr := range splitConjuncts(q.Where.Expr) {
+			variable, property, _, ok := fullTextPredicate(expr)
+			if !ok {
+				continue
+			}
+			label := q.patternLabel(variable)
+			if label != "" && ft.HasFullTextIndex(label, property) {
+				stats.FullTextIndexes[label+"."+property] = true
+			}
+		}
+	}
+
+	if si, ok := g.(spatialIndexer); ok && q.Where != nil {
+		stats.SpatialIndexes = addIndexes(stats.SpatialIndexes)
+		for _, expr := range splitConjuncts(q.Where.Expr) {
+			variable, property, _, _, ok := distancePredicate(expr)
+			if !ok {
+				continue
+			}
+			label := q.patternLabel(variable)
+			if label != "" && si.HasSpatialIndex(label, property) {
+				stats.SpatialIndexes[label+"."+property] = true
+			}
+		}
+	}
+}
+
+// addCounts returns counts, allocating it if it is nil
+func addCounts(counts map[string]int) map[string]int {
+	if counts == nil {
+		return make(map[string]int)
+	}
+	return counts
+}
+
+// addIndexes returns indexes, allocating it if it is nil
+func addIndexes(indexes map[string]bool) map[string]bool {
+	if indexes == nil {
+		return make(map[string]bool)
+	}
+	return indexes
+}
+
+// hasFullTextIndex reports whether a full-text index covers label.property
+func (s *OptimizerStats) hasFullTextIndex(label, property string) bool {
+	return s != nil && s.FullTextIndexes[label+"."+property]
+}
+
+// hasPrefixIndex reports whether a property index can scan label.property
+// by prefix
+func (s *OptimizerStats) hasPrefixIndex(label, property string) bool {
+	return s != nil && s.PrefixIndexes[label+"."+property]
+}
+
+// hasSpatialIndex reports whether a spatial index covers label.property
+func (s *OptimizerStats) hasSpatialIndex(label, property string) bool {
+	return s != nil && s.SpatialIndexes[label+"."+property]
+}
+
+// indexSeekProperty returns an inline property of node that a property
+// index can look up, preferring the first in name order. Lists and maps
+// are not looked up.
+func (s *OptimizerStats) indexSeekProperty(node NodePattern) (string, bool) {
+	if s == nil || node.Label == "" {
+		return "", false
+	}
+	keys := make([]string, 0, len(node.Properties))
+	for k := range node.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, isExpr := node.Properties[k].(Expression); isExpr {
+			continue
+		}
+		if s.PropertyIndexes[node.Label+"."+k] {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// patternLabel returns the label of the node bound to variable in the
+// patterns, or "" if it is unlabeled or not a node variable
+func (q *Query) patternLabel(variable string) string {
+	if q.Match == nil {
+		return ""
+	}
+	for _, pattern := range q.Match.Patterns {
+		for _, node := range pattern.Nodes {
+			if node.Variable == variable && node.Label != "" {
+				return node.Label
+			}
+		}
+	}
+	return ""
+}
+
+// splitConjuncts flattens a tree of AND expressions into its operands
+func splitConjuncts(expr Expression) []Expression {
+	if b, ok := expr.(*BinaryExpr); ok && b.Operator == "AND" {
+		return append(splitConjuncts(b.Left), splitConjuncts(b.Right)...)
+	}
+	return []Expression{expr}
+}
+
+// joinConjuncts combines expressions with AND; it returns nil for none
+func joinConjuncts(exprs []Expression) Expression {
+	if len(exprs) == 0 {
+		return nil
+	}
+	expr := exprs[0]
+	for _, next := range exprs[1:] {
+		expr = &BinaryExpr{Left: expr, Operator: "AND", Right: next}
+	}
+	return expr
+}
+
+// fullTextPredicate matches var.property CONTAINS "text" where text has a
+// letter or digit, so that a full-text index can narrow the candidates
+func fullTextPredicate(expr Expression) (variable, property, text string, ok bool) {
+	b, isBinary := expr.(*BinaryExpr)
+	if !isBinary || b.Operator != "CONTAINS" {
+		return "", "", "", false
+	}
+	prop, isProp := b.Left.(*PropertyAccess)
+	lit, isLit := b.Right.(*Literal)
+	if !isProp || !isLit || len(prop.Path) > 0 {
+		return "", "", "", false
+	}
+	text, ok = lit.Value.(string)
+	hasTerm := strings.IndexFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0
+	return prop.Variable, prop.Property, text, ok && hasTerm
+}
+
+// prefixPredicate matches var.property STARTS WITH "prefix". ENDS WITH
+// and CONTAINS cannot use the sorted values of a property index.
+func prefixPredicate(expr Expression) (variable, property, prefix string, ok bool) {
+	b, isBinary := expr.(*BinaryExpr)
+	if !isBinary || b.Operator != "STARTS WITH" {
+		return "", "", "", false
+	}
+	prop, isProp := b.Left.(*PropertyAccess)
+	lit, isLit := b.Right.(*Literal)
+	if !isProp || !isLit || len(prop.Path) > 0 {
+		return "", "", "", false
+	}
+	prefix, ok = lit.Value.(string)
+	return prop.Variable, prop.Property, prefix, ok
+}
+
+// equalityPredicate matches var.property = value, with the operands in
+// either order, where value is a literal or a parameter
+func equalityPredicate(expr Expression) (variable, property string, ok bool) {
+	b, isBinary := expr.(*BinaryExpr)
+	if !isBinary || b.Operator != "=" {
+		return "", "", false
+	}
+	for _, pair := range [][2]Expression{{b.Left, b.Right}, {b.Right, b.Left}} {
+		prop, isProp := pair[0].(*PropertyAccess)
+		if !isProp || len(prop.Path) > 0 {
+			continue
+		}
+		switch pair[1].(type) {
+		case *Literal, *Parameter:
+			return prop.Variable, prop.Property, true
+		}
+	}
+	return "", "", false
+}
+
+// joinPredicate matches left = right, with the operands in either order,
+// where left refers only to variables in before and right only to
+// variables in after
+func joinPredicate(expr Expression, before, after map[string]bool) (left, right Expression, ok bool) {
+	b, isBinary := expr.(*BinaryExpr)
+	if !isBinary || b.Operator != "=" {
+		return nil, nil, false
+	}
+	if refersOnlyTo(b.Left, before) && refersOnlyTo(b.Right, after) {
+		return b.Left, b.Right, true
+	}
+	if refersOnlyTo(b.Right, before) && refersOnlyTo(b.Left, after) {
+		return b.Right, b.Left, true
+	}
+	return nil, nil, false
+}
+
+// refersOnlyTo reports whether expr refers to at least one variable, and
+// only to variables in names
+func refersOnlyTo(expr Expression, names map[string]bool) bool {
+	found, only := false, true
+	referencedVariables(expr, func(name string) {
+		found = true
+		only = only && names[name]
+	})
+	return found && only
+}
+
+// conjunctsOn splits conjuncts into those whose variables all satisfy in
+// and the rest
+func conjunctsOn(conjuncts []Expression, in func(string) bool) (on, rest []Expression) {
+	for _, expr := range conjuncts {
+		all := true
+		referencedVariables(expr, func(name string) {
+			all = all && in(name)
+		})
+		if all {
+			on = append(on, expr)
+		} else {
+			rest = append(rest, expr)
+		}
+	}
+	return on, rest
+}
+
+// distancePredicate matches distance(var.property, center) < radius or
+// <= radius, with the arguments in either order. center is a point
+// literal or a parameter and radius is in meters.
+func distancePredicate(expr Expression) (variable, property string, center Expression, radius float64, ok bool) {
+	b, isBinary := expr.(*BinaryExpr)
+	if !isBinary || b.Operator != "<" && b.Operator != "<=" {
+		return "", "", nil, 0, false
+	}
+	call, isCall := b.Left.(*FunctionCall)
+	lit, isLit := b.Right.(*Literal)
+	if !isCall || call.Name != "distance" || len(call.Args) != 2 || !isLit || !isNumber(lit.Value) {
+		return "", "", nil, 0, false
+	}
+
+	for i := range call.Args {
+		prop, isProp := call.Args[i].(*PropertyAccess)
+		if !isProp || len(prop.Path) > 0 {
+			continue
+		}
+		switch other := call.Args[1-i].(type) {
+		case *PointLiteral, *Parameter:
+			return prop.Variable, prop.Property, other, toFloat(lit.Value), true
+		}
+	}
+	return "", "", nil, 0, false
+}
+
+// equalitySelectivity is the fraction of nodes an equality filter is
+// assumed to keep, one in equalitySelectivity, as there are no statistics
+// on property values
+const equalitySelectivity = 10
+
+// estimateCardinality returns the expected number of nodes a scan of
+// node would produce once its inline properties and the given number of
+// WHERE equality filters on it have been applied
+func (s *OptimizerStats) estimateCardinality(node NodePattern, equalities int) int {
+	count := s.NodeCount
+	if node.Label != "" {
+		count = s.LabelCounts[node.Label]
+	}
+	for i := 0; i < len(node.Properties)+equalities; i++ {
+		count = (count + equalitySelectivity - 1) / equalitySelectivity
+	}
+	return count
+}
+
+// selectMostSelectiveStartNode picks the named node variable with the
+// smallest estimated cardinality, counting the equality filters of where
+// on it. Ties are broken in favor of a node with a filter, then by
+// pattern order. It returns "" if no stats are available or no node is
+// named.
+func selectMostSelectiveStartNode(patterns []Pattern, where []Expression, stats *OptimizerStats) string {
+	if stats == nil {
+		return ""
+	}
+
+	equalities := make(map[string]int)
+	for _, expr := range where {
+		if variable, _, ok := equalityPredicate(expr); ok {
+			equalities[variable]++
+		}
+	}
+
+	best := ""
+	bestCount := 0
+	bestFiltered := false
+
+	for _, pattern := range patterns {
+		for _, node := range pattern.Nodes {
+			if node.Variable == "" {
+				continue
+			}
+			count := stats.estimateCardinality(node, equalities[node.Variable])
+			filtered := len(node.Properties) > 0 || equalities[node.Variable] > 0
+
+			if best == "" || count < bestCount || (count == bestCount && filtered && !bestFiltered) {
+				best = node.Variable
+				bestCount = count
+				bestFiltered = filtered
+			}
+		}
+	}
+
+	return best
+}
+
+// reorderFilters splits the filters among a pattern's operators into
+// their conjuncts and moves each to just after the operator that binds
+// the last of its variables, so that it runs before expansions it does
+// not depend on. Conjuncts on variables bound before the pattern move to
+// its start. Filters that end up together run cheapest and most selective
+// first, as ranked by filterRank.
+func reorderFilters(ops []Operator, q *Query, stats *OptimizerStats) []Operator {
+	var steps []Operator
+	var conjuncts []Expression
+	for _, op := range ops {
+		if f, ok := op.(*FilterOperator); ok {
+			conjuncts = append(conjuncts, splitConjuncts(f.Predicate)...)
+		} else {
+			steps = append(steps, op)
+		}
+	}
+	if len(conjuncts) == 0 {
+		return ops
+	}
+
+	// boundAt maps each variable to the step that binds it
+	boundAt := make(map[string]int)
+	for i, op := range steps {
+		for _, name := range operatorBinds(op) {
+			if _, ok := boundAt[name]; !ok {
+				boundAt[name] = i
+			}
+		}
+	}
+
+	// after[i+1] holds the conjuncts that run after step i
+	after := make([][]Expression, len(steps)+1)
+	for _, expr := range conjuncts {
+		pos := -1
+		referencedVariables(expr, func(name string) {
+			if i, ok := boundAt[name]; ok && i > pos {
+				pos = i
+			}
+		})
+		after[pos+1] = append(after[pos+1], expr)
+	}
+
+	reordered := make([]Operator, 0, len(steps)+len(conjuncts))
+	for i := 0; i <= len(steps); i++ {
+		exprs := after[i]
+		sort.SliceStable(exprs, func(a, b int) bool {
+			return filterRank(exprs[a], q, stats) < filterRank(exprs[b], q, stats)
+		})
+		for _, expr := range exprs {
+			reordered = append(reordered, &FilterOperator{Predicate: expr})
+		}
+		if i < len(steps) {
+			reordered = append(reordered, steps[i])
+		}
+	}
+	return reordered
+}
+
+// operatorBinds returns the variables an operator binds
+func operatorBinds(op Operator) []string {
+	switch o := op.(type) {
+	case *ScanOperator:
+		return []string{o.Variable}
+	case *BoundNodeOperator:
+		return []string{o.Variable}
+	case *IndexSeekOperator:
+		return []string{o.Variable}
+	case *IndexPrefixScanOperator:
+		return []string{o.Variable}
+	case *FullTextScanOperator:
+		return []string{o.Variable}
+	case *SpatialScanOperator:
+		return []string{o.Variable}
+	case *ExpandOperator:
+		return []string{o.TargetVar, o.EdgeVar}
+	case *PathOperator:
+		return []string{o.Variable}
+	case *HashJoinOperator:
+		var names []string
+		for _, inner := range o.Operators {
+			names = append(names, operatorBinds(inner)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// filterRank orders conjuncts that run at the same point: equality on an
+// indexed property first, as the index marks it as selective, then other
+// equalities, then range comparisons, then everything else
+func filterRank(expr Expression, q *Query, stats *OptimizerStats) int {
+	b, ok := expr.(*BinaryExpr)
+	if !ok {
+		return 3
+	}
+	switch b.Operator {
+	case "=":
+		if variable, property, ok := equalityPredicate(expr); ok && stats != nil {
+			if label := q.patternLabel(variable); label != "" && stats.PropertyIndexes[label+"."+property] {
+				return 0
+			}
+		}
+		return 1
+	case "<", "<=", ">", ">=", "STARTS WITH":
+		return 2
+	}
+	return 3
+}
@@ -0,0 +1,743 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createEmploymentGraph builds many Person nodes working at a few Companies
+func createEmploymentGraph(t *testing.T) *storage.Graph {
+	g := storage.NewGraph()
+
+	companies := make([]*graph.Node, 3)
+	for i := range companies {
+		companies[i], _ = g.AddNode("Company", graph.Properties{"name": fmt.Sprintf("Company%d", i)})
+	}
+	for i := 0; i < 100; i++ {
+		p, _ := g.AddNode("Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)})
+		_, err := g.AddEdge(p.ID, companies[i%len(companies)].ID, "WORKS_AT", nil)
+		require.NoError(t, err)
+	}
+
+	return g
+}
+
+func TestSelectMostSelectiveStartNode(t *testing.T) {
+	stats := &OptimizerStats{
+		NodeCount:   103,
+		LabelCounts: map[string]int{"Person": 100, "Company": 3},
+	}
+
+	patterns := []Pattern{{
+		Nodes: []NodePattern{{Variable: "p", Label: "Person"}, {Variable: "c", Label: "Company"}},
+		Edges: []EdgePattern{{Type: "WORKS_AT", Direction: DirectionOut}},
+	}}
+	assert.Equal(t, "c", selectMostSelectiveStartNode(patterns, nil, stats))
+
+	// Equal cardinality: prefer the node with a property filter
+	patterns = []Pattern{{
+		Nodes: []NodePattern{
+			{Variable: "a", Label: "Person"},
+			{Variable: "b", Label: "Person", Properties: map[string]interface{}{"name": "Person1"}},
+		},
+		Edges: []EdgePattern{{Direction: DirectionBoth}},
+	}}
+	assert.Equal(t, "b", selectMostSelectiveStartNode(patterns, nil, stats))
+
+	assert.Equal(t, "", selectMostSelectiveStartNode(patterns, nil, nil))
+
+	// An equality filter outweighs a somewhat smaller label
+	stats = &OptimizerStats{
+		NodeCount:   250,
+		LabelCounts: map[string]int{"Person": 200, "City": 50},
+	}
+	patterns = []Pattern{{
+		Nodes: []NodePattern{
+			{Variable: "p", Label: "Person", Properties: map[string]interface{}{"name": "Person1"}},
+			{Variable: "c", Label: "City"},
+		},
+		Edges: []EdgePattern{{Type: "LIVES_IN", Direction: DirectionOut}},
+	}}
+	assert.Equal(t, "p", selectMostSelectiveStartNode(patterns, nil, stats))
+
+	// WHERE equalities count as well
+	query, err := NewParser(`MATCH (p:Person {name: "Person1"})-[:LIVES_IN]->(c:City) WHERE c.name = "Paris" AND c.population > 1000 RETURN p`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "c", selectMostSelectiveStartNode(query.Match.Patterns, splitConjuncts(query.Where.Expr), stats))
+}
+
+func TestPlanner_StartsFromFilteredNode(t *testing.T) {
+	g := createTestGraph(t)
+
+	// The unlabeled a would match every node; Bob is one Person
+	query, err := NewParser(`MATCH (a)-[:KNOWS]->(b:Person {name: "Bob"}) RETURN a.name`).Parse()
+	require.NoError(t, err)
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+
+	scan, ok := plan.Operators[0].(*ScanOperator)
+	require.True(t, ok)
+	assert.Equal(t, "b", scan.Variable)
+	require.IsType(t, &FilterOperator{}, plan.Operators[1])
+	expand, ok := plan.Operators[2].(*ExpandOperator)
+	require.True(t, ok)
+	assert.Equal(t, "b", expand.SourceVar)
+	assert.Equal(t, "a", expand.TargetVar)
+	assert.Equal(t, DirectionIn, expand.Direction)
+
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Alice", result.Rows[0]["a.name"])
+
+	// A WHERE equality anchors the same way
+	query, err = NewParser(`MATCH (a)-[:KNOWS]->(b:Person) WHERE b.name = "Bob" RETURN a.name`).Parse()
+	require.NoError(t, err)
+	plan, err = BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	assert.Equal(t, "b", plan.Operators[0].(*ScanOperator).Variable)
+	result, err = query.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Alice", result.Rows[0]["a.name"])
+}
+
+func TestPlanner_StartsFromSmallerLabel(t *testing.T) {
+	g := createEmploymentGraph(t)
+
+	query, err := NewParser(`MATCH (p:Person)-[:WORKS_AT]->(c:Company) RETURN p.name, c.name`).Parse()
+	require.NoError(t, err)
+
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+
+	scan, ok := plan.Operators[0].(*ScanOperator)
+	require.True(t, ok)
+	assert.Equal(t, "c", scan.Variable)
+
+	expand, ok := plan.Operators[1].(*ExpandOperator)
+	require.True(t, ok)
+	assert.Equal(t, "c", expand.SourceVar)
+	assert.Equal(t, "p", expand.TargetVar)
+	assert.Equal(t, DirectionIn, expand.Direction)
+
+	// Reordering must not change the results
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 100)
+	for _, row := range result.Rows {
+		assert.Contains(t, row["p.name"], "Person")
+		assert.Contains(t, row["c.name"], "Company")
+	}
+}
+
+func TestPlanner_StartsFromSmallestLabelInChain(t *testing.T) {
+	g := storage.NewGraph()
+	cities := make([]*graph.Node, 2)
+	for i := range cities {
+		cities[i], _ = g.AddNode("City", graph.Properties{"name": fmt.Sprintf("City%d", i)})
+	}
+	companies := make([]*graph.Node, 20)
+	for i := range companies {
+		companies[i], _ = g.AddNode("Company", nil)
+		g.AddEdge(companies[i].ID, cities[i%len(cities)].ID, "LOCATED_IN", nil)
+	}
+	for i := 0; i < 1000; i++ {
+		p, _ := g.AddNode("Person", nil)
+		g.AddEdge(p.ID, companies[i%len(companies)].ID, "WORKS_AT", nil)
+	}
+
+	query, err := NewParser(`MATCH (p:Person)-[:WORKS_AT]->(c:Company)-[:LOCATED_IN]->(city:City) RETURN city.name`).Parse()
+	require.NoError(t, err)
+
+	stats := collectOptimizerStats(query, g)
+	assert.Equal(t, map[string]int{"Person": 1000, "Company": 20, "City": 2}, stats.LabelCounts)
+	assert.Equal(t, map[string]int{"WORKS_AT": 1000, "LOCATED_IN": 20}, stats.EdgeTypeCounts)
+
+	plan, err := BuildExecutionPlanWithStats(query, stats)
+	require.NoError(t, err)
+	scan, ok := plan.Operators[0].(*ScanOperator)
+	require.True(t, ok)
+	assert.Equal(t, "city", scan.Variable)
+
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 1000)
+}
+
+func TestPlanner_ReordersFilters(t *testing.T) {
+	g := storage.NewGraph()
+	companies := make([]*graph.Node, 3)
+	for i := range companies {
+		companies[i], _ = g.AddNode("Company", graph.Properties{"name": fmt.Sprintf("Company%d", i)})
+	}
+	for i := 0; i < 100; i++ {
+		p, _ := g.AddNode("Person", graph.Properties{"name": fmt.Sprintf("Person%d", i), "age": i})
+		g.AddEdge(p.ID, companies[i%len(companies)].ID, "WORKS_AT", nil)
+	}
+	require.NoError(t, g.CreatePropertyIndex("Person", "name"))
+
+	query, err := NewParser(`MATCH (c:Company)<-[:WORKS_AT]-(p:Person) WHERE p.age < 50 AND c.name = "Company1" AND p.name = "Person7" RETURN p.name`).Parse()
+	require.NoError(t, err)
+
+	// The filter on c runs before the expansion, and the selective
+	// equality on the indexed p.name before the range filter on p.age
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	var steps []string
+	for _, op := range plan.Operators {
+		switch o := op.(type) {
+		case *ScanOperator:
+			steps = append(steps, "scan "+o.Variable)
+		case *ExpandOperator:
+			steps = append(steps, "expand "+o.TargetVar)
+		case *FilterOperator:
+			steps = append(steps, "filter "+expressionText(o.Predicate))
+		}
+	}
+	assert.Equal(t, []string{
+		"scan c",
+		`filter c.name = "Company1"`,
+		"expand p",
+		`filter p.name = "Person7"`,
+		"filter p.age < 50",
+	}, steps)
+
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Person7", result.Rows[0]["p.name"])
+
+	// Without the index, equality still runs before the range filter
+	query, err = NewParser(`MATCH (p:Person) WHERE p.age < 50 AND p.age = 7 RETURN p.name`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, []Operator{
+		&FilterOperator{Predicate: splitConjuncts(query.Where.Expr)[1]},
+		&FilterOperator{Predicate: splitConjuncts(query.Where.Expr)[0]},
+	}, reorderFilters([]Operator{&FilterOperator{Predicate: query.Where.Expr}}, query, nil))
+}
+
+func TestPlanner_HintOverridesStats(t *testing.T) {
+	g := createEmploymentGraph(t)
+
+	query, err := NewParser(`MATCH (p:Person)-[:WORKS_AT]->(c:Company) USING INDEX p:Person(name) RETURN p.name`).Parse()
+	require.NoError(t, err)
+
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+
+	scan := plan.Operators[0].(*ScanOperator)
+	assert.Equal(t, "p", scan.Variable)
+
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 100)
+}
+
+func TestPlanner_UsesFullTextIndex(t *testing.T) {
+	g := storage.NewGraph()
+	g.AddNode("Person", graph.Properties{"name": "Alice", "bio": "Works on distributed systems"})
+	g.AddNode("Person", graph.Properties{"name": "Bob", "bio": "distributed systems, distributed everything"})
+	g.AddNode("Person", graph.Properties{"name": "Carol", "bio": "Frontend"})
+
+	input := `MATCH (n:Person) WHERE n.bio CONTAINS "distributed systems" RETURN n.name`
+
+	// Without an index CONTAINS is a substring filter over a label scan
+	query, err := NewParser(input).Parse()
+	require.NoError(t, err)
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+
+	require.NoError(t, g.CreateFullTextIndex("Person", "bio"))
+
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	scan, ok := plan.Operators[0].(*FullTextScanOperator)
+	require.True(t, ok, "expected a full-text scan, got %T", plan.Operators[0])
+	assert.Equal(t, "bio", scan.Property)
+	hasFilter := false
+	for _, op := range plan.Operators {
+		if _, isFilter := op.(*FilterOperator); isFilter {
+			hasFilter = true
+		}
+	}
+	assert.True(t, hasFilter, "CONTAINS filter should stay after the index narrows the candidates")
+
+	result, err = query.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, "Bob", result.Rows[0]["n.name"])
+	assert.Equal(t, "Alice", result.Rows[1]["n.name"])
+
+	// Substrings that cut terms, or differ only in case, give the same
+	// rows as the unindexed filter
+	for text, want := range map[string][]string{
+		"stributed sys": {"Alice", "Bob"},
+		"Works on dist": {"Alice"},
+		"Distributed":   nil,
+		"tems, distri":  {"Bob"},
+		"end":           {"Carol"},
+	} {
+		query, err := NewParser(`MATCH (n:Person) WHERE n.bio CONTAINS "` + text + `" RETURN n.name`).Parse()
+		require.NoError(t, err)
+		plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+		require.NoError(t, err)
+		_, ok := plan.Operators[0].(*FullTextScanOperator)
+		require.True(t, ok, "expected a full-text scan for %q, got %T", text, plan.Operators[0])
+
+		result, err := query.Execute(g)
+		require.NoError(t, err)
+		var names []string
+		for _, row := range result.Rows {
+			names = append(names, row["n.name"].(string))
+		}
+		sort.Strings(names)
+		assert.Equal(t, want, names, "CONTAINS %q", text)
+	}
+
+	// Text without terms falls back to the label scan
+	query, err = NewParser(`MATCH (n:Person) WHERE n.bio CONTAINS ", " RETURN n.name`).Parse()
+	require.NoError(t, err)
+	plan, err = BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	_, ok = plan.Operators[0].(*ScanOperator)
+	require.True(t, ok, "expected a label scan, got %T", plan.Operators[0])
+	result, err = query.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 1)
+}
+
+func TestPlanner_UsesSpatialIndex(t *testing.T) {
+	g := createCityGraph(t)
+	g.AddNode("City", graph.Properties{"name": "Jersey City", "location": graph.Point{Lat: 40.7178, Lon: -74.0431}})
+
+	input := `MATCH (c:City) WHERE distance(c.location, $here) < 20000 RETURN c.name`
+	query, err := NewParser(input).Parse()
+	require.NoError(t, err)
+	query.Parameters = map[string]interface{}{"here": graph.Point{Lat: 40.7128, Lon: -74.0060}}
+
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	_, ok := plan.Operators[0].(*ScanOperator)
+	require.True(t, ok, "expected a label scan without an index, got %T", plan.Operators[0])
+
+	require.NoError(t, g.CreateSpatialIndex("City", "location"))
+
+	plan, err = BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	scan, ok := plan.Operators[0].(*SpatialScanOperator)
+	require.True(t, ok, "expected a spatial scan, got %T", plan.Operators[0])
+	assert.Equal(t, "location", scan.Property)
+	assert.Equal(t, 20000.0, scan.RadiusMeters)
+	assert.Equal(t, &Parameter{Name: "here"}, scan.Center)
+
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	var names []interface{}
+	for _, row := range result.Rows {
+		names = append(names, row["c.name"])
+	}
+	assert.Equal(t, []interface{}{"New York", "Jersey City", "Newark"}, names)
+
+	// The distance filter still applies on top of the index search
+	query, err = NewParser(`MATCH (c:City) WHERE distance(point({latitude: 40.7128, longitude: -74.006}), c.location) < 1000 RETURN c.name`).Parse()
+	require.NoError(t, err)
+	plan, err = BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	require.IsType(t, &SpatialScanOperator{}, plan.Operators[0])
+	result, err = query.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "New York", result.Rows[0]["c.name"])
+}
+
+func TestPlanner_UsesPropertyIndex(t *testing.T) {
+	g := createEmploymentGraph(t)
+
+	query, err := NewParser(`MATCH (c:Company)<-[:WORKS_AT]-(p:Person {name: "Person7"}) RETURN p.name, c.name`).Parse()
+	require.NoError(t, err)
+
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	assert.Equal(t, "c", plan.Operators[0].(*ScanOperator).Variable)
+
+	require.NoError(t, g.CreatePropertyIndex("Person", "name"))
+
+	// The indexed node becomes the anchor even though Company is smaller
+	plan, err = BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	seek, ok := plan.Operators[0].(*IndexSeekOperator)
+	require.True(t, ok, "expected an index seek, got %T", plan.Operators[0])
+	assert.Equal(t, IndexSeekOperator{Variable: "p", Label: "Person", Property: "name", Value: "Person7"}, *seek)
+
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Person7", result.Rows[0]["p.name"])
+	assert.Equal(t, "Company1", result.Rows[0]["c.name"])
+}
+
+func TestPlanner_UsesPropertyIndexForPrefix(t *testing.T) {
+	g := createEmploymentGraph(t)
+	g.AddNode("Person", graph.Properties{"name": 17})
+
+	queries := []string{
+		`MATCH (p:Person) WHERE p.name STARTS WITH "Person1" RETURN p.name ORDER BY p.name`,
+		`MATCH (c:Company)<-[:WORKS_AT]-(p:Person) WHERE p.name STARTS WITH "Person9" RETURN p.name, c.name ORDER BY p.name`,
+		`MATCH (p:Person) WHERE p.name STARTS WITH "" AND p.name ENDS WITH "7" RETURN p.name ORDER BY p.name`,
+		`MATCH (p:Person) WHERE p.name STARTS WITH "Nobody" RETURN p.name`,
+	}
+	scanned := make([][]Row, len(queries))
+	for i, input := range queries {
+		query, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		result, err := query.Execute(g)
+		require.NoError(t, err)
+		scanned[i] = result.Rows
+	}
+	assert.Len(t, scanned[0], 11)
+	assert.Len(t, scanned[1], 11)
+	assert.Len(t, scanned[2], 10)
+	assert.Empty(t, scanned[3])
+
+	require.NoError(t, g.CreatePropertyIndex("Person", "name"))
+
+	for i, input := range queries {
+		query, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+		require.NoError(t, err)
+		scan, ok := plan.Operators[0].(*IndexPrefixScanOperator)
+		require.True(t, ok, "expected a prefix scan for %s, got %T", input, plan.Operators[0])
+		assert.Equal(t, "p", scan.Variable)
+
+		result, err := query.Execute(g)
+		require.NoError(t, err)
+		assert.Equal(t, scanned[i], result.Rows, input)
+	}
+
+	// ENDS WITH and CONTAINS stay filters over a label scan
+	for _, input := range []string{
+		`MATCH (p:Person) WHERE p.name ENDS WITH "7" RETURN p.name`,
+		`MATCH (p:Person) WHERE p.name CONTAINS "son" RETURN p.name`,
+	} {
+		query, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+		require.NoError(t, err)
+		assert.IsType(t, &ScanOperator{}, plan.Operators[0], input)
+	}
+}
+
+func TestPlanner_ScanStopsAtLimit(t *testing.T) {
+	g := createEmploymentGraph(t)
+
+	query, err := NewParser(`MATCH (p:Person {name: "Person7"}) RETURN p LIMIT 1`).Parse()
+	require.NoError(t, err)
+
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	scan := plan.Operators[0].(*ScanOperator)
+	assert.Equal(t, 1, scan.Limit)
+	assert.NotNil(t, scan.Filter)
+
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "Person7", result.Rows[0]["p"].(*graph.Node).Properties.Map()["name"])
+
+	// WHERE conditions are folded into the scan as well
+	query, err = NewParser(`MATCH (p:Person) WHERE p.name = "Person3" OR p.name = "Person4" RETURN p.name LIMIT 5`).Parse()
+	require.NoError(t, err)
+	result, err = query.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+}
+
+// neighborCounter counts the edges IterateNeighbors hands to its callers
+type neighborCounter struct {
+	*storage.Graph
+	visited int
+}
+
+func (c *neighborCounter) IterateNeighbors(nodeID graph.NodeID, direction graph.Direction, callback func(*graph.Edge, *graph.Node) bool) error {
+	return c.Graph.IterateNeighbors(nodeID, direction, func(edge *graph.Edge, node *graph.Node) bool {
+		c.visited++
+		return callback(edge, node)
+	})
+}
+
+func TestPlanner_ExpandStopsAtLimit(t *testing.T) {
+	g := storage.NewGraph()
+	hub, _ := g.AddNode("Hub", nil)
+	for i := 0; i < 1000; i++ {
+		leaf, _ := g.AddNode("Leaf", graph.Properties{"kind": int64(i % 2)})
+		g.AddEdge(hub.ID, leaf.ID, "LINK", nil)
+	}
+	counter := &neighborCounter{Graph: g}
+
+	query, err := NewParser(`MATCH (h:Hub)-[:LINK]->(l) RETURN l LIMIT 3`).Parse()
+	require.NoError(t, err)
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	expand := plan.Operators[1].(*ExpandOperator)
+	assert.Equal(t, 3, expand.Limit)
+
+	result, err := query.Execute(counter)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 3)
+	assert.Equal(t, 3, counter.visited)
+
+	// DISTINCT needs every match to fill its LIMIT
+	counter.visited = 0
+	query, err = NewParser(`MATCH (h:Hub)-[:LINK]->(l) WITH DISTINCT l.kind AS kind RETURN kind LIMIT 2`).Parse()
+	require.NoError(t, err)
+	result, err = query.Execute(counter)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+	assert.Equal(t, 1000, counter.visited)
+}
+
+// BenchmarkAnchoredLookup compares MATCH (p:Person {name: ...}) RETURN p
+// over 100k Persons with and without a property index. The indexed lookup
+// does constant work per query; the scan grows with the label.
+func BenchmarkAnchoredLookup(b *testing.B) {
+	const people = 100000
+	g := storage.NewGraph()
+	for i := 0; i < people; i++ {
+		g.AddNode("Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)})
+	}
+
+	query, err := NewParser(`MATCH (p:Person {name: "Person54321"}) RETURN p`).Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+	run := func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			result, err := query.Execute(g)
+			if err != nil || len(result.Rows) != 1 {
+				b.Fatalf("unexpected result: %v, %v", result, err)
+			}
+		}
+	}
+
+	b.Run("scan", run)
+	if err := g.CreatePropertyIndex("Person", "name"); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("index", run)
+}
+
+// BenchmarkPrefixLookup compares WHERE p.name STARTS WITH ... over 100k
+// Persons with and without a property index. The index scan only visits
+// the matching names; the label scan visits every Person.
+func BenchmarkPrefixLookup(b *testing.B) {
+	const people = 100000
+	g := storage.NewGraph()
+	for i := 0; i < people; i++ {
+		g.AddNode("Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)})
+	}
+
+	query, err := NewParser(`MATCH (p:Person) WHERE p.name STARTS WITH "Person5432" RETURN p`).Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+	run := func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			result, err := query.Execute(g)
+			if err != nil || len(result.Rows) != 11 {
+				b.Fatalf("unexpected result: %v, %v", result, err)
+			}
+		}
+	}
+
+	b.Run("scan", run)
+	if err := g.CreatePropertyIndex("Person", "name"); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("index", run)
+}
+
+// BenchmarkSelectiveAnchor runs MATCH (a)-[:KNOWS]->(b:Person {name: ...})
+// on a graph whose KNOWS edges concentrate on a few Persons, anchored at
+// the first node as written and at the node the planner picks. The first
+// expands from every node; the planned one from the single match for b.
+func BenchmarkSelectiveAnchor(b *testing.B) {
+	const people = 20000
+	g := storage.NewGraph()
+	nodes := make([]*graph.Node, people)
+	for i := range nodes {
+		nodes[i], _ = g.AddNode("Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)})
+	}
+	for i, node := range nodes {
+		g.AddEdge(node.ID, nodes[i*7%100].ID, "KNOWS", nil)
+	}
+
+	query, err := NewParser(`MATCH (a)-[:KNOWS]->(b:Person {name: "Person42"}) RETURN a`).Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+	run := func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			result, err := query.Execute(g)
+			if err != nil || len(result.Rows) != people/100 {
+				b.Fatalf("unexpected result: %v, %v", result, err)
+			}
+		}
+	}
+
+	query.Hints = &PlannerHints{StartVariable: "a"}
+	b.Run("first", run)
+	query.Hints = nil
+	b.Run("planned", run)
+}
+
+// createResidentGraph builds Persons living in a few cities, some of them
+// with the city as a number or missing
+func createResidentGraph(t *testing.T) *storage.Graph {
+	g := storage.NewGraph()
+	for i := 0; i < 30; i++ {
+		props := graph.Properties{"name": fmt.Sprintf("Person%d", i), "city": fmt.Sprintf("City%d", i%4)}
+		switch i % 10 {
+		case 8:
+			props["city"] = 7
+		case 9:
+			delete(props, "city")
+		}
+		_, err := g.AddNode("Person", props)
+		require.NoError(t, err)
+	}
+	for i := 0; i < 5; i++ {
+		props := graph.Properties{"name": fmt.Sprintf("City%d", i), "code": float64(7 + i)}
+		_, err := g.AddNode("City", props)
+		require.NoError(t, err)
+	}
+	return g
+}
+
+func TestPlanner_HashJoin(t *testing.T) {
+	g := createResidentGraph(t)
+
+	queries := []string{
+		`MATCH (a:Person), (b:Person) WHERE a.city = b.city AND a.name < b.name RETURN a.name, b.name`,
+		`MATCH (c:City), (p:Person) WHERE p.city = c.name AND c.name != "City0" RETURN c.name, p.name`,
+		`MATCH (p:Person), (c:City) WHERE c.code = p.city RETURN p.name, c.name`,
+		`MATCH (p:Person)-[]->(c), (q:Person) WHERE q.city = p.city RETURN p.name, q.name`,
+		`MATCH (p:Person {name: "Person1"}) WITH p.city AS city MATCH (q:Person) WHERE q.city = city RETURN q.name`,
+	}
+	for _, input := range queries {
+		query, err := NewParser(input).Parse()
+		require.NoError(t, err)
+
+		query.StableOrder = true
+		plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+		require.NoError(t, err)
+		joins := 0
+		for _, op := range plan.Operators {
+			if _, ok := op.(*HashJoinOperator); ok {
+				joins++
+			}
+		}
+		assert.Equal(t, 1, joins, input)
+
+		joined, err := query.Execute(g)
+		require.NoError(t, err)
+
+		query.Hints = &PlannerHints{NoHashJoin: true}
+		nested, err := query.Execute(g)
+		require.NoError(t, err)
+		assert.Equal(t, nested, joined, input)
+	}
+
+	// Filters on the joined pattern alone run before the join, and the
+	// rest after it
+	query, err := NewParser(`MATCH (c:City), (p:Person) WHERE p.city = c.name AND p.name STARTS WITH "Person1" AND p.name > c.name RETURN p.name`).Parse()
+	require.NoError(t, err)
+	plan, err := BuildExecutionPlan(query)
+	require.NoError(t, err)
+	require.Len(t, plan.Operators, 4)
+	assert.Equal(t, "c", plan.Operators[0].(*ScanOperator).Variable)
+	join := plan.Operators[1].(*HashJoinOperator)
+	assert.Equal(t, "c.name", expressionText(join.Left))
+	assert.Equal(t, "p.city", expressionText(join.Right))
+	require.Len(t, join.Operators, 1)
+	scan := join.Operators[0].(*ScanOperator)
+	assert.Equal(t, "p", scan.Variable)
+	assert.Equal(t, `p.name STARTS WITH "Person1"`, expressionText(scan.Filter))
+	assert.Equal(t, "p.name > c.name", expressionText(plan.Operators[2].(*FilterOperator).Predicate))
+
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	var names []string
+	for _, row := range result.Rows {
+		names = append(names, row["p.name"].(string))
+	}
+	assert.ElementsMatch(t, []string{"Person1", "Person10", "Person11", "Person12", "Person13", "Person14", "Person15", "Person16", "Person17"}, names)
+}
+
+func TestPlanner_HashJoinKeys(t *testing.T) {
+	g := storage.NewGraph()
+	when := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, value := range []interface{}{1, 1.0, "1", nil, when, []graph.PropertyValue{1, 2}} {
+		props := graph.Properties{"name": fmt.Sprintf("l%d", i)}
+		if value != nil {
+			props["key"] = value
+		}
+		g.AddNode("Left", props)
+	}
+	for i, value := range []interface{}{int64(1), "2024-03-01T12:00:00Z", nil, []graph.PropertyValue{1.0, 2.0}, true} {
+		props := graph.Properties{"name": fmt.Sprintf("r%d", i)}
+		if value != nil {
+			props["key"] = value
+		}
+		g.AddNode("Right", props)
+	}
+
+	query, err := NewParser(`MATCH (l:Left), (r:Right) WHERE l.key = r.key RETURN l.name, r.name`).Parse()
+	require.NoError(t, err)
+	query.StableOrder = true
+	joined, err := query.Execute(g)
+	require.NoError(t, err)
+
+	// Keys match as the equality filter would match them
+	query.Hints = &PlannerHints{NoHashJoin: true}
+	nested, err := query.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, nested, joined)
+	assert.Len(t, joined.Rows, 5)
+}
+
+// BenchmarkHashJoin joins 10k Persons with themselves on a property with
+// 1000 values, by hash join and by the nested loop it replaces
+func BenchmarkHashJoin(b *testing.B) {
+	const people = 10000
+	g := storage.NewGraph()
+	for i := 0; i < people; i++ {
+		g.AddNode("Person", graph.Properties{"name": fmt.Sprintf("Person%d", i), "city": fmt.Sprintf("City%d", i%1000)})
+	}
+
+	query, err := NewParser(`MATCH (a:Person), (b:Person) WHERE a.city = b.city RETURN a.name, b.name`).Parse()
+	if err != nil {
+		b.Fatal(err)
+	}
+	run := func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			result, err := query.Execute(g)
+			if err != nil || len(result.Rows) != people*people/1000 {
+				b.Fatalf("unexpected result: %v, %v", len(result.Rows), err)
+			}
+		}
+	}
+
+	b.Run("hash", run)
+	query.Hints = &PlannerHints{NoHashJoin: true}
+	b.Run("nested", run)
+}
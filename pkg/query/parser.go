@@ -4,6 +4,7 @@ package query
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // Parser parses RQL queries into AST
@@ -12,14 +13,28 @@ type Parser struct {
 	current Token
 	peek    Token
 	errors  []string
+
+	// errTok and errMsg locate the first error for ErrorWithContext
+	errTok *Token
+	errMsg string
+
+	firstLine int // Line of the input within its file; see NewParserAt
 }
 
 // NewParser creates a new parser
 func NewParser(input string) *Parser {
+	return NewParserAt(input, 1)
+}
+
+// NewParserAt creates a parser for a statement that starts on the given
+// line of a larger file, so that error positions refer to that file
+func NewParserAt(input string, line int) *Parser {
 	l := NewLexer(input)
+	l.line += line - 1
 	p := &Parser{
-		lexer:  l,
-		errors: []string{},
+		lexer:     l,
+		errors:    []string{},
+		firstLine: line,
 	}
 	// Read two tokens to initialize current and peek
 	p.nextToken()
@@ -36,6 +51,13 @@ func (p *Parser) currentTokenIs(t TokenType) bool {
 	return p.current.Type == t
 }
 
+// currentWordIs reports whether the current token is word, ignoring case,
+// for words that are keywords only where the parser expects them. A
+// backtick-quoted identifier never is.
+func (p *Parser) currentWordIs(word string) bool {
+	return p.currentTokenIs(TokenIdentifier) && !p.current.Quoted && strings.EqualFold(p.current.Literal, word)
+}
+
 func (p *Parser) peekTokenIs(t TokenType) bool {
 	return p.peek.Type == t
 }
@@ -50,13 +72,48 @@ func (p *Parser) expectPeek(t TokenType) bool {
 }
 
 func (p *Parser) peekError(t TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead at line %d",
-		t, p.peek.Type, p.peek.Line)
-	p.errors = append(p.errors, msg)
+	p.errorAt(p.peek, fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peek.Type))
 }
 
 func (p *Parser) error(msg string) {
-	p.errors = append(p.errors, fmt.Sprintf("%s at line %d", msg, p.current.Line))
+	p.errorAt(p.current, msg)
+}
+
+// errorAt records msg with the position of tok
+func (p *Parser) errorAt(tok Token, msg string) {
+	p.errors = append(p.errors, fmt.Sprintf("%s at line %d, column %d", msg, tok.Line, tok.StartColumn))
+	if p.errTok == nil {
+		p.errTok = &tok
+		p.errMsg = msg
+	}
+}
+
+// ErrorWithContext describes the first parse error on three lines: the
+// message with its position, the input line, and a caret under the
+// offending token. It returns "" when parsing succeeded.
+func (p *Parser) ErrorWithContext() string {
+	if p.errTok == nil {
+		return ""
+	}
+	line := ""
+	lines := strings.Split(p.lexer.input, "\n")
+	if i := p.errTok.Line - p.firstLine; i >= 0 && i < len(lines) {
+		line = strings.TrimRight(lines[i], "\r")
+	}
+
+	// Keep tabs so that the caret lines up with the input
+	var caret strings.Builder
+	for i := 0; i < p.errTok.StartColumn-1; i++ {
+		if i < len(line) && line[i] == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	caret.WriteByte('^')
+
+	return fmt.Sprintf("line %d, column %d: %s\n%s\n%s",
+		p.errTok.Line, p.errTok.StartColumn, p.errMsg, line, caret.String())
 }
 
 // Errors returns parsing errors
@@ -64,26 +121,166 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
-// Parse parses the entire query
+// Parse parses the entire query. Errors give the line and column of the
+// token where parsing failed; see also ErrorWithContext.
 func (p *Parser) Parse() (*Query, error) {
+	query, err := p.parseUnion()
+	if comment := p.lexer.commentStart; comment != nil {
+		// Whatever the parser made of the rest of the input, it was
+		// meant to be read
+		p.errorAt(*comment, "unterminated block comment")
+		return nil, p.lexer.Err()
+	}
+	if err != nil {
+		p.error(err.Error())
+		return nil, fmt.Errorf("%w at line %d, column %d", err, p.current.Line, p.current.StartColumn)
+	}
+	if len(p.errors) > 0 {
+		return nil, fmt.Errorf("parse errors: %v", p.errors)
+	}
+	return query, nil
+}
+
+// parseUnion parses queries joined by UNION or UNION ALL. The two may
+// not be mixed.
+func (p *Parser) parseUnion() (*Query, error) {
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; p.currentTokenIs(TokenUnion); i++ {
+		p.nextToken() // consume UNION
+		distinct := true
+		if p.currentWordIs("ALL") {
+			distinct = false
+			p.nextToken()
+		}
+		if i > 0 && distinct != query.Union.Distinct {
+			return nil, fmt.Errorf("cannot mix UNION and UNION ALL")
+		}
+		if p.currentTokenIs(TokenEOF) {
+			return nil, fmt.Errorf("expected a query after UNION")
+		}
+
+		right, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		union := NewQuery()
+		union.Union = &UnionQuery{Left: query, Right: right, Distinct: distinct}
+		query = union
+	}
+	return query, nil
+}
+
+// parseQuery parses the clauses of a query in order, up to the end of the
+// input or a UNION
+func (p *Parser) parseQuery() (*Query, error) {
 	query := NewQuery()
 
-	// Parse MATCH clause
-	if p.currentTokenIs(TokenMatch) {
-		match, err := p.parseMatchClause()
+	// Parse SHOW (standalone metadata statement)
+	if p.currentTokenIs(TokenShow) {
+		show, err := p.parseShowClause()
 		if err != nil {
 			return nil, err
 		}
-		query.Match = match
+		query.Show = show
+		if !p.currentTokenIs(TokenEOF) {
+			return nil, fmt.Errorf("unexpected %s after SHOW %s", describeToken(p.current), show.Target)
+		}
+		return query, nil
 	}
 
-	// Parse WHERE clause
-	if p.currentTokenIs(TokenWhere) {
-		where, err := p.parseWhereClause()
+	// Parse CREATE (standalone; see CreateClause)
+	if p.currentTokenIs(TokenCreate) {
+		create, err := p.parseCreateClause()
+		if err != nil {
+			return nil, err
+		}
+		query.Create = create
+		if !p.currentTokenIs(TokenEOF) {
+			return nil, fmt.Errorf("unexpected %s after CREATE pattern", describeToken(p.current))
+		}
+		return query, nil
+	}
+
+	// Parse CALL (standalone procedure invocation)
+	if p.currentTokenIs(TokenCall) {
+		call, err := p.parseCallClause()
+		if err != nil {
+			return nil, err
+		}
+		query.Call = call
+	}
+
+	// Parse the query parts. Each WITH ends a part and starts the next.
+	for {
+		// Parse MATCH clause
+		if p.currentTokenIs(TokenMatch) {
+			match, err := p.parseMatchClause()
+			if err != nil {
+				return nil, err
+			}
+			query.Match = match
+		}
+
+		// Parse USING INDEX hint
+		if p.currentTokenIs(TokenUsing) {
+			hints, err := p.parseUsingClause()
+			if err != nil {
+				return nil, err
+			}
+			query.Hints = hints
+		}
+
+		// Parse AS OF TIMESTAMP clause
+		if p.currentTokenIs(TokenAsOf) {
+			temporal, err := p.parseAsOfClause()
+			if err != nil {
+				return nil, err
+			}
+			query.Temporal = temporal
+		}
+
+		// Parse WHERE clause
+		if p.currentTokenIs(TokenWhere) {
+			where, err := p.parseWhereClause()
+			if err != nil {
+				return nil, err
+			}
+			query.Where = where
+		}
+
+		if !p.currentTokenIs(TokenWith) {
+			break
+		}
+		with, err := p.parseWithClause()
+		if err != nil {
+			return nil, err
+		}
+		query.With = with
+		next := NewQuery()
+		next.Input = query
+		query = next
+	}
+
+	// AS OF applies to the whole query, whichever part it follows
+	for part := query.Input; part != nil && query.Temporal == nil; part = part.Input {
+		query.Temporal = part.Temporal
+	}
+
+	// A CALL after MATCH runs for each match and ends the query
+	if p.currentTokenIs(TokenCall) && query.Call == nil {
+		call, err := p.parseCallClause()
 		if err != nil {
 			return nil, err
 		}
-		query.Where = where
+		query.Call = call
+		if !p.currentTokenIs(TokenEOF) {
+			return nil, fmt.Errorf("unexpected %s after CALL", describeToken(p.current))
+		}
+		return query, nil
 	}
 
 	// Parse RETURN clause
@@ -95,6 +292,15 @@ func (p *Parser) Parse() (*Query, error) {
 		query.Return = ret
 	}
 
+	// Parse ORDER BY clause
+	if p.currentTokenIs(TokenOrderBy) {
+		orderBy, err := p.parseOrderByClause()
+		if err != nil {
+			return nil, err
+		}
+		query.OrderBy = orderBy
+	}
+
 	// Parse LIMIT clause
 	if p.currentTokenIs(TokenLimit) {
 		limit, err := p.parseLimitClause()
@@ -104,14 +310,98 @@ func (p *Parser) Parse() (*Query, error) {
 		query.Limit = &limit
 	}
 
-	if len(p.errors) > 0 {
-		return nil, fmt.Errorf("parse errors: %v", p.errors)
+	if !p.currentTokenIs(TokenEOF) && !p.currentTokenIs(TokenUnion) {
+		return nil, fmt.Errorf("unexpected %s", describeToken(p.current))
 	}
-
 	return query, nil
 }
 
-// parseMatchClause parses MATCH (a)-[]->(b)
+// describeToken names a token for error messages
+func describeToken(tok Token) string {
+	switch tok.Type {
+	case TokenEOF:
+		return "end of query"
+	case TokenString:
+		return "string " + strconv.Quote(tok.Literal)
+	}
+	return fmt.Sprintf("%q", tok.Literal)
+}
+
+// parseShowClause parses SHOW LABELS, SHOW RELATIONSHIP TYPES, SHOW
+// INDEXES or SHOW CONSTRAINTS. The target words are not reserved.
+func (p *Parser) parseShowClause() (*ShowClause, error) {
+	p.nextToken() // consume SHOW
+
+	targets := map[string]ShowTarget{
+		"LABELS":       ShowLabels,
+		"RELATIONSHIP": ShowRelationshipTypes,
+		"INDEXES":      ShowIndexes,
+		"CONSTRAINTS":  ShowConstraints,
+	}
+	target, ok := targets[strings.ToUpper(p.current.Literal)]
+	if !p.currentTokenIs(TokenIdentifier) || p.current.Quoted || !ok {
+		return nil, fmt.Errorf("expected LABELS, RELATIONSHIP TYPES, INDEXES or CONSTRAINTS after SHOW")
+	}
+	p.nextToken()
+
+	if target == ShowRelationshipTypes {
+		if !p.currentWordIs("TYPES") {
+			return nil, fmt.Errorf("expected TYPES after SHOW RELATIONSHIP")
+		}
+		p.nextToken()
+	}
+	return &ShowClause{Target: target}, nil
+}
+
+// parseCallClause parses CALL namespace.procedure(arg, ...)
+func (p *Parser) parseCallClause() (*CallClause, error) {
+	if !p.currentTokenIs(TokenCall) {
+		return nil, fmt.Errorf("expected CALL")
+	}
+	p.nextToken()
+
+	if !p.currentTokenIs(TokenIdentifier) {
+		return nil, fmt.Errorf("expected procedure name after CALL")
+	}
+	name := p.current.Literal
+	p.nextToken()
+
+	for p.currentTokenIs(TokenDot) {
+		p.nextToken()
+		if !p.currentTokenIs(TokenIdentifier) {
+			return nil, fmt.Errorf("expected name after . in procedure name")
+		}
+		name += "." + p.current.Literal
+		p.nextToken()
+	}
+
+	call := &CallClause{Procedure: name, Args: make([]Expression, 0)}
+
+	if !p.currentTokenIs(TokenLeftParen) {
+		return nil, fmt.Errorf("expected ( after procedure name")
+	}
+	p.nextToken()
+
+	for !p.currentTokenIs(TokenRightParen) {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		if p.currentTokenIs(TokenComma) {
+			p.nextToken()
+		} else if !p.currentTokenIs(TokenRightParen) {
+			return nil, fmt.Errorf("expected , or ) in procedure arguments")
+		}
+	}
+	p.nextToken()
+
+	return call, nil
+}
+
+// parseMatchClause parses MATCH (a)-[]->(b), (c), each pattern optionally
+// bound to a path variable
 func (p *Parser) parseMatchClause() (*MatchClause, error) {
 	if !p.currentTokenIs(TokenMatch) {
 		return nil, fmt.Errorf("expected MATCH")
@@ -122,14 +412,116 @@ func (p *Parser) parseMatchClause() (*MatchClause, error) {
 		Patterns: make([]Pattern, 0),
 	}
 
-	// Parse patterns (for now, just one pattern)
-	pattern, err := p.parsePattern()
+	for {
+		// Path variable: p = (a)-->(b)
+		pathVar := ""
+		if p.currentTokenIs(TokenIdentifier) && p.peekTokenIs(TokenEqual) {
+			pathVar = p.current.Literal
+			p.nextToken() // consume variable
+			p.nextToken() // consume =
+		}
+
+		pattern, err := p.parsePattern()
+		if err != nil {
+			return nil, err
+		}
+		pattern.Variable = pathVar
+		match.Patterns = append(match.Patterns, *pattern)
+
+		if !p.currentTokenIs(TokenComma) {
+			return match, nil
+		}
+		p.nextToken()
+	}
+}
+
+// parseCreateClause parses CREATE (a:Label {...})-[:TYPE {...}]->(b), ...
+// Every relationship needs a type and a direction and spans one hop.
+func (p *Parser) parseCreateClause() (*CreateClause, error) {
+	p.nextToken() // consume CREATE
+
+	create := &CreateClause{Patterns: make([]Pattern, 0)}
+	for {
+		pattern, err := p.parsePattern()
+		if err != nil {
+			return nil, err
+		}
+		for _, edge := range pattern.Edges {
+			switch {
+			case edge.Type == "":
+				return nil, fmt.Errorf("relationships in CREATE need a type")
+			case edge.Direction == DirectionBoth:
+				return nil, fmt.Errorf("relationships in CREATE need a direction")
+			case edge.MinHops != nil:
+				return nil, fmt.Errorf("variable-length relationships cannot be created")
+			}
+		}
+		create.Patterns = append(create.Patterns, *pattern)
+
+		if !p.currentTokenIs(TokenComma) {
+			return create, nil
+		}
+		p.nextToken()
+	}
+}
+
+// parseUsingClause parses USING INDEX n:Label(property)
+func (p *Parser) parseUsingClause() (*PlannerHints, error) {
+	p.nextToken()
+	if !p.currentTokenIs(TokenIndex) {
+		return nil, fmt.Errorf("expected INDEX after USING")
+	}
+	p.nextToken()
+
+	if !p.currentTokenIs(TokenIdentifier) {
+		return nil, fmt.Errorf("expected variable after USING INDEX")
+	}
+	hints := &PlannerHints{StartVariable: p.current.Literal}
+	p.nextToken()
+
+	if !p.currentTokenIs(TokenColon) {
+		return nil, fmt.Errorf("expected : after index variable")
+	}
+	p.nextToken()
+	if !p.currentTokenIs(TokenIdentifier) {
+		return nil, fmt.Errorf("expected label in index hint")
+	}
+	p.nextToken()
+
+	if !p.currentTokenIs(TokenLeftParen) {
+		return nil, fmt.Errorf("expected ( after index label")
+	}
+	p.nextToken()
+	if !p.currentTokenIs(TokenIdentifier) {
+		return nil, fmt.Errorf("expected property in index hint")
+	}
+	p.nextToken()
+	if !p.currentTokenIs(TokenRightParen) {
+		return nil, fmt.Errorf("expected ) to close index hint")
+	}
+	p.nextToken()
+
+	return hints, nil
+}
+
+// parseAsOfClause parses AS OF TIMESTAMP "2021-06-01"
+func (p *Parser) parseAsOfClause() (*TemporalFilter, error) {
+	p.nextToken()
+	if !p.currentTokenIs(TokenTimestamp) {
+		return nil, fmt.Errorf("expected TIMESTAMP after AS OF")
+	}
+	p.nextToken()
+
+	if !p.currentTokenIs(TokenString) {
+		return nil, fmt.Errorf("expected timestamp string after AS OF TIMESTAMP")
+	}
+	at, err := parseTimestamp(p.current.Literal)
 	if err != nil {
 		return nil, err
 	}
-	match.Patterns = append(match.Patterns, *pattern)
+	p.nextToken()
 
-	return match, nil
+	return &TemporalFilter{At: at}, nil
 }
 
 // parsePattern parses (a)-[r:TYPE]->(b)
@@ -209,22 +601,32 @@ func (p *Parser) parseNodePattern() (*NodePattern, error) {
 	return node, nil
 }
 
-// parseEdgePattern parses -[]-> or <-[:TYPE]- or -[]-
+// parseEdgePattern parses -[]-> or <-[:TYPE]- or -[]-, or one without
+// brackets such as --> or <--
 func (p *Parser) parseEdgePattern() (*EdgePattern, error) {
-	edge := &EdgePattern{}
-
-	// Determine direction by looking at the start
+	edge := &EdgePattern{Direction: DirectionUnset}
+
+	// The opening token decides half of the direction; the closing token
+	// decides the rest:
+	//
+	//	-[]-   DirectionBoth
+	//	-[]->  DirectionOut
+	//	<-[]-  DirectionIn
+	//	<-[]-> DirectionBoth
 	if p.currentTokenIs(TokenLeftArrow) {
-		// <-[...]
 		edge.Direction = DirectionIn
 		p.nextToken() // consume <-
 	} else if p.currentTokenIs(TokenDash) {
-		// -[...]
 		p.nextToken() // consume -
 	} else {
 		return nil, fmt.Errorf("expected - or <- to start edge pattern")
 	}
 
+	// Without brackets the edge has no variable, type or properties
+	if p.currentTokenIs(TokenArrow) || p.currentTokenIs(TokenDash) {
+		return edge, p.closeEdgePattern(edge)
+	}
+
 	// Parse [...]
 	if !p.currentTokenIs(TokenLeftBracket) {
 		return nil, fmt.Errorf("expected [ in edge pattern")
@@ -246,33 +648,89 @@ func (p *Parser) parseEdgePattern() (*EdgePattern, error) {
 		p.nextToken()
 	}
 
+	// Variable-length: *, *2, *1..3, *2.., *..3
+	if p.currentTokenIs(TokenStar) {
+		if err := p.parseHopRange(edge); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse inline properties (optional) {since: 2020}
+	if p.currentTokenIs(TokenLeftBrace) {
+		props, err := p.parseProperties()
+		if err != nil {
+			return nil, err
+		}
+		edge.Properties = props
+	}
+
 	if !p.currentTokenIs(TokenRightBracket) {
 		return nil, fmt.Errorf("expected ] to close edge pattern")
 	}
 	p.nextToken()
 
-	// Check for outgoing arrow
-	if p.currentTokenIs(TokenArrow) {
-		// If we already saw <-, this is bidirectional
-		if edge.Direction == DirectionIn {
-			edge.Direction = DirectionBoth
-		} else {
-			// Just -, now we see ->
-			edge.Direction = DirectionOut
+	if err := p.closeEdgePattern(edge); err != nil {
+		return nil, err
+	}
+	return edge, nil
+}
+
+// closeEdgePattern consumes the - or -> ending an edge pattern and sets
+// the edge's direction from it
+func (p *Parser) closeEdgePattern(edge *EdgePattern) error {
+	switch {
+	case p.currentTokenIs(TokenArrow) && edge.Direction == DirectionIn:
+		// Arrows at both ends point both ways
+		edge.Direction = DirectionBoth
+	case p.currentTokenIs(TokenArrow):
+		edge.Direction = DirectionOut
+	case p.currentTokenIs(TokenDash) && edge.Direction == DirectionUnset:
+		edge.Direction = DirectionBoth
+	case p.currentTokenIs(TokenDash):
+		// <-[]- keeps DirectionIn
+	default:
+		return fmt.Errorf("expected - or -> to close edge pattern")
+	}
+	p.nextToken()
+	return nil
+}
+
+// parseHopRange parses the *min..max suffix of a variable-length edge
+func (p *Parser) parseHopRange(edge *EdgePattern) error {
+	p.nextToken() // consume *
+
+	minHops := 1
+	edge.MinHops = &minHops
+
+	if p.currentTokenIs(TokenNumber) {
+		n, err := strconv.Atoi(p.current.Literal)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid hop count: %s", p.current.Literal)
 		}
+		minHops = n
 		p.nextToken()
-	} else if p.currentTokenIs(TokenDash) {
-		// -[]- (bidirectional/undirected)
-		if edge.Direction == 0 {
-			edge.Direction = DirectionBoth
+
+		if !p.currentTokenIs(TokenDotDot) {
+			// Exact length: *n
+			maxHops := n
+			edge.MaxHops = &maxHops
+			return nil
 		}
+	}
+
+	if p.currentTokenIs(TokenDotDot) {
 		p.nextToken()
-	} else if edge.Direction == 0 {
-		// Just -[] with no arrow, treat as both
-		edge.Direction = DirectionBoth
+		if p.currentTokenIs(TokenNumber) {
+			n, err := strconv.Atoi(p.current.Literal)
+			if err != nil || n < minHops {
+				return fmt.Errorf("invalid hop range: %d..%s", minHops, p.current.Literal)
+			}
+			edge.MaxHops = &n
+			p.nextToken()
+		}
 	}
 
-	return edge, nil
+	return nil
 }
 
 // parseProperties parses {key: value, ...}
@@ -296,7 +754,18 @@ func (p *Parser) parseProperties() (map[string]interface{}, error) {
 		}
 		p.nextToken()
 
-		valueExpr, err := p.parseLiteral()
+		var valueExpr Expression
+		var err error
+		switch {
+		case p.currentTokenIs(TokenLeftBracket):
+			valueExpr, err = p.parseListLiteral()
+		case p.currentTokenIs(TokenLeftBrace):
+			valueExpr, err = p.parseMapLiteral()
+		case p.currentTokenIs(TokenIdentifier) && p.peekTokenIs(TokenLeftParen):
+			valueExpr, err = p.parseFunctionCall()
+		default:
+			valueExpr, err = p.parseLiteral()
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -363,7 +832,7 @@ func (p *Parser) parseOrExpression() (Expression, error) {
 }
 
 func (p *Parser) parseAndExpression() (Expression, error) {
-	left, err := p.parseComparisonExpression()
+	left, err := p.parseNotExpression()
 	if err != nil {
 		return nil, err
 	}
@@ -371,7 +840,7 @@ func (p *Parser) parseAndExpression() (Expression, error) {
 	for p.currentTokenIs(TokenAnd) {
 		op := p.current.Literal
 		p.nextToken()
-		right, err := p.parseComparisonExpression()
+		right, err := p.parseNotExpression()
 		if err != nil {
 			return nil, err
 		}
@@ -381,8 +850,22 @@ func (p *Parser) parseAndExpression() (Expression, error) {
 	return left, nil
 }
 
+// parseNotExpression parses NOT, which binds tighter than AND but looser
+// than comparisons: NOT a.x = 1 AND b is (NOT (a.x = 1)) AND b
+func (p *Parser) parseNotExpression() (Expression, error) {
+	if !p.currentTokenIs(TokenNot) {
+		return p.parseComparisonExpression()
+	}
+	p.nextToken()
+	operand, err := p.parseNotExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &UnaryExpr{Operator: "NOT", Operand: operand}, nil
+}
+
 func (p *Parser) parseComparisonExpression() (Expression, error) {
-	left, err := p.parsePrimaryExpression()
+	left, err := p.parseAdditiveExpression()
 	if err != nil {
 		return nil, err
 	}
@@ -392,13 +875,73 @@ func (p *Parser) parseComparisonExpression() (Expression, error) {
 		p.currentTokenIs(TokenGreater) || p.currentTokenIs(TokenGreaterEqual) {
 		op := p.current.Literal
 		p.nextToken()
-		right, err := p.parsePrimaryExpression()
+		right, err := p.parseAdditiveExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpr{Left: left, Operator: op, Right: right}, nil
+	}
+
+	if p.currentTokenIs(TokenContains) || p.currentTokenIs(TokenStartsWith) || p.currentTokenIs(TokenEndsWith) {
+		op := p.current.Type.String()
+		p.nextToken()
+		right, err := p.parseAdditiveExpression()
 		if err != nil {
 			return nil, err
 		}
 		return &BinaryExpr{Left: left, Operator: op, Right: right}, nil
 	}
 
+	if p.currentTokenIs(TokenIn) {
+		p.nextToken()
+		right, err := p.parseAdditiveExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpr{Left: left, Operator: "IN", Right: right}, nil
+	}
+
+	return left, nil
+}
+
+// parseAdditiveExpression parses + and -, which bind tighter than
+// comparisons: a.x + 1 > b.x is (a.x + 1) > b.x
+func (p *Parser) parseAdditiveExpression() (Expression, error) {
+	left, err := p.parseMultiplicativeExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentTokenIs(TokenPlus) || p.currentTokenIs(TokenDash) {
+		op := p.current.Literal
+		p.nextToken()
+		right, err := p.parseMultiplicativeExpression()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Left: left, Operator: op, Right: right}
+	}
+
+	return left, nil
+}
+
+// parseMultiplicativeExpression parses *, / and %
+func (p *Parser) parseMultiplicativeExpression() (Expression, error) {
+	left, err := p.parsePrimaryExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.currentTokenIs(TokenStar) || p.currentTokenIs(TokenSlash) || p.currentTokenIs(TokenPercent) {
+		op := p.current.Literal
+		p.nextToken()
+		right, err := p.parsePrimaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Left: left, Operator: op, Right: right}
+	}
+
 	return left, nil
 }
 
@@ -411,9 +954,66 @@ func (p *Parser) parsePrimaryExpression() (Expression, error) {
 		if !p.currentTokenIs(TokenIdentifier) {
 			return nil, fmt.Errorf("expected property name after .")
 		}
-		prop := p.current.Literal
+		access := &PropertyAccess{Variable: variable, Property: p.current.Literal}
+		p.nextToken()
+
+		// Namespaced function call: path.length(p)
+		if p.currentTokenIs(TokenLeftParen) {
+			p.nextToken() // consume (
+			return p.parseCallArgs(variable + "." + access.Property)
+		}
+
+		// Nested map keys: p.address.city
+		for p.currentTokenIs(TokenDot) {
+			p.nextToken()
+			if !p.currentTokenIs(TokenIdentifier) {
+				return nil, fmt.Errorf("expected key name after .")
+			}
+			access.Path = append(access.Path, p.current.Literal)
+			p.nextToken()
+		}
+		return access, nil
+	}
+
+	// List literal: ["a", "b"]
+	if p.currentTokenIs(TokenLeftBracket) {
+		return p.parseListLiteral()
+	}
+
+	// Map literal: {city: "SF"}
+	if p.currentTokenIs(TokenLeftBrace) {
+		return p.parseMapLiteral()
+	}
+
+	// Function call: name(args)
+	if p.currentTokenIs(TokenIdentifier) && p.peekTokenIs(TokenLeftParen) {
+		return p.parseFunctionCall()
+	}
+
+	// Parenthesized expression: (a.x > 1 OR b)
+	if p.currentTokenIs(TokenLeftParen) && !p.atPatternExpression() {
+		p.nextToken()
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if !p.currentTokenIs(TokenRightParen) {
+			return nil, fmt.Errorf("expected ) after expression, got %s", p.current.Literal)
+		}
 		p.nextToken()
-		return &PropertyAccess{Variable: variable, Property: prop}, nil
+		return expr, nil
+	}
+
+	// Pattern expression: (p)-[:KNOWS]->()
+	if p.currentTokenIs(TokenLeftParen) {
+		pattern, err := p.parsePattern()
+		if err != nil {
+			return nil, err
+		}
+		if len(pattern.Edges) == 0 {
+			return nil, fmt.Errorf("expected relationship in pattern expression")
+		}
+		return &PatternExpression{Pattern: *pattern}, nil
 	}
 
 	// Identifier
@@ -423,11 +1023,173 @@ func (p *Parser) parsePrimaryExpression() (Expression, error) {
 		return id, nil
 	}
 
+	// Parameter: $name
+	if p.currentTokenIs(TokenParameter) {
+		param := &Parameter{Name: p.current.Literal}
+		p.nextToken()
+		return param, nil
+	}
+
 	// Literal
 	return p.parseLiteral()
 }
 
+// atPatternExpression reports whether the ( at the current token opens a
+// pattern rather than a parenthesized expression, by checking whether a
+// node pattern can be parsed from here. A lone (p) is therefore a pattern
+// missing its relationship. The parser position is restored either way.
+func (p *Parser) atPatternExpression() bool {
+	lexer, current, peek, errCount := *p.lexer, p.current, p.peek, len(p.errors)
+	defer func() {
+		*p.lexer, p.current, p.peek, p.errors = lexer, current, peek, p.errors[:errCount]
+	}()
+
+	_, err := p.parseNodePattern()
+	return err == nil
+}
+
+// parseListLiteral parses [expr, ...]
+func (p *Parser) parseListLiteral() (Expression, error) {
+	p.nextToken() // consume [
+
+	list := &ListLiteral{Items: make([]Expression, 0)}
+	for !p.currentTokenIs(TokenRightBracket) {
+		item, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, item)
+
+		if p.currentTokenIs(TokenComma) {
+			p.nextToken()
+		} else if !p.currentTokenIs(TokenRightBracket) {
+			return nil, fmt.Errorf("expected , or ] in list")
+		}
+	}
+	p.nextToken()
+
+	return list, nil
+}
+
+// parseMapLiteral parses {key: expr, ...}
+func (p *Parser) parseMapLiteral() (Expression, error) {
+	p.nextToken() // consume {
+
+	m := &MapLiteral{Entries: make(map[string]Expression)}
+	for !p.currentTokenIs(TokenRightBrace) {
+		if !p.currentTokenIs(TokenIdentifier) {
+			return nil, fmt.Errorf("expected key name in map")
+		}
+		key := p.current.Literal
+		if _, dup := m.Entries[key]; dup {
+			return nil, fmt.Errorf("duplicate key %s in map", key)
+		}
+		p.nextToken()
+
+		if !p.currentTokenIs(TokenColon) {
+			return nil, fmt.Errorf("expected : after map key")
+		}
+		p.nextToken()
+
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		m.Entries[key] = value
+
+		if p.currentTokenIs(TokenComma) {
+			p.nextToken()
+		} else if !p.currentTokenIs(TokenRightBrace) {
+			return nil, fmt.Errorf("expected , or } in map")
+		}
+	}
+	p.nextToken()
+
+	return m, nil
+}
+
+// parseFunctionCall parses name(arg, ...). point({lat: .., lon: ..}) is
+// parsed into a PointLiteral.
+func (p *Parser) parseFunctionCall() (Expression, error) {
+	spelling := p.current.Literal
+	p.nextToken() // consume name
+	p.nextToken() // consume (
+
+	if strings.EqualFold(spelling, "point") {
+		return p.parsePointLiteral()
+	}
+	return p.parseCallArgs(spelling)
+}
+
+// parseCallArgs parses the arguments of a call to the function spelled
+// as given, after its (
+func (p *Parser) parseCallArgs(spelling string) (Expression, error) {
+	name := strings.ToLower(spelling)
+	call := &FunctionCall{Name: name, Spelling: spelling, Args: make([]Expression, 0)}
+	for !p.currentTokenIs(TokenRightParen) {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+
+		if p.currentTokenIs(TokenComma) {
+			p.nextToken()
+		} else if !p.currentTokenIs(TokenRightParen) {
+			return nil, fmt.Errorf("expected , or ) in arguments to %s", name)
+		}
+	}
+	p.nextToken()
+
+	return call, nil
+}
+
+// parsePointLiteral parses the {lat: .., lon: ..} argument of point(...).
+// latitude and longitude are accepted as longer spellings of the keys.
+func (p *Parser) parsePointLiteral() (Expression, error) {
+	props, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+	if !p.currentTokenIs(TokenRightParen) {
+		return nil, fmt.Errorf("expected ) after point coordinates")
+	}
+	p.nextToken()
+
+	for long, short := range map[string]string{"latitude": "lat", "longitude": "lon"} {
+		if v, ok := props[long]; ok {
+			if _, dup := props[short]; dup {
+				return nil, fmt.Errorf("point has both %s and %s", short, long)
+			}
+			props[short] = v
+			delete(props, long)
+		}
+	}
+	lat, okLat := props["lat"]
+	lon, okLon := props["lon"]
+	if !okLat || !okLon || len(props) != 2 || !isNumber(lat) || !isNumber(lon) {
+		return nil, fmt.Errorf("point requires numeric lat and lon")
+	}
+	return &PointLiteral{Lat: toFloat(lat), Lon: toFloat(lon)}, nil
+}
+
 func (p *Parser) parseLiteral() (Expression, error) {
+	// Negative number
+	if p.currentTokenIs(TokenDash) && p.peekTokenIs(TokenNumber) {
+		p.nextToken()
+		lit, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		switch v := lit.(*Literal).Value.(type) {
+		case int:
+			lit.(*Literal).Value = -v
+		case float64:
+			lit.(*Literal).Value = -v
+		}
+		return lit, nil
+	}
+
 	if p.currentTokenIs(TokenString) {
 		lit := &Literal{Value: p.current.Literal}
 		p.nextToken()
@@ -461,6 +1223,12 @@ func (p *Parser) parseLiteral() (Expression, error) {
 		return lit, nil
 	}
 
+	if p.currentTokenIs(TokenNull) {
+		lit := &Literal{Value: nil}
+		p.nextToken()
+		return lit, nil
+	}
+
 	return nil, fmt.Errorf("unexpected token: %s", p.current.Type)
 }
 
@@ -475,15 +1243,34 @@ func (p *Parser) parseReturnClause() (*ReturnClause, error) {
 		Items: make([]ReturnItem, 0),
 	}
 
+	// RETURN * returns every bound variable and cannot be mixed with items
+	if p.currentTokenIs(TokenStar) {
+		p.nextToken()
+		switch p.current.Type {
+		case TokenOrderBy, TokenLimit, TokenUnion, TokenEOF:
+		default:
+			return nil, fmt.Errorf("unexpected %q after RETURN *", p.current.Literal)
+		}
+		ret.Star = true
+		return ret, nil
+	}
+
 	for {
-		expr, err := p.parseReturnExpression()
+		item, err := p.parseProjectionItem()
 		if err != nil {
 			return nil, err
 		}
-
-		item := ReturnItem{Expr: expr}
 		ret.Items = append(ret.Items, item)
 
+		// An item ends at a comma or the next clause. Anything else means
+		// the expression stopped early, e.g. at an operator it cannot
+		// combine, and must not be mistaken for the end of RETURN.
+		switch p.current.Type {
+		case TokenComma, TokenOrderBy, TokenLimit, TokenUnion, TokenEOF:
+		default:
+			return nil, fmt.Errorf("unexpected %q in RETURN", p.current.Literal)
+		}
+
 		if !p.currentTokenIs(TokenComma) {
 			break
 		}
@@ -493,9 +1280,107 @@ func (p *Parser) parseReturnClause() (*ReturnClause, error) {
 	return ret, nil
 }
 
+// parseWithClause parses WITH [DISTINCT] items [WHERE expr]. Items other
+// than plain variables need an alias to be referred to afterwards.
+func (p *Parser) parseWithClause() (*WithClause, error) {
+	p.nextToken() // consume WITH
+
+	with := &WithClause{}
+	if p.currentWordIs("DISTINCT") &&
+		!p.peekTokenIs(TokenComma) && !p.peekTokenIs(TokenDot) {
+		with.Distinct = true
+		p.nextToken()
+	}
+
+	for {
+		item, err := p.parseProjectionItem()
+		if err != nil {
+			return nil, err
+		}
+		if item.variable() == "" {
+			return nil, fmt.Errorf("expression %s in WITH must be aliased", expressionText(item.Expr))
+		}
+		with.Items = append(with.Items, item)
+
+		if !p.currentTokenIs(TokenComma) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if p.currentTokenIs(TokenWhere) {
+		where, err := p.parseWhereClause()
+		if err != nil {
+			return nil, err
+		}
+		with.Where = where
+	}
+	return with, nil
+}
+
+// parseProjectionItem parses a RETURN or WITH item: an expression with an
+// optional alias, as in size(...) AS friends
+func (p *Parser) parseProjectionItem() (ReturnItem, error) {
+	expr, err := p.parseReturnExpression()
+	if err != nil {
+		return ReturnItem{}, err
+	}
+
+	item := ReturnItem{Expr: expr}
+	if p.currentWordIs("AS") {
+		p.nextToken()
+		if !p.currentTokenIs(TokenIdentifier) {
+			return ReturnItem{}, fmt.Errorf("expected alias after AS")
+		}
+		item.Alias = p.current.Literal
+		p.nextToken()
+	}
+	return item, nil
+}
+
+// parseReturnExpression parses a RETURN or ORDER BY item, which may be any
+// expression including comparisons such as p.age >= 18
 func (p *Parser) parseReturnExpression() (Expression, error) {
-	// Property access or identifier
-	return p.parsePrimaryExpression()
+	return p.parseExpression()
+}
+
+// parseOrderByClause parses ORDER BY expr [ASC|DESC], ...
+func (p *Parser) parseOrderByClause() (*OrderByClause, error) {
+	if !p.currentTokenIs(TokenOrderBy) || !strings.EqualFold(p.current.Literal, "ORDER") {
+		return nil, fmt.Errorf("expected ORDER BY")
+	}
+	p.nextToken()
+	if !p.currentTokenIs(TokenOrderBy) || !strings.EqualFold(p.current.Literal, "BY") {
+		return nil, fmt.Errorf("expected BY after ORDER")
+	}
+	p.nextToken()
+
+	orderBy := &OrderByClause{}
+	for {
+		expr, err := p.parseReturnExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		field := OrderByField{Expr: expr}
+		if p.currentTokenIs(TokenIdentifier) && !p.current.Quoted {
+			switch strings.ToUpper(p.current.Literal) {
+			case "ASC", "ASCENDING":
+				p.nextToken()
+			case "DESC", "DESCENDING":
+				field.Descending = true
+				p.nextToken()
+			}
+		}
+		orderBy.Fields = append(orderBy.Fields, field)
+
+		if !p.currentTokenIs(TokenComma) {
+			break
+		}
+		p.nextToken() // consume comma
+	}
+
+	return orderBy, nil
 }
 
 // parseLimitClause parses LIMIT n
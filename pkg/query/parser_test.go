@@ -2,6 +2,7 @@ package query
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -175,6 +176,49 @@ func TestParser_InlineProperties(t *testing.T) {
 	assert.Equal(t, 30, node.Properties["age"])
 }
 
+func TestParser_MultiplePatterns(t *testing.T) {
+	query, err := NewParser(`MATCH (a:Person)-[:KNOWS]->(b), p = (b)-[:LIVES_IN]->(c:City), (d) RETURN a, d`).Parse()
+	require.NoError(t, err)
+
+	patterns := query.Match.Patterns
+	require.Len(t, patterns, 3)
+	assert.Equal(t, "", patterns[0].Variable)
+	assert.Equal(t, "KNOWS", patterns[0].Edges[0].Type)
+	assert.Equal(t, "p", patterns[1].Variable)
+	assert.Equal(t, "b", patterns[1].Nodes[0].Variable)
+	assert.Equal(t, "City", patterns[1].Nodes[1].Label)
+	assert.Equal(t, "d", patterns[2].Nodes[0].Variable)
+
+	_, err = NewParser(`MATCH (a), RETURN a`).Parse()
+	assert.Error(t, err)
+}
+
+func TestParser_QuotedIdentifiers(t *testing.T) {
+	query, err := NewParser("MATCH (n:`Strange Label` {`first name`: \"Ann\"})-[r:`WORKS AT`]->(`the company`) " +
+		"RETURN n.`odd key`, n.`order` AS `as`, `the company` ORDER BY `as` DESC").Parse()
+	require.NoError(t, err)
+
+	pattern := query.Match.Patterns[0]
+	assert.Equal(t, "Strange Label", pattern.Nodes[0].Label)
+	assert.Equal(t, "Ann", pattern.Nodes[0].Properties["first name"])
+	assert.Equal(t, "WORKS AT", pattern.Edges[0].Type)
+	assert.Equal(t, "the company", pattern.Nodes[1].Variable)
+
+	items := query.Return.Items
+	require.Len(t, items, 3)
+	assert.Equal(t, &PropertyAccess{Variable: "n", Property: "odd key"}, items[0].Expr)
+	assert.Equal(t, "n.`odd key`", items[0].columnName())
+	assert.Equal(t, &PropertyAccess{Variable: "n", Property: "order"}, items[1].Expr)
+	assert.Equal(t, "as", items[1].Alias)
+	assert.Equal(t, "`the company`", items[2].columnName())
+	require.Len(t, query.OrderBy.Fields, 1)
+	assert.Equal(t, &Identifier{Name: "as"}, query.OrderBy.Fields[0].Expr)
+	assert.True(t, query.OrderBy.Fields[0].Descending)
+
+	_, err = NewParser("MATCH (n:`Person) RETURN n").Parse()
+	assert.Error(t, err)
+}
+
 func TestParser_IncomingEdge(t *testing.T) {
 	input := `MATCH (a)<-[:FOLLOWS]-(b) RETURN a, b`
 
@@ -188,6 +232,36 @@ func TestParser_IncomingEdge(t *testing.T) {
 	assert.Equal(t, "FOLLOWS", edge.Type)
 }
 
+func TestParser_EdgeDirections(t *testing.T) {
+	for input, want := range map[string]Direction{
+		`MATCH (a)-[r]-(b) RETURN a`:   DirectionBoth,
+		`MATCH (a)-[r]->(b) RETURN a`:  DirectionOut,
+		`MATCH (a)<-[r]-(b) RETURN a`:  DirectionIn,
+		`MATCH (a)<-[r]->(b) RETURN a`: DirectionBoth,
+		`MATCH (a)-[]-(b) RETURN a`:    DirectionBoth,
+		`MATCH (a)<-[:T]-(b) RETURN a`: DirectionIn,
+	} {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		assert.Equal(t, want, q.Match.Patterns[0].Edges[0].Direction, input)
+	}
+
+	_, err := NewParser(`MATCH (a)-[r](b) RETURN a`).Parse()
+	assert.ErrorContains(t, err, "expected - or -> to close edge pattern")
+	_, err = NewParser(`MATCH (a)<-[r](b) RETURN a`).Parse()
+	assert.ErrorContains(t, err, "expected - or -> to close edge pattern")
+}
+
+func TestParser_EdgeProperties(t *testing.T) {
+	query, err := NewParser(`MATCH (a)-[r:KNOWS {since: 2020}]->(b) RETURN r.since`).Parse()
+	require.NoError(t, err)
+
+	edge := query.Match.Patterns[0].Edges[0]
+	assert.Equal(t, "r", edge.Variable)
+	assert.Equal(t, "KNOWS", edge.Type)
+	assert.Equal(t, map[string]interface{}{"since": 2020}, edge.Properties)
+}
+
 func TestParser_Errors(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -206,3 +280,323 @@ func TestParser_Errors(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_VariableLengthEdge(t *testing.T) {
+	tests := []struct {
+		input string
+		min   int
+		max   *int
+	}{
+		{`MATCH (a)-[*]->(b) RETURN b`, 1, nil},
+		{`MATCH (a)-[:KNOWS*2]->(b) RETURN b`, 2, intPtr(2)},
+		{`MATCH (a)-[r*1..3]->(b) RETURN b`, 1, intPtr(3)},
+		{`MATCH (a)-[*2..]->(b) RETURN b`, 2, nil},
+		{`MATCH (a)-[*..4]->(b) RETURN b`, 1, intPtr(4)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := NewParser(tt.input).Parse()
+			require.NoError(t, err)
+
+			edge := query.Match.Patterns[0].Edges[0]
+			require.NotNil(t, edge.MinHops)
+			assert.Equal(t, tt.min, *edge.MinHops)
+			assert.Equal(t, tt.max, edge.MaxHops)
+		})
+	}
+}
+
+func TestParser_UsingIndexHint(t *testing.T) {
+	input := `MATCH (p:Person)-[:WORKS_AT]->(c:Company) USING INDEX p:Person(name) RETURN c`
+
+	query, err := NewParser(input).Parse()
+	require.NoError(t, err)
+
+	require.NotNil(t, query.Hints)
+	assert.Equal(t, "p", query.Hints.StartVariable)
+	require.NotNil(t, query.Return)
+
+	_, err = NewParser(`MATCH (p:Person) USING INDEX p:Person RETURN p`).Parse()
+	assert.Error(t, err)
+}
+
+func TestParser_AsOfTimestamp(t *testing.T) {
+	input := `MATCH (a)-[r:EMPLOYED]->(c) AS OF TIMESTAMP "2021-06-01" WHERE a.name = "Alice" RETURN a.name, c.name`
+
+	query, err := NewParser(input).Parse()
+	require.NoError(t, err)
+
+	require.NotNil(t, query.Temporal)
+	assert.Equal(t, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), query.Temporal.At)
+	require.NotNil(t, query.Where)
+	require.NotNil(t, query.Return)
+
+	_, err = NewParser(`MATCH (a)-[r]->(c) AS OF TIMESTAMP "June 2021" RETURN a`).Parse()
+	assert.ErrorContains(t, err, "invalid timestamp")
+
+	_, err = NewParser(`MATCH (a)-[r]->(c) AS OF "2021-06-01" RETURN a`).Parse()
+	assert.Error(t, err)
+}
+
+func TestParser_InAndNestedAccess(t *testing.T) {
+	query, err := NewParser(`MATCH (p) WHERE "golang" IN p.skills AND p.address.city IN ["SF", "NY"] RETURN p.address.city`).Parse()
+	require.NoError(t, err)
+
+	and := query.Where.Expr.(*BinaryExpr)
+	in := and.Left.(*BinaryExpr)
+	assert.Equal(t, "IN", in.Operator)
+	assert.Equal(t, &Literal{Value: "golang"}, in.Left)
+	assert.Equal(t, &PropertyAccess{Variable: "p", Property: "skills"}, in.Right)
+
+	in = and.Right.(*BinaryExpr)
+	assert.Equal(t, &PropertyAccess{Variable: "p", Property: "address", Path: []string{"city"}}, in.Left)
+	assert.Equal(t, &ListLiteral{Items: []Expression{&Literal{Value: "SF"}, &Literal{Value: "NY"}}}, in.Right)
+	assert.Equal(t, "p.address.city", query.Return.Items[0].columnName())
+
+	_, err = NewParser(`MATCH (p) WHERE p.name IN ["a" "b"] RETURN p`).Parse()
+	assert.Error(t, err)
+	_, err = NewParser(`MATCH (p) RETURN p.address.`).Parse()
+	assert.Error(t, err)
+}
+
+func TestParser_MapLiteral(t *testing.T) {
+	query, err := NewParser(`CREATE (p:Person {address: {city: "SF", geo: {zip: 94103}}})`).Parse()
+	require.NoError(t, err)
+	address := query.Create.Patterns[0].Nodes[0].Properties["address"]
+	assert.Equal(t, &MapLiteral{Entries: map[string]Expression{
+		"city": &Literal{Value: "SF"},
+		"geo":  &MapLiteral{Entries: map[string]Expression{"zip": &Literal{Value: 94103}}},
+	}}, address)
+
+	query, err = NewParser(`MATCH (p) WHERE p.address = {zip: 94103, city: p.city} RETURN {name: p.name}`).Parse()
+	require.NoError(t, err)
+	eq := query.Where.Expr.(*BinaryExpr)
+	assert.Equal(t, &MapLiteral{Entries: map[string]Expression{
+		"city": &PropertyAccess{Variable: "p", Property: "city"},
+		"zip":  &Literal{Value: 94103},
+	}}, eq.Right)
+	assert.Equal(t, "{name: p.name}", query.Return.Items[0].columnName())
+
+	for _, input := range []string{
+		`MATCH (p) WHERE p.address = {city: "SF" zip: 1} RETURN p`,
+		`MATCH (p) WHERE p.address = {city: "SF", city: "NY"} RETURN p`,
+		`MATCH (p) WHERE p.address = {"city": "SF"} RETURN p`,
+	} {
+		_, err = NewParser(input).Parse()
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParser_OrderBy(t *testing.T) {
+	query, err := NewParser(`MATCH (p:Person) RETURN p.name AS name ORDER BY p.city ASC, p.age desc, name LIMIT 5`).Parse()
+	require.NoError(t, err)
+	require.NotNil(t, query.OrderBy)
+	assert.Equal(t, []OrderByField{
+		{Expr: &PropertyAccess{Variable: "p", Property: "city"}},
+		{Expr: &PropertyAccess{Variable: "p", Property: "age"}, Descending: true},
+		{Expr: &Identifier{Name: "name"}},
+	}, query.OrderBy.Fields)
+	assert.Equal(t, 5, *query.Limit)
+
+	_, err = NewParser(`MATCH (p) RETURN p ORDER p.name`).Parse()
+	assert.Error(t, err)
+}
+
+func TestParser_ReturnComparison(t *testing.T) {
+	query, err := NewParser(`MATCH (p:Person) RETURN p.name, p.age >= 18 AS adult LIMIT 2`).Parse()
+	require.NoError(t, err)
+	require.Len(t, query.Return.Items, 2)
+	assert.Equal(t, ReturnItem{
+		Expr: &BinaryExpr{
+			Left:     &PropertyAccess{Variable: "p", Property: "age"},
+			Operator: ">=",
+			Right:    &Literal{Value: 18},
+		},
+		Alias: "adult",
+	}, query.Return.Items[1])
+	assert.Equal(t, 2, *query.Limit)
+
+	// Comparisons end the item rather than swallowing the next clause
+	query, err = NewParser(`MATCH (p) RETURN p.age > 18 AND p.age < 65 ORDER BY p.age`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "p.age > 18 AND p.age < 65", query.Return.Items[0].columnName())
+	require.NotNil(t, query.OrderBy)
+
+	for _, input := range []string{
+		`MATCH (p) RETURN p.age >= 18 adult`,
+		`MATCH (p) RETURN p.age > 18 > 1`,
+		`MATCH (p) RETURN p.name WHERE p.age > 1`,
+	} {
+		_, err = NewParser(input).Parse()
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParser_ReturnStar(t *testing.T) {
+	query, err := NewParser(`MATCH (a:Person)-[r:KNOWS]->(b:Person) RETURN * ORDER BY a.name LIMIT 3`).Parse()
+	require.NoError(t, err)
+	assert.True(t, query.Return.Star)
+	assert.Empty(t, query.Return.Items)
+	require.NotNil(t, query.OrderBy)
+	assert.Equal(t, 3, *query.Limit)
+
+	query, err = NewParser(`MATCH (a) RETURN a`).Parse()
+	require.NoError(t, err)
+	assert.False(t, query.Return.Star)
+
+	_, err = NewParser(`MATCH (a) RETURN *, a.name`).Parse()
+	assert.Error(t, err)
+}
+
+func TestParser_StringPredicates(t *testing.T) {
+	query, err := NewParser(`MATCH (a) WHERE a.name STARTS WITH "Al" AND a.name ends with "ce" RETURN a`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, `a.name STARTS WITH "Al" AND a.name ENDS WITH "ce"`, expressionText(query.Where.Expr))
+
+}
+
+func TestParser_Not(t *testing.T) {
+	active := &PropertyAccess{Variable: "a", Property: "active"}
+
+	query, err := NewParser(`MATCH (a) WHERE NOT a.active RETURN a`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, &UnaryExpr{Operator: "NOT", Operand: active}, query.Where.Expr)
+
+	query, err = NewParser(`MATCH (a) WHERE NOT NOT a.active RETURN a`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, &UnaryExpr{Operator: "NOT", Operand: &UnaryExpr{Operator: "NOT", Operand: active}}, query.Where.Expr)
+
+	query, err = NewParser(`MATCH (a) WHERE NOT (a.active AND a.age > 30) RETURN a`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "NOT (a.active AND a.age > 30)", expressionText(query.Where.Expr))
+
+	// NOT binds tighter than AND and looser than comparisons
+	query, err = NewParser(`MATCH (a) WHERE NOT a.age > 30 AND a.active RETURN a`).Parse()
+	require.NoError(t, err)
+	and, ok := query.Where.Expr.(*BinaryExpr)
+	require.True(t, ok)
+	assert.Equal(t, "AND", and.Operator)
+	assert.Equal(t, "NOT (a.age > 30)", expressionText(and.Left))
+
+	for _, input := range []string{
+		`MATCH (a) WHERE NOT RETURN a`,
+		`MATCH (a) WHERE NOT (a.active RETURN a`,
+	} {
+		_, err = NewParser(input).Parse()
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParser_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`MATCH (a:Person RETURN a`, "expected ) to close node pattern at line 1, column 17"},
+		{`MATCH (a)-[:KNOWS->(b) RETURN a`, "expected ] to close edge pattern at line 1, column 18"},
+		{`MATCH (a) WHERE a.name IN ["x", "y" RETURN a`, "expected , or ] in list at line 1, column 37"},
+		{`MATCH (a) RETURN a LIMIT 5 6`, `unexpected "6" at line 1, column 28`},
+		{"MATCH (a)\n  WHERE a.age >\n\tRETURN a", "unexpected token: RETURN at line 3, column 2"},
+		{"MATCH (n:Person) RETURN n.name /* LIMIT 1", "unterminated block comment at line 1, column 32"},
+		{"MATCH (n:Person)\n/* WHERE n.age > 1\nRETURN n", "unterminated block comment at line 2, column 1"},
+	}
+	for _, tt := range tests {
+		_, err := NewParser(tt.input).Parse()
+		require.Error(t, err, tt.input)
+		assert.Equal(t, tt.err, err.Error(), tt.input)
+	}
+}
+
+func TestParser_ErrorWithContext(t *testing.T) {
+	p := NewParser("MATCH (a)\n\tWHERE a.age >= 1 1\nRETURN a")
+	_, err := p.Parse()
+	require.Error(t, err)
+	assert.Equal(t, "line 2, column 19: unexpected \"1\"\n\tWHERE a.age >= 1 1\n\t                 ^", p.ErrorWithContext())
+
+	p = NewParser("MATCH (a) RETURN a.name /* LIMIT 1")
+	_, err = p.Parse()
+	require.Error(t, err)
+	assert.Equal(t, "line 1, column 25: unterminated block comment\nMATCH (a) RETURN a.name /* LIMIT 1\n                        ^", p.ErrorWithContext())
+
+	p = NewParser(`MATCH (a) RETURN a`)
+	_, err = p.Parse()
+	require.NoError(t, err)
+	assert.Empty(t, p.ErrorWithContext())
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestParser_Create(t *testing.T) {
+	q, err := NewParser(`CREATE (a:Person {name: 'Alice', tags: ['x', 'y'], active: true}), (a)-[:KNOWS {since: 2020}]->(b:Person)<-[:LIKES]-(c)`).Parse()
+	require.NoError(t, err)
+	require.NotNil(t, q.Create)
+	require.Len(t, q.Create.Patterns, 2)
+
+	first := q.Create.Patterns[0]
+	require.Len(t, first.Nodes, 1)
+	assert.Equal(t, "Person", first.Nodes[0].Label)
+	assert.Equal(t, "Alice", first.Nodes[0].Properties["name"])
+	assert.Equal(t, true, first.Nodes[0].Properties["active"])
+	assert.Equal(t, &ListLiteral{Items: []Expression{&Literal{Value: "x"}, &Literal{Value: "y"}}}, first.Nodes[0].Properties["tags"])
+
+	second := q.Create.Patterns[1]
+	require.Len(t, second.Edges, 2)
+	assert.Equal(t, DirectionOut, second.Edges[0].Direction)
+	assert.Equal(t, map[string]interface{}{"since": 2020}, second.Edges[0].Properties)
+	assert.Equal(t, DirectionIn, second.Edges[1].Direction)
+	assert.Equal(t, "LIKES", second.Edges[1].Type)
+
+	for _, input := range []string{
+		`CREATE`,
+		`CREATE (a)-[]->(b)`,
+		`CREATE (a)-[:KNOWS]-(b)`,
+		`CREATE (a)-[:KNOWS*2]->(b)`,
+		`CREATE (a) RETURN a`,
+	} {
+		_, err := NewParser(input).Parse()
+		assert.Error(t, err, input)
+	}
+
+	_, err = q.Execute(nil)
+	assert.Error(t, err)
+}
+
+func TestParser_ErrorsAtLine(t *testing.T) {
+	p := NewParserAt("CREATE (a:Person\n  {name: 'A'", 10)
+	_, err := p.Parse()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "at line 11, column")
+	assert.Contains(t, p.ErrorWithContext(), "  {name: 'A'")
+}
+
+func TestParser_With(t *testing.T) {
+	q, err := NewParser(`MATCH (a:Person)-[:KNOWS]->(b:Person) WITH DISTINCT b, a.age + 1 AS next WHERE next > 20 MATCH (b)-[:WORKS_AT]->(c) RETURN b.name, c.name`).Parse()
+	require.NoError(t, err)
+
+	require.NotNil(t, q.Input)
+	input := q.Input
+	require.NotNil(t, input.With)
+	assert.True(t, input.With.Distinct)
+	require.Len(t, input.With.Items, 2)
+	assert.Equal(t, "b", input.With.Items[0].variable())
+	assert.Equal(t, "next", input.With.Items[1].variable())
+	require.NotNil(t, input.With.Where)
+	assert.Equal(t, "a", input.Match.Patterns[0].Nodes[0].Variable)
+
+	assert.Nil(t, q.With)
+	assert.Equal(t, "c", q.Match.Patterns[0].Nodes[1].Variable)
+	require.Len(t, q.Return.Items, 2)
+
+	q, err = NewParser(`MATCH (n) WITH n RETURN n`).Parse()
+	require.NoError(t, err)
+	assert.False(t, q.Input.With.Distinct)
+	assert.Nil(t, q.Match)
+
+	_, err = NewParser(`MATCH (n) WITH n.name RETURN n`).Parse()
+	assert.ErrorContains(t, err, "must be aliased")
+	_, err = NewParser(`MATCH (n) WITH RETURN n`).Parse()
+	assert.Error(t, err)
+}
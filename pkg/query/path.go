@@ -0,0 +1,191 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// Path is a walk through the graph, as bound to p by MATCH p = (a)-->(b)
+// or returned by shortestPath. Edges[i] connects Nodes[i] and Nodes[i+1].
+type Path struct {
+	Nodes []*graph.Node
+	Edges []*graph.Edge
+}
+
+// Length returns the number of hops in the path
+func (p *Path) Length() int {
+	return len(p.Edges)
+}
+
+// otherEnd returns the endpoint of edge that is not from. A self-loop
+// returns from.
+func otherEnd(edge *graph.Edge, from graph.NodeID) graph.NodeID {
+	source, target := edge.Endpoints()
+	if source == from {
+		return target
+	}
+	return source
+}
+
+// pathArg returns the path argument of a path function. ok is false for
+// null.
+func pathArg(name string, args []interface{}) (path *Path, ok bool, err error) {
+	if err := checkArgCount(name, args, 1, 1); err != nil {
+		return nil, false, err
+	}
+	switch v := args[0].(type) {
+	case nil:
+		return nil, false, nil
+	case *Path:
+		return v, true, nil
+	}
+	return nil, false, argTypeError(name, 0, "a path", args[0])
+}
+
+// fnPathLength returns the number of hops in a path
+func fnPathLength(args []interface{}) (interface{}, error) {
+	path, ok, err := pathArg("path.length", args)
+	if !ok {
+		return nil, err
+	}
+	return path.Length(), nil
+}
+
+// fnPathNodes returns the nodes of a path as a list
+func fnPathNodes(args []interface{}) (interface{}, error) {
+	path, ok, err := pathArg("path.nodes", args)
+	if !ok {
+		return nil, err
+	}
+	nodes := make([]graph.PropertyValue, len(path.Nodes))
+	for i, node := range path.Nodes {
+		nodes[i] = node
+	}
+	return nodes, nil
+}
+
+// fnPathRelationships returns the edges of a path as a list
+func fnPathRelationships(args []interface{}) (interface{}, error) {
+	path, ok, err := pathArg("path.relationships", args)
+	if !ok {
+		return nil, err
+	}
+	edges := make([]graph.PropertyValue, len(path.Edges))
+	for i, edge := range path.Edges {
+		edges[i] = edge
+	}
+	return edges, nil
+}
+
+// fnPathStart returns the first node of a path
+func fnPathStart(args []interface{}) (interface{}, error) {
+	path, ok, err := pathArg("path.start", args)
+	if !ok {
+		return nil, err
+	}
+	return path.Nodes[0], nil
+}
+
+// fnPathEnd returns the last node of a path
+func fnPathEnd(args []interface{}) (interface{}, error) {
+	path, ok, err := pathArg("path.end", args)
+	if !ok {
+		return nil, err
+	}
+	return path.Nodes[len(path.Nodes)-1], nil
+}
+
+// shortestPath finds a shortest path between the bound endpoints of a
+// pattern like (a)-[:KNOWS*..5]->(b) by breadth-first search. It returns
+// null when no path exists within the hop limit, which is the pattern's
+// maximum or DefaultHopLimit.
+func shortestPath(pe *PatternExpression, match BindingTable, g GraphStorage) (interface{}, error) {
+	pattern := pe.Pattern
+	if len(pattern.Edges) != 1 {
+		return nil, fmt.Errorf("shortestPath expects a pattern with one relationship")
+	}
+	edgePattern := pattern.Edges[0]
+	if edgePattern.MinHops == nil {
+		return nil, fmt.Errorf("shortestPath expects a variable-length relationship")
+	}
+	if *edgePattern.MinHops > 1 {
+		return nil, fmt.Errorf("shortestPath supports a minimum length of 0 or 1")
+	}
+	if !plainNodePattern(pattern.Nodes[0]) || !plainNodePattern(pattern.Nodes[1]) {
+		return nil, fmt.Errorf("shortestPath expects both ends to be bound variables")
+	}
+
+	var ends [2]*graph.Node
+	for i, np := range pattern.Nodes {
+		obj, ok := match[np.Variable]
+		if !ok {
+			return nil, unboundVariableError(np.Variable)
+		}
+		if obj == nil {
+			return nil, nil
+		}
+		if ends[i], ok = obj.(*graph.Node); !ok {
+			return nil, fmt.Errorf("variable %s is not a node", np.Variable)
+		}
+	}
+	if g == nil {
+		return nil, fmt.Errorf("shortestPath requires a graph")
+	}
+	source, target := ends[0], ends[1]
+	if source.ID == target.ID && *edgePattern.MinHops == 0 {
+		return &Path{Nodes: []*graph.Node{source}, Edges: []*graph.Edge{}}, nil
+	}
+	maxHops := DefaultHopLimit.MaxHops
+	if edgePattern.MaxHops != nil {
+		maxHops = *edgePattern.MaxHops
+	}
+
+	// parents maps each node reached to the edge it was first reached
+	// over and the node on the edge's near side
+	expand := &ExpandOperator{Direction: edgePattern.Direction, EdgeType: edgePattern.Type}
+	parents := map[graph.NodeID]expandStep{source.ID: {}}
+	frontier := []*graph.Node{source}
+	for depth := 0; depth < maxHops && len(frontier) > 0; depth++ {
+		var next []*graph.Node
+		for _, node := range frontier {
+			for _, step := range expand.adjacent(g, node) {
+				if !propertiesMatch(step.edge.GetProperty, edgePattern.Properties) {
+					continue
+				}
+				if step.node.ID == target.ID {
+					return buildPath(parents, source, expandStep{edge: step.edge, node: node}, target), nil
+				}
+				if _, seen := parents[step.node.ID]; seen {
+					continue
+				}
+				parents[step.node.ID] = expandStep{edge: step.edge, node: node}
+				next = append(next, step.node)
+			}
+		}
+		frontier = next
+	}
+	return nil, nil
+}
+
+// buildPath walks parents back from last, reached from its predecessor
+// over the edge in final, to source
+func buildPath(parents map[graph.NodeID]expandStep, source *graph.Node, final expandStep, last *graph.Node) *Path {
+	nodes := []*graph.Node{last}
+	edges := []*graph.Edge{final.edge}
+	for node := final.node; node.ID != source.ID; {
+		nodes = append(nodes, node)
+		parent := parents[node.ID]
+		edges = append(edges, parent.edge)
+		node = parent.node
+	}
+	nodes = append(nodes, source)
+
+	for i, j := 0, len(nodes)-1; i < j; i, j = i+1, j-1 {
+		nodes[i], nodes[j] = nodes[j], nodes[i]
+	}
+	for i, j := 0, len(edges)-1; i < j; i, j = i+1, j-1 {
+		edges[i], edges[j] = edges[j], edges[i]
+	}
+	return &Path{Nodes: nodes, Edges: edges}
+}
@@ -0,0 +1,199 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nodeNames returns the names of a list of nodes
+func nodeNames(t *testing.T, value interface{}) []string {
+	list, ok := value.([]graph.PropertyValue)
+	require.True(t, ok, "%T is not a list", value)
+	names := make([]string, len(list))
+	for i, v := range list {
+		name, _ := v.(*graph.Node).GetProperty("name")
+		names[i] = name.(string)
+	}
+	return names
+}
+
+func TestParser_PathVariable(t *testing.T) {
+	q, err := NewParser(`MATCH p = (a)-[*1..3]->(b) RETURN path.length(p)`).Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "p", q.Match.Patterns[0].Variable)
+	call, ok := q.Return.Items[0].Expr.(*FunctionCall)
+	require.True(t, ok)
+	assert.Equal(t, "path.length", call.Name)
+	assert.Equal(t, "path.length(p)", q.Return.Items[0].columnName())
+}
+
+func TestExecute_PathFunctions(t *testing.T) {
+	g := createTestGraph(t)
+
+	result := run(t, g, `MATCH p = (a:Person {name: "Alice"})-[:KNOWS*1..3]->(b)
+		RETURN b.name, path.length(p) AS hops, path.nodes(p) AS nodes, path.relationships(p) AS rels,
+			path.start(p) AS first, path.end(p) AS last ORDER BY hops`)
+	require.Len(t, result.Rows, 2)
+
+	bob, charlie := result.Rows[0], result.Rows[1]
+	assert.Equal(t, 1, bob["hops"])
+	assert.Equal(t, []string{"Alice", "Bob"}, nodeNames(t, bob["nodes"]))
+	assert.Equal(t, 2, charlie["hops"])
+	assert.Equal(t, []string{"Alice", "Bob", "Charlie"}, nodeNames(t, charlie["nodes"]))
+
+	rels := charlie["rels"].([]graph.PropertyValue)
+	require.Len(t, rels, 2)
+	for _, rel := range rels {
+		assert.Equal(t, "KNOWS", rel.(*graph.Edge).Label)
+	}
+	first, last := charlie["first"].(*graph.Node), charlie["last"].(*graph.Node)
+	assert.Equal(t, rels[0].(*graph.Edge).Source, first.ID)
+	assert.Equal(t, rels[1].(*graph.Edge).Target, last.ID)
+
+	// A single hop, against its direction, and a path in WHERE
+	result = run(t, g, `MATCH p = (c)<-[:WORKS_AT]-(a) RETURN path.nodes(p) AS nodes, path.length(p) + 1 AS n`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, []string{"Google", "Alice"}, nodeNames(t, result.Rows[0]["nodes"]))
+	assert.Equal(t, 2, result.Rows[0]["n"])
+
+	result = run(t, g, `MATCH p = (a)-[:KNOWS*]->(b) WHERE path.length(p) > 1 RETURN a.name, b.name`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, Row{"a.name": "Alice", "b.name": "Charlie"}, result.Rows[0])
+
+	// A path of one node
+	result = run(t, g, `MATCH p = (c:Company) RETURN path.length(p) AS hops, path.nodes(p) AS nodes`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, 0, result.Rows[0]["hops"])
+	assert.Equal(t, []string{"Google"}, nodeNames(t, result.Rows[0]["nodes"]))
+}
+
+func TestExecute_PathParallelEdges(t *testing.T) {
+	g := storage.NewGraph()
+	a, _ := g.AddNode("Person", graph.Properties{"name": "a"})
+	b, _ := g.AddNode("Person", graph.Properties{"name": "b"})
+	g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+
+	// Parallel edges make different paths, even without an edge variable
+	result := run(t, g, `MATCH p = (x {name: "a"})-[]->(y) RETURN path.length(p)`)
+	assert.Len(t, result.Rows, 2)
+}
+
+func TestExecute_PathRightAnchored(t *testing.T) {
+	g := storage.NewGraph()
+	var prev *graph.Node
+	for _, name := range []string{"A", "B", "C", "D"} {
+		label := "Step"
+		if name == "D" {
+			label = "Thing"
+		}
+		node, _ := g.AddNode(label, graph.Properties{"name": name})
+		if prev != nil {
+			g.AddEdge(prev.ID, node.ID, "NEXT", graph.Properties{"to": name})
+		}
+		prev = node
+	}
+
+	// The plan starts from D and expands backwards, but the path and the
+	// edge list keep the pattern's order
+	query, err := NewParser(`MATCH p=(a)-[r*3]->(b:Thing {name: "D"}) RETURN path.nodes(p) AS nodes, r`).Parse()
+	require.NoError(t, err)
+	plan, err := BuildExecutionPlanWithStats(query, collectOptimizerStats(query, g))
+	require.NoError(t, err)
+	scan, ok := plan.Operators[0].(*ScanOperator)
+	require.True(t, ok, "expected a scan, got %T", plan.Operators[0])
+	require.Equal(t, "b", scan.Variable)
+
+	result, err := query.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, []string{"A", "B", "C", "D"}, nodeNames(t, result.Rows[0]["nodes"]))
+	rels := result.Rows[0]["r"].([]*graph.Edge)
+	require.Len(t, rels, 3)
+	for i, want := range []string{"B", "C", "D"} {
+		to, _ := rels[i].GetProperty("to")
+		assert.Equal(t, want, to)
+	}
+
+	// A variable-length hop between two fixed ones
+	result = run(t, g, `MATCH p=(a)-[:NEXT]->(b)-[*1..2]->(c)-[:NEXT]->(d:Thing {name: "D"}) RETURN path.nodes(p) AS nodes`)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, []string{"A", "B", "C", "D"}, nodeNames(t, result.Rows[0]["nodes"]))
+}
+
+func TestPathFunctions_Arguments(t *testing.T) {
+	length, err := fnPathLength([]interface{}{nil})
+	require.NoError(t, err)
+	assert.Nil(t, length)
+
+	_, err = fnPathLength([]interface{}{1})
+	assert.EqualError(t, err, "path.length argument 1 must be a path, got int")
+	_, err = fnPathNodes(nil)
+	assert.Error(t, err)
+}
+
+func TestExecute_ShortestPath(t *testing.T) {
+	g := storage.NewGraph()
+	ids := make(map[string]graph.NodeID)
+	for _, name := range []string{"a", "b", "c", "d", "e", "x"} {
+		node, _ := g.AddNode("N", graph.Properties{"name": name})
+		ids[name] = node.ID
+	}
+	for _, hop := range [][2]string{{"a", "b"}, {"b", "c"}, {"c", "d"}, {"a", "e"}, {"e", "d"}} {
+		_, err := g.AddEdge(ids[hop[0]], ids[hop[1]], "LINK", nil)
+		require.NoError(t, err)
+	}
+
+	shortest := func(from, to, pattern string) interface{} {
+		result := run(t, g, `MATCH (from:N {name: "`+from+`"}) WITH from
+			MATCH (to:N {name: "`+to+`"}) RETURN shortestPath(`+pattern+`) AS p`)
+		require.Len(t, result.Rows, 1)
+		return result.Rows[0]["p"]
+	}
+
+	path, ok := shortest("a", "d", "(from)-[:LINK*]->(to)").(*Path)
+	require.True(t, ok)
+	assert.Equal(t, 2, path.Length())
+	names := make([]graph.PropertyValue, len(path.Nodes))
+	for i, node := range path.Nodes {
+		names[i] = node
+	}
+	assert.Equal(t, []string{"a", "e", "d"}, nodeNames(t, names))
+
+	// Against the edges, undirected, within a limit, and unreachable
+	path = shortest("d", "a", "(from)<-[*]-(to)").(*Path)
+	assert.Equal(t, 2, path.Length())
+	path = shortest("d", "a", "(from)-[*]-(to)").(*Path)
+	assert.Equal(t, 2, path.Length())
+	assert.Nil(t, shortest("a", "d", "(from)-[*..1]->(to)"))
+	assert.Nil(t, shortest("d", "a", "(from)-[*]->(to)"))
+	assert.Nil(t, shortest("a", "x", "(from)-[*]-(to)"))
+
+	// A node reaches itself in zero hops only when the pattern allows it
+	path = shortest("a", "a", "(from)-[*0..]->(to)").(*Path)
+	assert.Equal(t, 0, path.Length())
+	assert.Nil(t, shortest("a", "a", "(from)-[*]->(to)"))
+
+	q, err := NewParser(`MATCH (a:N) RETURN shortestPath((a)-[:LINK]->(b))`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.ErrorContains(t, err, "variable-length")
+}
+
+func TestShortestPath_ColumnName(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"(a)-[*]->(b)":            "shortestPath((a)-[*]->(b))",
+		"(a)-[:LINK*2]-(b)":       "shortestPath((a)-[:LINK*2]-(b))",
+		"(a)<-[r*0..]-(b)":        "shortestPath((a)<-[r*0..]-(b))",
+		"(a)-[*..3]->(b)":         "shortestPath((a)-[*1..3]->(b))",
+		"(a)-[*2..4 {w: 1}]->(b)": "shortestPath((a)-[*2..4 {w: 1}]->(b))",
+	} {
+		q, err := NewParser(`MATCH (a), (b) RETURN shortestPath(` + pattern + `)`).Parse()
+		require.NoError(t, err, pattern)
+		assert.Equal(t, want, q.Return.Items[0].columnName(), pattern)
+	}
+}
@@ -0,0 +1,128 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// Procedure implements a CALL-able procedure. Arguments are evaluated
+// before the call; the procedure returns its own result table.
+type Procedure func(g GraphStorage, args []interface{}) (*Result, error)
+
+// procedures maps dotted procedure names to their implementations
+var procedures = map[string]Procedure{
+	"db.refreshStats":      procRefreshStats,
+	"db.histogram":         procHistogram,
+	"db.labels":            procLabels,
+	"db.relationshipTypes": procRelationshipTypes,
+	"db.schema":            procSchema,
+}
+
+// RegisterProcedure makes a procedure available to CALL under name.
+// Packages that cannot be imported by query register theirs from init.
+func RegisterProcedure(name string, proc Procedure) {
+	procedures[name] = proc
+}
+
+// StatsRefresher is implemented by storage that can materialize
+// algorithm results (storage.PersistentGraph)
+type StatsRefresher interface {
+	RefreshStats(algo string) error
+	StatsDirty(algo string) bool
+}
+
+// executeCall evaluates the arguments and runs the named procedure
+func executeCall(call *CallClause, g GraphStorage) (*Result, error) {
+	return callProcedure(call, BindingTable{}, g)
+}
+
+// callProcedure runs the named procedure with its arguments evaluated
+// against match
+func callProcedure(call *CallClause, match BindingTable, g GraphStorage) (*Result, error) {
+	proc, ok := procedures[call.Procedure]
+	if !ok {
+		return nil, fmt.Errorf("unknown procedure: %s", call.Procedure)
+	}
+
+	args := make([]interface{}, len(call.Args))
+	for i, argExpr := range call.Args {
+		val, err := evaluateExpression(argExpr, match, g)
+		if err != nil {
+			return nil, fmt.Errorf("%s argument %d: %w", call.Procedure, i+1, err)
+		}
+		args[i] = val
+	}
+
+	return proc(g, args)
+}
+
+// procRefreshStats implements CALL db.refreshStats("algo")
+func procRefreshStats(g GraphStorage, args []interface{}) (*Result, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("db.refreshStats expects 1 argument, got %d", len(args))
+	}
+	algo, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("db.refreshStats expects a string algorithm name")
+	}
+
+	refresher, ok := g.(StatsRefresher)
+	if !ok {
+		return nil, fmt.Errorf("db.refreshStats requires persistent storage")
+	}
+
+	status := "fresh"
+	if refresher.StatsDirty(algo) {
+		status = "refreshing"
+	}
+	if err := refresher.RefreshStats(algo); err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Columns: []string{"algorithm", "status"},
+		Rows:    []Row{{"algorithm": algo, "status": status}},
+	}, nil
+}
+
+// procHistogram implements CALL db.histogram(n, "property"), returning a
+// row {bucket_low, bucket_high, count} for each bucket of the histogram
+// the node or relationship holds in property. A missing property yields
+// no rows.
+func procHistogram(g GraphStorage, args []interface{}) (*Result, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("db.histogram expects 2 arguments, got %d", len(args))
+	}
+	property, ok := args[1].(string)
+	if !ok {
+		return nil, argTypeError("db.histogram", 1, "a string", args[1])
+	}
+
+	var value interface{}
+	switch obj := args[0].(type) {
+	case *graph.Node:
+		value, _ = obj.GetProperty(property)
+	case *graph.Edge:
+		value, _ = obj.GetProperty(property)
+	default:
+		return nil, argTypeError("db.histogram", 0, "a node or relationship", args[0])
+	}
+
+	result := &Result{Columns: []string{"bucket_low", "bucket_high", "count"}, Rows: []Row{}}
+	if value == nil {
+		return result, nil
+	}
+	h, ok := value.(graph.Histogram)
+	if !ok {
+		return nil, fmt.Errorf("db.histogram: property %s is a %s, not a histogram", property, graph.TypeOf(value))
+	}
+	for i, count := range h.Counts {
+		result.Rows = append(result.Rows, Row{
+			"bucket_low":  h.Buckets[i],
+			"bucket_high": h.Buckets[i+1],
+			"count":       count,
+		})
+	}
+	return result, nil
+}
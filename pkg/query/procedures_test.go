@@ -0,0 +1,106 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	_ "github.com/fnuworsu/rdgDB/pkg/algorithms" // registers stats algorithms
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_Call(t *testing.T) {
+	q, err := NewParser(`CALL db.refreshStats("pagerank")`).Parse()
+	require.NoError(t, err)
+	require.NotNil(t, q.Call)
+	assert.Equal(t, "db.refreshStats", q.Call.Procedure)
+	require.Len(t, q.Call.Args, 1)
+	assert.Equal(t, "pagerank", q.Call.Args[0].(*Literal).Value)
+}
+
+func TestExecute_CallRefreshStats(t *testing.T) {
+	pg, err := storage.NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+
+	a, _ := pg.AddNode("Person", nil)
+	b, _ := pg.AddNode("Person", nil)
+	pg.AddEdge(a.ID, b.ID, "KNOWS", nil)
+
+	require.NoError(t, pg.MaterializeStats("degree", nil))
+	require.NoError(t, pg.WaitForStats())
+
+	// Stat properties are queryable like any other property
+	q, err := NewParser(`MATCH (n:Person) WHERE n._stat_degree > 0 RETURN n`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(pg)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 2)
+
+	pg.AddNode("Person", nil)
+
+	q, err = NewParser(`CALL db.refreshStats("degree")`).Parse()
+	require.NoError(t, err)
+	result, err = q.Execute(pg)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	assert.Equal(t, "refreshing", result.Rows[0]["status"])
+	require.NoError(t, pg.WaitForStats())
+
+	// Unknown procedure and non-persistent storage are errors
+	q, _ = NewParser(`CALL db.nope()`).Parse()
+	_, err = q.Execute(pg)
+	assert.Error(t, err)
+
+	q, _ = NewParser(`CALL db.refreshStats("degree")`).Parse()
+	_, err = q.Execute(storage.NewGraph())
+	assert.Error(t, err)
+}
+
+func TestExecute_CallHistogram(t *testing.T) {
+	g := storage.NewGraph()
+	api, err := g.AddNode("Stats", graph.Properties{"endpoint": "/api"})
+	require.NoError(t, err)
+	for _, latency := range []float64{12, 48, 7, 15} {
+		require.NoError(t, api.AddToHistogram("latency_ms", latency))
+	}
+	_, err = g.AddNode("Stats", graph.Properties{"endpoint": "/idle"})
+	require.NoError(t, err)
+
+	q, err := NewParser(`MATCH (n:Stats) WHERE n.endpoint = "/api" CALL db.histogram(n, "latency_ms")`).Parse()
+	require.NoError(t, err)
+	require.NoError(t, Validate(q))
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bucket_low", "bucket_high", "count"}, result.Columns)
+	require.Len(t, result.Rows, 10)
+	assert.Equal(t, Row{"bucket_low": 0.0, "bucket_high": 10.0, "count": int64(1)}, result.Rows[0])
+	assert.Equal(t, Row{"bucket_low": 10.0, "bucket_high": 20.0, "count": int64(2)}, result.Rows[1])
+	assert.Equal(t, Row{"bucket_low": 40.0, "bucket_high": 50.0, "count": int64(1)}, result.Rows[4])
+
+	// A node without the histogram has no buckets
+	q, err = NewParser(`MATCH (n:Stats {endpoint: "/idle"}) CALL db.histogram(n, "latency_ms")`).Parse()
+	require.NoError(t, err)
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Empty(t, result.Rows)
+
+	for src, msg := range map[string]string{
+		`MATCH (n:Stats) CALL db.histogram(n, "endpoint")`:            "property endpoint is a string, not a histogram",
+		`MATCH (n:Stats) CALL db.histogram(n.endpoint, "latency_ms")`: "db.histogram argument 1 must be a node or relationship, got string",
+		`MATCH (n:Stats) CALL db.histogram(n)`:                        "db.histogram expects 2 arguments",
+	} {
+		q, err := NewParser(src).Parse()
+		require.NoError(t, err)
+		_, err = q.Execute(g)
+		assert.ErrorContains(t, err, msg, src)
+	}
+
+	q, err = NewParser(`MATCH (n:Stats) CALL db.histogram(m, "latency_ms")`).Parse()
+	require.NoError(t, err)
+	assert.ErrorContains(t, Validate(q), "variable m not found")
+
+	_, err = NewParser(`MATCH (n:Stats) CALL db.histogram(n, "latency_ms") RETURN n`).Parse()
+	assert.ErrorContains(t, err, `unexpected "RETURN" after CALL`)
+}
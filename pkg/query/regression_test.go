@@ -0,0 +1,105 @@
+package query
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// update rewrites the golden files from the current results:
+// go test ./pkg/query -run TestRegressionSuite -update
+var update = flag.Bool("update", false, "rewrite the regression suite's golden files")
+
+// createRegressionGraph seeds the graph the regression suite runs on. Its
+// IDs are assigned in order, so results naming nodes are stable.
+func createRegressionGraph(t *testing.T) *storage.Graph {
+	g := storage.NewGraph()
+	addNode := func(label string, props graph.Properties) *graph.Node {
+		node, err := g.AddNode(label, props)
+		require.NoError(t, err)
+		return node
+	}
+	addEdge := func(source, target *graph.Node, label string, props graph.Properties) {
+		_, err := g.AddEdge(source.ID, target.ID, label, props)
+		require.NoError(t, err)
+	}
+
+	alice := addNode("Person", graph.Properties{"name": "Alice", "age": 30, "city": "New York", "skills": []graph.PropertyValue{"go", "sql"}})
+	bob := addNode("Person", graph.Properties{"name": "Bob", "age": 25, "city": "San Francisco", "skills": []graph.PropertyValue{"rust"}})
+	charlie := addNode("Person", graph.Properties{"name": "Charlie", "age": 35, "city": "London", "address": graph.Properties{"city": "London", "zip": "EC1"}})
+	dana := addNode("Person", graph.Properties{"name": "Dana", "age": 28, "city": "New York"})
+	google := addNode("Company", graph.Properties{"name": "Google", "hq": "Mountain View"})
+	acme := addNode("Company", graph.Properties{"name": "Acme", "hq": "London"})
+
+	addEdge(alice, bob, "KNOWS", graph.Properties{"since": 2020})
+	addEdge(bob, charlie, "KNOWS", nil)
+	addEdge(charlie, dana, "KNOWS", graph.Properties{"since": 2015})
+	addEdge(dana, alice, "KNOWS", nil)
+	addEdge(alice, google, "WORKS_AT", graph.Properties{"role": "Engineer"})
+	addEdge(bob, google, "WORKS_AT", graph.Properties{"role": "Designer"})
+	addEdge(charlie, acme, "WORKS_AT", graph.Properties{"role": "Manager"})
+	return g
+}
+
+// regressionQueries are the suite's canonical queries, by the name of
+// their golden file in testdata
+var regressionQueries = []struct {
+	name  string
+	query string
+}{
+	{"scan_label", `MATCH (p:Person) RETURN p.name, p.age`},
+	{"return_nodes", `MATCH (c:Company) RETURN c`},
+	{"where_comparison", `MATCH (p:Person) WHERE p.age >= 28 RETURN p.name`},
+	{"where_boolean", `MATCH (p:Person) WHERE p.city = "New York" OR NOT p.age < 35 RETURN p.name`},
+	{"inline_properties", `MATCH (p:Person {city: "New York"}) RETURN p.name`},
+	{"one_hop", `MATCH (a:Person)-[:KNOWS]->(b:Person) RETURN a.name, b.name`},
+	{"incoming", `MATCH (c:Company)<-[r:WORKS_AT]-(p) RETURN c.name, p.name, r.role`},
+	{"undirected", `MATCH (a:Person {name: "Alice"})-[:KNOWS]-(b) RETURN b.name`},
+	{"two_hops", `MATCH (a:Person)-[:KNOWS]->(b)-[:KNOWS]->(c) RETURN a.name, c.name`},
+	{"variable_length", `MATCH (a:Person {name: "Alice"})-[:KNOWS*1..3]->(b) RETURN b.name`},
+	{"multiple_patterns", `MATCH (a:Person)-[:WORKS_AT]->(c), (b:Person)-[:WORKS_AT]->(c) WHERE a.name < b.name RETURN a.name, b.name, c.name`},
+	{"order_limit", `MATCH (p:Person) RETURN p.name, p.age ORDER BY p.age DESC LIMIT 2`},
+	{"distinct", `MATCH (p:Person) WITH DISTINCT p.city AS city RETURN city`},
+	{"arithmetic", `MATCH (p:Person) WHERE p.age + 5 > 35 RETURN p.name, p.age * 2 AS double`},
+	{"list_membership", `MATCH (p:Person) WHERE "go" IN p.skills RETURN p.name, size(p.skills) AS skills`},
+	{"nested_property", `MATCH (p:Person) WHERE p.address.zip = "EC1" RETURN p.name, p.address.city`},
+	{"with_distinct", `MATCH (a:Person)-[:WORKS_AT]->(c) WITH DISTINCT c MATCH (c)<-[:WORKS_AT]-(e) RETURN c.name, e.name`},
+	{"union", `MATCH (p:Person) RETURN p.city AS city UNION MATCH (c:Company) RETURN c.hq AS city`},
+	{"path", `MATCH p = (a:Person {name: "Bob"})-[:KNOWS*2]->(b) RETURN b.name, path.length(p) AS hops, p`},
+	{"procedure", `CALL db.labels()`},
+}
+
+// TestRegressionSuite runs the canonical queries against the seeded graph
+// and compares their results with testdata/<name>.expected.json, ignoring
+// row and column order
+func TestRegressionSuite(t *testing.T) {
+	g := createRegressionGraph(t)
+	for _, tc := range regressionQueries {
+		t.Run(tc.name, func(t *testing.T) {
+			result := run(t, g, tc.query)
+			path := filepath.Join("testdata", tc.name+".expected.json")
+
+			if *update {
+				data, err := json.MarshalIndent(result, "", "  ")
+				require.NoError(t, err)
+				require.NoError(t, os.MkdirAll("testdata", 0755))
+				require.NoError(t, os.WriteFile(path, append(data, '\n'), 0644))
+				return
+			}
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err, "run with -update to create the golden file")
+			var expected Result
+			require.NoError(t, json.Unmarshal(data, &expected))
+			if !ResultEquals(&expected, result) {
+				t.Errorf("%s\nresult differs from %s:\n%s", tc.query, path, ResultDiff(&expected, result))
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ResultEquals reports whether a and b hold the same columns and the same
+// rows, in any order. Rows are compared in the JSON form Row.MarshalJSON
+// writes, so a result compares equal to one decoded from its own JSON:
+// nodes match by ID, label and properties, and an int matches the float64
+// of the same value. A nil result equals an empty one.
+func ResultEquals(a, b *Result) bool {
+	colsA, rowsA := normalizeResult(a)
+	colsB, rowsB := normalizeResult(b)
+	return slices.Equal(colsA, colsB) && slices.Equal(rowsA, rowsB)
+}
+
+// ResultDiff describes how b differs from a: the columns if they differ,
+// then each row of a missing from b, marked -, and each row of b not in
+// a, marked +. Equal results give "".
+func ResultDiff(a, b *Result) string {
+	colsA, rowsA := normalizeResult(a)
+	colsB, rowsB := normalizeResult(b)
+
+	var out strings.Builder
+	if !slices.Equal(colsA, colsB) {
+		fmt.Fprintf(&out, "columns: [%s] != [%s]\n", strings.Join(colsA, ", "), strings.Join(colsB, ", "))
+	}
+
+	// Both row lists are sorted, so one merge pass finds the rows each is
+	// missing, counting repeated rows
+	i, j := 0, 0
+	for i < len(rowsA) || j < len(rowsB) {
+		switch {
+		case j == len(rowsB) || i < len(rowsA) && rowsA[i] < rowsB[j]:
+			fmt.Fprintf(&out, "- %s\n", rowsA[i])
+			i++
+		case i == len(rowsA) || rowsB[j] < rowsA[i]:
+			fmt.Fprintf(&out, "+ %s\n", rowsB[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return out.String()
+}
+
+// normalizeResult returns the sorted column names of r and its rows as
+// sorted JSON strings
+func normalizeResult(r *Result) ([]string, []string) {
+	if r == nil {
+		return nil, nil
+	}
+	cols := append([]string(nil), r.Columns...)
+	sort.Strings(cols)
+
+	rows := make([]string, len(r.Rows))
+	for i, row := range r.Rows {
+		rows[i] = rowText(row)
+	}
+	sort.Strings(rows)
+	return cols, rows
+}
+
+// rowText renders row as JSON with every object's keys sorted, falling
+// back to Go syntax for values JSON cannot hold, such as NaN. Nodes and
+// relationships are written as structs, so the JSON is decoded and written
+// again to sort their keys as a decoded result's are.
+func rowText(row Row) string {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Sprintf("%v", map[string]interface{}(row))
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return string(data)
+	}
+	if data, err = json.Marshal(generic); err != nil {
+		return fmt.Sprintf("%v", map[string]interface{}(row))
+	}
+	return string(data)
+}
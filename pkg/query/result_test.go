@@ -0,0 +1,81 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultEquals(t *testing.T) {
+	a := &Result{
+		Columns: []string{"name", "age"},
+		Rows: []Row{
+			{"name": "Alice", "age": 30},
+			{"name": "Bob", "age": 25},
+			{"name": "Bob", "age": 25},
+		},
+	}
+
+	// Column and row order do not matter
+	b := &Result{
+		Columns: []string{"age", "name"},
+		Rows: []Row{
+			{"age": 25, "name": "Bob"},
+			{"name": "Alice", "age": 30},
+			{"name": "Bob", "age": 25},
+		},
+	}
+	assert.True(t, ResultEquals(a, b))
+	assert.Empty(t, ResultDiff(a, b))
+
+	// A result equals its own JSON decoded
+	data, err := json.Marshal(a)
+	require.NoError(t, err)
+	var decoded Result
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.True(t, ResultEquals(a, &decoded))
+
+	node := graph.NewNode(1, "Person")
+	node.Properties.SetAll(graph.Properties{"name": "Alice"})
+	withNode := &Result{Columns: []string{"p"}, Rows: []Row{{"p": node}}}
+	data, err = json.Marshal(withNode)
+	require.NoError(t, err)
+	decoded = Result{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.True(t, ResultEquals(withNode, &decoded))
+
+	// Repeated rows count
+	b.Rows = b.Rows[:2]
+	assert.False(t, ResultEquals(a, b))
+
+	b.Rows = append(b.Rows, Row{"name": "Bob", "age": 26})
+	assert.False(t, ResultEquals(a, b))
+
+	assert.False(t, ResultEquals(a, &Result{Columns: []string{"name"}, Rows: a.Rows}))
+	assert.True(t, ResultEquals(nil, &Result{}))
+	assert.False(t, ResultEquals(nil, a))
+}
+
+func TestResultDiff(t *testing.T) {
+	a := &Result{
+		Columns: []string{"name"},
+		Rows:    []Row{{"name": "Alice"}, {"name": "Bob"}, {"name": "Bob"}},
+	}
+	b := &Result{
+		Columns: []string{"name", "age"},
+		Rows:    []Row{{"name": "Bob"}, {"name": "Carol"}},
+	}
+	assert.Equal(t, `columns: [name] != [age, name]
+- {"name":"Alice"}
+- {"name":"Bob"}
++ {"name":"Carol"}
+`, ResultDiff(a, b))
+	assert.Equal(t, `columns: [age, name] != [name]
++ {"name":"Alice"}
++ {"name":"Bob"}
+- {"name":"Carol"}
+`, ResultDiff(b, a))
+}
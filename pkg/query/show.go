@@ -0,0 +1,183 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+)
+
+// labelCatalog is implemented by storage backends that count nodes per
+// label and edges per type
+type labelCatalog interface {
+	LabelCounts() map[string]int
+	EdgeTypeCounts() map[string]int
+}
+
+// keyCatalog is implemented by storage backends that track the property
+// keys set on the nodes of each label
+type keyCatalog interface {
+	PropertyKeys(label string) []string
+}
+
+// schemaCatalog is implemented by storage backends with indexes and label
+// schemas
+type schemaCatalog interface {
+	Catalog() *wal.Catalog
+}
+
+// executeShow lists the metadata a SHOW statement asks for
+func executeShow(show *ShowClause, g GraphStorage) (*Result, error) {
+	switch show.Target {
+	case ShowLabels, ShowRelationshipTypes:
+		lc, ok := g.(labelCatalog)
+		if !ok {
+			return nil, fmt.Errorf("storage does not support SHOW %s", show.Target)
+		}
+		if show.Target == ShowLabels {
+			return countRows("label", lc.LabelCounts()), nil
+		}
+		return countRows("type", lc.EdgeTypeCounts()), nil
+
+	case ShowIndexes, ShowConstraints:
+		sc, ok := g.(schemaCatalog)
+		if !ok {
+			return nil, fmt.Errorf("storage does not support SHOW %s", show.Target)
+		}
+		if show.Target == ShowIndexes {
+			return indexRows(sc.Catalog()), nil
+		}
+		return constraintRows(sc.Catalog()), nil
+	}
+	return nil, fmt.Errorf("unknown SHOW target %s", show.Target)
+}
+
+// countRows returns one row per name with its count, ordered by name
+func countRows(column string, counts map[string]int) *Result {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := &Result{Columns: []string{column, "count"}, Rows: make([]Row, len(names))}
+	for i, name := range names {
+		result.Rows[i] = Row{column: name, "count": counts[name]}
+	}
+	return result
+}
+
+// indexRows returns one row per index, ordered by label, property and
+// index type
+func indexRows(catalog *wal.Catalog) *Result {
+	result := &Result{Columns: []string{"label", "property", "type"}, Rows: make([]Row, 0)}
+	for _, kind := range []struct {
+		name string
+		defs []wal.IndexDef
+	}{
+		{"fulltext", catalog.FullTextIndexes},
+		{"property", catalog.PropertyIndexes},
+		{"spatial", catalog.SpatialIndexes},
+	} {
+		for _, def := range kind.defs {
+			result.Rows = append(result.Rows, Row{"label": def.Label, "property": def.Property, "type": kind.name})
+		}
+	}
+	sort.SliceStable(result.Rows, func(i, j int) bool {
+		a, b := result.Rows[i], result.Rows[j]
+		if a["label"] != b["label"] {
+			return a["label"].(string) < b["label"].(string)
+		}
+		return a["property"].(string) < b["property"].(string)
+	})
+	return result
+}
+
+// constraintRows returns one row per property a label schema constrains,
+// with its declared type (null if only required) and whether it is
+// required, ordered by label and property
+func constraintRows(catalog *wal.Catalog) *Result {
+	result := &Result{Columns: []string{"label", "property", "type", "required"}, Rows: make([]Row, 0)}
+	for _, schema := range catalog.Schemas {
+		required := make(map[string]bool, len(schema.Required))
+		properties := make([]string, 0, len(schema.Properties)+len(schema.Required))
+		for _, property := range schema.Required {
+			required[property] = true
+			properties = append(properties, property)
+		}
+		for property := range schema.Properties {
+			if !required[property] {
+				properties = append(properties, property)
+			}
+		}
+		sort.Strings(properties)
+
+		for _, property := range properties {
+			var typ interface{}
+			if t, ok := schema.Properties[property]; ok {
+				typ = t
+			}
+			result.Rows = append(result.Rows, Row{
+				"label":    schema.Label,
+				"property": property,
+				"type":     typ,
+				"required": required[property],
+			})
+		}
+	}
+	return result
+}
+
+// procLabels implements CALL db.labels(), returning one row per label in
+// use with its node count, ordered by label, as SHOW LABELS does
+func procLabels(g GraphStorage, args []interface{}) (*Result, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("db.labels expects no arguments, got %d", len(args))
+	}
+	lc, ok := g.(labelCatalog)
+	if !ok {
+		return nil, fmt.Errorf("db.labels is not supported for %T", g)
+	}
+	return countRows("label", lc.LabelCounts()), nil
+}
+
+// procRelationshipTypes implements CALL db.relationshipTypes(), returning
+// one row per relationship type in use with its edge count, ordered by
+// type, as SHOW RELATIONSHIP TYPES does
+func procRelationshipTypes(g GraphStorage, args []interface{}) (*Result, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("db.relationshipTypes expects no arguments, got %d", len(args))
+	}
+	lc, ok := g.(labelCatalog)
+	if !ok {
+		return nil, fmt.Errorf("db.relationshipTypes is not supported for %T", g)
+	}
+	return countRows("type", lc.EdgeTypeCounts()), nil
+}
+
+// procSchema implements CALL db.schema(), returning a row {label, count,
+// properties} for each label in use, where properties lists the keys set
+// on at least one node of the label, in order
+func procSchema(g GraphStorage, args []interface{}) (*Result, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("db.schema expects no arguments, got %d", len(args))
+	}
+	lc, lok := g.(labelCatalog)
+	kc, kok := g.(keyCatalog)
+	if !lok || !kok {
+		return nil, fmt.Errorf("db.schema is not supported for %T", g)
+	}
+
+	result := countRows("label", lc.LabelCounts())
+	result.Columns = append(result.Columns, "properties")
+	for _, row := range result.Rows {
+		keys := kc.PropertyKeys(row["label"].(string))
+		properties := make([]graph.PropertyValue, len(keys))
+		for i, key := range keys {
+			properties[i] = key
+		}
+		row["properties"] = properties
+	}
+	return result, nil
+}
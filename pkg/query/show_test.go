@@ -0,0 +1,110 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// show parses and runs a SHOW statement
+func show(t *testing.T, g GraphStorage, input string) *Result {
+	q, err := NewParser(input).Parse()
+	require.NoError(t, err)
+	require.NotNil(t, q.Show)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	return result
+}
+
+func TestParser_Show(t *testing.T) {
+	for input, target := range map[string]ShowTarget{
+		`SHOW LABELS`:             ShowLabels,
+		`show relationship types`: ShowRelationshipTypes,
+		`SHOW INDEXES`:            ShowIndexes,
+		`SHOW Constraints`:        ShowConstraints,
+	} {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		assert.Equal(t, &ShowClause{Target: target}, q.Show, input)
+	}
+
+	for _, input := range []string{
+		`SHOW`,
+		`SHOW NODES`,
+		`SHOW RELATIONSHIP`,
+		`SHOW LABELS RETURN label`,
+	} {
+		_, err := NewParser(input).Parse()
+		assert.Error(t, err, input)
+	}
+}
+
+func TestExecute_ShowLabelsAndTypes(t *testing.T) {
+	g := createTestGraph(t)
+
+	result := show(t, g, `SHOW LABELS`)
+	assert.Equal(t, []string{"label", "count"}, result.Columns)
+	assert.Equal(t, []Row{{"label": "Company", "count": 1}, {"label": "Person", "count": 3}}, result.Rows)
+
+	result = show(t, g, `SHOW RELATIONSHIP TYPES`)
+	assert.Equal(t, []string{"type", "count"}, result.Columns)
+	assert.Equal(t, []Row{{"type": "KNOWS", "count": 2}, {"type": "WORKS_AT", "count": 1}}, result.Rows)
+}
+
+func TestExecute_ShowIndexesAndConstraints(t *testing.T) {
+	g := createTestGraph(t)
+
+	assert.Empty(t, show(t, g, `SHOW INDEXES`).Rows)
+	assert.Empty(t, show(t, g, `SHOW CONSTRAINTS`).Rows)
+
+	require.NoError(t, g.CreatePropertyIndex("Person", "name"))
+	require.NoError(t, g.CreateFullTextIndex("Person", "name"))
+	require.NoError(t, g.CreatePropertyIndex("Company", "name"))
+	require.NoError(t, g.DefineSchema("Person", map[string]storage.PropertyType{
+		"name": storage.TypeString,
+		"age":  storage.TypeInt,
+	}, []string{"name", "city"}))
+
+	result := show(t, g, `SHOW INDEXES`)
+	assert.Equal(t, []string{"label", "property", "type"}, result.Columns)
+	assert.Equal(t, []Row{
+		{"label": "Company", "property": "name", "type": "property"},
+		{"label": "Person", "property": "name", "type": "fulltext"},
+		{"label": "Person", "property": "name", "type": "property"},
+	}, result.Rows)
+
+	result = show(t, g, `SHOW CONSTRAINTS`)
+	assert.Equal(t, []string{"label", "property", "type", "required"}, result.Columns)
+	assert.Equal(t, []Row{
+		{"label": "Person", "property": "age", "type": "int", "required": false},
+		{"label": "Person", "property": "city", "type": nil, "required": true},
+		{"label": "Person", "property": "name", "type": "string", "required": true},
+	}, result.Rows)
+}
+
+func TestExecute_SchemaProcedures(t *testing.T) {
+	g := createTestGraph(t)
+
+	result := run(t, g, `CALL db.labels()`)
+	assert.Equal(t, []string{"label", "count"}, result.Columns)
+	assert.Equal(t, []Row{{"label": "Company", "count": 1}, {"label": "Person", "count": 3}}, result.Rows)
+
+	result = run(t, g, `CALL db.relationshipTypes()`)
+	assert.Equal(t, []string{"type", "count"}, result.Columns)
+	assert.Equal(t, []Row{{"type": "KNOWS", "count": 2}, {"type": "WORKS_AT", "count": 1}}, result.Rows)
+
+	result = run(t, g, `CALL db.schema()`)
+	assert.Equal(t, []string{"label", "count", "properties"}, result.Columns)
+	assert.Equal(t, []Row{
+		{"label": "Company", "count": 1, "properties": []graph.PropertyValue{"name"}},
+		{"label": "Person", "count": 3, "properties": []graph.PropertyValue{"age", "city", "name"}},
+	}, result.Rows)
+
+	q, err := NewParser(`CALL db.schema("Person")`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.Error(t, err)
+}
@@ -0,0 +1,184 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// fnToUpper returns a string in upper case
+func fnToUpper(args []interface{}) (interface{}, error) {
+	return mapString("toUpper", args, strings.ToUpper)
+}
+
+// fnToLower returns a string in lower case
+func fnToLower(args []interface{}) (interface{}, error) {
+	return mapString("toLower", args, strings.ToLower)
+}
+
+// fnTrim returns a string without leading and trailing whitespace
+func fnTrim(args []interface{}) (interface{}, error) {
+	return mapString("trim", args, strings.TrimSpace)
+}
+
+// mapString applies fn to the single string argument of a function
+func mapString(name string, args []interface{}, fn func(string) string) (interface{}, error) {
+	if err := checkArgCount(name, args, 1, 1); err != nil {
+		return nil, err
+	}
+	if args[0] == nil {
+		return nil, nil
+	}
+	s, err := stringArg(name, args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return fn(s), nil
+}
+
+// fnSubstring returns the part of a string starting at a 0-based character
+// offset, up to an optional length. A start past the end gives "".
+func fnSubstring(args []interface{}) (interface{}, error) {
+	if err := checkArgCount("substring", args, 2, 3); err != nil {
+		return nil, err
+	}
+	if hasNull(args) {
+		return nil, nil
+	}
+	s, err := stringArg("substring", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	start, err := intArg("substring", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(s)
+	if start > len(runes) {
+		start = len(runes)
+	}
+	end := len(runes)
+	if len(args) == 3 {
+		length, err := intArg("substring", args, 2)
+		if err != nil {
+			return nil, err
+		}
+		// Compared this way round, a huge length can't overflow
+		if length < end-start {
+			end = start + length
+		}
+	}
+	return string(runes[start:end]), nil
+}
+
+// fnSplit splits a string around every occurrence of a delimiter
+func fnSplit(args []interface{}) (interface{}, error) {
+	if err := checkArgCount("split", args, 2, 2); err != nil {
+		return nil, err
+	}
+	if hasNull(args) {
+		return nil, nil
+	}
+	s, err := stringArg("split", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	delimiter, err := stringArg("split", args, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(s, delimiter)
+	list := make([]graph.PropertyValue, len(parts))
+	for i, part := range parts {
+		list[i] = part
+	}
+	return list, nil
+}
+
+// checkArgCount fails unless a function got between min and max arguments
+func checkArgCount(name string, args []interface{}, min, max int) error {
+	if len(args) >= min && len(args) <= max {
+		return nil
+	}
+	if min == max {
+		return fmt.Errorf("%s expects %d argument%s, got %d", name, min, plural(min), len(args))
+	}
+	return fmt.Errorf("%s expects %d to %d arguments, got %d", name, min, max, len(args))
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// hasNull reports whether any argument is null
+func hasNull(args []interface{}) bool {
+	for _, arg := range args {
+		if arg == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// stringArg returns argument i of a function as a string
+func stringArg(name string, args []interface{}, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", argTypeError(name, i, "a string", args[i])
+	}
+	return s, nil
+}
+
+// intArg returns argument i of a function as a non-negative integer.
+// Floats with no fractional part are accepted if they fit in an int.
+func intArg(name string, args []interface{}, i int) (int, error) {
+	if n, ok := toInteger(args[i]); ok {
+		if n < 0 {
+			return 0, fmt.Errorf("%s argument %d must not be negative, got %v", name, i+1, args[i])
+		}
+		if uint64(n) > math.MaxInt {
+			return 0, fmt.Errorf("%s argument %d is out of range, got %v", name, i+1, args[i])
+		}
+		return int(n), nil
+	}
+	if !isNumber(args[i]) {
+		return 0, argTypeError(name, i, "an integer", args[i])
+	}
+	f := toFloat(args[i])
+	if math.IsInf(f, 0) || f != math.Trunc(f) {
+		return 0, argTypeError(name, i, "an integer", args[i])
+	}
+	if f < 0 {
+		return 0, fmt.Errorf("%s argument %d must not be negative, got %v", name, i+1, args[i])
+	}
+	if f >= math.MaxInt {
+		return 0, fmt.Errorf("%s argument %d is out of range, got %v", name, i+1, args[i])
+	}
+	return int(f), nil
+}
+
+// argTypeError reports a wrongly typed argument by its 1-based position
+func argTypeError(name string, i int, want string, got interface{}) error {
+	return fmt.Errorf("%s argument %d must be %s, got %s", name, i+1, want, typeName(got))
+}
+
+// typeName names the type of a value for error messages
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case *graph.Node:
+		return "node"
+	case *graph.Edge:
+		return "relationship"
+	}
+	if typ := graph.TypeOf(v); typ != "" {
+		return typ
+	}
+	return fmt.Sprintf("%T", v)
+}
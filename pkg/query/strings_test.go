@@ -0,0 +1,110 @@
+package query
+
+import (
+	"math"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringFunctions(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []interface{}
+		want interface{}
+	}{
+		{"toupper", []interface{}{"héllo"}, "HÉLLO"},
+		{"tolower", []interface{}{"Alice@Corp.COM"}, "alice@corp.com"},
+		{"trim", []interface{}{"  padded\t"}, "padded"},
+		{"substring", []interface{}{"Charlie", 0, 3}, "Cha"},
+		{"substring", []interface{}{"Charlie", 4}, "lie"},
+		{"substring", []interface{}{"Charlie", 5, 10}, "ie"},
+		{"substring", []interface{}{"Charlie", 20}, ""},
+		{"substring", []interface{}{"héllo", 1, 2}, "él"},
+		{"substring", []interface{}{"Charlie", 1.0, 2}, "ha"},
+		{"substring", []interface{}{"Charlie", 2, math.MaxInt64}, "arlie"},
+		{"substring", []interface{}{"Charlie", math.MaxInt64, math.MaxInt64}, ""},
+		{"substring", []interface{}{"Charlie", 2, 1e18}, "arlie"},
+		{"split", []interface{}{"a,b,,c", ","}, []graph.PropertyValue{"a", "b", "", "c"}},
+		{"split", []interface{}{"", ","}, []graph.PropertyValue{""}},
+		{"size", []interface{}{"héllo"}, 5},
+
+		// Null in, null out
+		{"toupper", []interface{}{nil}, nil},
+		{"tolower", []interface{}{nil}, nil},
+		{"trim", []interface{}{nil}, nil},
+		{"substring", []interface{}{nil, 0}, nil},
+		{"substring", []interface{}{"abc", nil}, nil},
+		{"split", []interface{}{"a,b", nil}, nil},
+		{"size", []interface{}{nil}, nil},
+	} {
+		got, err := functions[tc.name](tc.args)
+		require.NoError(t, err, "%s%v", tc.name, tc.args)
+		assert.Equal(t, tc.want, got, "%s%v", tc.name, tc.args)
+	}
+}
+
+func TestStringFunctions_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []interface{}
+		err  string
+	}{
+		{"toupper", nil, "toUpper expects 1 argument, got 0"},
+		{"trim", []interface{}{"a", "b"}, "trim expects 1 argument, got 2"},
+		{"tolower", []interface{}{42}, "toLower argument 1 must be a string, got int"},
+		{"substring", []interface{}{"abc"}, "substring expects 2 to 3 arguments, got 1"},
+		{"substring", []interface{}{"abc", "1"}, "substring argument 2 must be an integer, got string"},
+		{"substring", []interface{}{"abc", 0, 1.5}, "substring argument 3 must be an integer, got float"},
+		{"substring", []interface{}{"abc", -1}, "substring argument 2 must not be negative, got -1"},
+		{"substring", []interface{}{"abc", 1e19}, "substring argument 2 is out of range, got 1e+19"},
+		{"substring", []interface{}{"abc", 0, 1e19}, "substring argument 3 is out of range, got 1e+19"},
+		{"substring", []interface{}{"abc", math.Inf(1)}, "substring argument 2 must be an integer, got float"},
+		{"substring", []interface{}{"abc", 0, math.NaN()}, "substring argument 3 must be an integer, got float"},
+		{"split", []interface{}{"a,b", 1}, "split argument 2 must be a string, got int"},
+		{"split", []interface{}{graph.NewNode(1, "Person"), ","}, "split argument 1 must be a string, got node"},
+		{"size", []interface{}{3.5}, "size argument 1 must be a list, string or pattern, got float"},
+	} {
+		_, err := functions[tc.name](tc.args)
+		assert.EqualError(t, err, tc.err, "%s%v", tc.name, tc.args)
+	}
+}
+
+func TestExecute_StringFunctions(t *testing.T) {
+	g := storage.NewGraph()
+	g.AddNode("Person", graph.Properties{"name": "Alice", "email": "Alice@CORP.com", "tags": "admin,ops"})
+	g.AddNode("Person", graph.Properties{"name": "Bob", "email": "bob@example.com", "tags": "dev"})
+	g.AddNode("Person", graph.Properties{"name": " Charlie "})
+
+	run := func(input string) *Result {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err, input)
+		result, err := q.Execute(g)
+		require.NoError(t, err, input)
+		return result
+	}
+
+	result := run(`MATCH (p:Person) WHERE toLower(p.email) ENDS WITH "@corp.com" RETURN toUpper(p.name) AS name, split(p.tags, ",") AS tags`)
+	assert.Equal(t, []Row{{"name": "ALICE", "tags": []graph.PropertyValue{"admin", "ops"}}}, result.Rows)
+
+	result = run(`MATCH (p:Person) WHERE p.name = "Bob" RETURN substring(p.name, 0, 2), size(p.name)`)
+	assert.Equal(t, []string{"substring(p.name, 0, 2)", "size(p.name)"}, result.Columns)
+	assert.Equal(t, []Row{{"substring(p.name, 0, 2)": "Bo", "size(p.name)": 3}}, result.Rows)
+
+	// Columns keep function names as written, though calls ignore case
+	result = run(`MATCH (p:Person) WHERE p.name = "Bob" RETURN toUpper(p.name), TRIM(toLower(p.name))`)
+	assert.Equal(t, []string{"toUpper(p.name)", "TRIM(toLower(p.name))"}, result.Columns)
+	assert.Equal(t, []Row{{"toUpper(p.name)": "BOB", "TRIM(toLower(p.name))": "bob"}}, result.Rows)
+
+	// A missing property propagates null rather than failing the query
+	result = run(`MATCH (p:Person) WHERE trim(p.name) = "Charlie" RETURN trim(p.name) AS name, toLower(p.email) AS email`)
+	assert.Equal(t, []Row{{"name": "Charlie", "email": nil}}, result.Rows)
+
+	q, err := NewParser(`MATCH (p:Person) RETURN substring(p.name, "x")`).Parse()
+	require.NoError(t, err)
+	_, err = q.Execute(g)
+	assert.ErrorContains(t, err, "substring argument 2 must be an integer, got string")
+}
@@ -0,0 +1,66 @@
+package query
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// timestampLayouts are the ISO-8601 forms accepted for AS OF TIMESTAMP and
+// for the "from"/"to" properties of temporal edges, most precise first
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseTimestamp parses an ISO-8601 date or date-time. Values without a
+// zone are read as UTC, and partial dates denote their first instant.
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q: expected ISO-8601 such as 2021-06-01", s)
+}
+
+// edgeActiveAt reports whether edge is valid at t. A missing "from" means
+// the edge has always been valid and a missing "to" that it still is.
+// Edges whose bounds cannot be read as timestamps never match.
+func edgeActiveAt(edge *graph.Edge, t time.Time) bool {
+	edge.Mu.RLock()
+	from, hasFrom := edge.Properties.GetProperty("from")
+	to, hasTo := edge.Properties.GetProperty("to")
+	edge.Mu.RUnlock()
+
+	if hasFrom && from != nil {
+		start, ok := timeValue(from)
+		if !ok || start.After(t) {
+			return false
+		}
+	}
+	if hasTo && to != nil {
+		end, ok := timeValue(to)
+		if !ok || end.Before(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// timeValue converts a property value to a time
+func timeValue(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, true
+	case string:
+		t, err := parseTimestamp(val)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,188 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createEmploymentHistory(t *testing.T) *storage.Graph {
+	g := storage.NewGraph()
+
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	acme, _ := g.AddNode("Company", graph.Properties{"name": "Acme"})
+	globex, _ := g.AddNode("Company", graph.Properties{"name": "Globex"})
+
+	edges := []struct {
+		source, target graph.NodeID
+		props          graph.Properties
+	}{
+		{alice.ID, acme.ID, graph.Properties{"from": "2020-01", "to": "2023-06"}},
+		{alice.ID, globex.ID, graph.Properties{"from": "2023-07"}},
+		{bob.ID, globex.ID, graph.Properties{"from": "2019-03-15", "to": "2021-05-31"}},
+	}
+	for _, e := range edges {
+		_, err := g.AddEdge(e.source, e.target, "EMPLOYED", e.props)
+		require.NoError(t, err)
+	}
+	return g
+}
+
+func TestParseTimestamp(t *testing.T) {
+	cases := map[string]time.Time{
+		"2021":                      time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		"2021-06":                   time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		"2021-06-01":                time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		"2021-06-01T12:30":          time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC),
+		"2021-06-01T12:30:15":       time.Date(2021, 6, 1, 12, 30, 15, 0, time.UTC),
+		"2021-06-01T12:30:15+02:00": time.Date(2021, 6, 1, 10, 30, 15, 0, time.UTC),
+	}
+	for input, want := range cases {
+		got, err := parseTimestamp(input)
+		require.NoError(t, err, input)
+		assert.True(t, want.Equal(got), "%s: got %v", input, got)
+	}
+
+	_, err := parseTimestamp("01/06/2021")
+	assert.Error(t, err)
+}
+
+func TestExecute_AsOfTimestamp(t *testing.T) {
+	g := createEmploymentHistory(t)
+
+	employers := func(t *testing.T, at string) map[string]string {
+		q, err := NewParser(`MATCH (a:Person)-[r:EMPLOYED]->(c:Company) AS OF TIMESTAMP "` + at + `" RETURN a.name, c.name`).Parse()
+		require.NoError(t, err)
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+
+		got := make(map[string]string)
+		for _, row := range result.Rows {
+			got[row["a.name"].(string)] = row["c.name"].(string)
+		}
+		return got
+	}
+
+	// Before any employment started
+	assert.Empty(t, employers(t, "2018-12-31"))
+
+	// Inside both Alice's and Bob's first windows
+	assert.Equal(t, map[string]string{"Alice": "Acme", "Bob": "Globex"}, employers(t, "2021-05-01"))
+
+	// Bounds are inclusive
+	assert.Equal(t, map[string]string{"Alice": "Acme", "Bob": "Globex"}, employers(t, "2021-05-31"))
+	assert.Equal(t, map[string]string{"Alice": "Acme"}, employers(t, "2021-06-01"))
+
+	// After Alice moved; her open-ended edge has no "to"
+	assert.Equal(t, map[string]string{"Alice": "Globex"}, employers(t, "2025-01-01"))
+
+	// Without AS OF every edge matches
+	q, err := NewParser(`MATCH (a:Person)-[r:EMPLOYED]->(c:Company) RETURN a.name`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 3)
+}
+
+func TestExecute_AsOfTimestampReverseAndVarLength(t *testing.T) {
+	g := storage.NewGraph()
+	a, _ := g.AddNode("Station", graph.Properties{"name": "A"})
+	b, _ := g.AddNode("Station", graph.Properties{"name": "B"})
+	c, _ := g.AddNode("Station", graph.Properties{"name": "C"})
+	g.AddEdge(a.ID, b.ID, "LINK", graph.Properties{"from": "2000"})
+	g.AddEdge(b.ID, c.ID, "LINK", graph.Properties{"from": "2010", "to": "2015"})
+	g.AddEdge(a.ID, c.ID, "LINK", graph.Properties{"from": "not a date"})
+
+	run := func(input string) []Row {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+		return result.Rows
+	}
+
+	// Every hop of a variable-length path must be valid
+	rows := run(`MATCH (s {name: "A"})-[:LINK*1..2]->(t) AS OF TIMESTAMP "2012-01-01" RETURN t.name`)
+	assert.Len(t, rows, 2)
+	rows = run(`MATCH (s {name: "A"})-[:LINK*1..2]->(t) AS OF TIMESTAMP "2020-01-01" RETURN t.name`)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "B", rows[0]["t.name"])
+
+	// Expanding backwards from the end node applies the same filter
+	rows = run(`MATCH (s)-[:LINK]->(t {name: "C"}) AS OF TIMESTAMP "2020-01-01" RETURN s.name`)
+	assert.Empty(t, rows)
+	rows = run(`MATCH (s)-[:LINK]->(t {name: "C"}) AS OF TIMESTAMP "2012-01-01" RETURN s.name`)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "B", rows[0]["s.name"])
+}
+
+func TestExecute_DatetimeValues(t *testing.T) {
+	g := storage.NewGraph()
+	g.AddNode("Event", graph.Properties{"name": "launch", "at": time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)})
+	g.AddNode("Event", graph.Properties{"name": "retro", "at": time.Date(2023, 11, 20, 0, 0, 0, 0, time.UTC)})
+	g.AddNode("Event", graph.Properties{"name": "draft", "at": "2024-05-01"})
+
+	run := func(input string) []Row {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		result, err := q.Execute(g)
+		require.NoError(t, err)
+		return result.Rows
+	}
+
+	rows := run(`MATCH (e:Event) WHERE e.at > datetime("2024-01-01") RETURN e.name`)
+	names := make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		names = append(names, row["e.name"])
+	}
+	// ISO strings compare as times against a datetime
+	assert.ElementsMatch(t, []interface{}{"launch", "draft"}, names)
+
+	rows = run(`MATCH (e:Event) WHERE e.at = datetime("2023-11-20T00:00:00Z") RETURN e.name`)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "retro", rows[0]["e.name"])
+
+	rows = run(`MATCH (e:Event) WHERE e.at < datetime() RETURN e.name`)
+	assert.Len(t, rows, 3)
+
+	_, err := fnDatetime([]interface{}{"not a date"})
+	assert.Error(t, err)
+}
+
+func TestExecute_EntityTimestamps(t *testing.T) {
+	g := storage.NewGraph()
+	before := time.Now()
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob", "created_at": "imported"})
+	g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+
+	q, err := NewParser(`MATCH (a:Person)-[r:KNOWS]->(b:Person) RETURN a.created_at, updated(a), created(r), b.created_at`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	row := result.Rows[0]
+
+	assert.Equal(t, alice.CreatedAt, row["a.created_at"])
+	assert.Equal(t, alice.UpdatedAt, row["updated(a)"])
+	created, ok := row["created(r)"].(time.Time)
+	require.True(t, ok)
+	assert.False(t, created.Before(before))
+
+	// A stored property of the same name takes precedence
+	assert.Equal(t, "imported", row["b.created_at"])
+
+	q, err = NewParser(`MATCH (p:Person) WHERE p.created_at >= datetime("2000") RETURN p.name`).Parse()
+	require.NoError(t, err)
+	result, err = q.Execute(g)
+	require.NoError(t, err)
+	assert.Len(t, result.Rows, 1)
+
+	_, err = fnCreated([]interface{}{"Alice"})
+	assert.Error(t, err)
+}
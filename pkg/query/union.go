@@ -0,0 +1,78 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// executeUnion runs both sides of a UNION and concatenates their rows,
+// removing duplicates unless it is UNION ALL
+func (q *Query) executeUnion(g GraphStorage) (*Result, error) {
+	left, err := q.unionPart(q.Union.Left).Execute(g)
+	if err != nil {
+		return nil, err
+	}
+	right, err := q.unionPart(q.Union.Right).Execute(g)
+	if err != nil {
+		return nil, err
+	}
+	if !sameColumns(left.Columns, right.Columns) {
+		return nil, fmt.Errorf("UNION queries must return the same columns, got %s and %s",
+			strings.Join(left.Columns, ", "), strings.Join(right.Columns, ", "))
+	}
+
+	rows := make([]Row, 0, len(left.Rows)+len(right.Rows))
+	rows = append(append(rows, left.Rows...), right.Rows...)
+	if q.Union.Distinct {
+		seen := make(map[string]bool, len(rows))
+		unique := rows[:0]
+		values := make([]interface{}, len(left.Columns))
+		for _, row := range rows {
+			for i, col := range left.Columns {
+				values[i] = row[col]
+			}
+			key := distinctKey(values)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			unique = append(unique, row)
+		}
+		rows = unique
+	}
+
+	return &Result{Columns: left.Columns, Rows: rows}, nil
+}
+
+// unionPart returns a side of the union with the execution settings of
+// the union query where the side does not set its own
+func (q *Query) unionPart(side *Query) *Query {
+	part := *side
+	if part.HopLimit == nil {
+		part.HopLimit = q.HopLimit
+	}
+	if part.ScanParallelism == nil {
+		part.ScanParallelism = q.ScanParallelism
+	}
+	if part.Temporal == nil {
+		part.Temporal = q.Temporal
+	}
+	if part.Parameters == nil {
+		part.Parameters = q.Parameters
+	}
+	part.StableOrder = part.StableOrder || q.StableOrder
+	return &part
+}
+
+// sameColumns reports whether a and b name the same columns in order
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
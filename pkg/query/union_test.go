@@ -0,0 +1,93 @@
+package query
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// names returns the sorted values of column in result
+func names(result *Result, column string) []string {
+	values := make([]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		values = append(values, row[column].(string))
+	}
+	sort.Strings(values)
+	return values
+}
+
+func TestParser_Union(t *testing.T) {
+	q, err := NewParser(`MATCH (n:Person) RETURN n.name UNION ALL MATCH (n:Company) RETURN n.name UNION ALL RETURN "x" AS name`).Parse()
+	require.NoError(t, err)
+	require.NotNil(t, q.Union)
+	assert.False(t, q.Union.Distinct)
+	assert.Equal(t, "name", q.Union.Right.Return.Items[0].Alias)
+	require.NotNil(t, q.Union.Left.Union)
+	assert.Equal(t, "Person", q.Union.Left.Union.Left.Match.Patterns[0].Nodes[0].Label)
+	assert.Equal(t, "Company", q.Union.Left.Union.Right.Match.Patterns[0].Nodes[0].Label)
+
+	_, err = NewParser(`RETURN 1 AS x UNION RETURN 2 AS x UNION ALL RETURN 3 AS x`).Parse()
+	assert.ErrorContains(t, err, "cannot mix UNION and UNION ALL")
+	_, err = NewParser(`RETURN 1 AS x UNION`).Parse()
+	assert.ErrorContains(t, err, "expected a query after UNION")
+}
+
+func TestExecute_Union(t *testing.T) {
+	g := createTestGraph(t)
+
+	result := run(t, g, `MATCH (n:Person) RETURN n.name UNION MATCH (n:Company) RETURN n.name`)
+	assert.Equal(t, []string{"n.name"}, result.Columns)
+	assert.Equal(t, []string{"Alice", "Bob", "Charlie", "Google"}, names(result, "n.name"))
+
+	// UNION removes duplicates, also within one side
+	result = run(t, g, `MATCH (n:Person) RETURN n.city AS city UNION MATCH (n:Person) RETURN n.city AS city`)
+	assert.Equal(t, []string{"NY", "SF"}, names(result, "city"))
+}
+
+func TestExecute_UnionAll(t *testing.T) {
+	g := createTestGraph(t)
+
+	result := run(t, g, `MATCH (n:Person) RETURN n.city AS city UNION ALL MATCH (n:Person) RETURN n.city AS city`)
+	assert.Equal(t, []string{"NY", "NY", "SF", "SF", "SF", "SF"}, names(result, "city"))
+}
+
+func TestExecute_UnionLimits(t *testing.T) {
+	g := storage.NewGraph()
+	for _, name := range []string{"a", "b", "c"} {
+		g.AddNode("Person", graph.Properties{"name": name})
+		g.AddNode("Company", graph.Properties{"name": name + "-inc"})
+	}
+
+	// Each side applies its own LIMIT
+	result := run(t, g, `MATCH (n:Person) RETURN n.name LIMIT 1 UNION ALL MATCH (n:Company) RETURN n.name LIMIT 2`)
+	assert.Equal(t, []string{"a", "a-inc", "b-inc"}, names(result, "n.name"))
+}
+
+func TestExecute_UnionColumnMismatch(t *testing.T) {
+	g := createTestGraph(t)
+
+	for _, input := range []string{
+		`MATCH (n:Person) RETURN n.name UNION MATCH (n:Company) RETURN n.name AS name`,
+		`RETURN 1 AS a, 2 AS b UNION RETURN 2 AS b, 1 AS a`,
+	} {
+		q, err := NewParser(input).Parse()
+		require.NoError(t, err)
+		_, err = q.Execute(g)
+		assert.ErrorContains(t, err, "UNION queries must return the same columns", input)
+	}
+}
+
+func TestExecute_UnionParameters(t *testing.T) {
+	g := createTestGraph(t)
+
+	q, err := NewParser(`MATCH (n:Person) WHERE n.age > $min RETURN n.name UNION MATCH (n:Company) WHERE n.name = $company RETURN n.name`).Parse()
+	require.NoError(t, err)
+	q.Parameters = map[string]interface{}{"min": 28, "company": "Google"}
+	result, err := q.Execute(g)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice", "Charlie", "Google"}, names(result, "n.name"))
+}
@@ -0,0 +1,179 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError is a semantic error Validate found in a query
+type ValidationError struct {
+	Clause   string // Clause the error is in, such as "WHERE" or "RETURN"
+	Variable string // The unbound variable, for ErrUnboundVariable errors
+	Message  string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Clause + ": " + e.Message
+}
+
+// Is makes errors.Is(err, ErrUnboundVariable) hold for unbound variables
+func (e *ValidationError) Is(target error) bool {
+	return e.Variable != "" && target == ErrUnboundVariable
+}
+
+// ValidationErrors is every error Validate found, in query order
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap lets errors.Is and errors.As see each error
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Validate checks a parsed query without running it: it builds the
+// execution plan and checks that every variable an expression refers to
+// is bound where it is used. Planning errors are returned as they are;
+// semantic errors are returned together as ValidationErrors. Parameters
+// are not checked, since they are supplied at execution.
+func Validate(q *Query) error {
+	if q.Union != nil {
+		var errs ValidationErrors
+		for _, side := range []*Query{q.Union.Left, q.Union.Right} {
+			err := Validate(side)
+			if sideErrs, ok := err.(ValidationErrors); ok {
+				errs = append(errs, sideErrs...)
+			} else if err != nil {
+				return err
+			}
+		}
+		if len(errs) > 0 {
+			return errs
+		}
+		return nil
+	}
+	if q.Show == nil && !q.standaloneCall() {
+		if _, err := BuildExecutionPlan(q); err != nil {
+			return err
+		}
+	}
+
+	var errs ValidationErrors
+	validatePart(q, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validatePart checks one part of a query and returns the variables bound
+// at its end
+func validatePart(q *Query, errs *ValidationErrors) map[string]bool {
+	scope := make(map[string]bool)
+	if q.Input != nil {
+		scope = validatePart(q.Input, errs)
+	}
+	check := func(clause string, expr Expression, scope map[string]bool) {
+		referencedVariables(expr, func(name string) {
+			if !scope[name] {
+				*errs = append(*errs, &ValidationError{
+					Clause:   clause,
+					Variable: name,
+					Message:  fmt.Sprintf("variable %s not found", name),
+				})
+			}
+		})
+	}
+
+	if q.Match != nil {
+		for _, pattern := range q.Match.Patterns {
+			if pattern.Variable != "" {
+				scope[pattern.Variable] = true
+			}
+			for _, node := range pattern.Nodes {
+				if node.Variable != "" {
+					scope[node.Variable] = true
+				}
+			}
+			for _, edge := range pattern.Edges {
+				if edge.Variable != "" {
+					scope[edge.Variable] = true
+				}
+			}
+		}
+	}
+	if q.Where != nil {
+		check("WHERE", q.Where.Expr, scope)
+	}
+	if q.Call != nil {
+		for _, arg := range q.Call.Args {
+			check("CALL", arg, scope)
+		}
+	}
+
+	// Property values in CREATE see only variables bound before it
+	if q.Create != nil {
+		for _, pattern := range q.Create.Patterns {
+			for _, node := range pattern.Nodes {
+				for _, value := range node.Properties {
+					if expr, ok := value.(Expression); ok {
+						check("CREATE", expr, scope)
+					}
+				}
+			}
+			for _, edge := range pattern.Edges {
+				for _, value := range edge.Properties {
+					if expr, ok := value.(Expression); ok {
+						check("CREATE", expr, scope)
+					}
+				}
+			}
+		}
+	}
+
+	if q.With != nil {
+		projected := make(map[string]bool, len(q.With.Items))
+		for _, item := range q.With.Items {
+			check("WITH", item.Expr, scope)
+			projected[item.variable()] = true
+		}
+		if q.With.Where != nil {
+			check("WITH", q.With.Where.Expr, projected)
+		}
+		return projected
+	}
+
+	if q.Return != nil {
+		for _, item := range q.Return.Items {
+			check("RETURN", item.Expr, scope)
+		}
+	}
+
+	// ORDER BY may also name RETURN columns, and any bound variable after
+	// RETURN *
+	if q.OrderBy != nil && (q.Return == nil || !q.Return.Star) {
+		sortScope := make(map[string]bool, len(scope))
+		for name := range scope {
+			sortScope[name] = true
+		}
+		if q.Return != nil {
+			for _, item := range q.Return.Items {
+				sortScope[item.columnName()] = true
+			}
+		}
+		for _, field := range q.OrderBy.Fields {
+			check("ORDER BY", field.Expr, sortScope)
+		}
+	}
+	return scope
+}
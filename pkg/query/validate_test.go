@@ -0,0 +1,85 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validate(t *testing.T, input string) error {
+	q, err := NewParser(input).Parse()
+	require.NoError(t, err, input)
+	return Validate(q)
+}
+
+func TestValidate(t *testing.T) {
+	for _, input := range []string{
+		`MATCH (p:Person)-[r:KNOWS]->(f) WHERE p.age > 30 AND r.since < 2020 RETURN p.name, f`,
+		`MATCH (p:Person) RETURN p.name AS name ORDER BY name`,
+		`MATCH (p:Person) RETURN * ORDER BY p.age`,
+		`MATCH (p:Person) WHERE size((p)-[:KNOWS]->()) > 1 RETURN p`,
+		`MATCH (p:Person) WHERE p.age > $min RETURN p`,
+		`MATCH (a)-[:KNOWS]->(b) WITH DISTINCT b, a.age AS age WHERE age > 1 MATCH (b)-->(x) RETURN b, x, age`,
+		`RETURN 1 + 1`,
+		`CREATE (a:Person {name: "A"})-[:KNOWS]->(b)`,
+		`SHOW INDEXES`,
+	} {
+		assert.NoError(t, validate(t, input), input)
+	}
+}
+
+func TestValidate_UnboundReturn(t *testing.T) {
+	err := validate(t, `MATCH (p:Person) RETURN q.name`)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrUnboundVariable)
+
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 1)
+	assert.Equal(t, &ValidationError{Clause: "RETURN", Variable: "q", Message: "variable q not found"}, errs[0])
+	assert.EqualError(t, err, "RETURN: variable q not found")
+}
+
+func TestValidate_TypoInWhere(t *testing.T) {
+	err := validate(t, `MATCH (person:Person)-[:KNOWS]->(friend) WHERE persn.age > 30 AND freind.age < 20 RETURN friend.name`)
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	assert.Equal(t, "WHERE", errs[0].Clause)
+	assert.Equal(t, "persn", errs[0].Variable)
+	assert.Equal(t, "freind", errs[1].Variable)
+}
+
+func TestValidate_Scopes(t *testing.T) {
+	// Only the WITH variables are visible after WITH
+	err := validate(t, `MATCH (a)-[:KNOWS]->(b) WITH b RETURN a.name`)
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 1)
+	assert.Equal(t, "a", errs[0].Variable)
+
+	err = validate(t, `MATCH (a) WITH a.age AS age WHERE a.age > 1 RETURN age`)
+	require.True(t, errors.As(err, &errs))
+	assert.Equal(t, "WITH", errs[0].Clause)
+
+	err = validate(t, `MATCH (a) RETURN a.name ORDER BY b.name`)
+	require.True(t, errors.As(err, &errs))
+	assert.Equal(t, "ORDER BY", errs[0].Clause)
+
+	err = validate(t, `CREATE (a:Person {tags: [x.name]})`)
+	require.True(t, errors.As(err, &errs))
+	assert.Equal(t, "CREATE", errs[0].Clause)
+
+	// Planning errors are returned as they are
+	err = validate(t, `MATCH (a)-[:KNOWS*1..2 {since: 2020}]->(b) RETURN b`)
+	assert.ErrorContains(t, err, "variable-length")
+}
+
+func TestValidate_Union(t *testing.T) {
+	assert.NoError(t, validate(t, `MATCH (n:Person) RETURN n.name UNION MATCH (m:Company) RETURN m.name AS name`))
+
+	err := validate(t, `MATCH (n:Person) RETURN n.name UNION MATCH (m:Company) RETURN n.name`)
+	assert.ErrorIs(t, err, ErrUnboundVariable)
+}
@@ -0,0 +1,150 @@
+// Package report builds a one-page structural summary of a graph
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/fnuworsu/rdgDB/pkg/algorithms"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// TopN is how many labels and edge types a report lists
+const TopN = 5
+
+func init() {
+	query.RegisterProcedure("db.report", procReport)
+}
+
+// Count is a label or edge type with its number of occurrences
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Report summarizes the size and shape of a graph
+type Report struct {
+	Nodes            int     `json:"nodes"`
+	Edges            int     `json:"edges"`
+	TopLabels        []Count `json:"top_labels"`
+	TopEdgeTypes     []Count `json:"top_edge_types"`
+	AvgDegree        float64 `json:"avg_degree"`
+	MaxDegree        int     `json:"max_degree"`
+	Density          float64 `json:"density"`
+	Components       int     `json:"components"`
+	LargestComponent int     `json:"largest_component"`
+	IsDAG            bool    `json:"is_dag"`
+}
+
+// Generate computes the report for g
+func Generate(g *storage.Graph) *Report {
+	degrees := algorithms.DegreeStats(g)
+	components := algorithms.WeaklyConnectedComponents(g)
+
+	r := &Report{
+		Nodes:        g.NodeCount(),
+		Edges:        g.EdgeCount(),
+		TopLabels:    topCounts(g.LabelCounts(), TopN),
+		TopEdgeTypes: topCounts(g.EdgeTypeCounts(), TopN),
+		AvgDegree:    degrees.Average,
+		MaxDegree:    degrees.Max,
+		Density:      algorithms.Density(g),
+		Components:   len(components),
+		IsDAG:        algorithms.IsDAG(g),
+	}
+	if len(components) > 0 {
+		r.LargestComponent = len(components[0])
+	}
+	return r
+}
+
+// WriteText writes the report as aligned plain text
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "Graph Summary")
+	fmt.Fprintf(tw, "  Nodes\t%d\n", r.Nodes)
+	fmt.Fprintf(tw, "  Edges\t%d\n", r.Edges)
+	fmt.Fprintf(tw, "  Average degree\t%.2f\n", r.AvgDegree)
+	fmt.Fprintf(tw, "  Max degree\t%d\n", r.MaxDegree)
+	fmt.Fprintf(tw, "  Density\t%.4f\n", r.Density)
+	fmt.Fprintf(tw, "  Components\t%d\n", r.Components)
+	fmt.Fprintf(tw, "  Largest component\t%d\n", r.LargestComponent)
+	fmt.Fprintf(tw, "  DAG\t%t\n", r.IsDAG)
+
+	writeCounts(tw, "Top labels", r.TopLabels)
+	writeCounts(tw, "Top edge types", r.TopEdgeTypes)
+
+	return tw.Flush()
+}
+
+func writeCounts(w io.Writer, title string, counts []Count) {
+	fmt.Fprintf(w, "\n%s\n", title)
+	if len(counts) == 0 {
+		fmt.Fprintln(w, "  (none)")
+		return
+	}
+	for _, c := range counts {
+		fmt.Fprintf(w, "  %s\t%d\n", c.Name, c.Count)
+	}
+}
+
+// topCounts returns the n largest counts, ties broken by name
+func topCounts(counts map[string]int, n int) []Count {
+	result := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, Count{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// procReport implements CALL db.report(), returning one metric per row
+func procReport(g query.GraphStorage, args []interface{}) (*query.Result, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("db.report expects no arguments, got %d", len(args))
+	}
+
+	var base *storage.Graph
+	switch s := g.(type) {
+	case *storage.Graph:
+		base = s
+	case *storage.PersistentGraph:
+		base = s.Graph
+	default:
+		return nil, fmt.Errorf("db.report is not supported for %T", g)
+	}
+
+	r := Generate(base)
+	result := &query.Result{Columns: []string{"metric", "value"}}
+	add := func(metric string, value interface{}) {
+		result.Rows = append(result.Rows, query.Row{"metric": metric, "value": value})
+	}
+
+	add("nodes", r.Nodes)
+	add("edges", r.Edges)
+	add("avg_degree", r.AvgDegree)
+	add("max_degree", r.MaxDegree)
+	add("density", r.Density)
+	add("components", r.Components)
+	add("largest_component", r.LargestComponent)
+	add("is_dag", r.IsDAG)
+	for _, c := range r.TopLabels {
+		add("label:"+c.Name, c.Count)
+	}
+	for _, c := range r.TopEdgeTypes {
+		add("edge_type:"+c.Name, c.Count)
+	}
+	return result, nil
+}
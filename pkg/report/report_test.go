@@ -0,0 +1,78 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedGraph builds the same data as the REPL's seed command
+func seedGraph(t *testing.T) *storage.Graph {
+	g := storage.NewGraph()
+	alice, err := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	charlie, _ := g.AddNode("Person", graph.Properties{"name": "Charlie"})
+	google, _ := g.AddNode("Company", graph.Properties{"name": "Google"})
+
+	g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	g.AddEdge(bob.ID, charlie.ID, "KNOWS", nil)
+	g.AddEdge(alice.ID, google.ID, "WORKS_AT", nil)
+	g.AddEdge(bob.ID, google.ID, "WORKS_AT", nil)
+	return g
+}
+
+func TestGenerate(t *testing.T) {
+	r := Generate(seedGraph(t))
+
+	assert.Equal(t, 4, r.Nodes)
+	assert.Equal(t, 4, r.Edges)
+	require.NotEmpty(t, r.TopLabels)
+	assert.Equal(t, Count{Name: "Person", Count: 3}, r.TopLabels[0])
+	assert.Equal(t, []Count{{"KNOWS", 2}, {"WORKS_AT", 2}}, r.TopEdgeTypes)
+	assert.Equal(t, 2.0, r.AvgDegree)
+	assert.Equal(t, 3, r.MaxDegree)
+	assert.InDelta(t, 4.0/12.0, r.Density, 1e-9)
+	assert.Equal(t, 1, r.Components)
+	assert.Equal(t, 4, r.LargestComponent)
+	assert.True(t, r.IsDAG)
+
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"top_labels":[{"name":"Person","count":3}`)
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Generate(seedGraph(t)).WriteText(&buf))
+
+	out := buf.String()
+	assert.Contains(t, out, "  Nodes              4\n")
+	assert.Contains(t, out, "  Largest component  4\n")
+	assert.Contains(t, out, "  Person   3\n  Company  1\n")
+
+	buf.Reset()
+	require.NoError(t, Generate(storage.NewGraph()).WriteText(&buf))
+	assert.Contains(t, buf.String(), "(none)")
+}
+
+func TestCallReport(t *testing.T) {
+	q, err := query.NewParser(`CALL db.report()`).Parse()
+	require.NoError(t, err)
+	result, err := q.Execute(seedGraph(t))
+	require.NoError(t, err)
+
+	values := make(map[string]interface{})
+	for _, row := range result.Rows {
+		values[row["metric"].(string)] = row["value"]
+	}
+	assert.Equal(t, 4, values["nodes"])
+	assert.Equal(t, 4, values["edges"])
+	assert.Equal(t, 3, values["label:Person"])
+}
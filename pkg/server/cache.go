@@ -0,0 +1,167 @@
+package server
+
+import (
+	"container/list"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/pkg/query"
+)
+
+// CacheStats counts query cache lookups
+type CacheStats struct {
+	Entries   int    `json:"cache_entries"`
+	Hits      uint64 `json:"cache_hits"`
+	Misses    uint64 `json:"cache_misses"`
+	Evictions uint64 `json:"cache_evictions"` // Dropped to make room
+	Stale     uint64 `json:"cache_stale"`     // Dropped after a mutation or past the maximum age
+}
+
+// cacheEntry is a cached result and the graph version it was computed at
+type cacheEntry struct {
+	key     string
+	result  *query.Result
+	version uint64
+	stored  time.Time
+	until   time.Time // When a node or edge expires; zero if none will
+}
+
+// queryCache is an LRU cache of query results. An entry is only served
+// while the graph version it was stored with is current, so any mutation
+// invalidates every entry at once, and until the next node or edge
+// expires, which hides it without a mutation. A capacity of zero disables
+// caching.
+type queryCache struct {
+	mu       sync.Mutex
+	capacity int
+	maxAge   time.Duration
+	now      func() time.Time
+	order    *list.List // Most recently used first
+	entries  map[string]*list.Element
+	stats    CacheStats
+}
+
+func newQueryCache(capacity int, maxAge time.Duration, now func() time.Time) *queryCache {
+	return &queryCache{
+		capacity: capacity,
+		maxAge:   maxAge,
+		now:      now,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns the result cached under key if it was computed at version
+func (c *queryCache) get(key string, version uint64) (*query.Result, bool) {
+	if c.capacity <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	now := c.now()
+	if entry.version != version || c.maxAge > 0 && now.Sub(entry.stored) > c.maxAge ||
+		!entry.until.IsZero() && !now.Before(entry.until) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		c.stats.Stale++
+		c.stats.Misses++
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.result, true
+}
+
+// put caches result under key until the time until, or indefinitely if
+// it is zero, evicting the least recently used entry when the cache is
+// full
+func (c *queryCache) put(key string, version uint64, until time.Time, result *query.Result) {
+	if c.capacity <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, result: result, version: version, stored: c.now(), until: until}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+// Stats returns the current cache counts
+func (c *queryCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
+
+// cacheKey identifies a query by its tokens and parameters, so queries
+// differing only in whitespace or keyword case share an entry
+func cacheKey(input string, parameters map[string]interface{}) (string, error) {
+	var b strings.Builder
+	lexer := query.NewLexer(input)
+	for {
+		tok := lexer.NextToken()
+		if tok.Type == query.TokenEOF {
+			break
+		}
+		b.WriteString(tok.Type.String())
+		switch tok.Type {
+		case query.TokenIdentifier, query.TokenString, query.TokenNumber, query.TokenParameter:
+			b.WriteString(strconv.Quote(tok.Literal))
+		}
+		b.WriteByte(' ')
+	}
+
+	// Maps marshal with sorted keys
+	params, err := json.Marshal(parameters)
+	if err != nil {
+		return "", err
+	}
+	b.Write(params)
+	return b.String(), nil
+}
+
+// cacheable reports whether q's result depends only on the graph. A query
+// that reads the current time with datetime() always runs.
+func cacheable(q *query.Query) bool {
+	return readOnly(q) && !q.ReadsClock()
+}
+
+// readOnly reports whether q only reads the graph. CREATE writes and
+// procedures may have side effects, so a query with either in any of its
+// parts, or on either side of a UNION, always runs.
+func readOnly(q *query.Query) bool {
+	if q == nil {
+		return true
+	}
+	if q.Call != nil || q.Create != nil {
+		return false
+	}
+	if q.Union != nil && !(readOnly(q.Union.Left) && readOnly(q.Union.Right)) {
+		return false
+	}
+	return readOnly(q.Input)
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getMetrics decodes GET /metrics
+func getMetrics(t *testing.T, srv *Server) Metrics {
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var metrics Metrics
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &metrics))
+	return metrics
+}
+
+func TestQueryCache_HitAndInvalidation(t *testing.T) {
+	srv := createPeopleServer(t, 3, DefaultOptions())
+	req := QueryRequest{Query: `MATCH (p:Person) WHERE p.age >= $min RETURN p.age ORDER BY p.age`,
+		Parameters: map[string]interface{}{"min": 1}}
+
+	var page Page
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &page).Code)
+	assert.Equal(t, []float64{1, 2}, pageAges(page))
+
+	// Whitespace and keyword case do not matter; parameters do
+	same := QueryRequest{Query: "match (p:Person)\n  where p.age >= $min\nreturn p.age order by p.age",
+		Parameters: map[string]interface{}{"min": 1}}
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", same, &page).Code)
+	assert.Equal(t, []float64{1, 2}, pageAges(page))
+	assert.Equal(t, CacheStats{Entries: 1, Hits: 1, Misses: 1}, getMetrics(t, srv).CacheStats)
+
+	other := QueryRequest{Query: req.Query, Parameters: map[string]interface{}{"min": 2}}
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", other, &page).Code)
+	assert.Equal(t, []float64{2}, pageAges(page))
+
+	// A mutation makes the cached results stale
+	_, err := srv.graph.AddNode("Person", graph.Properties{"name": "p3", "age": 3})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &page).Code)
+	assert.Equal(t, []float64{1, 2, 3}, pageAges(page))
+	assert.Equal(t, CacheStats{Entries: 2, Hits: 1, Misses: 3, Stale: 1}, getMetrics(t, srv).CacheStats)
+}
+
+func TestQueryCache_CallNotCached(t *testing.T) {
+	srv := createPeopleServer(t, 1, DefaultOptions())
+	req := QueryRequest{Query: `CALL db.refreshStats("exact")`}
+
+	post(t, srv, "/query", req, nil)
+	post(t, srv, "/query", req, nil)
+	assert.Equal(t, CacheStats{}, getMetrics(t, srv).CacheStats)
+}
+
+func TestQueryCache_WritesNotCached(t *testing.T) {
+	srv := createPeopleServer(t, 1, DefaultOptions())
+	req := QueryRequest{Query: `CREATE (p:Person {name: "new"})`}
+
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, nil).Code)
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, nil).Code)
+	assert.Equal(t, 3, srv.graph.NodeCount())
+	assert.Equal(t, CacheStats{}, getMetrics(t, srv).CacheStats)
+
+	// Procedure calls are found on either side of a UNION
+	for _, input := range []string{
+		`MATCH (p:Person) RETURN p.name AS label UNION CALL db.labels()`,
+		`CALL db.labels() UNION MATCH (p:Person) RETURN p.name AS label`,
+	} {
+		q, err := query.NewParser(input).Parse()
+		require.NoError(t, err, input)
+		assert.False(t, cacheable(q), input)
+	}
+
+	// and in the parts before a WITH
+	call := &query.Query{Call: &query.CallClause{}}
+	assert.False(t, cacheable(&query.Query{Input: &query.Query{Input: call}}))
+	assert.True(t, cacheable(&query.Query{Union: &query.UnionQuery{Left: &query.Query{}, Right: &query.Query{Input: &query.Query{}}}}))
+}
+
+func TestQueryCache_CurrentTimeNotCached(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.Clock = func() time.Time { return now }
+	srv := createPeopleServer(t, 1, opts)
+	req := QueryRequest{Query: `MATCH (p:Person) RETURN p.age, datetime() AS now`}
+
+	// Well within the maximum age, the query still runs again
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, nil).Code)
+	now = now.Add(time.Second)
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, nil).Code)
+	assert.Equal(t, CacheStats{}, getMetrics(t, srv).CacheStats)
+
+	for input, want := range map[string]bool{
+		`MATCH (p:Person) WHERE p.born < datetime() RETURN p.age`:                           false,
+		`MATCH (p:Person) WITH p WHERE p.born < datetime() RETURN p.age`:                    false,
+		`MATCH (p:Person) RETURN p.born AS t UNION MATCH (p:Person) RETURN datetime() AS t`: false,
+		`MATCH (p:Person) WHERE p.born < datetime("2024-01-01") RETURN p.age`:               true,
+	} {
+		q, err := query.NewParser(input).Parse()
+		require.NoError(t, err, input)
+		assert.Equal(t, want, cacheable(q), input)
+	}
+}
+
+func TestQueryCache_Expiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	opts := DefaultOptions()
+	opts.Clock = clock
+	srv := createPeopleServer(t, 3, opts)
+	srv.graph.SetClock(clock)
+	require.NoError(t, srv.graph.SetExpiry(1, now.Add(10*time.Second)))
+	req := QueryRequest{Query: `MATCH (p:Person) RETURN p.age ORDER BY p.age`}
+
+	var page Page
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &page).Code)
+	assert.Equal(t, []float64{0, 1, 2}, pageAges(page))
+	now = now.Add(5 * time.Second)
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &page).Code)
+	assert.Equal(t, []float64{0, 1, 2}, pageAges(page))
+
+	// The node expires without a write, well within the maximum age, and
+	// the sweeper is off, so only the expiry time makes the result stale
+	now = now.Add(5 * time.Second)
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &page).Code)
+	assert.Equal(t, []float64{1, 2}, pageAges(page))
+	assert.Equal(t, CacheStats{Entries: 1, Hits: 1, Misses: 2, Stale: 1}, getMetrics(t, srv).CacheStats)
+
+	// With nothing left to expire, the new result is served until a write
+	now = now.Add(30 * time.Second)
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &page).Code)
+	assert.Equal(t, []float64{1, 2}, pageAges(page))
+	assert.Equal(t, uint64(2), getMetrics(t, srv).CacheStats.Hits)
+}
+
+// Run with -race: UpdateNode logs before it applies, so the cache must
+// not take the WAL index as the version of what the query read
+func TestQueryCache_ConcurrentSet(t *testing.T) {
+	opts := DefaultOptions()
+	opts.QueryCacheMaxAge = 0
+	srv := createPeopleServer(t, 1, opts)
+	req := QueryRequest{Query: `MATCH (p:Person) RETURN p.age`}
+
+	const rounds = 200
+	done := make(chan error)
+	go func() {
+		defer close(done)
+		for i := 1; i <= rounds; i++ {
+			if err := srv.graph.UpdateNode(1, graph.Properties{"age": i}); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case err, ok := <-done:
+			require.NoError(t, err)
+			running = ok
+		default:
+		}
+		require.Equal(t, http.StatusOK, post(t, srv, "/query", req, nil).Code)
+	}
+
+	// Whatever was cached along the way, the last write is seen
+	var page Page
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &page).Code)
+	assert.Equal(t, []float64{rounds}, pageAges(page))
+}
+
+func TestQueryCache_Eviction(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newQueryCache(2, time.Minute, func() time.Time { return now })
+	a, b, c := &query.Result{}, &query.Result{}, &query.Result{}
+
+	cache.put("a", 1, time.Time{}, a)
+	cache.put("b", 1, time.Time{}, b)
+	_, ok := cache.get("a", 1) // a is now the most recently used
+	require.True(t, ok)
+	cache.put("c", 1, time.Time{}, c)
+
+	_, ok = cache.get("b", 1)
+	assert.False(t, ok)
+	result, ok := cache.get("a", 1)
+	assert.True(t, ok)
+	assert.Same(t, a, result)
+	result, ok = cache.get("c", 1)
+	assert.True(t, ok)
+	assert.Same(t, c, result)
+	assert.Equal(t, CacheStats{Entries: 2, Hits: 3, Misses: 1, Evictions: 1}, cache.Stats())
+
+	// Entries past the maximum age are dropped
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get("a", 1)
+	assert.False(t, ok)
+	assert.Equal(t, 1, cache.Stats().Entries)
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/pkg/query"
+)
+
+// ErrTooManyCursors is returned when a client already holds the maximum
+// number of open cursors
+var ErrTooManyCursors = errors.New("too many open cursors")
+
+// ErrCursorNotFound is returned for an unknown, exhausted, closed or
+// expired cursor token
+var ErrCursorNotFound = errors.New("cursor not found")
+
+// Page is one page of a query result. Cursor is empty on the last page.
+type Page struct {
+	Columns []string    `json:"columns"`
+	Rows    []query.Row `json:"rows"`
+	Cursor  string      `json:"cursor,omitempty"`
+}
+
+// CursorStats counts cursors so that leaks are visible
+type CursorStats struct {
+	Open    int    `json:"open_cursors"`
+	Opened  uint64 `json:"cursors_opened"`
+	Closed  uint64 `json:"cursors_closed"`  // Exhausted or explicitly closed
+	Expired uint64 `json:"cursors_expired"` // Idle past the timeout
+}
+
+// cursor holds the unread rows of a paginated result
+type cursor struct {
+	client   string
+	columns  []string
+	rows     []query.Row
+	pageSize int
+	lastUsed time.Time
+}
+
+// cursorStore keeps paginated results between requests. Cursors idle for
+// longer than idleTimeout are dropped the next time the store is used.
+type cursorStore struct {
+	mu           sync.Mutex
+	cursors      map[string]*cursor
+	perClient    map[string]int
+	idleTimeout  time.Duration
+	maxPerClient int
+	now          func() time.Time
+	stats        CursorStats
+}
+
+func newCursorStore(idleTimeout time.Duration, maxPerClient int, now func() time.Time) *cursorStore {
+	return &cursorStore{
+		cursors:      make(map[string]*cursor),
+		perClient:    make(map[string]int),
+		idleTimeout:  idleTimeout,
+		maxPerClient: maxPerClient,
+		now:          now,
+	}
+}
+
+// open returns the first page of result. If more rows remain they are
+// kept under a new cursor whose token is set on the page.
+func (cs *cursorStore) open(client string, result *query.Result, pageSize int) (Page, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.expireLocked()
+
+	page := Page{Columns: result.Columns, Rows: result.Rows}
+	if len(result.Rows) <= pageSize {
+		return page, nil
+	}
+	if cs.maxPerClient > 0 && cs.perClient[client] >= cs.maxPerClient {
+		return Page{}, ErrTooManyCursors
+	}
+
+	token, err := newCursorToken()
+	if err != nil {
+		return Page{}, err
+	}
+	page.Rows = result.Rows[:pageSize]
+	page.Cursor = token
+	cs.cursors[token] = &cursor{
+		client:   client,
+		columns:  result.Columns,
+		rows:     result.Rows[pageSize:],
+		pageSize: pageSize,
+		lastUsed: cs.now(),
+	}
+	cs.perClient[client]++
+	cs.stats.Opened++
+	return page, nil
+}
+
+// next returns the following page of the cursor. The cursor is closed
+// once its last page has been returned.
+func (cs *cursorStore) next(token string) (Page, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.expireLocked()
+
+	c, ok := cs.cursors[token]
+	if !ok {
+		return Page{}, ErrCursorNotFound
+	}
+
+	n := c.pageSize
+	if n > len(c.rows) {
+		n = len(c.rows)
+	}
+	page := Page{Columns: c.columns, Rows: c.rows[:n]}
+	c.rows = c.rows[n:]
+	c.lastUsed = cs.now()
+
+	if len(c.rows) == 0 {
+		cs.removeLocked(token)
+		cs.stats.Closed++
+	} else {
+		page.Cursor = token
+	}
+	return page, nil
+}
+
+// close releases a cursor before it is exhausted
+func (cs *cursorStore) close(token string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.expireLocked()
+
+	if _, ok := cs.cursors[token]; !ok {
+		return ErrCursorNotFound
+	}
+	cs.removeLocked(token)
+	cs.stats.Closed++
+	return nil
+}
+
+// Stats returns the current cursor counts
+func (cs *cursorStore) Stats() CursorStats {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.expireLocked()
+
+	stats := cs.stats
+	stats.Open = len(cs.cursors)
+	return stats
+}
+
+// expireLocked drops cursors idle for longer than the timeout.
+// Caller holds cs.mu.
+func (cs *cursorStore) expireLocked() {
+	if cs.idleTimeout <= 0 {
+		return
+	}
+	cutoff := cs.now().Add(-cs.idleTimeout)
+	for token, c := range cs.cursors {
+		if c.lastUsed.Before(cutoff) {
+			cs.removeLocked(token)
+			cs.stats.Expired++
+		}
+	}
+}
+
+// removeLocked deletes a cursor. Caller holds cs.mu.
+func (cs *cursorStore) removeLocked(token string) {
+	c := cs.cursors[token]
+	delete(cs.cursors, token)
+	if cs.perClient[c.client]--; cs.perClient[c.client] == 0 {
+		delete(cs.perClient, c.client)
+	}
+}
+
+// newCursorToken returns a random opaque token
+func newCursorToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
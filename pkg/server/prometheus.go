@@ -0,0 +1,61 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// prometheusContentType is version 0.0.4 of the Prometheus text format,
+// which scrapers accept even when they prefer OpenMetrics
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// wantsPrometheus reports whether the client asks for the Prometheus text
+// format, as scrapers do in their Accept header. Clients that ask for
+// neither it nor OpenMetrics, like those sending no Accept header at
+// all, get JSON.
+func wantsPrometheus(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err == nil && (mediaType == "text/plain" || mediaType == "application/openmetrics-text") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writePrometheus writes m in the Prometheus text format
+func writePrometheus(w http.ResponseWriter, m Metrics) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	for _, metric := range []struct {
+		name, kind, help string
+		value            uint64
+	}{
+		{"rdgdb_open_cursors", "gauge", "Result cursors currently open.", uint64(m.Open)},
+		{"rdgdb_cursors_opened_total", "counter", "Result cursors opened.", m.Opened},
+		{"rdgdb_cursors_closed_total", "counter", "Result cursors exhausted or explicitly closed.", m.Closed},
+		{"rdgdb_cursors_expired_total", "counter", "Result cursors closed after idling past the timeout.", m.Expired},
+		{"rdgdb_query_cache_entries", "gauge", "Results held in the query cache.", uint64(m.Entries)},
+		{"rdgdb_query_cache_hits_total", "counter", "Queries answered from the query cache.", m.Hits},
+		{"rdgdb_query_cache_misses_total", "counter", "Cacheable queries not found in the query cache.", m.Misses},
+		{"rdgdb_query_cache_evictions_total", "counter", "Query cache entries dropped to make room.", m.Evictions},
+		{"rdgdb_query_cache_stale_total", "counter", "Query cache entries dropped after a mutation or past the maximum age.", m.Stale},
+		{"rdgdb_wal_corruption_total", "counter", "Corrupt WAL entries found by the integrity scanner, counted on every scan that finds them.", m.WALCorruptions},
+	} {
+		if err := writeMetric(w, metric.name, metric.kind, metric.help, metric.value); err != nil {
+			log.Printf("failed to write response: %v", err)
+			return
+		}
+	}
+}
+
+// writeMetric writes one sample with its HELP and TYPE lines
+func writeMetric(w io.Writer, name, kind, help string, value uint64) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, kind, name, value)
+	return err
+}
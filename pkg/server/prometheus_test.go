@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrapeAccept is the Accept header Prometheus sends when scraping
+const scrapeAccept = "application/openmetrics-text;version=1.0.0,application/openmetrics-text;version=0.0.1;q=0.75,text/plain;version=0.0.4;q=0.5,*/*;q=0.1"
+
+// scrape fetches GET /metrics as a Prometheus scraper would
+func scrape(t *testing.T, srv *Server) string {
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", scrapeAccept)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, prometheusContentType, rec.Header().Get("Content-Type"))
+	return rec.Body.String()
+}
+
+func TestMetrics_Prometheus(t *testing.T) {
+	srv := createPeopleServer(t, 3, DefaultOptions())
+	srv.walCorruptions.Add(2)
+	req := QueryRequest{Query: `MATCH (p:Person) RETURN p.name`}
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, nil).Code)
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, nil).Code)
+
+	body := scrape(t, srv)
+	assert.Contains(t, body, "# HELP rdgdb_wal_corruption_total ")
+	assert.Contains(t, body, "# TYPE rdgdb_wal_corruption_total counter\nrdgdb_wal_corruption_total 2\n")
+	assert.Contains(t, body, "# TYPE rdgdb_query_cache_hits_total counter\nrdgdb_query_cache_hits_total 1\n")
+	assert.Contains(t, body, "# TYPE rdgdb_query_cache_entries gauge\nrdgdb_query_cache_entries 1\n")
+	assert.Contains(t, body, "# TYPE rdgdb_open_cursors gauge\nrdgdb_open_cursors 0\n")
+
+	// Other clients still get JSON
+	assert.Equal(t, uint64(2), getMetrics(t, srv).WALCorruptions)
+}
+
+func TestWantsPrometheus(t *testing.T) {
+	for accept, want := range map[string]bool{
+		"":                 false,
+		"*/*":              false,
+		"application/json": false,
+		"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8": false,
+		"text/plain":                true,
+		"text/plain; version=0.0.4": true,
+		"application/openmetrics-text; version=1.0.0": true,
+		scrapeAccept: true,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		assert.Equal(t, want, wantsPrometheus(req), accept)
+	}
+}
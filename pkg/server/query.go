@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+)
+
+// QueryRequest is the body of POST /query
+type QueryRequest struct {
+	Query      string                 `json:"query"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+
+	// PageSize splits the result into pages of at most this many rows.
+	// Zero returns every row at once.
+	PageSize int `json:"pageSize,omitempty"`
+}
+
+// CursorRequest is the body of POST /query/next and POST /query/close
+type CursorRequest struct {
+	Cursor string `json:"cursor"`
+}
+
+// handleQuery runs a query and returns its rows, or the first page and a
+// cursor for the rest when a page size is given. The result is computed
+// once; later pages are served from the cursor without re-running it.
+// Read-only queries repeated before the graph changes are answered from
+// the query cache.
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req QueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.PageSize < 0 {
+		http.Error(w, "pageSize must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	q, err := query.NewParser(req.Query).Parse()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	q.StableOrder = s.stableOrder
+	if len(req.Parameters) > 0 {
+		q.Parameters = make(map[string]interface{}, len(req.Parameters))
+		for name, value := range req.Parameters {
+			q.Parameters[name] = graph.DecodeJSONValue(value)
+		}
+	}
+
+	result, err := s.execute(q, req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("query failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	page := Page{Columns: result.Columns, Rows: result.Rows}
+	if req.PageSize > 0 {
+		page, err = s.cursors.open(clientAddr(r), result, req.PageSize)
+		if errors.Is(err, ErrTooManyCursors) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to open cursor: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	writeJSON(w, page)
+}
+
+// execute runs q, serving read-only queries from the cache when the graph
+// has not changed since the result was stored
+func (s *Server) execute(q *query.Query, req QueryRequest) (*query.Result, error) {
+	if !cacheable(q) {
+		return q.Execute(s.graph)
+	}
+	key, err := cacheKey(req.Query, req.Parameters)
+	if err != nil {
+		return q.Execute(s.graph)
+	}
+
+	// The version is read first and only advances once a write is in
+	// memory, so a mutation racing with the query leaves the stored
+	// result under a version that is already stale
+	version := s.graph.Version()
+	if result, ok := s.cache.get(key, version); ok {
+		return result, nil
+	}
+	// Expired entities are hidden before the sweeper deletes them, so the
+	// result also goes stale when the next one expires
+	until, _ := s.graph.NextExpiry()
+	result, err := q.Execute(s.graph)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(key, version, until, result)
+	return result, nil
+}
+
+// handleQueryNext returns the next page of a cursor
+func (s *Server) handleQueryNext(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCursorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := s.cursors.next(req.Cursor)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, page)
+}
+
+// handleQueryClose releases a cursor before its last page
+func (s *Server) handleQueryClose(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeCursorRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.cursors.close(req.Cursor); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Metrics is the body of GET /metrics. Prometheus scrapers get the same
+// counters in its text format; see wantsPrometheus.
+type Metrics struct {
+	CursorStats
+	CacheStats
+
+	// WALCorruptions counts corrupt entries found by the WAL integrity
+	// scanner, once per scan that finds them
+	WALCorruptions uint64 `json:"rdgdb_wal_corruption_total"`
+}
+
+// handleMetrics reports server counters as JSON, or in the Prometheus
+// text format to clients that ask for it
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	metrics := Metrics{
+		CursorStats:    s.cursors.Stats(),
+		CacheStats:     s.cache.Stats(),
+		WALCorruptions: s.walCorruptions.Load(),
+	}
+	if wantsPrometheus(r) {
+		writePrometheus(w, metrics)
+		return
+	}
+	writeJSON(w, metrics)
+}
+
+func decodeCursorRequest(w http.ResponseWriter, r *http.Request) (CursorRequest, bool) {
+	var req CursorRequest
+	if !requireMethod(w, r, http.MethodPost) {
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Cursor == "" {
+		http.Error(w, "request must name a cursor", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// requireMethod rejects requests with any other method
+func requireMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+// clientAddr identifies the client for cursor limits by its IP address
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/query"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createPeopleServer serves a graph of n Person nodes with ages 0..n-1
+func createPeopleServer(t *testing.T, n int, opts Options) *Server {
+	g, err := storage.NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { g.Close() })
+
+	for i := 0; i < n; i++ {
+		_, err := g.AddNode("Person", graph.Properties{"name": fmt.Sprintf("p%d", i), "age": i})
+		require.NoError(t, err)
+	}
+	return NewWithOptions(g, opts)
+}
+
+// post sends body as JSON and decodes a JSON response into out, if given
+func post(t *testing.T, srv *Server, path string, body interface{}, out interface{}) *httptest.ResponseRecorder {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+	if out != nil && rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+	return rec
+}
+
+// pageAges returns the age column of a decoded page
+func pageAges(page Page) []float64 {
+	ages := make([]float64, len(page.Rows))
+	for i, row := range page.Rows {
+		ages[i] = row["p.age"].(float64)
+	}
+	return ages
+}
+
+func TestQueryEndpoint(t *testing.T) {
+	srv := createPeopleServer(t, 3, DefaultOptions())
+
+	var page Page
+	rec := post(t, srv, "/query", QueryRequest{
+		Query:      `MATCH (p:Person) WHERE p.age >= $min RETURN p.age ORDER BY p.age`,
+		Parameters: map[string]interface{}{"min": 1},
+	}, &page)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"p.age"}, page.Columns)
+	assert.Equal(t, []float64{1, 2}, pageAges(page))
+	assert.Empty(t, page.Cursor)
+
+	rec = post(t, srv, "/query", QueryRequest{Query: `MATCH (p RETURN p`}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/query", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestQueryShow(t *testing.T) {
+	srv := createPeopleServer(t, 3, DefaultOptions())
+	require.NoError(t, srv.graph.CreatePropertyIndex("Person", "name"))
+
+	var page Page
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", QueryRequest{Query: `SHOW LABELS`}, &page).Code)
+	assert.Equal(t, []string{"label", "count"}, page.Columns)
+	assert.Equal(t, []query.Row{{"label": "Person", "count": 3.0}}, page.Rows)
+
+	page = Page{}
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", QueryRequest{Query: `SHOW INDEXES`}, &page).Code)
+	assert.Equal(t, []query.Row{{"label": "Person", "property": "name", "type": "property"}}, page.Rows)
+}
+
+func TestQueryStableOrder(t *testing.T) {
+	opts := DefaultOptions()
+	opts.StableOrder = true
+	opts.QueryCacheSize = 0
+	srv := createPeopleServer(t, 20, opts)
+
+	want := make([]float64, 20)
+	for i := range want {
+		want[i] = float64(i)
+	}
+	for i := 0; i < 10; i++ {
+		var page Page
+		require.Equal(t, http.StatusOK, post(t, srv, "/query", QueryRequest{Query: `MATCH (p:Person) RETURN p.age`}, &page).Code)
+		assert.Equal(t, want, pageAges(page))
+	}
+}
+
+func TestQueryPagination(t *testing.T) {
+	srv := createPeopleServer(t, 5, DefaultOptions())
+
+	var page Page
+	rec := post(t, srv, "/query", QueryRequest{Query: `MATCH (p:Person) RETURN p.age ORDER BY p.age`, PageSize: 2}, &page)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []float64{0, 1}, pageAges(page))
+	require.NotEmpty(t, page.Cursor)
+	token := page.Cursor
+
+	var ages []float64
+	for page.Cursor != "" {
+		var next Page
+		rec = post(t, srv, "/query/next", CursorRequest{Cursor: page.Cursor}, &next)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []string{"p.age"}, next.Columns)
+		ages = append(ages, pageAges(next)...)
+		page = next
+	}
+	assert.Equal(t, []float64{2, 3, 4}, ages)
+
+	// The cursor is gone after its last page
+	rec = post(t, srv, "/query/next", CursorRequest{Cursor: token}, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// A result that fits in one page needs no cursor
+	rec = post(t, srv, "/query", QueryRequest{Query: `MATCH (p:Person) RETURN p.age`, PageSize: 5}, &page)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, page.Rows, 5)
+	assert.Empty(t, page.Cursor)
+
+	stats := srv.cursors.Stats()
+	assert.Equal(t, CursorStats{Open: 0, Opened: 1, Closed: 1}, stats)
+}
+
+func TestQueryCursorClose(t *testing.T) {
+	srv := createPeopleServer(t, 5, DefaultOptions())
+
+	var page Page
+	post(t, srv, "/query", QueryRequest{Query: `MATCH (p:Person) RETURN p.age`, PageSize: 1}, &page)
+	require.NotEmpty(t, page.Cursor)
+
+	rec := post(t, srv, "/query/close", CursorRequest{Cursor: page.Cursor}, nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	rec = post(t, srv, "/query/close", CursorRequest{Cursor: page.Cursor}, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = post(t, srv, "/query/next", CursorRequest{Cursor: page.Cursor}, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = post(t, srv, "/query/next", CursorRequest{}, nil)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestQueryCursorLimitAndExpiry(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+
+	srv := createPeopleServer(t, 3, Options{CursorIdleTimeout: time.Minute, MaxCursorsPerClient: 2, Clock: clock})
+	req := QueryRequest{Query: `MATCH (p:Person) RETURN p.age`, PageSize: 1}
+
+	var first, second Page
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &first).Code)
+	advance(30 * time.Second)
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &second).Code)
+	assert.Equal(t, http.StatusTooManyRequests, post(t, srv, "/query", req, nil).Code)
+
+	// Using a cursor keeps it alive; the other one expires
+	advance(40 * time.Second)
+	require.Equal(t, http.StatusOK, post(t, srv, "/query/next", CursorRequest{Cursor: second.Cursor}, nil).Code)
+	assert.Equal(t, http.StatusNotFound, post(t, srv, "/query/next", CursorRequest{Cursor: first.Cursor}, nil).Code)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stats CursorStats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, CursorStats{Open: 1, Opened: 2, Expired: 1}, stats)
+
+	// The expired cursor no longer counts against the limit
+	assert.Equal(t, http.StatusOK, post(t, srv, "/query", req, nil).Code)
+}
+
+func TestQueryRelationshipJSON(t *testing.T) {
+	srv := createPeopleServer(t, 2, DefaultOptions())
+	_, err := srv.graph.AddEdge(1, 2, "KNOWS", graph.Properties{"since": 2020})
+	require.NoError(t, err)
+
+	rec := post(t, srv, "/query", QueryRequest{Query: `MATCH (a)-[r:KNOWS]->(b) RETURN r`}, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"columns": ["r"], "rows": [
+		{"r": {"id": 1, "type": "KNOWS", "source": 1, "target": 2, "properties": {"since": 2020}}}
+	]}`, rec.Body.String())
+}
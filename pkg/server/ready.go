@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// Readiness is the body of GET /ready
+type Readiness struct {
+	Ready bool `json:"ready"`
+
+	// Phase is the recovery phase: "starting" until recovery reports
+	// progress, then "snapshot", "wal" and "complete". Current and Total
+	// count its work, as in storage.RecoveryProgress.
+	Phase   string  `json:"phase"`
+	Current uint64  `json:"current"`
+	Total   uint64  `json:"total"`
+	Percent float64 `json:"percent"`
+
+	Nodes int `json:"nodes"`
+	Edges int `json:"edges"`
+}
+
+func newReadiness(p storage.RecoveryProgress) Readiness {
+	return Readiness{
+		Ready:   p.Phase == storage.RecoveryComplete,
+		Phase:   p.Phase.String(),
+		Current: p.Current,
+		Total:   p.Total,
+		Percent: p.Percent(),
+		Nodes:   p.Nodes,
+		Edges:   p.Edges,
+	}
+}
+
+// handleReady reports that the graph is recovered and serving
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	writeJSON(w, newReadiness(storage.RecoveryProgress{
+		Phase: storage.RecoveryComplete,
+		Nodes: s.graph.NodeCount(),
+		Edges: s.graph.EdgeCount(),
+	}))
+}
+
+// Startup answers HTTP requests while the graph is being recovered, before
+// a Server can be created for it. GET /ready reports the recovery
+// progress, and every request is answered 503 Service Unavailable, which
+// clients such as pkg/client retry. Once Ready is called, requests go to
+// the server. Listening from the start lets readiness probes watch a long
+// recovery.
+type Startup struct {
+	mu       sync.RWMutex
+	progress *storage.RecoveryProgress // Nil until the first report
+	server   *Server
+}
+
+// NewStartup creates a Startup with no recovery progress reported yet
+func NewStartup() *Startup {
+	return &Startup{}
+}
+
+// Progress records recovery progress. Pass it as
+// storage.Options.RecoveryProgress.
+func (s *Startup) Progress(p storage.RecoveryProgress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.progress = &p
+}
+
+// Ready hands requests over to srv
+func (s *Startup) Ready(srv *Server) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.server = srv
+}
+
+// ServeHTTP implements http.Handler
+func (s *Startup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	srv, progress := s.server, s.progress
+	s.mu.RUnlock()
+	if srv != nil {
+		srv.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Retry-After", "1")
+	if r.URL.Path != "/ready" {
+		http.Error(w, "graph is recovering", http.StatusServiceUnavailable)
+		return
+	}
+	if !requireMethod(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	// Not ready until the server takes over, even once recovery is complete
+	readiness := Readiness{Phase: "starting"}
+	if progress != nil {
+		readiness = newReadiness(*progress)
+		readiness.Ready = false
+	}
+	if err := json.NewEncoder(w).Encode(readiness); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
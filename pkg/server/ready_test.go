@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getReady requests GET /ready from h and decodes the answer
+func getReady(t *testing.T, h http.Handler) (int, Readiness) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	var readiness Readiness
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&readiness))
+	return rec.Code, readiness
+}
+
+func TestStartup(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	g, err := storage.NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	_, err = g.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+	require.NoError(t, g.Close())
+
+	startup := NewStartup()
+	code, readiness := getReady(t, startup)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, Readiness{Phase: "starting"}, readiness)
+
+	startup.Progress(storage.RecoveryProgress{Phase: storage.RecoveryWAL, Current: 250, Total: 1000, Nodes: 200})
+	code, readiness = getReady(t, startup)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, Readiness{Phase: "wal", Current: 250, Total: 1000, Percent: 25, Nodes: 200}, readiness)
+
+	// Everything else waits for the graph
+	rec := httptest.NewRecorder()
+	startup.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// Recovery reports through the startup until the server takes over
+	opts := storage.DefaultOptions()
+	opts.RecoveryOutput = io.Discard
+	opts.RecoveryProgress = startup.Progress
+	g, err = storage.NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer g.Close()
+	code, readiness = getReady(t, startup)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, Readiness{Phase: "complete", Percent: 100, Nodes: 1}, readiness)
+
+	srv := New(g)
+	defer srv.Close()
+	startup.Ready(srv)
+	code, readiness = getReady(t, startup)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Readiness{Ready: true, Phase: "complete", Percent: 100, Nodes: 1}, readiness)
+
+	rec = httptest.NewRecorder()
+	startup.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
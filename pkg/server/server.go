@@ -0,0 +1,259 @@
+// Package server exposes a PersistentGraph over HTTP
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/report"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+)
+
+// Server serves HTTP requests against a single graph
+type Server struct {
+	graph   *storage.PersistentGraph
+	mux     *http.ServeMux
+	cursors *cursorStore
+	cache   *queryCache
+
+	stableOrder bool
+
+	// Background WAL checksum verification; stopScan is nil when off
+	stopScan       context.CancelFunc
+	walCorruptions atomic.Uint64
+}
+
+// Options configures a Server
+type Options struct {
+	// CursorIdleTimeout drops pagination cursors unused for this long.
+	// Zero keeps them until they are exhausted or closed.
+	CursorIdleTimeout time.Duration
+
+	// MaxCursorsPerClient limits the open cursors of one client address.
+	// Zero means no limit.
+	MaxCursorsPerClient int
+
+	// QueryCacheSize is the number of query results kept for repeated
+	// queries. Any mutation of the graph invalidates them. Zero disables
+	// the cache.
+	QueryCacheSize int
+
+	// QueryCacheMaxAge bounds how long a result is served from the cache.
+	// Entities expiring and datetime() change results without a mutation,
+	// so this limits how stale they can be. Zero means no limit.
+	QueryCacheMaxAge time.Duration
+
+	// StableOrder runs every query with query.Query.StableOrder, so
+	// repeated queries return rows in the same order. Off by default, as
+	// it sorts every scan.
+	StableOrder bool
+
+	// WALScanInterval is how often the WAL's entry checksums are
+	// verified in the background. Corrupt entries are logged and counted
+	// in the metrics. Zero disables the scanner.
+	WALScanInterval time.Duration
+
+	// Clock decides when cursors go idle and cached results age. Nil
+	// means time.Now.
+	Clock func() time.Time
+}
+
+// Defaults used by DefaultOptions
+const (
+	DefaultCursorIdleTimeout   = 5 * time.Minute
+	DefaultMaxCursorsPerClient = 16
+	DefaultQueryCacheSize      = 256
+	DefaultQueryCacheMaxAge    = time.Minute
+	DefaultWALScanInterval     = time.Hour
+)
+
+// DefaultOptions returns the options used by New
+func DefaultOptions() Options {
+	return Options{
+		CursorIdleTimeout:   DefaultCursorIdleTimeout,
+		MaxCursorsPerClient: DefaultMaxCursorsPerClient,
+		QueryCacheSize:      DefaultQueryCacheSize,
+		QueryCacheMaxAge:    DefaultQueryCacheMaxAge,
+		WALScanInterval:     DefaultWALScanInterval,
+	}
+}
+
+// New creates a server for g
+func New(g *storage.PersistentGraph) *Server {
+	return NewWithOptions(g, DefaultOptions())
+}
+
+// NewWithOptions creates a server for g configured by opts
+func NewWithOptions(g *storage.PersistentGraph, opts Options) *Server {
+	clock := opts.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	s := &Server{
+		graph:   g,
+		mux:     http.NewServeMux(),
+		cursors: newCursorStore(opts.CursorIdleTimeout, opts.MaxCursorsPerClient, clock),
+		cache:   newQueryCache(opts.QueryCacheSize, opts.QueryCacheMaxAge, clock),
+
+		stableOrder: opts.StableOrder,
+	}
+	s.mux.HandleFunc("/admin/backup", s.handleBackup)
+	s.mux.HandleFunc("/admin/reindex", s.handleReindex)
+	s.mux.HandleFunc("/report", s.handleReport)
+	s.mux.HandleFunc("/nodes", s.handleAddNode)
+	s.mux.HandleFunc("/query", s.handleQuery)
+	s.mux.HandleFunc("/query/next", s.handleQueryNext)
+	s.mux.HandleFunc("/query/close", s.handleQueryClose)
+	s.mux.HandleFunc("/metrics", s.handleMetrics)
+	s.mux.HandleFunc("/ready", s.handleReady)
+	if opts.WALScanInterval > 0 {
+		s.stopScan = g.StartIntegrityScanner(opts.WALScanInterval, s.reportCorruption)
+	}
+	return s
+}
+
+// Close stops the server's background work. It does not close the graph.
+func (s *Server) Close() {
+	if s.stopScan != nil {
+		s.stopScan()
+	}
+}
+
+// reportCorruption logs and counts a corrupt WAL entry
+func (s *Server) reportCorruption(index uint64, err error) {
+	s.walCorruptions.Add(1)
+	log.Printf("WAL integrity check failed at entry %d: %v", index, err)
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleBackup streams a backup archive (see PersistentGraph.WriteBackup)
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filename := fmt.Sprintf("rdgdb-backup-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	cw := &countingWriter{w: w}
+	if _, err := s.graph.WriteBackup(cw); err != nil {
+		if cw.n == 0 {
+			w.Header().Del("Content-Disposition")
+			http.Error(w, fmt.Sprintf("backup failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		// The status is already sent; the client sees a truncated archive
+		log.Printf("backup failed after %d bytes: %v", cw.n, err)
+	}
+}
+
+// ReindexResponse is the body of a POST /admin/reindex response
+type ReindexResponse struct {
+	DurationMillis int64 `json:"duration_ms"`
+}
+
+// handleReindex rebuilds every index (see storage.Graph.Reindex)
+func (s *Server) handleReindex(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	start := time.Now()
+	if err := s.graph.Reindex(); err != nil {
+		http.Error(w, fmt.Sprintf("reindex failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	elapsed := time.Since(start)
+	log.Printf("reindexed in %s", elapsed)
+	writeJSON(w, ReindexResponse{DurationMillis: elapsed.Milliseconds()})
+}
+
+// NodeRequest is the body of POST /nodes. Properties are in the typed
+// form of graph.Properties.MarshalJSON; plain JSON values are accepted too.
+type NodeRequest struct {
+	Label      string           `json:"label"`
+	Properties graph.Properties `json:"properties,omitempty"`
+}
+
+// NodeResponse is the body of a POST /nodes response, with properties in
+// typed form so that they decode to the values stored
+type NodeResponse struct {
+	ID         graph.NodeID     `json:"id"`
+	Label      string           `json:"label"`
+	Properties graph.Properties `json:"properties"`
+}
+
+// handleAddNode adds a node and returns it with its assigned ID
+func (s *Server) handleAddNode(w http.ResponseWriter, r *http.Request) {
+	if !requireMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req NodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Label == "" {
+		http.Error(w, "request must give a label", http.StatusBadRequest)
+		return
+	}
+
+	node, err := s.graph.AddNode(req.Label, req.Properties)
+	switch {
+	case errors.Is(err, storage.ErrReadOnly):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, storage.ErrSchemaViolation):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, fmt.Sprintf("failed to add node: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	node.Mu.RLock()
+	resp := NodeResponse{ID: node.ID, Label: node.Label, Properties: node.Properties.Map()}
+	node.Mu.RUnlock()
+	writeJSON(w, resp)
+}
+
+// handleReport returns the graph summary report as JSON
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report.Generate(s.graph.Graph)); err != nil {
+		log.Printf("failed to write report: %v", err)
+	}
+}
+
+// countingWriter records how many bytes have been written through it
+type countingWriter struct {
+	w http.ResponseWriter
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
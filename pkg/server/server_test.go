@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/report"
+	"github.com/fnuworsu/rdgDB/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupEndpoint(t *testing.T) {
+	dataDir := t.TempDir()
+	g, err := storage.NewPersistentGraph(filepath.Join(dataDir, storage.WALSubdir), filepath.Join(dataDir, storage.SnapshotSubdir))
+	require.NoError(t, err)
+	defer g.Close()
+
+	alice, err := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+	bob, err := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	require.NoError(t, err)
+	_, err = g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	require.NoError(t, err)
+
+	srv := New(g)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/backup", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/backup", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/gzip", rec.Header().Get("Content-Type"))
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, os.WriteFile(archive, rec.Body.Bytes(), 0644))
+
+	restoreDir := filepath.Join(t.TempDir(), "restored")
+	_, err = storage.RestoreBackup(archive, restoreDir)
+	require.NoError(t, err)
+
+	restored, err := storage.NewPersistentGraph(filepath.Join(restoreDir, storage.WALSubdir), filepath.Join(restoreDir, storage.SnapshotSubdir))
+	require.NoError(t, err)
+	defer restored.Close()
+
+	assert.Equal(t, 2, restored.NodeCount())
+	assert.Equal(t, 1, restored.EdgeCount())
+}
+
+func TestReportEndpoint(t *testing.T) {
+	g, err := storage.NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer g.Close()
+
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	g.AddNode("Company", graph.Properties{"name": "Google"})
+	g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+
+	srv := New(g)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/report", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var r report.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+	assert.Equal(t, 3, r.Nodes)
+	assert.Equal(t, 1, r.Edges)
+	assert.Equal(t, []report.Count{{Name: "Person", Count: 2}, {Name: "Company", Count: 1}}, r.TopLabels)
+	assert.Equal(t, 2, r.Components)
+}
+
+func TestWALIntegrityScanner(t *testing.T) {
+	dataDir := t.TempDir()
+	walDir := filepath.Join(dataDir, storage.WALSubdir)
+	g, err := storage.NewPersistentGraph(walDir, filepath.Join(dataDir, storage.SnapshotSubdir))
+	require.NoError(t, err)
+	defer g.Close()
+
+	_, err = g.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+
+	opts := DefaultOptions()
+	opts.WALScanInterval = 5 * time.Millisecond
+	srv := NewWithOptions(g, opts)
+	defer srv.Close()
+	assert.Zero(t, getMetrics(t, srv).WALCorruptions)
+
+	path := filepath.Join(walDir, "wal.log")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, bytes.Replace(data, []byte(`"Alice"`), []byte(`"Alicf"`), 1), 0644))
+
+	assert.Eventually(t, func() bool {
+		return getMetrics(t, srv).WALCorruptions > 0
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.NotContains(t, scrape(t, srv), "rdgdb_wal_corruption_total 0\n")
+}
+
+func TestReindexEndpoint(t *testing.T) {
+	opts := DefaultOptions()
+	opts.QueryCacheSize = 0
+	srv := createPeopleServer(t, 10, opts)
+	require.NoError(t, srv.graph.CreatePropertyIndex("Person", "name"))
+
+	req := QueryRequest{Query: `MATCH (p:Person {name: "p7"}) RETURN p.age`}
+	var before Page
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &before).Code)
+	assert.Equal(t, []float64{7}, pageAges(before))
+
+	var resp ReindexResponse
+	require.Equal(t, http.StatusOK, post(t, srv, "/admin/reindex", nil, &resp).Code)
+	assert.GreaterOrEqual(t, resp.DurationMillis, int64(0))
+
+	var after Page
+	require.Equal(t, http.StatusOK, post(t, srv, "/query", req, &after).Code)
+	assert.Equal(t, before, after)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/reindex", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestAddNodeEndpoint(t *testing.T) {
+	srv := createPeopleServer(t, 0, DefaultOptions())
+	require.NoError(t, srv.graph.DefineSchema("Person", nil, []string{"name"}))
+
+	born := time.Date(1990, 5, 1, 12, 0, 0, 0, time.UTC)
+	var resp NodeResponse
+	rec := post(t, srv, "/nodes", NodeRequest{
+		Label:      "Person",
+		Properties: graph.Properties{"name": "Alice", "age": 30, "born": born},
+	}, &resp)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "Person", resp.Label)
+
+	// Typed values come back as stored, not as JSON numbers and strings
+	assert.Equal(t, 30, resp.Properties["age"])
+	assert.True(t, born.Equal(resp.Properties["born"].(time.Time)))
+	node, err := srv.graph.GetNode(resp.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", node.Properties.Map()["name"])
+
+	assert.Equal(t, http.StatusBadRequest, post(t, srv, "/nodes", NodeRequest{Label: "Person"}, nil).Code)
+	assert.Equal(t, http.StatusBadRequest, post(t, srv, "/nodes", NodeRequest{}, nil).Code)
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nodes", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
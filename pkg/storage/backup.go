@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BackupFormatVersion is the archive layout version written by Backup
+const BackupFormatVersion = 1
+
+// Data directory layout shared by the commands and RestoreBackup
+const (
+	WALSubdir      = "wal"
+	SnapshotSubdir = "snapshots"
+)
+
+// Archive member names
+const (
+	backupManifestName = "manifest.json"
+	backupSnapshotName = SnapshotSubdir + "/snapshot-latest.json"
+	backupWALName      = WALSubdir + "/wal.log"
+)
+
+// BackupManifest describes the contents of a backup archive
+type BackupManifest struct {
+	FormatVersion int          `json:"format_version"`
+	CreatedAt     time.Time    `json:"created_at"`
+	WALIndex      uint64       `json:"wal_index"` // Last WAL index included
+	NodeCount     int          `json:"node_count"`
+	EdgeCount     int          `json:"edge_count"`
+	Files         []BackupFile `json:"files"`
+}
+
+// BackupFile records the size and checksum of one archive member
+type BackupFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Backup writes a gzip-compressed tar archive of the graph to path.
+// See WriteBackup.
+func (pg *PersistentGraph) Backup(path string) (*BackupManifest, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup file: %w", err)
+	}
+
+	manifest, err := pg.WriteBackup(f)
+	if err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close backup file: %w", err)
+	}
+	return manifest, nil
+}
+
+// WriteBackup writes a gzip-compressed tar archive holding a fresh snapshot,
+// the WAL tail logged after it and a manifest with checksums. Snapshots are
+// blocked while the archive is assembled, and the WAL is copied under its
+// own lock, so the archive always recovers to a consistent state.
+func (pg *PersistentGraph) WriteBackup(w io.Writer) (*BackupManifest, error) {
+	if pg.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	pg.mu.Lock()
+	defer pg.mu.Unlock()
+
+	if err := pg.snapshot(); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := os.ReadFile(pg.snapshotManager.LatestPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var walTail bytes.Buffer
+	walIndex, err := pg.wal.CopyTo(&walTail)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []struct {
+		name string
+		data []byte
+	}{
+		{backupSnapshotName, snapshot},
+		{backupWALName, walTail.Bytes()},
+	}
+
+	manifest := &BackupManifest{
+		FormatVersion: BackupFormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		WALIndex:      walIndex,
+		NodeCount:     pg.NodeCount(),
+		EdgeCount:     pg.EdgeCount(),
+	}
+	for _, m := range members {
+		sum := sha256.Sum256(m.data)
+		manifest.Files = append(manifest.Files, BackupFile{
+			Name:   m.name,
+			Size:   int64(len(m.data)),
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	// The manifest goes first so restores can verify members as they stream
+	if err := writeTarFile(tw, backupManifestName, manifestData, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if err := writeTarFile(tw, m.name, m.data, manifest.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish backup archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish backup archive: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// RestoreBackup unpacks the archive at path into dataDir, which must be
+// empty or not exist. Every member is checked against the manifest. On
+// success dataDir holds wal/ and snapshots/ directories ready to be opened
+// with NewPersistentGraph.
+func RestoreBackup(path, dataDir string) (*BackupManifest, error) {
+	if entries, err := os.ReadDir(dataDir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("data directory %s is not empty", dataDir)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	manifest, err := extractBackup(f, dataDir)
+	if err != nil {
+		// Leave nothing behind that could be mistaken for a valid data dir
+		os.RemoveAll(filepath.Join(dataDir, WALSubdir))
+		os.RemoveAll(filepath.Join(dataDir, SnapshotSubdir))
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func extractBackup(r io.Reader, dataDir string) (*BackupManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != backupManifestName {
+		return nil, fmt.Errorf("backup archive does not start with %s", backupManifestName)
+	}
+	var manifest BackupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if manifest.FormatVersion != BackupFormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+
+	expected := make(map[string]BackupFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		expected[file.Name] = file
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup archive: %w", err)
+		}
+
+		file, ok := expected[hdr.Name]
+		if !ok {
+			return nil, fmt.Errorf("unexpected file %s in backup", hdr.Name)
+		}
+		dest, err := backupFilePath(dataDir, file.Name)
+		if err != nil {
+			return nil, err
+		}
+		if err := extractBackupFile(tr, dest, file); err != nil {
+			return nil, err
+		}
+		delete(expected, hdr.Name)
+	}
+
+	for name := range expected {
+		return nil, fmt.Errorf("backup is missing %s", name)
+	}
+	return &manifest, nil
+}
+
+// backupFilePath returns where a file named in a backup is restored to,
+// rejecting names that would put it outside dataDir
+func backupFilePath(dataDir, name string) (string, error) {
+	local := filepath.FromSlash(name)
+	if filepath.IsAbs(local) || filepath.VolumeName(local) != "" {
+		return "", fmt.Errorf("backup file %s has an absolute path", name)
+	}
+	dest := filepath.Join(dataDir, local)
+	rel, err := filepath.Rel(dataDir, dest)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("backup file %s is outside the data directory", name)
+	}
+	return dest, nil
+}
+
+func extractBackupFile(r io.Reader, dest string, file BackupFile) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", file.Name, err)
+	}
+	defer out.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(out, hash), r)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", file.Name, err)
+	}
+	if n != file.Size || hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s", file.Name)
+	}
+	return out.Sync()
+}
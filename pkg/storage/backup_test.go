@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openDataDir(t *testing.T, dataDir string) *PersistentGraph {
+	pg, err := NewPersistentGraph(filepath.Join(dataDir, WALSubdir), filepath.Join(dataDir, SnapshotSubdir))
+	require.NoError(t, err)
+	return pg
+}
+
+// assertSameGraph checks that two graphs hold the same nodes and edges
+func assertSameGraph(t *testing.T, want, got *Graph) {
+	require.Equal(t, want.NodeCount(), got.NodeCount())
+	require.Equal(t, want.EdgeCount(), got.EdgeCount())
+
+	want.IterateNodes(func(node *graph.Node) bool {
+		restored, err := got.GetNode(node.ID)
+		require.NoError(t, err)
+		assert.Equal(t, node.Label, restored.Label)
+		assert.Equal(t, node.Properties.Map(), restored.Properties.Map())
+		return true
+	})
+	want.IterateEdges(func(edge *graph.Edge) bool {
+		restored, err := got.GetEdge(edge.ID)
+		require.NoError(t, err)
+		assert.Equal(t, edge.Source, restored.Source)
+		assert.Equal(t, edge.Target, restored.Target)
+		assert.Equal(t, edge.Label, restored.Label)
+		assert.Equal(t, edge.Properties.Map(), restored.Properties.Map())
+		return true
+	})
+}
+
+func TestBackupRestore(t *testing.T) {
+	srcDir := t.TempDir()
+	pg := openDataDir(t, srcDir)
+
+	alice, err := pg.AddNode("Person", graph.Properties{"name": "Alice", "age": 30})
+	require.NoError(t, err)
+	bob, err := pg.AddNode("Person", graph.Properties{"name": "Bob", "home": graph.Point{Lat: 51.5, Lon: -0.12}})
+	require.NoError(t, err)
+	_, err = pg.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+	require.NoError(t, err)
+	require.NoError(t, pg.Snapshot())
+
+	// Writes after the last snapshot live only in the WAL
+	carol, err := pg.AddNode("Person", graph.Properties{"name": "Carol"})
+	require.NoError(t, err)
+	_, err = pg.AddEdge(bob.ID, carol.ID, "KNOWS", nil)
+	require.NoError(t, err)
+	require.NoError(t, pg.DeleteNode(alice.ID))
+	require.NoError(t, pg.CreateFullTextIndex("Person", "name"))
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	manifest, err := pg.Backup(archive)
+	require.NoError(t, err)
+	assert.Equal(t, BackupFormatVersion, manifest.FormatVersion)
+	assert.Equal(t, 2, manifest.NodeCount)
+	assert.Equal(t, 1, manifest.EdgeCount)
+	assert.Len(t, manifest.Files, 2)
+	require.NoError(t, pg.Close())
+
+	restoreDir := filepath.Join(t.TempDir(), "restored")
+	restoredManifest, err := RestoreBackup(archive, restoreDir)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.WALIndex, restoredManifest.WALIndex)
+
+	// Compare against the source as it recovers from its own directory
+	src := openDataDir(t, srcDir)
+	defer src.Close()
+	restored := openDataDir(t, restoreDir)
+	defer restored.Close()
+
+	assertSameGraph(t, src.Graph, restored.Graph)
+	assert.True(t, restored.HasFullTextIndex("Person", "name"))
+
+	home, _ := restored.Graph.nodes[bob.ID].GetProperty("home")
+	assert.Equal(t, graph.Point{Lat: 51.5, Lon: -0.12}, home)
+}
+
+func TestRestoreBackupRejectsNonEmptyDir(t *testing.T) {
+	pg := openDataDir(t, t.TempDir())
+	_, err := pg.AddNode("Person", nil)
+	require.NoError(t, err)
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	_, err = pg.Backup(archive)
+	require.NoError(t, err)
+	require.NoError(t, pg.Close())
+
+	target := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(target, "keep.txt"), []byte("x"), 0644))
+
+	_, err = RestoreBackup(archive, target)
+	assert.ErrorContains(t, err, "not empty")
+}
+
+func TestRestoreBackupDetectsCorruption(t *testing.T) {
+	pg := openDataDir(t, t.TempDir())
+	_, err := pg.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	_, err = pg.Backup(archive)
+	require.NoError(t, err)
+	require.NoError(t, pg.Close())
+
+	// Rewrite the archive with a modified snapshot but the original manifest
+	corrupt := filepath.Join(t.TempDir(), "corrupt.tar.gz")
+	rewriteArchive(t, archive, corrupt, func(name string, data []byte) []byte {
+		if name == backupSnapshotName {
+			return bytes.Replace(data, []byte("Alice"), []byte("Alicf"), 1)
+		}
+		return data
+	})
+
+	target := filepath.Join(t.TempDir(), "restored")
+	_, err = RestoreBackup(corrupt, target)
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	_, statErr := os.Stat(filepath.Join(target, SnapshotSubdir))
+	assert.True(t, os.IsNotExist(statErr), "partial restore should be removed")
+}
+
+func TestRestoreBackupRejectsPathsOutsideDataDir(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "snapshots/../../evil.txt", "/tmp/evil.txt", "."} {
+		t.Run(name, func(t *testing.T) {
+			data := []byte("pwned")
+			sum := sha256.Sum256(data)
+			manifest, err := json.Marshal(BackupManifest{
+				FormatVersion: BackupFormatVersion,
+				Files:         []BackupFile{{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}},
+			})
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			tw := tar.NewWriter(gz)
+			for _, member := range []struct {
+				name string
+				data []byte
+			}{{backupManifestName, manifest}, {name, data}} {
+				require.NoError(t, tw.WriteHeader(&tar.Header{Name: member.name, Mode: 0644, Size: int64(len(member.data))}))
+				_, err = tw.Write(member.data)
+				require.NoError(t, err)
+			}
+			require.NoError(t, tw.Close())
+			require.NoError(t, gz.Close())
+
+			parent := t.TempDir()
+			archive := filepath.Join(parent, "evil.tar.gz")
+			require.NoError(t, os.WriteFile(archive, buf.Bytes(), 0644))
+			target := filepath.Join(parent, "data")
+
+			_, err = RestoreBackup(archive, target)
+			assert.Error(t, err)
+			_, statErr := os.Stat(filepath.Join(parent, "evil.txt"))
+			assert.True(t, os.IsNotExist(statErr), "file written outside the data directory")
+		})
+	}
+}
+
+func rewriteArchive(t *testing.T, src, dst string, edit func(name string, data []byte) []byte) {
+	in, err := os.Open(src)
+	require.NoError(t, err)
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	out, err := os.Create(dst)
+	require.NoError(t, err)
+	defer out.Close()
+	gzOut := gzip.NewWriter(out)
+	tw := tar.NewWriter(gzOut)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+
+		data = edit(hdr.Name, data)
+		hdr.Size = int64(len(data))
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err = tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzOut.Close())
+}
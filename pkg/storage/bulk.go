@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+)
+
+// errLoadFinished is returned by a BulkLoader used after its BulkLoad returned
+var errLoadFinished = errors.New("bulk load has finished")
+
+// BulkLoader adds nodes and edges during PersistentGraph.BulkLoad. It
+// satisfies the writer interfaces of graphio, so any importer can load
+// through it. A BulkLoader is not safe for concurrent use.
+type BulkLoader struct {
+	pg       *PersistentGraph
+	entries  []wal.LogEntry // WAL entries written when the load commits
+	nodes    []graph.NodeID
+	edges    []graph.EdgeID
+	finished bool
+}
+
+// BulkLoad runs fn to load data in bulk. Nodes and edges added through the
+// loader are visible as soon as they are added, but their WAL entries are
+// buffered and written with a single fsync once fn returns, which is far
+// faster than syncing every addition. If fn returns an error, or the log
+// cannot be written, everything the loader added is removed again and the
+// error is returned. A crash during the load loses all of it, so the load
+// can simply be restarted.
+//
+// Other writers should leave the loaded data alone until BulkLoad returns:
+// a change to it logged before the load itself could not be replayed.
+// Snapshots wait for the load, so fn must not write to the graph other
+// than through the loader, or take a snapshot.
+func (pg *PersistentGraph) BulkLoad(fn func(loader *BulkLoader) error) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	loader := &BulkLoader{pg: pg}
+	defer func() { loader.finished = true }()
+
+	if err := fn(loader); err != nil {
+		loader.discard()
+		return err
+	}
+	if len(loader.entries) == 0 {
+		return nil
+	}
+	if pg.walEnabled {
+		if _, err := pg.wal.AppendBatch(loader.entries); err != nil {
+			loader.discard()
+			return fmt.Errorf("failed to log bulk load: %w", err)
+		}
+	}
+
+	pg.markStatsDirty()
+	return nil
+}
+
+// AddNode creates a new node
+func (l *BulkLoader) AddNode(label string, properties graph.Properties) (*graph.Node, error) {
+	if l.finished {
+		return nil, errLoadFinished
+	}
+	node, err := l.pg.Graph.AddNode(label, properties)
+	if err != nil {
+		return nil, err
+	}
+	l.addedNode(node.ID, label, properties, node.CreatedAt)
+	return node, nil
+}
+
+// AddNodeWithID creates a node with a caller-chosen ID
+func (l *BulkLoader) AddNodeWithID(id graph.NodeID, label string, properties graph.Properties) (*graph.Node, error) {
+	if l.finished {
+		return nil, errLoadFinished
+	}
+	node, err := l.pg.Graph.AddNodeWithID(id, label, properties)
+	if err != nil {
+		return nil, err
+	}
+	l.addedNode(node.ID, label, properties, node.CreatedAt)
+	return node, nil
+}
+
+// AddEdge creates a new edge
+func (l *BulkLoader) AddEdge(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error) {
+	if l.finished {
+		return nil, errLoadFinished
+	}
+	edge, err := l.pg.Graph.AddEdge(source, target, label, properties)
+	if err != nil {
+		return nil, err
+	}
+	l.addedEdge(edge.ID, source, target, label, properties, edge.CreatedAt)
+	return edge, nil
+}
+
+// AddEdgeWithID creates an edge with a caller-chosen ID
+func (l *BulkLoader) AddEdgeWithID(id graph.EdgeID, source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error) {
+	if l.finished {
+		return nil, errLoadFinished
+	}
+	edge, err := l.pg.Graph.AddEdgeWithID(id, source, target, label, properties)
+	if err != nil {
+		return nil, err
+	}
+	l.addedEdge(edge.ID, source, target, label, properties, edge.CreatedAt)
+	return edge, nil
+}
+
+func (l *BulkLoader) addedNode(id graph.NodeID, label string, properties graph.Properties, createdAt time.Time) {
+	l.nodes = append(l.nodes, id)
+	l.entries = append(l.entries, wal.AddNodeEntry(id, label, properties, createdAt))
+}
+
+func (l *BulkLoader) addedEdge(id graph.EdgeID, source, target graph.NodeID, label string, properties graph.Properties, createdAt time.Time) {
+	l.edges = append(l.edges, id)
+	l.entries = append(l.entries, wal.AddEdgeEntry(id, source, target, label, properties, createdAt))
+}
+
+// discard removes everything the loader added, newest first
+func (l *BulkLoader) discard() {
+	for i := len(l.edges) - 1; i >= 0; i-- {
+		l.pg.Graph.removeEdge(l.edges[i])
+	}
+	for i := len(l.nodes) - 1; i >= 0; i-- {
+		l.pg.Graph.removeNode(l.nodes[i])
+	}
+	l.entries, l.nodes, l.edges = nil, nil, nil
+}
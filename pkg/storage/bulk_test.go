@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkLoad(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	existing, err := pg.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+	before := pg.WALIndex()
+
+	var saved *BulkLoader
+	err = pg.BulkLoad(func(loader *BulkLoader) error {
+		saved = loader
+		prev := existing.ID
+		for i := 0; i < 100; i++ {
+			node, err := loader.AddNode("Person", graph.Properties{"i": i})
+			if err != nil {
+				return err
+			}
+			if _, err := loader.AddEdge(prev, node.ID, "NEXT", nil); err != nil {
+				return err
+			}
+			prev = node.ID
+		}
+		// Loaded data is visible during the load
+		assert.Equal(t, 101, pg.NodeCount())
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, before+200, pg.WALIndex())
+
+	_, err = saved.AddNode("Person", nil)
+	assert.ErrorIs(t, err, errLoadFinished)
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+	assert.Equal(t, 101, pg.NodeCount())
+	assert.Equal(t, 100, pg.EdgeCount())
+	neighbors, err := pg.GetNeighbors(existing.ID)
+	require.NoError(t, err)
+	require.Len(t, neighbors, 1)
+	i, _ := neighbors[0].GetProperty("i")
+	assert.EqualValues(t, 0, i)
+}
+
+func TestBulkLoad_Discard(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	alice, _ := pg.AddNode("Person", graph.Properties{"name": "Alice"})
+	before := pg.WALIndex()
+
+	failure := errors.New("bad row")
+	err = pg.BulkLoad(func(loader *BulkLoader) error {
+		bob, err := loader.AddNode("Person", graph.Properties{"name": "Bob"})
+		require.NoError(t, err)
+		_, err = loader.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+		require.NoError(t, err)
+		_, err = loader.AddNodeWithID(100, "Person", nil)
+		require.NoError(t, err)
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	// Nothing the load added is left, in memory or in the log
+	assert.Equal(t, 1, pg.NodeCount())
+	assert.Equal(t, 0, pg.EdgeCount())
+	assert.Equal(t, before, pg.WALIndex())
+	node, err := pg.GetNode(alice.ID)
+	require.NoError(t, err)
+	assert.Empty(t, node.OutEdges)
+
+	// A failed edge fails the load only if fn returns the error
+	err = pg.BulkLoad(func(loader *BulkLoader) error {
+		_, err := loader.AddEdge(alice.ID, 999, "KNOWS", nil)
+		assert.Error(t, err)
+		_, err = loader.AddNode("Person", nil)
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+	assert.Equal(t, 2, pg.NodeCount())
+	_, err = pg.GetNode(100)
+	assert.Error(t, err)
+}
+
+func TestBulkLoad_ReadOnly(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	require.NoError(t, pg.Close())
+
+	opts := DefaultOptions()
+	opts.ReadOnly = true
+	ro, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	called := false
+	err = ro.BulkLoad(func(*BulkLoader) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.False(t, called)
+}
+
+// bulkBenchNodes is the number of nodes loaded per benchmark iteration
+const bulkBenchNodes = 100000
+
+func BenchmarkBulkLoad(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		pg, err := NewPersistentGraph(b.TempDir(), b.TempDir())
+		require.NoError(b, err)
+		err = pg.BulkLoad(func(loader *BulkLoader) error {
+			for j := 0; j < bulkBenchNodes; j++ {
+				if _, err := loader.AddNode("Person", graph.Properties{"id": j}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(b, err)
+		pg.Close()
+	}
+}
+
+// BenchmarkIndividualLoad loads the same nodes as BenchmarkBulkLoad with
+// one fsync per node
+func BenchmarkIndividualLoad(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		pg, err := NewPersistentGraph(b.TempDir(), b.TempDir())
+		require.NoError(b, err)
+		for j := 0; j < bulkBenchNodes; j++ {
+			_, err := pg.AddNode("Person", graph.Properties{"id": j})
+			require.NoError(b, err)
+		}
+		pg.Close()
+	}
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// Clone returns a copy of the graph for algorithms that change its
+// structure. Nodes and edges keep their IDs and share nothing with the
+// original: properties, including lists and maps within them, and
+// adjacency lists are copied, and every copy has a fresh mutex. The ID
+// allocators continue after the highest IDs copied.
+//
+// The node and edge maps are copied under their locks and everything else
+// without them, so writers are not held up. An edge added or removed
+// while the copy is made may be half present; such inconsistencies are
+// repaired as Repair would. Index definitions, schemas and tombstones are
+// not copied.
+func (g *Graph) Clone() (*Graph, error) {
+	g.nodesMu.RLock()
+	nodes := maps.Clone(g.nodes)
+	g.nodesMu.RUnlock()
+	g.edgesMu.RLock()
+	edges := maps.Clone(g.edges)
+	g.edgesMu.RUnlock()
+
+	c := NewGraph()
+	c.clock = g.clock
+	c.softDelete.Store(g.softDelete.Load())
+
+	var maxNode graph.NodeID
+	for id, node := range nodes {
+		c.insertNode(cloneNode(node))
+		if id > maxNode {
+			maxNode = id
+		}
+	}
+
+	var maxEdge graph.EdgeID
+	c.edgesMu.Lock()
+	for id, edge := range edges {
+		clone := cloneEdge(edge)
+		c.putEdge(clone)
+		c.trackEdgeExpiry(clone)
+		if id > maxEdge {
+			maxEdge = id
+		}
+	}
+	c.edgesMu.Unlock()
+
+	c.nextNodeID.Store(uint64(maxNode) + 1)
+	c.nextEdgeID.Store(uint64(maxEdge) + 1)
+
+	if found := c.Validate(); len(found) > 0 {
+		if err := c.Repair(found); err != nil {
+			return nil, fmt.Errorf("failed to repair clone: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func cloneNode(node *graph.Node) *graph.Node {
+	node.Mu.RLock()
+	defer node.Mu.RUnlock()
+	return &graph.Node{
+		ID:         node.ID,
+		Label:      node.Label,
+		Properties: graph.NewSmallProperties(cloneProperties(node.Properties.Map())),
+		OutEdges:   append(make([]graph.EdgeID, 0, len(node.OutEdges)), node.OutEdges...),
+		InEdges:    append(make([]graph.EdgeID, 0, len(node.InEdges)), node.InEdges...),
+		CreatedAt:  node.CreatedAt,
+		UpdatedAt:  node.UpdatedAt,
+		ExpiresAt:  node.ExpiresAt,
+		DeletedAt:  node.DeletedAt,
+	}
+}
+
+func cloneEdge(edge *graph.Edge) *graph.Edge {
+	edge.Mu.RLock()
+	defer edge.Mu.RUnlock()
+	return &graph.Edge{
+		ID:         edge.ID,
+		Source:     edge.Source,
+		Target:     edge.Target,
+		Label:      edge.Label,
+		Properties: graph.NewSmallProperties(cloneProperties(edge.Properties.Map())),
+		CreatedAt:  edge.CreatedAt,
+		UpdatedAt:  edge.UpdatedAt,
+		ExpiresAt:  edge.ExpiresAt,
+		DeletedAt:  edge.DeletedAt,
+	}
+}
+
+// cloneProperties copies props, including the lists and maps in it
+func cloneProperties(props graph.Properties) graph.Properties {
+	out := make(graph.Properties, len(props))
+	for k, v := range props {
+		out[k] = cloneValue(v)
+	}
+	return out
+}
+
+func cloneValue(v graph.PropertyValue) graph.PropertyValue {
+	switch v := v.(type) {
+	case []graph.PropertyValue:
+		list := make([]graph.PropertyValue, len(v))
+		for i, item := range v {
+			list[i] = cloneValue(item)
+		}
+		return list
+	case graph.Properties:
+		return cloneProperties(v)
+	case graph.Histogram:
+		return graph.Histogram{
+			Buckets: append([]float64(nil), v.Buckets...),
+			Counts:  append([]int64(nil), v.Counts...),
+		}
+	}
+	return v
+}
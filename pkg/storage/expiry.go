@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// SetClock replaces the clock used to decide whether nodes and edges have
+// expired. Tests use it to move time forward without sleeping.
+func (g *Graph) SetClock(now func() time.Time) {
+	g.expiryMu.Lock()
+	defer g.expiryMu.Unlock()
+	g.clock = now
+}
+
+// now returns the current time according to the graph's clock
+func (g *Graph) now() time.Time {
+	g.expiryMu.Lock()
+	clock := g.clock
+	g.expiryMu.Unlock()
+	return clock()
+}
+
+// SetExpiry sets the time at which a node expires. Expired nodes are
+// hidden from queries until they are deleted by a sweep. A zero time
+// removes the expiry.
+func (g *Graph) SetExpiry(id graph.NodeID, expiresAt time.Time) error {
+	node, err := g.GetNode(id)
+	if err != nil {
+		return err
+	}
+
+	node.Mu.Lock()
+	node.ExpiresAt = expiresAt
+	node.Mu.Unlock()
+
+	g.trackNodeExpiry(node)
+	return nil
+}
+
+// SetEdgeExpiry sets the time at which an edge expires. A zero time
+// removes the expiry.
+func (g *Graph) SetEdgeExpiry(id graph.EdgeID, expiresAt time.Time) error {
+	edge, err := g.GetEdge(id)
+	if err != nil {
+		return err
+	}
+
+	edge.Mu.Lock()
+	edge.ExpiresAt = expiresAt
+	edge.Mu.Unlock()
+
+	g.trackEdgeExpiry(edge)
+	return nil
+}
+
+// NodeExpired reports whether node has passed its expiry time
+func (g *Graph) NodeExpired(node *graph.Node) bool {
+	node.Mu.RLock()
+	expiresAt := node.ExpiresAt
+	node.Mu.RUnlock()
+	return !expiresAt.IsZero() && !g.now().Before(expiresAt)
+}
+
+// EdgeExpired reports whether edge has passed its expiry time
+func (g *Graph) EdgeExpired(edge *graph.Edge) bool {
+	edge.Mu.RLock()
+	expiresAt := edge.ExpiresAt
+	edge.Mu.RUnlock()
+	return !expiresAt.IsZero() && !g.now().Before(expiresAt)
+}
+
+// Expired returns the IDs of expired nodes and edges, ordered by ID. At
+// most limit IDs are returned in total, nodes first; limit <= 0 returns
+// all of them.
+func (g *Graph) Expired(limit int) ([]graph.NodeID, []graph.EdgeID) {
+	now := g.now()
+
+	g.expiryMu.Lock()
+	var nodes []graph.NodeID
+	for id, expiresAt := range g.nodeExpiry {
+		if !now.Before(expiresAt) {
+			nodes = append(nodes, id)
+		}
+	}
+	var edges []graph.EdgeID
+	for id, expiresAt := range g.edgeExpiry {
+		if !now.Before(expiresAt) {
+			edges = append(edges, id)
+		}
+	}
+	g.expiryMu.Unlock()
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	sort.Slice(edges, func(i, j int) bool { return edges[i] < edges[j] })
+	if limit > 0 {
+		if len(nodes) > limit {
+			nodes = nodes[:limit]
+		}
+		if len(edges) > limit-len(nodes) {
+			edges = edges[:limit-len(nodes)]
+		}
+	}
+	return nodes, edges
+}
+
+// NextExpiry returns the earliest expiry time of a node or edge that has
+// not expired yet, and false if there is none. Query results stay valid
+// until then, as long as the graph is not written to.
+func (g *Graph) NextExpiry() (time.Time, bool) {
+	now := g.now()
+
+	g.expiryMu.Lock()
+	defer g.expiryMu.Unlock()
+	var next time.Time
+	consider := func(expiresAt time.Time) {
+		if now.Before(expiresAt) && (next.IsZero() || expiresAt.Before(next)) {
+			next = expiresAt
+		}
+	}
+	for _, expiresAt := range g.nodeExpiry {
+		consider(expiresAt)
+	}
+	for _, expiresAt := range g.edgeExpiry {
+		consider(expiresAt)
+	}
+	return next, !next.IsZero()
+}
+
+// trackNodeExpiry records the expiry of a node inserted directly, e.g.
+// from a snapshot
+func (g *Graph) trackNodeExpiry(node *graph.Node) {
+	node.Mu.RLock()
+	expiresAt := node.ExpiresAt
+	node.Mu.RUnlock()
+
+	g.expiryMu.Lock()
+	defer g.expiryMu.Unlock()
+	if expiresAt.IsZero() {
+		delete(g.nodeExpiry, node.ID)
+	} else {
+		g.nodeExpiry[node.ID] = expiresAt
+	}
+}
+
+// trackEdgeExpiry records the expiry of an edge inserted directly
+func (g *Graph) trackEdgeExpiry(edge *graph.Edge) {
+	edge.Mu.RLock()
+	expiresAt := edge.ExpiresAt
+	edge.Mu.RUnlock()
+
+	g.expiryMu.Lock()
+	defer g.expiryMu.Unlock()
+	if expiresAt.IsZero() {
+		delete(g.edgeExpiry, edge.ID)
+	} else {
+		g.edgeExpiry[edge.ID] = expiresAt
+	}
+}
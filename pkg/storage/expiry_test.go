@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock for expiry tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestExpiry(t *testing.T) {
+	clock := newFakeClock()
+	g := NewGraph()
+	g.SetClock(clock.Now)
+
+	a, _ := g.AddNode("Session", nil)
+	b, _ := g.AddNode("Session", nil)
+	e, _ := g.AddEdge(a.ID, b.ID, "NEXT", nil)
+
+	require.NoError(t, g.SetExpiry(a.ID, clock.Now().Add(time.Minute)))
+	require.NoError(t, g.SetEdgeExpiry(e.ID, clock.Now().Add(30*time.Second)))
+	assert.Error(t, g.SetExpiry(99, clock.Now()))
+
+	assert.False(t, g.NodeExpired(a))
+	nodes, edges := g.Expired(0)
+	assert.Empty(t, nodes)
+	assert.Empty(t, edges)
+
+	clock.Advance(30 * time.Second)
+	assert.True(t, g.EdgeExpired(e))
+	assert.False(t, g.NodeExpired(a))
+
+	clock.Advance(30 * time.Second)
+	assert.True(t, g.NodeExpired(a))
+	assert.False(t, g.NodeExpired(b), "nodes without an expiry never expire")
+	nodes, edges = g.Expired(0)
+	assert.Equal(t, []graph.NodeID{a.ID}, nodes)
+	assert.Equal(t, []graph.EdgeID{e.ID}, edges)
+
+	// The limit covers nodes first
+	nodes, edges = g.Expired(1)
+	assert.Equal(t, []graph.NodeID{a.ID}, nodes)
+	assert.Empty(t, edges)
+
+	// Clearing and deleting both stop tracking
+	require.NoError(t, g.SetExpiry(a.ID, time.Time{}))
+	assert.False(t, g.NodeExpired(a))
+	require.NoError(t, g.DeleteEdge(e.ID))
+	nodes, edges = g.Expired(0)
+	assert.Empty(t, nodes)
+	assert.Empty(t, edges)
+}
@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+)
+
+// IndexDef identifies a property index by label and property name
+type IndexDef = wal.IndexDef
+
+// fullTextIndex is an inverted index over one string property of one label
+type fullTextIndex struct {
+	def      IndexDef
+	postings map[string]map[graph.NodeID]int // term -> node -> term frequency
+}
+
+// Tokenize splits text on whitespace and punctuation and lowercases each term
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !isTermRune(r)
+	})
+	for i, f := range fields {
+		fields[i] = strings.ToLower(f)
+	}
+	return fields
+}
+
+// isTermRune reports whether r is part of a term rather than a separator
+func isTermRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (idx *fullTextIndex) add(node *graph.Node) {
+	value, _ := node.Properties.GetProperty(idx.def.Property)
+	text, ok := value.(string)
+	if !ok {
+		return
+	}
+	for _, term := range Tokenize(text) {
+		nodes, ok := idx.postings[term]
+		if !ok {
+			nodes = make(map[graph.NodeID]int)
+			idx.postings[term] = nodes
+		}
+		nodes[node.ID]++
+	}
+}
+
+func (idx *fullTextIndex) remove(node *graph.Node) {
+	value, _ := node.Properties.GetProperty(idx.def.Property)
+	text, ok := value.(string)
+	if !ok {
+		return
+	}
+	for _, term := range Tokenize(text) {
+		if nodes, ok := idx.postings[term]; ok {
+			delete(nodes, node.ID)
+			if len(nodes) == 0 {
+				delete(idx.postings, term)
+			}
+		}
+	}
+}
+
+// CreateFullTextIndex builds an inverted index over the string property of
+// all nodes with the given label. The index is maintained on every write.
+func (g *Graph) CreateFullTextIndex(label, property string) error {
+	def := IndexDef{Label: label, Property: property}
+
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+
+	if _, exists := g.ftIndexes[def]; exists {
+		return fmt.Errorf("full-text index on :%s(%s) already exists", label, property)
+	}
+
+	idx := &fullTextIndex{def: def, postings: make(map[string]map[graph.NodeID]int)}
+	g.IterateNodesByLabel(label, func(node *graph.Node) bool {
+		node.Mu.RLock()
+		idx.add(node)
+		node.Mu.RUnlock()
+		return true
+	})
+
+	g.ftIndexes[def] = idx
+	return nil
+}
+
+// DropFullTextIndex removes a full-text index
+func (g *Graph) DropFullTextIndex(label, property string) error {
+	def := IndexDef{Label: label, Property: property}
+
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+
+	if _, exists := g.ftIndexes[def]; !exists {
+		return fmt.Errorf("no full-text index on :%s(%s)", label, property)
+	}
+	delete(g.ftIndexes, def)
+	return nil
+}
+
+// HasFullTextIndex reports whether a full-text index exists for label and property
+func (g *Graph) HasFullTextIndex(label, property string) bool {
+	g.idxMu.RLock()
+	defer g.idxMu.RUnlock()
+	_, ok := g.ftIndexes[IndexDef{Label: label, Property: property}]
+	return ok
+}
+
+// FullTextIndexes returns the definitions of all full-text indexes
+func (g *Graph) FullTextIndexes() []IndexDef {
+	g.idxMu.RLock()
+	defer g.idxMu.RUnlock()
+
+	defs := make([]IndexDef, 0, len(g.ftIndexes))
+	for def := range g.ftIndexes {
+		defs = append(defs, def)
+	}
+	sortIndexDefs(defs)
+	return defs
+}
+
+// sortIndexDefs orders index definitions by label, then property
+func sortIndexDefs(defs []IndexDef) {
+	sort.Slice(defs, func(i, j int) bool {
+		if defs[i].Label != defs[j].Label {
+			return defs[i].Label < defs[j].Label
+		}
+		return defs[i].Property < defs[j].Property
+	})
+}
+
+// FullTextSearch returns the nodes whose indexed property contains every
+// term of query. Results are ordered by total term frequency, highest first.
+func (g *Graph) FullTextSearch(label, property, query string) ([]*graph.Node, error) {
+	terms := Tokenize(query)
+	return g.searchFullText(label, property, terms, make([]func(string) bool, len(terms)))
+}
+
+// FullTextContains returns the nodes whose indexed property may contain
+// text as a substring: a superset of them, ignoring case, for callers to
+// filter exactly. A term of text that its start or end cuts off may be
+// part of a longer term, so the first term matches any term ending with
+// it and the last any term starting with it; the others must match whole.
+// Results are ordered like FullTextSearch's. Text without terms matches
+// every node, which the index cannot list, so it is an error.
+func (g *Graph) FullTextContains(label, property, text string) ([]*graph.Node, error) {
+	terms := Tokenize(text)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("full-text index cannot search for %q, which has no terms", text)
+	}
+	first, _ := utf8.DecodeRuneInString(text)
+	last, _ := utf8.DecodeLastRuneInString(text)
+	cutStart, cutEnd := isTermRune(first), isTermRune(last)
+
+	matchers := make([]func(string) bool, len(terms))
+	for i, term := range terms {
+		term := term
+		atStart, atEnd := i == 0 && cutStart, i == len(terms)-1 && cutEnd
+		switch {
+		case atStart && atEnd:
+			matchers[i] = func(t string) bool { return strings.Contains(t, term) }
+		case atStart:
+			matchers[i] = func(t string) bool { return strings.HasSuffix(t, term) }
+		case atEnd:
+			matchers[i] = func(t string) bool { return strings.HasPrefix(t, term) }
+		}
+	}
+	return g.searchFullText(label, property, terms, matchers)
+}
+
+// searchFullText returns the nodes with a posting for every term, ordered
+// by total term frequency. A term with a matcher stands for every indexed
+// term the matcher accepts; one without must be indexed as it is.
+func (g *Graph) searchFullText(label, property string, terms []string, matchers []func(string) bool) ([]*graph.Node, error) {
+	g.idxMu.RLock()
+	idx, ok := g.ftIndexes[IndexDef{Label: label, Property: property}]
+	if !ok {
+		g.idxMu.RUnlock()
+		return nil, fmt.Errorf("no full-text index on :%s(%s)", label, property)
+	}
+
+	// Intersect posting lists, summing frequencies as the score
+	var scores map[graph.NodeID]int
+	for i, term := range terms {
+		postings := idx.postings[term]
+		if matchers[i] != nil {
+			postings = make(map[graph.NodeID]int)
+			for indexed, nodes := range idx.postings {
+				if !matchers[i](indexed) {
+					continue
+				}
+				for id, freq := range nodes {
+					postings[id] += freq
+				}
+			}
+		}
+		next := make(map[graph.NodeID]int)
+		for id, freq := range postings {
+			if scores == nil {
+				next[id] = freq
+			} else if score, ok := scores[id]; ok {
+				next[id] = score + freq
+			}
+		}
+		scores = next
+		if len(scores) == 0 {
+			break
+		}
+	}
+	g.idxMu.RUnlock()
+
+	ids := make([]graph.NodeID, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	nodes := make([]*graph.Node, 0, len(ids))
+	for _, id := range ids {
+		if node, ok := g.nodes[id]; ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// indexFullText adds node to every full-text index on its label.
+// Caller holds idxMu and node.Mu.
+func (g *Graph) indexFullText(node *graph.Node) {
+	for def, idx := range g.ftIndexes {
+		if def.Label == node.Label {
+			idx.add(node)
+		}
+	}
+}
+
+// unindexFullText removes node from every full-text index on its label.
+// Caller holds idxMu and node.Mu.
+func (g *Graph) unindexFullText(node *graph.Node) {
+	for def, idx := range g.ftIndexes {
+		if def.Label == node.Label {
+			idx.remove(node)
+		}
+	}
+}
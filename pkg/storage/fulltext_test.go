@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nodeNames(nodes []*graph.Node) []string {
+	names := make([]string, len(nodes))
+	for i, n := range nodes {
+		name, _ := n.GetProperty("name")
+		names[i], _ = name.(string)
+	}
+	return names
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"distributed", "systems", "go", "rust"},
+		Tokenize("Distributed Systems; Go/Rust!"))
+	assert.Empty(t, Tokenize("  ... "))
+}
+
+func TestFullTextSearch(t *testing.T) {
+	g := NewGraph()
+	g.AddNode("Person", graph.Properties{"name": "Alice", "bio": "Works on distributed systems"})
+	g.AddNode("Person", graph.Properties{"name": "Bob", "bio": "Distributed databases and distributed SYSTEMS, systems everywhere"})
+	g.AddNode("Person", graph.Properties{"name": "Carol", "bio": "Frontend systems"})
+	g.AddNode("Company", graph.Properties{"name": "Acme", "bio": "distributed systems vendor"})
+
+	require.NoError(t, g.CreateFullTextIndex("Person", "bio"))
+	assert.True(t, g.HasFullTextIndex("Person", "bio"))
+	assert.Error(t, g.CreateFullTextIndex("Person", "bio"))
+
+	t.Run("multi-term AND", func(t *testing.T) {
+		nodes, err := g.FullTextSearch("Person", "bio", "distributed systems")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Alice", "Bob"}, nodeNames(nodes))
+	})
+
+	t.Run("case-insensitive", func(t *testing.T) {
+		nodes, err := g.FullTextSearch("Person", "bio", "SYSTEMS")
+		require.NoError(t, err)
+		assert.Len(t, nodes, 3)
+	})
+
+	t.Run("ordered by frequency", func(t *testing.T) {
+		nodes, err := g.FullTextSearch("Person", "bio", "distributed systems")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Bob", "Alice"}, nodeNames(nodes))
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		nodes, err := g.FullTextSearch("Person", "bio", "distributed frontend")
+		require.NoError(t, err)
+		assert.Empty(t, nodes)
+	})
+
+	_, err := g.FullTextSearch("Person", "name", "alice")
+	assert.Error(t, err)
+}
+
+func TestFullTextContains(t *testing.T) {
+	g := NewGraph()
+	g.AddNode("Person", graph.Properties{"name": "Alice", "bio": "Works on distributed systems"})
+	g.AddNode("Person", graph.Properties{"name": "Bob", "bio": "Distributed databases"})
+	g.AddNode("Person", graph.Properties{"name": "Carol", "bio": "Frontend systems"})
+	require.NoError(t, g.CreateFullTextIndex("Person", "bio"))
+
+	for text, want := range map[string][]string{
+		"distributed systems": {"Alice"},
+		"stributed sys":       {"Alice"},
+		"tribut":              {"Alice", "Bob"},
+		"end sys":             {"Carol"},
+		"on dist":             {"Alice"},
+		"databases ":          {"Bob"},
+		"base":                {"Bob"},
+		"frontend":            {"Carol"},
+	} {
+		nodes, err := g.FullTextContains("Person", "bio", text)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, want, nodeNames(nodes), "contains %q", text)
+	}
+
+	_, err := g.FullTextContains("Person", "bio", " - ")
+	assert.Error(t, err)
+}
+
+func TestFullTextIndexMaintenance(t *testing.T) {
+	g := NewGraph()
+	require.NoError(t, g.CreateFullTextIndex("Person", "bio"))
+
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice", "bio": "graph databases"})
+
+	nodes, _ := g.FullTextSearch("Person", "bio", "graph")
+	assert.Len(t, nodes, 1)
+
+	require.NoError(t, g.UpdateNode(alice.ID, graph.Properties{"bio": "compilers"}))
+	nodes, _ = g.FullTextSearch("Person", "bio", "graph")
+	assert.Empty(t, nodes)
+	nodes, _ = g.FullTextSearch("Person", "bio", "compilers")
+	assert.Len(t, nodes, 1)
+
+	require.NoError(t, g.DeleteNode(alice.ID))
+	nodes, _ = g.FullTextSearch("Person", "bio", "compilers")
+	assert.Empty(t, nodes)
+}
+
+func TestFullTextIndex_Persistence(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	pg.AddNode("Person", graph.Properties{"name": "Alice", "bio": "distributed systems"})
+	require.NoError(t, pg.CreateFullTextIndex("Person", "bio"))
+	require.NoError(t, pg.Close())
+
+	// Recovered from the WAL entry
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	require.True(t, pg.HasFullTextIndex("Person", "bio"))
+	nodes, err := pg.FullTextSearch("Person", "bio", "systems")
+	require.NoError(t, err)
+	assert.Len(t, nodes, 1)
+
+	// Recovered from the snapshot catalog after the WAL is truncated
+	require.NoError(t, pg.Snapshot())
+	pg.AddNode("Person", graph.Properties{"name": "Bob", "bio": "systems programming"})
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+	nodes, err = pg.FullTextSearch("Person", "bio", "systems")
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+}
@@ -3,8 +3,10 @@ package storage
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
 )
@@ -18,21 +20,86 @@ type Graph struct {
 	// ID generators
 	nextNodeID atomic.Uint64
 	nextEdgeID atomic.Uint64
+	idBlocks   *idBlocks // Reservations of the IDs handed out; nil for none
 
 	// Locks for thread-safety
 	nodesMu sync.RWMutex
 	edgesMu sync.RWMutex
 
-	// Optional: Secondary indexes can be added here
-	// nodesByLabel map[string][]graph.NodeID
-	// edgesByLabel map[string][]graph.EdgeID
+	// Serializes edge reconnections, so concurrent moves of one edge
+	// can't interleave their adjacency updates (see reconnectEdge)
+	reconnectMu sync.Mutex
+
+	// Secondary indexes (protected by nodesMu)
+	nodesByLabel map[string]map[graph.NodeID]struct{}
+
+	// Number of edges per type (protected by edgesMu)
+	edgeTypeCounts map[string]int
+
+	// Property indexes (see fulltext.go, spatial.go, propindex.go).
+	// idxMu is acquired before nodesMu.
+	ftIndexes      map[IndexDef]*fullTextIndex
+	spatialIndexes map[IndexDef]*spatialIndex
+	propIndexes    map[IndexDef]*propertyIndex
+	idxMu          sync.RWMutex
+
+	// Number of nodes per label having each property key (protected by
+	// idxMu)
+	propertyKeys map[string]map[string]int
+
+	// Incremented whenever nodes are indexed or unindexed, so Reindex can
+	// tell whether a write slipped in while it rebuilt (protected by idxMu)
+	idxGen uint64
+
+	// Per-label property schemas (see schema.go)
+	schemas  map[string]*Schema
+	schemaMu sync.RWMutex
+
+	// Expiry times of nodes and edges that have one (see expiry.go).
+	// expiryMu is never held while acquiring another lock.
+	nodeExpiry map[graph.NodeID]time.Time
+	edgeExpiry map[graph.EdgeID]time.Time
+	clock      func() time.Time
+	expiryMu   sync.Mutex
+
+	// Soft-deleted nodes and edges (see tombstone.go). tombMu is acquired
+	// before every other lock.
+	softDelete   atomic.Bool
+	deletedNodes map[graph.NodeID]*graph.Node
+	deletedEdges map[graph.EdgeID]*graph.Edge
+	tombMu       sync.Mutex
+
+	// Tombstoned edges that were deleted along with one of their nodes,
+	// and so come back with it (protected by tombMu)
+	cascadedEdges map[graph.EdgeID]struct{}
+
+	// uniqueMu serializes AddEdgeUnique and MergeEdge (see unique.go). It
+	// is acquired before every other lock.
+	uniqueMu sync.Mutex
+
+	// histMu serializes AddToHistogram, so concurrent counts are not lost
+	// (see histogram.go). It is acquired before every other lock.
+	histMu sync.Mutex
 }
 
 // NewGraph creates a new in-memory graph storage
 func NewGraph() *Graph {
 	g := &Graph{
-		nodes: make(map[graph.NodeID]*graph.Node),
-		edges: make(map[graph.EdgeID]*graph.Edge),
+		nodes:          make(map[graph.NodeID]*graph.Node),
+		edges:          make(map[graph.EdgeID]*graph.Edge),
+		nodesByLabel:   make(map[string]map[graph.NodeID]struct{}),
+		edgeTypeCounts: make(map[string]int),
+		ftIndexes:      make(map[IndexDef]*fullTextIndex),
+		spatialIndexes: make(map[IndexDef]*spatialIndex),
+		propIndexes:    make(map[IndexDef]*propertyIndex),
+		propertyKeys:   make(map[string]map[string]int),
+		schemas:        make(map[string]*Schema),
+		nodeExpiry:     make(map[graph.NodeID]time.Time),
+		edgeExpiry:     make(map[graph.EdgeID]time.Time),
+		clock:          time.Now,
+		deletedNodes:   make(map[graph.NodeID]*graph.Node),
+		deletedEdges:   make(map[graph.EdgeID]*graph.Edge),
+		cascadedEdges:  make(map[graph.EdgeID]struct{}),
 	}
 	// Start IDs from 1 (0 can be reserved for null/invalid)
 	g.nextNodeID.Store(1)
@@ -42,22 +109,261 @@ func NewGraph() *Graph {
 
 // AddNode creates a new node in the graph
 func (g *Graph) AddNode(label string, properties graph.Properties) (*graph.Node, error) {
-	nodeID := graph.NodeID(g.nextNodeID.Add(1) - 1)
+	properties, err := graph.NormalizeProperties(properties)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.validateNode(0, label, properties); err != nil {
+		return nil, err
+	}
 
-	node := graph.NewNode(nodeID, label)
-	if properties != nil {
-		for k, v := range properties {
-			node.SetProperty(k, v)
+	nodeID := graph.NodeID(g.nextNodeID.Add(1) - 1)
+	if g.idBlocks != nil {
+		if err := g.idBlocks.reserveNode(uint64(nodeID)); err != nil {
+			return nil, err
 		}
 	}
 
-	g.nodesMu.Lock()
-	g.nodes[nodeID] = node
-	g.nodesMu.Unlock()
+	node := newNode(nodeID, label, properties)
+	g.insertNode(node)
 
 	return node, nil
 }
 
+// newNode returns a node holding properties, updated when it was created
+func newNode(id graph.NodeID, label string, properties graph.Properties) *graph.Node {
+	node := graph.NewNode(id, label)
+	for k, v := range properties {
+		node.SetProperty(k, v)
+	}
+	node.UpdatedAt = node.CreatedAt
+	return node
+}
+
+// insertNode stores a node and updates the label and property indexes
+func (g *Graph) insertNode(node *graph.Node) {
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+	g.nodesMu.Lock()
+	defer g.nodesMu.Unlock()
+
+	if old, exists := g.nodes[node.ID]; exists {
+		g.unindexLabel(old)
+		g.unindexProperties(old)
+	}
+	g.nodes[node.ID] = node
+	g.indexProperties(node)
+
+	ids, ok := g.nodesByLabel[node.Label]
+	if !ok {
+		ids = make(map[graph.NodeID]struct{})
+		g.nodesByLabel[node.Label] = ids
+	}
+	ids[node.ID] = struct{}{}
+
+	g.trackNodeExpiry(node)
+}
+
+// indexProperties adds node to all property indexes. Caller holds idxMu.
+func (g *Graph) indexProperties(node *graph.Node) {
+	g.idxGen++
+	node.Mu.RLock()
+	defer node.Mu.RUnlock()
+	g.indexFullText(node)
+	g.indexSpatial(node)
+	g.indexPropertyValues(node)
+	countPropertyKeys(g.propertyKeys, node)
+}
+
+// unindexProperties removes node from all property indexes. Caller holds idxMu.
+func (g *Graph) unindexProperties(node *graph.Node) {
+	g.idxGen++
+	node.Mu.RLock()
+	defer node.Mu.RUnlock()
+	g.unindexFullText(node)
+	g.unindexSpatial(node)
+	g.unindexPropertyValues(node)
+	uncountPropertyKeys(g.propertyKeys, node)
+}
+
+// countPropertyKeys counts the property keys of node under its label.
+// Caller holds node.Mu.
+func countPropertyKeys(registry map[string]map[string]int, node *graph.Node) {
+	if node.Properties.Len() == 0 {
+		return
+	}
+	keys, ok := registry[node.Label]
+	if !ok {
+		keys = make(map[string]int)
+		registry[node.Label] = keys
+	}
+	node.Properties.Range(func(key string, _ graph.PropertyValue) bool {
+		keys[key]++
+		return true
+	})
+}
+
+// uncountPropertyKeys reverses countPropertyKeys. Caller holds node.Mu.
+func uncountPropertyKeys(registry map[string]map[string]int, node *graph.Node) {
+	keys, ok := registry[node.Label]
+	if !ok {
+		return
+	}
+	node.Properties.Range(func(key string, _ graph.PropertyValue) bool {
+		if keys[key]--; keys[key] <= 0 {
+			delete(keys, key)
+		}
+		return true
+	})
+	if len(keys) == 0 {
+		delete(registry, node.Label)
+	}
+}
+
+// unindexLabel removes a node from the label index. Caller holds nodesMu.
+func (g *Graph) unindexLabel(node *graph.Node) {
+	if ids, ok := g.nodesByLabel[node.Label]; ok {
+		delete(ids, node.ID)
+		if len(ids) == 0 {
+			delete(g.nodesByLabel, node.Label)
+		}
+	}
+}
+
+// LabelCount returns the number of nodes with the given label
+func (g *Graph) LabelCount(label string) int {
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+	return len(g.nodesByLabel[label])
+}
+
+// LabelCounts returns the number of nodes per label
+func (g *Graph) LabelCounts() map[string]int {
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	counts := make(map[string]int, len(g.nodesByLabel))
+	for label, ids := range g.nodesByLabel {
+		counts[label] = len(ids)
+	}
+	return counts
+}
+
+// Labels returns the labels of the nodes in the graph, in order
+func (g *Graph) Labels() []string {
+	g.nodesMu.RLock()
+	labels := make([]string, 0, len(g.nodesByLabel))
+	for label := range g.nodesByLabel {
+		labels = append(labels, label)
+	}
+	g.nodesMu.RUnlock()
+
+	sort.Strings(labels)
+	return labels
+}
+
+// RelationshipTypes returns the types of the edges in the graph, in order
+func (g *Graph) RelationshipTypes() []string {
+	g.edgesMu.RLock()
+	types := make([]string, 0, len(g.edgeTypeCounts))
+	for typ := range g.edgeTypeCounts {
+		types = append(types, typ)
+	}
+	g.edgesMu.RUnlock()
+
+	sort.Strings(types)
+	return types
+}
+
+// PropertyKeys returns the property keys set on at least one node with
+// the given label, in order
+func (g *Graph) PropertyKeys(label string) []string {
+	g.idxMu.RLock()
+	keys := make([]string, 0, len(g.propertyKeys[label]))
+	for key := range g.propertyKeys[label] {
+		keys = append(keys, key)
+	}
+	g.idxMu.RUnlock()
+
+	sort.Strings(keys)
+	return keys
+}
+
+// EdgeTypeCount returns the number of edges with the given type
+func (g *Graph) EdgeTypeCount(label string) int {
+	g.edgesMu.RLock()
+	defer g.edgesMu.RUnlock()
+	return g.edgeTypeCounts[label]
+}
+
+// EdgeTypeCounts returns the number of edges per type
+func (g *Graph) EdgeTypeCounts() map[string]int {
+	g.edgesMu.RLock()
+	defer g.edgesMu.RUnlock()
+
+	counts := make(map[string]int, len(g.edgeTypeCounts))
+	for label, n := range g.edgeTypeCounts {
+		counts[label] = n
+	}
+	return counts
+}
+
+// putEdge stores an edge and counts it under its type, replacing any edge
+// stored with the same ID. Caller holds edgesMu.
+func (g *Graph) putEdge(edge *graph.Edge) {
+	if old, ok := g.edges[edge.ID]; ok {
+		g.uncountEdge(old)
+	}
+	g.edges[edge.ID] = edge
+	g.edgeTypeCounts[edge.Label]++
+}
+
+// dropEdge deletes an edge and its type count. Caller holds edgesMu.
+func (g *Graph) dropEdge(id graph.EdgeID) {
+	if edge, ok := g.edges[id]; ok {
+		g.uncountEdge(edge)
+		delete(g.edges, id)
+	}
+}
+
+// uncountEdge removes an edge from the type counts. Caller holds edgesMu.
+func (g *Graph) uncountEdge(edge *graph.Edge) {
+	if g.edgeTypeCounts[edge.Label]--; g.edgeTypeCounts[edge.Label] <= 0 {
+		delete(g.edgeTypeCounts, edge.Label)
+	}
+}
+
+// IterateNodesByLabel calls the callback for every node with the given
+// label using the label index. If callback returns false, iteration stops.
+func (g *Graph) IterateNodesByLabel(label string, callback func(*graph.Node) bool) {
+	g.nodesMu.RLock()
+	ids := g.nodesByLabel[label]
+	nodes := make([]*graph.Node, 0, len(ids))
+	for id := range ids {
+		nodes = append(nodes, g.nodes[id])
+	}
+	g.nodesMu.RUnlock()
+
+	for _, node := range nodes {
+		if !callback(node) {
+			break
+		}
+	}
+}
+
+// IterateNodesByLabelSorted is IterateNodesByLabel in ascending ID order
+func (g *Graph) IterateNodesByLabelSorted(label string, callback func(*graph.Node) bool) {
+	g.nodesMu.RLock()
+	ids := g.nodesByLabel[label]
+	nodes := make([]*graph.Node, 0, len(ids))
+	for id := range ids {
+		nodes = append(nodes, g.nodes[id])
+	}
+	g.nodesMu.RUnlock()
+
+	iterateSorted(nodes, callback)
+}
+
 // GetNode retrieves a node by ID
 func (g *Graph) GetNode(id graph.NodeID) (*graph.Node, error) {
 	g.nodesMu.RLock()
@@ -70,7 +376,48 @@ func (g *Graph) GetNode(id graph.NodeID) (*graph.Node, error) {
 	return node, nil
 }
 
-// AddEdge creates a new edge between two nodes
+// UpdateNode sets the given properties on an existing node, keeping
+// properties that are not mentioned
+func (g *Graph) UpdateNode(id graph.NodeID, properties graph.Properties) error {
+	properties, err := graph.NormalizeProperties(properties)
+	if err != nil {
+		return err
+	}
+	return g.updateNodeAt(id, properties, time.Now())
+}
+
+// updateNodeAt applies an update and sets the node's UpdatedAt to at. Lists
+// are not checked for mixed types, so updates replayed from older logs apply.
+func (g *Graph) updateNodeAt(id graph.NodeID, properties graph.Properties, at time.Time) error {
+	node, err := g.GetNode(id)
+	if err != nil {
+		return err
+	}
+	properties, err = graph.NormalizeStoredProperties(properties)
+	if err != nil {
+		return err
+	}
+	if err := g.validateUpdate(node, properties); err != nil {
+		return err
+	}
+
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+
+	g.unindexProperties(node)
+	for k, v := range properties {
+		node.SetProperty(k, v)
+	}
+	node.Mu.Lock()
+	node.UpdatedAt = at
+	node.Mu.Unlock()
+	g.indexProperties(node)
+	return nil
+}
+
+// AddEdge creates a new edge between two nodes. The graph is a
+// multigraph: any number of edges with the same label may connect the same
+// pair of nodes. AddEdgeUnique adds an edge only if there is none yet.
 func (g *Graph) AddEdge(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error) {
 	// Verify nodes exist
 	srcNode, err := g.GetNode(source)
@@ -83,26 +430,111 @@ func (g *Graph) AddEdge(source, target graph.NodeID, label string, properties gr
 		return nil, fmt.Errorf("target node: %w", err)
 	}
 
+	properties, err = graph.NormalizeProperties(properties)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create edge
 	edgeID := graph.EdgeID(g.nextEdgeID.Add(1) - 1)
-	edge := graph.NewEdge(edgeID, source, target, label)
+	if g.idBlocks != nil {
+		if err := g.idBlocks.reserveEdge(uint64(edgeID)); err != nil {
+			return nil, err
+		}
+	}
+	return g.addEdge(edgeID, srcNode, tgtNode, label, properties), nil
+}
+
+// AddNodeWithID creates a node with a caller-chosen ID, e.g. when restoring
+// a dump. The ID allocator is advanced past id. Lists are not checked for
+// mixed types, so dumps of older data load.
+func (g *Graph) AddNodeWithID(id graph.NodeID, label string, properties graph.Properties) (*graph.Node, error) {
+	if _, err := g.GetNode(id); err == nil {
+		return nil, fmt.Errorf("node %d already exists", id)
+	}
+	properties, err := graph.NormalizeStoredProperties(properties)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.validateNode(id, label, properties); err != nil {
+		return nil, err
+	}
+
+	node := newNode(id, label, properties)
+	g.insertNode(node)
+	advanceID(&g.nextNodeID, uint64(id))
+	return node, nil
+}
+
+// AddEdgeWithID creates an edge with a caller-chosen ID. The ID allocator
+// is advanced past id. Like AddNodeWithID, it does not check lists for
+// mixed types.
+func (g *Graph) AddEdgeWithID(id graph.EdgeID, source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error) {
+	if _, err := g.GetEdge(id); err == nil {
+		return nil, fmt.Errorf("edge %d already exists", id)
+	}
+
+	srcNode, err := g.GetNode(source)
+	if err != nil {
+		return nil, fmt.Errorf("source node: %w", err)
+	}
+
+	tgtNode, err := g.GetNode(target)
+	if err != nil {
+		return nil, fmt.Errorf("target node: %w", err)
+	}
+
+	properties, err = graph.NormalizeStoredProperties(properties)
+	if err != nil {
+		return nil, err
+	}
+
+	edge := g.addEdge(id, srcNode, tgtNode, label, properties)
+	advanceID(&g.nextEdgeID, uint64(id))
+	return edge, nil
+}
 
-	if properties != nil {
-		for k, v := range properties {
-			edge.SetProperty(k, v)
+// advanceID moves an ID allocator past id if it has not already passed it
+func advanceID(next *atomic.Uint64, id uint64) {
+	for {
+		cur := next.Load()
+		if cur > id || next.CompareAndSwap(cur, id+1) {
+			return
 		}
 	}
+}
+
+// addEdge stores an edge and links it into the adjacency lists
+func (g *Graph) addEdge(edgeID graph.EdgeID, srcNode, tgtNode *graph.Node, label string, properties graph.Properties) *graph.Edge {
+	edge := graph.NewEdge(edgeID, srcNode.ID, tgtNode.ID, label)
+	for k, v := range properties {
+		edge.SetProperty(k, v)
+	}
+	edge.UpdatedAt = edge.CreatedAt
 
 	// Store edge
 	g.edgesMu.Lock()
-	g.edges[edgeID] = edge
+	g.putEdge(edge)
 	g.edgesMu.Unlock()
 
-	// Update adjacency lists
-	srcNode.AddOutEdge(edgeID)
-	tgtNode.AddInEdge(edgeID)
+	// Update adjacency lists, stamping the endpoints as replay will
+	linkEdgeAt(srcNode, edgeID, true, edge.CreatedAt)
+	linkEdgeAt(tgtNode, edgeID, false, edge.CreatedAt)
 
-	return edge, nil
+	return edge
+}
+
+// linkEdgeAt adds an edge to a node's outgoing or incoming adjacency list
+// and sets the node's UpdatedAt to at, the edge's CreatedAt
+func linkEdgeAt(node *graph.Node, edgeID graph.EdgeID, outgoing bool, at time.Time) {
+	node.Mu.Lock()
+	defer node.Mu.Unlock()
+	if outgoing {
+		node.OutEdges = append(node.OutEdges, edgeID)
+	} else {
+		node.InEdges = append(node.InEdges, edgeID)
+	}
+	node.UpdatedAt = at
 }
 
 // GetEdge retrieves an edge by ID
@@ -117,66 +549,198 @@ func (g *Graph) GetEdge(id graph.EdgeID) (*graph.Edge, error) {
 	return edge, nil
 }
 
-// GetNeighbors returns all neighbors of a node (nodes connected by outgoing edges)
-func (g *Graph) GetNeighbors(nodeID graph.NodeID) ([]*graph.Node, error) {
-	node, err := g.GetNode(nodeID)
+// GetEdgesBetween returns the edges from src to target in the order they
+// were added. It scans the outgoing edges of src, so it takes time
+// proportional to its out-degree.
+func (g *Graph) GetEdgesBetween(src, target graph.NodeID) ([]*graph.Edge, error) {
+	return g.edgesBetween(src, target)
+}
+
+// GetEdgesBetweenByLabel returns the edges from src to target with the
+// given label
+func (g *Graph) GetEdgesBetweenByLabel(src, target graph.NodeID, label string) ([]*graph.Edge, error) {
+	return g.edgesBetween(src, target, label)
+}
+
+// edgesBetween returns the edges from src to target with one of labels,
+// or with any label when none are given
+func (g *Graph) edgesBetween(src, target graph.NodeID, labels ...string) ([]*graph.Edge, error) {
+	srcNode, err := g.GetNode(src)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := g.GetNode(target); err != nil {
+		return nil, err
+	}
 
-	neighbors := make([]*graph.Node, 0, len(node.OutEdges))
-
-	node.Mu.RLock()
-	outEdges := make([]graph.EdgeID, len(node.OutEdges))
-	copy(outEdges, node.OutEdges)
-	node.Mu.RUnlock()
+	srcNode.Mu.RLock()
+	edgeIDs := append([]graph.EdgeID(nil), srcNode.OutEdges...)
+	srcNode.Mu.RUnlock()
 
-	for _, edgeID := range outEdges {
+	edges := make([]*graph.Edge, 0)
+	for _, edgeID := range edgeIDs {
 		edge, err := g.GetEdge(edgeID)
 		if err != nil {
-			continue // Skip missing edges
+			continue // Deleted since the adjacency list was read
 		}
-
-		neighbor, err := g.GetNode(edge.Target)
-		if err != nil {
-			continue // Skip missing nodes
+		edge.Mu.RLock()
+		matches := edge.Source == src && edge.Target == target && hasLabel(labels, edge.Label)
+		edge.Mu.RUnlock()
+		if matches {
+			edges = append(edges, edge)
 		}
-
-		neighbors = append(neighbors, neighbor)
 	}
+	return edges, nil
+}
 
-	return neighbors, nil
+// GetNeighbors returns all neighbors of a node (nodes connected by outgoing edges)
+func (g *Graph) GetNeighbors(nodeID graph.NodeID) ([]*graph.Node, error) {
+	return g.adjacentNodes(nodeID, graph.Outgoing)
 }
 
 // GetIncomingNeighbors returns all nodes with edges pointing to the given node
 func (g *Graph) GetIncomingNeighbors(nodeID graph.NodeID) ([]*graph.Node, error) {
-	node, err := g.GetNode(nodeID)
+	return g.adjacentNodes(nodeID, graph.Incoming)
+}
+
+// adjacentNodes returns the far ends of a node's edges in direction
+func (g *Graph) adjacentNodes(nodeID graph.NodeID, direction graph.Direction) ([]*graph.Node, error) {
+	adjacent, err := g.GetAdjacent(nodeID, direction)
 	if err != nil {
 		return nil, err
 	}
+	neighbors := make([]*graph.Node, len(adjacent))
+	for i, adj := range adjacent {
+		neighbors[i] = adj.Node
+	}
+	return neighbors, nil
+}
 
-	neighbors := make([]*graph.Node, 0, len(node.InEdges))
-
+// GetAdjacent returns the edges of a node in the given direction, each
+// with the node on its other end. When labels are given only edges with
+// one of those labels are returned. It collects what IterateNeighbors
+// visits; callers that may stop early should iterate instead.
+func (g *Graph) GetAdjacent(nodeID graph.NodeID, direction graph.Direction, labels ...string) ([]graph.Adjacency, error) {
+	node, err := g.GetNode(nodeID)
+	if err != nil {
+		return nil, err
+	}
 	node.Mu.RLock()
-	inEdges := make([]graph.EdgeID, len(node.InEdges))
-	copy(inEdges, node.InEdges)
+	degree := 0
+	if direction != graph.Incoming {
+		degree += len(node.OutEdges)
+	}
+	if direction != graph.Outgoing {
+		degree += len(node.InEdges)
+	}
 	node.Mu.RUnlock()
 
-	for _, edgeID := range inEdges {
-		edge, err := g.GetEdge(edgeID)
-		if err != nil {
+	adjacent := make([]graph.Adjacency, 0, degree)
+	err = g.IterateNeighbors(nodeID, direction, func(edge *graph.Edge, other *graph.Node) bool {
+		if hasLabel(labels, edge.Label) {
+			adjacent = append(adjacent, graph.Adjacency{Edge: edge, Node: other})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return adjacent, nil
+}
+
+// neighborChunk is how many edges IterateNeighbors reads from an adjacency
+// list at a time
+const neighborChunk = 256
+
+// IterateNeighbors calls callback for each edge of a node in the given
+// direction, outgoing before incoming, with the node on its other end,
+// until callback returns false. Each adjacency list is resolved in chunks
+// rather than copied whole, so stopping early on a node with millions of
+// edges costs little. No lock is held while callback runs. The list is
+// captured when iteration reaches it: removals replace the list instead
+// of shifting its entries, so every edge in it is visited unless it is
+// removed first, and edges added afterwards are not visited.
+func (g *Graph) IterateNeighbors(nodeID graph.NodeID, direction graph.Direction, callback func(edge *graph.Edge, node *graph.Node) bool) error {
+	node, err := g.GetNode(nodeID)
+	if err != nil {
+		return err
+	}
+
+	var chunk [neighborChunk]graph.Adjacency
+	for _, outgoing := range [2]bool{true, false} {
+		if outgoing && direction == graph.Incoming || !outgoing && direction == graph.Outgoing {
 			continue
 		}
+		node.Mu.RLock()
+		list := node.InEdges
+		if outgoing {
+			list = node.OutEdges
+		}
+		node.Mu.RUnlock()
+
+		for len(list) > 0 {
+			n := len(list)
+			if n > neighborChunk {
+				n = neighborChunk
+			}
+			for _, adj := range g.resolveAdjacent(nodeID, list[:n], outgoing, chunk[:0]) {
+				if !callback(adj.Edge, adj.Node) {
+					return nil
+				}
+			}
+			list = list[n:]
+		}
+	}
+	return nil
+}
 
-		neighbor, err := g.GetNode(edge.Source)
-		if err != nil {
+// resolveAdjacent appends to the empty slice adjacent the edges of node
+// with the given IDs that still exist and still start (outgoing) or end at
+// it, each with the node on its other end, locking the edge and node maps
+// once for the whole chunk rather than once per edge. edgeIDs holds at
+// most neighborChunk IDs.
+func (g *Graph) resolveAdjacent(node graph.NodeID, edgeIDs []graph.EdgeID, outgoing bool, adjacent []graph.Adjacency) []graph.Adjacency {
+	var others [neighborChunk]graph.NodeID
+	g.edgesMu.RLock()
+	for _, edgeID := range edgeIDs {
+		edge, exists := g.edges[edgeID]
+		if !exists {
 			continue
 		}
+		source, target := edge.Endpoints()
+		near, far := target, source
+		if outgoing {
+			near, far = source, target
+		}
+		if near == node {
+			others[len(adjacent)] = far
+			adjacent = append(adjacent, graph.Adjacency{Edge: edge})
+		}
+	}
+	g.edgesMu.RUnlock()
 
-		neighbors = append(neighbors, neighbor)
+	resolved := adjacent[:0]
+	g.nodesMu.RLock()
+	for i, adj := range adjacent {
+		if adj.Node = g.nodes[others[i]]; adj.Node != nil {
+			resolved = append(resolved, adj)
+		}
 	}
+	g.nodesMu.RUnlock()
+	return resolved
+}
 
-	return neighbors, nil
+// hasLabel reports whether label is one of labels, or labels is empty
+func hasLabel(labels []string, label string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
 }
 
 // NodeCount returns the number of nodes in the graph
@@ -193,8 +757,17 @@ func (g *Graph) EdgeCount() int {
 	return len(g.edges)
 }
 
-// DeleteNode removes a node and all its associated edges
+// DeleteNode removes a node and all its associated edges. In soft-delete
+// mode they are tombstoned instead; see SetSoftDelete.
 func (g *Graph) DeleteNode(id graph.NodeID) error {
+	if g.softDelete.Load() {
+		return g.tombstoneNode(id, g.now())
+	}
+	return g.removeNode(id)
+}
+
+// removeNode permanently removes a node and its edges
+func (g *Graph) removeNode(id graph.NodeID) error {
 	node, err := g.GetNode(id)
 	if err != nil {
 		return err
@@ -209,69 +782,160 @@ func (g *Graph) DeleteNode(id graph.NodeID) error {
 	node.Mu.RUnlock()
 
 	for _, edgeID := range outEdges {
-		g.DeleteEdge(edgeID)
+		g.removeEdge(edgeID)
 	}
 
 	for _, edgeID := range inEdges {
-		g.DeleteEdge(edgeID)
+		g.removeEdge(edgeID)
 	}
 
 	// Remove node
+	g.idxMu.Lock()
+	g.unindexProperties(node)
 	g.nodesMu.Lock()
+	g.unindexLabel(node)
 	delete(g.nodes, id)
 	g.nodesMu.Unlock()
+	g.idxMu.Unlock()
+
+	g.expiryMu.Lock()
+	delete(g.nodeExpiry, id)
+	g.expiryMu.Unlock()
 
 	return nil
 }
 
-// DeleteEdge removes an edge from the graph
+// DeleteEdge removes an edge from the graph. In soft-delete mode it is
+// tombstoned instead.
 func (g *Graph) DeleteEdge(id graph.EdgeID) error {
+	if g.softDelete.Load() {
+		return g.tombstoneEdge(id, g.now())
+	}
+	return g.removeEdge(id)
+}
+
+// removeEdge permanently removes an edge
+func (g *Graph) removeEdge(id graph.EdgeID) error {
 	edge, err := g.GetEdge(id)
 	if err != nil {
 		return err
 	}
 
-	// Remove from adjacency lists
-	srcNode, _ := g.GetNode(edge.Source)
+	// Remove from adjacency lists, without a reconnection moving the edge
+	// onto nodes it was not removed from
+	g.reconnectMu.Lock()
+	defer g.reconnectMu.Unlock()
+	source, target := edge.Endpoints()
+	srcNode, _ := g.GetNode(source)
 	if srcNode != nil {
 		g.removeOutEdge(srcNode, id)
 	}
 
-	tgtNode, _ := g.GetNode(edge.Target)
+	tgtNode, _ := g.GetNode(target)
 	if tgtNode != nil {
 		g.removeInEdge(tgtNode, id)
 	}
 
 	// Delete edge
 	g.edgesMu.Lock()
-	delete(g.edges, id)
+	g.dropEdge(id)
 	g.edgesMu.Unlock()
 
+	g.expiryMu.Lock()
+	delete(g.edgeExpiry, id)
+	g.expiryMu.Unlock()
+
 	return nil
 }
 
-func (g *Graph) removeOutEdge(node *graph.Node, edgeID graph.EdgeID) {
-	node.Mu.Lock()
-	defer node.Mu.Unlock()
+// UpdateEdgeTarget points an existing edge at a different target node,
+// keeping its ID, label and properties
+func (g *Graph) UpdateEdgeTarget(id graph.EdgeID, newTarget graph.NodeID) error {
+	edge, err := g.GetEdge(id)
+	if err != nil {
+		return err
+	}
+	g.reconnectMu.Lock()
+	defer g.reconnectMu.Unlock()
+	source, _ := edge.Endpoints()
+	return g.reconnectEdge(edge, source, newTarget, time.Now())
+}
 
-	for i, eid := range node.OutEdges {
-		if eid == edgeID {
-			node.OutEdges = append(node.OutEdges[:i], node.OutEdges[i+1:]...)
-			break
+// UpdateEdgeSource moves an existing edge to a different source node,
+// keeping its ID, label and properties
+func (g *Graph) UpdateEdgeSource(id graph.EdgeID, newSource graph.NodeID) error {
+	edge, err := g.GetEdge(id)
+	if err != nil {
+		return err
+	}
+	g.reconnectMu.Lock()
+	defer g.reconnectMu.Unlock()
+	_, target := edge.Endpoints()
+	return g.reconnectEdge(edge, newSource, target, time.Now())
+}
+
+// reconnectEdge sets an edge's endpoints, moves it between the adjacency
+// lists of the old and new nodes and sets its UpdatedAt to at. Caller
+// holds reconnectMu, so the endpoints it read are still current.
+func (g *Graph) reconnectEdge(edge *graph.Edge, source, target graph.NodeID, at time.Time) error {
+	srcNode, err := g.GetNode(source)
+	if err != nil {
+		return fmt.Errorf("source node: %w", err)
+	}
+	tgtNode, err := g.GetNode(target)
+	if err != nil {
+		return fmt.Errorf("target node: %w", err)
+	}
+	if current, err := g.GetEdge(edge.ID); err != nil || current != edge {
+		return fmt.Errorf("edge %d not found", edge.ID)
+	}
+
+	edge.Mu.Lock()
+	oldSource, oldTarget := edge.Source, edge.Target
+	edge.Source, edge.Target = source, target
+	edge.UpdatedAt = at
+	edge.Mu.Unlock()
+
+	if oldSource != source {
+		if oldNode, _ := g.GetNode(oldSource); oldNode != nil {
+			g.removeOutEdge(oldNode, edge.ID)
 		}
+		srcNode.AddOutEdge(edge.ID)
 	}
+	if oldTarget != target {
+		if oldNode, _ := g.GetNode(oldTarget); oldNode != nil {
+			g.removeInEdge(oldNode, edge.ID)
+		}
+		tgtNode.AddInEdge(edge.ID)
+	}
+	return nil
+}
+
+func (g *Graph) removeOutEdge(node *graph.Node, edgeID graph.EdgeID) {
+	node.Mu.Lock()
+	defer node.Mu.Unlock()
+	node.OutEdges = withoutEdge(node.OutEdges, edgeID)
 }
 
 func (g *Graph) removeInEdge(node *graph.Node, edgeID graph.EdgeID) {
 	node.Mu.Lock()
 	defer node.Mu.Unlock()
+	node.InEdges = withoutEdge(node.InEdges, edgeID)
+}
 
-	for i, eid := range node.InEdges {
+// withoutEdge returns a copy of an adjacency list without edgeID, or the
+// list itself if it does not hold it. The list is never changed in place,
+// so an IterateNeighbors that captured it keeps seeing every entry where
+// it was.
+func withoutEdge(list []graph.EdgeID, edgeID graph.EdgeID) []graph.EdgeID {
+	for i, eid := range list {
 		if eid == edgeID {
-			node.InEdges = append(node.InEdges[:i], node.InEdges[i+1:]...)
-			break
+			kept := make([]graph.EdgeID, 0, len(list)-1)
+			kept = append(kept, list[:i]...)
+			return append(kept, list[i+1:]...)
 		}
 	}
+	return list
 }
 
 // IterateNodes iterates over all nodes in the graph and calls the callback
@@ -298,3 +962,44 @@ func (g *Graph) IterateNodes(callback func(*graph.Node) bool) {
 		}
 	}
 }
+
+// IterateNodesSorted is IterateNodes in ascending ID order. IterateNodes
+// follows map order, which differs between calls.
+func (g *Graph) IterateNodesSorted(callback func(*graph.Node) bool) {
+	g.nodesMu.RLock()
+	nodes := make([]*graph.Node, 0, len(g.nodes))
+	for _, node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+	g.nodesMu.RUnlock()
+
+	iterateSorted(nodes, callback)
+}
+
+// iterateSorted sorts nodes by ID and calls callback for each until it
+// returns false
+func iterateSorted(nodes []*graph.Node, callback func(*graph.Node) bool) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	for _, node := range nodes {
+		if !callback(node) {
+			break
+		}
+	}
+}
+
+// IterateEdges iterates over all edges in the graph and calls the callback
+// If callback returns false, iteration stops
+func (g *Graph) IterateEdges(callback func(*graph.Edge) bool) {
+	g.edgesMu.RLock()
+	edges := make([]*graph.Edge, 0, len(g.edges))
+	for _, edge := range g.edges {
+		edges = append(edges, edge)
+	}
+	g.edgesMu.RUnlock()
+
+	for _, edge := range edges {
+		if !callback(edge) {
+			break
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"sort"
 	"sync"
 	"testing"
 
@@ -127,6 +128,140 @@ func TestGetIncomingNeighbors(t *testing.T) {
 	assert.Contains(t, incomingIDs, charlie.ID)
 }
 
+func TestGetAdjacent(t *testing.T) {
+	g := NewGraph()
+
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	acme, _ := g.AddNode("Company", graph.Properties{"name": "Acme"})
+
+	knows, _ := g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	works, _ := g.AddEdge(alice.ID, acme.ID, "WORKS_AT", nil)
+	back, _ := g.AddEdge(bob.ID, alice.ID, "KNOWS", nil)
+
+	out, err := g.GetAdjacent(alice.ID, graph.Outgoing)
+	require.NoError(t, err)
+	assert.Equal(t, []graph.Adjacency{{Edge: knows, Node: bob}, {Edge: works, Node: acme}}, out)
+
+	in, err := g.GetAdjacent(alice.ID, graph.Incoming)
+	require.NoError(t, err)
+	assert.Equal(t, []graph.Adjacency{{Edge: back, Node: bob}}, in)
+
+	both, err := g.GetAdjacent(alice.ID, graph.Both, "KNOWS")
+	require.NoError(t, err)
+	assert.Equal(t, []graph.Adjacency{{Edge: knows, Node: bob}, {Edge: back, Node: bob}}, both)
+
+	none, err := g.GetAdjacent(alice.ID, graph.Outgoing, "LIKES")
+	require.NoError(t, err)
+	assert.Empty(t, none)
+
+	_, err = g.GetAdjacent(graph.NodeID(999), graph.Outgoing)
+	assert.Error(t, err)
+}
+
+func TestIterateNeighbors(t *testing.T) {
+	g := NewGraph()
+
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	knows, _ := g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	back, _ := g.AddEdge(bob.ID, alice.ID, "KNOWS", nil)
+	self, _ := g.AddEdge(alice.ID, alice.ID, "LIKES", nil)
+
+	collect := func(direction graph.Direction) []graph.Adjacency {
+		var adjacent []graph.Adjacency
+		err := g.IterateNeighbors(alice.ID, direction, func(edge *graph.Edge, node *graph.Node) bool {
+			adjacent = append(adjacent, graph.Adjacency{Edge: edge, Node: node})
+			return true
+		})
+		require.NoError(t, err)
+		return adjacent
+	}
+	assert.Equal(t, []graph.Adjacency{{Edge: knows, Node: bob}, {Edge: self, Node: alice}}, collect(graph.Outgoing))
+	assert.Equal(t, []graph.Adjacency{{Edge: back, Node: bob}, {Edge: self, Node: alice}}, collect(graph.Incoming))
+
+	// Outgoing edges come first, and a self-loop is visited from both ends
+	assert.Equal(t, []graph.Adjacency{
+		{Edge: knows, Node: bob}, {Edge: self, Node: alice},
+		{Edge: back, Node: bob}, {Edge: self, Node: alice},
+	}, collect(graph.Both))
+
+	err := g.IterateNeighbors(graph.NodeID(999), graph.Outgoing, func(*graph.Edge, *graph.Node) bool { return true })
+	assert.Error(t, err)
+}
+
+func TestIterateNeighbors_Supernode(t *testing.T) {
+	g, center := newStarGraph(3*neighborChunk + 10)
+
+	// Deleted edges are skipped, including across chunks
+	center.Mu.RLock()
+	deleted := center.OutEdges[neighborChunk]
+	center.Mu.RUnlock()
+	require.NoError(t, g.DeleteEdge(deleted))
+
+	count := 0
+	err := g.IterateNeighbors(center.ID, graph.Outgoing, func(edge *graph.Edge, node *graph.Node) bool {
+		assert.NotEqual(t, deleted, edge.ID)
+		assert.Equal(t, edge.Target, node.ID)
+		count++
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3*neighborChunk+9, count)
+
+	// Stops as soon as the callback returns false
+	count = 0
+	g.IterateNeighbors(center.ID, graph.Outgoing, func(*graph.Edge, *graph.Node) bool {
+		count++
+		return count < neighborChunk+5
+	})
+	assert.Equal(t, neighborChunk+5, count)
+
+	// The callback may write to the graph without deadlocking
+	g.IterateNeighbors(center.ID, graph.Outgoing, func(edge *graph.Edge, node *graph.Node) bool {
+		_, err := g.AddEdge(node.ID, center.ID, "BACK", nil)
+		assert.NoError(t, err)
+		return false
+	})
+	in, _ := g.GetAdjacent(center.ID, graph.Incoming)
+	assert.Len(t, in, 1)
+}
+
+func TestIterateNeighbors_DeleteWhileIterating(t *testing.T) {
+	g, center := newStarGraph(3*neighborChunk + 10)
+
+	// Deleting each edge once visited shifts nothing still to come, so
+	// no live edge is skipped at a chunk boundary
+	visited := make(map[graph.EdgeID]bool)
+	err := g.IterateNeighbors(center.ID, graph.Outgoing, func(edge *graph.Edge, node *graph.Node) bool {
+		assert.False(t, visited[edge.ID], "edge %d visited twice", edge.ID)
+		visited[edge.ID] = true
+		assert.NoError(t, g.DeleteEdge(edge.ID))
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3*neighborChunk+10, len(visited))
+	assert.Equal(t, 0, g.EdgeCount())
+
+	// An edge moved off the node before its chunk is resolved is not
+	// reported as one of its edges
+	g, center = newStarGraph(neighborChunk + 1)
+	center.Mu.RLock()
+	first, moved := center.OutEdges[0], center.OutEdges[neighborChunk]
+	center.Mu.RUnlock()
+	other, _ := g.AddNode("Person", nil)
+	count := 0
+	g.IterateNeighbors(center.ID, graph.Outgoing, func(edge *graph.Edge, node *graph.Node) bool {
+		if edge.ID == first {
+			require.NoError(t, g.UpdateEdgeSource(moved, other.ID))
+		}
+		assert.NotEqual(t, moved, edge.ID)
+		count++
+		return true
+	})
+	assert.Equal(t, neighborChunk, count)
+}
+
 func TestDeleteEdge(t *testing.T) {
 	g := NewGraph()
 
@@ -146,6 +281,105 @@ func TestDeleteEdge(t *testing.T) {
 	assert.NotContains(t, node2.InEdges, edge.ID)
 }
 
+func TestUpdateEdgeEndpoints(t *testing.T) {
+	g := NewGraph()
+
+	alice, _ := g.AddNode("Person", nil)
+	bob, _ := g.AddNode("Person", nil)
+	carol, _ := g.AddNode("Person", nil)
+	edge, _ := g.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+
+	require.NoError(t, g.UpdateEdgeTarget(edge.ID, carol.ID))
+	got, err := g.GetEdge(edge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, carol.ID, got.Target)
+	assert.Equal(t, alice.ID, got.Source)
+	since, _ := got.GetProperty("since")
+	assert.EqualValues(t, 2020, since)
+	assert.NotContains(t, bob.InEdges, edge.ID)
+	assert.Contains(t, carol.InEdges, edge.ID)
+	assert.Equal(t, []graph.EdgeID{edge.ID}, alice.OutEdges)
+
+	require.NoError(t, g.UpdateEdgeSource(edge.ID, bob.ID))
+	assert.Equal(t, bob.ID, got.Source)
+	assert.Empty(t, alice.OutEdges)
+	assert.Contains(t, bob.OutEdges, edge.ID)
+	neighbors, err := g.GetNeighbors(bob.ID)
+	require.NoError(t, err)
+	require.Len(t, neighbors, 1)
+	assert.Equal(t, carol.ID, neighbors[0].ID)
+
+	// Missing endpoints and edges leave the edge as it was
+	assert.Error(t, g.UpdateEdgeTarget(edge.ID, 999))
+	assert.Error(t, g.UpdateEdgeSource(edge.ID, 999))
+	assert.Error(t, g.UpdateEdgeTarget(999, alice.ID))
+	assert.Equal(t, bob.ID, got.Source)
+	assert.Equal(t, carol.ID, got.Target)
+	assert.Equal(t, 1, g.EdgeCount())
+}
+
+func TestUpdateEdgeEndpoints_Concurrent(t *testing.T) {
+	g := NewGraph()
+
+	nodes := make([]*graph.Node, 4)
+	for i := range nodes {
+		nodes[i], _ = g.AddNode("Person", nil)
+	}
+	edge, _ := g.AddEdge(nodes[0].ID, nodes[1].ID, "KNOWS", nil)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				next := nodes[(w+i)%len(nodes)].ID
+				if i%2 == 0 {
+					assert.NoError(t, g.UpdateEdgeTarget(edge.ID, next))
+				} else {
+					assert.NoError(t, g.UpdateEdgeSource(edge.ID, next))
+				}
+			}
+		}(w)
+	}
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				for _, node := range nodes {
+					adjacent, err := g.GetAdjacent(node.ID, graph.Both)
+					assert.NoError(t, err)
+					for _, adj := range adjacent {
+						assert.NotNil(t, adj.Node)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The edge ends up in exactly the adjacency lists of its endpoints
+	source, target := edge.Endpoints()
+	for _, node := range nodes {
+		outs, ins := 0, 0
+		for _, id := range node.OutEdges {
+			if id == edge.ID {
+				outs++
+			}
+		}
+		for _, id := range node.InEdges {
+			if id == edge.ID {
+				ins++
+			}
+		}
+		assert.Equal(t, node.ID == source, outs == 1, "node %d out-edges", node.ID)
+		assert.Equal(t, node.ID == target, ins == 1, "node %d in-edges", node.ID)
+		assert.LessOrEqual(t, outs, 1)
+		assert.LessOrEqual(t, ins, 1)
+	}
+}
+
 func TestDeleteNode(t *testing.T) {
 	g := NewGraph()
 
@@ -246,3 +480,281 @@ func BenchmarkGetNeighbors(b *testing.B) {
 		g.GetNeighbors(center.ID)
 	}
 }
+
+// BenchmarkNeighborEdges resolves each neighbor and its edge one lookup at
+// a time from parallel readers, locking the edge and node maps once each
+// per edge
+func BenchmarkNeighborEdges(b *testing.B) {
+	g, center := newStarGraph(100)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			center.Mu.RLock()
+			outEdges := append([]graph.EdgeID(nil), center.OutEdges...)
+			center.Mu.RUnlock()
+			adjacent := make([]graph.Adjacency, 0, len(outEdges))
+			for _, edgeID := range outEdges {
+				edge, _ := g.GetEdge(edgeID)
+				node, _ := g.GetNode(edge.Target)
+				adjacent = append(adjacent, graph.Adjacency{Edge: edge, Node: node})
+			}
+		}
+	})
+}
+
+// BenchmarkGetAdjacent resolves the same pairs as BenchmarkNeighborEdges,
+// locking each map once per call
+func BenchmarkGetAdjacent(b *testing.B) {
+	g, center := newStarGraph(100)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			g.GetAdjacent(center.ID, graph.Outgoing)
+		}
+	})
+}
+
+// BenchmarkIterateNeighbors_Supernode reads the first few neighbors of a
+// node with a million edges. Iterating stops after one chunk, while
+// GetAdjacent resolves and allocates every edge first.
+func BenchmarkIterateNeighbors_Supernode(b *testing.B) {
+	g, center := newStarGraph(1_000_000)
+	first := func(n int) func(*graph.Edge, *graph.Node) bool {
+		return func(*graph.Edge, *graph.Node) bool {
+			n--
+			return n > 0
+		}
+	}
+
+	b.Run("IterateNeighbors", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			g.IterateNeighbors(center.ID, graph.Outgoing, first(10))
+		}
+	})
+	b.Run("GetAdjacent", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			adjacent, _ := g.GetAdjacent(center.ID, graph.Outgoing)
+			visit := first(10)
+			for _, adj := range adjacent {
+				if !visit(adj.Edge, adj.Node) {
+					break
+				}
+			}
+		}
+	})
+}
+
+// newStarGraph returns a graph with one node linked to n others
+func newStarGraph(n int) (*Graph, *graph.Node) {
+	g := NewGraph()
+	center, _ := g.AddNode("Center", nil)
+	for i := 0; i < n; i++ {
+		neighbor, _ := g.AddNode("Neighbor", nil)
+		g.AddEdge(center.ID, neighbor.ID, "CONNECTS", nil)
+	}
+	return g, center
+}
+
+func TestIterateEdges(t *testing.T) {
+	g := NewGraph()
+
+	n1, _ := g.AddNode("Person", nil)
+	n2, _ := g.AddNode("Person", nil)
+	g.AddEdge(n1.ID, n2.ID, "KNOWS", nil)
+	g.AddEdge(n2.ID, n1.ID, "KNOWS", nil)
+
+	count := 0
+	g.IterateEdges(func(e *graph.Edge) bool {
+		count++
+		return true
+	})
+	assert.Equal(t, 2, count)
+
+	// Early stop
+	count = 0
+	g.IterateEdges(func(e *graph.Edge) bool {
+		count++
+		return false
+	})
+	assert.Equal(t, 1, count)
+}
+
+func TestIterateNodesSorted(t *testing.T) {
+	g := NewGraph()
+	for i := 0; i < 100; i++ {
+		label := "Person"
+		if i%3 == 0 {
+			label = "Company"
+		}
+		g.AddNode(label, nil)
+	}
+	require.NoError(t, g.DeleteNode(50))
+
+	collect := func(iterate func(func(*graph.Node) bool)) []graph.NodeID {
+		var ids []graph.NodeID
+		iterate(func(n *graph.Node) bool {
+			ids = append(ids, n.ID)
+			return true
+		})
+		return ids
+	}
+
+	ids := collect(g.IterateNodesSorted)
+	assert.Len(t, ids, 99)
+	assert.True(t, sort.SliceIsSorted(ids, func(i, j int) bool { return ids[i] < ids[j] }))
+
+	ids = collect(func(cb func(*graph.Node) bool) { g.IterateNodesByLabelSorted("Company", cb) })
+	assert.Len(t, ids, 34)
+	assert.Equal(t, []graph.NodeID{1, 4, 7}, ids[:3])
+	assert.True(t, sort.SliceIsSorted(ids, func(i, j int) bool { return ids[i] < ids[j] }))
+
+	// Early stop
+	count := 0
+	g.IterateNodesSorted(func(n *graph.Node) bool {
+		count++
+		return n.ID < 5
+	})
+	assert.Equal(t, 5, count)
+}
+
+func TestLabelIndex(t *testing.T) {
+	g := NewGraph()
+
+	p1, _ := g.AddNode("Person", nil)
+	g.AddNode("Person", nil)
+	g.AddNode("Company", nil)
+
+	assert.Equal(t, 2, g.LabelCount("Person"))
+	assert.Equal(t, 1, g.LabelCount("Company"))
+	assert.Equal(t, 0, g.LabelCount("Missing"))
+
+	count := 0
+	g.IterateNodesByLabel("Person", func(n *graph.Node) bool {
+		assert.Equal(t, "Person", n.Label)
+		count++
+		return true
+	})
+	assert.Equal(t, 2, count)
+
+	require.NoError(t, g.DeleteNode(p1.ID))
+	assert.Equal(t, 1, g.LabelCount("Person"))
+}
+
+func TestLabelAndEdgeTypeCounts(t *testing.T) {
+	g := NewGraph()
+
+	a, _ := g.AddNode("Person", nil)
+	b, _ := g.AddNode("Person", nil)
+	c, _ := g.AddNode("Company", nil)
+	g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	g.AddEdge(a.ID, c.ID, "WORKS_AT", nil)
+	g.AddEdge(b.ID, c.ID, "WORKS_AT", nil)
+
+	assert.Equal(t, map[string]int{"Person": 2, "Company": 1}, g.LabelCounts())
+	assert.Equal(t, map[string]int{"KNOWS": 1, "WORKS_AT": 2}, g.EdgeTypeCounts())
+
+	require.NoError(t, g.DeleteNode(c.ID))
+	assert.Equal(t, map[string]int{"Person": 2}, g.LabelCounts())
+	assert.Equal(t, map[string]int{"KNOWS": 1}, g.EdgeTypeCounts())
+	assert.Equal(t, 0, g.EdgeTypeCount("WORKS_AT"))
+}
+
+func TestLabelsTypesAndPropertyKeys(t *testing.T) {
+	g := NewGraph()
+	assert.Empty(t, g.Labels())
+	assert.Empty(t, g.RelationshipTypes())
+	assert.Empty(t, g.PropertyKeys("Person"))
+
+	a, _ := g.AddNode("Person", graph.Properties{"name": "Alice", "age": 30})
+	b, _ := g.AddNode("Person", graph.Properties{"name": "Bob", "email": "bob@example.com"})
+	c, _ := g.AddNode("Company", nil)
+	g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	g.AddEdge(a.ID, c.ID, "WORKS_AT", nil)
+
+	assert.Equal(t, []string{"Company", "Person"}, g.Labels())
+	assert.Equal(t, []string{"KNOWS", "WORKS_AT"}, g.RelationshipTypes())
+	assert.Equal(t, []string{"age", "email", "name"}, g.PropertyKeys("Person"))
+	assert.Empty(t, g.PropertyKeys("Company"))
+
+	// Keys follow updates and deletes
+	require.NoError(t, g.UpdateNode(c.ID, graph.Properties{"founded": 1999}))
+	assert.Equal(t, []string{"founded"}, g.PropertyKeys("Company"))
+	require.NoError(t, g.DeleteNode(b.ID))
+	assert.Equal(t, []string{"age", "name"}, g.PropertyKeys("Person"))
+	assert.Equal(t, []string{"WORKS_AT"}, g.RelationshipTypes())
+	require.NoError(t, g.DeleteNode(c.ID))
+	assert.Equal(t, []string{"Person"}, g.Labels())
+	assert.Empty(t, g.PropertyKeys("Company"))
+
+	// Soft-deleted nodes are left out until restored
+	g.SetSoftDelete(true)
+	require.NoError(t, g.DeleteNode(a.ID))
+	assert.Empty(t, g.Labels())
+	assert.Empty(t, g.PropertyKeys("Person"))
+	require.NoError(t, g.RestoreNode(a.ID))
+	assert.Equal(t, []string{"age", "name"}, g.PropertyKeys("Person"))
+
+	require.NoError(t, g.Reindex())
+	assert.Equal(t, []string{"age", "name"}, g.PropertyKeys("Person"))
+}
+
+func TestEdgeTypeCount_SoftDelete(t *testing.T) {
+	g := NewGraph()
+	g.SetSoftDelete(true)
+
+	a, _ := g.AddNode("Person", nil)
+	b, _ := g.AddNode("Person", nil)
+	g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	g.AddEdge(b.ID, a.ID, "KNOWS", nil)
+	assert.Equal(t, 2, g.EdgeTypeCount("KNOWS"))
+
+	// Tombstoned edges are not counted until they are restored
+	require.NoError(t, g.DeleteNode(b.ID))
+	assert.Equal(t, 0, g.EdgeTypeCount("KNOWS"))
+	require.NoError(t, g.RestoreNode(b.ID))
+	assert.Equal(t, 2, g.EdgeTypeCount("KNOWS"))
+}
+
+func TestGetEdgesBetween(t *testing.T) {
+	g := NewGraph()
+	alice, _ := g.AddNode("Person", nil)
+	bob, _ := g.AddNode("Person", nil)
+	carol, _ := g.AddNode("Person", nil)
+
+	edges, err := g.GetEdgesBetween(alice.ID, bob.ID)
+	require.NoError(t, err)
+	assert.Empty(t, edges)
+
+	// Parallel edges, an edge the other way and one to another node
+	knows, _ := g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	likes, _ := g.AddEdge(alice.ID, bob.ID, "LIKES", nil)
+	knowsAgain, _ := g.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+	g.AddEdge(bob.ID, alice.ID, "KNOWS", nil)
+	g.AddEdge(alice.ID, carol.ID, "KNOWS", nil)
+
+	edges, err = g.GetEdgesBetween(alice.ID, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []*graph.Edge{knows, likes, knowsAgain}, edges)
+
+	edges, err = g.GetEdgesBetweenByLabel(alice.ID, bob.ID, "KNOWS")
+	require.NoError(t, err)
+	assert.Equal(t, []*graph.Edge{knows, knowsAgain}, edges)
+
+	edges, err = g.GetEdgesBetweenByLabel(alice.ID, bob.ID, "WORKS_WITH")
+	require.NoError(t, err)
+	assert.Empty(t, edges)
+
+	require.NoError(t, g.DeleteEdge(likes.ID))
+	edges, err = g.GetEdgesBetween(alice.ID, bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []*graph.Edge{knows, knowsAgain}, edges)
+
+	_, err = g.GetEdgesBetween(99, bob.ID)
+	assert.Error(t, err)
+	_, err = g.GetEdgesBetweenByLabel(alice.ID, 99, "KNOWS")
+	assert.Error(t, err)
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// AddToHistogram counts value in the histogram held by the property key
+// of a node, creating the histogram as graph.Node.AddToHistogram does.
+// The counted histogram is set like any UpdateNode, so indexes and schemas
+// see it.
+func (g *Graph) AddToHistogram(id graph.NodeID, key string, value float64) error {
+	g.histMu.Lock()
+	defer g.histMu.Unlock()
+
+	h, err := g.countInHistogram(id, key, value)
+	if err != nil {
+		return err
+	}
+	return g.UpdateNode(id, graph.Properties{key: h})
+}
+
+// countInHistogram returns a node's histogram property key with value
+// counted, leaving the node unchanged. Caller holds histMu.
+func (g *Graph) countInHistogram(id graph.NodeID, key string, value float64) (graph.Histogram, error) {
+	node, err := g.GetNode(id)
+	if err != nil {
+		return graph.Histogram{}, err
+	}
+	current, _ := node.GetProperty(key)
+	return graph.CountInHistogram(key, current, value)
+}
+
+// AddToHistogram counts value in a node's histogram property and logs the
+// counted histogram to WAL, so it survives a restart
+func (pg *PersistentGraph) AddToHistogram(id graph.NodeID, key string, value float64) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	pg.Graph.histMu.Lock()
+	defer pg.Graph.histMu.Unlock()
+
+	h, err := pg.Graph.countInHistogram(id, key, value)
+	if err != nil {
+		return err
+	}
+	return pg.UpdateNode(id, graph.Properties{key: h})
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddToHistogram(t *testing.T) {
+	g := NewGraph()
+	stats, _ := g.AddNode("Stats", graph.Properties{"name": "api"})
+
+	// Concurrent counts are all kept
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, g.AddToHistogram(stats.ID, "latency_ms", float64(i)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	v, ok := stats.GetProperty("latency_ms")
+	require.True(t, ok)
+	assert.Equal(t, int64(400), v.(graph.Histogram).Total())
+
+	assert.ErrorContains(t, g.AddToHistogram(stats.ID, "name", 1), "not a histogram")
+	assert.Error(t, g.AddToHistogram(stats.ID, "latency_ms", -1))
+	assert.Error(t, g.AddToHistogram(graph.NodeID(999), "latency_ms", 1))
+}
+
+func TestPersistentAddToHistogram(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	stats, err := pg.AddNode("Stats", nil)
+	require.NoError(t, err)
+	for _, latency := range []float64{12, 48, 7} {
+		require.NoError(t, pg.AddToHistogram(stats.ID, "latency_ms", latency))
+	}
+	require.NoError(t, pg.Snapshot())
+	require.NoError(t, pg.AddToHistogram(stats.ID, "latency_ms", 95))
+	want, _ := stats.GetProperty("latency_ms")
+	require.NoError(t, pg.Close())
+
+	// Counts from the snapshot and from the WAL after it survive reopening
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+	recovered, err := pg.GetNode(stats.ID)
+	require.NoError(t, err)
+	got, _ := recovered.GetProperty("latency_ms")
+	assert.Equal(t, want, got)
+	assert.Equal(t, []int64{1, 1, 0, 0, 1, 0, 0, 0, 0, 1}, got.(graph.Histogram).Counts)
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultIDBlockSize is a suggested Options.IDBlockSize: large enough that
+// reservations are a small share of the WAL
+const DefaultIDBlockSize = 1000
+
+// idBlocks hands out IDs only from blocks reserved in the WAL. A write is
+// rolled back if it cannot be logged, and lost if the process crashes
+// first, yet its caller may already have seen and kept the new ID.
+// Recovery moves the allocators past the last reservation (see
+// applyWALEntry), so such an ID is never reissued to another entity.
+type idBlocks struct {
+	size uint64
+	log  func(nodesUntil, edgesUntil uint64) error
+
+	// IDs below these bounds are reserved. They only change under mu,
+	// once the new reservation is logged.
+	mu         sync.Mutex
+	nodesUntil atomic.Uint64
+	edgesUntil atomic.Uint64
+}
+
+// newIDBlocks creates reservations for allocators that will hand out
+// nextNode and nextEdge next. IDs below those were reserved or used
+// before, so they count as reserved.
+func newIDBlocks(size int, nextNode, nextEdge uint64, log func(nodesUntil, edgesUntil uint64) error) *idBlocks {
+	b := &idBlocks{size: uint64(size), log: log}
+	b.nodesUntil.Store(nextNode)
+	b.edgesUntil.Store(nextEdge)
+	return b
+}
+
+// reserveNode makes sure node ID id is reserved before it is handed out
+func (b *idBlocks) reserveNode(id uint64) error {
+	return b.reserve(id, true)
+}
+
+// reserveEdge makes sure edge ID id is reserved before it is handed out
+func (b *idBlocks) reserveEdge(id uint64) error {
+	return b.reserve(id, false)
+}
+
+// reserve logs a new block starting at id unless id is already reserved
+func (b *idBlocks) reserve(id uint64, node bool) error {
+	until := &b.edgesUntil
+	if node {
+		until = &b.nodesUntil
+	}
+	if id < until.Load() {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if id < until.Load() {
+		return nil
+	}
+	nodesUntil, edgesUntil := b.nodesUntil.Load(), b.edgesUntil.Load()
+	if node {
+		nodesUntil = id + b.size
+	} else {
+		edgesUntil = id + b.size
+	}
+	if err := b.log(nodesUntil, edgesUntil); err != nil {
+		return fmt.Errorf("failed to reserve IDs: %w", err)
+	}
+	b.nodesUntil.Store(nodesUntil)
+	b.edgesUntil.Store(edgesUntil)
+	return nil
+}
+
+// bounds returns the current reservation. A reservation being logged is
+// waited for, so that once the WAL is truncated at an index read before
+// the call, every reservation it drops is covered.
+func (b *idBlocks) bounds() (nodesUntil, edgesUntil uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nodesUntil.Load(), b.edgesUntil.Load()
+}
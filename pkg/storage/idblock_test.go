@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openWithIDBlocks opens a graph that reserves IDs in blocks of size
+func openWithIDBlocks(t *testing.T, walDir, snapDir string, size int) *PersistentGraph {
+	opts := DefaultOptions()
+	opts.RecoveryOutput = io.Discard
+	opts.SweepInterval = 0
+	opts.IDBlockSize = size
+	pg, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	return pg
+}
+
+// loadLost adds n people and an edge through a bulk load whose writes never
+// reach the log, as in a crash, and returns the IDs the caller saw
+func loadLost(t *testing.T, pg *PersistentGraph, n int) ([]graph.NodeID, graph.EdgeID) {
+	var nodes []graph.NodeID
+	var edge graph.EdgeID
+	crash := errors.New("crash")
+	err := pg.BulkLoad(func(loader *BulkLoader) error {
+		for i := 0; i < n; i++ {
+			node, err := loader.AddNode("Person", nil)
+			require.NoError(t, err)
+			nodes = append(nodes, node.ID)
+		}
+		e, err := loader.AddEdge(nodes[0], nodes[1], "KNOWS", nil)
+		require.NoError(t, err)
+		edge = e.ID
+		return crash
+	})
+	require.ErrorIs(t, err, crash)
+	return nodes, edge
+}
+
+func TestIDBlocks_NoReuseAfterLostWrites(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg := openWithIDBlocks(t, walDir, snapDir, 10)
+	alice, err := pg.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+	assert.Equal(t, graph.NodeID(1), alice.ID)
+
+	// The lost load runs past the first block of node IDs
+	lost, lostEdge := loadLost(t, pg, 12)
+	assert.Equal(t, graph.NodeID(2), lost[0])
+	assert.Equal(t, graph.NodeID(13), lost[11])
+	require.NoError(t, pg.Close())
+
+	pg = openWithIDBlocks(t, walDir, snapDir, 10)
+	bob, err := pg.AddNode("Person", graph.Properties{"name": "Bob"})
+	require.NoError(t, err)
+	assert.Equal(t, graph.NodeID(21), bob.ID, "IDs continue after the last reservation")
+	assert.NotContains(t, lost, bob.ID)
+	knows, err := pg.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	require.NoError(t, err)
+	assert.Equal(t, graph.EdgeID(11), knows.ID)
+	assert.NotEqual(t, lostEdge, knows.ID)
+
+	// A snapshot truncates the reservations from the WAL but keeps the
+	// latest one
+	lost, _ = loadLost(t, pg, 3)
+	require.NoError(t, pg.Snapshot())
+	require.NoError(t, pg.Close())
+
+	pg = openWithIDBlocks(t, walDir, snapDir, 10)
+	carol, err := pg.AddNode("Person", graph.Properties{"name": "Carol"})
+	require.NoError(t, err)
+	assert.Equal(t, graph.NodeID(31), carol.ID)
+	assert.NotContains(t, lost, carol.ID)
+	assert.Equal(t, 3, pg.NodeCount())
+	require.NoError(t, pg.Close())
+}
+
+func TestIDBlocks_Disabled(t *testing.T) {
+	// Without reservations, recovery continues from the highest ID logged,
+	// reissuing the IDs of the lost writes
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg := openWithIDBlocks(t, walDir, snapDir, 0)
+	_, err := pg.AddNode("Person", nil)
+	require.NoError(t, err)
+	lost, _ := loadLost(t, pg, 3)
+	require.NoError(t, pg.Close())
+
+	pg = openWithIDBlocks(t, walDir, snapDir, 0)
+	defer pg.Close()
+	node, err := pg.AddNode("Person", nil)
+	require.NoError(t, err)
+	assert.Equal(t, lost[0], node.ID)
+	entries, _ := pg.WALSize()
+	assert.Equal(t, 2, entries)
+}
+
+func TestIDBlocks_LogsOnlyNewBlocks(t *testing.T) {
+	pg := openWithIDBlocks(t, t.TempDir(), t.TempDir(), 100)
+	defer pg.Close()
+	ch := pg.Subscribe()
+	defer pg.Unsubscribe(ch)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, err := pg.AddNode("Person", nil)
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 200 nodes take two blocks, each reserved before any of its IDs is
+	// logged
+	var reservations []wal.LogEntry
+	for i := 0; i < 202; i++ {
+		entry := <-ch
+		switch entry.OpType {
+		case wal.OpReserveIDs:
+			reservations = append(reservations, entry)
+		case wal.OpAddNode:
+			require.NotEmpty(t, reservations)
+			assert.Less(t, entry.Data["node_id"], reservations[len(reservations)-1].Data["nodes_until"])
+		}
+	}
+	require.Len(t, reservations, 2)
+	assert.Equal(t, 101.0, reservations[0].Data["nodes_until"])
+	assert.Equal(t, 201.0, reservations[1].Data["nodes_until"])
+	assert.Equal(t, 1.0, reservations[1].Data["edges_until"])
+}
+
+func TestIDBlocks_ReservationFailure(t *testing.T) {
+	failure := errors.New("disk full")
+	var logged [][2]uint64
+	fail := false
+	blocks := newIDBlocks(10, 5, 1, func(nodesUntil, edgesUntil uint64) error {
+		if fail {
+			return failure
+		}
+		logged = append(logged, [2]uint64{nodesUntil, edgesUntil})
+		return nil
+	})
+
+	require.NoError(t, blocks.reserveNode(4))
+	assert.Empty(t, logged)
+	require.NoError(t, blocks.reserveNode(5))
+	require.NoError(t, blocks.reserveEdge(1))
+	assert.Equal(t, [][2]uint64{{15, 1}, {15, 11}}, logged)
+
+	// A reservation that cannot be logged is not made
+	fail = true
+	assert.ErrorIs(t, blocks.reserveNode(15), failure)
+	nodesUntil, edgesUntil := blocks.bounds()
+	assert.Equal(t, uint64(15), nodesUntil)
+	assert.Equal(t, uint64(11), edgesUntil)
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// NodeIterator walks the nodes a graph held when the iterator was created.
+// Only the IDs are copied up front; each node is looked up as Next reaches
+// it, so nodes deleted in the meantime are skipped and nodes added later
+// are not seen. No lock is held between calls, so the caller may modify
+// the graph while iterating:
+//
+//	iter := g.Nodes()
+//	defer iter.Close()
+//	for node, ok := iter.Next(); ok; node, ok = iter.Next() {
+//		...
+//	}
+type NodeIterator struct {
+	g   *Graph
+	ids []graph.NodeID
+	pos int
+}
+
+// Nodes returns an iterator over all nodes, in no particular order
+func (g *Graph) Nodes() *NodeIterator {
+	g.nodesMu.RLock()
+	ids := make([]graph.NodeID, 0, len(g.nodes))
+	for id := range g.nodes {
+		ids = append(ids, id)
+	}
+	g.nodesMu.RUnlock()
+
+	return &NodeIterator{g: g, ids: ids}
+}
+
+// NodesByLabel returns an iterator over the nodes with the given label
+// using the label index
+func (g *Graph) NodesByLabel(label string) *NodeIterator {
+	g.nodesMu.RLock()
+	ids := make([]graph.NodeID, 0, len(g.nodesByLabel[label]))
+	for id := range g.nodesByLabel[label] {
+		ids = append(ids, id)
+	}
+	g.nodesMu.RUnlock()
+
+	return &NodeIterator{g: g, ids: ids}
+}
+
+// Next returns the next node that still exists, or false when the
+// iterator is exhausted or closed
+func (it *NodeIterator) Next() (*graph.Node, bool) {
+	for it.pos < len(it.ids) {
+		id := it.ids[it.pos]
+		it.pos++
+
+		it.g.nodesMu.RLock()
+		node := it.g.nodes[id]
+		it.g.nodesMu.RUnlock()
+		if node == nil {
+			continue
+		}
+
+		// A node tombstoned since the lookup is marked under its own lock
+		node.Mu.RLock()
+		deleted := !node.DeletedAt.IsZero()
+		node.Mu.RUnlock()
+		if !deleted {
+			return node, true
+		}
+	}
+	return nil, false
+}
+
+// Close releases the iterator's ID snapshot. Next returns false afterwards.
+func (it *NodeIterator) Close() {
+	it.ids = nil
+	it.pos = 0
+}
+
+// EdgeIterator walks the edges a graph held when the iterator was
+// created, with the same guarantees as NodeIterator
+type EdgeIterator struct {
+	g   *Graph
+	ids []graph.EdgeID
+	pos int
+}
+
+// Edges returns an iterator over all edges, in no particular order
+func (g *Graph) Edges() *EdgeIterator {
+	g.edgesMu.RLock()
+	ids := make([]graph.EdgeID, 0, len(g.edges))
+	for id := range g.edges {
+		ids = append(ids, id)
+	}
+	g.edgesMu.RUnlock()
+
+	return &EdgeIterator{g: g, ids: ids}
+}
+
+// Next returns the next edge that still exists, or false when the
+// iterator is exhausted or closed
+func (it *EdgeIterator) Next() (*graph.Edge, bool) {
+	for it.pos < len(it.ids) {
+		id := it.ids[it.pos]
+		it.pos++
+
+		it.g.edgesMu.RLock()
+		edge := it.g.edges[id]
+		it.g.edgesMu.RUnlock()
+		if edge == nil {
+			continue
+		}
+
+		edge.Mu.RLock()
+		deleted := !edge.DeletedAt.IsZero()
+		edge.Mu.RUnlock()
+		if !deleted {
+			return edge, true
+		}
+	}
+	return nil, false
+}
+
+// Close releases the iterator's ID snapshot. Next returns false afterwards.
+func (it *EdgeIterator) Close() {
+	it.ids = nil
+	it.pos = 0
+}
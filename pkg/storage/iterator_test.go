@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeIterator(t *testing.T) {
+	g := NewGraph()
+	alice, _ := g.AddNode("Person", nil)
+	bob, _ := g.AddNode("Person", nil)
+	acme, _ := g.AddNode("Company", nil)
+
+	var ids []graph.NodeID
+	iter := g.Nodes()
+	for node, ok := iter.Next(); ok; node, ok = iter.Next() {
+		ids = append(ids, node.ID)
+	}
+	iter.Close()
+	assert.ElementsMatch(t, []graph.NodeID{alice.ID, bob.ID, acme.ID}, ids)
+
+	ids = nil
+	iter = g.NodesByLabel("Person")
+	for node, ok := iter.Next(); ok; node, ok = iter.Next() {
+		ids = append(ids, node.ID)
+	}
+	assert.ElementsMatch(t, []graph.NodeID{alice.ID, bob.ID}, ids)
+
+	_, ok := g.NodesByLabel("Missing").Next()
+	assert.False(t, ok)
+
+	// A closed iterator is exhausted
+	iter = g.Nodes()
+	_, ok = iter.Next()
+	require.True(t, ok)
+	iter.Close()
+	_, ok = iter.Next()
+	assert.False(t, ok)
+}
+
+func TestNodeIterator_SeesDeletes(t *testing.T) {
+	g := NewGraph()
+	alice, _ := g.AddNode("Person", nil)
+	bob, _ := g.AddNode("Person", nil)
+
+	iter := g.Nodes()
+	defer iter.Close()
+
+	// Nodes deleted after the snapshot are skipped, new ones are not seen
+	require.NoError(t, g.DeleteNode(bob.ID))
+	g.AddNode("Person", nil)
+
+	var ids []graph.NodeID
+	for node, ok := iter.Next(); ok; node, ok = iter.Next() {
+		ids = append(ids, node.ID)
+	}
+	assert.Equal(t, []graph.NodeID{alice.ID}, ids)
+}
+
+func TestEdgeIterator(t *testing.T) {
+	g := NewGraph()
+	g.SetSoftDelete(true)
+	a, _ := g.AddNode("Person", nil)
+	b, _ := g.AddNode("Person", nil)
+	knows, _ := g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	likes, _ := g.AddEdge(b.ID, a.ID, "LIKES", nil)
+
+	iter := g.Edges()
+	defer iter.Close()
+	require.NoError(t, g.DeleteEdge(likes.ID))
+
+	var ids []graph.EdgeID
+	for edge, ok := iter.Next(); ok; edge, ok = iter.Next() {
+		ids = append(ids, edge.ID)
+	}
+	assert.Equal(t, []graph.EdgeID{knows.ID}, ids)
+}
+
+func TestIterators_Concurrent(t *testing.T) {
+	g := NewGraph()
+	for i := 0; i < 200; i++ {
+		node, _ := g.AddNode("Person", graph.Properties{"i": i})
+		if i > 0 {
+			g.AddEdge(node.ID, node.ID-1, "NEXT", nil)
+		}
+	}
+
+	// Writers add and delete nodes while readers iterate; iteration must
+	// neither deadlock nor return a deleted node
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				node, err := g.AddNode("Person", nil)
+				if err == nil {
+					g.DeleteNode(node.ID)
+				}
+			}
+		}()
+	}
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				count := 0
+				iter := g.NodesByLabel("Person")
+				for node, ok := iter.Next(); ok; node, ok = iter.Next() {
+					node.Mu.RLock()
+					_, hasIndex := node.Properties.GetProperty("i")
+					node.Mu.RUnlock()
+					if hasIndex {
+						count++
+					}
+				}
+				iter.Close()
+				assert.Equal(t, 200, count)
+
+				edges := g.Edges()
+				for _, ok := edges.Next(); ok; _, ok = edges.Next() {
+				}
+				edges.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 200, g.NodeCount())
+}
@@ -2,8 +2,14 @@
 package storage
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
 	"github.com/fnuworsu/rdgDB/pkg/wal"
@@ -15,15 +21,161 @@ type PersistentGraph struct {
 	wal             *wal.WAL
 	snapshotManager *wal.SnapshotManager
 	walEnabled      bool
+	opts            Options
 	mu              sync.RWMutex
+
+	// Held shared by each write and exclusively while a snapshot captures
+	// the graph (see beginWrite)
+	writeMu sync.RWMutex
+
+	// Advanced as each write finishes (see Version)
+	version atomic.Uint64
+
+	// Last WAL index a read-only graph has applied (see Refresh)
+	replayed  uint64
+	refreshMu sync.Mutex
+
+	// WAL index of the latest snapshot (see snapshotter.go)
+	snapshotIndex atomic.Uint64
+
+	// Materialized algorithm results (see stats.go). Once statsClosed is
+	// set, no run starts.
+	stats       map[string]*statsState
+	statsClosed bool
+	statsMu     sync.Mutex
+	statsWG     sync.WaitGroup
+	statsStop   chan struct{}
+	statsOnce   sync.Once
+	statsTickWG sync.WaitGroup
+
+	// Background deletion of expired entities (see ttl.go)
+	sweepStop chan struct{}
+	sweepOnce sync.Once
+	sweepWG   sync.WaitGroup
+}
+
+// RecoverMode selects how much state is restored when a graph is opened
+type RecoverMode int
+
+const (
+	// RecoverFull loads the latest snapshot and replays the WAL after it
+	RecoverFull RecoverMode = iota
+
+	// RecoverSnapshotOnly loads the latest snapshot and skips WAL replay.
+	// Startup is faster, but every write logged since that snapshot is
+	// missing, so the graph may be stale. Intended for read replicas, the
+	// graph is opened as if ReadOnly were set, since writes could reuse
+	// the IDs of the skipped entries; Refresh applies those entries.
+	RecoverSnapshotOnly
+)
+
+// ErrReadOnly is returned by mutating operations on a read-only graph
+var ErrReadOnly = errors.New("graph is opened read-only")
+
+// Options configures a PersistentGraph
+type Options struct {
+	RecoverMode RecoverMode
+
+	// ReadOnly opens the WAL for replay only and rejects every mutation
+	// with ErrReadOnly, so several processes can read one data directory.
+	// Call Refresh to pick up what the writer has logged since opening.
+	ReadOnly bool
+
+	// SweepInterval is how often expired nodes and edges are deleted, and
+	// tombstones purged, in the background. Zero, the default, runs no
+	// sweeper; SweepExpired and PurgeTombstones can still be called
+	// directly. DefaultSweepInterval suits most servers.
+	SweepInterval time.Duration
+
+	// SweepBatchSize caps the deletions made by one sweep.
+	// Zero means DefaultSweepBatchSize.
+	SweepBatchSize int
+
+	// StatsRefreshInterval is how often materialized stats that the graph
+	// has changed under are recomputed in the background. Zero, the
+	// default, never refreshes them; RefreshStats can still be called.
+	StatsRefreshInterval time.Duration
+
+	// Clock decides when entities expire. Nil means time.Now.
+	Clock func() time.Time
+
+	// SoftDelete makes DeleteNode and DeleteEdge leave tombstones that
+	// RestoreNode can bring back (see Graph.SetSoftDelete)
+	SoftDelete bool
+
+	// TombstoneRetention is how long tombstones are kept before the
+	// sweeper purges them
+	TombstoneRetention time.Duration
+
+	// WALFormat is the encoding of a new WAL (see wal.Options). An
+	// existing WAL keeps its format until the next snapshot truncates it.
+	WALFormat wal.Format
+
+	// RecoveryOutput receives the status messages and progress written
+	// while the graph is recovered. Nil means standard output.
+	RecoveryOutput io.Writer
+
+	// RecoveryProgress, if set, is called as the snapshot is loaded and
+	// the WAL replayed, and once when recovery is complete, for example
+	// to report readiness. Calls are never concurrent. A read-only graph
+	// reports only the snapshot and completion.
+	RecoveryProgress func(RecoveryProgress)
+
+	// RecoveryParallelism is the number of goroutines that load the
+	// snapshot. Zero means GOMAXPROCS.
+	RecoveryParallelism int
+
+	// IDBlockSize is how many node or edge IDs are reserved in the WAL at
+	// a time. IDs are only handed out once reserved, and recovery skips
+	// past the last reservation, so an ID a caller has seen is never
+	// reissued, even if its write was rolled back or lost in a crash.
+	// Zero, the default, disables reservations, and recovery then
+	// continues from the highest ID in the graph. Reservations are logged
+	// as wal.OpReserveIDs entries, which subscribers also receive.
+	IDBlockSize int
+}
+
+// Sweeper defaults. DefaultOptions leaves the sweeper off; callers that
+// enable it usually set SweepInterval to DefaultSweepInterval.
+const (
+	DefaultSweepInterval      = time.Minute
+	DefaultSweepBatchSize     = 1000
+	DefaultTombstoneRetention = 24 * time.Hour
+)
+
+// DefaultOptions returns the options used by NewPersistentGraph
+func DefaultOptions() Options {
+	return Options{
+		RecoverMode:        RecoverFull,
+		SweepBatchSize:     DefaultSweepBatchSize,
+		TombstoneRetention: DefaultTombstoneRetention,
+	}
 }
 
 // NewPersistentGraph creates a new persistent graph with WAL and snapshots
 func NewPersistentGraph(walDir, snapshotDir string) (*PersistentGraph, error) {
+	return NewPersistentGraphWithOptions(walDir, snapshotDir, DefaultOptions())
+}
+
+// NewPersistentGraphWithOptions creates a persistent graph configured by opts
+func NewPersistentGraphWithOptions(walDir, snapshotDir string, opts Options) (*PersistentGraph, error) {
 	g := NewGraph()
+	if opts.Clock != nil {
+		g.SetClock(opts.Clock)
+	}
+	g.SetSoftDelete(opts.SoftDelete)
+	if opts.RecoverMode == RecoverSnapshotOnly {
+		opts.ReadOnly = true
+	}
 
 	// Initialize WAL
-	walLog, err := wal.NewWAL(walDir)
+	var walLog *wal.WAL
+	var err error
+	if opts.ReadOnly {
+		walLog, err = wal.NewReadOnlyWAL(walDir)
+	} else {
+		walLog, err = wal.NewWALWithOptions(walDir, wal.Options{Format: opts.WALFormat})
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create WAL: %w", err)
 	}
@@ -40,6 +192,8 @@ func NewPersistentGraph(walDir, snapshotDir string) (*PersistentGraph, error) {
 		wal:             walLog,
 		snapshotManager: snapMgr,
 		walEnabled:      true,
+		opts:            opts,
+		stats:           make(map[string]*statsState),
 	}
 
 	// Attempt recovery
@@ -47,11 +201,59 @@ func NewPersistentGraph(walDir, snapshotDir string) (*PersistentGraph, error) {
 		return nil, fmt.Errorf("failed to recover: %w", err)
 	}
 
+	if !opts.ReadOnly && opts.IDBlockSize > 0 {
+		g.idBlocks = newIDBlocks(opts.IDBlockSize, g.nextNodeID.Load(), g.nextEdgeID.Load(), walLog.LogReserveIDs)
+	}
+
+	if !opts.ReadOnly && opts.SweepInterval > 0 {
+		pg.startSweeper(opts.SweepInterval)
+	}
+	if !opts.ReadOnly && opts.StatsRefreshInterval > 0 {
+		pg.startStatsRefresher(opts.StatsRefreshInterval)
+	}
+
 	return pg, nil
 }
 
+// ReadOnly reports whether the graph rejects mutations
+func (pg *PersistentGraph) ReadOnly() bool {
+	return pg.opts.ReadOnly
+}
+
+// WALIndex returns the index of the last WAL entry. It grows with every
+// logged mutation, so callers can use it to detect changes.
+func (pg *PersistentGraph) WALIndex() uint64 {
+	return pg.wal.GetCurrentIndex()
+}
+
+// beginWrite holds off snapshots until the returned function is called.
+// Writers hold it from their first change to memory or the WAL until
+// their last, so a snapshot never captures a write that is applied but not
+// yet logged, or logged but not yet applied. A goroutine must not hold it
+// twice, as a waiting snapshot would deadlock.
+func (pg *PersistentGraph) beginWrite() (end func()) {
+	pg.writeMu.RLock()
+	return func() {
+		pg.version.Add(1)
+		pg.writeMu.RUnlock()
+	}
+}
+
+// Version returns a counter that advances whenever a write finishes,
+// once its change is in memory. Unlike WALIndex, which some writes
+// advance before applying their change, a result read from the graph
+// after Version returned v reflects every write counted in v.
+func (pg *PersistentGraph) Version() uint64 {
+	return pg.version.Load()
+}
+
 // AddNode creates a new node and logs to WAL
 func (pg *PersistentGraph) AddNode(label string, properties graph.Properties) (*graph.Node, error) {
+	if pg.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
 	node, err := pg.Graph.AddNode(label, properties)
 	if err != nil {
 		return nil, err
@@ -59,18 +261,25 @@ func (pg *PersistentGraph) AddNode(label string, properties graph.Properties) (*
 
 	// Log to WAL
 	if pg.walEnabled {
-		if err := pg.wal.LogAddNode(node.ID, label, properties); err != nil {
+		if err := pg.wal.LogAddNode(node.ID, label, properties, node.CreatedAt); err != nil {
 			// Rollback in-memory change
-			pg.Graph.DeleteNode(node.ID)
+			pg.Graph.removeNode(node.ID)
 			return nil, fmt.Errorf("failed to log node addition: %w", err)
 		}
 	}
 
+	pg.markStatsDirty()
 	return node, nil
 }
 
-// AddEdge creates a new edge and logs to WAL
+// AddEdge creates a new edge and logs to WAL. Like Graph.AddEdge, it
+// permits parallel edges.
 func (pg *PersistentGraph) AddEdge(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error) {
+	if pg.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
 	edge, err := pg.Graph.AddEdge(source, target, label, properties)
 	if err != nil {
 		return nil, err
@@ -78,61 +287,378 @@ func (pg *PersistentGraph) AddEdge(source, target graph.NodeID, label string, pr
 
 	// Log to WAL
 	if pg.walEnabled {
-		if err := pg.wal.LogAddEdge(edge.ID, source, target, label, properties); err != nil {
+		if err := pg.wal.LogAddEdge(edge.ID, source, target, label, properties, edge.CreatedAt); err != nil {
 			// Rollback
-			pg.Graph.DeleteEdge(edge.ID)
+			pg.Graph.removeEdge(edge.ID)
+			return nil, fmt.Errorf("failed to log edge addition: %w", err)
+		}
+	}
+
+	pg.markStatsDirty()
+	return edge, nil
+}
+
+// AddNodeWithID creates a node with a caller-chosen ID and logs to WAL
+func (pg *PersistentGraph) AddNodeWithID(id graph.NodeID, label string, properties graph.Properties) (*graph.Node, error) {
+	if pg.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	node, err := pg.Graph.AddNodeWithID(id, label, properties)
+	if err != nil {
+		return nil, err
+	}
+
+	if pg.walEnabled {
+		if err := pg.wal.LogAddNode(node.ID, label, properties, node.CreatedAt); err != nil {
+			pg.Graph.removeNode(node.ID)
+			return nil, fmt.Errorf("failed to log node addition: %w", err)
+		}
+	}
+
+	pg.markStatsDirty()
+	return node, nil
+}
+
+// AddEdgeWithID creates an edge with a caller-chosen ID and logs to WAL
+func (pg *PersistentGraph) AddEdgeWithID(id graph.EdgeID, source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error) {
+	if pg.opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	edge, err := pg.Graph.AddEdgeWithID(id, source, target, label, properties)
+	if err != nil {
+		return nil, err
+	}
+
+	if pg.walEnabled {
+		if err := pg.wal.LogAddEdge(edge.ID, source, target, label, properties, edge.CreatedAt); err != nil {
+			pg.Graph.removeEdge(edge.ID)
 			return nil, fmt.Errorf("failed to log edge addition: %w", err)
 		}
 	}
 
+	pg.markStatsDirty()
 	return edge, nil
 }
 
 // DeleteNode deletes a node and logs to WAL
 func (pg *PersistentGraph) DeleteNode(id graph.NodeID) error {
-	if err := pg.Graph.DeleteNode(id); err != nil {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	if pg.Graph.SoftDelete() {
+		return pg.tombstoneNode(id)
+	}
+	defer pg.beginWrite()()
+	if _, err := pg.Graph.GetNode(id); err != nil {
 		return err
 	}
 
-	// Log to WAL
+	// Log before applying so a failed append leaves memory untouched
 	if pg.walEnabled {
 		if err := pg.wal.LogDeleteNode(id); err != nil {
 			return fmt.Errorf("failed to log node deletion: %w", err)
 		}
 	}
 
+	if err := pg.Graph.removeNode(id); err != nil {
+		return err
+	}
+	pg.markStatsDirty()
 	return nil
 }
 
 // DeleteEdge deletes an edge and logs to WAL
 func (pg *PersistentGraph) DeleteEdge(id graph.EdgeID) error {
-	if err := pg.Graph.DeleteEdge(id); err != nil {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	if pg.Graph.SoftDelete() {
+		return pg.tombstoneEdge(id)
+	}
+	defer pg.beginWrite()()
+	if _, err := pg.Graph.GetEdge(id); err != nil {
 		return err
 	}
 
-	// Log to WAL
 	if pg.walEnabled {
 		if err := pg.wal.LogDeleteEdge(id); err != nil {
 			return fmt.Errorf("failed to log edge deletion: %w", err)
 		}
 	}
 
+	if err := pg.Graph.removeEdge(id); err != nil {
+		return err
+	}
+	pg.markStatsDirty()
+	return nil
+}
+
+// UpdateEdgeTarget points an existing edge at a different target node and
+// logs to WAL
+func (pg *PersistentGraph) UpdateEdgeTarget(id graph.EdgeID, newTarget graph.NodeID) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	edge, err := pg.Graph.GetEdge(id)
+	if err != nil {
+		return err
+	}
+	pg.reconnectMu.Lock()
+	defer pg.reconnectMu.Unlock()
+	source, _ := edge.Endpoints()
+	return pg.reconnectEdge(edge, source, newTarget)
+}
+
+// UpdateEdgeSource moves an existing edge to a different source node and
+// logs to WAL
+func (pg *PersistentGraph) UpdateEdgeSource(id graph.EdgeID, newSource graph.NodeID) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	edge, err := pg.Graph.GetEdge(id)
+	if err != nil {
+		return err
+	}
+	pg.reconnectMu.Lock()
+	defer pg.reconnectMu.Unlock()
+	_, target := edge.Endpoints()
+	return pg.reconnectEdge(edge, newSource, target)
+}
+
+// reconnectEdge validates and logs new endpoints for edge, then applies
+// them. Caller holds reconnectMu, which also keeps the WAL order of
+// concurrent reconnections the order they are applied in.
+func (pg *PersistentGraph) reconnectEdge(edge *graph.Edge, source, target graph.NodeID) error {
+	defer pg.beginWrite()()
+	if _, err := pg.Graph.GetNode(source); err != nil {
+		return fmt.Errorf("source node: %w", err)
+	}
+	if _, err := pg.Graph.GetNode(target); err != nil {
+		return fmt.Errorf("target node: %w", err)
+	}
+
+	// Log before applying so a failed append leaves memory untouched
+	now := time.Now()
+	if pg.walEnabled {
+		if err := pg.wal.LogReconnectEdge(edge.ID, source, target, now); err != nil {
+			return fmt.Errorf("failed to log edge reconnection: %w", err)
+		}
+	}
+
+	if err := pg.Graph.reconnectEdge(edge, source, target, now); err != nil {
+		return err
+	}
+	pg.markStatsDirty()
+	return nil
+}
+
+// UpdateNode sets properties on an existing node and logs to WAL
+func (pg *PersistentGraph) UpdateNode(id graph.NodeID, properties graph.Properties) error {
+	if err := pg.updateNode(id, properties); err != nil {
+		return err
+	}
+
+	pg.markStatsDirty()
+	return nil
+}
+
+// updateNode logs and applies a property update without touching stats state
+func (pg *PersistentGraph) updateNode(id graph.NodeID, properties graph.Properties) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	node, err := pg.Graph.GetNode(id)
+	if err != nil {
+		return err
+	}
+	properties, err = graph.NormalizeProperties(properties)
+	if err != nil {
+		return err
+	}
+	if err := pg.Graph.validateUpdate(node, properties); err != nil {
+		return err
+	}
+
+	// Log before applying so a failed append leaves memory untouched
+	now := time.Now()
+	if pg.walEnabled {
+		if err := pg.wal.LogSetNodeProperties(id, properties, now); err != nil {
+			return fmt.Errorf("failed to log node update: %w", err)
+		}
+	}
+
+	return pg.Graph.updateNodeAt(id, properties, now)
+}
+
+// CreateFullTextIndex builds a full-text index and logs it to WAL
+func (pg *PersistentGraph) CreateFullTextIndex(label, property string) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	if err := pg.Graph.CreateFullTextIndex(label, property); err != nil {
+		return err
+	}
+
+	if pg.walEnabled {
+		if err := pg.wal.LogCreateFullTextIndex(label, property); err != nil {
+			pg.Graph.DropFullTextIndex(label, property)
+			return fmt.Errorf("failed to log index creation: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// CreateSpatialIndex builds a spatial index and logs it to WAL
+func (pg *PersistentGraph) CreateSpatialIndex(label, property string) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	if err := pg.Graph.CreateSpatialIndex(label, property); err != nil {
+		return err
+	}
+
+	if pg.walEnabled {
+		if err := pg.wal.LogCreateSpatialIndex(label, property); err != nil {
+			pg.Graph.DropSpatialIndex(label, property)
+			return fmt.Errorf("failed to log index creation: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// CreatePropertyIndex builds a property index and logs it to WAL
+func (pg *PersistentGraph) CreatePropertyIndex(label, property string) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	if err := pg.Graph.CreatePropertyIndex(label, property); err != nil {
+		return err
+	}
+
+	if pg.walEnabled {
+		if err := pg.wal.LogCreatePropertyIndex(label, property); err != nil {
+			pg.Graph.DropPropertyIndex(label, property)
+			return fmt.Errorf("failed to log index creation: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DefineSchema declares a label schema and logs it to WAL.
+// See Graph.DefineSchema.
+func (pg *PersistentGraph) DefineSchema(label string, properties map[string]PropertyType, required []string) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	previous, hadPrevious := pg.Graph.GetSchema(label)
+	if err := pg.Graph.DefineSchema(label, properties, required); err != nil {
+		return err
+	}
+
+	if pg.walEnabled {
+		schema, _ := pg.Graph.GetSchema(label)
+		if err := pg.wal.LogDefineSchema(schemaDef(schema)); err != nil {
+			if hadPrevious {
+				pg.Graph.DefineSchema(label, previous.Properties, previous.Required)
+			} else {
+				pg.Graph.DropSchema(label)
+			}
+			return fmt.Errorf("failed to log schema definition: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DropSchema removes a label schema and logs it to WAL
+func (pg *PersistentGraph) DropSchema(label string) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	previous, ok := pg.Graph.GetSchema(label)
+	if err := pg.Graph.DropSchema(label); err != nil {
+		return err
+	}
+
+	if pg.walEnabled && ok {
+		if err := pg.wal.LogDropSchema(label); err != nil {
+			pg.Graph.DefineSchema(label, previous.Properties, previous.Required)
+			return fmt.Errorf("failed to log schema removal: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// restoreCatalog recreates the schema objects recorded in a snapshot and
+// restores its ID reservation
+func (pg *PersistentGraph) restoreCatalog(catalog *wal.Catalog) {
+	if catalog == nil {
+		return
+	}
+	pg.reserveIDs(catalog.NodesUntil, catalog.EdgesUntil)
+	for _, def := range catalog.FullTextIndexes {
+		if !pg.Graph.HasFullTextIndex(def.Label, def.Property) {
+			pg.Graph.CreateFullTextIndex(def.Label, def.Property)
+		}
+	}
+	for _, def := range catalog.SpatialIndexes {
+		if !pg.Graph.HasSpatialIndex(def.Label, def.Property) {
+			pg.Graph.CreateSpatialIndex(def.Label, def.Property)
+		}
+	}
+	for _, def := range catalog.PropertyIndexes {
+		if !pg.Graph.HasPropertyIndex(def.Label, def.Property) {
+			pg.Graph.CreatePropertyIndex(def.Label, def.Property)
+		}
+	}
+	for _, def := range catalog.Schemas {
+		pg.Graph.defineSchemaDef(def)
+	}
+}
+
 // Snapshot creates a snapshot of the current graph state
 func (pg *PersistentGraph) Snapshot() error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+
 	pg.mu.RLock()
 	defer pg.mu.RUnlock()
 
-	// Get current WAL index
-	walIndex := pg.wal.GetCurrentIndex()
+	return pg.snapshot()
+}
+
+// snapshot writes a snapshot and truncates the WAL. Caller holds pg.mu.
+func (pg *PersistentGraph) snapshot() error {
+	walIndex, nodes, edges, catalog, tombstones := pg.captureSnapshot()
 
 	// Create snapshot
-	if err := pg.snapshotManager.CreateSnapshot(walIndex, pg.nodes, pg.edges); err != nil {
+	if err := pg.snapshotManager.CreateSnapshotWithTombstones(walIndex, nodes, edges, catalog, tombstones); err != nil {
 		return fmt.Errorf("failed to create snapshot: %w", err)
 	}
 
+	pg.snapshotIndex.Store(walIndex)
+
 	// Truncate WAL up to snapshot point
 	if err := pg.wal.Truncate(walIndex); err != nil {
 		return fmt.Errorf("failed to truncate WAL: %w", err)
@@ -141,12 +667,69 @@ func (pg *PersistentGraph) Snapshot() error {
 	return nil
 }
 
+// captureSnapshot copies the graph state a snapshot records, along with
+// the index of the last WAL entry that state includes. Writes wait while
+// it runs, and the nodes and edges are copied rather than shared, so they
+// can be encoded while writers carry on.
+func (pg *PersistentGraph) captureSnapshot() (walIndex uint64, nodes map[graph.NodeID]*graph.Node,
+	edges map[graph.EdgeID]*graph.Edge, catalog *wal.Catalog, tombstones *wal.Tombstones) {
+	pg.writeMu.Lock()
+	defer pg.writeMu.Unlock()
+
+	walIndex = pg.wal.GetCurrentIndex()
+
+	pg.nodesMu.RLock()
+	nodes = make(map[graph.NodeID]*graph.Node, len(pg.nodes))
+	for id, node := range pg.nodes {
+		nodes[id] = cloneNode(node)
+	}
+	pg.nodesMu.RUnlock()
+	pg.edgesMu.RLock()
+	edges = make(map[graph.EdgeID]*graph.Edge, len(pg.edges))
+	for id, edge := range pg.edges {
+		edges[id] = cloneEdge(edge)
+	}
+	pg.edgesMu.RUnlock()
+
+	// The WAL entries of reservations are truncated, so the snapshot
+	// keeps the current one
+	catalog = pg.Graph.Catalog()
+	if pg.idBlocks != nil {
+		catalog.NodesUntil, catalog.EdgesUntil = pg.idBlocks.bounds()
+	}
+
+	if tombstones = pg.tombstones(); tombstones != nil {
+		for i, node := range tombstones.Nodes {
+			tombstones.Nodes[i] = cloneNode(node)
+		}
+		for i, edge := range tombstones.Edges {
+			tombstones.Edges[i] = cloneEdge(edge)
+		}
+	}
+	return walIndex, nodes, edges, catalog, tombstones
+}
+
 // Recover restores graph state from snapshot and WAL
 func (pg *PersistentGraph) Recover() error {
 	// Disable WAL during recovery to avoid double-logging
 	pg.walEnabled = false
 	defer func() { pg.walEnabled = true }()
 
+	var out io.Writer = os.Stdout
+	if pg.opts.RecoveryOutput != nil {
+		out = pg.opts.RecoveryOutput
+	}
+	printer := newRecoveryPrinter(out)
+	report := func(progress RecoveryProgress) {
+		printer.report(progress)
+		if pg.opts.RecoveryProgress != nil {
+			pg.opts.RecoveryProgress(progress)
+		}
+	}
+	complete := func() {
+		report(RecoveryProgress{Phase: RecoveryComplete, Nodes: pg.NodeCount(), Edges: pg.EdgeCount()})
+	}
+
 	// Load latest snapshot
 	snapshot, err := pg.snapshotManager.LoadLatestSnapshot()
 	if err != nil {
@@ -155,37 +738,105 @@ func (pg *PersistentGraph) Recover() error {
 
 	if snapshot != nil {
 		// Restore from snapshot
-		fmt.Printf("Recovering from snapshot (index %d)...\n", snapshot.Metadata.Index)
+		fmt.Fprintf(out, "Recovering from snapshot (index %d)...\n", snapshot.Metadata.Index)
+		pg.snapshotIndex.Store(snapshot.Metadata.Index)
 
-		for _, node := range snapshot.Nodes {
-			pg.Graph.nodes[node.ID] = node
-			if uint64(node.ID) >= pg.Graph.nextNodeID.Load() {
-				pg.Graph.nextNodeID.Store(uint64(node.ID) + 1)
-			}
+		// Indexes first so that restored nodes are indexed as they are loaded
+		pg.restoreCatalog(snapshot.Catalog)
+
+		total := uint64(len(snapshot.Nodes) + len(snapshot.Edges))
+		pg.Graph.loadSnapshot(snapshot.Nodes, snapshot.Edges, pg.opts.RecoveryParallelism, func(nodes, edges int) {
+			report(RecoveryProgress{
+				Phase:   RecoverySnapshot,
+				Current: uint64(nodes + edges),
+				Total:   total,
+				Nodes:   nodes,
+				Edges:   edges,
+			})
+		})
+
+		if snapshot.Tombstones != nil {
+			pg.Graph.insertTombstones(snapshot.Tombstones.Nodes, snapshot.Tombstones.Edges, snapshot.Tombstones.Cascaded)
 		}
+	}
 
-		for _, edge := range snapshot.Edges {
-			pg.Graph.edges[edge.ID] = edge
-			if uint64(edge.ID) >= pg.Graph.nextEdgeID.Load() {
-				pg.Graph.nextEdgeID.Store(uint64(edge.ID) + 1)
-			}
+	if pg.opts.RecoverMode == RecoverSnapshotOnly {
+		if snapshot != nil {
+			pg.replayed = snapshot.Metadata.Index
 		}
+		fmt.Fprintf(out, "Recovery complete (snapshot only, WAL skipped): %d nodes, %d edges\n", pg.NodeCount(), pg.EdgeCount())
+		complete()
+		return nil
 	}
 
-	// Replay WAL entries after snapshot
-	fmt.Println("Replaying WAL...")
-	err = pg.wal.Replay(func(entry wal.LogEntry) error {
-		return pg.applyWALEntry(entry)
-	})
+	// Replay WAL entries after snapshot. Truncation keeps the entry at the
+	// snapshot index so indexes stay monotonic across restarts; it is
+	// already part of the snapshot and must not be applied twice.
+	var snapshotIndex uint64
+	if snapshot != nil {
+		snapshotIndex = snapshot.Metadata.Index
+	}
+	if pg.opts.ReadOnly {
+		// A writer may be appending; stop at its last complete entry and
+		// remember where, so Refresh can continue from there
+		fmt.Fprintln(out, "Replaying WAL...")
+		pg.replayed, err = pg.wal.ReplayAfter(snapshotIndex, pg.applyWALEntry)
+	} else {
+		err = pg.wal.ReplayWithProgress(func(entry wal.LogEntry) error {
+			if entry.Index <= snapshotIndex {
+				return nil
+			}
+			return pg.applyWALEntry(entry)
+		}, func(current, total uint64) {
+			report(RecoveryProgress{
+				Phase:   RecoveryWAL,
+				Current: current,
+				Total:   total,
+				Nodes:   pg.NodeCount(),
+				Edges:   pg.EdgeCount(),
+			})
+		})
+	}
 
 	if err != nil {
 		return fmt.Errorf("failed to replay WAL: %w", err)
 	}
 
-	fmt.Printf("Recovery complete: %d nodes, %d edges\n", pg.NodeCount(), pg.EdgeCount())
+	fmt.Fprintf(out, "Recovery complete: %d nodes, %d edges\n", pg.NodeCount(), pg.EdgeCount())
+	complete()
 	return nil
 }
 
+// Refresh applies the WAL entries another process has appended since this
+// read-only graph was opened or last refreshed, and returns how many were
+// applied. If the writer has snapshotted and truncated entries this graph
+// has not seen, it returns wal.ErrTruncated and the graph must be
+// reopened. A writable graph is always current, so Refresh does nothing.
+func (pg *PersistentGraph) Refresh() (int, error) {
+	if !pg.opts.ReadOnly {
+		return 0, nil
+	}
+	pg.refreshMu.Lock()
+	defer pg.refreshMu.Unlock()
+
+	applied := 0
+	last, err := pg.wal.ReplayAfter(pg.replayed, func(entry wal.LogEntry) error {
+		if err := pg.applyWALEntry(entry); err != nil {
+			return err
+		}
+		applied++
+		return nil
+	})
+	pg.replayed = last
+	if applied > 0 {
+		pg.version.Add(1)
+	}
+	if err != nil {
+		return applied, fmt.Errorf("failed to refresh: %w", err)
+	}
+	return applied, nil
+}
+
 // applyWALEntry applies a single WAL entry to the graph
 func (pg *PersistentGraph) applyWALEntry(entry wal.LogEntry) error {
 	switch entry.OpType {
@@ -198,11 +849,12 @@ func (pg *PersistentGraph) applyWALEntry(entry wal.LogEntry) error {
 		for k, v := range props {
 			node.SetProperty(k, v)
 		}
+		// Keep the original write time rather than the replay time
+		node.CreatedAt = loggedTime(entry, "created_at")
+		node.UpdatedAt = node.CreatedAt
 
-		pg.Graph.nodes[nodeID] = node
-		if uint64(nodeID) >= pg.Graph.nextNodeID.Load() {
-			pg.Graph.nextNodeID.Store(uint64(nodeID) + 1)
-		}
+		pg.Graph.insertNode(node)
+		advanceID(&pg.Graph.nextNodeID, uint64(nodeID))
 
 	case wal.OpAddEdge:
 		edgeID := graph.EdgeID(uint64(entry.Data["edge_id"].(float64)))
@@ -215,32 +867,171 @@ func (pg *PersistentGraph) applyWALEntry(entry wal.LogEntry) error {
 		for k, v := range props {
 			edge.SetProperty(k, v)
 		}
+		edge.CreatedAt = loggedTime(entry, "created_at")
+		edge.UpdatedAt = edge.CreatedAt
 
-		pg.Graph.edges[edgeID] = edge
-		if uint64(edgeID) >= pg.Graph.nextEdgeID.Load() {
-			pg.Graph.nextEdgeID.Store(uint64(edgeID) + 1)
-		}
+		// Refresh replays into a graph that is serving reads, so take the
+		// same locks a live write would
+		pg.Graph.edgesMu.Lock()
+		pg.Graph.putEdge(edge)
+		pg.Graph.edgesMu.Unlock()
+		advanceID(&pg.Graph.nextEdgeID, uint64(edgeID))
 
 		// Update adjacency lists
-		if srcNode, ok := pg.Graph.nodes[source]; ok {
-			srcNode.AddOutEdge(edgeID)
+		if srcNode, err := pg.Graph.GetNode(source); err == nil {
+			linkEdgeAt(srcNode, edgeID, true, edge.CreatedAt)
 		}
-		if tgtNode, ok := pg.Graph.nodes[target]; ok {
-			tgtNode.AddInEdge(edgeID)
+		if tgtNode, err := pg.Graph.GetNode(target); err == nil {
+			linkEdgeAt(tgtNode, edgeID, false, edge.CreatedAt)
 		}
 
 	case wal.OpDeleteNode:
 		nodeID := graph.NodeID(uint64(entry.Data["node_id"].(float64)))
-		pg.Graph.DeleteNode(nodeID)
+		pg.Graph.removeNode(nodeID)
+
+	case wal.OpSetNodeProp:
+		nodeID := graph.NodeID(uint64(entry.Data["node_id"].(float64)))
+		props := convertProperties(entry.Data["properties"])
+		at := loggedTime(entry, "updated_at")
+		pg.Graph.updateNodeAt(nodeID, props, at)
 
 	case wal.OpDeleteEdge:
 		edgeID := graph.EdgeID(uint64(entry.Data["edge_id"].(float64)))
-		pg.Graph.DeleteEdge(edgeID)
+		pg.Graph.removeEdge(edgeID)
+
+	case wal.OpSetEdgeProp:
+		edgeID := graph.EdgeID(uint64(entry.Data["edge_id"].(float64)))
+		props := convertProperties(entry.Data["properties"])
+		at := loggedTime(entry, "updated_at")
+		pg.Graph.updateEdgeAt(edgeID, props, at)
+
+	case wal.OpReconnectEdge:
+		edgeID := graph.EdgeID(uint64(entry.Data["edge_id"].(float64)))
+		source := graph.NodeID(uint64(entry.Data["source"].(float64)))
+		target := graph.NodeID(uint64(entry.Data["target"].(float64)))
+		at := loggedTime(entry, "updated_at")
+		if edge, err := pg.Graph.GetEdge(edgeID); err == nil {
+			pg.reconnectMu.Lock()
+			pg.Graph.reconnectEdge(edge, source, target, at)
+			pg.reconnectMu.Unlock()
+		}
+
+	case wal.OpCreateFTIndex:
+		label := entry.Data["label"].(string)
+		property := entry.Data["property"].(string)
+		// The index may already be restored from the snapshot catalog
+		if !pg.Graph.HasFullTextIndex(label, property) {
+			pg.Graph.CreateFullTextIndex(label, property)
+		}
+
+	case wal.OpCreateSpatialIndex:
+		label := entry.Data["label"].(string)
+		property := entry.Data["property"].(string)
+		if !pg.Graph.HasSpatialIndex(label, property) {
+			pg.Graph.CreateSpatialIndex(label, property)
+		}
+
+	case wal.OpCreatePropIndex:
+		label := entry.Data["label"].(string)
+		property := entry.Data["property"].(string)
+		if !pg.Graph.HasPropertyIndex(label, property) {
+			pg.Graph.CreatePropertyIndex(label, property)
+		}
+
+	case wal.OpDefineSchema:
+		def := wal.SchemaDef{Label: entry.Data["label"].(string)}
+		if props, ok := entry.Data["properties"].(map[string]interface{}); ok {
+			def.Properties = make(map[string]string, len(props))
+			for property, typ := range props {
+				def.Properties[property], _ = typ.(string)
+			}
+		}
+		if required, ok := entry.Data["required"].([]interface{}); ok {
+			for _, property := range required {
+				if name, ok := property.(string); ok {
+					def.Required = append(def.Required, name)
+				}
+			}
+		}
+		pg.Graph.defineSchemaDef(def)
+
+	case wal.OpDropSchema:
+		pg.Graph.DropSchema(entry.Data["label"].(string))
+
+	case wal.OpSetNodeExpiry:
+		nodeID := graph.NodeID(uint64(entry.Data["node_id"].(float64)))
+		pg.Graph.SetExpiry(nodeID, parseExpiry(entry.Data["expires_at"]))
+
+	case wal.OpSetEdgeExpiry:
+		edgeID := graph.EdgeID(uint64(entry.Data["edge_id"].(float64)))
+		pg.Graph.SetEdgeExpiry(edgeID, parseExpiry(entry.Data["expires_at"]))
+
+	case wal.OpTombstoneNode:
+		nodeID := graph.NodeID(uint64(entry.Data["node_id"].(float64)))
+		pg.Graph.tombstoneNode(nodeID, parseDeletedAt(entry))
+
+	case wal.OpTombstoneEdge:
+		edgeID := graph.EdgeID(uint64(entry.Data["edge_id"].(float64)))
+		pg.Graph.tombstoneEdge(edgeID, parseDeletedAt(entry))
+
+	case wal.OpRestoreNode:
+		nodeID := graph.NodeID(uint64(entry.Data["node_id"].(float64)))
+		pg.Graph.RestoreNode(nodeID)
+
+	case wal.OpPurgeTombstones:
+		var nodeIDs []graph.NodeID
+		if ids, ok := entry.Data["node_ids"].([]interface{}); ok {
+			for _, id := range ids {
+				nodeIDs = append(nodeIDs, graph.NodeID(uint64(id.(float64))))
+			}
+		}
+		var edgeIDs []graph.EdgeID
+		if ids, ok := entry.Data["edge_ids"].([]interface{}); ok {
+			for _, id := range ids {
+				edgeIDs = append(edgeIDs, graph.EdgeID(uint64(id.(float64))))
+			}
+		}
+		pg.Graph.purge(nodeIDs, edgeIDs)
+
+	case wal.OpReserveIDs:
+		nodesUntil, _ := entry.Data["nodes_until"].(float64)
+		edgesUntil, _ := entry.Data["edges_until"].(float64)
+		pg.reserveIDs(uint64(nodesUntil), uint64(edgesUntil))
+
+	case wal.OpLinkEdge, wal.OpUnlinkEdge:
+		nodeID := graph.NodeID(uint64(entry.Data["node_id"].(float64)))
+		edgeID := graph.EdgeID(uint64(entry.Data["edge_id"].(float64)))
+		outgoing := entry.Data["direction"] == "out"
+		if node, err := pg.Graph.GetNode(nodeID); err == nil {
+			pg.Graph.setAdjacency(node, edgeID, outgoing, entry.OpType == wal.OpLinkEdge)
+		}
 	}
 
 	return nil
 }
 
+// loggedTime returns the time an entry records under key. Entries
+// written before the time was logged fall back to the entry's timestamp.
+func loggedTime(entry wal.LogEntry, key string) time.Time {
+	if text, ok := entry.Data[key].(string); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			return parsed
+		}
+	}
+	return entry.Timestamp
+}
+
+// reserveIDs moves the ID allocators past a logged reservation, so that
+// no ID it covers is handed out again. A zero bound reserves nothing.
+func (pg *PersistentGraph) reserveIDs(nodesUntil, edgesUntil uint64) {
+	if nodesUntil > 0 {
+		advanceID(&pg.Graph.nextNodeID, nodesUntil-1)
+	}
+	if edgesUntil > 0 {
+		advanceID(&pg.Graph.nextEdgeID, edgesUntil-1)
+	}
+}
+
 // convertProperties converts map[string]interface{} from JSON to graph.Properties
 func convertProperties(data interface{}) graph.Properties {
 	if data == nil {
@@ -250,14 +1041,48 @@ func convertProperties(data interface{}) graph.Properties {
 	props := graph.Properties{}
 	if m, ok := data.(map[string]interface{}); ok {
 		for k, v := range m {
-			props[k] = v
+			props[k] = graph.DecodeJSONValue(v)
 		}
 	}
 	return props
 }
 
+// Subscribe returns a change stream of every WAL entry committed from now
+// on, in order. Slow consumers lose entries and receive a wal.OpGap entry
+// in their place; use SubscribeWithOptions to block writers instead.
+func (pg *PersistentGraph) Subscribe() <-chan wal.LogEntry {
+	return pg.wal.Subscribe(wal.DefaultSubscribeOptions())
+}
+
+// SubscribeWithOptions returns a change stream configured by opts
+func (pg *PersistentGraph) SubscribeWithOptions(opts wal.SubscribeOptions) <-chan wal.LogEntry {
+	return pg.wal.Subscribe(opts)
+}
+
+// Unsubscribe ends a change stream and closes its channel
+func (pg *PersistentGraph) Unsubscribe(ch <-chan wal.LogEntry) {
+	pg.wal.Unsubscribe(ch)
+}
+
+// WALSize returns the number of entries in the WAL and its length in
+// bytes, which after a snapshot count only what was logged since
+func (pg *PersistentGraph) WALSize() (entries int, bytes int64) {
+	return pg.wal.Size()
+}
+
+// StartIntegrityScanner checks the WAL's entry checksums every interval
+// until the returned function is called. See wal.WAL.StartIntegrityScanner.
+func (pg *PersistentGraph) StartIntegrityScanner(interval time.Duration, onCorrupt func(index uint64, err error)) context.CancelFunc {
+	return pg.wal.StartIntegrityScanner(interval, onCorrupt)
+}
+
 // Close closes WAL and snapshot manager
 func (pg *PersistentGraph) Close() error {
+	// Let in-flight stats materialization and sweeps finish before
+	// closing the WAL
+	pg.stopSweeper()
+	pg.stopStats()
+
 	if pg.wal != nil {
 		return pg.wal.Close()
 	}
@@ -2,9 +2,13 @@ package storage
 
 import (
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -57,6 +61,96 @@ func TestPersistentAddEdge(t *testing.T) {
 	assert.Equal(t, node2.ID, edge.Target)
 }
 
+func TestPersistentUpdateEdgeEndpoints(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	alice, _ := pg.AddNode("Person", nil)
+	bob, _ := pg.AddNode("Person", nil)
+	carol, _ := pg.AddNode("Person", nil)
+	edge, _ := pg.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+
+	require.NoError(t, pg.UpdateEdgeTarget(edge.ID, carol.ID))
+	require.NoError(t, pg.UpdateEdgeSource(edge.ID, bob.ID))
+	assert.Error(t, pg.UpdateEdgeTarget(edge.ID, 999))
+	require.NoError(t, pg.Close())
+
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+
+	got, err := pg2.GetEdge(edge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, bob.ID, got.Source)
+	assert.Equal(t, carol.ID, got.Target)
+	since, _ := got.GetProperty("since")
+	assert.EqualValues(t, 2020, since)
+
+	incoming, err := pg2.GetIncomingNeighbors(carol.ID)
+	require.NoError(t, err)
+	require.Len(t, incoming, 1)
+	assert.Equal(t, bob.ID, incoming[0].ID)
+	neighbors, err := pg2.GetNeighbors(alice.ID)
+	require.NoError(t, err)
+	assert.Empty(t, neighbors)
+}
+
+func TestPersistentUpdateEdgeEndpoints_Concurrent(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	nodes := make([]*graph.Node, 4)
+	for i := range nodes {
+		nodes[i], _ = pg.AddNode("Person", nil)
+	}
+	edge, _ := pg.AddEdge(nodes[0].ID, nodes[1].ID, "KNOWS", nil)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				next := nodes[(w+i)%len(nodes)].ID
+				if i%2 == 0 {
+					assert.NoError(t, pg.UpdateEdgeTarget(edge.ID, next))
+				} else {
+					assert.NoError(t, pg.UpdateEdgeSource(edge.ID, next))
+				}
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			for _, node := range nodes {
+				_, err := pg.GetAdjacent(node.ID, graph.Both)
+				assert.NoError(t, err)
+			}
+		}
+	}()
+	wg.Wait()
+
+	// Replay applies the reconnections in the order they were made
+	source, target := edge.Endpoints()
+	require.NoError(t, pg.Close())
+
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+	got, err := pg2.GetEdge(edge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, source, got.Source)
+	assert.Equal(t, target, got.Target)
+}
+
 func TestPersistence_Restart(t *testing.T) {
 	walDir := t.TempDir()
 	snapDir := t.TempDir()
@@ -117,6 +211,453 @@ func TestSnapshot_Recovery(t *testing.T) {
 	assert.Equal(t, 10, pg2.NodeCount())
 }
 
+func TestRecoveryEdgeTypeCounts(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	a, _ := pg.AddNode("Person", nil)
+	b, _ := pg.AddNode("Person", nil)
+	_, err = pg.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	require.NoError(t, err)
+	require.NoError(t, pg.Snapshot())
+
+	// Edges from the snapshot and from the log are both counted
+	_, err = pg.AddEdge(b.ID, a.ID, "KNOWS", nil)
+	require.NoError(t, err)
+	_, err = pg.AddEdge(b.ID, b.ID, "LIKES", nil)
+	require.NoError(t, err)
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+	assert.Equal(t, map[string]int{"KNOWS": 2, "LIKES": 1}, pg.EdgeTypeCounts())
+}
+
+func TestRecoveryPreservesTypesAndTimestamps(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+	when := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	a, err := pg1.AddNode("Event", graph.Properties{"at": when, "count": 3})
+	require.NoError(t, err)
+	require.NoError(t, pg1.Snapshot())
+
+	// Written after the snapshot, so only in the WAL
+	b, err := pg1.AddNode("Event", graph.Properties{"at": when, "count": 4})
+	require.NoError(t, err)
+	require.NoError(t, pg1.Close())
+
+	reopened := time.Now()
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+
+	for _, id := range []graph.NodeID{a.ID, b.ID} {
+		node, err := pg2.GetNode(id)
+		require.NoError(t, err)
+
+		at, _ := node.GetProperty("at")
+		assert.Equal(t, when, at)
+		count, _ := node.GetProperty("count")
+		assert.IsType(t, 0, count)
+	}
+
+	// Creation times come from the log, not from the restart
+	recovered, _ := pg2.GetNode(b.ID)
+	assert.WithinDuration(t, b.CreatedAt, recovered.CreatedAt, time.Second)
+	assert.True(t, recovered.CreatedAt.Before(reopened))
+}
+
+func TestRecoveryPreservesUpdatedAt(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	n, err := pg1.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+	createdAt := n.CreatedAt
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, pg1.UpdateNode(n.ID, graph.Properties{"age": 31}))
+	updatedAt := n.UpdatedAt
+	require.True(t, updatedAt.After(createdAt))
+	require.NoError(t, pg1.Close())
+
+	time.Sleep(5 * time.Millisecond)
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+
+	// Replay restores the logged modification time exactly
+	recovered, err := pg2.GetNode(n.ID)
+	require.NoError(t, err)
+	age, _ := recovered.GetProperty("age")
+	assert.Equal(t, 31, age)
+	assert.True(t, recovered.UpdatedAt.Equal(updatedAt), "UpdatedAt %v, want %v", recovered.UpdatedAt, updatedAt)
+	assert.True(t, recovered.CreatedAt.Before(updatedAt))
+}
+
+func TestRecoveryPreservesCreatedAt(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	a, err := pg1.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+	b, err := pg1.AddNode("Person", graph.Properties{"name": "Bob"})
+	require.NoError(t, err)
+	e, err := pg1.AddEdge(a.ID, b.ID, "KNOWS", graph.Properties{"since": 2020})
+	require.NoError(t, err)
+
+	// A bulk load commits in one batch, yet each node keeps its own time
+	var loaded []*graph.Node
+	require.NoError(t, pg1.BulkLoad(func(loader *BulkLoader) error {
+		for i := 0; i < 2; i++ {
+			time.Sleep(time.Millisecond)
+			node, err := loader.AddNode("Person", graph.Properties{"n": i})
+			if err != nil {
+				return err
+			}
+			loaded = append(loaded, node)
+		}
+		return nil
+	}))
+	require.False(t, loaded[0].CreatedAt.Equal(loaded[1].CreatedAt))
+	require.NoError(t, pg1.Close())
+
+	time.Sleep(5 * time.Millisecond)
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+
+	for _, want := range append([]*graph.Node{a, b}, loaded...) {
+		node, err := pg2.GetNode(want.ID)
+		require.NoError(t, err)
+		assert.True(t, node.CreatedAt.Equal(want.CreatedAt), "node %d CreatedAt %v, want %v", want.ID, node.CreatedAt, want.CreatedAt)
+		assert.True(t, node.UpdatedAt.Equal(want.UpdatedAt), "node %d UpdatedAt %v, want %v", want.ID, node.UpdatedAt, want.UpdatedAt)
+	}
+	edge, err := pg2.GetEdge(e.ID)
+	require.NoError(t, err)
+	assert.True(t, edge.CreatedAt.Equal(e.CreatedAt))
+	assert.True(t, edge.UpdatedAt.Equal(e.UpdatedAt))
+}
+
+func TestCompositeProperties(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+	props := graph.Properties{
+		"skills":  []string{"go", "sql"},
+		"address": map[string]interface{}{"city": "SF", "zip": 94103},
+	}
+	want := graph.Properties{
+		"skills":  []graph.PropertyValue{"go", "sql"},
+		"address": graph.Properties{"city": "SF", "zip": 94103},
+	}
+
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	a, err := pg1.AddNode("Person", props)
+	require.NoError(t, err)
+	assert.Equal(t, want, a.Properties.Map())
+	require.NoError(t, pg1.Snapshot())
+	b, err := pg1.AddNode("Person", props)
+	require.NoError(t, err)
+
+	// Rejected before anything is logged
+	var deep graph.PropertyValue = "leaf"
+	for i := 0; i <= graph.MaxValueDepth; i++ {
+		deep = []graph.PropertyValue{deep}
+	}
+	_, err = pg1.AddNode("Person", graph.Properties{"deep": deep})
+	assert.ErrorContains(t, err, "property deep")
+	assert.Error(t, pg1.UpdateNode(a.ID, graph.Properties{"bad": struct{}{}}))
+	assert.Equal(t, 2, pg1.NodeCount())
+	require.NoError(t, pg1.Close())
+
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+	assert.Equal(t, 2, pg2.NodeCount())
+
+	for _, id := range []graph.NodeID{a.ID, b.ID} {
+		node, err := pg2.GetNode(id)
+		require.NoError(t, err)
+		assert.Equal(t, want, node.Properties.Map())
+	}
+}
+
+func TestHistogramProperties(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	stats, err := pg1.AddNode("Stats", graph.Properties{"endpoint": "/api"})
+	require.NoError(t, err)
+	for _, latency := range []float64{12, 48, 7} {
+		require.NoError(t, stats.AddToHistogram("latency_ms", latency))
+	}
+	h, _ := stats.GetProperty("latency_ms")
+	require.NoError(t, pg1.UpdateNode(stats.ID, graph.Properties{"latency_ms": h}))
+	require.NoError(t, pg1.Snapshot())
+
+	require.NoError(t, stats.AddToHistogram("latency_ms", 95))
+	h, _ = stats.GetProperty("latency_ms")
+	require.NoError(t, pg1.UpdateNode(stats.ID, graph.Properties{"latency_ms": h}))
+	require.NoError(t, pg1.Close())
+
+	// The WAL holds the histogram as a structured object
+	data, err := os.ReadFile(filepath.Join(walDir, "wal.log"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"latency_ms":{"type":"histogram","buckets":[0,10,20,30,40,50,60,70,80,90,100],"counts":[1,1,0,0,1,0,0,0,0,1]}`)
+
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+	recovered, err := pg2.GetNode(stats.ID)
+	require.NoError(t, err)
+	got, _ := recovered.GetProperty("latency_ms")
+	assert.Equal(t, h, got)
+}
+
+func TestBinaryWAL(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+	opts := DefaultOptions()
+	opts.WALFormat = wal.FormatBinary
+
+	pg1, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	alice, err := pg1.AddNode("Person", graph.Properties{"name": "Alice", "age": 30, "home": graph.Point{Lat: 52.5, Lon: 13.4}})
+	require.NoError(t, err)
+	bob, err := pg1.AddNode("Person", graph.Properties{"name": "Bob"})
+	require.NoError(t, err)
+	_, err = pg1.AddEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+	require.NoError(t, err)
+	require.NoError(t, pg1.UpdateNode(bob.ID, graph.Properties{"age": 25}))
+	require.NoError(t, pg1.CreatePropertyIndex("Person", "name"))
+	require.NoError(t, pg1.Close())
+
+	// Recovery reads the binary log, whatever the options
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+	assert.Equal(t, 2, pg2.NodeCount())
+	assert.Equal(t, 1, pg2.EdgeCount())
+	recovered, err := pg2.GetNode(alice.ID)
+	require.NoError(t, err)
+	home, _ := recovered.GetProperty("home")
+	assert.Equal(t, graph.Point{Lat: 52.5, Lon: 13.4}, home)
+	age, _ := recovered.GetProperty("age")
+	assert.Equal(t, 30, age)
+	recovered, err = pg2.GetNode(bob.ID)
+	require.NoError(t, err)
+	age, _ = recovered.GetProperty("age")
+	assert.Equal(t, 25, age)
+	assert.True(t, pg2.HasPropertyIndex("Person", "name"))
+}
+
+func TestSnapshotOnlyRecovery(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	a, _ := pg1.AddNode("Person", graph.Properties{"name": "Alice"})
+	b, _ := pg1.AddNode("Person", graph.Properties{"name": "Bob"})
+	pg1.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	require.NoError(t, pg1.Snapshot())
+
+	// Written after the snapshot, so only in the WAL
+	c, _ := pg1.AddNode("Person", graph.Properties{"name": "Carol"})
+	pg1.AddEdge(b.ID, c.ID, "KNOWS", nil)
+	require.NoError(t, pg1.Close())
+
+	opts := DefaultOptions()
+	opts.RecoverMode = RecoverSnapshotOnly
+	pg2, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, pg2.NodeCount())
+	assert.Equal(t, 1, pg2.EdgeCount())
+	_, err = pg2.GetNode(c.ID)
+	assert.Error(t, err)
+
+	// Writes could reuse Carol's ID, so the graph is read-only, and
+	// Refresh applies what was skipped
+	assert.True(t, pg2.ReadOnly())
+	_, err = pg2.AddNode("Person", graph.Properties{"name": "Dave"})
+	assert.ErrorIs(t, err, ErrReadOnly)
+	applied, err := pg2.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, 3, pg2.NodeCount())
+	assert.Equal(t, 2, pg2.EdgeCount())
+	require.NoError(t, pg2.Close())
+
+	// Full recovery still sees everything
+	pg3, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg3.Close()
+	assert.Equal(t, 3, pg3.NodeCount())
+	assert.Equal(t, 2, pg3.EdgeCount())
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	writer, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer writer.Close()
+
+	a, _ := writer.AddNode("Person", graph.Properties{"name": "Alice"})
+	b, _ := writer.AddNode("Person", graph.Properties{"name": "Bob"})
+	e, _ := writer.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	require.NoError(t, writer.Snapshot())
+	writer.AddNode("Person", graph.Properties{"name": "Carol"})
+
+	walBefore, err := os.ReadFile(walDir + "/wal.log")
+	require.NoError(t, err)
+
+	// Two readers open the directory while the writer still holds it
+	opts := DefaultOptions()
+	opts.ReadOnly = true
+	for i := 0; i < 2; i++ {
+		ro, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+		require.NoError(t, err)
+		defer ro.Close()
+
+		assert.True(t, ro.ReadOnly())
+		assert.Equal(t, 3, ro.NodeCount())
+		assert.Equal(t, 1, ro.EdgeCount())
+		neighbors, err := ro.GetNeighbors(a.ID)
+		require.NoError(t, err)
+		assert.Len(t, neighbors, 1)
+
+		_, err = ro.AddNode("Person", nil)
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = ro.AddEdge(a.ID, b.ID, "KNOWS", nil)
+		assert.ErrorIs(t, err, ErrReadOnly)
+		assert.ErrorIs(t, ro.DeleteNode(a.ID), ErrReadOnly)
+		assert.ErrorIs(t, ro.DeleteEdge(e.ID), ErrReadOnly)
+		assert.ErrorIs(t, ro.UpdateNode(a.ID, graph.Properties{"age": 1}), ErrReadOnly)
+		assert.ErrorIs(t, ro.Snapshot(), ErrReadOnly)
+		_, err = ro.Backup(t.TempDir() + "/backup.tar.gz")
+		assert.ErrorIs(t, err, ErrReadOnly)
+
+		// Rejected writes leave memory untouched
+		assert.Equal(t, 3, ro.NodeCount())
+		_, err = ro.GetNode(a.ID)
+		assert.NoError(t, err)
+	}
+
+	walAfter, err := os.ReadFile(walDir + "/wal.log")
+	require.NoError(t, err)
+	assert.Equal(t, walBefore, walAfter)
+
+	// The writer is unaffected by the readers
+	_, err = writer.AddNode("Person", graph.Properties{"name": "Dave"})
+	assert.NoError(t, err)
+}
+
+func TestReadOnlyRefresh(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	writer, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer writer.Close()
+	a, _ := writer.AddNode("Person", graph.Properties{"name": "Alice"})
+
+	opts := DefaultOptions()
+	opts.ReadOnly = true
+	ro, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer ro.Close()
+	assert.Equal(t, 1, ro.NodeCount())
+
+	// Nothing new yet
+	n, err := ro.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	b, _ := writer.AddNode("Person", graph.Properties{"name": "Bob"})
+	writer.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	require.NoError(t, writer.UpdateNode(a.ID, graph.Properties{"age": 30}))
+
+	n, err = ro.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, 2, ro.NodeCount())
+	assert.Equal(t, 1, ro.EdgeCount())
+	node, err := ro.GetNode(a.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 30, node.Properties.Map()["age"])
+
+	// A reader that falls behind a snapshot must reopen
+	writer.AddNode("Person", graph.Properties{"name": "Carol"})
+	writer.AddNode("Person", graph.Properties{"name": "Eve"})
+	require.NoError(t, writer.Snapshot())
+	writer.AddNode("Person", graph.Properties{"name": "Dave"})
+	_, err = ro.Refresh()
+	assert.ErrorIs(t, err, wal.ErrTruncated)
+
+	reopened, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, 5, reopened.NodeCount())
+
+	// Writable graphs are always current
+	n, err = writer.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestSubscribe(t *testing.T) {
+	pg, err := NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+
+	ch := pg.Subscribe()
+
+	a, _ := pg.AddNode("Person", graph.Properties{"name": "Alice"})
+	b, _ := pg.AddNode("Person", graph.Properties{"name": "Bob"})
+	e, _ := pg.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	require.NoError(t, pg.UpdateNode(a.ID, graph.Properties{"age": 30}))
+	require.NoError(t, pg.DeleteEdge(e.ID))
+	require.NoError(t, pg.DeleteNode(b.ID))
+
+	// Rejected mutations produce no entries
+	_, err = pg.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	require.Error(t, err)
+
+	expected := []wal.OpType{
+		wal.OpAddNode, wal.OpAddNode, wal.OpAddEdge,
+		wal.OpSetNodeProp, wal.OpDeleteEdge, wal.OpDeleteNode,
+	}
+	entries := make([]wal.LogEntry, len(expected))
+	for i, op := range expected {
+		entries[i] = <-ch
+		assert.Equal(t, op, entries[i].OpType, "entry %d", i)
+		if i > 0 {
+			assert.Equal(t, entries[i-1].Index+1, entries[i].Index)
+		}
+	}
+	assert.Equal(t, "Alice", convertProperties(entries[0].Data["properties"])["name"])
+
+	pg.Unsubscribe(ch)
+	_, open := <-ch
+	assert.False(t, open)
+}
+
 func TestDeleteOperations_Persistence(t *testing.T) {
 	walDir := t.TempDir()
 	snapDir := t.TempDir()
@@ -143,6 +684,71 @@ func TestDeleteOperations_Persistence(t *testing.T) {
 	assert.Equal(t, 0, pg2.EdgeCount())
 }
 
+// loadHub bulk-loads a node with degree edges to other nodes
+func loadHub(t testing.TB, pg *PersistentGraph, degree int) graph.NodeID {
+	var hub graph.NodeID
+	err := pg.BulkLoad(func(loader *BulkLoader) error {
+		node, err := loader.AddNode("Hub", nil)
+		if err != nil {
+			return err
+		}
+		hub = node.ID
+		for i := 0; i < degree; i++ {
+			leaf, err := loader.AddNode("Leaf", nil)
+			if err != nil {
+				return err
+			}
+			if _, err := loader.AddEdge(hub, leaf.ID, "LINKS", nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return hub
+}
+
+func TestDeleteNode_CascadeIsOneEntry(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	hub := loadHub(t, pg1, 100)
+
+	// The edges go with the node, under the node's single entry, which
+	// replay cascades the same way
+	before := pg1.WALIndex()
+	require.NoError(t, pg1.DeleteNode(hub))
+	assert.Equal(t, before+1, pg1.WALIndex())
+	assert.Equal(t, 0, pg1.EdgeCount())
+	pg1.Close()
+
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+	assert.Equal(t, 100, pg2.NodeCount())
+	assert.Equal(t, 0, pg2.EdgeCount())
+}
+
+// BenchmarkDeleteHighDegreeNode deletes a node with 10k edges, which
+// costs one WAL append and fsync however many edges go with it
+func BenchmarkDeleteHighDegreeNode(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		pg, err := NewPersistentGraph(b.TempDir(), b.TempDir())
+		require.NoError(b, err)
+		hub := loadHub(b, pg, 10000)
+		b.StartTimer()
+
+		require.NoError(b, pg.DeleteNode(hub))
+
+		b.StopTimer()
+		pg.Close()
+		b.StartTimer()
+	}
+}
+
 func TestRecovery_EmptyState(t *testing.T) {
 	walDir := t.TempDir()
 	snapDir := t.TempDir()
@@ -213,3 +819,163 @@ func TestConcurrentOperations_WithPersistence(t *testing.T) {
 
 	assert.Equal(t, 10, pg.NodeCount())
 }
+
+func TestSnapshot_ConcurrentWriters(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	const writers, perWriter = 4, 50
+	stop := make(chan struct{})
+	var snapshots sync.WaitGroup
+	snapshots.Add(1)
+	go func() {
+		defer snapshots.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				assert.NoError(t, pg.Snapshot())
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			var prev *graph.Node
+			for i := 0; i < perWriter; i++ {
+				node, err := pg.AddNode("Person", graph.Properties{"writer": w})
+				if !assert.NoError(t, err) {
+					return
+				}
+				assert.NoError(t, pg.UpdateNode(node.ID, graph.Properties{"updated": true, "tags": []string{"a", "b"}}))
+				if prev != nil {
+					_, err := pg.AddEdge(prev.ID, node.ID, "NEXT", graph.Properties{"step": i})
+					assert.NoError(t, err)
+				}
+				prev = node
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(stop)
+	snapshots.Wait()
+
+	require.NoError(t, pg.Snapshot())
+	require.NoError(t, pg.Close())
+
+	// Every write is in the snapshot or the WAL after it, exactly once
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+	assert.Equal(t, writers*perWriter, pg2.NodeCount())
+	assert.Equal(t, writers*(perWriter-1), pg2.EdgeCount())
+	linked := 0
+	pg2.IterateNodes(func(node *graph.Node) bool {
+		updated, _ := node.GetProperty("updated")
+		assert.Equal(t, true, updated)
+		linked += len(node.OutEdges)
+		return true
+	})
+	assert.Equal(t, writers*(perWriter-1), linked)
+	assert.Empty(t, pg2.Validate())
+}
+
+func TestPersistentUpdateNode_Recovery(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	node, _ := pg1.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, pg1.UpdateNode(node.ID, graph.Properties{"city": "SF"}))
+	assert.Error(t, pg1.UpdateNode(graph.NodeID(999), graph.Properties{"x": 1}))
+	pg1.Close()
+
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+
+	recovered, err := pg2.GetNode(node.ID)
+	require.NoError(t, err)
+	name, _ := recovered.GetProperty("name")
+	city, _ := recovered.GetProperty("city")
+	assert.Equal(t, "Alice", name)
+	assert.Equal(t, "SF", city)
+}
+
+func TestLegacyMixedLists(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+	mixed := graph.Properties{"tags": []interface{}{"a", 1}}
+	want := []graph.PropertyValue{"a", 1}
+
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	// New writes are checked
+	_, err = pg1.AddNode("Person", mixed)
+	assert.ErrorContains(t, err, "list mixes string and int elements")
+
+	// Imports of older data are not
+	a, err := pg1.AddNodeWithID(10, "Person", mixed)
+	require.NoError(t, err)
+	b, err := pg1.AddNodeWithID(11, "Person", nil)
+	require.NoError(t, err)
+	e, err := pg1.AddEdgeWithID(5, a.ID, b.ID, "KNOWS", mixed)
+	require.NoError(t, err)
+	assert.Error(t, pg1.UpdateNode(b.ID, mixed))
+	assert.Error(t, pg1.UpdateEdge(e.ID, mixed))
+
+	// An update logged before the check existed still replays
+	require.NoError(t, pg1.wal.LogSetNodeProperties(b.ID, mixed, time.Now()))
+	require.NoError(t, pg1.wal.LogSetEdgeProperties(e.ID, graph.Properties{"more": []interface{}{true, "x"}}, time.Now()))
+	require.NoError(t, pg1.Close())
+
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg2.Close()
+
+	for _, id := range []graph.NodeID{a.ID, b.ID} {
+		node, err := pg2.GetNode(id)
+		require.NoError(t, err)
+		tags, _ := node.GetProperty("tags")
+		assert.Equal(t, want, tags)
+	}
+	edge, err := pg2.GetEdge(e.ID)
+	require.NoError(t, err)
+	tags, _ := edge.GetProperty("tags")
+	assert.Equal(t, want, tags)
+	more, _ := edge.GetProperty("more")
+	assert.Equal(t, []graph.PropertyValue{true, "x"}, more)
+}
+
+func TestDelete_FailedLogLeavesMemoryUntouched(t *testing.T) {
+	pg, err := NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	a, _ := pg.AddNode("Person", nil)
+	b, _ := pg.AddNode("Person", nil)
+	e, _ := pg.AddEdge(a.ID, b.ID, "KNOWS", nil)
+
+	// Every append fails once the log is closed
+	require.NoError(t, pg.wal.Close())
+
+	assert.Error(t, pg.DeleteNode(a.ID))
+	_, err = pg.GetNode(a.ID)
+	assert.NoError(t, err, "node deletion was not logged")
+	_, err = pg.GetEdge(e.ID)
+	assert.NoError(t, err, "the node's edges stay as well")
+
+	assert.Error(t, pg.DeleteEdge(e.ID))
+	_, err = pg.GetEdge(e.ID)
+	assert.NoError(t, err, "edge deletion was not logged")
+	assert.Equal(t, 2, pg.NodeCount())
+	assert.Equal(t, 1, pg.EdgeCount())
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// progressBarWidth is the number of cells in the recovery progress bar
+const progressBarWidth = 10
+
+// recoveryPrinter writes recovery progress to out. On a terminal it
+// redraws a bar in place, as in
+//
+//	Replaying WAL: [####------] 4000/10000 entries
+//
+// and elsewhere, such as when output is piped to a log, it writes a line
+// each time another tenth of the phase is done.
+type recoveryPrinter struct {
+	out      io.Writer
+	terminal bool
+	phase    RecoveryPhase
+	tenths   uint64 // Tenths of the phase already reported when not on a terminal
+}
+
+func newRecoveryPrinter(out io.Writer) *recoveryPrinter {
+	return &recoveryPrinter{out: out, terminal: isTerminal(out)}
+}
+
+// report prints progress; it is a callback for Options.RecoveryProgress
+func (p *recoveryPrinter) report(progress RecoveryProgress) {
+	var title, unit string
+	switch progress.Phase {
+	case RecoverySnapshot:
+		title, unit = "Loading snapshot", "nodes and edges"
+	case RecoveryWAL:
+		title, unit = "Replaying WAL", "entries"
+	default:
+		return
+	}
+	if progress.Phase != p.phase {
+		p.phase, p.tenths = progress.Phase, 0
+	}
+
+	current, total := progress.Current, progress.Total
+	if total == 0 {
+		fmt.Fprintf(p.out, "%s: no %s\n", title, unit)
+		return
+	}
+
+	if p.terminal {
+		filled := int(current * progressBarWidth / total)
+		bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+		fmt.Fprintf(p.out, "\r%s: [%s] %d/%d %s", title, bar, current, total, unit)
+		if current >= total {
+			fmt.Fprintln(p.out)
+		}
+		return
+	}
+
+	if tenths := current * 10 / total; tenths > p.tenths {
+		p.tenths = tenths
+		fmt.Fprintf(p.out, "%s: %d%% (%d/%d %s)\n", title, tenths*10, current, total, unit)
+	}
+}
+
+// isTerminal reports whether w is a terminal, which can redraw a line
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
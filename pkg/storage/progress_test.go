@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryPrinter_Terminal(t *testing.T) {
+	var out bytes.Buffer
+	p := &recoveryPrinter{out: &out, terminal: true}
+	p.report(RecoveryProgress{Phase: RecoverySnapshot, Current: 20000, Total: 20000})
+	p.report(RecoveryProgress{Phase: RecoveryWAL, Current: 4000, Total: 10000})
+	p.report(RecoveryProgress{Phase: RecoveryWAL, Current: 10000, Total: 10000})
+	p.report(RecoveryProgress{Phase: RecoveryComplete})
+	assert.Equal(t, "\rLoading snapshot: [##########] 20000/20000 nodes and edges\n"+
+		"\rReplaying WAL: [####------] 4000/10000 entries"+
+		"\rReplaying WAL: [##########] 10000/10000 entries\n", out.String())
+}
+
+func TestRecoveryPrinter_Piped(t *testing.T) {
+	var out bytes.Buffer
+	p := newRecoveryPrinter(&out)
+	assert.False(t, p.terminal)
+	for current := uint64(1000); current <= 25000; current += 1000 {
+		p.report(RecoveryProgress{Phase: RecoveryWAL, Current: current, Total: 25000})
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 10)
+	assert.Equal(t, "Replaying WAL: 10% (3000/25000 entries)", lines[0])
+	assert.Equal(t, "Replaying WAL: 100% (25000/25000 entries)", lines[9])
+
+	// Each phase counts its tenths afresh
+	out.Reset()
+	p = newRecoveryPrinter(&out)
+	p.report(RecoveryProgress{Phase: RecoverySnapshot, Current: 10, Total: 10})
+	p.report(RecoveryProgress{Phase: RecoveryWAL, Current: 5, Total: 10})
+	assert.Equal(t, "Loading snapshot: 100% (10/10 nodes and edges)\n"+
+		"Replaying WAL: 50% (5/10 entries)\n", out.String())
+
+	out.Reset()
+	newRecoveryPrinter(&out).report(RecoveryProgress{Phase: RecoveryWAL})
+	assert.Equal(t, "Replaying WAL: no entries\n", out.String())
+}
+
+func TestRecover_ReportsProgress(t *testing.T) {
+	dir := t.TempDir()
+	walDir, snapDir := filepath.Join(dir, "wal"), filepath.Join(dir, "snapshots")
+	g, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	for i := 0; i < 2000; i++ {
+		_, err := g.AddNode("Person", graph.Properties{"n": i})
+		require.NoError(t, err)
+	}
+	require.NoError(t, g.Close())
+
+	var out bytes.Buffer
+	opts := DefaultOptions()
+	opts.RecoveryOutput = &out
+	g, err = NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer g.Close()
+	assert.Equal(t, 2000, g.NodeCount())
+	assert.Contains(t, out.String(), "Replaying WAL: 50% (1000/2000 entries)\n")
+	assert.Contains(t, out.String(), "Replaying WAL: 100% (2000/2000 entries)\n")
+	assert.Contains(t, out.String(), "Recovery complete: 2000 nodes, 0 edges\n")
+}
@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// propertyIndex is an equality index over one property of one label.
+// Scalars are keyed so that values the query engine considers equal share
+// a key (30 and 30.0, for example). Other values are kept aside and
+// returned by every lookup, so lookups never miss a match and callers
+// re-check equality. The distinct string values are also kept sorted for
+// prefix scans.
+type propertyIndex struct {
+	def     IndexDef
+	values  map[string]map[graph.NodeID]struct{}
+	other   map[graph.NodeID]struct{}
+	strings []string // Sorted distinct string values
+}
+
+// propertyKey returns the index key for a scalar value
+func propertyKey(v graph.PropertyValue) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return "s:" + val, true
+	case bool:
+		return "b:" + strconv.FormatBool(val), true
+	case int:
+		return numberKey(float64(val)), true
+	case int8:
+		return numberKey(float64(val)), true
+	case int16:
+		return numberKey(float64(val)), true
+	case int32:
+		return numberKey(float64(val)), true
+	case int64:
+		return numberKey(float64(val)), true
+	case uint:
+		return numberKey(float64(val)), true
+	case uint8:
+		return numberKey(float64(val)), true
+	case uint16:
+		return numberKey(float64(val)), true
+	case uint32:
+		return numberKey(float64(val)), true
+	case uint64:
+		return numberKey(float64(val)), true
+	case float32:
+		return numberKey(float64(val)), true
+	case float64:
+		return numberKey(val), true
+	}
+	return "", false
+}
+
+func numberKey(f float64) string {
+	return "n:" + strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func (idx *propertyIndex) add(node *graph.Node) {
+	if str, ok := idx.insert(node); ok {
+		i := sort.SearchStrings(idx.strings, str)
+		idx.strings = append(idx.strings, "")
+		copy(idx.strings[i+1:], idx.strings[i:])
+		idx.strings[i] = str
+	}
+}
+
+// insert adds node to the index without touching the sorted strings, and
+// returns its value if that is a string no other node has, for the caller
+// to add
+func (idx *propertyIndex) insert(node *graph.Node) (string, bool) {
+	v, ok := node.Properties.GetProperty(idx.def.Property)
+	if !ok || v == nil {
+		return "", false
+	}
+	key, ok := propertyKey(v)
+	if !ok {
+		idx.other[node.ID] = struct{}{}
+		return "", false
+	}
+	nodes, ok := idx.values[key]
+	if !ok {
+		nodes = make(map[graph.NodeID]struct{})
+		idx.values[key] = nodes
+	}
+	nodes[node.ID] = struct{}{}
+	str, isString := v.(string)
+	return str, !ok && isString
+}
+
+func (idx *propertyIndex) remove(node *graph.Node) {
+	v, ok := node.Properties.GetProperty(idx.def.Property)
+	if !ok || v == nil {
+		return
+	}
+	key, ok := propertyKey(v)
+	if !ok {
+		delete(idx.other, node.ID)
+		return
+	}
+	if nodes, ok := idx.values[key]; ok {
+		delete(nodes, node.ID)
+		if len(nodes) == 0 {
+			delete(idx.values, key)
+			if str, isString := v.(string); isString {
+				i := sort.SearchStrings(idx.strings, str)
+				idx.strings = append(idx.strings[:i], idx.strings[i+1:]...)
+			}
+		}
+	}
+}
+
+// CreatePropertyIndex builds an equality index over the property of all
+// nodes with the given label. The index is maintained on every write.
+func (g *Graph) CreatePropertyIndex(label, property string) error {
+	def := IndexDef{Label: label, Property: property}
+
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+
+	if _, exists := g.propIndexes[def]; exists {
+		return fmt.Errorf("property index on :%s(%s) already exists", label, property)
+	}
+
+	idx := &propertyIndex{
+		def:    def,
+		values: make(map[string]map[graph.NodeID]struct{}),
+		other:  make(map[graph.NodeID]struct{}),
+	}
+	g.IterateNodesByLabel(label, func(node *graph.Node) bool {
+		node.Mu.RLock()
+		idx.add(node)
+		node.Mu.RUnlock()
+		return true
+	})
+
+	g.propIndexes[def] = idx
+	return nil
+}
+
+// DropPropertyIndex removes a property index
+func (g *Graph) DropPropertyIndex(label, property string) error {
+	def := IndexDef{Label: label, Property: property}
+
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+
+	if _, exists := g.propIndexes[def]; !exists {
+		return fmt.Errorf("no property index on :%s(%s)", label, property)
+	}
+	delete(g.propIndexes, def)
+	return nil
+}
+
+// HasPropertyIndex reports whether a property index exists for label and property
+func (g *Graph) HasPropertyIndex(label, property string) bool {
+	g.idxMu.RLock()
+	defer g.idxMu.RUnlock()
+	_, ok := g.propIndexes[IndexDef{Label: label, Property: property}]
+	return ok
+}
+
+// PropertyIndexes returns the definitions of all property indexes
+func (g *Graph) PropertyIndexes() []IndexDef {
+	g.idxMu.RLock()
+	defer g.idxMu.RUnlock()
+
+	defs := make([]IndexDef, 0, len(g.propIndexes))
+	for def := range g.propIndexes {
+		defs = append(defs, def)
+	}
+	sortIndexDefs(defs)
+	return defs
+}
+
+// PropertyLookup returns the nodes whose indexed property may equal value,
+// ordered by ID. Candidates holding lists, maps or other non-scalar values
+// are always included, so callers must compare the property themselves.
+func (g *Graph) PropertyLookup(label, property string, value graph.PropertyValue) ([]*graph.Node, error) {
+	g.idxMu.RLock()
+	idx, ok := g.propIndexes[IndexDef{Label: label, Property: property}]
+	if !ok {
+		g.idxMu.RUnlock()
+		return nil, fmt.Errorf("no property index on :%s(%s)", label, property)
+	}
+
+	ids := make([]graph.NodeID, 0, len(idx.other)+1)
+	if key, ok := propertyKey(value); ok {
+		for id := range idx.values[key] {
+			ids = append(ids, id)
+		}
+	}
+	for id := range idx.other {
+		ids = append(ids, id)
+	}
+	g.idxMu.RUnlock()
+
+	return g.indexedNodes(ids), nil
+}
+
+// PropertyPrefixLookup returns the nodes whose indexed property is a
+// string starting with prefix, ordered by ID. It scans the sorted string
+// values from prefix up to the first value without it, so it only
+// touches matching values.
+func (g *Graph) PropertyPrefixLookup(label, property, prefix string) ([]*graph.Node, error) {
+	g.idxMu.RLock()
+	idx, ok := g.propIndexes[IndexDef{Label: label, Property: property}]
+	if !ok {
+		g.idxMu.RUnlock()
+		return nil, fmt.Errorf("no property index on :%s(%s)", label, property)
+	}
+
+	var ids []graph.NodeID
+	for i := sort.SearchStrings(idx.strings, prefix); i < len(idx.strings); i++ {
+		str := idx.strings[i]
+		if !strings.HasPrefix(str, prefix) {
+			break
+		}
+		for id := range idx.values["s:"+str] {
+			ids = append(ids, id)
+		}
+	}
+	g.idxMu.RUnlock()
+
+	return g.indexedNodes(ids), nil
+}
+
+// indexedNodes sorts ids and resolves them to nodes, skipping any removed
+// since the index was read
+func (g *Graph) indexedNodes(ids []graph.NodeID) []*graph.Node {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	nodes := make([]*graph.Node, 0, len(ids))
+	for _, id := range ids {
+		if node, ok := g.nodes[id]; ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// indexPropertyValues adds node to every property index on its label.
+// Caller holds idxMu and node.Mu.
+func (g *Graph) indexPropertyValues(node *graph.Node) {
+	for def, idx := range g.propIndexes {
+		if def.Label == node.Label {
+			idx.add(node)
+		}
+	}
+}
+
+// unindexPropertyValues removes node from every property index on its
+// label. Caller holds idxMu and node.Mu.
+func (g *Graph) unindexPropertyValues(node *graph.Node) {
+	for def, idx := range g.propIndexes {
+		if def.Label == node.Label {
+			idx.remove(node)
+		}
+	}
+}
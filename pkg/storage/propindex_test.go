@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookupNames(t *testing.T, g *Graph, label, property string, value graph.PropertyValue) []string {
+	nodes, err := g.PropertyLookup(label, property, value)
+	require.NoError(t, err)
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Properties.Map()["name"].(string))
+	}
+	return names
+}
+
+func TestPropertyIndex(t *testing.T) {
+	g := NewGraph()
+	g.AddNode("Person", graph.Properties{"name": "Alice", "age": 30})
+	g.AddNode("Person", graph.Properties{"name": "Bob", "age": 30.0})
+	g.AddNode("Robot", graph.Properties{"name": "Alice"})
+
+	require.NoError(t, g.CreatePropertyIndex("Person", "name"))
+	require.NoError(t, g.CreatePropertyIndex("Person", "age"))
+	assert.Error(t, g.CreatePropertyIndex("Person", "name"))
+	assert.True(t, g.HasPropertyIndex("Person", "name"))
+	assert.Equal(t, []IndexDef{{Label: "Person", Property: "age"}, {Label: "Person", Property: "name"}}, g.PropertyIndexes())
+
+	assert.Equal(t, []string{"Alice"}, lookupNames(t, g, "Person", "name", "Alice"))
+	assert.Empty(t, lookupNames(t, g, "Person", "name", "Carol"))
+
+	// Numbers match across integer and float representations
+	assert.Equal(t, []string{"Alice", "Bob"}, lookupNames(t, g, "Person", "age", 30))
+
+	_, err := g.PropertyLookup("Robot", "name", "Alice")
+	assert.Error(t, err)
+
+	require.NoError(t, g.DropPropertyIndex("Person", "age"))
+	assert.False(t, g.HasPropertyIndex("Person", "age"))
+}
+
+func TestPropertyPrefixLookup(t *testing.T) {
+	g := NewGraph()
+	require.NoError(t, g.CreatePropertyIndex("Person", "name"))
+	for _, name := range []string{"Alma", "Bob", "Al", "Alice", "Alice", "alfred"} {
+		g.AddNode("Person", graph.Properties{"name": name})
+	}
+	g.AddNode("Person", graph.Properties{"name": 42})
+	carol, _ := g.AddNode("Person", graph.Properties{"name": "Carol"})
+
+	prefixNames := func(prefix string) []string {
+		nodes, err := g.PropertyPrefixLookup("Person", "name", prefix)
+		require.NoError(t, err)
+		var names []string
+		for _, n := range nodes {
+			names = append(names, n.Properties.Map()["name"].(string))
+		}
+		return names
+	}
+	assert.Equal(t, []string{"Alma", "Al", "Alice", "Alice"}, prefixNames("Al"))
+	assert.Equal(t, []string{"Alice", "Alice"}, prefixNames("Alice"))
+	assert.Empty(t, prefixNames("Alicia"))
+	assert.Len(t, prefixNames(""), 7)
+
+	// Removed values leave the sorted values
+	require.NoError(t, g.UpdateNode(carol.ID, graph.Properties{"name": "Alf"}))
+	assert.Equal(t, []string{"Alma", "Al", "Alice", "Alice", "Alf"}, prefixNames("Al"))
+	assert.Empty(t, prefixNames("C"))
+
+	_, err := g.PropertyPrefixLookup("Robot", "name", "A")
+	assert.Error(t, err)
+}
+
+func TestPropertyIndexMaintenance(t *testing.T) {
+	g := NewGraph()
+	require.NoError(t, g.CreatePropertyIndex("Person", "name"))
+
+	n, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	assert.Equal(t, []string{"Alice"}, lookupNames(t, g, "Person", "name", "Alice"))
+
+	require.NoError(t, g.UpdateNode(n.ID, graph.Properties{"name": "Alicia"}))
+	assert.Empty(t, lookupNames(t, g, "Person", "name", "Alice"))
+	assert.Equal(t, []string{"Alicia"}, lookupNames(t, g, "Person", "name", "Alicia"))
+
+	require.NoError(t, g.DeleteNode(n.ID))
+	assert.Empty(t, lookupNames(t, g, "Person", "name", "Alicia"))
+}
+
+func TestPropertyIndex_Persistence(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	pg.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, pg.CreatePropertyIndex("Person", "name"))
+	require.NoError(t, pg.Snapshot())
+	pg.AddNode("Person", graph.Properties{"name": "Bob"})
+	require.NoError(t, pg.Close())
+
+	// Restored from the snapshot catalog
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	require.True(t, pg.HasPropertyIndex("Person", "name"))
+	assert.Equal(t, []string{"Bob"}, lookupNames(t, pg.Graph, "Person", "name", "Bob"))
+	require.NoError(t, pg.Close())
+
+	// Replayed from the WAL
+	walDir, snapDir = t.TempDir(), t.TempDir()
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	require.NoError(t, pg.CreatePropertyIndex("Person", "name"))
+	pg.AddNode("Person", graph.Properties{"name": "Carol"})
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+	assert.Equal(t, []string{"Carol"}, lookupNames(t, pg.Graph, "Person", "name", "Carol"))
+}
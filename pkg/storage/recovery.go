@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// RecoveryPhase is the step of recovery a RecoveryProgress reports on
+type RecoveryPhase int
+
+const (
+	// RecoverySnapshot is loading the nodes and edges of the latest snapshot
+	RecoverySnapshot RecoveryPhase = iota
+
+	// RecoveryWAL is replaying the WAL entries logged after the snapshot
+	RecoveryWAL
+
+	// RecoveryComplete is reported once, when the graph is ready
+	RecoveryComplete
+)
+
+func (p RecoveryPhase) String() string {
+	switch p {
+	case RecoverySnapshot:
+		return "snapshot"
+	case RecoveryWAL:
+		return "wal"
+	case RecoveryComplete:
+		return "complete"
+	}
+	return "unknown"
+}
+
+// RecoveryProgress reports how far the recovery of a graph has got
+type RecoveryProgress struct {
+	Phase RecoveryPhase
+
+	// Current counts the work of the phase done so far, and Total all of
+	// it: the nodes and edges of the snapshot, or the WAL entries
+	Current uint64
+	Total   uint64
+
+	// Nodes and Edges are the number in the graph so far
+	Nodes int
+	Edges int
+}
+
+// Percent returns how much of the phase is done, from 0 to 100. A phase
+// with nothing to do, and a completed recovery, are 100.
+func (p RecoveryProgress) Percent() float64 {
+	if p.Phase == RecoveryComplete || p.Total == 0 {
+		return 100
+	}
+	return float64(p.Current) * 100 / float64(p.Total)
+}
+
+// snapshotProgressInterval is the number of nodes or edges loaded from a
+// snapshot between progress reports
+const snapshotProgressInterval = 10000
+
+// loadSnapshot inserts the nodes and edges of a snapshot using up to
+// workers goroutines, or GOMAXPROCS if workers is zero. The edges are
+// inserted on one goroutine while the nodes are inserted on another, and
+// once the nodes are in, the label index, the property key counts and
+// each property index are rebuilt on one goroutine apiece. This replaces
+// inserting the nodes one at a time, which updates every index under the
+// index lock for each. The ID allocators continue after the highest IDs
+// loaded.
+//
+// progress is called with the number of nodes and edges inserted after
+// every snapshotProgressInterval of either, and once at the end. Calls
+// are never concurrent.
+func (g *Graph) loadSnapshot(nodes []*graph.Node, edges []*graph.Edge, workers int, progress func(nodes, edges int)) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var (
+		progressMu               sync.Mutex
+		loadedNodes, loadedEdges atomic.Int64
+	)
+	report := func() {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		progress(int(loadedNodes.Load()), int(loadedEdges.Load()))
+	}
+
+	// The edges need nothing from the nodes, so they go in alongside
+	group := newWorkerGroup(workers)
+	group.Go(func() {
+		g.edgesMu.Lock()
+		defer g.edgesMu.Unlock()
+		for _, edge := range edges {
+			g.putEdge(edge)
+			g.trackEdgeExpiry(edge)
+			if uint64(edge.ID) >= g.nextEdgeID.Load() {
+				g.nextEdgeID.Store(uint64(edge.ID) + 1)
+			}
+			if loadedEdges.Add(1)%snapshotProgressInterval == 0 {
+				report()
+			}
+		}
+	})
+
+	// The index lock is held from the first node inserted until the
+	// indexes are swapped in, so no lookup sees a node the indexes miss
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+	nodesDone := make(chan struct{})
+	group.Go(func() {
+		defer close(nodesDone)
+		g.nodesMu.Lock()
+		defer g.nodesMu.Unlock()
+		for _, node := range nodes {
+			g.nodes[node.ID] = node
+			g.trackNodeExpiry(node)
+			if uint64(node.ID) >= g.nextNodeID.Load() {
+				g.nextNodeID.Store(uint64(node.ID) + 1)
+			}
+			if loadedNodes.Add(1)%snapshotProgressInterval == 0 {
+				report()
+			}
+		}
+	})
+	<-nodesDone
+
+	// Building the indexes waits for the edges too
+	g.swapIndexes(g.buildIndexesWith(group))
+	report()
+}
+
+// workerGroup runs functions on goroutines, at most limit at a time. Go
+// blocks while limit are running, so with a limit of one the functions
+// run one after another, in order.
+type workerGroup struct {
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newWorkerGroup(limit int) *workerGroup {
+	return &workerGroup{slots: make(chan struct{}, limit)}
+}
+
+// Go runs fn on a goroutine once a slot is free
+func (w *workerGroup) Go(fn func()) {
+	w.slots <- struct{}{}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() { <-w.slots }()
+		fn()
+	}()
+}
+
+// Wait waits for every function started to return
+func (w *workerGroup) Wait() {
+	w.wg.Wait()
+}
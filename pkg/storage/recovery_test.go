@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSnapshotGraph creates a graph with indexes, n people in a chain and
+// a few nodes and edges logged after its snapshot
+func writeSnapshotGraph(t testing.TB, walDir, snapDir string, n int) {
+	opts := DefaultOptions()
+	opts.RecoveryOutput = io.Discard
+	pg, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer pg.Close()
+	require.NoError(t, pg.CreatePropertyIndex("Person", "name"))
+	require.NoError(t, pg.CreateFullTextIndex("Person", "bio"))
+	require.NoError(t, pg.CreateSpatialIndex("Place", "location"))
+
+	err = pg.BulkLoad(func(loader *BulkLoader) error {
+		var prev *graph.Node
+		for i := 0; i < n; i++ {
+			node, err := loader.AddNode("Person", graph.Properties{
+				"name": fmt.Sprintf("Person%d", i),
+				"bio":  fmt.Sprintf("likes graph number %d", i),
+			})
+			if err != nil {
+				return err
+			}
+			if prev != nil {
+				if _, err := loader.AddEdge(prev.ID, node.ID, "NEXT", nil); err != nil {
+					return err
+				}
+			}
+			prev = node
+		}
+		_, err := loader.AddNode("Place", graph.Properties{"location": graph.Point{Lat: 52.5, Lon: 13.4}})
+		return err
+	})
+	require.NoError(t, err)
+	require.NoError(t, pg.Snapshot())
+
+	for i := 0; i < 3; i++ {
+		_, err := pg.AddNode("Company", graph.Properties{"name": fmt.Sprintf("Company%d", i)})
+		require.NoError(t, err)
+	}
+}
+
+func TestRecover_ParallelSnapshotLoad(t *testing.T) {
+	dir := t.TempDir()
+	walDir, snapDir := filepath.Join(dir, "wal"), filepath.Join(dir, "snapshots")
+	writeSnapshotGraph(t, walDir, snapDir, 15000)
+
+	for _, parallelism := range []int{1, 4} {
+		t.Run(fmt.Sprintf("parallelism=%d", parallelism), func(t *testing.T) {
+			var reports []RecoveryProgress
+			opts := DefaultOptions()
+			opts.RecoveryOutput = io.Discard
+			opts.RecoveryParallelism = parallelism
+			opts.RecoveryProgress = func(p RecoveryProgress) {
+				reports = append(reports, p)
+			}
+			pg, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+			require.NoError(t, err)
+			defer pg.Close()
+
+			assert.Equal(t, 15004, pg.NodeCount())
+			assert.Equal(t, 14999, pg.EdgeCount())
+			assert.Equal(t, map[string]int{"Person": 15000, "Place": 1, "Company": 3}, pg.LabelCounts())
+			assert.Empty(t, pg.Validate())
+			found, err := pg.CheckIndexConsistency()
+			require.NoError(t, err)
+			assert.Empty(t, found)
+
+			nodes, err := pg.PropertyLookup("Person", "name", "Person123")
+			require.NoError(t, err)
+			assert.Len(t, nodes, 1)
+			nodes, err = pg.FullTextSearch("Person", "bio", "12345")
+			require.NoError(t, err)
+			assert.Len(t, nodes, 1)
+			nodes, err = pg.RadiusSearch("Place", "location", graph.Point{Lat: 52.5, Lon: 13.4}, 1)
+			require.NoError(t, err)
+			assert.Len(t, nodes, 1)
+			assert.Equal(t, []string{"bio", "name"}, pg.PropertyKeys("Person"))
+
+			// New IDs continue after the loaded ones
+			assert.Equal(t, uint64(15005), pg.nextNodeID.Load())
+			assert.Equal(t, uint64(15000), pg.nextEdgeID.Load())
+
+			// The snapshot is reported as it loads, then the WAL, then
+			// completion
+			require.NotEmpty(t, reports)
+			var snapshot, replay []RecoveryProgress
+			for _, p := range reports[:len(reports)-1] {
+				switch p.Phase {
+				case RecoverySnapshot:
+					snapshot = append(snapshot, p)
+				case RecoveryWAL:
+					replay = append(replay, p)
+				}
+			}
+			require.Greater(t, len(snapshot), 1)
+			for i := 1; i < len(snapshot); i++ {
+				assert.Greater(t, snapshot[i].Current, snapshot[i-1].Current)
+			}
+			assert.Equal(t, RecoveryProgress{
+				Phase: RecoverySnapshot, Current: 30000, Total: 30000, Nodes: 15001, Edges: 14999,
+			}, snapshot[len(snapshot)-1])
+			require.NotEmpty(t, replay)
+			assert.Equal(t, 100.0, replay[len(replay)-1].Percent())
+			assert.Equal(t, RecoveryProgress{Phase: RecoveryComplete, Nodes: 15004, Edges: 14999}, reports[len(reports)-1])
+		})
+	}
+}
+
+func TestRecoveryProgress_Percent(t *testing.T) {
+	assert.Equal(t, 25.0, RecoveryProgress{Phase: RecoveryWAL, Current: 1, Total: 4}.Percent())
+	assert.Equal(t, 100.0, RecoveryProgress{Phase: RecoveryWAL}.Percent())
+	assert.Equal(t, 100.0, RecoveryProgress{Phase: RecoveryComplete}.Percent())
+	assert.Equal(t, "snapshot", RecoverySnapshot.String())
+}
+
+// recoveryBenchNodes is the size of the graph BenchmarkRecoverSnapshot
+// recovers
+const recoveryBenchNodes = 200000
+
+// BenchmarkRecoverSnapshot opens a graph of recoveryBenchNodes people in a
+// chain from its snapshot, loading the snapshot on one goroutine and on
+// GOMAXPROCS
+func BenchmarkRecoverSnapshot(b *testing.B) {
+	dir := b.TempDir()
+	walDir, snapDir := filepath.Join(dir, "wal"), filepath.Join(dir, "snapshots")
+	writeSnapshotGraph(b, walDir, snapDir, recoveryBenchNodes)
+
+	for _, parallelism := range []int{1, 0} {
+		b.Run(fmt.Sprintf("parallelism=%d", parallelism), func(b *testing.B) {
+			opts := DefaultOptions()
+			opts.RecoveryOutput = io.Discard
+			opts.RecoveryParallelism = parallelism
+			opts.SweepInterval = 0
+			for i := 0; i < b.N; i++ {
+				pg, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+				require.NoError(b, err)
+				pg.Close()
+			}
+		})
+	}
+}
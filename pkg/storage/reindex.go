@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// reindexAttempts is how many times Reindex rebuilds under the read lock
+// before rebuilding under the write lock instead
+const reindexAttempts = 3
+
+// secondaryIndexes holds the label index and every property index
+type secondaryIndexes struct {
+	byLabel map[string]map[graph.NodeID]struct{}
+	keys    map[string]map[string]int
+	ft      map[IndexDef]*fullTextIndex
+	spatial map[IndexDef]*spatialIndex
+	prop    map[IndexDef]*propertyIndex
+}
+
+// Reindex rebuilds the label index, the property key counts and all
+// full-text, spatial and property indexes from the nodes, keeping the
+// index definitions. It repairs indexes left inconsistent by a failed
+// update.
+//
+// The indexes are built while holding the index lock for reading, which
+// blocks writers but not lookups, and swapped in under the write lock. A
+// write that slips in between makes Reindex start over.
+func (g *Graph) Reindex() error {
+	for attempt := 0; attempt < reindexAttempts; attempt++ {
+		g.idxMu.RLock()
+		gen := g.idxGen
+		rebuilt := g.buildIndexes()
+		g.idxMu.RUnlock()
+
+		g.idxMu.Lock()
+		if g.idxGen == gen {
+			g.swapIndexes(rebuilt)
+			g.idxMu.Unlock()
+			return nil
+		}
+		g.idxMu.Unlock()
+	}
+
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+	g.swapIndexes(g.buildIndexes())
+	return nil
+}
+
+// buildIndexes builds fresh indexes with the current definitions. Caller
+// holds idxMu.
+func (g *Graph) buildIndexes() *secondaryIndexes {
+	return g.buildIndexesWith(newWorkerGroup(1))
+}
+
+// buildIndexesWith is buildIndexes with the label index, the property key
+// counts and each property index built by a function of its own, run on
+// group. It waits for everything on group to finish. Caller holds idxMu.
+func (g *Graph) buildIndexesWith(group *workerGroup) *secondaryIndexes {
+	rebuilt := &secondaryIndexes{
+		byLabel: make(map[string]map[graph.NodeID]struct{}),
+		keys:    make(map[string]map[string]int),
+		ft:      make(map[IndexDef]*fullTextIndex, len(g.ftIndexes)),
+		spatial: make(map[IndexDef]*spatialIndex, len(g.spatialIndexes)),
+		prop:    make(map[IndexDef]*propertyIndex, len(g.propIndexes)),
+	}
+
+	g.nodesMu.RLock()
+	nodes := make([]*graph.Node, 0, len(g.nodes))
+	for _, node := range g.nodes {
+		nodes = append(nodes, node)
+	}
+	g.nodesMu.RUnlock()
+
+	// each calls add for every node with the label, under the node's lock
+	each := func(label string, add func(*graph.Node)) {
+		for _, node := range nodes {
+			if label != "" && node.Label != label {
+				continue
+			}
+			node.Mu.RLock()
+			add(node)
+			node.Mu.RUnlock()
+		}
+	}
+
+	group.Go(func() {
+		for _, node := range nodes {
+			ids, ok := rebuilt.byLabel[node.Label]
+			if !ok {
+				ids = make(map[graph.NodeID]struct{})
+				rebuilt.byLabel[node.Label] = ids
+			}
+			ids[node.ID] = struct{}{}
+		}
+	})
+	group.Go(func() {
+		each("", func(node *graph.Node) { countPropertyKeys(rebuilt.keys, node) })
+	})
+	for def := range g.ftIndexes {
+		idx := &fullTextIndex{def: def, postings: make(map[string]map[graph.NodeID]int)}
+		rebuilt.ft[def] = idx
+		group.Go(func() { each(idx.def.Label, idx.add) })
+	}
+	for def := range g.spatialIndexes {
+		idx := &spatialIndex{def: def, buckets: make(map[string]*geohashBucket)}
+		rebuilt.spatial[def] = idx
+		group.Go(func() { each(idx.def.Label, idx.add) })
+	}
+	for def := range g.propIndexes {
+		idx := &propertyIndex{
+			def:    def,
+			values: make(map[string]map[graph.NodeID]struct{}),
+			other:  make(map[graph.NodeID]struct{}),
+		}
+		rebuilt.prop[def] = idx
+		group.Go(func() {
+			// Sorting the distinct strings once beats inserting each in
+			// place, which is quadratic in their number
+			each(idx.def.Label, func(node *graph.Node) {
+				if str, ok := idx.insert(node); ok {
+					idx.strings = append(idx.strings, str)
+				}
+			})
+			sort.Strings(idx.strings)
+		})
+	}
+	group.Wait()
+	return rebuilt
+}
+
+// swapIndexes replaces the indexes with rebuilt. Caller holds idxMu for
+// writing.
+func (g *Graph) swapIndexes(rebuilt *secondaryIndexes) {
+	g.ftIndexes = rebuilt.ft
+	g.spatialIndexes = rebuilt.spatial
+	g.propIndexes = rebuilt.prop
+	g.propertyKeys = rebuilt.keys
+	g.idxGen++
+
+	g.nodesMu.Lock()
+	g.nodesByLabel = rebuilt.byLabel
+	g.nodesMu.Unlock()
+}
+
+// IndexInconsistency is an index entry that does not match the graph,
+// as reported by CheckIndexConsistency
+type IndexInconsistency struct {
+	Kind     string // "label", "full-text", "spatial" or "property"
+	Label    string
+	Property string // Empty for the label index
+	Node     graph.NodeID
+
+	// Missing is true when the node is not indexed as its properties
+	// require, and false when the index holds an entry the node does not
+	// match, such as an old value or a deleted node
+	Missing bool
+}
+
+func (i IndexInconsistency) String() string {
+	index := fmt.Sprintf("%s index :%s", i.Kind, i.Label)
+	if i.Property != "" {
+		index += "(" + i.Property + ")"
+	}
+	if i.Missing {
+		return fmt.Sprintf("%s is missing node %d", index, i.Node)
+	}
+	return fmt.Sprintf("%s has a stale entry for node %d", index, i.Node)
+}
+
+// indexEntry is one entry of an index, for comparing two versions of it
+type indexEntry struct {
+	key  string
+	node graph.NodeID
+}
+
+// CheckIndexConsistency compares every index with one built afresh from
+// the nodes and reports the nodes whose entries differ, ordered by index
+// and node. Reindex repairs them.
+func (g *Graph) CheckIndexConsistency() ([]IndexInconsistency, error) {
+	g.idxMu.RLock()
+	defer g.idxMu.RUnlock()
+
+	expected := g.buildIndexes()
+	var found []IndexInconsistency
+	compare := func(kind string, def IndexDef, want, got map[indexEntry]struct{}) {
+		found = append(found, diffEntries(kind, def, want, got)...)
+	}
+
+	g.nodesMu.RLock()
+	labels := make(map[string]bool)
+	for label := range expected.byLabel {
+		labels[label] = true
+	}
+	for label := range g.nodesByLabel {
+		labels[label] = true
+	}
+	for label := range labels {
+		compare("label", IndexDef{Label: label}, labelEntries(expected.byLabel[label]), labelEntries(g.nodesByLabel[label]))
+	}
+	g.nodesMu.RUnlock()
+
+	for def, idx := range g.ftIndexes {
+		compare("full-text", def, expected.ft[def].entries(), idx.entries())
+	}
+	for def, idx := range g.spatialIndexes {
+		compare("spatial", def, expected.spatial[def].entries(), idx.entries())
+	}
+	for def, idx := range g.propIndexes {
+		compare("property", def, expected.prop[def].entries(), idx.entries())
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		if a.Property != b.Property {
+			return a.Property < b.Property
+		}
+		if a.Node != b.Node {
+			return a.Node < b.Node
+		}
+		return a.Missing && !b.Missing
+	})
+	return found, nil
+}
+
+// diffEntries reports each node with entries in want but not got as
+// missing, and each with entries in got but not want as stale
+func diffEntries(kind string, def IndexDef, want, got map[indexEntry]struct{}) []IndexInconsistency {
+	missing := make(map[graph.NodeID]bool)
+	stale := make(map[graph.NodeID]bool)
+	for entry := range want {
+		if _, ok := got[entry]; !ok {
+			missing[entry.node] = true
+		}
+	}
+	for entry := range got {
+		if _, ok := want[entry]; !ok {
+			stale[entry.node] = true
+		}
+	}
+
+	found := make([]IndexInconsistency, 0, len(missing)+len(stale))
+	for id := range missing {
+		found = append(found, IndexInconsistency{Kind: kind, Label: def.Label, Property: def.Property, Node: id, Missing: true})
+	}
+	for id := range stale {
+		found = append(found, IndexInconsistency{Kind: kind, Label: def.Label, Property: def.Property, Node: id})
+	}
+	return found
+}
+
+func labelEntries(ids map[graph.NodeID]struct{}) map[indexEntry]struct{} {
+	entries := make(map[indexEntry]struct{}, len(ids))
+	for id := range ids {
+		entries[indexEntry{node: id}] = struct{}{}
+	}
+	return entries
+}
+
+func (idx *fullTextIndex) entries() map[indexEntry]struct{} {
+	entries := make(map[indexEntry]struct{})
+	for term, nodes := range idx.postings {
+		for id, freq := range nodes {
+			entries[indexEntry{key: fmt.Sprintf("%s:%d", term, freq), node: id}] = struct{}{}
+		}
+	}
+	return entries
+}
+
+func (idx *spatialIndex) entries() map[indexEntry]struct{} {
+	entries := make(map[indexEntry]struct{})
+	for key, bucket := range idx.buckets {
+		for id, p := range bucket.points {
+			entries[indexEntry{key: fmt.Sprintf("%s:%v", key, p), node: id}] = struct{}{}
+		}
+	}
+	return entries
+}
+
+func (idx *propertyIndex) entries() map[indexEntry]struct{} {
+	entries := make(map[indexEntry]struct{})
+	for key, nodes := range idx.values {
+		for id := range nodes {
+			entries[indexEntry{key: key, node: id}] = struct{}{}
+		}
+	}
+	for id := range idx.other {
+		entries[indexEntry{key: "other", node: id}] = struct{}{}
+	}
+	return entries
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createIndexedGraph builds Persons and Cities covered by every kind of
+// index
+func createIndexedGraph(t *testing.T) *Graph {
+	g := NewGraph()
+	for i := 0; i < 20; i++ {
+		_, err := g.AddNode("Person", graph.Properties{
+			"name": fmt.Sprintf("Person%d", i),
+			"bio":  fmt.Sprintf("likes graphs and number %d", i%3),
+		})
+		require.NoError(t, err)
+	}
+	for i := 0; i < 5; i++ {
+		_, err := g.AddNode("City", graph.Properties{
+			"name":     fmt.Sprintf("City%d", i),
+			"location": graph.Point{Lat: 40 + float64(i)*0.1, Lon: -74},
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, g.CreatePropertyIndex("Person", "name"))
+	require.NoError(t, g.CreateFullTextIndex("Person", "bio"))
+	require.NoError(t, g.CreateSpatialIndex("City", "location"))
+	return g
+}
+
+// indexResults runs a lookup against each index
+func indexResults(t *testing.T, g *Graph) map[string][]graph.NodeID {
+	ids := func(nodes []*graph.Node, err error) []graph.NodeID {
+		require.NoError(t, err)
+		result := make([]graph.NodeID, len(nodes))
+		for i, node := range nodes {
+			result[i] = node.ID
+		}
+		return result
+	}
+	var byLabel []graph.NodeID
+	g.IterateNodesByLabelSorted("Person", func(node *graph.Node) bool {
+		byLabel = append(byLabel, node.ID)
+		return true
+	})
+	return map[string][]graph.NodeID{
+		"label":    byLabel,
+		"property": ids(g.PropertyLookup("Person", "name", "Person7")),
+		"prefix":   ids(g.PropertyPrefixLookup("Person", "name", "Person1")),
+		"fulltext": ids(g.FullTextSearch("Person", "bio", "number 2")),
+		"spatial":  ids(g.RadiusSearch("City", "location", graph.Point{Lat: 40, Lon: -74}, 25)),
+	}
+}
+
+func TestCheckIndexConsistency(t *testing.T) {
+	g := createIndexedGraph(t)
+	found, err := g.CheckIndexConsistency()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	person7 := indexResults(t, g)["property"][0]
+	city := indexResults(t, g)["spatial"][0]
+
+	// Corrupt each index the way a failed update could
+	delete(g.propIndexes[IndexDef{Label: "Person", Property: "name"}].values["s:Person7"], person7)
+	g.propIndexes[IndexDef{Label: "Person", Property: "name"}].values["s:Ghost"] = map[graph.NodeID]struct{}{999: {}}
+	delete(g.ftIndexes[IndexDef{Label: "Person", Property: "bio"}].postings["graphs"], person7)
+	for _, bucket := range g.spatialIndexes[IndexDef{Label: "City", Property: "location"}].buckets {
+		delete(bucket.points, city)
+	}
+	delete(g.nodesByLabel["Person"], person7)
+	g.nodesByLabel["Person"][998] = struct{}{}
+
+	found, err = g.CheckIndexConsistency()
+	require.NoError(t, err)
+	assert.Equal(t, []IndexInconsistency{
+		{Kind: "full-text", Label: "Person", Property: "bio", Node: person7, Missing: true},
+		{Kind: "label", Label: "Person", Node: person7, Missing: true},
+		{Kind: "label", Label: "Person", Node: 998},
+		{Kind: "property", Label: "Person", Property: "name", Node: person7, Missing: true},
+		{Kind: "property", Label: "Person", Property: "name", Node: 999},
+		{Kind: "spatial", Label: "City", Property: "location", Node: city, Missing: true},
+	}, found)
+	assert.Equal(t, "property index :Person(name) is missing node 8", found[3].String())
+	assert.Equal(t, "label index :Person has a stale entry for node 998", found[2].String())
+
+	require.NoError(t, g.Reindex())
+	found, err = g.CheckIndexConsistency()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	assert.Equal(t, []graph.NodeID{person7}, indexResults(t, g)["property"])
+}
+
+func TestReindex_SameResults(t *testing.T) {
+	g := createIndexedGraph(t)
+	before := indexResults(t, g)
+
+	require.NoError(t, g.Reindex())
+	assert.Equal(t, before, indexResults(t, g))
+	assert.Equal(t, 20, g.LabelCount("Person"))
+	assert.Equal(t, 5, g.LabelCount("City"))
+	assert.True(t, g.HasPropertyIndex("Person", "name"))
+
+	// The rebuilt indexes are maintained as before
+	node, err := g.AddNode("Person", graph.Properties{"name": "Person7"})
+	require.NoError(t, err)
+	assert.Len(t, indexResults(t, g)["property"], 2)
+	require.NoError(t, g.DeleteNode(node.ID))
+	assert.Equal(t, before, indexResults(t, g))
+}
+
+func TestReindex_ConcurrentWrites(t *testing.T) {
+	g := createIndexedGraph(t)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			node, err := g.AddNode("Person", graph.Properties{"name": fmt.Sprintf("Writer%d", i), "bio": "concurrent"})
+			assert.NoError(t, err)
+			if i%2 == 0 {
+				assert.NoError(t, g.DeleteNode(node.ID))
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			assert.NoError(t, g.Reindex())
+		}
+	}()
+	wg.Wait()
+
+	found, err := g.CheckIndexConsistency()
+	require.NoError(t, err)
+	assert.Empty(t, found)
+	assert.Equal(t, 120, g.LabelCount("Person"))
+}
@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+)
+
+// PropertyType is the declared type of a schema property
+type PropertyType string
+
+// Property types a schema can declare
+const (
+	TypeString    PropertyType = graph.TypeString
+	TypeInt       PropertyType = graph.TypeInt
+	TypeFloat     PropertyType = graph.TypeFloat // Also accepts ints
+	TypeBool      PropertyType = graph.TypeBool
+	TypePoint     PropertyType = graph.TypePoint
+	TypeDatetime  PropertyType = graph.TypeDatetime
+	TypeList      PropertyType = graph.TypeList
+	TypeMap       PropertyType = graph.TypeMap
+	TypeHistogram PropertyType = graph.TypeHistogram
+)
+
+// ErrSchemaViolation is wrapped by every *SchemaViolation
+var ErrSchemaViolation = errors.New("schema violation")
+
+// Schema declares property types for the nodes of one label. Labels
+// without a schema accept any properties.
+type Schema struct {
+	Label      string
+	Properties map[string]PropertyType // Typed properties; others are unchecked
+	Required   []string                // Properties that must be present and non-null
+}
+
+// SchemaViolation describes a node property that does not match its
+// label's schema
+type SchemaViolation struct {
+	NodeID   graph.NodeID // 0 for a node that was rejected before creation
+	Label    string
+	Property string
+	Expected PropertyType // Declared type, "" for an untyped required property
+	Actual   string       // Type tag of the stored value, "" when missing
+}
+
+func (v *SchemaViolation) Error() string {
+	if v.Actual == "" {
+		return fmt.Sprintf("%v: :%s requires property %s", ErrSchemaViolation, v.Label, v.Property)
+	}
+	return fmt.Sprintf("%v: :%s(%s) must be %s, got %s", ErrSchemaViolation, v.Label, v.Property, v.Expected, v.Actual)
+}
+
+func (v *SchemaViolation) Unwrap() error {
+	return ErrSchemaViolation
+}
+
+// DefineSchema declares the property types of label and the properties
+// every node of label must have, replacing any previous schema. Existing
+// nodes are not checked; see ValidateExisting.
+func (g *Graph) DefineSchema(label string, properties map[string]PropertyType, required []string) error {
+	for property, typ := range properties {
+		if !validPropertyType(typ) {
+			return fmt.Errorf("unknown type %q for property %s", typ, property)
+		}
+	}
+
+	schema := &Schema{
+		Label:      label,
+		Properties: make(map[string]PropertyType, len(properties)),
+		Required:   append([]string(nil), required...),
+	}
+	for property, typ := range properties {
+		schema.Properties[property] = typ
+	}
+	sort.Strings(schema.Required)
+
+	g.schemaMu.Lock()
+	defer g.schemaMu.Unlock()
+	g.schemas[label] = schema
+	return nil
+}
+
+// DropSchema removes the schema of label, making it schemaless again
+func (g *Graph) DropSchema(label string) error {
+	g.schemaMu.Lock()
+	defer g.schemaMu.Unlock()
+
+	if _, exists := g.schemas[label]; !exists {
+		return fmt.Errorf("no schema for :%s", label)
+	}
+	delete(g.schemas, label)
+	return nil
+}
+
+// GetSchema returns the schema of label, if one is defined
+func (g *Graph) GetSchema(label string) (Schema, bool) {
+	g.schemaMu.RLock()
+	defer g.schemaMu.RUnlock()
+
+	schema, ok := g.schemas[label]
+	if !ok {
+		return Schema{}, false
+	}
+	return *schema, true
+}
+
+// Schemas returns all defined schemas ordered by label
+func (g *Graph) Schemas() []Schema {
+	g.schemaMu.RLock()
+	defer g.schemaMu.RUnlock()
+
+	schemas := make([]Schema, 0, len(g.schemas))
+	for _, schema := range g.schemas {
+		schemas = append(schemas, *schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Label < schemas[j].Label })
+	return schemas
+}
+
+// ValidateExisting checks every node whose label has a schema and returns
+// the violations ordered by node ID and property. Nothing is modified.
+func (g *Graph) ValidateExisting() []SchemaViolation {
+	var violations []SchemaViolation
+	for _, schema := range g.Schemas() {
+		schema := schema
+		g.IterateNodesByLabel(schema.Label, func(node *graph.Node) bool {
+			node.Mu.RLock()
+			violations = append(violations, schema.check(node.ID, node.Properties.Map())...)
+			node.Mu.RUnlock()
+			return true
+		})
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].NodeID != violations[j].NodeID {
+			return violations[i].NodeID < violations[j].NodeID
+		}
+		return violations[i].Property < violations[j].Property
+	})
+	return violations
+}
+
+// validateNode returns the first schema violation of a node with the given
+// label and (normalized) properties, or nil
+func (g *Graph) validateNode(id graph.NodeID, label string, properties graph.Properties) error {
+	schema, ok := g.GetSchema(label)
+	if !ok {
+		return nil
+	}
+	if violations := schema.check(id, properties); len(violations) > 0 {
+		return &violations[0]
+	}
+	return nil
+}
+
+// validateUpdate checks the properties node would have after update
+func (g *Graph) validateUpdate(node *graph.Node, update graph.Properties) error {
+	schema, ok := g.GetSchema(node.Label)
+	if !ok {
+		return nil
+	}
+
+	node.Mu.RLock()
+	merged := node.Properties.Map()
+	node.Mu.RUnlock()
+	for k, v := range update {
+		merged[k] = v
+	}
+
+	if violations := schema.check(node.ID, merged); len(violations) > 0 {
+		return &violations[0]
+	}
+	return nil
+}
+
+// check returns the violations of properties, ordered by property name.
+// Null values count as missing.
+func (s Schema) check(id graph.NodeID, properties graph.Properties) []SchemaViolation {
+	var violations []SchemaViolation
+	violate := func(property, actual string) {
+		violations = append(violations, SchemaViolation{
+			NodeID:   id,
+			Label:    s.Label,
+			Property: property,
+			Expected: s.Properties[property],
+			Actual:   actual,
+		})
+	}
+
+	for _, property := range s.Required {
+		if properties[property] == nil {
+			violate(property, "")
+		}
+	}
+	for property, typ := range s.Properties {
+		v := properties[property]
+		if v == nil {
+			continue
+		}
+		actual := graph.TypeOf(v)
+		if actual != string(typ) && !(typ == TypeFloat && actual == graph.TypeInt) {
+			violate(property, actual)
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool { return violations[i].Property < violations[j].Property })
+	return violations
+}
+
+func validPropertyType(typ PropertyType) bool {
+	switch typ {
+	case TypeString, TypeInt, TypeFloat, TypeBool, TypePoint, TypeDatetime, TypeList, TypeMap, TypeHistogram:
+		return true
+	}
+	return false
+}
+
+// Catalog collects the schema objects of the graph: its indexes and label
+// schemas, each ordered by label and property. Snapshots store it
+// alongside the data.
+func (g *Graph) Catalog() *wal.Catalog {
+	catalog := &wal.Catalog{
+		FullTextIndexes: g.FullTextIndexes(),
+		SpatialIndexes:  g.SpatialIndexes(),
+		PropertyIndexes: g.PropertyIndexes(),
+	}
+	for _, schema := range g.Schemas() {
+		catalog.Schemas = append(catalog.Schemas, schemaDef(schema))
+	}
+	return catalog
+}
+
+// schemaDef converts a schema to its catalog form
+func schemaDef(s Schema) wal.SchemaDef {
+	def := wal.SchemaDef{Label: s.Label, Required: s.Required}
+	if len(s.Properties) > 0 {
+		def.Properties = make(map[string]string, len(s.Properties))
+		for property, typ := range s.Properties {
+			def.Properties[property] = string(typ)
+		}
+	}
+	return def
+}
+
+// defineSchemaDef applies a schema recorded in the catalog or WAL
+func (g *Graph) defineSchemaDef(def wal.SchemaDef) error {
+	properties := make(map[string]PropertyType, len(def.Properties))
+	for property, typ := range def.Properties {
+		properties[property] = PropertyType(typ)
+	}
+	return g.DefineSchema(def.Label, properties, def.Required)
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaValidation(t *testing.T) {
+	g := NewGraph()
+	require.NoError(t, g.DefineSchema("Person", map[string]PropertyType{
+		"name":   TypeString,
+		"age":    TypeInt,
+		"height": TypeFloat,
+	}, []string{"name"}))
+
+	_, err := g.AddNode("Person", graph.Properties{"name": "Alice", "age": 30, "height": 170})
+	require.NoError(t, err)
+
+	_, err = g.AddNode("Person", graph.Properties{"name": "Bob", "age": "30"})
+	var violation *SchemaViolation
+	require.True(t, errors.As(err, &violation))
+	assert.True(t, errors.Is(err, ErrSchemaViolation))
+	assert.Equal(t, SchemaViolation{Label: "Person", Property: "age", Expected: TypeInt, Actual: graph.TypeString}, *violation)
+	assert.EqualError(t, err, "schema violation: :Person(age) must be int, got string")
+
+	_, err = g.AddNode("Person", graph.Properties{"age": 30})
+	assert.EqualError(t, err, "schema violation: :Person requires property name")
+	assert.Equal(t, 1, g.NodeCount(), "rejected nodes must not be stored")
+
+	// Labels without a schema stay schemaless
+	_, err = g.AddNode("Robot", graph.Properties{"age": "unknown"})
+	require.NoError(t, err)
+
+	assert.Error(t, g.DefineSchema("City", map[string]PropertyType{"name": "text"}, nil))
+}
+
+func TestSchemaValidation_Updates(t *testing.T) {
+	g := NewGraph()
+	require.NoError(t, g.DefineSchema("Person", map[string]PropertyType{"age": TypeInt}, []string{"name"}))
+
+	n, err := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, g.UpdateNode(n.ID, graph.Properties{"age": "30"}), ErrSchemaViolation)
+	assert.ErrorIs(t, g.UpdateNode(n.ID, graph.Properties{"name": nil}), ErrSchemaViolation)
+	_, hasAge := n.GetProperty("age")
+	assert.False(t, hasAge)
+
+	require.NoError(t, g.UpdateNode(n.ID, graph.Properties{"age": 31}))
+}
+
+func TestValidateExisting(t *testing.T) {
+	g := NewGraph()
+	a, _ := g.AddNode("Person", graph.Properties{"name": "Alice", "age": "30"})
+	b, _ := g.AddNode("Person", graph.Properties{"age": 40.5})
+	g.AddNode("Person", graph.Properties{"name": "Carol", "age": 25})
+
+	assert.Empty(t, g.ValidateExisting())
+
+	require.NoError(t, g.DefineSchema("Person", map[string]PropertyType{"age": TypeInt}, []string{"name"}))
+	assert.Equal(t, []SchemaViolation{
+		{NodeID: a.ID, Label: "Person", Property: "age", Expected: TypeInt, Actual: graph.TypeString},
+		{NodeID: b.ID, Label: "Person", Property: "age", Expected: TypeInt, Actual: graph.TypeFloat},
+		{NodeID: b.ID, Label: "Person", Property: "name"},
+	}, g.ValidateExisting())
+
+	// Reporting does not touch the data
+	age, _ := a.GetProperty("age")
+	assert.Equal(t, "30", age)
+	assert.Equal(t, 3, g.NodeCount())
+
+	require.NoError(t, g.DropSchema("Person"))
+	assert.Empty(t, g.ValidateExisting())
+	assert.Error(t, g.DropSchema("Person"))
+}
+
+func TestSchema_Persistence(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	require.NoError(t, pg.DefineSchema("Person", map[string]PropertyType{"age": TypeInt}, []string{"name"}))
+	require.NoError(t, pg.Snapshot())
+	require.NoError(t, pg.DefineSchema("City", map[string]PropertyType{"location": TypePoint}, nil))
+	n, err := pg.AddNode("Person", graph.Properties{"name": "Alice", "age": 30})
+	require.NoError(t, err)
+	assert.ErrorIs(t, pg.UpdateNode(n.ID, graph.Properties{"age": "old"}), ErrSchemaViolation)
+	require.NoError(t, pg.Close())
+
+	// The Person schema comes from the snapshot, City from the WAL
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+
+	schemas := pg.Schemas()
+	require.Len(t, schemas, 2)
+	assert.Equal(t, Schema{Label: "City", Properties: map[string]PropertyType{"location": TypePoint}}, schemas[0])
+	assert.Equal(t, Schema{Label: "Person", Properties: map[string]PropertyType{"age": TypeInt}, Required: []string{"name"}}, schemas[1])
+
+	age, _ := n.GetProperty("age")
+	assert.Equal(t, 30, age)
+	_, err = pg.AddNode("Person", graph.Properties{"name": "Bob", "age": "30"})
+	assert.ErrorIs(t, err, ErrSchemaViolation)
+
+	require.NoError(t, pg.DropSchema("City"))
+	_, err = pg.AddNode("City", graph.Properties{"location": "nowhere"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Snapshot policy defaults used by DefaultSnapshotPolicy
+const (
+	DefaultSnapshotInterval      = 5 * time.Minute
+	DefaultSnapshotMaxWALEntries = 100000
+	DefaultSnapshotMaxWALBytes   = 64 << 20
+
+	// DefaultSnapshotCheckInterval is how often StartSnapshotter checks
+	// the WAL size when the policy does not say
+	DefaultSnapshotCheckInterval = time.Second
+)
+
+// SnapshotPolicy decides when StartSnapshotter takes snapshots. Each
+// limit that is zero is disabled.
+type SnapshotPolicy struct {
+	// Interval is the longest time between snapshots. A snapshot is
+	// skipped when nothing has been logged since the last one.
+	Interval time.Duration
+
+	// MaxWALEntries and MaxWALBytes trigger a snapshot as soon as the WAL
+	// holds more entries or bytes than this since the last one, so heavy
+	// write load does not let it grow until the next interval
+	MaxWALEntries int
+	MaxWALBytes   int64
+
+	// CheckInterval is how often the WAL size is compared against the
+	// limits. Zero means DefaultSnapshotCheckInterval.
+	CheckInterval time.Duration
+}
+
+// DefaultSnapshotPolicy returns the policy the server snapshots with
+func DefaultSnapshotPolicy() SnapshotPolicy {
+	return SnapshotPolicy{
+		Interval:      DefaultSnapshotInterval,
+		MaxWALEntries: DefaultSnapshotMaxWALEntries,
+		MaxWALBytes:   DefaultSnapshotMaxWALBytes,
+	}
+}
+
+// SnapshotTrigger is the reason StartSnapshotter took a snapshot
+type SnapshotTrigger string
+
+const (
+	SnapshotOnInterval   SnapshotTrigger = "interval"
+	SnapshotOnWALEntries SnapshotTrigger = "wal_entries"
+	SnapshotOnWALBytes   SnapshotTrigger = "wal_bytes"
+)
+
+// StartSnapshotter takes snapshots in the background as policy directs
+// until the returned function is called, which waits for a snapshot in
+// progress to finish. onSnapshot, if not nil, is called after every
+// snapshot with its trigger and error.
+func (pg *PersistentGraph) StartSnapshotter(policy SnapshotPolicy, onSnapshot func(trigger SnapshotTrigger, err error)) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	check := policy.CheckInterval
+	if check <= 0 {
+		check = DefaultSnapshotCheckInterval
+	}
+
+	go func() {
+		defer close(done)
+
+		var interval <-chan time.Time
+		if policy.Interval > 0 {
+			ticker := time.NewTicker(policy.Interval)
+			defer ticker.Stop()
+			interval = ticker.C
+		}
+		var size <-chan time.Time
+		if policy.MaxWALEntries > 0 || policy.MaxWALBytes > 0 {
+			ticker := time.NewTicker(check)
+			defer ticker.Stop()
+			size = ticker.C
+		}
+
+		snapshot := func(trigger SnapshotTrigger) {
+			err := pg.Snapshot()
+			if onSnapshot != nil {
+				onSnapshot(trigger, err)
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-interval:
+				if pg.WALIndex() != pg.snapshotIndex.Load() {
+					snapshot(SnapshotOnInterval)
+				}
+			case <-size:
+				if trigger, ok := pg.walOverLimit(policy); ok {
+					snapshot(trigger)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// walOverLimit reports which of the policy's WAL limits, if any, the WAL
+// has exceeded. The entry a snapshot keeps in the WAL is not counted.
+func (pg *PersistentGraph) walOverLimit(policy SnapshotPolicy) (SnapshotTrigger, bool) {
+	entries, bytes := pg.WALSize()
+	if pg.snapshotIndex.Load() > 0 {
+		entries--
+	}
+	switch {
+	case policy.MaxWALEntries > 0 && entries > policy.MaxWALEntries:
+		return SnapshotOnWALEntries, true
+	case policy.MaxWALBytes > 0 && bytes > policy.MaxWALBytes:
+		return SnapshotOnWALBytes, true
+	}
+	return "", false
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// snapshotRecorder collects the snapshots StartSnapshotter reports
+type snapshotRecorder struct {
+	mu       sync.Mutex
+	triggers []SnapshotTrigger
+	taken    chan struct{}
+}
+
+func newSnapshotRecorder() *snapshotRecorder {
+	return &snapshotRecorder{taken: make(chan struct{}, 100)}
+}
+
+func (r *snapshotRecorder) record(trigger SnapshotTrigger, err error) {
+	if err != nil {
+		panic(err)
+	}
+	r.mu.Lock()
+	r.triggers = append(r.triggers, trigger)
+	r.mu.Unlock()
+	select {
+	case r.taken <- struct{}{}:
+	default:
+	}
+}
+
+func (r *snapshotRecorder) wait(t *testing.T) {
+	select {
+	case <-r.taken:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no snapshot was taken")
+	}
+}
+
+func (r *snapshotRecorder) recorded() []SnapshotTrigger {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SnapshotTrigger(nil), r.triggers...)
+}
+
+func TestSnapshotter_WALEntries(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+
+	recorder := newSnapshotRecorder()
+	stop := pg.StartSnapshotter(SnapshotPolicy{
+		Interval:      time.Hour,
+		MaxWALEntries: 50,
+		CheckInterval: 5 * time.Millisecond,
+	}, recorder.record)
+	defer stop()
+
+	// Writes well short of the limit do not trigger a snapshot
+	for i := 0; i < 50; i++ {
+		_, err := pg.AddNode("Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)})
+		require.NoError(t, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, recorder.recorded())
+
+	// Going past it does, long before the interval
+	for i := 50; i < 200; i++ {
+		_, err := pg.AddNode("Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)})
+		require.NoError(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		entries, _ := pg.WALSize()
+		return entries <= 51
+	}, 5*time.Second, time.Millisecond)
+	stop()
+
+	triggers := recorder.recorded()
+	require.NotEmpty(t, triggers)
+	for _, trigger := range triggers {
+		assert.Equal(t, SnapshotOnWALEntries, trigger)
+	}
+	require.NoError(t, pg.Close())
+
+	// The snapshot holds the graph
+	reopened, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, 200, reopened.NodeCount())
+}
+
+func TestSnapshotter_WALBytes(t *testing.T) {
+	pg, err := NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+
+	recorder := newSnapshotRecorder()
+	stop := pg.StartSnapshotter(SnapshotPolicy{
+		MaxWALBytes:   4096,
+		CheckInterval: 5 * time.Millisecond,
+	}, recorder.record)
+	defer stop()
+
+	for i := 0; i < 5; i++ {
+		_, err := pg.AddNode("Document", graph.Properties{"body": fmt.Sprintf("%01000d", i)})
+		require.NoError(t, err)
+	}
+	recorder.wait(t)
+	stop()
+
+	assert.Equal(t, SnapshotOnWALBytes, recorder.recorded()[0])
+	_, bytes := pg.WALSize()
+	assert.Less(t, bytes, int64(4096))
+}
+
+func TestSnapshotter_IntervalSkipsIdle(t *testing.T) {
+	pg, err := NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+
+	_, err = pg.AddNode("Person", nil)
+	require.NoError(t, err)
+
+	recorder := newSnapshotRecorder()
+	stop := pg.StartSnapshotter(SnapshotPolicy{Interval: 10 * time.Millisecond}, recorder.record)
+	defer stop()
+
+	// One snapshot for the write, then none while nothing is logged
+	recorder.wait(t)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, []SnapshotTrigger{SnapshotOnInterval}, recorder.recorded())
+
+	_, err = pg.AddNode("Person", nil)
+	require.NoError(t, err)
+	recorder.wait(t)
+	stop()
+	assert.Equal(t, []SnapshotTrigger{SnapshotOnInterval, SnapshotOnInterval}, recorder.recorded())
+}
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// spatialGeohashPrecision is the geohash length of a spatial index
+// bucket. Four characters give cells of roughly 39 x 20 km.
+const spatialGeohashPrecision = 4
+
+// Cell sizes in degrees at spatialGeohashPrecision. Geohash bits alternate
+// starting with longitude, which gets the extra bit for odd bit counts.
+var (
+	bucketLonDegrees = 360 / float64(int(1)<<((spatialGeohashPrecision*5+1)/2))
+	bucketLatDegrees = 180 / float64(int(1)<<(spatialGeohashPrecision*5/2))
+)
+
+// kmPerDegree is the length of one degree of latitude
+const kmPerDegree = graph.EarthRadiusKm * math.Pi / 180
+
+// gridCell is the row and column of a geohash bucket
+type gridCell struct {
+	lat, lon int
+}
+
+func cellFor(p graph.Point) gridCell {
+	return gridCell{
+		lat: clampCell(int(math.Floor((p.Lat+90)/bucketLatDegrees)), 180/bucketLatDegrees),
+		lon: clampCell(int(math.Floor((p.Lon+180)/bucketLonDegrees)), 360/bucketLonDegrees),
+	}
+}
+
+// clampCell keeps the cell of a point on the upper edge (lat 90 or
+// lon 180) inside the grid, matching its geohash
+func clampCell(i int, cells float64) int {
+	if n := int(cells); i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// geohash returns the bucket key of the cell
+func (c gridCell) geohash() string {
+	center := graph.Point{
+		Lat: -90 + (float64(c.lat)+0.5)*bucketLatDegrees,
+		Lon: -180 + (float64(c.lon)+0.5)*bucketLonDegrees,
+	}
+	return graph.Geohash(center, spatialGeohashPrecision)
+}
+
+// geohashBucket holds the indexed points of one geohash cell
+type geohashBucket struct {
+	cell   gridCell
+	points map[graph.NodeID]graph.Point
+}
+
+// spatialIndex buckets one Point property of one label by geohash prefix
+type spatialIndex struct {
+	def     IndexDef
+	buckets map[string]*geohashBucket
+}
+
+func (idx *spatialIndex) add(node *graph.Node) {
+	value, _ := node.Properties.GetProperty(idx.def.Property)
+	p, ok := value.(graph.Point)
+	if !ok {
+		return
+	}
+	key := graph.Geohash(p, spatialGeohashPrecision)
+	bucket, ok := idx.buckets[key]
+	if !ok {
+		bucket = &geohashBucket{cell: cellFor(p), points: make(map[graph.NodeID]graph.Point)}
+		idx.buckets[key] = bucket
+	}
+	bucket.points[node.ID] = p
+}
+
+func (idx *spatialIndex) remove(node *graph.Node) {
+	value, _ := node.Properties.GetProperty(idx.def.Property)
+	p, ok := value.(graph.Point)
+	if !ok {
+		return
+	}
+	key := graph.Geohash(p, spatialGeohashPrecision)
+	if bucket, ok := idx.buckets[key]; ok {
+		delete(bucket.points, node.ID)
+		if len(bucket.points) == 0 {
+			delete(idx.buckets, key)
+		}
+	}
+}
+
+// candidateBuckets returns the populated buckets inside the bounding box
+// of the circle of radiusKm around center
+func (idx *spatialIndex) candidateBuckets(center graph.Point, radiusKm float64) []*geohashBucket {
+	latDelta := radiusKm / kmPerDegree
+	minLat := math.Max(-90, center.Lat-latDelta)
+	maxLat := math.Min(90, center.Lat+latDelta)
+
+	// Longitude degrees shrink towards the poles; fall back to the full
+	// range near a pole or across the antimeridian
+	wrapLon := true
+	minLon, maxLon := -180.0, 180.0
+	if cosLat := math.Cos(math.Max(math.Abs(minLat), math.Abs(maxLat)) * math.Pi / 180); cosLat > 1e-6 {
+		lonDelta := latDelta / cosLat
+		if center.Lon-lonDelta >= -180 && center.Lon+lonDelta <= 180 {
+			minLon, maxLon = center.Lon-lonDelta, center.Lon+lonDelta
+			wrapLon = false
+		}
+	}
+
+	lo := cellFor(graph.Point{Lat: minLat, Lon: minLon})
+	hi := cellFor(graph.Point{Lat: maxLat, Lon: maxLon})
+
+	buckets := make([]*geohashBucket, 0)
+	boxSize := (hi.lat - lo.lat + 1) * (hi.lon - lo.lon + 1)
+	if boxSize > len(idx.buckets) {
+		// Sparse index: cheaper to filter the populated buckets
+		for _, bucket := range idx.buckets {
+			cell := bucket.cell
+			if cell.lat >= lo.lat && cell.lat <= hi.lat && (wrapLon || cell.lon >= lo.lon && cell.lon <= hi.lon) {
+				buckets = append(buckets, bucket)
+			}
+		}
+		return buckets
+	}
+
+	for lat := lo.lat; lat <= hi.lat; lat++ {
+		for lon := lo.lon; lon <= hi.lon; lon++ {
+			if bucket, ok := idx.buckets[gridCell{lat, lon}.geohash()]; ok {
+				buckets = append(buckets, bucket)
+			}
+		}
+	}
+	return buckets
+}
+
+// CreateSpatialIndex builds a geohash-bucket index over the Point property of all
+// nodes with the given label. The index is maintained on every write.
+func (g *Graph) CreateSpatialIndex(label, property string) error {
+	def := IndexDef{Label: label, Property: property}
+
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+
+	if _, exists := g.spatialIndexes[def]; exists {
+		return fmt.Errorf("spatial index on :%s(%s) already exists", label, property)
+	}
+
+	idx := &spatialIndex{def: def, buckets: make(map[string]*geohashBucket)}
+	g.IterateNodesByLabel(label, func(node *graph.Node) bool {
+		node.Mu.RLock()
+		idx.add(node)
+		node.Mu.RUnlock()
+		return true
+	})
+
+	g.spatialIndexes[def] = idx
+	return nil
+}
+
+// DropSpatialIndex removes a spatial index
+func (g *Graph) DropSpatialIndex(label, property string) error {
+	def := IndexDef{Label: label, Property: property}
+
+	g.idxMu.Lock()
+	defer g.idxMu.Unlock()
+
+	if _, exists := g.spatialIndexes[def]; !exists {
+		return fmt.Errorf("no spatial index on :%s(%s)", label, property)
+	}
+	delete(g.spatialIndexes, def)
+	return nil
+}
+
+// HasSpatialIndex reports whether a spatial index exists for label and property
+func (g *Graph) HasSpatialIndex(label, property string) bool {
+	g.idxMu.RLock()
+	defer g.idxMu.RUnlock()
+	_, ok := g.spatialIndexes[IndexDef{Label: label, Property: property}]
+	return ok
+}
+
+// SpatialIndexes returns the definitions of all spatial indexes
+func (g *Graph) SpatialIndexes() []IndexDef {
+	g.idxMu.RLock()
+	defer g.idxMu.RUnlock()
+
+	defs := make([]IndexDef, 0, len(g.spatialIndexes))
+	for def := range g.spatialIndexes {
+		defs = append(defs, def)
+	}
+	sortIndexDefs(defs)
+	return defs
+}
+
+// RadiusSearch returns the nodes whose indexed point lies within radiusKm
+// of center, nearest first
+func (g *Graph) RadiusSearch(label, property string, center graph.Point, radiusKm float64) ([]*graph.Node, error) {
+	g.idxMu.RLock()
+	idx, ok := g.spatialIndexes[IndexDef{Label: label, Property: property}]
+	if !ok {
+		g.idxMu.RUnlock()
+		return nil, fmt.Errorf("no spatial index on :%s(%s)", label, property)
+	}
+
+	distances := make(map[graph.NodeID]float64)
+	for _, bucket := range idx.candidateBuckets(center, radiusKm) {
+		for id, p := range bucket.points {
+			if d := center.DistanceKm(p); d <= radiusKm {
+				distances[id] = d
+			}
+		}
+	}
+	g.idxMu.RUnlock()
+
+	ids := make([]graph.NodeID, 0, len(distances))
+	for id := range distances {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if distances[ids[i]] != distances[ids[j]] {
+			return distances[ids[i]] < distances[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	g.nodesMu.RLock()
+	defer g.nodesMu.RUnlock()
+
+	nodes := make([]*graph.Node, 0, len(ids))
+	for _, id := range ids {
+		if node, ok := g.nodes[id]; ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// indexSpatial adds node to every spatial index on its label.
+// Caller holds idxMu and node.Mu.
+func (g *Graph) indexSpatial(node *graph.Node) {
+	for def, idx := range g.spatialIndexes {
+		if def.Label == node.Label {
+			idx.add(node)
+		}
+	}
+}
+
+// unindexSpatial removes node from every spatial index on its label.
+// Caller holds idxMu and node.Mu.
+func (g *Graph) unindexSpatial(node *graph.Node) {
+	for def, idx := range g.spatialIndexes {
+		if def.Label == node.Label {
+			idx.remove(node)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createCityGraph adds a handful of cities with approximate coordinates
+func createCityGraph(g interface {
+	AddNode(string, graph.Properties) (*graph.Node, error)
+}) {
+	cities := []struct {
+		name     string
+		lat, lon float64
+	}{
+		{"New York", 40.7128, -74.0060},
+		{"Newark", 40.7357, -74.1724},
+		{"Philadelphia", 39.9526, -75.1652},
+		{"Boston", 42.3601, -71.0589},
+		{"London", 51.5074, -0.1278},
+	}
+	for _, c := range cities {
+		g.AddNode("City", graph.Properties{"name": c.name, "location": graph.Point{Lat: c.lat, Lon: c.lon}})
+	}
+}
+
+func TestRadiusSearch(t *testing.T) {
+	g := NewGraph()
+	createCityGraph(g)
+	require.NoError(t, g.CreateSpatialIndex("City", "location"))
+	assert.Error(t, g.CreateSpatialIndex("City", "location"))
+
+	nyc := graph.Point{Lat: 40.7128, Lon: -74.0060}
+
+	nodes, err := g.RadiusSearch("City", "location", nyc, 50)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"New York", "Newark"}, nodeNames(nodes))
+
+	nodes, err = g.RadiusSearch("City", "location", nyc, 400)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"New York", "Newark", "Philadelphia", "Boston"}, nodeNames(nodes))
+
+	// A radius larger than half the globe covers everything
+	nodes, err = g.RadiusSearch("City", "location", nyc, 20000)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 5)
+
+	_, err = g.RadiusSearch("City", "name", nyc, 10)
+	assert.Error(t, err)
+}
+
+func TestSpatialIndexMaintenance(t *testing.T) {
+	g := NewGraph()
+	require.NoError(t, g.CreateSpatialIndex("City", "location"))
+
+	london := graph.Point{Lat: 51.5074, Lon: -0.1278}
+	n, _ := g.AddNode("City", graph.Properties{"name": "Somewhere", "location": london})
+
+	nodes, _ := g.RadiusSearch("City", "location", london, 1)
+	assert.Len(t, nodes, 1)
+
+	require.NoError(t, g.UpdateNode(n.ID, graph.Properties{"location": graph.Point{Lat: 48.8566, Lon: 2.3522}}))
+	nodes, _ = g.RadiusSearch("City", "location", london, 1)
+	assert.Empty(t, nodes)
+
+	require.NoError(t, g.DeleteNode(n.ID))
+	nodes, _ = g.RadiusSearch("City", "location", graph.Point{Lat: 48.8566, Lon: 2.3522}, 1)
+	assert.Empty(t, nodes)
+}
+
+func TestSpatialIndex_Persistence(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	createCityGraph(pg)
+	require.NoError(t, pg.CreateSpatialIndex("City", "location"))
+	require.NoError(t, pg.Snapshot())
+	pg.AddNode("City", graph.Properties{"name": "Jersey City", "location": graph.Point{Lat: 40.7178, Lon: -74.0431}})
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+
+	require.True(t, pg.HasSpatialIndex("City", "location"))
+	nodes, err := pg.RadiusSearch("City", "location", graph.Point{Lat: 40.7128, Lon: -74.0060}, 50)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"New York", "Jersey City", "Newark"}, nodeNames(nodes))
+}
+
+func TestSpatialIndex_GeohashBuckets(t *testing.T) {
+	g := NewGraph()
+	require.NoError(t, g.CreateSpatialIndex("Place", "location"))
+
+	// A dense grid around New York populates enough buckets that radius
+	// searches enumerate the bounding box rather than every bucket
+	points := make(map[graph.NodeID]graph.Point)
+	for i := 0; i < 40; i++ {
+		for j := 0; j < 40; j++ {
+			p := graph.Point{Lat: 39 + float64(i)*0.1, Lon: -76 + float64(j)*0.1}
+			n, err := g.AddNode("Place", graph.Properties{"location": p})
+			require.NoError(t, err)
+			points[n.ID] = p
+		}
+	}
+
+	idx := g.spatialIndexes[IndexDef{Label: "Place", Property: "location"}]
+	for key, bucket := range idx.buckets {
+		assert.Equal(t, key, bucket.cell.geohash())
+		for _, p := range bucket.points {
+			assert.Equal(t, key, graph.Geohash(p, spatialGeohashPrecision))
+		}
+	}
+
+	center := graph.Point{Lat: 40.7128, Lon: -74.0060}
+	for _, radius := range []float64{5, 30, 120} {
+		nodes, err := g.RadiusSearch("Place", "location", center, radius)
+		require.NoError(t, err)
+
+		want := 0
+		for _, p := range points {
+			if center.DistanceKm(p) <= radius {
+				want++
+			}
+		}
+		assert.Len(t, nodes, want, "radius %v", radius)
+		assert.Less(t, len(idx.candidateBuckets(center, radius)), len(idx.buckets))
+	}
+}
@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+)
+
+// StatPropertyPrefix is prepended to the algorithm name to form the node
+// property holding a materialized score, e.g. "_stat_pagerank"
+const StatPropertyPrefix = "_stat_"
+
+// StatFunc computes a per-node score over the graph.
+// config is algorithm specific and may be nil to use defaults.
+type StatFunc func(g *Graph, config interface{}) (map[graph.NodeID]float64, error)
+
+var (
+	statFuncs   = make(map[string]StatFunc)
+	statFuncsMu sync.RWMutex
+)
+
+// RegisterStatAlgorithm makes an algorithm available to MaterializeStats.
+// The algorithms package registers its centrality measures on import.
+func RegisterStatAlgorithm(name string, fn StatFunc) {
+	statFuncsMu.Lock()
+	defer statFuncsMu.Unlock()
+	statFuncs[name] = fn
+}
+
+// StatAlgorithms returns the names of all registered algorithms
+func StatAlgorithms() []string {
+	statFuncsMu.RLock()
+	defer statFuncsMu.RUnlock()
+
+	names := make([]string, 0, len(statFuncs))
+	for name := range statFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupStatFunc(name string) (StatFunc, bool) {
+	statFuncsMu.RLock()
+	defer statFuncsMu.RUnlock()
+	fn, ok := statFuncs[name]
+	return fn, ok
+}
+
+// statsState tracks one materialized algorithm
+type statsState struct {
+	config  interface{}
+	dirty   bool // graph mutated since the last run
+	running bool
+	pending bool // asked to run again while running
+	lastErr error
+	skipped []graph.NodeID // Nodes the last run could not update
+}
+
+// MaterializeStats runs the named algorithm in the background and stores
+// each node's score as the property StatPropertyPrefix+algo.
+// Use WaitForStats to block until the results have been applied.
+func (pg *PersistentGraph) MaterializeStats(algo string, config interface{}) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+
+	fn, ok := lookupStatFunc(algo)
+	if !ok {
+		return fmt.Errorf("unknown stats algorithm: %s", algo)
+	}
+
+	pg.statsMu.Lock()
+	state, exists := pg.stats[algo]
+	if !exists {
+		state = &statsState{}
+		pg.stats[algo] = state
+	}
+	state.config = config
+	pg.statsMu.Unlock()
+
+	pg.runStats(algo, fn, state)
+	return nil
+}
+
+// RefreshStats re-runs a previously materialized algorithm if the graph
+// has changed since it last ran
+func (pg *PersistentGraph) RefreshStats(algo string) error {
+	fn, ok := lookupStatFunc(algo)
+	if !ok {
+		return fmt.Errorf("unknown stats algorithm: %s", algo)
+	}
+
+	pg.statsMu.Lock()
+	state, exists := pg.stats[algo]
+	if !exists {
+		pg.statsMu.Unlock()
+		return fmt.Errorf("stats %s have not been materialized", algo)
+	}
+	stale := state.dirty
+	pg.statsMu.Unlock()
+
+	if stale {
+		pg.runStats(algo, fn, state)
+	}
+	return nil
+}
+
+// StatsDirty reports whether the materialized stats for algo are stale
+func (pg *PersistentGraph) StatsDirty(algo string) bool {
+	pg.statsMu.Lock()
+	defer pg.statsMu.Unlock()
+
+	state, ok := pg.stats[algo]
+	return !ok || state.dirty
+}
+
+// WaitForStats blocks until all running materializations finish and
+// returns the first error encountered by any of them
+func (pg *PersistentGraph) WaitForStats() error {
+	pg.statsWG.Wait()
+
+	pg.statsMu.Lock()
+	defer pg.statsMu.Unlock()
+
+	for algo, state := range pg.stats {
+		if state.lastErr != nil {
+			return fmt.Errorf("stats %s: %w", algo, state.lastErr)
+		}
+	}
+	return nil
+}
+
+// StatsSkipped returns the nodes the last run of algo left without a
+// score, because storing it would have violated their label's schema
+func (pg *PersistentGraph) StatsSkipped(algo string) []graph.NodeID {
+	pg.statsMu.Lock()
+	defer pg.statsMu.Unlock()
+
+	state, ok := pg.stats[algo]
+	if !ok {
+		return nil
+	}
+	return append([]graph.NodeID(nil), state.skipped...)
+}
+
+func (pg *PersistentGraph) runStats(algo string, fn StatFunc, state *statsState) {
+	pg.statsMu.Lock()
+	defer pg.statsMu.Unlock()
+
+	if pg.statsClosed {
+		return
+	}
+	if state.running {
+		// Run again once this run finishes, with the latest config and
+		// any mutations made meanwhile
+		state.pending = true
+		return
+	}
+	state.running = true
+	pg.statsWG.Add(1)
+	go func() {
+		defer pg.statsWG.Done()
+
+		for {
+			pg.statsMu.Lock()
+			state.dirty = false
+			state.pending = false
+			config := state.config
+			pg.statsMu.Unlock()
+
+			skipped, err := pg.applyStats(algo, fn, config)
+
+			pg.statsMu.Lock()
+			state.lastErr = err
+			state.skipped = skipped
+			if err != nil {
+				state.dirty = true
+			}
+			if !state.pending || pg.statsClosed {
+				state.running = false
+				pg.statsMu.Unlock()
+				return
+			}
+			pg.statsMu.Unlock()
+		}
+	}()
+}
+
+// applyStats runs the algorithm and stores its scores, logging them as one
+// WAL batch. Nodes deleted while the algorithm ran are skipped, as are
+// nodes whose schema the score would violate; the latter are returned.
+func (pg *PersistentGraph) applyStats(algo string, fn StatFunc, config interface{}) ([]graph.NodeID, error) {
+	scores, err := fn(pg.Graph, config)
+	if err != nil {
+		return nil, err
+	}
+
+	defer pg.beginWrite()()
+	key := StatPropertyPrefix + algo
+	now := time.Now()
+	var skipped []graph.NodeID
+	updates := make(map[graph.NodeID]graph.Properties, len(scores))
+	entries := make([]wal.LogEntry, 0, len(scores))
+	for id, score := range scores {
+		node, err := pg.Graph.GetNode(id)
+		if err != nil {
+			continue
+		}
+		properties := graph.Properties{key: score}
+		if err := pg.Graph.validateUpdate(node, properties); err != nil {
+			log.Printf("stats %s: skipping node %d: %v", algo, id, err)
+			skipped = append(skipped, id)
+			continue
+		}
+		updates[id] = properties
+		entries = append(entries, wal.SetNodePropertiesEntry(id, properties, now))
+	}
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i] < skipped[j] })
+
+	if pg.walEnabled && len(entries) > 0 {
+		if _, err := pg.wal.AppendBatch(entries); err != nil {
+			return skipped, fmt.Errorf("failed to log stats: %w", err)
+		}
+	}
+	for id, properties := range updates {
+		// A node deleted since it was logged stays deleted on replay too
+		pg.Graph.updateNodeAt(id, properties, now)
+	}
+	return skipped, nil
+}
+
+// startStatsRefresher re-runs every stale materialized algorithm each
+// interval until Close
+func (pg *PersistentGraph) startStatsRefresher(interval time.Duration) {
+	pg.statsStop = make(chan struct{})
+	pg.statsTickWG.Add(1)
+	go func() {
+		defer pg.statsTickWG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pg.statsStop:
+				return
+			case <-ticker.C:
+				pg.statsMu.Lock()
+				var stale []string
+				for algo, state := range pg.stats {
+					if state.dirty {
+						stale = append(stale, algo)
+					}
+				}
+				pg.statsMu.Unlock()
+
+				for _, algo := range stale {
+					if err := pg.RefreshStats(algo); err != nil {
+						log.Printf("stats refresh failed: %v", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// stopStats stops the periodic refresh, lets a running materialization
+// finish and keeps new ones from starting
+func (pg *PersistentGraph) stopStats() {
+	pg.statsOnce.Do(func() {
+		if pg.statsStop != nil {
+			close(pg.statsStop)
+		}
+	})
+	pg.statsTickWG.Wait()
+
+	pg.statsMu.Lock()
+	pg.statsClosed = true
+	pg.statsMu.Unlock()
+	pg.statsWG.Wait()
+}
+
+// markStatsDirty flags every materialized algorithm as stale
+func (pg *PersistentGraph) markStatsDirty() {
+	pg.statsMu.Lock()
+	defer pg.statsMu.Unlock()
+
+	for _, state := range pg.stats {
+		state.dirty = true
+	}
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	// Test algorithm: score = out-degree
+	RegisterStatAlgorithm("test_outdegree", func(g *Graph, config interface{}) (map[graph.NodeID]float64, error) {
+		scores := make(map[graph.NodeID]float64)
+		g.IterateNodes(func(n *graph.Node) bool {
+			n.Mu.RLock()
+			scores[n.ID] = float64(len(n.OutEdges))
+			n.Mu.RUnlock()
+			return true
+		})
+		return scores, nil
+	})
+
+	// Like test_outdegree, but each run waits for statsGate
+	RegisterStatAlgorithm("test_gated", func(g *Graph, config interface{}) (map[graph.NodeID]float64, error) {
+		statsStarted <- struct{}{}
+		<-statsGate
+		fn, _ := lookupStatFunc("test_outdegree")
+		return fn(g, config)
+	})
+}
+
+var statsStarted, statsGate = make(chan struct{}), make(chan struct{})
+
+func TestMaterializeStats(t *testing.T) {
+	pg, err := NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+
+	a, _ := pg.AddNode("Node", nil)
+	b, _ := pg.AddNode("Node", nil)
+	pg.AddEdge(a.ID, b.ID, "LINK", nil)
+
+	require.NoError(t, pg.MaterializeStats("test_outdegree", nil))
+	require.NoError(t, pg.WaitForStats())
+
+	score, ok := a.GetProperty("_stat_test_outdegree")
+	require.True(t, ok)
+	assert.Equal(t, 1.0, score)
+	assert.False(t, pg.StatsDirty("test_outdegree"))
+
+	// Mutation marks stats stale; refresh recomputes
+	pg.AddEdge(a.ID, b.ID, "LINK", nil)
+	assert.True(t, pg.StatsDirty("test_outdegree"))
+
+	require.NoError(t, pg.RefreshStats("test_outdegree"))
+	require.NoError(t, pg.WaitForStats())
+
+	score, _ = a.GetProperty("_stat_test_outdegree")
+	assert.Equal(t, 2.0, score)
+	assert.False(t, pg.StatsDirty("test_outdegree"))
+}
+
+func TestMaterializeStats_Errors(t *testing.T) {
+	pg, err := NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+
+	assert.Error(t, pg.MaterializeStats("nope", nil))
+	assert.Error(t, pg.RefreshStats("test_outdegree"), "refresh before materialize")
+}
+
+func TestMaterializeStats_RerunRequestedMidRun(t *testing.T) {
+	pg, err := NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+
+	a, _ := pg.AddNode("Node", nil)
+	b, _ := pg.AddNode("Node", nil)
+	pg.AddEdge(a.ID, b.ID, "LINK", nil)
+
+	require.NoError(t, pg.MaterializeStats("test_gated", nil))
+	<-statsStarted
+
+	// A mutation and refresh while the first run computes are not lost
+	pg.AddEdge(a.ID, b.ID, "LINK", nil)
+	require.NoError(t, pg.RefreshStats("test_gated"))
+	statsGate <- struct{}{}
+	<-statsStarted
+	statsGate <- struct{}{}
+	require.NoError(t, pg.WaitForStats())
+
+	score, _ := a.GetProperty("_stat_test_gated")
+	assert.Equal(t, 2.0, score)
+	assert.False(t, pg.StatsDirty("test_gated"))
+}
+
+func TestMaterializeStats_SkipsSchemaViolations(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	a, _ := pg.AddNode("Person", graph.Properties{"name": "Alice"})
+	b, _ := pg.AddNode("Person", nil)
+	pg.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	pg.AddEdge(b.ID, a.ID, "KNOWS", nil)
+	// b was added before the schema and already violates it
+	require.NoError(t, pg.DefineSchema("Person", nil, []string{"name"}))
+
+	require.NoError(t, pg.MaterializeStats("test_outdegree", nil))
+	require.NoError(t, pg.WaitForStats())
+	assert.Equal(t, []graph.NodeID{b.ID}, pg.StatsSkipped("test_outdegree"))
+	_, ok := b.GetProperty("_stat_test_outdegree")
+	assert.False(t, ok)
+
+	// The other scores are stored, and logged
+	require.NoError(t, pg.Close())
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+	node, err := pg.GetNode(a.ID)
+	require.NoError(t, err)
+	score, ok := node.GetProperty("_stat_test_outdegree")
+	require.True(t, ok)
+	assert.Equal(t, 1.0, score)
+}
+
+func TestMaterializeStats_PeriodicRefresh(t *testing.T) {
+	opts := DefaultOptions()
+	opts.StatsRefreshInterval = 10 * time.Millisecond
+	pg, err := NewPersistentGraphWithOptions(t.TempDir(), t.TempDir(), opts)
+	require.NoError(t, err)
+	defer pg.Close()
+
+	a, _ := pg.AddNode("Node", nil)
+	b, _ := pg.AddNode("Node", nil)
+	require.NoError(t, pg.MaterializeStats("test_outdegree", nil))
+	require.NoError(t, pg.WaitForStats())
+
+	pg.AddEdge(a.ID, b.ID, "LINK", nil)
+	assert.Eventually(t, func() bool {
+		score, _ := a.GetProperty("_stat_test_outdegree")
+		return score == 1.0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestMaterializeStats_AfterClose(t *testing.T) {
+	pg, err := NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	pg.AddNode("Node", nil)
+
+	require.NoError(t, pg.MaterializeStats("test_gated", nil))
+	<-statsStarted
+	closed := make(chan error)
+	go func() { closed <- pg.Close() }()
+
+	// Close waits for the run, and no run starts after it
+	statsGate <- struct{}{}
+	require.NoError(t, <-closed)
+	require.NoError(t, pg.MaterializeStats("test_outdegree", nil))
+	assert.NoError(t, pg.WaitForStats())
+}
@@ -0,0 +1,368 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/fnuworsu/rdgDB/pkg/wal"
+)
+
+// SetSoftDelete switches DeleteNode and DeleteEdge between removing
+// entities and tombstoning them. Tombstoned nodes and edges are invisible
+// to lookups, scans, traversals and counts until RestoreNode brings them
+// back or PurgeTombstones removes them for good.
+func (g *Graph) SetSoftDelete(enabled bool) {
+	g.softDelete.Store(enabled)
+}
+
+// SoftDelete reports whether deletions leave tombstones
+func (g *Graph) SoftDelete() bool {
+	return g.softDelete.Load()
+}
+
+// Tombstones returns the IDs of soft-deleted nodes and edges, ordered by ID
+func (g *Graph) Tombstones() ([]graph.NodeID, []graph.EdgeID) {
+	g.tombMu.Lock()
+	defer g.tombMu.Unlock()
+
+	nodes := make([]graph.NodeID, 0, len(g.deletedNodes))
+	for id := range g.deletedNodes {
+		nodes = append(nodes, id)
+	}
+	edges := make([]graph.EdgeID, 0, len(g.deletedEdges))
+	for id := range g.deletedEdges {
+		edges = append(edges, id)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	sort.Slice(edges, func(i, j int) bool { return edges[i] < edges[j] })
+	return nodes, edges
+}
+
+// RestoreNode resurrects a tombstoned node together with the edges deleted
+// along with it, or along with the node on their other end, once that
+// other end is live. Edges to nodes that are still tombstoned come back
+// when those nodes are restored. Edges deleted on their own stay deleted.
+func (g *Graph) RestoreNode(id graph.NodeID) error {
+	g.tombMu.Lock()
+	defer g.tombMu.Unlock()
+
+	if err := g.checkRestoreLocked(id); err != nil {
+		return err
+	}
+	node := g.deletedNodes[id]
+	delete(g.deletedNodes, id)
+	node.Mu.Lock()
+	node.DeletedAt = time.Time{}
+	node.OutEdges = make([]graph.EdgeID, 0)
+	node.InEdges = make([]graph.EdgeID, 0)
+	node.Mu.Unlock()
+	g.insertNode(node)
+
+	edgeIDs := make([]graph.EdgeID, 0)
+	for edgeID := range g.cascadedEdges {
+		edge, ok := g.deletedEdges[edgeID]
+		if !ok {
+			continue
+		}
+		if source, target := edge.Endpoints(); source == id || target == id {
+			edgeIDs = append(edgeIDs, edgeID)
+		}
+	}
+	sort.Slice(edgeIDs, func(i, j int) bool { return edgeIDs[i] < edgeIDs[j] })
+
+	for _, edgeID := range edgeIDs {
+		edge := g.deletedEdges[edgeID]
+		src, err := g.GetNode(edge.Source)
+		if err != nil {
+			continue
+		}
+		tgt, err := g.GetNode(edge.Target)
+		if err != nil {
+			continue
+		}
+
+		delete(g.deletedEdges, edgeID)
+		delete(g.cascadedEdges, edgeID)
+		edge.Mu.Lock()
+		edge.DeletedAt = time.Time{}
+		edge.Mu.Unlock()
+
+		g.edgesMu.Lock()
+		g.putEdge(edge)
+		g.edgesMu.Unlock()
+		src.AddOutEdge(edgeID)
+		tgt.AddInEdge(edgeID)
+		g.trackEdgeExpiry(edge)
+	}
+	return nil
+}
+
+// checkRestore returns an error if RestoreNode would fail for id
+func (g *Graph) checkRestore(id graph.NodeID) error {
+	g.tombMu.Lock()
+	defer g.tombMu.Unlock()
+	return g.checkRestoreLocked(id)
+}
+
+// checkRestoreLocked is checkRestore. Caller holds tombMu.
+func (g *Graph) checkRestoreLocked(id graph.NodeID) error {
+	if _, ok := g.deletedNodes[id]; !ok {
+		return fmt.Errorf("node %d is not deleted", id)
+	}
+	if _, err := g.GetNode(id); err == nil {
+		return fmt.Errorf("node %d already exists", id)
+	}
+	return nil
+}
+
+// PurgeTombstones permanently removes nodes and edges tombstoned at or
+// before cutoff and returns their IDs
+func (g *Graph) PurgeTombstones(cutoff time.Time) ([]graph.NodeID, []graph.EdgeID) {
+	nodes, edges := g.tombstonedBefore(cutoff)
+	g.purge(nodes, edges)
+	return nodes, edges
+}
+
+// tombstonedBefore returns the IDs of the nodes and edges tombstoned at or
+// before cutoff, ordered by ID
+func (g *Graph) tombstonedBefore(cutoff time.Time) ([]graph.NodeID, []graph.EdgeID) {
+	g.tombMu.Lock()
+	var nodes []graph.NodeID
+	for id, node := range g.deletedNodes {
+		if !node.DeletedAt.After(cutoff) {
+			nodes = append(nodes, id)
+		}
+	}
+	var edges []graph.EdgeID
+	for id, edge := range g.deletedEdges {
+		if !edge.DeletedAt.After(cutoff) {
+			edges = append(edges, id)
+		}
+	}
+	g.tombMu.Unlock()
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	sort.Slice(edges, func(i, j int) bool { return edges[i] < edges[j] })
+	return nodes, edges
+}
+
+// purge drops the given tombstones, ignoring IDs that are not tombstoned
+func (g *Graph) purge(nodes []graph.NodeID, edges []graph.EdgeID) {
+	g.tombMu.Lock()
+	defer g.tombMu.Unlock()
+
+	for _, id := range nodes {
+		delete(g.deletedNodes, id)
+	}
+	for _, id := range edges {
+		delete(g.deletedEdges, id)
+		delete(g.cascadedEdges, id)
+	}
+}
+
+// tombstoneNode removes a node and its edges from the live graph and keeps
+// them as tombstones deleted at at
+func (g *Graph) tombstoneNode(id graph.NodeID, at time.Time) error {
+	g.tombMu.Lock()
+	defer g.tombMu.Unlock()
+
+	node, err := g.GetNode(id)
+	if err != nil {
+		return err
+	}
+
+	node.Mu.RLock()
+	edgeIDs := make([]graph.EdgeID, 0, len(node.OutEdges)+len(node.InEdges))
+	edgeIDs = append(edgeIDs, node.OutEdges...)
+	edgeIDs = append(edgeIDs, node.InEdges...)
+	node.Mu.RUnlock()
+
+	for _, edgeID := range edgeIDs {
+		// Self-loops appear twice; the second attempt finds nothing
+		if g.tombstoneEdgeLocked(edgeID, at) == nil {
+			g.cascadedEdges[edgeID] = struct{}{}
+		}
+	}
+	if err := g.removeNode(id); err != nil {
+		return err
+	}
+
+	node.Mu.Lock()
+	node.DeletedAt = at
+	node.Mu.Unlock()
+	g.deletedNodes[id] = node
+	return nil
+}
+
+// tombstoneEdge removes an edge from the live graph and keeps it as a
+// tombstone deleted at at
+func (g *Graph) tombstoneEdge(id graph.EdgeID, at time.Time) error {
+	g.tombMu.Lock()
+	defer g.tombMu.Unlock()
+	return g.tombstoneEdgeLocked(id, at)
+}
+
+// tombstoneEdgeLocked tombstones an edge. Caller holds tombMu.
+func (g *Graph) tombstoneEdgeLocked(id graph.EdgeID, at time.Time) error {
+	edge, err := g.GetEdge(id)
+	if err != nil {
+		return err
+	}
+	if err := g.removeEdge(id); err != nil {
+		return err
+	}
+
+	edge.Mu.Lock()
+	edge.DeletedAt = at
+	edge.Mu.Unlock()
+	g.deletedEdges[id] = edge
+	return nil
+}
+
+// insertTombstones adds tombstones restored from a snapshot, cascaded
+// listing the edges deleted along with a node
+func (g *Graph) insertTombstones(nodes []*graph.Node, edges []*graph.Edge, cascaded []graph.EdgeID) {
+	g.tombMu.Lock()
+	defer g.tombMu.Unlock()
+
+	for _, id := range cascaded {
+		g.cascadedEdges[id] = struct{}{}
+	}
+	for _, node := range nodes {
+		g.deletedNodes[node.ID] = node
+		advanceID(&g.nextNodeID, uint64(node.ID))
+	}
+	for _, edge := range edges {
+		g.deletedEdges[edge.ID] = edge
+		advanceID(&g.nextEdgeID, uint64(edge.ID))
+	}
+}
+
+// tombstoneState returns the current tombstones for a snapshot, and the
+// IDs of the edges deleted along with a node, ordered by ID
+func (g *Graph) tombstoneState() ([]*graph.Node, []*graph.Edge, []graph.EdgeID) {
+	g.tombMu.Lock()
+	defer g.tombMu.Unlock()
+
+	nodes := make([]*graph.Node, 0, len(g.deletedNodes))
+	for _, node := range g.deletedNodes {
+		nodes = append(nodes, node)
+	}
+	edges := make([]*graph.Edge, 0, len(g.deletedEdges))
+	for _, edge := range g.deletedEdges {
+		edges = append(edges, edge)
+	}
+	cascaded := make([]graph.EdgeID, 0, len(g.cascadedEdges))
+	for id := range g.cascadedEdges {
+		cascaded = append(cascaded, id)
+	}
+	sort.Slice(cascaded, func(i, j int) bool { return cascaded[i] < cascaded[j] })
+	return nodes, edges, cascaded
+}
+
+// tombstoneNode soft-deletes a node and logs to WAL
+func (pg *PersistentGraph) tombstoneNode(id graph.NodeID) error {
+	defer pg.beginWrite()()
+	if _, err := pg.Graph.GetNode(id); err != nil {
+		return err
+	}
+
+	// Log before applying so a failed append leaves memory untouched
+	at := pg.Graph.now()
+	if pg.walEnabled {
+		if err := pg.wal.LogTombstoneNode(id, at); err != nil {
+			return fmt.Errorf("failed to log node deletion: %w", err)
+		}
+	}
+
+	if err := pg.Graph.tombstoneNode(id, at); err != nil {
+		return err
+	}
+	pg.markStatsDirty()
+	return nil
+}
+
+// tombstoneEdge soft-deletes an edge and logs to WAL
+func (pg *PersistentGraph) tombstoneEdge(id graph.EdgeID) error {
+	defer pg.beginWrite()()
+	if _, err := pg.Graph.GetEdge(id); err != nil {
+		return err
+	}
+
+	at := pg.Graph.now()
+	if pg.walEnabled {
+		if err := pg.wal.LogTombstoneEdge(id, at); err != nil {
+			return fmt.Errorf("failed to log edge deletion: %w", err)
+		}
+	}
+
+	if err := pg.Graph.tombstoneEdge(id, at); err != nil {
+		return err
+	}
+	pg.markStatsDirty()
+	return nil
+}
+
+// RestoreNode resurrects a tombstoned node and logs to WAL.
+// See Graph.RestoreNode.
+func (pg *PersistentGraph) RestoreNode(id graph.NodeID) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+	if err := pg.Graph.checkRestore(id); err != nil {
+		return err
+	}
+
+	if pg.walEnabled {
+		if err := pg.wal.LogRestoreNode(id); err != nil {
+			return fmt.Errorf("failed to log node restore: %w", err)
+		}
+	}
+
+	if err := pg.Graph.RestoreNode(id); err != nil {
+		return err
+	}
+	pg.markStatsDirty()
+	return nil
+}
+
+// PurgeTombstones permanently removes tombstones older than the
+// TombstoneRetention option, logs the removal and returns how many nodes
+// and edges were purged
+func (pg *PersistentGraph) PurgeTombstones() (int, error) {
+	if pg.opts.ReadOnly {
+		return 0, ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	nodes, edges := pg.Graph.tombstonedBefore(pg.Graph.now().Add(-pg.opts.TombstoneRetention))
+	if len(nodes) == 0 && len(edges) == 0 {
+		return 0, nil
+	}
+
+	if pg.walEnabled {
+		if err := pg.wal.LogPurgeTombstones(nodes, edges); err != nil {
+			return 0, fmt.Errorf("failed to log tombstone purge: %w", err)
+		}
+	}
+	pg.Graph.purge(nodes, edges)
+	return len(nodes) + len(edges), nil
+}
+
+// tombstones returns the snapshot form of the current tombstones, or nil
+func (pg *PersistentGraph) tombstones() *wal.Tombstones {
+	nodes, edges, cascaded := pg.Graph.tombstoneState()
+	if len(nodes) == 0 && len(edges) == 0 {
+		return nil
+	}
+	return &wal.Tombstones{Nodes: nodes, Edges: edges, Cascaded: cascaded}
+}
+
+// parseDeletedAt returns the deletion time logged with a tombstone entry,
+// falling back to the entry time
+func parseDeletedAt(entry wal.LogEntry) time.Time {
+	return loggedTime(entry, "deleted_at")
+}
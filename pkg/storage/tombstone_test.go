@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftDelete(t *testing.T) {
+	clock := newFakeClock()
+	g := NewGraph()
+	g.SetClock(clock.Now)
+	g.SetSoftDelete(true)
+	require.NoError(t, g.CreatePropertyIndex("Person", "name"))
+
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+	carol, _ := g.AddNode("Person", graph.Properties{"name": "Carol"})
+	ab, _ := g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	bc, _ := g.AddEdge(bob.ID, carol.ID, "KNOWS", nil)
+	loop, _ := g.AddEdge(bob.ID, bob.ID, "SELF", nil)
+
+	require.NoError(t, g.DeleteNode(bob.ID))
+	assert.Equal(t, 2, g.NodeCount())
+	assert.Equal(t, 0, g.EdgeCount())
+	assert.Equal(t, 2, g.LabelCount("Person"))
+	_, err := g.GetNode(bob.ID)
+	assert.Error(t, err)
+	assert.Empty(t, lookupNames(t, g, "Person", "name", "Bob"))
+	assert.Empty(t, alice.OutEdges)
+	assert.Equal(t, clock.Now(), bob.DeletedAt)
+
+	nodes, edges := g.Tombstones()
+	assert.Equal(t, []graph.NodeID{bob.ID}, nodes)
+	assert.Equal(t, []graph.EdgeID{ab.ID, bc.ID, loop.ID}, edges)
+
+	require.NoError(t, g.RestoreNode(bob.ID))
+	assert.Error(t, g.RestoreNode(bob.ID))
+	assert.Equal(t, 3, g.NodeCount())
+	assert.Equal(t, 3, g.EdgeCount())
+	assert.True(t, bob.DeletedAt.IsZero())
+	assert.Equal(t, []string{"Bob"}, lookupNames(t, g, "Person", "name", "Bob"))
+	neighbors, err := g.GetNeighbors(alice.ID)
+	require.NoError(t, err)
+	require.Len(t, neighbors, 1)
+	assert.Equal(t, bob.ID, neighbors[0].ID)
+
+	nodes, edges = g.Tombstones()
+	assert.Empty(t, nodes)
+	assert.Empty(t, edges)
+}
+
+func TestRestoreNode_WaitsForOtherEnd(t *testing.T) {
+	g := NewGraph()
+	g.SetSoftDelete(true)
+
+	a, _ := g.AddNode("Person", nil)
+	b, _ := g.AddNode("Person", nil)
+	e, _ := g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+
+	require.NoError(t, g.DeleteNode(a.ID))
+	require.NoError(t, g.DeleteNode(b.ID))
+
+	// The edge needs both ends live
+	require.NoError(t, g.RestoreNode(a.ID))
+	_, err := g.GetEdge(e.ID)
+	assert.Error(t, err)
+
+	require.NoError(t, g.RestoreNode(b.ID))
+	_, err = g.GetEdge(e.ID)
+	assert.NoError(t, err)
+}
+
+func TestRestoreNode_KeepsEdgesDeletedOnTheirOwn(t *testing.T) {
+	g := NewGraph()
+	g.SetSoftDelete(true)
+
+	a, _ := g.AddNode("Person", nil)
+	b, _ := g.AddNode("Person", nil)
+	kept, _ := g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	dropped, _ := g.AddEdge(b.ID, a.ID, "KNOWS", nil)
+
+	require.NoError(t, g.DeleteEdge(dropped.ID))
+	require.NoError(t, g.DeleteNode(a.ID))
+	require.NoError(t, g.RestoreNode(a.ID))
+
+	_, err := g.GetEdge(kept.ID)
+	assert.NoError(t, err)
+	_, err = g.GetEdge(dropped.ID)
+	assert.Error(t, err, "an edge deleted before its node stays deleted")
+	_, edges := g.Tombstones()
+	assert.Equal(t, []graph.EdgeID{dropped.ID}, edges)
+}
+
+func TestPurgeTombstones(t *testing.T) {
+	clock := newFakeClock()
+	g := NewGraph()
+	g.SetClock(clock.Now)
+	g.SetSoftDelete(true)
+
+	a, _ := g.AddNode("Person", nil)
+	b, _ := g.AddNode("Person", nil)
+	c, _ := g.AddNode("Person", nil)
+	e, _ := g.AddEdge(a.ID, b.ID, "KNOWS", nil)
+
+	require.NoError(t, g.DeleteEdge(e.ID))
+	require.NoError(t, g.DeleteNode(a.ID))
+	clock.Advance(time.Hour)
+	require.NoError(t, g.DeleteNode(c.ID))
+
+	nodes, edges := g.PurgeTombstones(clock.Now().Add(-time.Minute))
+	assert.Equal(t, []graph.NodeID{a.ID}, nodes)
+	assert.Equal(t, []graph.EdgeID{e.ID}, edges)
+	assert.Error(t, g.RestoreNode(a.ID))
+
+	remaining, _ := g.Tombstones()
+	assert.Equal(t, []graph.NodeID{c.ID}, remaining)
+}
+
+func TestSoftDelete_Persistence(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+	clock := newFakeClock()
+
+	opts := DefaultOptions()
+	opts.SweepInterval = 0
+	opts.Clock = clock.Now
+	opts.SoftDelete = true
+	opts.TombstoneRetention = time.Hour
+
+	pg, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	a, _ := pg.AddNode("Person", graph.Properties{"name": "Alice"})
+	b, _ := pg.AddNode("Person", graph.Properties{"name": "Bob"})
+	c, _ := pg.AddNode("Person", graph.Properties{"name": "Carol"})
+	e, _ := pg.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	require.NoError(t, pg.DeleteNode(a.ID))
+	require.NoError(t, pg.Snapshot())
+
+	// Logged after the snapshot
+	require.NoError(t, pg.DeleteNode(b.ID))
+	require.NoError(t, pg.RestoreNode(a.ID))
+	clock.Advance(30 * time.Minute)
+	require.NoError(t, pg.DeleteNode(c.ID))
+	require.NoError(t, pg.Close())
+
+	// Tombstones come back from both the snapshot and the WAL
+	pg, err = NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pg.NodeCount())
+	nodes, edges := pg.Tombstones()
+	assert.Equal(t, []graph.NodeID{b.ID, c.ID}, nodes)
+	assert.Equal(t, []graph.EdgeID{e.ID}, edges)
+
+	clock.Advance(30 * time.Minute)
+	purged, err := pg.PurgeTombstones()
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged, "Bob and the edge are past retention, Carol is not")
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer pg.Close()
+	nodes, edges = pg.Tombstones()
+	assert.Equal(t, []graph.NodeID{c.ID}, nodes)
+	assert.Empty(t, edges)
+	require.NoError(t, pg.RestoreNode(c.ID))
+	assert.Equal(t, 2, pg.NodeCount())
+}
+
+func TestSoftDelete_SnapshotKeepsCascadedEdges(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+	opts := DefaultOptions()
+	opts.SoftDelete = true
+
+	pg, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	a, _ := pg.AddNode("Person", nil)
+	b, _ := pg.AddNode("Person", nil)
+	kept, _ := pg.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	dropped, _ := pg.AddEdge(b.ID, a.ID, "KNOWS", nil)
+	require.NoError(t, pg.DeleteEdge(dropped.ID))
+	require.NoError(t, pg.DeleteNode(a.ID))
+	require.NoError(t, pg.Snapshot())
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer pg.Close()
+	require.NoError(t, pg.RestoreNode(a.ID))
+	_, err = pg.GetEdge(kept.ID)
+	assert.NoError(t, err)
+	_, err = pg.GetEdge(dropped.ID)
+	assert.Error(t, err)
+}
+
+func TestSoftDelete_FailedLogLeavesMemoryUntouched(t *testing.T) {
+	clock := newFakeClock()
+	opts := DefaultOptions()
+	opts.Clock = clock.Now
+	opts.SoftDelete = true
+
+	pg, err := NewPersistentGraphWithOptions(t.TempDir(), t.TempDir(), opts)
+	require.NoError(t, err)
+	a, _ := pg.AddNode("Person", nil)
+	b, _ := pg.AddNode("Person", nil)
+	c, _ := pg.AddNode("Person", nil)
+	e, _ := pg.AddEdge(a.ID, b.ID, "KNOWS", nil)
+	require.NoError(t, pg.DeleteNode(c.ID))
+	clock.Advance(2 * opts.TombstoneRetention)
+
+	// Every append fails once the log is closed
+	require.NoError(t, pg.wal.Close())
+
+	assert.Error(t, pg.DeleteNode(a.ID))
+	_, err = pg.GetNode(a.ID)
+	assert.NoError(t, err, "node deletion was not logged")
+
+	assert.Error(t, pg.DeleteEdge(e.ID))
+	_, err = pg.GetEdge(e.ID)
+	assert.NoError(t, err, "edge deletion was not logged")
+
+	assert.Error(t, pg.RestoreNode(c.ID))
+	_, err = pg.GetNode(c.ID)
+	assert.Error(t, err, "restore was not logged")
+
+	_, err = pg.PurgeTombstones()
+	assert.Error(t, err)
+	nodes, _ := pg.Tombstones()
+	assert.Equal(t, []graph.NodeID{c.ID}, nodes, "purge was not logged")
+}
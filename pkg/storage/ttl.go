@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// SetExpiry sets the time at which a node expires and logs it to WAL.
+// See Graph.SetExpiry.
+func (pg *PersistentGraph) SetExpiry(id graph.NodeID, expiresAt time.Time) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+	if _, err := pg.Graph.GetNode(id); err != nil {
+		return err
+	}
+
+	if pg.walEnabled {
+		if err := pg.wal.LogSetNodeExpiry(id, expiresAt); err != nil {
+			return fmt.Errorf("failed to log node expiry: %w", err)
+		}
+	}
+
+	return pg.Graph.SetExpiry(id, expiresAt)
+}
+
+// SetEdgeExpiry sets the time at which an edge expires and logs it to WAL
+func (pg *PersistentGraph) SetEdgeExpiry(id graph.EdgeID, expiresAt time.Time) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+	if _, err := pg.Graph.GetEdge(id); err != nil {
+		return err
+	}
+
+	if pg.walEnabled {
+		if err := pg.wal.LogSetEdgeExpiry(id, expiresAt); err != nil {
+			return fmt.Errorf("failed to log edge expiry: %w", err)
+		}
+	}
+
+	return pg.Graph.SetEdgeExpiry(id, expiresAt)
+}
+
+// SweepExpired deletes up to SweepBatchSize expired nodes and edges,
+// logging each deletion so that recovery agrees, and returns how many
+// were deleted. Edges removed along with an expired node are not counted.
+func (pg *PersistentGraph) SweepExpired() (int, error) {
+	if pg.opts.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	batch := pg.opts.SweepBatchSize
+	if batch <= 0 {
+		batch = DefaultSweepBatchSize
+	}
+
+	nodes, edges := pg.Graph.Expired(batch)
+	deleted := 0
+	for _, id := range nodes {
+		if err := pg.DeleteNode(id); err != nil {
+			return deleted, fmt.Errorf("failed to delete expired node %d: %w", id, err)
+		}
+		deleted++
+	}
+	for _, id := range edges {
+		if _, err := pg.Graph.GetEdge(id); err != nil {
+			continue // Deleted with one of its nodes
+		}
+		if err := pg.DeleteEdge(id); err != nil {
+			return deleted, fmt.Errorf("failed to delete expired edge %d: %w", id, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// startSweeper runs SweepExpired, and PurgeTombstones in soft-delete
+// mode, every interval until Close
+func (pg *PersistentGraph) startSweeper(interval time.Duration) {
+	pg.sweepStop = make(chan struct{})
+	pg.sweepWG.Add(1)
+	go func() {
+		defer pg.sweepWG.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-pg.sweepStop:
+				return
+			case <-ticker.C:
+				if _, err := pg.SweepExpired(); err != nil {
+					log.Printf("expiry sweep failed: %v", err)
+				}
+				if pg.Graph.SoftDelete() {
+					if _, err := pg.PurgeTombstones(); err != nil {
+						log.Printf("tombstone purge failed: %v", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// stopSweeper stops the background sweeper and waits for a running sweep
+func (pg *PersistentGraph) stopSweeper() {
+	pg.sweepOnce.Do(func() {
+		if pg.sweepStop != nil {
+			close(pg.sweepStop)
+		}
+	})
+	pg.sweepWG.Wait()
+}
+
+// parseExpiry decodes an expiry logged by the WAL, "" meaning none
+func parseExpiry(data interface{}) time.Time {
+	text, _ := data.(string)
+	if text == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
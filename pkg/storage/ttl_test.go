@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweepExpired(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+	clock := newFakeClock()
+
+	opts := DefaultOptions()
+	opts.SweepInterval = 0
+	opts.SweepBatchSize = 2
+	opts.Clock = clock.Now
+
+	pg, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+
+	var sessions []*graph.Node
+	for i := 0; i < 3; i++ {
+		n, err := pg.AddNode("Session", graph.Properties{"n": i})
+		require.NoError(t, err)
+		require.NoError(t, pg.SetExpiry(n.ID, clock.Now().Add(time.Hour)))
+		sessions = append(sessions, n)
+	}
+	user, _ := pg.AddNode("User", nil)
+	e, _ := pg.AddEdge(user.ID, sessions[0].ID, "OWNS", nil)
+	kept, _ := pg.AddEdge(user.ID, user.ID, "SELF", nil)
+	require.NoError(t, pg.SetEdgeExpiry(kept.ID, clock.Now().Add(2*time.Hour)))
+
+	deleted, err := pg.SweepExpired()
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	clock.Advance(time.Hour)
+	deleted, err = pg.SweepExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted, "sweeps are capped at the batch size")
+	deleted, err = pg.SweepExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	assert.Equal(t, 1, pg.NodeCount())
+	_, err = pg.GetEdge(e.ID)
+	assert.Error(t, err, "edges go with their expired nodes")
+	require.NoError(t, pg.Close())
+
+	// The deletions were logged, and the pending edge expiry survives
+	pg, err = NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer pg.Close()
+	assert.Equal(t, 1, pg.NodeCount())
+
+	clock.Advance(time.Hour)
+	edge, err := pg.GetEdge(kept.ID)
+	require.NoError(t, err)
+	assert.True(t, pg.EdgeExpired(edge))
+	deleted, err = pg.SweepExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, 0, pg.EdgeCount())
+}
+
+func TestExpiry_SnapshotRestore(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+	clock := newFakeClock()
+
+	opts := DefaultOptions()
+	opts.SweepInterval = 0
+	opts.Clock = clock.Now
+
+	pg, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	n, _ := pg.AddNode("Session", nil)
+	expiresAt := clock.Now().Add(time.Minute)
+	require.NoError(t, pg.SetExpiry(n.ID, expiresAt))
+	require.NoError(t, pg.Snapshot())
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer pg.Close()
+
+	restored, err := pg.GetNode(n.ID)
+	require.NoError(t, err)
+	assert.True(t, expiresAt.Equal(restored.ExpiresAt))
+
+	clock.Advance(time.Minute)
+	nodes, _ := pg.Expired(0)
+	assert.Equal(t, []graph.NodeID{n.ID}, nodes)
+}
+
+func TestSweeper_Background(t *testing.T) {
+	clock := newFakeClock()
+	opts := DefaultOptions()
+	opts.SweepInterval = time.Millisecond
+	opts.Clock = clock.Now
+
+	pg, err := NewPersistentGraphWithOptions(t.TempDir(), t.TempDir(), opts)
+	require.NoError(t, err)
+	defer pg.Close()
+
+	n, _ := pg.AddNode("Session", nil)
+	require.NoError(t, pg.SetExpiry(n.ID, clock.Now()))
+
+	assert.Eventually(t, func() bool { return pg.NodeCount() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestSweeper_OffByDefault(t *testing.T) {
+	pg, err := NewPersistentGraph(t.TempDir(), t.TempDir())
+	require.NoError(t, err)
+	defer pg.Close()
+	assert.Nil(t, pg.sweepStop, "no sweeper unless SweepInterval is set")
+}
+
+func TestExpiry_ReadOnly(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	n, _ := pg.AddNode("Session", nil)
+	require.NoError(t, pg.Close())
+
+	ro, err := NewPersistentGraphWithOptions(walDir, snapDir, Options{ReadOnly: true})
+	require.NoError(t, err)
+	defer ro.Close()
+	assert.ErrorIs(t, ro.SetExpiry(n.ID, time.Now()), ErrReadOnly)
+	_, err = ro.SweepExpired()
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
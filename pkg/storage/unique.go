@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// AddEdgeUnique adds an edge from source to target unless one with the
+// same label already connects them, in which case the existing edge is
+// returned unchanged. The second result reports whether the edge was
+// created. Only AddEdgeUnique and MergeEdge check for duplicates: an edge
+// added concurrently through AddEdge can still duplicate one they add.
+func (g *Graph) AddEdgeUnique(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, bool, error) {
+	g.uniqueMu.Lock()
+	defer g.uniqueMu.Unlock()
+
+	return uniqueEdge(g, source, target, label, properties, nil)
+}
+
+// MergeEdge is AddEdgeUnique, except that properties are set on an
+// existing edge, as MERGE ... ON MATCH SET would
+func (g *Graph) MergeEdge(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, bool, error) {
+	g.uniqueMu.Lock()
+	defer g.uniqueMu.Unlock()
+
+	return uniqueEdge(g, source, target, label, properties, g.UpdateEdge)
+}
+
+// UpdateEdge sets the given properties on an existing edge, keeping
+// properties that are not mentioned
+func (g *Graph) UpdateEdge(id graph.EdgeID, properties graph.Properties) error {
+	properties, err := graph.NormalizeProperties(properties)
+	if err != nil {
+		return err
+	}
+	return g.updateEdgeAt(id, properties, time.Now())
+}
+
+// updateEdgeAt applies an update and sets the edge's UpdatedAt to at. As
+// with updateNodeAt, lists are not checked for mixed types.
+func (g *Graph) updateEdgeAt(id graph.EdgeID, properties graph.Properties, at time.Time) error {
+	edge, err := g.GetEdge(id)
+	if err != nil {
+		return err
+	}
+	properties, err = graph.NormalizeStoredProperties(properties)
+	if err != nil {
+		return err
+	}
+
+	edge.Mu.Lock()
+	defer edge.Mu.Unlock()
+	edge.Properties.SetAll(properties)
+	edge.UpdatedAt = at
+	return nil
+}
+
+// AddEdgeUnique adds an edge unless one with the same label already
+// connects source to target, logging only an edge it creates. See
+// Graph.AddEdgeUnique.
+func (pg *PersistentGraph) AddEdgeUnique(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, bool, error) {
+	if pg.opts.ReadOnly {
+		return nil, false, ErrReadOnly
+	}
+	pg.Graph.uniqueMu.Lock()
+	defer pg.Graph.uniqueMu.Unlock()
+
+	return uniqueEdge(pg, source, target, label, properties, nil)
+}
+
+// MergeEdge is AddEdgeUnique, except that properties are set on an
+// existing edge and the update is logged
+func (pg *PersistentGraph) MergeEdge(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, bool, error) {
+	if pg.opts.ReadOnly {
+		return nil, false, ErrReadOnly
+	}
+	pg.Graph.uniqueMu.Lock()
+	defer pg.Graph.uniqueMu.Unlock()
+
+	return uniqueEdge(pg, source, target, label, properties, pg.UpdateEdge)
+}
+
+// UpdateEdge sets properties on an existing edge and logs to WAL
+func (pg *PersistentGraph) UpdateEdge(id graph.EdgeID, properties graph.Properties) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+
+	if _, err := pg.Graph.GetEdge(id); err != nil {
+		return err
+	}
+	properties, err := graph.NormalizeProperties(properties)
+	if err != nil {
+		return err
+	}
+
+	// Log before applying so a failed append leaves memory untouched
+	now := time.Now()
+	if pg.walEnabled {
+		if err := pg.wal.LogSetEdgeProperties(id, properties, now); err != nil {
+			return fmt.Errorf("failed to log edge update: %w", err)
+		}
+	}
+
+	return pg.Graph.updateEdgeAt(id, properties, now)
+}
+
+// edgeAdder is the part of Graph and PersistentGraph uniqueEdge needs
+type edgeAdder interface {
+	GetEdgesBetweenByLabel(src, target graph.NodeID, label string) ([]*graph.Edge, error)
+	AddEdge(source, target graph.NodeID, label string, properties graph.Properties) (*graph.Edge, error)
+}
+
+// uniqueEdge returns the first edge labeled label from source to target,
+// passing it to update with properties if update is not nil, or adds one.
+// The caller holds uniqueMu.
+func uniqueEdge(g edgeAdder, source, target graph.NodeID, label string, properties graph.Properties,
+	update func(graph.EdgeID, graph.Properties) error) (*graph.Edge, bool, error) {
+	existing, err := g.GetEdgesBetweenByLabel(source, target, label)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(existing) == 0 {
+		edge, err := g.AddEdge(source, target, label, properties)
+		if err != nil {
+			return nil, false, err
+		}
+		return edge, true, nil
+	}
+
+	edge := existing[0]
+	if update != nil && len(properties) > 0 {
+		if err := update(edge.ID, properties); err != nil {
+			return nil, false, err
+		}
+	}
+	return edge, false, nil
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddEdgeUnique(t *testing.T) {
+	g := NewGraph()
+	alice, _ := g.AddNode("Person", graph.Properties{"name": "Alice"})
+	bob, _ := g.AddNode("Person", graph.Properties{"name": "Bob"})
+
+	edge, created, err := g.AddEdgeUnique(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	again, created, err := g.AddEdgeUnique(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2024})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, edge.ID, again.ID)
+	since, _ := again.GetProperty("since")
+	assert.Equal(t, 2020, since)
+	assert.Equal(t, 1, g.EdgeCount())
+
+	// Another label or the reverse direction is a different edge
+	_, created, err = g.AddEdgeUnique(alice.ID, bob.ID, "LIKES", nil)
+	require.NoError(t, err)
+	assert.True(t, created)
+	_, created, err = g.AddEdgeUnique(bob.ID, alice.ID, "KNOWS", nil)
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// Plain AddEdge still allows parallel edges, and AddEdgeUnique then
+	// returns the first of them
+	parallel, err := g.AddEdge(alice.ID, bob.ID, "KNOWS", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, edge.ID, parallel.ID)
+	again, _, err = g.AddEdgeUnique(alice.ID, bob.ID, "KNOWS", nil)
+	require.NoError(t, err)
+	assert.Equal(t, edge.ID, again.ID)
+
+	_, _, err = g.AddEdgeUnique(alice.ID, 999, "KNOWS", nil)
+	assert.Error(t, err)
+}
+
+func TestAddEdgeUnique_Concurrent(t *testing.T) {
+	g := NewGraph()
+	a, _ := g.AddNode("Person", nil)
+	b, _ := g.AddNode("Person", nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := g.AddEdgeUnique(a.ID, b.ID, "KNOWS", nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, g.EdgeCount())
+}
+
+func TestMergeEdge(t *testing.T) {
+	g := NewGraph()
+	alice, _ := g.AddNode("Person", nil)
+	bob, _ := g.AddNode("Person", nil)
+
+	edge, created, err := g.MergeEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020, "weight": 1})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	merged, created, err := g.MergeEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2024})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, edge.ID, merged.ID)
+	assert.Equal(t, graph.Properties{"since": 2024, "weight": 1}, merged.Properties.Map())
+}
+
+func TestPersistentAddEdgeUnique(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+
+	alice, _ := pg.AddNode("Person", nil)
+	bob, _ := pg.AddNode("Person", nil)
+	edge, created, err := pg.AddEdgeUnique(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2020})
+	require.NoError(t, err)
+	require.True(t, created)
+
+	// A duplicate writes nothing to the log
+	before := pg.WALIndex()
+	_, created, err = pg.AddEdgeUnique(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2022})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, before, pg.WALIndex())
+
+	_, created, err = pg.MergeEdge(alice.ID, bob.ID, "KNOWS", graph.Properties{"since": 2024})
+	require.NoError(t, err)
+	assert.False(t, created)
+	require.NoError(t, pg.Close())
+
+	pg, err = NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg.Close()
+	assert.Equal(t, 1, pg.EdgeCount())
+	replayed, err := pg.GetEdge(edge.ID)
+	require.NoError(t, err)
+	since, _ := replayed.GetProperty("since")
+	assert.EqualValues(t, 2024, since)
+}
+
+func TestPersistentAddEdgeUnique_ReadOnly(t *testing.T) {
+	walDir, snapDir := t.TempDir(), t.TempDir()
+	pg, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	require.NoError(t, pg.Close())
+
+	opts := DefaultOptions()
+	opts.ReadOnly = true
+	ro, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	_, _, err = ro.AddEdgeUnique(1, 2, "KNOWS", nil)
+	assert.ErrorIs(t, err, ErrReadOnly)
+	_, _, err = ro.MergeEdge(1, 2, "KNOWS", nil)
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.ErrorIs(t, ro.UpdateEdge(1, nil), ErrReadOnly)
+}
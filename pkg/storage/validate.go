@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// InconsistencyKind names the invariant an Inconsistency breaks
+type InconsistencyKind string
+
+const (
+	// A node lists an outgoing (incoming) edge that does not exist or
+	// does not start (end) at it
+	DanglingOutEdge InconsistencyKind = "dangling-out-edge"
+	DanglingInEdge  InconsistencyKind = "dangling-in-edge"
+
+	// An edge is missing from the outgoing list of its source (the
+	// incoming list of its target)
+	UnlistedOutEdge InconsistencyKind = "unlisted-out-edge"
+	UnlistedInEdge  InconsistencyKind = "unlisted-in-edge"
+
+	// An edge's source or target node does not exist
+	MissingEndpoint InconsistencyKind = "missing-endpoint"
+
+	// The label index lacks a node, or lists one that does not exist or
+	// has another label
+	MissingLabelEntry InconsistencyKind = "missing-label-entry"
+	StaleLabelEntry   InconsistencyKind = "stale-label-entry"
+
+	// The node (edge) ID allocator would hand out an ID in use
+	NodeIDInUse InconsistencyKind = "node-id-in-use"
+	EdgeIDInUse InconsistencyKind = "edge-id-in-use"
+)
+
+// Inconsistency is a broken invariant of the graph's structure, as
+// reported by Validate
+type Inconsistency struct {
+	Kind  InconsistencyKind
+	Node  graph.NodeID // The node concerned; the largest node ID for NodeIDInUse
+	Edge  graph.EdgeID // The edge concerned; the largest edge ID for EdgeIDInUse
+	Label string       // The label of a label index entry
+}
+
+func (i Inconsistency) String() string {
+	switch i.Kind {
+	case DanglingOutEdge:
+		return fmt.Sprintf("node %d lists outgoing edge %d, which does not exist or does not start there", i.Node, i.Edge)
+	case DanglingInEdge:
+		return fmt.Sprintf("node %d lists incoming edge %d, which does not exist or does not end there", i.Node, i.Edge)
+	case UnlistedOutEdge:
+		return fmt.Sprintf("edge %d is missing from the outgoing edges of its source node %d", i.Edge, i.Node)
+	case UnlistedInEdge:
+		return fmt.Sprintf("edge %d is missing from the incoming edges of its target node %d", i.Edge, i.Node)
+	case MissingEndpoint:
+		return fmt.Sprintf("edge %d connects node %d, which does not exist", i.Edge, i.Node)
+	case MissingLabelEntry:
+		return fmt.Sprintf("label index :%s is missing node %d", i.Label, i.Node)
+	case StaleLabelEntry:
+		return fmt.Sprintf("label index :%s has a stale entry for node %d", i.Label, i.Node)
+	case NodeIDInUse:
+		return fmt.Sprintf("next node ID does not exceed existing node %d", i.Node)
+	case EdgeIDInUse:
+		return fmt.Sprintf("next edge ID does not exceed existing edge %d", i.Edge)
+	}
+	return string(i.Kind)
+}
+
+// Validate checks the graph's structure and returns every inconsistency
+// found, ordered by kind, node and edge: adjacency lists must name
+// existing edges with matching endpoints, every edge must be listed by
+// and connect existing nodes, the label index must match the nodes, and
+// the ID allocators must be past every ID in use. Repair fixes what it
+// reports.
+//
+// Writes made while Validate runs may be reported as inconsistencies, so
+// it is meant for a graph nothing else is writing to.
+func (g *Graph) Validate() []Inconsistency {
+	g.nodesMu.RLock()
+	nodes := make(map[graph.NodeID]*graph.Node, len(g.nodes))
+	for id, node := range g.nodes {
+		nodes[id] = node
+	}
+	g.nodesMu.RUnlock()
+	g.edgesMu.RLock()
+	edges := make(map[graph.EdgeID]*graph.Edge, len(g.edges))
+	for id, edge := range g.edges {
+		edges[id] = edge
+	}
+	g.edgesMu.RUnlock()
+
+	var found []Inconsistency
+	report := func(kind InconsistencyKind, node graph.NodeID, edge graph.EdgeID) {
+		found = append(found, Inconsistency{Kind: kind, Node: node, Edge: edge})
+	}
+
+	// Adjacency lists against the edges they name
+	type listing struct {
+		node graph.NodeID
+		edge graph.EdgeID
+	}
+	outLists := make(map[listing]bool)
+	inLists := make(map[listing]bool)
+	var maxNode graph.NodeID
+	for id, node := range nodes {
+		if id > maxNode {
+			maxNode = id
+		}
+		node.Mu.RLock()
+		out := append([]graph.EdgeID(nil), node.OutEdges...)
+		in := append([]graph.EdgeID(nil), node.InEdges...)
+		node.Mu.RUnlock()
+
+		for _, edgeID := range out {
+			outLists[listing{id, edgeID}] = true
+			if edge, ok := edges[edgeID]; !ok || edgeSource(edge) != id {
+				report(DanglingOutEdge, id, edgeID)
+			}
+		}
+		for _, edgeID := range in {
+			inLists[listing{id, edgeID}] = true
+			if edge, ok := edges[edgeID]; !ok || edgeTarget(edge) != id {
+				report(DanglingInEdge, id, edgeID)
+			}
+		}
+	}
+
+	// Edges against their endpoints
+	var maxEdge graph.EdgeID
+	for id, edge := range edges {
+		if id > maxEdge {
+			maxEdge = id
+		}
+		source, target := edgeSource(edge), edgeTarget(edge)
+		if _, ok := nodes[source]; !ok {
+			report(MissingEndpoint, source, id)
+		} else if !outLists[listing{source, id}] {
+			report(UnlistedOutEdge, source, id)
+		}
+		if _, ok := nodes[target]; !ok {
+			if target != source {
+				report(MissingEndpoint, target, id)
+			}
+		} else if !inLists[listing{target, id}] {
+			report(UnlistedInEdge, target, id)
+		}
+	}
+
+	// The label index, as CheckIndexConsistency compares it
+	indexFound, _ := g.CheckIndexConsistency()
+	for _, inc := range indexFound {
+		if inc.Kind != "label" {
+			continue
+		}
+		kind := StaleLabelEntry
+		if inc.Missing {
+			kind = MissingLabelEntry
+		}
+		found = append(found, Inconsistency{Kind: kind, Node: inc.Node, Label: inc.Label})
+	}
+
+	if len(nodes) > 0 && g.nextNodeID.Load() <= uint64(maxNode) {
+		report(NodeIDInUse, maxNode, 0)
+	}
+	if len(edges) > 0 && g.nextEdgeID.Load() <= uint64(maxEdge) {
+		report(EdgeIDInUse, 0, maxEdge)
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Node != b.Node {
+			return a.Node < b.Node
+		}
+		if a.Edge != b.Edge {
+			return a.Edge < b.Edge
+		}
+		return a.Label < b.Label
+	})
+	return found
+}
+
+func edgeSource(edge *graph.Edge) graph.NodeID {
+	edge.Mu.RLock()
+	defer edge.Mu.RUnlock()
+	return edge.Source
+}
+
+func edgeTarget(edge *graph.Edge) graph.NodeID {
+	edge.Mu.RLock()
+	defer edge.Mu.RUnlock()
+	return edge.Target
+}
+
+// repairLog records the changes a repair makes, so PersistentGraph can
+// write them to the WAL before they are applied
+type repairLog interface {
+	LogDeleteEdge(edgeID graph.EdgeID) error
+	LogLinkEdge(nodeID graph.NodeID, edgeID graph.EdgeID, outgoing bool) error
+	LogUnlinkEdge(nodeID graph.NodeID, edgeID graph.EdgeID, outgoing bool) error
+}
+
+// Repair fixes the inconsistencies found by Validate. Edges with a missing
+// endpoint are deleted, dangling entries are dropped from adjacency lists
+// and unlisted edges added to them, the indexes are rebuilt, and the ID
+// allocators are moved past the IDs in use.
+func (g *Graph) Repair(found []Inconsistency) error {
+	return g.repair(found, nil)
+}
+
+// repair applies the repairs, logging each change to log first when it is
+// not nil
+func (g *Graph) repair(found []Inconsistency, log repairLog) error {
+	reindex := false
+	deleted := make(map[graph.EdgeID]bool)
+
+	// Edges without an endpoint go first, since deleting them also
+	// removes them from the adjacency list of the endpoint that exists
+	for _, inc := range found {
+		if inc.Kind != MissingEndpoint || deleted[inc.Edge] {
+			continue
+		}
+		if log != nil {
+			if err := log.LogDeleteEdge(inc.Edge); err != nil {
+				return fmt.Errorf("failed to log edge deletion: %w", err)
+			}
+		}
+		g.removeEdge(inc.Edge)
+		deleted[inc.Edge] = true
+	}
+
+	for _, inc := range found {
+		switch inc.Kind {
+		case DanglingOutEdge, DanglingInEdge, UnlistedOutEdge, UnlistedInEdge:
+			node, err := g.GetNode(inc.Node)
+			if err != nil || deleted[inc.Edge] {
+				continue
+			}
+			outgoing := inc.Kind == DanglingOutEdge || inc.Kind == UnlistedOutEdge
+			link := inc.Kind == UnlistedOutEdge || inc.Kind == UnlistedInEdge
+			if log != nil {
+				logChange := log.LogUnlinkEdge
+				if link {
+					logChange = log.LogLinkEdge
+				}
+				if err := logChange(inc.Node, inc.Edge, outgoing); err != nil {
+					return fmt.Errorf("failed to log adjacency repair: %w", err)
+				}
+			}
+			g.setAdjacency(node, inc.Edge, outgoing, link)
+
+		case MissingLabelEntry, StaleLabelEntry:
+			reindex = true
+		case NodeIDInUse:
+			advanceID(&g.nextNodeID, uint64(inc.Node))
+		case EdgeIDInUse:
+			advanceID(&g.nextEdgeID, uint64(inc.Edge))
+		}
+	}
+
+	if reindex {
+		return g.Reindex()
+	}
+	return nil
+}
+
+// setAdjacency adds edgeID to the outgoing or incoming edges of node if
+// link is set, and otherwise removes it
+func (g *Graph) setAdjacency(node *graph.Node, edgeID graph.EdgeID, outgoing, link bool) {
+	switch {
+	case link && outgoing:
+		node.AddOutEdge(edgeID)
+	case link:
+		node.AddInEdge(edgeID)
+	case outgoing:
+		g.removeOutEdge(node, edgeID)
+	default:
+		g.removeInEdge(node, edgeID)
+	}
+}
+
+// Repair fixes the inconsistencies found by Validate as Graph.Repair
+// does, writing each change to an edge or adjacency list to the WAL
+// before applying it. The indexes and ID allocators are rebuilt on
+// recovery, so their repairs are not logged.
+func (pg *PersistentGraph) Repair(found []Inconsistency) error {
+	if pg.opts.ReadOnly {
+		return ErrReadOnly
+	}
+	defer pg.beginWrite()()
+	var log repairLog
+	if pg.walEnabled {
+		log = pg.wal
+	}
+	if err := pg.Graph.repair(found, log); err != nil {
+		return err
+	}
+	pg.markStatsDirty()
+	return nil
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptChain builds the chain a->b->c->d plus x->b, then breaks one
+// invariant of each kind Validate checks
+func corruptChain(t *testing.T, g *Graph) (nodes []*graph.Node, edges []*graph.Edge) {
+	for i := 0; i < 5; i++ {
+		node, err := g.AddNode("Person", nil)
+		require.NoError(t, err)
+		nodes = append(nodes, node)
+	}
+	a, b, c, d, x := nodes[0], nodes[1], nodes[2], nodes[3], nodes[4]
+	for _, pair := range [][2]*graph.Node{{a, b}, {b, c}, {x, b}, {c, d}} {
+		edge, err := g.AddEdge(pair[0].ID, pair[1].ID, "KNOWS", nil)
+		require.NoError(t, err)
+		edges = append(edges, edge)
+	}
+
+	a.OutEdges = append(a.OutEdges, 99)
+	c.InEdges = append(c.InEdges, edges[0].ID)
+	b.OutEdges = nil
+	d.InEdges = nil
+	delete(g.nodes, x.ID)
+	delete(g.nodesByLabel["Person"], d.ID)
+	g.nextNodeID.Store(3)
+	g.nextEdgeID.Store(2)
+	return nodes, edges
+}
+
+func TestValidate(t *testing.T) {
+	g := NewGraph()
+	assert.Empty(t, g.Validate())
+
+	nodes, edges := corruptChain(t, g)
+	a, b, c, d, x := nodes[0].ID, nodes[1].ID, nodes[2].ID, nodes[3].ID, nodes[4].ID
+	found := g.Validate()
+	assert.Equal(t, []Inconsistency{
+		{Kind: DanglingInEdge, Node: c, Edge: edges[0].ID},
+		{Kind: DanglingOutEdge, Node: a, Edge: 99},
+		{Kind: EdgeIDInUse, Edge: edges[3].ID},
+		{Kind: MissingEndpoint, Node: x, Edge: edges[2].ID},
+		{Kind: MissingLabelEntry, Node: d, Label: "Person"},
+		{Kind: NodeIDInUse, Node: d},
+		{Kind: StaleLabelEntry, Node: x, Label: "Person"},
+		{Kind: UnlistedInEdge, Node: d, Edge: edges[3].ID},
+		{Kind: UnlistedOutEdge, Node: b, Edge: edges[1].ID},
+	}, found)
+	assert.Equal(t, "node 1 lists outgoing edge 99, which does not exist or does not start there", found[1].String())
+	assert.Equal(t, "edge 3 connects node 5, which does not exist", found[3].String())
+
+	require.NoError(t, g.Repair(found))
+	assert.Empty(t, g.Validate())
+
+	assert.Equal(t, []graph.EdgeID{edges[0].ID}, nodes[0].OutEdges)
+	assert.Equal(t, []graph.EdgeID{edges[0].ID}, nodes[1].InEdges, "the edge from the missing node is deleted")
+	assert.Equal(t, []graph.EdgeID{edges[1].ID}, nodes[1].OutEdges)
+	assert.Equal(t, []graph.EdgeID{edges[1].ID}, nodes[2].InEdges)
+	assert.Equal(t, []graph.EdgeID{edges[3].ID}, nodes[3].InEdges)
+	_, err := g.GetEdge(edges[2].ID)
+	assert.Error(t, err)
+	assert.Equal(t, 4, g.LabelCount("Person"))
+
+	node, err := g.AddNode("Person", nil)
+	require.NoError(t, err)
+	assert.Equal(t, graph.NodeID(5), node.ID)
+	edge, err := g.AddEdge(a, d, "KNOWS", nil)
+	require.NoError(t, err)
+	assert.Equal(t, graph.EdgeID(5), edge.ID)
+}
+
+func TestPersistentRepair(t *testing.T) {
+	walDir := t.TempDir()
+	snapDir := t.TempDir()
+
+	// The corruption reaches the snapshot, so it survives a restart
+	pg1, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	corruptChain(t, pg1.Graph)
+	require.NoError(t, pg1.Snapshot())
+	require.NoError(t, pg1.Close())
+
+	opts := DefaultOptions()
+	opts.ReadOnly = true
+	ro, err := NewPersistentGraphWithOptions(walDir, snapDir, opts)
+	require.NoError(t, err)
+	found := ro.Validate()
+	assert.NotEmpty(t, found)
+	assert.ErrorIs(t, ro.Repair(found), ErrReadOnly)
+	require.NoError(t, ro.Close())
+
+	pg2, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	found = pg2.Validate()
+	require.NotEmpty(t, found)
+	require.NoError(t, pg2.Repair(found))
+	assert.Empty(t, pg2.Validate())
+	require.NoError(t, pg2.Close())
+
+	// The repairs were logged, so they are replayed over the snapshot
+	pg3, err := NewPersistentGraph(walDir, snapDir)
+	require.NoError(t, err)
+	defer pg3.Close()
+	assert.Empty(t, pg3.Validate())
+	assert.Equal(t, 3, pg3.EdgeCount())
+}
@@ -0,0 +1,435 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+)
+
+// binaryMagic starts every binary log. JSON logs start with '{', so the
+// first byte tells the formats apart.
+var binaryMagic = []byte("RDGWAL\x01")
+
+// maxBinaryRecord bounds the length a record header may claim, so a
+// corrupt length cannot trigger a huge allocation
+const maxBinaryRecord = 1 << 28
+
+// A binary record is the length of its body as a little-endian uint32,
+// the body, and the CRC-32C of the body. The body holds the index as a
+// uvarint, the timestamp as varint Unix nanoseconds, the op type as a
+// byte and the data as a tagged value.
+//
+// Data values decode as their JSON encoding would: numbers as float64,
+// lists as []interface{} and objects as map[string]interface{}. Property
+// maps are the exception; their values are written in the graph's binary
+// property encoding and decode to graph values such as int, graph.Point
+// and time.Time rather than their typed JSON form. graph.DecodeJSONValue
+// accepts both.
+
+// binaryOps numbers the op types in binary records. Codes are stored in
+// logs, so new op types are only ever appended. An op type missing from
+// the list is written as code 0 followed by its name.
+var binaryOps = []OpType{
+	"",
+	OpAddNode, OpAddEdge, OpDeleteNode, OpDeleteEdge, OpSetNodeProp, OpSetEdgeProp,
+	OpReconnectEdge,
+	OpCreateFTIndex, OpCreateSpatialIndex, OpCreatePropIndex,
+	OpDefineSchema, OpDropSchema,
+	OpSetNodeExpiry, OpSetEdgeExpiry,
+	OpTombstoneNode, OpTombstoneEdge, OpRestoreNode, OpPurgeTombstones,
+	OpLinkEdge, OpUnlinkEdge,
+	OpReserveIDs,
+}
+
+var binaryOpCodes = func() map[OpType]byte {
+	codes := make(map[OpType]byte, len(binaryOps))
+	for i, op := range binaryOps[1:] {
+		codes[op] = byte(i + 1)
+	}
+	return codes
+}()
+
+// Tags of the binary data encoding
+const (
+	valNull byte = iota
+	valFalse
+	valTrue
+	valUint
+	valInt
+	valFloat
+	valString
+	valList
+	valMap
+	valProperties // A property map, its values in graph's binary encoding
+	valJSON       // Any other value, JSON-encoded
+)
+
+var errShortRecord = errors.New("truncated binary record")
+
+// binaryCodec reads and writes the binary format
+type binaryCodec struct{}
+
+func (binaryCodec) format() Format { return FormatBinary }
+
+func (binaryCodec) header() []byte { return binaryMagic }
+
+func (binaryCodec) encode(entry *LogEntry) ([]byte, error) {
+	buf := make([]byte, 4, 128) // Length, filled in below
+	buf = binary.AppendUvarint(buf, entry.Index)
+	buf = binary.AppendVarint(buf, entry.Timestamp.UnixNano())
+	if code, ok := binaryOpCodes[entry.OpType]; ok {
+		buf = append(buf, code)
+	} else {
+		buf = appendBinaryString(append(buf, 0), string(entry.OpType))
+	}
+	buf, err := appendData(buf, entry.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	body := buf[4:]
+	if len(body) > maxBinaryRecord {
+		return nil, fmt.Errorf("entry of %d bytes exceeds the record limit", len(body))
+	}
+	binary.LittleEndian.PutUint32(buf, uint32(len(body)))
+	return binary.LittleEndian.AppendUint32(buf, crc32.Checksum(body, castagnoli)), nil
+}
+
+func (c binaryCodec) decode(rec []byte) (LogEntry, error) {
+	body, err := c.body(rec)
+	if err != nil {
+		return LogEntry{}, err
+	}
+
+	r := &dataReader{buf: body}
+	entry := LogEntry{
+		Index:     r.uvarint(),
+		Timestamp: time.Unix(0, r.varint()),
+	}
+	code := r.byte()
+	if code == 0 {
+		entry.OpType = OpType(r.string())
+	} else if int(code) < len(binaryOps) {
+		entry.OpType = binaryOps[code]
+	} else {
+		r.fail(fmt.Errorf("unknown op code %d", code))
+	}
+	data := r.value()
+	if r.err == nil && len(r.buf) > 0 {
+		r.fail(fmt.Errorf("%d bytes after entry data", len(r.buf)))
+	}
+	if r.err != nil {
+		return LogEntry{}, fmt.Errorf("%w: %v", ErrCorruptEntry, r.err)
+	}
+	entry.Data, _ = data.(map[string]interface{})
+	return entry, nil
+}
+
+func (c binaryCodec) verify(rec []byte) (uint64, error) {
+	var index uint64
+	if len(rec) > 4 {
+		if v, n := binary.Uvarint(rec[4:]); n > 0 {
+			index = v
+		}
+	}
+	_, err := c.body(rec)
+	return index, err
+}
+
+// body returns the body of rec after checking its length and checksum
+func (binaryCodec) body(rec []byte) ([]byte, error) {
+	if len(rec) < 8 || int(binary.LittleEndian.Uint32(rec)) != len(rec)-8 {
+		return nil, fmt.Errorf("%w: bad record length", ErrCorruptEntry)
+	}
+	body := rec[4 : len(rec)-4]
+	want := binary.LittleEndian.Uint32(rec[len(rec)-4:])
+	if sum := crc32.Checksum(body, castagnoli); sum != want {
+		return nil, fmt.Errorf("%w: checksum %08x, expected %08x", ErrCorruptEntry, sum, want)
+	}
+	return body, nil
+}
+
+func (binaryCodec) next(r *bufio.Reader) ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, err // io.EOF before a record, io.ErrUnexpectedEOF within
+	}
+	n := binary.LittleEndian.Uint32(length[:])
+	if n > maxBinaryRecord {
+		return nil, fmt.Errorf("%w: record length %d exceeds the limit", ErrCorruptEntry, n)
+	}
+
+	rec := make([]byte, 4+int(n)+4)
+	copy(rec, length[:])
+	if _, err := io.ReadFull(r, rec[4:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return rec, nil
+}
+
+func appendBinaryString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// appendData appends a value of an entry's data. Nil maps and slices are
+// written as null, as JSON writes them.
+func appendData(buf []byte, v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case nil:
+		return append(buf, valNull), nil
+	case bool:
+		if val {
+			return append(buf, valTrue), nil
+		}
+		return append(buf, valFalse), nil
+	case string:
+		return appendBinaryString(append(buf, valString), val), nil
+	case graph.NodeID:
+		return binary.AppendUvarint(append(buf, valUint), uint64(val)), nil
+	case graph.EdgeID:
+		return binary.AppendUvarint(append(buf, valUint), uint64(val)), nil
+	case uint64:
+		return binary.AppendUvarint(append(buf, valUint), val), nil
+	case int:
+		return binary.AppendVarint(append(buf, valInt), int64(val)), nil
+	case int64:
+		return binary.AppendVarint(append(buf, valInt), val), nil
+	case float64:
+		return binary.LittleEndian.AppendUint64(append(buf, valFloat), math.Float64bits(val)), nil
+	case graph.Properties:
+		if val == nil {
+			return append(buf, valNull), nil
+		}
+		if encoded, err := appendProperties(append(buf, valProperties), val); err == nil {
+			return encoded, nil
+		}
+	case map[string]interface{}:
+		if val == nil {
+			return append(buf, valNull), nil
+		}
+		buf = binary.AppendUvarint(append(buf, valMap), uint64(len(val)))
+		for k, elem := range val {
+			var err error
+			if buf, err = appendData(appendBinaryString(buf, k), elem); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]string:
+		if val == nil {
+			return append(buf, valNull), nil
+		}
+		buf = binary.AppendUvarint(append(buf, valMap), uint64(len(val)))
+		for k, elem := range val {
+			buf = appendBinaryString(append(appendBinaryString(buf, k), valString), elem)
+		}
+		return buf, nil
+	case []interface{}:
+		if val == nil {
+			return append(buf, valNull), nil
+		}
+		buf = binary.AppendUvarint(append(buf, valList), uint64(len(val)))
+		for _, elem := range val {
+			var err error
+			if buf, err = appendData(buf, elem); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case []string:
+		if val == nil {
+			return append(buf, valNull), nil
+		}
+		buf = binary.AppendUvarint(append(buf, valList), uint64(len(val)))
+		for _, elem := range val {
+			buf = appendBinaryString(append(buf, valString), elem)
+		}
+		return buf, nil
+	case []graph.NodeID:
+		if val == nil {
+			return append(buf, valNull), nil
+		}
+		buf = binary.AppendUvarint(append(buf, valList), uint64(len(val)))
+		for _, id := range val {
+			buf = binary.AppendUvarint(append(buf, valUint), uint64(id))
+		}
+		return buf, nil
+	case []graph.EdgeID:
+		if val == nil {
+			return append(buf, valNull), nil
+		}
+		buf = binary.AppendUvarint(append(buf, valList), uint64(len(val)))
+		for _, id := range val {
+			buf = binary.AppendUvarint(append(buf, valUint), uint64(id))
+		}
+		return buf, nil
+	}
+
+	// Anything else, including properties the binary property encoding
+	// does not cover, is stored as JSON
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	buf = binary.AppendUvarint(append(buf, valJSON), uint64(len(encoded)))
+	return append(buf, encoded...), nil
+}
+
+func appendProperties(buf []byte, props graph.Properties) ([]byte, error) {
+	buf = binary.AppendUvarint(buf, uint64(len(props)))
+	for k, v := range props {
+		var err error
+		if buf, err = graph.AppendBinaryValue(appendBinaryString(buf, k), v); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// dataReader decodes the body of a binary record. The first error sticks;
+// later reads return zero values.
+type dataReader struct {
+	buf []byte
+	err error
+}
+
+func (r *dataReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *dataReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail(errShortRecord)
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *dataReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail(errShortRecord)
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *dataReader) bytes(n uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if uint64(len(r.buf)) < n {
+		r.fail(errShortRecord)
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *dataReader) byte() byte {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *dataReader) string() string {
+	return string(r.bytes(r.uvarint()))
+}
+
+// count reads a length and rejects one larger than the remaining input
+func (r *dataReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		r.fail(errShortRecord)
+		return 0
+	}
+	return int(n)
+}
+
+func (r *dataReader) value() interface{} {
+	tag := r.byte()
+	if r.err != nil {
+		return nil
+	}
+	switch tag {
+	case valNull:
+		return nil
+	case valFalse:
+		return false
+	case valTrue:
+		return true
+	case valUint:
+		return float64(r.uvarint())
+	case valInt:
+		return float64(r.varint())
+	case valFloat:
+		b := r.bytes(8)
+		if b == nil {
+			return nil
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case valString:
+		return r.string()
+	case valList:
+		list := make([]interface{}, r.count())
+		for i := range list {
+			list[i] = r.value()
+		}
+		return list
+	case valMap:
+		n := r.count()
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n && r.err == nil; i++ {
+			k := r.string()
+			m[k] = r.value()
+		}
+		return m
+	case valProperties:
+		n := r.count()
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n && r.err == nil; i++ {
+			k := r.string()
+			v, rest, err := graph.DecodeBinaryValue(r.buf)
+			if err != nil {
+				r.fail(fmt.Errorf("property %s: %w", k, err))
+				break
+			}
+			m[k], r.buf = v, rest
+		}
+		return m
+	case valJSON:
+		var v interface{}
+		if err := json.Unmarshal(r.bytes(r.uvarint()), &v); err != nil {
+			r.fail(err)
+		}
+		return v
+	}
+	r.fail(fmt.Errorf("unknown value tag %d", tag))
+	return nil
+}
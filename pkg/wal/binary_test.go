@@ -0,0 +1,244 @@
+package wal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logEveryOp writes one entry of each kind
+func logEveryOp(t *testing.T, w *WAL) {
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, w.LogAddNode(1, "Person", graph.Properties{
+		"name":     "Alice",
+		"age":      30,
+		"score":    4.5,
+		"active":   true,
+		"home":     graph.Point{Lat: 52.5, Lon: 13.4},
+		"born":     time.Date(1990, 5, 1, 12, 0, 0, 0, time.UTC),
+		"tags":     []graph.PropertyValue{"a", 1},
+		"address":  graph.Properties{"city": "Berlin"},
+		"visits":   graph.Histogram{Buckets: []float64{0, 10, 20}, Counts: []int64{3, 4}},
+		"nickname": nil,
+	}, time.Time{}))
+	require.NoError(t, w.LogAddNode(2, "Person", nil, time.Time{}))
+	require.NoError(t, w.LogAddEdge(1, 1, 2, "KNOWS", graph.Properties{"since": 2020}, time.Time{}))
+	require.NoError(t, w.LogSetNodeProperties(1, graph.Properties{"age": 31}, expires))
+	require.NoError(t, w.LogDefineSchema(SchemaDef{Label: "Person", Properties: map[string]string{"age": "int"}, Required: []string{"name"}}))
+	require.NoError(t, w.LogSetNodeExpiry(2, expires))
+	require.NoError(t, w.LogPurgeTombstones([]graph.NodeID{3, 4}, nil))
+	require.NoError(t, w.LogLinkEdge(1, 1, true))
+	require.NoError(t, w.LogDeleteEdge(1))
+	require.NoError(t, w.LogReserveIDs(1001, 1001))
+	_, err := w.Append("CUSTOM_OP", map[string]interface{}{"nested": map[string]interface{}{"list": []interface{}{1.5, "x"}}})
+	require.NoError(t, err)
+}
+
+// replayAll returns every entry with the property values decoded, so that
+// both formats compare equal
+func replayAll(t *testing.T, w *WAL) []LogEntry {
+	var entries []LogEntry
+	require.NoError(t, w.Replay(func(entry LogEntry) error {
+		if props, ok := entry.Data["properties"].(map[string]interface{}); ok && entry.OpType != OpDefineSchema {
+			decoded := make(map[string]interface{}, len(props))
+			for k, v := range props {
+				decoded[k] = graph.DecodeJSONValue(v)
+			}
+			entry.Data["properties"] = decoded
+		}
+		entry.Timestamp = entry.Timestamp.UTC().Round(0)
+		entries = append(entries, entry)
+		return nil
+	}))
+	return entries
+}
+
+func TestBinaryFormat_MatchesJSON(t *testing.T) {
+	jsonDir, binaryDir := t.TempDir(), t.TempDir()
+	jsonWAL, err := NewWAL(jsonDir)
+	require.NoError(t, err)
+	defer jsonWAL.Close()
+	binaryWAL, err := NewWALWithOptions(binaryDir, Options{Format: FormatBinary})
+	require.NoError(t, err)
+	defer binaryWAL.Close()
+
+	logEveryOp(t, jsonWAL)
+	logEveryOp(t, binaryWAL)
+	assert.Equal(t, FormatJSON, jsonWAL.Format())
+	assert.Equal(t, FormatBinary, binaryWAL.Format())
+
+	fromJSON, fromBinary := replayAll(t, jsonWAL), replayAll(t, binaryWAL)
+	require.Len(t, fromBinary, 11)
+	for i := range fromJSON {
+		// Timestamps differ between the logs; everything else decodes alike
+		fromJSON[i].Timestamp, fromBinary[i].Timestamp = time.Time{}, time.Time{}
+		assert.Equal(t, fromJSON[i], fromBinary[i], "entry %d", i+1)
+	}
+	assert.Equal(t, map[string]interface{}{"nodes_until": 1001.0, "edges_until": 1001.0}, fromBinary[9].Data)
+	assert.Equal(t, OpType("CUSTOM_OP"), fromBinary[10].OpType)
+
+	// The binary log is marked by its header and is smaller
+	data, err := os.ReadFile(filepath.Join(binaryDir, "wal.log"))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, binaryMagic))
+	_, jsonSize := jsonWAL.Size()
+	_, binarySize := binaryWAL.Size()
+	assert.Equal(t, int64(len(data)), binarySize)
+	assert.Less(t, binarySize, jsonSize/2)
+	assert.Empty(t, checkIntegrity(t, binaryWAL))
+}
+
+func TestBinaryFormat_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWALWithOptions(dir, Options{Format: FormatBinary})
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", graph.Properties{"n": i}, time.Time{}))
+	}
+	require.NoError(t, w.Close())
+
+	// The format is detected, whatever the options say
+	w, err = NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, FormatBinary, w.Format())
+	assert.Equal(t, uint64(3), w.GetCurrentIndex())
+	entries, _ := w.Size()
+	assert.Equal(t, 3, entries)
+
+	ch := w.Subscribe(DefaultSubscribeOptions())
+	require.NoError(t, w.LogAddNode(4, "Person", graph.Properties{"n": 1<<60 + 1}, time.Time{}))
+	entry := <-ch
+	assert.Equal(t, 4.0, entry.Data["node_id"])
+
+	// Integers keep every bit, which JSON numbers do not
+	assert.Equal(t, 1<<60+1, graph.DecodeJSONValue(entry.Data["properties"].(map[string]interface{})["n"]))
+
+	readOnly, err := NewReadOnlyWAL(dir)
+	require.NoError(t, err)
+	assert.Equal(t, FormatBinary, readOnly.Format())
+	assert.Len(t, replayAll(t, readOnly), 4)
+}
+
+func TestBinaryFormat_TruncateConverts(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)}, time.Time{}))
+	}
+	require.NoError(t, w.Close())
+
+	// An existing JSON log is appended to as JSON until Truncate rewrites it
+	w, err = NewWALWithOptions(dir, Options{Format: FormatBinary})
+	require.NoError(t, err)
+	defer w.Close()
+	assert.Equal(t, FormatJSON, w.Format())
+	before := replayAll(t, w)
+
+	require.NoError(t, w.Truncate(3))
+	assert.Equal(t, FormatBinary, w.Format())
+	require.NoError(t, w.LogAddNode(6, "Person", graph.Properties{"name": "Person6"}, time.Time{}))
+
+	after := replayAll(t, w)
+	require.Len(t, after, 4)
+	for i, entry := range after[:3] {
+		assert.Equal(t, before[i+2].Index, entry.Index)
+		assert.Equal(t, before[i+2].Data, entry.Data)
+		assert.True(t, before[i+2].Timestamp.Equal(entry.Timestamp))
+	}
+	assert.Equal(t, uint64(6), after[3].Index)
+
+	entries, size := w.Size()
+	info, err := os.Stat(filepath.Join(dir, "wal.log"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, entries)
+	assert.Equal(t, info.Size(), size)
+	assert.Empty(t, checkIntegrity(t, w))
+}
+
+func TestBinaryFormat_Corruption(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWALWithOptions(dir, Options{Format: FormatBinary})
+	require.NoError(t, err)
+	defer w.Close()
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)}, time.Time{}))
+	}
+
+	// A damaged record fails its checksum and is skipped by the check
+	corruptEntry(t, dir, "Person3", "Persoo3")
+	found := checkIntegrity(t, w)
+	require.Len(t, found, 1)
+	assert.Equal(t, uint64(3), found[0].index)
+	assert.ErrorIs(t, found[0].err, ErrCorruptEntry)
+	err = w.Replay(func(LogEntry) error { return nil })
+	assert.ErrorIs(t, err, ErrCorruptEntry)
+	corruptEntry(t, dir, "Persoo3", "Person3")
+
+	// A record cut short ends ReplayAfter without an error
+	path := filepath.Join(dir, "wal.log")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+	last, err := w.ReplayAfter(0, func(LogEntry) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), last)
+	assert.Error(t, w.Replay(func(LogEntry) error { return nil }))
+	found = checkIntegrity(t, w)
+	require.Len(t, found, 1)
+	assert.Equal(t, uint64(5), found[0].index)
+}
+
+// BenchmarkAppendAddNode appends AddNode entries in batches of 1000, as a
+// bulk load would, and replays them
+func BenchmarkAppendAddNode(b *testing.B) {
+	const batchSize = 1000
+	batch := make([]LogEntry, batchSize)
+	for i := range batch {
+		batch[i] = AddNodeEntry(graph.NodeID(i+1), "Person", graph.Properties{
+			"name":  fmt.Sprintf("Person%d", i),
+			"age":   20 + i%50,
+			"email": fmt.Sprintf("person%d@example.com", i),
+			"score": float64(i) / 7,
+		}, time.Time{})
+	}
+
+	for _, format := range []Format{FormatJSON, FormatBinary} {
+		b.Run(format.String()+"/append", func(b *testing.B) {
+			w, err := NewWALWithOptions(b.TempDir(), Options{Format: format})
+			require.NoError(b, err)
+			defer w.Close()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := w.AppendBatch(batch)
+				require.NoError(b, err)
+			}
+			b.StopTimer()
+			entries, size := w.Size()
+			b.ReportMetric(float64(size)/float64(entries), "bytes/entry")
+			b.ReportMetric(float64(entries)/b.Elapsed().Seconds(), "entries/s")
+		})
+		b.Run(format.String()+"/replay", func(b *testing.B) {
+			w, err := NewWALWithOptions(b.TempDir(), Options{Format: format})
+			require.NoError(b, err)
+			defer w.Close()
+			for i := 0; i < 100; i++ {
+				_, err := w.AppendBatch(batch)
+				require.NoError(b, err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, w.Replay(func(LogEntry) error { return nil }))
+			}
+			b.ReportMetric(float64(100*batchSize*b.N)/b.Elapsed().Seconds(), "entries/s")
+		})
+	}
+}
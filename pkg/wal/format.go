@@ -0,0 +1,196 @@
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the encoding of the entries in the log file
+type Format int
+
+const (
+	// FormatJSON writes each entry as a line of JSON. It is the default,
+	// since the log can then be read with standard tools.
+	FormatJSON Format = iota
+
+	// FormatBinary writes length-prefixed binary records after a magic
+	// header. It is smaller and faster to write and replay than JSON.
+	FormatBinary
+)
+
+func (f Format) String() string {
+	if f == FormatBinary {
+		return "binary"
+	}
+	return "json"
+}
+
+// ParseFormat parses a format name as written by Format.String
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return FormatJSON, nil
+	case "binary":
+		return FormatBinary, nil
+	}
+	return FormatJSON, fmt.Errorf("unknown WAL format %q (want json or binary)", name)
+}
+
+// Options configures a WAL
+type Options struct {
+	// Format is used for a new or empty log and when Truncate rewrites
+	// the log. An existing log is appended to in the format it was
+	// written in.
+	Format Format
+}
+
+// DefaultOptions returns the options used by NewWAL
+func DefaultOptions() Options {
+	return Options{Format: FormatJSON}
+}
+
+// codec encodes and decodes the records of one log format. A record is
+// an entry as written to the log, with its framing and checksum.
+type codec interface {
+	format() Format
+
+	// header is written at the start of the log
+	header() []byte
+
+	encode(entry *LogEntry) ([]byte, error)
+	decode(rec []byte) (LogEntry, error)
+
+	// verify checks the record's checksum and returns its index, or 0 if
+	// the index cannot be decoded. A mismatch is reported as
+	// ErrCorruptEntry.
+	verify(rec []byte) (uint64, error)
+
+	// next reads the next record. It returns io.EOF after the last record
+	// and io.ErrUnexpectedEOF for a final record that is only partly
+	// written.
+	next(r *bufio.Reader) ([]byte, error)
+}
+
+func codecFor(f Format) codec {
+	if f == FormatBinary {
+		return binaryCodec{}
+	}
+	return jsonCodec{}
+}
+
+// logReader reads the records of a log file in the format it was written in
+type logReader struct {
+	codec
+	r *bufio.Reader
+}
+
+// newLogReader detects the format of the log read from r by its header.
+// An empty log reads as JSON.
+func newLogReader(r io.Reader) (*logReader, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	first, err := br.Peek(1)
+	if err == io.EOF || (err == nil && first[0] != binaryMagic[0]) {
+		return &logReader{codec: jsonCodec{}, r: br}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || !bytes.Equal(magic, binaryMagic) {
+		return nil, fmt.Errorf("%w: bad binary log header", ErrCorruptEntry)
+	}
+	return &logReader{codec: binaryCodec{}, r: br}, nil
+}
+
+// openLog opens the log file in dir for reading. It returns an
+// os.IsNotExist error if there is none.
+func openLog(dir string) (*os.File, *logReader, error) {
+	file, err := os.Open(filepath.Join(dir, "wal.log"))
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := newLogReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to read WAL header: %w", err)
+	}
+	return file, reader, nil
+}
+
+// nextRecord returns the next record as written
+func (lr *logReader) nextRecord() ([]byte, error) {
+	return lr.codec.next(lr.r)
+}
+
+// nextEntry returns the next entry, decoded
+func (lr *logReader) nextEntry() (LogEntry, error) {
+	rec, err := lr.codec.next(lr.r)
+	if err != nil {
+		return LogEntry{}, err
+	}
+	return lr.codec.decode(rec)
+}
+
+// jsonCodec reads and writes entries as lines of JSON (see record)
+type jsonCodec struct{}
+
+func (jsonCodec) format() Format { return FormatJSON }
+
+func (jsonCodec) header() []byte { return nil }
+
+func (jsonCodec) encode(entry *LogEntry) ([]byte, error) {
+	encoded, err := encodeEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	return append(encoded, '\n'), nil
+}
+
+func (jsonCodec) decode(rec []byte) (LogEntry, error) {
+	var entry LogEntry
+	err := json.Unmarshal(rec, &entry)
+	return entry, err
+}
+
+func (jsonCodec) verify(rec []byte) (uint64, error) {
+	var r record
+	if err := json.Unmarshal(rec, &r); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrCorruptEntry, err)
+	}
+	// Entries written before checksums were added are not checked
+	if r.Checksum != "" {
+		if sum := r.sum(); sum != r.Checksum {
+			return r.Index, fmt.Errorf("%w: checksum %s, expected %s", ErrCorruptEntry, sum, r.Checksum)
+		}
+	}
+	return r.Index, nil
+}
+
+// next returns the next non-blank line. A final line without a newline
+// counts as partly written unless it is complete JSON.
+func (jsonCodec) next(r *bufio.Reader) ([]byte, error) {
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return nil, io.EOF
+			}
+			continue
+		}
+		if err != nil && !json.Valid(line) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return line, nil
+	}
+}
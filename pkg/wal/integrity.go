@@ -0,0 +1,166 @@
+package wal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrCorruptEntry is reported by CheckIntegrity for an entry whose
+// checksum does not match its contents or that cannot be decoded
+var ErrCorruptEntry = errors.New("corrupt WAL entry")
+
+// castagnoli is the CRC-32 table for entry checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// record is a log line as written. Data is kept as encoded, since the
+// checksum covers its exact bytes.
+type record struct {
+	Index     uint64          `json:"index"`
+	Timestamp time.Time       `json:"timestamp"`
+	OpType    OpType          `json:"op_type"`
+	Data      json.RawMessage `json:"data"`
+	Checksum  string          `json:"checksum,omitempty"`
+}
+
+// sum returns the CRC-32C of the record's fields other than Checksum, in
+// hex
+func (r *record) sum() string {
+	h := crc32.New(castagnoli)
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00", r.Index, r.Timestamp.Format(time.RFC3339Nano), r.OpType)
+	h.Write(r.Data)
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// encodeEntry encodes entry as a log line, without the newline, with its
+// checksum
+func encodeEntry(entry *LogEntry) ([]byte, error) {
+	data, err := json.Marshal(entry.Data)
+	if err != nil {
+		return nil, err
+	}
+	rec := record{
+		Index:     entry.Index,
+		Timestamp: entry.Timestamp,
+		OpType:    entry.OpType,
+		Data:      data,
+	}
+	rec.Checksum = rec.sum()
+	return json.Marshal(&rec)
+}
+
+// CheckIntegrity verifies the checksum of every entry in the log when it
+// is called, passing each corrupt entry to onCorrupt. An entry that cannot
+// be decoded is reported with the index following the last entry that
+// could. Entries written before checksums were added are not checked. A
+// cut-short entry, or a binary record whose length is damaged, is
+// reported once, since the entries after it cannot be found.
+//
+// Writers are blocked only while the length of the log is read: entries
+// appended during the check are left for the next one, and a check that
+// overlaps Truncate reports nothing, since the log it read was rewritten.
+func (w *WAL) CheckIntegrity(onCorrupt func(index uint64, err error)) error {
+	path := filepath.Join(w.dir, "wal.log")
+
+	w.mu.Lock()
+	info, err := os.Stat(path)
+	truncations := w.truncations
+	w.mu.Unlock()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	readFile, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer readFile.Close()
+	reader, err := newLogReader(io.LimitReader(readFile, info.Size()))
+	if err != nil {
+		return fmt.Errorf("failed to read WAL: %w", err)
+	}
+
+	type corruption struct {
+		index uint64
+		err   error
+	}
+	var found []corruption
+	var last uint64
+	for {
+		rec, err := reader.nextRecord()
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF || errors.Is(err, ErrCorruptEntry) {
+			// The rest of the log cannot be split into entries
+			if err == io.ErrUnexpectedEOF {
+				err = fmt.Errorf("%w: entry is cut short", ErrCorruptEntry)
+			}
+			found = append(found, corruption{last + 1, err})
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read WAL: %w", err)
+		}
+
+		index, err := reader.verify(rec)
+		if index == 0 {
+			index = last + 1
+		}
+		if err != nil {
+			found = append(found, corruption{index, err})
+		}
+		if index > last {
+			last = index
+		}
+	}
+
+	w.mu.Lock()
+	truncated := w.truncations != truncations
+	w.mu.Unlock()
+	if truncated {
+		return nil
+	}
+	for _, c := range found {
+		onCorrupt(c.index, c.err)
+	}
+	return nil
+}
+
+// StartIntegrityScanner runs CheckIntegrity every interval in the
+// background until the returned function is called, which waits for a
+// check in progress to finish. Corruption is reported to onCorrupt on
+// every check that finds it; a log that cannot be read is reported with
+// index 0.
+func (w *WAL) StartIntegrityScanner(interval time.Duration, onCorrupt func(index uint64, err error)) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.CheckIntegrity(onCorrupt); err != nil {
+					onCorrupt(0, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
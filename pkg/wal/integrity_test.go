@@ -0,0 +1,168 @@
+package wal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// corruption is one entry reported by CheckIntegrity
+type corruption struct {
+	index uint64
+	err   error
+}
+
+// checkIntegrity runs CheckIntegrity and returns what it reported
+func checkIntegrity(t *testing.T, w *WAL) []corruption {
+	var found []corruption
+	require.NoError(t, w.CheckIntegrity(func(index uint64, err error) {
+		found = append(found, corruption{index, err})
+	}))
+	return found
+}
+
+// corruptEntry overwrites the first occurrence of old in the log with new,
+// which must be as long
+func corruptEntry(t *testing.T, dir, old, new string) {
+	path := filepath.Join(dir, "wal.log")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	offset := bytes.Index(data, []byte(old))
+	require.GreaterOrEqual(t, offset, 0, "%q not in log", old)
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteAt([]byte(new), int64(offset))
+	require.NoError(t, err)
+}
+
+func TestCheckIntegrity(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)}, time.Time{}))
+	}
+	assert.Empty(t, checkIntegrity(t, w))
+
+	corruptEntry(t, dir, `"Person3"`, `"Persoo3"`)
+	found := checkIntegrity(t, w)
+	require.Len(t, found, 1)
+	assert.Equal(t, uint64(3), found[0].index)
+	assert.ErrorIs(t, found[0].err, ErrCorruptEntry)
+
+	// A line that no longer decodes is reported after the last good entry
+	corruptEntry(t, dir, `{"index":5`, `{"index"?5`)
+	found = checkIntegrity(t, w)
+	require.Len(t, found, 2)
+	assert.Equal(t, uint64(3), found[0].index)
+	assert.Equal(t, uint64(5), found[1].index)
+	assert.ErrorIs(t, found[1].err, ErrCorruptEntry)
+}
+
+func TestCheckIntegrity_EntriesWithoutChecksum(t *testing.T) {
+	dir := t.TempDir()
+	line := `{"index":1,"timestamp":"2024-01-01T00:00:00Z","op_type":"ADD_NODE","data":{"id":1,"label":"Person"}}` + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "wal.log"), []byte(line), 0644))
+
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+	require.NoError(t, w.LogAddNode(graph.NodeID(2), "Person", nil, time.Time{}))
+
+	assert.Empty(t, checkIntegrity(t, w))
+}
+
+func TestCheckIntegrity_AfterTruncate(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", graph.Properties{"id": 1<<60 + i}, time.Time{}))
+	}
+	require.NoError(t, w.Truncate(3))
+	assert.Empty(t, checkIntegrity(t, w))
+
+	var indexes []uint64
+	require.NoError(t, w.Replay(func(entry LogEntry) error {
+		indexes = append(indexes, entry.Index)
+		return nil
+	}))
+	assert.Equal(t, []uint64{3, 4, 5}, indexes)
+}
+
+func TestIntegrityScanner(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+
+	var mu sync.Mutex
+	var found []corruption
+	reported := make(chan struct{}, 100)
+	stop := w.StartIntegrityScanner(5*time.Millisecond, func(index uint64, err error) {
+		mu.Lock()
+		found = append(found, corruption{index, err})
+		mu.Unlock()
+		select {
+		case reported <- struct{}{}:
+		default:
+		}
+	})
+
+	// Writers keep appending while another goroutine corrupts an entry
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 200; i++ {
+			assert.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", graph.Properties{"name": fmt.Sprintf("Person%d", i)}, time.Time{}))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for w.GetCurrentIndex() < 10 {
+			time.Sleep(time.Millisecond)
+		}
+		corruptEntry(t, dir, `"Person7"`, `"Persoo7"`)
+	}()
+	wg.Wait()
+
+	// The scanner keeps reporting the entry on later scans
+	for i := 0; i < 2; i++ {
+		select {
+		case <-reported:
+		case <-time.After(5 * time.Second):
+			t.Fatal("corruption was not reported")
+		}
+	}
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.GreaterOrEqual(t, len(found), 2)
+	for _, c := range found {
+		assert.Equal(t, uint64(7), c.index)
+		assert.ErrorIs(t, c.err, ErrCorruptEntry)
+	}
+
+	// Nothing is reported once the scanner is stopped
+	n := len(found)
+	mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	assert.Len(t, found, n)
+}
@@ -2,10 +2,18 @@
 package wal
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
@@ -24,8 +32,57 @@ type Snapshot struct {
 	Metadata SnapshotMetadata `json:"metadata"`
 	Nodes    []*graph.Node    `json:"nodes"`
 	Edges    []*graph.Edge    `json:"edges"`
+	Catalog  *Catalog         `json:"catalog,omitempty"`
+
+	Tombstones *Tombstones `json:"tombstones,omitempty"`
+}
+
+// Tombstones holds soft-deleted nodes and edges awaiting restore or purge
+type Tombstones struct {
+	Nodes []*graph.Node `json:"nodes,omitempty"`
+	Edges []*graph.Edge `json:"edges,omitempty"`
+
+	// Cascaded lists the edges deleted along with one of their nodes
+	Cascaded []graph.EdgeID `json:"cascaded,omitempty"`
+}
+
+// Catalog holds schema objects, and other state logged in the WAL, that
+// must survive WAL truncation
+type Catalog struct {
+	FullTextIndexes []IndexDef  `json:"fulltext_indexes,omitempty"`
+	SpatialIndexes  []IndexDef  `json:"spatial_indexes,omitempty"`
+	PropertyIndexes []IndexDef  `json:"property_indexes,omitempty"`
+	Schemas         []SchemaDef `json:"schemas,omitempty"`
+
+	// Bounds of the ID reservation at snapshot time (see OpReserveIDs)
+	NodesUntil uint64 `json:"nodes_until,omitempty"`
+	EdgesUntil uint64 `json:"edges_until,omitempty"`
+}
+
+// IndexDef identifies an index by label and property name
+type IndexDef struct {
+	Label    string `json:"label"`
+	Property string `json:"property"`
 }
 
+// SchemaDef declares property types for the nodes of one label
+type SchemaDef struct {
+	Label      string            `json:"label"`
+	Properties map[string]string `json:"properties,omitempty"` // Property -> type tag
+	Required   []string          `json:"required,omitempty"`
+}
+
+// binarySnapshotMagic starts every binary snapshot. JSON snapshots start
+// with '{', so the first byte tells the formats apart.
+var binarySnapshotMagic = []byte{0x52, 0x44, 0x47} // "RDG"
+
+// Names of the copies of the most recent snapshot. Only one exists at a
+// time, matching the format the snapshot was written in.
+const (
+	latestJSONName   = "snapshot-latest.json"
+	latestBinaryName = "snapshot-latest.bin"
+)
+
 // SnapshotManager handles snapshot creation and loading
 type SnapshotManager struct {
 	dir string
@@ -46,6 +103,52 @@ func (sm *SnapshotManager) CreateSnapshot(
 	nodes map[graph.NodeID]*graph.Node,
 	edges map[graph.EdgeID]*graph.Edge,
 ) error {
+	return sm.CreateSnapshotWithCatalog(walIndex, nodes, edges, nil)
+}
+
+// CreateSnapshotWithCatalog saves the graph state together with its catalog
+func (sm *SnapshotManager) CreateSnapshotWithCatalog(
+	walIndex uint64,
+	nodes map[graph.NodeID]*graph.Node,
+	edges map[graph.EdgeID]*graph.Edge,
+	catalog *Catalog,
+) error {
+	return sm.CreateSnapshotWithTombstones(walIndex, nodes, edges, catalog, nil)
+}
+
+// CreateSnapshotWithTombstones saves the graph state with its catalog and
+// soft-deleted entities. tombstones may be nil.
+func (sm *SnapshotManager) CreateSnapshotWithTombstones(
+	walIndex uint64,
+	nodes map[graph.NodeID]*graph.Node,
+	edges map[graph.EdgeID]*graph.Edge,
+	catalog *Catalog,
+	tombstones *Tombstones,
+) error {
+	return sm.saveSnapshot(newSnapshot(walIndex, nodes, edges, catalog, tombstones), false)
+}
+
+// CreateBinarySnapshot saves what CreateSnapshotWithTombstones does in the
+// gob-encoded binary format, which is smaller and faster to write and load
+// than JSON. catalog and tombstones may be nil.
+func (sm *SnapshotManager) CreateBinarySnapshot(
+	walIndex uint64,
+	nodes map[graph.NodeID]*graph.Node,
+	edges map[graph.EdgeID]*graph.Edge,
+	catalog *Catalog,
+	tombstones *Tombstones,
+) error {
+	return sm.saveSnapshot(newSnapshot(walIndex, nodes, edges, catalog, tombstones), true)
+}
+
+// newSnapshot collects the graph state into a Snapshot
+func newSnapshot(
+	walIndex uint64,
+	nodes map[graph.NodeID]*graph.Node,
+	edges map[graph.EdgeID]*graph.Edge,
+	catalog *Catalog,
+	tombstones *Tombstones,
+) *Snapshot {
 	// Convert maps to slices
 	nodeSlice := make([]*graph.Node, 0, len(nodes))
 	for _, node := range nodes {
@@ -57,65 +160,129 @@ func (sm *SnapshotManager) CreateSnapshot(
 		edgeSlice = append(edgeSlice, edge)
 	}
 
-	snapshot := Snapshot{
+	return &Snapshot{
 		Metadata: SnapshotMetadata{
 			Index:     walIndex,
 			Timestamp: time.Now(),
 			NodeCount: len(nodeSlice),
 			EdgeCount: len(edgeSlice),
 		},
-		Nodes: nodeSlice,
-		Edges: edgeSlice,
+		Nodes:      nodeSlice,
+		Edges:      edgeSlice,
+		Catalog:    catalog,
+		Tombstones: tombstones,
+	}
+}
+
+// saveSnapshot writes snapshot to a new file and makes it the latest
+func (sm *SnapshotManager) saveSnapshot(snapshot *Snapshot, binary bool) error {
+	ext, latestName, staleName := ".json", latestJSONName, latestBinaryName
+	if binary {
+		ext, latestName, staleName = ".bin", latestBinaryName, latestJSONName
 	}
 
 	// Use timestamp-based filename
-	filename := fmt.Sprintf("snapshot-%d-%d.json", walIndex, time.Now().Unix())
+	filename := fmt.Sprintf("snapshot-%d-%d%s", snapshot.Metadata.Index, time.Now().Unix(), ext)
 	path := filepath.Join(sm.dir, filename)
 
+	if err := writeSnapshotFile(path, snapshot, binary); err != nil {
+		return err
+	}
+
+	// Also create a "latest" symlink or copy
+	latestPath := filepath.Join(sm.dir, latestName)
+	os.Remove(latestPath) // Remove old symlink if exists
+
+	// Copy instead of symlink for better portability
+	if err := sm.copyFile(path, latestPath); err != nil {
+		return fmt.Errorf("failed to update latest snapshot: %w", err)
+	}
+
+	// Only one format may hold the latest snapshot
+	if err := os.Remove(filepath.Join(sm.dir, staleName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale latest snapshot: %w", err)
+	}
+	return nil
+}
+
+// writeSnapshotFile encodes snapshot to path as JSON or, if binary is set,
+// as the magic header followed by the gob encoding
+func writeSnapshotFile(path string, snapshot *Snapshot, binary bool) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("failed to create snapshot file: %w", err)
 	}
 	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ") // Pretty print for debugging
-
-	if err := encoder.Encode(&snapshot); err != nil {
+	w := bufio.NewWriter(file)
+	if binary {
+		w.Write(binarySnapshotMagic)
+		err = gob.NewEncoder(w).Encode(snapshot)
+	} else {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ") // Pretty print for debugging
+		err = encoder.Encode(snapshot)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to encode snapshot: %w", err)
 	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
 
 	if err := file.Sync(); err != nil {
 		return fmt.Errorf("failed to sync snapshot file: %w", err)
 	}
+	return nil
+}
 
-	// Also create a "latest" symlink or copy
-	latestPath := filepath.Join(sm.dir, "snapshot-latest.json")
-	os.Remove(latestPath) // Remove old symlink if exists
-
-	// Copy instead of symlink for better portability
-	if err := sm.copyFile(path, latestPath); err != nil {
-		return fmt.Errorf("failed to update latest snapshot: %w", err)
+// LatestPath returns the path of the most recent snapshot file, in
+// whichever format it was written
+func (sm *SnapshotManager) LatestPath() string {
+	binaryPath := filepath.Join(sm.dir, latestBinaryName)
+	if _, err := os.Stat(binaryPath); err == nil {
+		return binaryPath
 	}
-
-	return nil
+	return filepath.Join(sm.dir, latestJSONName)
 }
 
 // LoadLatestSnapshot loads the most recent snapshot
 func (sm *SnapshotManager) LoadLatestSnapshot() (*Snapshot, error) {
-	latestPath := filepath.Join(sm.dir, "snapshot-latest.json")
+	snapshot, err := LoadSnapshot(sm.LatestPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil // No snapshot exists
+	}
+	return snapshot, err
+}
 
-	file, err := os.Open(latestPath)
+// LoadSnapshot reads the snapshot file at path. The format is detected
+// from the first byte: binary snapshots start with the "RDG" magic.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil // No snapshot exists
-		}
 		return nil, fmt.Errorf("failed to open snapshot: %w", err)
 	}
 	defer file.Close()
 
+	r := bufio.NewReader(file)
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
 	var snapshot Snapshot
-	decoder := json.NewDecoder(file)
+	if first[0] == binarySnapshotMagic[0] {
+		magic := make([]byte, len(binarySnapshotMagic))
+		if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, binarySnapshotMagic) {
+			return nil, fmt.Errorf("failed to decode snapshot: bad binary snapshot header")
+		}
+		if err := gob.NewDecoder(r).Decode(&snapshot); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+		return &snapshot, nil
+	}
+
+	decoder := json.NewDecoder(r)
 
 	if err := decoder.Decode(&snapshot); err != nil {
 		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
@@ -124,6 +291,43 @@ func (sm *SnapshotManager) LoadLatestSnapshot() (*Snapshot, error) {
 	return &snapshot, nil
 }
 
+// ConvertToBinary rewrites every JSON snapshot in the directory, including
+// the latest, in the binary format. Each file keeps its name apart from
+// the extension, and the JSON file is removed once its replacement is
+// safely written.
+func (sm *SnapshotManager) ConvertToBinary() error {
+	entries, err := os.ReadDir(sm.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		jsonPath := filepath.Join(sm.dir, entry.Name())
+		snapshot, err := LoadSnapshot(jsonPath)
+		if err != nil {
+			return fmt.Errorf("failed to convert %s: %w", entry.Name(), err)
+		}
+
+		binPath := strings.TrimSuffix(jsonPath, ".json") + ".bin"
+		tmpPath := binPath + ".tmp"
+		if err := writeSnapshotFile(tmpPath, snapshot, true); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to convert %s: %w", entry.Name(), err)
+		}
+		if err := os.Rename(tmpPath, binPath); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to convert %s: %w", entry.Name(), err)
+		}
+		if err := os.Remove(jsonPath); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entry.Name(), err)
+		}
+	}
+	return nil
+}
+
 // copyFile copies a file from src to dst
 func (sm *SnapshotManager) copyFile(src, dst string) error {
 	data, err := os.ReadFile(src)
@@ -142,8 +346,8 @@ func (sm *SnapshotManager) ListSnapshots() ([]string, error) {
 
 	snapshots := []string{}
 	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
-			if entry.Name() != "snapshot-latest.json" {
+		if ext := filepath.Ext(entry.Name()); !entry.IsDir() && (ext == ".json" || ext == ".bin") {
+			if entry.Name() != latestJSONName && entry.Name() != latestBinaryName {
 				snapshots = append(snapshots, entry.Name())
 			}
 		}
@@ -176,3 +380,120 @@ func (sm *SnapshotManager) CleanupOldSnapshots(keepCount int) error {
 
 	return nil
 }
+
+// NodeDiff records the properties of a node before and after
+type NodeDiff struct {
+	ID       graph.NodeID     `json:"id"`
+	OldProps graph.Properties `json:"old_props"`
+	NewProps graph.Properties `json:"new_props"`
+}
+
+// EdgeDiff records the properties of an edge before and after
+type EdgeDiff struct {
+	ID       graph.EdgeID     `json:"id"`
+	OldProps graph.Properties `json:"old_props"`
+	NewProps graph.Properties `json:"new_props"`
+}
+
+// SnapshotDiffResult lists what changed between two snapshots. IDs are in
+// ascending order.
+type SnapshotDiffResult struct {
+	NodesAdded    []graph.NodeID `json:"nodes_added"`
+	NodesDeleted  []graph.NodeID `json:"nodes_deleted"`
+	NodesModified []NodeDiff     `json:"nodes_modified"`
+	EdgesAdded    []graph.EdgeID `json:"edges_added"`
+	EdgesDeleted  []graph.EdgeID `json:"edges_deleted"`
+	EdgesModified []EdgeDiff     `json:"edges_modified"`
+}
+
+// Empty reports whether the snapshots hold the same nodes and edges
+func (d SnapshotDiffResult) Empty() bool {
+	return len(d.NodesAdded) == 0 && len(d.NodesDeleted) == 0 && len(d.NodesModified) == 0 &&
+		len(d.EdgesAdded) == 0 && len(d.EdgesDeleted) == 0 && len(d.EdgesModified) == 0
+}
+
+// SnapshotDiff compares snap1 (before) with snap2 (after). Nodes and edges
+// are matched by ID; one present in both counts as modified when its
+// properties differ in value or type.
+func SnapshotDiff(snap1, snap2 *Snapshot) (SnapshotDiffResult, error) {
+	var result SnapshotDiffResult
+	if snap1 == nil || snap2 == nil {
+		return result, fmt.Errorf("cannot diff a nil snapshot")
+	}
+
+	oldNodes := make(map[graph.NodeID]*graph.Node, len(snap1.Nodes))
+	for _, n := range snap1.Nodes {
+		oldNodes[n.ID] = n
+	}
+	newNodes := make(map[graph.NodeID]*graph.Node, len(snap2.Nodes))
+	for _, n := range snap2.Nodes {
+		newNodes[n.ID] = n
+		old, ok := oldNodes[n.ID]
+		switch {
+		case !ok:
+			result.NodesAdded = append(result.NodesAdded, n.ID)
+		case !propertiesEqual(&old.Properties, &n.Properties):
+			result.NodesModified = append(result.NodesModified, NodeDiff{ID: n.ID, OldProps: old.Properties.Map(), NewProps: n.Properties.Map()})
+		}
+	}
+	for id := range oldNodes {
+		if _, ok := newNodes[id]; !ok {
+			result.NodesDeleted = append(result.NodesDeleted, id)
+		}
+	}
+
+	oldEdges := make(map[graph.EdgeID]*graph.Edge, len(snap1.Edges))
+	for _, e := range snap1.Edges {
+		oldEdges[e.ID] = e
+	}
+	newEdges := make(map[graph.EdgeID]*graph.Edge, len(snap2.Edges))
+	for _, e := range snap2.Edges {
+		newEdges[e.ID] = e
+		old, ok := oldEdges[e.ID]
+		switch {
+		case !ok:
+			result.EdgesAdded = append(result.EdgesAdded, e.ID)
+		case !propertiesEqual(&old.Properties, &e.Properties):
+			result.EdgesModified = append(result.EdgesModified, EdgeDiff{ID: e.ID, OldProps: old.Properties.Map(), NewProps: e.Properties.Map()})
+		}
+	}
+	for id := range oldEdges {
+		if _, ok := newEdges[id]; !ok {
+			result.EdgesDeleted = append(result.EdgesDeleted, id)
+		}
+	}
+
+	sort.Slice(result.NodesAdded, func(i, j int) bool { return result.NodesAdded[i] < result.NodesAdded[j] })
+	sort.Slice(result.NodesDeleted, func(i, j int) bool { return result.NodesDeleted[i] < result.NodesDeleted[j] })
+	sort.Slice(result.NodesModified, func(i, j int) bool { return result.NodesModified[i].ID < result.NodesModified[j].ID })
+	sort.Slice(result.EdgesAdded, func(i, j int) bool { return result.EdgesAdded[i] < result.EdgesAdded[j] })
+	sort.Slice(result.EdgesDeleted, func(i, j int) bool { return result.EdgesDeleted[i] < result.EdgesDeleted[j] })
+	sort.Slice(result.EdgesModified, func(i, j int) bool { return result.EdgesModified[i].ID < result.EdgesModified[j].ID })
+	return result, nil
+}
+
+// propertiesEqual compares properties by their typed encoding, so 30 and
+// 30.0 differ
+func propertiesEqual(a, b *graph.SmallProperties) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	equal := true
+	a.Range(func(k string, va graph.PropertyValue) bool {
+		vb, ok := b.GetProperty(k)
+		equal = ok && valuesEqual(va, vb)
+		return equal
+	})
+	return equal
+}
+
+// valuesEqual reports whether two property values have the same type and
+// value
+func valuesEqual(a, b graph.PropertyValue) bool {
+	ta, errA := graph.EncodeValue(a)
+	tb, errB := graph.EncodeValue(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return ta.Type == tb.Type && bytes.Equal(ta.Value, tb.Value)
+}
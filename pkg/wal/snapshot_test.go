@@ -1,9 +1,11 @@
 package wal
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
 	"github.com/stretchr/testify/assert"
@@ -116,7 +118,7 @@ func TestSnapshotPreservesData(t *testing.T) {
 	assert.Equal(t, "Person", snapshot.Nodes[0].Label)
 
 	// Note: Properties might need type assertion after JSON round-trip
-	name, ok := snapshot.Nodes[0].Properties["name"]
+	name, ok := snapshot.Nodes[0].Properties.GetProperty("name")
 	assert.True(t, ok)
 	assert.Equal(t, "Alice", name)
 }
@@ -200,3 +202,213 @@ func TestSnapshotWithLargeGraph(t *testing.T) {
 	assert.Len(t, snapshot.Nodes, 100)
 	assert.Len(t, snapshot.Edges, 99)
 }
+
+func TestBinarySnapshot(t *testing.T) {
+	dir := t.TempDir()
+	sm, err := NewSnapshotManager(dir)
+	require.NoError(t, err)
+
+	nodes := map[graph.NodeID]*graph.Node{
+		1: graph.NewNode(1, "City"),
+		2: graph.NewNode(2, "City"),
+	}
+	nodes[1].SetProperty("name", "Oslo")
+	nodes[1].SetProperty("population", 709037)
+	nodes[1].SetProperty("location", graph.Point{Lat: 59.91, Lon: 10.75})
+	edges := map[graph.EdgeID]*graph.Edge{
+		1: graph.NewEdge(1, 1, 2, "ROAD"),
+	}
+
+	deleted := graph.NewNode(3, "City")
+	deleted.DeletedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	catalog := &Catalog{
+		PropertyIndexes: []IndexDef{{Label: "City", Property: "name"}},
+		Schemas:         []SchemaDef{{Label: "City", Properties: map[string]string{"population": "int"}}},
+		NodesUntil:      100,
+	}
+	tombstones := &Tombstones{Nodes: []*graph.Node{deleted}, Cascaded: []graph.EdgeID{7}}
+
+	require.NoError(t, sm.CreateSnapshot(5, nodes, edges))
+	require.NoError(t, sm.CreateBinarySnapshot(10, nodes, edges, catalog, tombstones))
+
+	// The binary copy replaces the JSON one as the latest
+	assert.Equal(t, filepath.Join(dir, "snapshot-latest.bin"), sm.LatestPath())
+	_, err = os.Stat(filepath.Join(dir, "snapshot-latest.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	data, err := os.ReadFile(sm.LatestPath())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RDG"), data[:3])
+
+	snapshot, err := sm.LoadLatestSnapshot()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), snapshot.Metadata.Index)
+	require.Len(t, snapshot.Nodes, 2)
+	require.Len(t, snapshot.Edges, 1)
+	for _, n := range snapshot.Nodes {
+		if n.ID == 1 {
+			assert.Equal(t, nodes[1].Properties.Map(), n.Properties.Map())
+		}
+	}
+	assert.Equal(t, "ROAD", snapshot.Edges[0].Label)
+
+	// The catalog and tombstones are kept, as in a JSON snapshot
+	assert.Equal(t, catalog, snapshot.Catalog)
+	require.NotNil(t, snapshot.Tombstones)
+	require.Len(t, snapshot.Tombstones.Nodes, 1)
+	assert.Equal(t, graph.NodeID(3), snapshot.Tombstones.Nodes[0].ID)
+	assert.True(t, deleted.DeletedAt.Equal(snapshot.Tombstones.Nodes[0].DeletedAt))
+	assert.Equal(t, []graph.EdgeID{7}, snapshot.Tombstones.Cascaded)
+
+	snapshots, err := sm.ListSnapshots()
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 2)
+
+	// A later JSON snapshot becomes the latest again
+	require.NoError(t, sm.CreateSnapshot(15, nodes, edges))
+	assert.Equal(t, filepath.Join(dir, "snapshot-latest.json"), sm.LatestPath())
+
+	// Corrupt binary headers are rejected
+	bad := filepath.Join(dir, "bad.bin")
+	require.NoError(t, os.WriteFile(bad, []byte("RXG"), 0644))
+	_, err = LoadSnapshot(bad)
+	assert.Error(t, err)
+}
+
+func TestConvertToBinary(t *testing.T) {
+	dir := t.TempDir()
+	sm, err := NewSnapshotManager(dir)
+	require.NoError(t, err)
+
+	nodes := map[graph.NodeID]*graph.Node{1: graph.NewNode(1, "Person")}
+	nodes[1].SetProperty("age", 30)
+	require.NoError(t, sm.CreateSnapshotWithCatalog(20, nodes, nil, &Catalog{
+		PropertyIndexes: []IndexDef{{Label: "Person", Property: "age"}},
+	}))
+	before, err := sm.LoadLatestSnapshot()
+	require.NoError(t, err)
+
+	require.NoError(t, sm.ConvertToBinary())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		assert.Equal(t, ".bin", filepath.Ext(entry.Name()))
+	}
+	assert.Equal(t, filepath.Join(dir, "snapshot-latest.bin"), sm.LatestPath())
+
+	after, err := sm.LoadLatestSnapshot()
+	require.NoError(t, err)
+	assert.Equal(t, before.Metadata.Index, after.Metadata.Index)
+	assert.True(t, before.Metadata.Timestamp.Equal(after.Metadata.Timestamp))
+	assert.Equal(t, before.Catalog, after.Catalog)
+	require.Len(t, after.Nodes, 1)
+	assert.Equal(t, 30, after.Nodes[0].Properties.Map()["age"])
+
+	// Nothing left to convert
+	require.NoError(t, sm.ConvertToBinary())
+}
+
+func TestSnapshotDiff(t *testing.T) {
+	before := &Snapshot{
+		Nodes: []*graph.Node{
+			{ID: 1, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Alice", "age": 30})},
+			{ID: 2, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Bob"})},
+			{ID: 3, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Carol", "age": 40})},
+		},
+		Edges: []*graph.Edge{
+			{ID: 1, Source: 1, Target: 2, Label: "KNOWS", Properties: graph.NewSmallProperties(graph.Properties{"since": 2020})},
+			{ID: 2, Source: 2, Target: 3, Label: "KNOWS"},
+		},
+	}
+	after := &Snapshot{
+		Nodes: []*graph.Node{
+			{ID: 4, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Dave"})},
+			{ID: 1, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Alice", "age": 31})},
+			{ID: 3, Label: "Person", Properties: graph.NewSmallProperties(graph.Properties{"name": "Carol", "age": 40.0})},
+		},
+		Edges: []*graph.Edge{
+			{ID: 1, Source: 1, Target: 2, Label: "KNOWS", Properties: graph.NewSmallProperties(graph.Properties{"since": 2020})},
+			{ID: 3, Source: 1, Target: 4, Label: "KNOWS", Properties: graph.NewSmallProperties(graph.Properties{})},
+		},
+	}
+
+	diff, err := SnapshotDiff(before, after)
+	require.NoError(t, err)
+	assert.Equal(t, []graph.NodeID{4}, diff.NodesAdded)
+	assert.Equal(t, []graph.NodeID{2}, diff.NodesDeleted)
+	// 40 and 40.0 are different types, so Carol counts as modified
+	require.Len(t, diff.NodesModified, 2)
+	assert.Equal(t, NodeDiff{
+		ID:       1,
+		OldProps: graph.Properties{"name": "Alice", "age": 30},
+		NewProps: graph.Properties{"name": "Alice", "age": 31},
+	}, diff.NodesModified[0])
+	assert.Equal(t, graph.NodeID(3), diff.NodesModified[1].ID)
+	assert.Equal(t, []graph.EdgeID{3}, diff.EdgesAdded)
+	assert.Equal(t, []graph.EdgeID{2}, diff.EdgesDeleted)
+	assert.Empty(t, diff.EdgesModified)
+	assert.False(t, diff.Empty())
+
+	same, err := SnapshotDiff(after, after)
+	require.NoError(t, err)
+	assert.True(t, same.Empty())
+
+	_, err = SnapshotDiff(nil, after)
+	assert.Error(t, err)
+}
+
+// benchmarkGraph builds a chain of n nodes with a few typed properties
+func benchmarkGraph(n int) (map[graph.NodeID]*graph.Node, map[graph.EdgeID]*graph.Edge) {
+	nodes := make(map[graph.NodeID]*graph.Node, n)
+	edges := make(map[graph.EdgeID]*graph.Edge, n)
+	for i := 1; i <= n; i++ {
+		node := graph.NewNode(graph.NodeID(i), "Node")
+		node.Properties.SetProperty("name", fmt.Sprintf("node-%d", i))
+		node.Properties.SetProperty("rank", i)
+		node.Properties.SetProperty("score", float64(i)/3)
+		nodes[node.ID] = node
+		if i > 1 {
+			edge := graph.NewEdge(graph.EdgeID(i), graph.NodeID(i-1), graph.NodeID(i), "NEXT")
+			edge.Properties.SetProperty("weight", i%7)
+			edges[edge.ID] = edge
+		}
+	}
+	return nodes, edges
+}
+
+// BenchmarkSnapshot compares writing and loading JSON and binary
+// snapshots of a 10k node graph, reporting the file size
+func BenchmarkSnapshot(b *testing.B) {
+	nodes, edges := benchmarkGraph(10000)
+
+	formats := []struct {
+		name   string
+		create func(sm *SnapshotManager) error
+	}{
+		{"json", func(sm *SnapshotManager) error { return sm.CreateSnapshot(1, nodes, edges) }},
+		{"binary", func(sm *SnapshotManager) error { return sm.CreateBinarySnapshot(1, nodes, edges, nil, nil) }},
+	}
+	for _, format := range formats {
+		b.Run(format.name+"/write", func(b *testing.B) {
+			sm, err := NewSnapshotManager(b.TempDir())
+			require.NoError(b, err)
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, format.create(sm))
+			}
+			info, err := os.Stat(sm.LatestPath())
+			require.NoError(b, err)
+			b.ReportMetric(float64(info.Size()), "bytes/file")
+		})
+		b.Run(format.name+"/read", func(b *testing.B) {
+			sm, err := NewSnapshotManager(b.TempDir())
+			require.NoError(b, err)
+			require.NoError(b, format.create(sm))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := sm.LoadLatestSnapshot()
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package wal
+
+import (
+	"sync"
+)
+
+// OpGap marks entries a subscriber missed because its buffer was full.
+// Its data holds "from_index", "to_index" and "dropped".
+const OpGap OpType = "GAP"
+
+// SlowConsumerPolicy decides what happens when a subscriber's buffer is full
+type SlowConsumerPolicy int
+
+const (
+	// DropWithGap discards entries for the slow subscriber and delivers a
+	// single OpGap entry describing them once there is room again
+	DropWithGap SlowConsumerPolicy = iota
+
+	// Block waits for the subscriber. Appends, and therefore every writer,
+	// stall until it catches up or unsubscribes.
+	Block
+)
+
+// SubscribeOptions configures a WAL subscription
+type SubscribeOptions struct {
+	BufferSize int // Channel capacity (default 1024)
+	Policy     SlowConsumerPolicy
+}
+
+// DefaultSubscribeOptions returns the options used by Subscribe
+func DefaultSubscribeOptions() SubscribeOptions {
+	return SubscribeOptions{BufferSize: 1024, Policy: DropWithGap}
+}
+
+// subscriber is one consumer of the change stream
+type subscriber struct {
+	ch     chan LogEntry
+	policy SlowConsumerPolicy
+	done   chan struct{} // closed to release a blocked send
+	once   sync.Once
+
+	mu     sync.Mutex // guards sends, closed and the pending gap
+	closed bool
+
+	// Pending gap under DropWithGap; dropped == 0 when there is none
+	gapFrom, gapTo uint64
+	dropped        int
+}
+
+// Subscribe returns a channel receiving every entry appended from now on,
+// in index order, once it is durable. Entries carry the data exactly as
+// Replay would decode it and must be treated as read-only.
+func (w *WAL) Subscribe(opts SubscribeOptions) <-chan LogEntry {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultSubscribeOptions().BufferSize
+	}
+	sub := &subscriber{
+		ch:     make(chan LogEntry, opts.BufferSize),
+		policy: opts.Policy,
+		done:   make(chan struct{}),
+	}
+
+	w.subMu.Lock()
+	defer w.subMu.Unlock()
+	if w.subs == nil {
+		w.subs = make(map[<-chan LogEntry]*subscriber)
+	}
+	w.subs[sub.ch] = sub
+	return sub.ch
+}
+
+// Unsubscribe stops delivery to ch and closes it. Entries still buffered
+// can be drained by the caller.
+func (w *WAL) Unsubscribe(ch <-chan LogEntry) {
+	w.subMu.Lock()
+	sub, ok := w.subs[ch]
+	delete(w.subs, ch)
+	w.subMu.Unlock()
+
+	if ok {
+		sub.close()
+	}
+}
+
+// closeSubscribers closes every subscription channel
+func (w *WAL) closeSubscribers() {
+	w.subMu.Lock()
+	subs := w.subs
+	w.subs = nil
+	w.subMu.Unlock()
+
+	for _, sub := range subs {
+		sub.close()
+	}
+}
+
+// publish delivers the encoded entry to all subscribers.
+// Caller holds w.mu, so entries are published in index order.
+func (w *WAL) publish(encoded []byte) {
+	w.subMu.Lock()
+	subs := make([]*subscriber, 0, len(w.subs))
+	for _, sub := range w.subs {
+		subs = append(subs, sub)
+	}
+	w.subMu.Unlock()
+
+	if len(subs) == 0 {
+		return
+	}
+
+	entry, err := w.codec.decode(encoded)
+	if err != nil {
+		return
+	}
+	for _, sub := range subs {
+		sub.deliver(entry)
+	}
+}
+
+// close releases a blocked send and closes the channel
+func (s *subscriber) close() {
+	s.once.Do(func() { close(s.done) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+func (s *subscriber) deliver(entry LogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	if s.policy == Block {
+		select {
+		case s.ch <- entry:
+		case <-s.done:
+		}
+		return
+	}
+	s.offer(entry)
+}
+
+// offer delivers entry without blocking, recording a gap if it cannot
+func (s *subscriber) offer(entry LogEntry) {
+	if s.dropped > 0 {
+		gap := LogEntry{
+			Timestamp: entry.Timestamp,
+			OpType:    OpGap,
+			Data: map[string]interface{}{
+				"from_index": s.gapFrom,
+				"to_index":   s.gapTo,
+				"dropped":    s.dropped,
+			},
+		}
+		select {
+		case s.ch <- gap:
+			s.dropped = 0
+		default:
+			s.gapTo = entry.Index
+			s.dropped++
+			return
+		}
+	}
+
+	select {
+	case s.ch <- entry:
+	default:
+		s.gapFrom, s.gapTo = entry.Index, entry.Index
+		s.dropped = 1
+	}
+}
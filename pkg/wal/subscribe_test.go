@@ -0,0 +1,116 @@
+package wal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fnuworsu/rdgDB/internal/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe_DeliversInOrder(t *testing.T) {
+	w, err := NewWAL(t.TempDir())
+	require.NoError(t, err)
+	defer w.Close()
+
+	ch := w.Subscribe(DefaultSubscribeOptions())
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", graph.Properties{"n": i}, time.Time{}))
+	}
+
+	for i := 1; i <= 3; i++ {
+		entry := <-ch
+		assert.Equal(t, uint64(i), entry.Index)
+		assert.Equal(t, OpAddNode, entry.OpType)
+		// Same shape as Replay: JSON numbers decode as float64
+		assert.Equal(t, float64(i), entry.Data["node_id"])
+	}
+}
+
+func TestSubscribe_DropWithGap(t *testing.T) {
+	w, err := NewWAL(t.TempDir())
+	require.NoError(t, err)
+	defer w.Close()
+
+	ch := w.Subscribe(SubscribeOptions{BufferSize: 2, Policy: DropWithGap})
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, w.LogDeleteNode(graph.NodeID(i)))
+	}
+
+	// Entries 1-2 fit; 3-5 are dropped
+	assert.Equal(t, uint64(1), (<-ch).Index)
+	assert.Equal(t, uint64(2), (<-ch).Index)
+
+	require.NoError(t, w.LogDeleteNode(graph.NodeID(6)))
+
+	gap := <-ch
+	assert.Equal(t, OpGap, gap.OpType)
+	assert.Equal(t, uint64(3), gap.Data["from_index"])
+	assert.Equal(t, uint64(5), gap.Data["to_index"])
+	assert.Equal(t, 3, gap.Data["dropped"])
+	assert.Equal(t, uint64(6), (<-ch).Index)
+}
+
+func TestSubscribe_Block(t *testing.T) {
+	w, err := NewWAL(t.TempDir())
+	require.NoError(t, err)
+	defer w.Close()
+
+	ch := w.Subscribe(SubscribeOptions{BufferSize: 1, Policy: Block})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 1; i <= 5; i++ {
+			w.LogDeleteNode(graph.NodeID(i))
+		}
+		close(done)
+	}()
+
+	for i := 1; i <= 5; i++ {
+		assert.Equal(t, uint64(i), (<-ch).Index)
+	}
+	<-done
+}
+
+func TestUnsubscribe_ReleasesBlockedWriter(t *testing.T) {
+	w, err := NewWAL(t.TempDir())
+	require.NoError(t, err)
+	defer w.Close()
+
+	ch := w.Subscribe(SubscribeOptions{BufferSize: 1, Policy: Block})
+	require.NoError(t, w.LogDeleteNode(1)) // fills the buffer
+
+	done := make(chan struct{})
+	go func() {
+		w.LogDeleteNode(2) // blocks on the full subscriber
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	w.Unsubscribe(ch)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("writer still blocked after unsubscribe")
+	}
+
+	// Buffered entries drain, then the channel is closed
+	assert.Equal(t, uint64(1), (<-ch).Index)
+	_, open := <-ch
+	assert.False(t, open)
+
+	w.Unsubscribe(ch) // no-op
+}
+
+func TestClose_ClosesSubscriptions(t *testing.T) {
+	w, err := NewWAL(t.TempDir())
+	require.NoError(t, err)
+
+	ch := w.Subscribe(DefaultSubscribeOptions())
+	require.NoError(t, w.Close())
+
+	_, open := <-ch
+	assert.False(t, open)
+}
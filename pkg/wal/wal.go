@@ -2,7 +2,7 @@
 package wal
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -23,6 +23,30 @@ const (
 	OpDeleteEdge  OpType = "DELETE_EDGE"
 	OpSetNodeProp OpType = "SET_NODE_PROP"
 	OpSetEdgeProp OpType = "SET_EDGE_PROP"
+
+	OpReconnectEdge OpType = "RECONNECT_EDGE"
+
+	OpCreateFTIndex      OpType = "CREATE_FT_INDEX"
+	OpCreateSpatialIndex OpType = "CREATE_SPATIAL_INDEX"
+	OpCreatePropIndex    OpType = "CREATE_PROP_INDEX"
+
+	OpDefineSchema OpType = "DEFINE_SCHEMA"
+	OpDropSchema   OpType = "DROP_SCHEMA"
+
+	OpSetNodeExpiry OpType = "SET_NODE_EXPIRY"
+	OpSetEdgeExpiry OpType = "SET_EDGE_EXPIRY"
+
+	OpTombstoneNode   OpType = "TOMBSTONE_NODE"
+	OpTombstoneEdge   OpType = "TOMBSTONE_EDGE"
+	OpRestoreNode     OpType = "RESTORE_NODE"
+	OpPurgeTombstones OpType = "PURGE_TOMBSTONES"
+
+	// Written by repairs of adjacency lists (see storage.Graph.Validate)
+	OpLinkEdge   OpType = "LINK_EDGE"
+	OpUnlinkEdge OpType = "UNLINK_EDGE"
+
+	// Reserves a block of IDs (see storage.Options.IDBlockSize)
+	OpReserveIDs OpType = "RESERVE_IDS"
 )
 
 // LogEntry represents a single entry in the WAL
@@ -33,17 +57,58 @@ type LogEntry struct {
 	Data      map[string]interface{} `json:"data"`
 }
 
+// ErrReadOnly is returned when writing to a WAL opened with NewReadOnlyWAL
+var ErrReadOnly = errors.New("WAL is read-only")
+
+// ErrTruncated is returned by ReplayAfter when the entries to replay have
+// already been truncated away
+var ErrTruncated = errors.New("WAL entries were truncated")
+
+// logFile is the file the log appends to
+type logFile interface {
+	io.WriteCloser
+	io.Seeker
+	Sync() error
+	Truncate(size int64) error
+}
+
 // WAL represents the write-ahead log
 type WAL struct {
 	dir       string
-	file      *os.File
-	encoder   *json.Encoder
+	file      logFile
 	nextIndex uint64
+	readOnly  bool
 	mu        sync.Mutex
+
+	// codec encodes entries in the format of the log file, and opts.Format
+	// is the format Truncate rewrites it in
+	codec codec
+	opts  Options
+
+	// Set when a failed write could not be undone, leaving the end of the
+	// log unknown; appends are refused from then on
+	failed error
+
+	// Entries in the log and its length in bytes (see Size)
+	entries int
+	size    int64
+
+	// Number of times Truncate has rewritten the log, so integrity scans
+	// can discard what they read while it did (see integrity.go)
+	truncations uint64
+
+	// Change stream subscribers (see subscribe.go)
+	subs  map[<-chan LogEntry]*subscriber
+	subMu sync.Mutex
 }
 
-// NewWAL creates a new write-ahead log
+// NewWAL creates a new write-ahead log in the default JSON format
 func NewWAL(dir string) (*WAL, error) {
+	return NewWALWithOptions(dir, DefaultOptions())
+}
+
+// NewWALWithOptions creates a write-ahead log configured by opts
+func NewWALWithOptions(dir string, opts Options) (*WAL, error) {
 	// Create directory if it doesn't exist
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create WAL directory: %w", err)
@@ -59,8 +124,9 @@ func NewWAL(dir string) (*WAL, error) {
 	wal := &WAL{
 		dir:       dir,
 		file:      file,
-		encoder:   json.NewEncoder(file),
 		nextIndex: 1,
+		codec:     codecFor(opts.Format),
+		opts:      opts,
 	}
 
 	// Determine next index by reading existing entries
@@ -69,13 +135,40 @@ func NewWAL(dir string) (*WAL, error) {
 		return nil, fmt.Errorf("failed to load last index: %w", err)
 	}
 
+	// A new log starts with the header of its format
+	if wal.size == 0 {
+		if err := wal.writeHeader(); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+
+	return wal, nil
+}
+
+// NewReadOnlyWAL opens an existing log for replay only. The log file is
+// never opened for writing, so any number of processes may read it while
+// a single writer appends. A missing log replays as empty.
+func NewReadOnlyWAL(dir string) (*WAL, error) {
+	wal := &WAL{
+		dir:       dir,
+		nextIndex: 1,
+		readOnly:  true,
+		codec:     jsonCodec{},
+	}
+
+	if err := wal.loadLastIndex(); err != nil {
+		return nil, fmt.Errorf("failed to load last index: %w", err)
+	}
+
 	return wal, nil
 }
 
-// loadLastIndex scans the log to find the last index
+// loadLastIndex scans the log to find the last index. A log that is not
+// empty is kept in the format it was written in.
 func (w *WAL) loadLastIndex() error {
 	// Reopen file for reading
-	readFile, err := os.Open(filepath.Join(w.dir, "wal.log"))
+	readFile, reader, err := openLog(w.dir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // New log file
@@ -84,13 +177,15 @@ func (w *WAL) loadLastIndex() error {
 	}
 	defer readFile.Close()
 
-	decoder := json.NewDecoder(readFile)
 	var lastIndex uint64 = 0
+	entries := 0
 
 	for {
-		var entry LogEntry
-		if err := decoder.Decode(&entry); err != nil {
-			if err == io.EOF {
+		entry, err := reader.nextEntry()
+		if err != nil {
+			// A reader may open the log while the writer is mid-append;
+			// like ReplayAfter, it stops at the last complete entry
+			if err == io.EOF || (w.readOnly && err == io.ErrUnexpectedEOF) {
 				break
 			}
 			return fmt.Errorf("failed to decode entry: %w", err)
@@ -98,17 +193,58 @@ func (w *WAL) loadLastIndex() error {
 		if entry.Index > lastIndex {
 			lastIndex = entry.Index
 		}
+		entries++
 	}
 
+	info, err := readFile.Stat()
+	if err != nil {
+		return err
+	}
 	w.nextIndex = lastIndex + 1
+	w.entries = entries
+	w.size = info.Size()
+	if w.size > 0 {
+		w.codec = reader.codec
+	}
+	return nil
+}
+
+// writeHeader starts an empty log with the header of its format. Caller
+// holds w.mu or has the WAL to itself.
+func (w *WAL) writeHeader() error {
+	header := w.codec.header()
+	if len(header) == 0 {
+		return nil
+	}
+	if _, err := w.file.Write(header); err != nil {
+		return w.discardWrite(fmt.Errorf("failed to write WAL header: %w", err))
+	}
+	if err := w.file.Sync(); err != nil {
+		return w.discardWrite(fmt.Errorf("failed to sync WAL: %w", err))
+	}
+	w.size = int64(len(header))
 	return nil
 }
 
+// Format returns the format of the log file
+func (w *WAL) Format() Format {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.codec.format()
+}
+
 // Append adds a new entry to the WAL
 func (w *WAL) Append(opType OpType, data map[string]interface{}) (uint64, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	if w.readOnly {
+		return 0, ErrReadOnly
+	}
+	if w.failed != nil {
+		return 0, w.failed
+	}
+
 	entry := LogEntry{
 		Index:     w.nextIndex,
 		Timestamp: time.Now(),
@@ -116,41 +252,140 @@ func (w *WAL) Append(opType OpType, data map[string]interface{}) (uint64, error)
 		Data:      data,
 	}
 
-	if err := w.encoder.Encode(&entry); err != nil {
+	encoded, err := w.codec.encode(&entry)
+	if err != nil {
 		return 0, fmt.Errorf("failed to encode entry: %w", err)
 	}
+	if _, err := w.file.Write(encoded); err != nil {
+		return 0, w.discardWrite(fmt.Errorf("failed to write entry: %w", err))
+	}
 
 	// Flush to disk (fsync for durability)
 	if err := w.file.Sync(); err != nil {
-		return 0, fmt.Errorf("failed to sync WAL: %w", err)
+		return 0, w.discardWrite(fmt.Errorf("failed to sync WAL: %w", err))
 	}
 
 	index := w.nextIndex
 	w.nextIndex++
+	w.entries++
+	w.size += int64(len(encoded))
+
+	// Only durable entries reach the change stream
+	w.publish(encoded)
 	return index, nil
 }
 
-// LogAddNode logs a node addition
-func (w *WAL) LogAddNode(nodeID graph.NodeID, label string, properties graph.Properties) error {
-	data := map[string]interface{}{
+// AppendBatch adds entries to the WAL with a single write and fsync. The
+// OpType and Data of each entry are kept and its Index and Timestamp
+// assigned. It returns the index of the first entry.
+func (w *WAL) AppendBatch(entries []LogEntry) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.readOnly {
+		return 0, ErrReadOnly
+	}
+	if w.failed != nil {
+		return 0, w.failed
+	}
+
+	first := w.nextIndex
+	now := time.Now()
+	var buf []byte
+	ends := make([]int, len(entries)) // End of each entry in buf
+	for i, e := range entries {
+		entry := LogEntry{
+			Index:     first + uint64(i),
+			Timestamp: now,
+			OpType:    e.OpType,
+			Data:      e.Data,
+		}
+		encoded, err := w.codec.encode(&entry)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode entry: %w", err)
+		}
+		buf = append(buf, encoded...)
+		ends[i] = len(buf)
+	}
+
+	if _, err := w.file.Write(buf); err != nil {
+		return 0, w.discardWrite(fmt.Errorf("failed to write entries: %w", err))
+	}
+	if err := w.file.Sync(); err != nil {
+		return 0, w.discardWrite(fmt.Errorf("failed to sync WAL: %w", err))
+	}
+	w.nextIndex += uint64(len(entries))
+	w.entries += len(entries)
+	w.size += int64(len(buf))
+
+	start := 0
+	for _, end := range ends {
+		w.publish(buf[start:end])
+		start = end
+	}
+	return first, nil
+}
+
+// discardWrite undoes a failed append by truncating the log back to its
+// length before the write, so that a partly written line, or one whose
+// fsync failed and whose change the caller rolls back, is never replayed.
+// The write offset is moved back too, as truncating leaves it past the end
+// unless the file was opened for appending. It returns err. If the log
+// cannot be truncated, its end is unknown and every later append fails.
+// Caller holds w.mu.
+func (w *WAL) discardWrite(err error) error {
+	truncErr := w.file.Truncate(w.size)
+	if truncErr == nil {
+		_, truncErr = w.file.Seek(w.size, io.SeekStart)
+	}
+	if truncErr != nil {
+		w.failed = fmt.Errorf("WAL unusable after failed append: %v; truncating it failed: %w", err, truncErr)
+		return w.failed
+	}
+	return err
+}
+
+// AddNodeEntry returns the entry LogAddNode writes, for AppendBatch
+func AddNodeEntry(nodeID graph.NodeID, label string, properties graph.Properties, createdAt time.Time) LogEntry {
+	return LogEntry{OpType: OpAddNode, Data: withCreatedAt(map[string]interface{}{
 		"node_id":    nodeID,
 		"label":      label,
 		"properties": properties,
-	}
-	_, err := w.Append(OpAddNode, data)
-	return err
+	}, createdAt)}
 }
 
-// LogAddEdge logs an edge addition
-func (w *WAL) LogAddEdge(edgeID graph.EdgeID, source, target graph.NodeID, label string, properties graph.Properties) error {
-	data := map[string]interface{}{
+// AddEdgeEntry returns the entry LogAddEdge writes, for AppendBatch
+func AddEdgeEntry(edgeID graph.EdgeID, source, target graph.NodeID, label string, properties graph.Properties, createdAt time.Time) LogEntry {
+	return LogEntry{OpType: OpAddEdge, Data: withCreatedAt(map[string]interface{}{
 		"edge_id":    edgeID,
 		"source":     source,
 		"target":     target,
 		"label":      label,
 		"properties": properties,
+	}, createdAt)}
+}
+
+// withCreatedAt records when an entity was created, so replay restores
+// its CreatedAt rather than the entry's timestamp. A zero time is not
+// recorded.
+func withCreatedAt(data map[string]interface{}, createdAt time.Time) map[string]interface{} {
+	if !createdAt.IsZero() {
+		data["created_at"] = createdAt.Format(time.RFC3339Nano)
 	}
-	_, err := w.Append(OpAddEdge, data)
+	return data
+}
+
+// LogAddNode logs a node addition along with the node's CreatedAt
+func (w *WAL) LogAddNode(nodeID graph.NodeID, label string, properties graph.Properties, createdAt time.Time) error {
+	entry := AddNodeEntry(nodeID, label, properties, createdAt)
+	_, err := w.Append(entry.OpType, entry.Data)
+	return err
+}
+
+// LogAddEdge logs an edge addition along with the edge's CreatedAt
+func (w *WAL) LogAddEdge(edgeID graph.EdgeID, source, target graph.NodeID, label string, properties graph.Properties, createdAt time.Time) error {
+	entry := AddEdgeEntry(edgeID, source, target, label, properties, createdAt)
+	_, err := w.Append(entry.OpType, entry.Data)
 	return err
 }
 
@@ -172,9 +407,206 @@ func (w *WAL) LogDeleteEdge(edgeID graph.EdgeID) error {
 	return err
 }
 
+// LogReconnectEdge logs new endpoints of an existing edge along with the
+// edge's new UpdatedAt
+func (w *WAL) LogReconnectEdge(edgeID graph.EdgeID, source, target graph.NodeID, updatedAt time.Time) error {
+	data := map[string]interface{}{
+		"edge_id":    edgeID,
+		"source":     source,
+		"target":     target,
+		"updated_at": updatedAt.Format(time.RFC3339Nano),
+	}
+	_, err := w.Append(OpReconnectEdge, data)
+	return err
+}
+
+// SetNodePropertiesEntry returns the entry LogSetNodeProperties writes,
+// for AppendBatch
+func SetNodePropertiesEntry(nodeID graph.NodeID, properties graph.Properties, updatedAt time.Time) LogEntry {
+	return LogEntry{OpType: OpSetNodeProp, Data: map[string]interface{}{
+		"node_id":    nodeID,
+		"properties": properties,
+		"updated_at": updatedAt.Format(time.RFC3339Nano),
+	}}
+}
+
+// LogSetNodeProperties logs property updates on an existing node along
+// with the node's new UpdatedAt, so replay restores the original time
+func (w *WAL) LogSetNodeProperties(nodeID graph.NodeID, properties graph.Properties, updatedAt time.Time) error {
+	entry := SetNodePropertiesEntry(nodeID, properties, updatedAt)
+	_, err := w.Append(entry.OpType, entry.Data)
+	return err
+}
+
+// LogSetEdgeProperties logs property updates on an existing edge along
+// with the edge's new UpdatedAt
+func (w *WAL) LogSetEdgeProperties(edgeID graph.EdgeID, properties graph.Properties, updatedAt time.Time) error {
+	data := map[string]interface{}{
+		"edge_id":    edgeID,
+		"properties": properties,
+		"updated_at": updatedAt.Format(time.RFC3339Nano),
+	}
+	_, err := w.Append(OpSetEdgeProp, data)
+	return err
+}
+
+// LogCreateFullTextIndex logs the creation of a full-text index
+func (w *WAL) LogCreateFullTextIndex(label, property string) error {
+	data := map[string]interface{}{
+		"label":    label,
+		"property": property,
+	}
+	_, err := w.Append(OpCreateFTIndex, data)
+	return err
+}
+
+// LogCreateSpatialIndex logs the creation of a spatial index
+func (w *WAL) LogCreateSpatialIndex(label, property string) error {
+	data := map[string]interface{}{
+		"label":    label,
+		"property": property,
+	}
+	_, err := w.Append(OpCreateSpatialIndex, data)
+	return err
+}
+
+// LogCreatePropertyIndex logs the creation of a property index
+func (w *WAL) LogCreatePropertyIndex(label, property string) error {
+	data := map[string]interface{}{
+		"label":    label,
+		"property": property,
+	}
+	_, err := w.Append(OpCreatePropIndex, data)
+	return err
+}
+
+// LogDefineSchema logs the definition of a label schema
+func (w *WAL) LogDefineSchema(def SchemaDef) error {
+	data := map[string]interface{}{
+		"label":      def.Label,
+		"properties": def.Properties,
+		"required":   def.Required,
+	}
+	_, err := w.Append(OpDefineSchema, data)
+	return err
+}
+
+// LogDropSchema logs the removal of a label schema
+func (w *WAL) LogDropSchema(label string) error {
+	data := map[string]interface{}{
+		"label": label,
+	}
+	_, err := w.Append(OpDropSchema, data)
+	return err
+}
+
+// LogSetNodeExpiry logs a node's expiry time. A zero time clears it.
+func (w *WAL) LogSetNodeExpiry(nodeID graph.NodeID, expiresAt time.Time) error {
+	data := map[string]interface{}{
+		"node_id":    nodeID,
+		"expires_at": formatExpiry(expiresAt),
+	}
+	_, err := w.Append(OpSetNodeExpiry, data)
+	return err
+}
+
+// LogSetEdgeExpiry logs an edge's expiry time. A zero time clears it.
+func (w *WAL) LogSetEdgeExpiry(edgeID graph.EdgeID, expiresAt time.Time) error {
+	data := map[string]interface{}{
+		"edge_id":    edgeID,
+		"expires_at": formatExpiry(expiresAt),
+	}
+	_, err := w.Append(OpSetEdgeExpiry, data)
+	return err
+}
+
+// LogTombstoneNode logs the soft deletion of a node and its edges
+func (w *WAL) LogTombstoneNode(nodeID graph.NodeID, deletedAt time.Time) error {
+	data := map[string]interface{}{
+		"node_id":    nodeID,
+		"deleted_at": deletedAt.Format(time.RFC3339Nano),
+	}
+	_, err := w.Append(OpTombstoneNode, data)
+	return err
+}
+
+// LogTombstoneEdge logs the soft deletion of an edge
+func (w *WAL) LogTombstoneEdge(edgeID graph.EdgeID, deletedAt time.Time) error {
+	data := map[string]interface{}{
+		"edge_id":    edgeID,
+		"deleted_at": deletedAt.Format(time.RFC3339Nano),
+	}
+	_, err := w.Append(OpTombstoneEdge, data)
+	return err
+}
+
+// LogRestoreNode logs the restoration of a tombstoned node
+func (w *WAL) LogRestoreNode(nodeID graph.NodeID) error {
+	data := map[string]interface{}{
+		"node_id": nodeID,
+	}
+	_, err := w.Append(OpRestoreNode, data)
+	return err
+}
+
+// LogPurgeTombstones logs the permanent removal of tombstones
+func (w *WAL) LogPurgeTombstones(nodeIDs []graph.NodeID, edgeIDs []graph.EdgeID) error {
+	data := map[string]interface{}{
+		"node_ids": nodeIDs,
+		"edge_ids": edgeIDs,
+	}
+	_, err := w.Append(OpPurgeTombstones, data)
+	return err
+}
+
+// formatExpiry renders an expiry time for the log, "" meaning none
+func formatExpiry(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// LogReserveIDs logs that node IDs below nodesUntil and edge IDs below
+// edgesUntil may have been handed out, so they must never be reissued
+func (w *WAL) LogReserveIDs(nodesUntil, edgesUntil uint64) error {
+	data := map[string]interface{}{
+		"nodes_until": nodesUntil,
+		"edges_until": edgesUntil,
+	}
+	_, err := w.Append(OpReserveIDs, data)
+	return err
+}
+
+// LogLinkEdge logs adding an edge to a node's outgoing or incoming
+// adjacency list, without changing the edge
+func (w *WAL) LogLinkEdge(nodeID graph.NodeID, edgeID graph.EdgeID, outgoing bool) error {
+	_, err := w.Append(OpLinkEdge, adjacencyData(nodeID, edgeID, outgoing))
+	return err
+}
+
+// LogUnlinkEdge logs removing an edge from a node's outgoing or incoming
+// adjacency list, without changing the edge
+func (w *WAL) LogUnlinkEdge(nodeID graph.NodeID, edgeID graph.EdgeID, outgoing bool) error {
+	_, err := w.Append(OpUnlinkEdge, adjacencyData(nodeID, edgeID, outgoing))
+	return err
+}
+
+func adjacencyData(nodeID graph.NodeID, edgeID graph.EdgeID, outgoing bool) map[string]interface{} {
+	direction := "in"
+	if outgoing {
+		direction = "out"
+	}
+	return map[string]interface{}{
+		"node_id":   nodeID,
+		"edge_id":   edgeID,
+		"direction": direction,
+	}
+}
+
 // Replay reads all entries from the WAL and calls the handler for each
 func (w *WAL) Replay(handler func(entry LogEntry) error) error {
-	readFile, err := os.Open(filepath.Join(w.dir, "wal.log"))
+	readFile, reader, err := openLog(w.dir)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil // No log to replay
@@ -183,11 +615,9 @@ func (w *WAL) Replay(handler func(entry LogEntry) error) error {
 	}
 	defer readFile.Close()
 
-	decoder := json.NewDecoder(readFile)
-
 	for {
-		var entry LogEntry
-		if err := decoder.Decode(&entry); err != nil {
+		entry, err := reader.nextEntry()
+		if err != nil {
 			if err == io.EOF {
 				break
 			}
@@ -202,8 +632,136 @@ func (w *WAL) Replay(handler func(entry LogEntry) error) error {
 	return nil
 }
 
+// ProgressInterval is the number of entries ReplayWithProgress replays
+// between progress reports
+const ProgressInterval = 1000
+
+// ReplayWithProgress is Replay that also reports progress: after every
+// ProgressInterval entries and after the last one, progress is called
+// with the number of entries replayed so far and the total in the log.
+// The total is counted by a pass over the log that frames the entries
+// without decoding them.
+func (w *WAL) ReplayWithProgress(handler func(entry LogEntry) error, progress func(current, total uint64)) error {
+	total, err := w.countEntries()
+	if err != nil {
+		return err
+	}
+
+	var current uint64
+	err = w.Replay(func(entry LogEntry) error {
+		if err := handler(entry); err != nil {
+			return err
+		}
+		current++
+		if current%ProgressInterval == 0 && current < total {
+			progress(current, total)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	progress(current, total)
+	return nil
+}
+
+// countEntries counts the complete entries in the log
+func (w *WAL) countEntries() (uint64, error) {
+	readFile, reader, err := openLog(w.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer readFile.Close()
+
+	var n uint64
+	for {
+		_, err := reader.nextRecord()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("failed to read WAL: %w", err)
+		}
+		n++
+	}
+}
+
+// errStopReplay ends ReplayRange once it has passed the end of the range
+var errStopReplay = errors.New("stop replay")
+
+// ReplayRange calls handler for entries whose index lies within
+// [from, to]. A to of 0 replays through the end of the log.
+func (w *WAL) ReplayRange(from, to uint64, handler func(entry LogEntry) error) error {
+	err := w.Replay(func(entry LogEntry) error {
+		if entry.Index < from {
+			return nil
+		}
+		if to != 0 && entry.Index > to {
+			return errStopReplay // Indexes only increase
+		}
+		return handler(entry)
+	})
+	if errors.Is(err, errStopReplay) {
+		return nil
+	}
+	return err
+}
+
+// ReplayAfter calls handler for every entry with an index above after and
+// returns the last index handled, or after if there was none. A final
+// entry that is only partly written, as when another process is appending
+// to the log, ends the replay without an error. If the log no longer
+// holds the entry following after, it returns ErrTruncated. A read-only
+// WAL advances GetCurrentIndex to the index returned.
+func (w *WAL) ReplayAfter(after uint64, handler func(entry LogEntry) error) (uint64, error) {
+	last := after
+	readFile, reader, err := openLog(w.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return last, nil
+		}
+		return last, err
+	}
+	defer readFile.Close()
+
+	for first := true; ; first = false {
+		entry, err := reader.nextEntry()
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return last, fmt.Errorf("failed to decode entry during replay: %w", err)
+		}
+		if first && after > 0 && entry.Index > after+1 {
+			return last, fmt.Errorf("%w: log starts at %d, expected at most %d", ErrTruncated, entry.Index, after+1)
+		}
+		if entry.Index <= last {
+			continue
+		}
+
+		if err := handler(entry); err != nil {
+			return last, fmt.Errorf("handler failed for entry %d: %w", entry.Index, err)
+		}
+		last = entry.Index
+	}
+
+	if w.readOnly {
+		w.mu.Lock()
+		if last >= w.nextIndex {
+			w.nextIndex = last + 1
+		}
+		w.mu.Unlock()
+	}
+	return last, nil
+}
+
 // Close closes the WAL file
 func (w *WAL) Close() error {
+	w.closeSubscribers()
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -213,59 +771,155 @@ func (w *WAL) Close() error {
 	return nil
 }
 
-// Truncate removes all entries before the given index (used after snapshotting)
+// Truncate removes all entries before the given index (used after
+// snapshotting). The log is rewritten in the format of the WAL's options,
+// converting the entries kept if it was written in the other. The entries
+// kept are written and synced to a temporary file that then replaces the
+// log, so a crash leaves either the old log or the new one. A failure
+// before the replacement leaves the log as it was; one after it leaves
+// the log's state unknown, and every later append fails.
 func (w *WAL) Truncate(beforeIndex uint64) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
+	if w.readOnly {
+		return ErrReadOnly
+	}
+
 	// Read all entries after beforeIndex
-	readFile, err := os.Open(filepath.Join(w.dir, "wal.log"))
+	readFile, reader, err := openLog(w.dir)
 	if err != nil {
 		return err
 	}
 
-	var entriesToKeep []LogEntry
-	decoder := json.NewDecoder(readFile)
+	// Entries are kept as written, so their checksums stay valid, unless
+	// the log changes format
+	target := codecFor(w.opts.Format)
+	var entriesToKeep [][]byte
 
 	for {
-		var entry LogEntry
-		if err := decoder.Decode(&entry); err != nil {
+		rec, err := reader.nextRecord()
+		if err != nil {
 			if err == io.EOF {
 				break
 			}
 			readFile.Close()
 			return err
 		}
+		entry, err := reader.decode(rec)
+		if err != nil {
+			readFile.Close()
+			return err
+		}
 
-		if entry.Index >= beforeIndex {
-			entriesToKeep = append(entriesToKeep, entry)
+		if entry.Index < beforeIndex {
+			continue
+		}
+		if reader.format() != target.format() {
+			if rec, err = target.encode(&entry); err != nil {
+				readFile.Close()
+				return fmt.Errorf("failed to convert entry %d: %w", entry.Index, err)
+			}
 		}
+		entriesToKeep = append(entriesToKeep, rec)
 	}
 	readFile.Close()
 
-	// Close current file
-	if err := w.file.Close(); err != nil {
-		return err
-	}
-
-	// Rewrite the log with only entries to keep
+	// Write the entries to keep beside the log
 	logPath := filepath.Join(w.dir, "wal.log")
-	file, err := os.OpenFile(logPath, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	tmpPath := logPath + ".tmp"
+	size, err := writeLogFile(tmpPath, target.header(), entriesToKeep)
 	if err != nil {
-		return err
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rewrite WAL: %w", err)
+	}
+	if err := os.Rename(tmpPath, logPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace WAL: %w", err)
 	}
 
+	// The open file is now the replaced log; appends go to the new one
+	w.truncations++
+	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		w.failed = fmt.Errorf("WAL unusable after truncation: failed to reopen log: %w", err)
+		return w.failed
+	}
+	w.file.Close()
 	w.file = file
-	w.encoder = json.NewEncoder(file)
+	w.codec = target
+	w.entries = len(entriesToKeep)
+	w.size = size
 
-	// Write retained entries
-	for _, entry := range entriesToKeep {
-		if err := w.encoder.Encode(&entry); err != nil {
-			return err
+	if err := syncDir(w.dir); err != nil {
+		w.failed = fmt.Errorf("WAL unusable after truncation: %w", err)
+		return w.failed
+	}
+	return nil
+}
+
+// writeLogFile creates a log file at path holding header and records,
+// synced to disk, and returns its size
+func writeLogFile(path string, header []byte, records [][]byte) (int64, error) {
+	file, err := os.OpenFile(path, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	size := int64(0)
+	for _, rec := range append([][]byte{header}, records...) {
+		if _, err := file.Write(rec); err != nil {
+			file.Close()
+			return 0, err
 		}
+		size += int64(len(rec))
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return 0, err
+	}
+	return size, file.Close()
+}
+
+// syncDir flushes a directory's entries, so a file renamed into it
+// survives a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL directory: %w", err)
+	}
+	return nil
+}
+
+// CopyTo writes the current log contents to dst while holding the log
+// lock, so no entry is partially copied. It returns the last index copied.
+func (w *WAL) CopyTo(dst io.Writer) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	readFile, err := os.Open(filepath.Join(w.dir, "wal.log"))
+	if err != nil {
+		return 0, err
 	}
+	defer readFile.Close()
 
-	return w.file.Sync()
+	if _, err := io.Copy(dst, readFile); err != nil {
+		return 0, fmt.Errorf("failed to copy WAL: %w", err)
+	}
+	return w.nextIndex - 1, nil
+}
+
+// Size returns the number of entries in the log and its length in bytes.
+// After Truncate only the entries it kept are counted, so the size is what
+// has been logged since the last snapshot. A read-only WAL reports the
+// size of the log when it was opened.
+func (w *WAL) Size() (entries int, bytes int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.entries, w.size
 }
 
 // GetCurrentIndex returns the current WAL index
@@ -1,9 +1,12 @@
 package wal
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/fnuworsu/rdgDB/internal/graph"
 	"github.com/stretchr/testify/assert"
@@ -33,7 +36,7 @@ func TestLogAddNode(t *testing.T) {
 	defer wal.Close()
 
 	props := graph.Properties{"name": "Alice", "age": 30}
-	err = wal.LogAddNode(graph.NodeID(1), "Person", props)
+	err = wal.LogAddNode(graph.NodeID(1), "Person", props, time.Time{})
 	require.NoError(t, err)
 }
 
@@ -44,19 +47,54 @@ func TestLogAddEdge(t *testing.T) {
 	defer wal.Close()
 
 	props := graph.Properties{"since": 2020}
-	err = wal.LogAddEdge(graph.EdgeID(100), graph.NodeID(1), graph.NodeID(2), "KNOWS", props)
+	err = wal.LogAddEdge(graph.EdgeID(100), graph.NodeID(1), graph.NodeID(2), "KNOWS", props, time.Time{})
 	require.NoError(t, err)
 }
 
+func TestAppendBatch(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := NewWAL(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, wal.LogAddNode(graph.NodeID(1), "Person", nil, time.Time{}))
+	ch := wal.Subscribe(SubscribeOptions{})
+	first, err := wal.AppendBatch([]LogEntry{
+		AddNodeEntry(graph.NodeID(2), "Person", graph.Properties{"name": "Bob"}, time.Time{}),
+		AddEdgeEntry(graph.EdgeID(1), graph.NodeID(1), graph.NodeID(2), "KNOWS", nil, time.Time{}),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), first)
+	assert.Equal(t, uint64(3), wal.GetCurrentIndex())
+
+	// Subscribers see each entry of the batch
+	assert.Equal(t, OpAddNode, (<-ch).OpType)
+	assert.Equal(t, OpAddEdge, (<-ch).OpType)
+	require.NoError(t, wal.Close())
+
+	wal, err = NewWAL(dir)
+	require.NoError(t, err)
+	defer wal.Close()
+	var entries []LogEntry
+	require.NoError(t, wal.Replay(func(entry LogEntry) error {
+		entries = append(entries, entry)
+		return nil
+	}))
+	require.Len(t, entries, 3)
+	assert.Equal(t, uint64(2), entries[1].Index)
+	assert.Equal(t, "Person", entries[1].Data["label"])
+	assert.Equal(t, uint64(3), entries[2].Index)
+	assert.Equal(t, OpAddEdge, entries[2].OpType)
+}
+
 func TestReplay(t *testing.T) {
 	dir := t.TempDir()
 	wal, err := NewWAL(dir)
 	require.NoError(t, err)
 
 	// Log some operations
-	wal.LogAddNode(graph.NodeID(1), "Person", graph.Properties{"name": "Alice"})
-	wal.LogAddNode(graph.NodeID(2), "Person", graph.Properties{"name": "Bob"})
-	wal.LogAddEdge(graph.EdgeID(1), graph.NodeID(1), graph.NodeID(2), "KNOWS", nil)
+	wal.LogAddNode(graph.NodeID(1), "Person", graph.Properties{"name": "Alice"}, time.Time{})
+	wal.LogAddNode(graph.NodeID(2), "Person", graph.Properties{"name": "Bob"}, time.Time{})
+	wal.LogAddEdge(graph.EdgeID(1), graph.NodeID(1), graph.NodeID(2), "KNOWS", nil, time.Time{})
 
 	wal.Close()
 
@@ -81,6 +119,48 @@ func TestReplay(t *testing.T) {
 	assert.Equal(t, uint64(4), wal2.nextIndex)
 }
 
+func TestReplayWithProgress(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+
+	// An empty log reports once, with nothing to replay
+	var reports [][2]uint64
+	progress := func(current, total uint64) {
+		reports = append(reports, [2]uint64{current, total})
+	}
+	require.NoError(t, w.ReplayWithProgress(func(LogEntry) error { return nil }, progress))
+	assert.Equal(t, [][2]uint64{{0, 0}}, reports)
+
+	batch := make([]LogEntry, 2000)
+	for i := range batch {
+		batch[i] = AddNodeEntry(graph.NodeID(i+1), "Person", nil, time.Time{})
+	}
+	_, err = w.AppendBatch(batch)
+	require.NoError(t, err)
+
+	reports = nil
+	replayed := 0
+	require.NoError(t, w.ReplayWithProgress(func(LogEntry) error {
+		replayed++
+		return nil
+	}, progress))
+	assert.Equal(t, 2000, replayed)
+	assert.Equal(t, [][2]uint64{{1000, 2000}, {2000, 2000}}, reports)
+
+	// A failing handler ends the replay without a final report
+	reports = nil
+	err = w.ReplayWithProgress(func(entry LogEntry) error {
+		if entry.Index == 1500 {
+			return errors.New("boom")
+		}
+		return nil
+	}, progress)
+	assert.Error(t, err)
+	assert.Equal(t, [][2]uint64{{1000, 2000}}, reports)
+}
+
 func TestMultipleOperations(t *testing.T) {
 	dir := t.TempDir()
 	wal, err := NewWAL(dir)
@@ -89,7 +169,7 @@ func TestMultipleOperations(t *testing.T) {
 
 	// Add multiple nodes
 	for i := 1; i <= 10; i++ {
-		err := wal.LogAddNode(graph.NodeID(i), "Person", nil)
+		err := wal.LogAddNode(graph.NodeID(i), "Person", nil, time.Time{})
 		require.NoError(t, err)
 	}
 
@@ -115,7 +195,7 @@ func TestTruncate(t *testing.T) {
 
 	// Add 10 entries
 	for i := 1; i <= 10; i++ {
-		wal.LogAddNode(graph.NodeID(i), "Person", nil)
+		wal.LogAddNode(graph.NodeID(i), "Person", nil, time.Time{})
 	}
 
 	// Truncate before index 6 (keep entries 6-10)
@@ -137,6 +217,176 @@ func TestTruncate(t *testing.T) {
 	wal.Close()
 }
 
+func TestTruncate_FailedRewriteKeepsLog(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", nil, time.Time{}))
+	}
+
+	// The rewritten log cannot be created, so the old one stays in place
+	tmpPath := filepath.Join(dir, "wal.log.tmp")
+	require.NoError(t, os.Mkdir(tmpPath, 0755))
+	assert.Error(t, w.Truncate(4))
+
+	// and takes further appends
+	require.NoError(t, w.LogAddNode(6, "Person", nil, time.Time{}))
+	var indexes []uint64
+	require.NoError(t, w.Replay(func(entry LogEntry) error {
+		indexes = append(indexes, entry.Index)
+		return nil
+	}))
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6}, indexes)
+
+	// A successful truncation leaves no temporary file behind
+	require.NoError(t, w.Truncate(4))
+	require.NoError(t, w.LogAddNode(7, "Person", nil, time.Time{}))
+	_, err = os.Stat(tmpPath)
+	assert.True(t, os.IsNotExist(err))
+	indexes = nil
+	require.NoError(t, w.Replay(func(entry LogEntry) error {
+		indexes = append(indexes, entry.Index)
+		return nil
+	}))
+	assert.Equal(t, []uint64{4, 5, 6, 7}, indexes)
+}
+
+func TestSize(t *testing.T) {
+	dir := t.TempDir()
+	wal, err := NewWAL(dir)
+	require.NoError(t, err)
+
+	entries, bytes := wal.Size()
+	assert.Equal(t, 0, entries)
+	assert.Equal(t, int64(0), bytes)
+
+	for i := 1; i <= 10; i++ {
+		require.NoError(t, wal.LogAddNode(graph.NodeID(i), "Person", nil, time.Time{}))
+	}
+	_, err = wal.AppendBatch([]LogEntry{AddNodeEntry(11, "Person", nil, time.Time{}), AddNodeEntry(12, "Person", nil, time.Time{})})
+	require.NoError(t, err)
+
+	// The size matches the log file, before and after truncation
+	logSize := func() int64 {
+		info, err := os.Stat(filepath.Join(dir, "wal.log"))
+		require.NoError(t, err)
+		return info.Size()
+	}
+	entries, bytes = wal.Size()
+	assert.Equal(t, 12, entries)
+	assert.Equal(t, logSize(), bytes)
+
+	require.NoError(t, wal.Truncate(9))
+	entries, bytes = wal.Size()
+	assert.Equal(t, 4, entries)
+	assert.Equal(t, logSize(), bytes)
+	require.NoError(t, wal.Close())
+
+	// Reopening counts the log
+	wal, err = NewWAL(dir)
+	require.NoError(t, err)
+	defer wal.Close()
+	entries, bytes = wal.Size()
+	assert.Equal(t, 4, entries)
+	assert.Equal(t, logSize(), bytes)
+}
+
+// failingFile wraps the log file and fails the next write after writing
+// only part of it, the next sync, or truncation
+type failingFile struct {
+	logFile
+	tornWrite, failSync, failTruncate bool
+}
+
+func (f *failingFile) Write(p []byte) (int, error) {
+	if f.tornWrite {
+		f.tornWrite = false
+		n, _ := f.logFile.Write(p[:len(p)/2])
+		return n, syscall.ENOSPC
+	}
+	return f.logFile.Write(p)
+}
+
+func (f *failingFile) Sync() error {
+	if f.failSync {
+		f.failSync = false
+		return syscall.EIO
+	}
+	return f.logFile.Sync()
+}
+
+func (f *failingFile) Truncate(size int64) error {
+	if f.failTruncate {
+		return syscall.EIO
+	}
+	return f.logFile.Truncate(size)
+}
+
+func TestAppend_FailedWriteLeavesNoTornEntry(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	require.NoError(t, w.LogAddNode(1, "Person", graph.Properties{"name": "Alice"}, time.Time{}))
+
+	// Truncation after a snapshot reopens the log, which must not leave
+	// undone writes behind either
+	require.NoError(t, w.Truncate(0))
+	failing := &failingFile{logFile: w.file}
+	w.file = failing
+	entries, size := w.Size()
+
+	failing.tornWrite = true
+	err = w.LogAddNode(2, "Person", graph.Properties{"name": "Bob"}, time.Time{})
+	assert.ErrorIs(t, err, syscall.ENOSPC)
+	failing.failSync = true
+	_, err = w.AppendBatch([]LogEntry{AddNodeEntry(2, "Person", nil, time.Time{}), AddNodeEntry(3, "Person", nil, time.Time{})})
+	assert.ErrorIs(t, err, syscall.EIO)
+
+	// Nothing of the failed appends is left, and the next append follows
+	// the last good entry
+	e, sz := w.Size()
+	assert.Equal(t, entries, e)
+	assert.Equal(t, size, sz)
+	info, err := os.Stat(filepath.Join(dir, "wal.log"))
+	require.NoError(t, err)
+	assert.Equal(t, size, info.Size())
+
+	require.NoError(t, w.LogAddNode(2, "Person", graph.Properties{"name": "Bob"}, time.Time{}))
+	require.NoError(t, w.Close())
+
+	w, err = NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+	var replayed []LogEntry
+	require.NoError(t, w.Replay(func(entry LogEntry) error {
+		replayed = append(replayed, entry)
+		return nil
+	}))
+	require.Len(t, replayed, 2)
+	assert.Equal(t, uint64(2), replayed[1].Index)
+	assert.Equal(t, 2.0, replayed[1].Data["node_id"])
+	assert.Empty(t, checkIntegrity(t, w))
+}
+
+func TestAppend_FailsAfterUndoFails(t *testing.T) {
+	w, err := NewWAL(t.TempDir())
+	require.NoError(t, err)
+	defer w.Close()
+	failing := &failingFile{logFile: w.file, tornWrite: true, failTruncate: true}
+	w.file = failing
+
+	err = w.LogAddNode(1, "Person", nil, time.Time{})
+	assert.ErrorIs(t, err, syscall.EIO)
+	assert.ErrorContains(t, err, "WAL unusable after failed append")
+
+	// The end of the log is unknown, so later appends are refused
+	err = w.LogAddNode(1, "Person", nil, time.Time{})
+	assert.ErrorContains(t, err, "WAL unusable")
+	assert.Equal(t, uint64(0), w.GetCurrentIndex())
+}
+
 func TestPersistence(t *testing.T) {
 	dir := t.TempDir()
 
@@ -144,7 +394,7 @@ func TestPersistence(t *testing.T) {
 	wal1, err := NewWAL(dir)
 	require.NoError(t, err)
 
-	wal1.LogAddNode(graph.NodeID(1), "Person", graph.Properties{"name": "Test"})
+	wal1.LogAddNode(graph.NodeID(1), "Person", graph.Properties{"name": "Test"}, time.Time{})
 	wal1.Close()
 
 	// Reopen and verify data persisted
@@ -160,7 +410,161 @@ func TestPersistence(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Len(t, entries, 1)
-	assert.Equal(t, "Test", entries[0].Data["properties"].(map[string]interface{})["name"])
+	name := entries[0].Data["properties"].(map[string]interface{})["name"]
+	assert.Equal(t, "Test", graph.DecodeJSONValue(name))
+}
+
+func TestReplayRange(t *testing.T) {
+	wal, err := NewWAL(t.TempDir())
+	require.NoError(t, err)
+	defer wal.Close()
+
+	for i := 1; i <= 10; i++ {
+		require.NoError(t, wal.LogAddNode(graph.NodeID(i), "Node", nil, time.Time{}))
+	}
+
+	collect := func(from, to uint64) []uint64 {
+		var indexes []uint64
+		require.NoError(t, wal.ReplayRange(from, to, func(entry LogEntry) error {
+			indexes = append(indexes, entry.Index)
+			return nil
+		}))
+		return indexes
+	}
+
+	assert.Equal(t, []uint64{3, 4, 5}, collect(3, 5))
+	assert.Equal(t, []uint64{9, 10}, collect(9, 0))
+	assert.Len(t, collect(0, 0), 10)
+	assert.Empty(t, collect(11, 0))
+}
+
+func TestReadOnlyWAL(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	w.LogAddNode(graph.NodeID(1), "Person", nil, time.Time{})
+	w.LogAddNode(graph.NodeID(2), "Person", nil, time.Time{})
+	require.NoError(t, w.Close())
+
+	ro, err := NewReadOnlyWAL(dir)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	assert.Equal(t, uint64(2), ro.GetCurrentIndex())
+
+	count := 0
+	require.NoError(t, ro.Replay(func(entry LogEntry) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 2, count)
+
+	assert.ErrorIs(t, ro.LogAddNode(graph.NodeID(3), "Person", nil, time.Time{}), ErrReadOnly)
+	assert.ErrorIs(t, ro.Truncate(2), ErrReadOnly)
+
+	// A missing log opens as empty without being created
+	empty := t.TempDir() + "/missing"
+	ro2, err := NewReadOnlyWAL(empty)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), ro2.GetCurrentIndex())
+	_, err = os.Stat(empty)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadOnlyWAL_PartialTail(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatBinary} {
+		dir := t.TempDir()
+		w, err := NewWALWithOptions(dir, Options{Format: format})
+		require.NoError(t, err)
+		for i := 1; i <= 3; i++ {
+			require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", graph.Properties{"name": "Alice"}, time.Time{}))
+		}
+		require.NoError(t, w.Close())
+
+		// Cut the last entry short, as a writer caught mid-append leaves it
+		path := filepath.Join(dir, "wal.log")
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(path, info.Size()-5))
+
+		ro, err := NewReadOnlyWAL(dir)
+		require.NoError(t, err, "format %v", format)
+		assert.Equal(t, uint64(2), ro.GetCurrentIndex())
+		entries, _ := ro.Size()
+		assert.Equal(t, 2, entries)
+
+		last, err := ro.ReplayAfter(0, func(LogEntry) error { return nil })
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), last)
+		require.NoError(t, ro.Close())
+	}
+}
+
+func TestReplayAfter(t *testing.T) {
+	dir := t.TempDir()
+
+	w, err := NewWAL(dir)
+	require.NoError(t, err)
+	defer w.Close()
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, w.LogAddNode(graph.NodeID(i), "Person", nil, time.Time{}))
+	}
+
+	ro, err := NewReadOnlyWAL(dir)
+	require.NoError(t, err)
+	defer ro.Close()
+
+	var seen []uint64
+	handler := func(entry LogEntry) error {
+		seen = append(seen, entry.Index)
+		return nil
+	}
+	last, err := ro.ReplayAfter(1, handler)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), last)
+	assert.Equal(t, []uint64{2, 3}, seen)
+
+	// A partly written entry at the tail is left for the next call
+	f, err := os.OpenFile(filepath.Join(dir, "wal.log"), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"index":4,"type":"ADD_N`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	seen = nil
+	last, err = ro.ReplayAfter(3, handler)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), last)
+	assert.Empty(t, seen)
+
+	// Once the writer has truncated past after, the gap is reported
+	w2, err := NewWAL(t.TempDir())
+	require.NoError(t, err)
+	defer w2.Close()
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, w2.LogAddNode(graph.NodeID(i), "Person", nil, time.Time{}))
+	}
+	require.NoError(t, w2.Truncate(4))
+
+	ro2, err := NewReadOnlyWAL(w2.dir)
+	require.NoError(t, err)
+	defer ro2.Close()
+	_, err = ro2.ReplayAfter(2, handler)
+	assert.ErrorIs(t, err, ErrTruncated)
+
+	seen = nil
+	last, err = ro2.ReplayAfter(3, handler)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), last)
+	assert.Equal(t, []uint64{4, 5}, seen)
+
+	seen = nil
+	last, err = ro2.ReplayAfter(4, handler)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), last)
+	assert.Equal(t, []uint64{5}, seen)
+	assert.Equal(t, uint64(5), ro2.GetCurrentIndex())
 }
 
 func TestDeleteOperations(t *testing.T) {